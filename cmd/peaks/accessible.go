@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// trendThreshold is the relative change in the average rate between two
+// announcements that is reported as rising or falling
+const trendThreshold = 0.2
+
+// announcer averages samples between announcements and describes them in
+// words, for --accessible
+type announcer struct {
+	spoken func(uint64) string
+	names  [2]string
+	sums   [2]float64
+	count  int
+	last   [2]float64
+	primed bool
+}
+
+// newAnnouncer creates an announcer speaking rates of the given source
+func newAnnouncer(source string) *announcer {
+	a := &announcer{spoken: spokenBandwidth, names: [2]string{monitor.SeriesUpload, monitor.SeriesDownload}}
+	if source == monitor.SourceCPU {
+		a.spoken = spokenCPU
+	}
+	return a
+}
+
+// add accumulates a sample
+func (a *announcer) add(series []monitor.Series) {
+	for i := range min(len(series), 2) {
+		a.names[i] = series[i].Name
+		a.sums[i] += float64(series[i].Value)
+	}
+	a.count++
+}
+
+// announce describes the average rates since the previous announcement,
+// download (the series above the axis) first, e.g. "download 4.2 megabytes
+// per second, rising; upload 120 kilobytes per second, steady". It returns
+// an empty string when nothing was sampled.
+func (a *announcer) announce() string {
+	if a.count == 0 {
+		return ""
+	}
+	var parts []string
+	for _, i := range []int{1, 0} {
+		average := a.sums[i] / float64(a.count)
+		part := a.names[i] + " " + a.spoken(uint64(average))
+		if a.primed {
+			part += ", " + trend(a.last[i], average)
+		}
+		parts = append(parts, part)
+		a.last[i] = average
+	}
+	a.sums, a.count, a.primed = [2]float64{}, 0, true
+	return strings.Join(parts, "; ")
+}
+
+// trend describes the change from previous to current
+func trend(previous, current float64) string {
+	switch {
+	case current > previous*(1+trendThreshold) && current-previous >= 1:
+		return "rising"
+	case current < previous*(1-trendThreshold):
+		return "falling"
+	}
+	return "steady"
+}
+
+// spokenBandwidth formats a byte rate with unit names spelled out, which
+// screen readers pronounce more reliably than "MB/s"
+func spokenBandwidth(bps uint64) string {
+	units := []string{"bytes", "kilobytes", "megabytes", "gigabytes", "terabytes", "petabytes"}
+	value, exp := float64(bps), 0
+	for value >= 1024 && exp < len(units)-1 {
+		value /= 1024
+		exp++
+	}
+	return spokenNumber(value) + " " + units[exp] + " per second"
+}
+
+// spokenCPU formats a CPU time rate as a percentage of one core
+func spokenCPU(usecPerSec uint64) string {
+	return spokenNumber(float64(usecPerSec)/1e4) + " percent"
+}
+
+// spokenNumber rounds to one decimal place, dropping a trailing ".0"
+func spokenNumber(value float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0")
+}
+
+// runAccessibleMode samples without drawing a chart and writes a line of
+// text every interval, for terminal screen readers. Plain lines are read
+// out as they arrive; nothing is redrawn or moved on screen.
+func runAccessibleMode(opts options, every time.Duration, out io.Writer) error {
+	collector, err := newCollector(opts)
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	ledger, err := newLedger(opts)
+	if err != nil {
+		return err
+	}
+	if ledger != nil {
+		defer ledger.Flush()
+	}
+	history, closeHistory, err := openHistory(opts)
+	if err != nil {
+		return err
+	}
+	defer closeHistory()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, terminationSignals...)
+	defer signal.Stop(sigChan)
+
+	ticker := time.NewTicker(updateInterval)
+	defer ticker.Stop()
+	announceTicker := time.NewTicker(every)
+	defer announceTicker.Stop()
+
+	a := newAnnouncer(opts.source)
+	fmt.Fprintf(out, "peaks: announcing rates every %s. Press Ctrl+C to stop.\n", every)
+	for {
+		select {
+		case <-ticker.C:
+			series, err := collector.Sample()
+			if err != nil {
+				// Gaps and failed samples are simply left out of the average
+				continue
+			}
+			a.add(series)
+			upload, download := monitor.SplitSeries(series)
+			if history != nil {
+				history.Record(time.Now(), upload, download)
+			}
+			if ledger != nil {
+				ledger.Add(time.Now(),
+					uint64(float64(upload)*updateInterval.Seconds()),
+					uint64(float64(download)*updateInterval.Seconds()))
+			}
+		case <-announceTicker.C:
+			if line := a.announce(); line != "" {
+				fmt.Fprintln(out, line)
+			}
+		case <-sigChan:
+			return nil
+		}
+	}
+}
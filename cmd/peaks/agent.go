@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// runAgent implements "peaks agent": sample a source without a display and
+// stream the rates to any peaks that connects, so a server or container can
+// be watched from a workstation's TUI with "peaks connect HOST:7070".
+func runAgent(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:"+monitor.DefaultAgentPort, "address to accept connections on (:7070 for all interfaces)")
+	source := fs.String("source", monitor.SourceNetwork, "data source to stream (net, disk, cpu, ...)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if monitor.IsAgentSource(*source) {
+		return fmt.Errorf("an agent can't relay another agent")
+	}
+	collector, err := newCollector(options{source: *source, seed: 1})
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	return serveAgent(ln, collector, *source, stdout, updateInterval)
+}
+
+// serveAgent samples the collector every interval and publishes each sample
+// to the agent's clients until interrupted
+func serveAgent(ln net.Listener, collector monitor.Collector, source string, stdout io.Writer, interval time.Duration) error {
+	host, _ := os.Hostname()
+	server := monitor.NewAgentServer(host, source)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ln) }()
+	defer ln.Close()
+	fmt.Fprintf(stdout, "peaks agent streaming %s from %s on %s\n", source, host, ln.Addr())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, terminationSignals...)
+	defer signal.Stop(sigChan)
+
+	// The first sample only primes the counters
+	collector.Sample()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case at := <-ticker.C:
+			series, err := collector.Sample()
+			if errors.Is(err, monitor.ErrSampleGap) {
+				server.Publish(monitor.AgentSample{Time: at, Gap: true})
+				continue
+			} else if err != nil {
+				continue
+			}
+			upload, download := monitor.SplitSeries(series)
+			server.Publish(monitor.AgentSample{Time: at, Upload: upload, Download: download})
+		case err := <-served:
+			return err
+		case <-sigChan:
+			return nil
+		}
+	}
+}
+
+// connectArgs turns "peaks connect HOST[:PORT] [flags]" into the flags of a
+// full-screen peaks charting that agent, so every display flag still applies
+func connectArgs(args []string) ([]string, error) {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return nil, fmt.Errorf("usage: peaks connect HOST[:PORT] [flags]")
+	}
+	addr, err := monitor.AgentAddress(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{"--source", monitor.SourceAgentPrefix + addr}, args[1:]...), nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/alerts"
+)
+
+// alertFlashTicks is how many ticks the statusbar flashes after a rate
+// crosses its threshold
+const alertFlashTicks = 6
+
+// alertActionMsg reports the outcome of an alert's notification and hook
+type alertActionMsg struct {
+	err error
+}
+
+// checkAlerts compares a sample with the --alert-up/--alert-down thresholds.
+// A crossing is marked on the chart, flashes the statusbar and is logged;
+// the desktop notification and hook run in the background.
+func (m *model) checkAlerts(upload, download uint64) tea.Cmd {
+	if m.alerts == nil {
+		return nil
+	}
+	m.alertFlash = max(m.alertFlash-1, 0)
+	var cmds []tea.Cmd
+	for _, event := range m.alerts.Check(m.clock(), upload, download) {
+		if event.Above {
+			m.chart.AddMarker()
+			m.alertFlash = alertFlashTicks
+		}
+		if m.alertLog != "" {
+			if err := alerts.AppendLog(m.alertLog, event); err != nil {
+				m.alertErr = err
+			}
+		}
+		cmds = append(cmds, m.alertActions(event))
+	}
+	return tea.Batch(cmds...)
+}
+
+// alertActions fires the desktop notification (when raised) and the hook
+// command for an event
+func (m model) alertActions(event alerts.Event) tea.Cmd {
+	notify, hook := m.alertNotify && event.Above, m.alertCmd
+	if !notify && hook == "" {
+		return nil
+	}
+	title, body := m.alertText(event)
+	return func() tea.Msg {
+		var err error
+		if notify {
+			err = alerts.Notify(title, body)
+		}
+		if hook != "" {
+			if hookErr := alerts.RunHook(hook, event); err == nil {
+				err = hookErr
+			}
+		}
+		return alertActionMsg{err: err}
+	}
+}
+
+// alertText describes an event for a desktop notification, e.g. "peaks:
+// download above 50.00 MB/s" and "62.10 MB/s at 14:02:11"
+func (m model) alertText(event alerts.Event) (title, body string) {
+	direction := "download"
+	if event.Direction == alerts.Upload {
+		direction = "upload"
+	}
+	title = fmt.Sprintf("peaks: %s above %s", direction, m.formatRate(event.Threshold))
+	body = fmt.Sprintf("%s at %s", m.formatRate(event.Rate), event.Time.Local().Format("15:04:05"))
+	return title, body
+}
+
+// alertStatus shows the rates above their thresholds, flashing for a few
+// ticks after each crossing, or flags a failed log, notification or hook
+func (m model) alertStatus() string {
+	if m.alerts == nil {
+		return ""
+	}
+	style := lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+		Bold(true)
+	if m.alertErr != nil {
+		return style.Render("Alert: action failed")
+	}
+	upload, download := m.alerts.Raised()
+	thresholds := m.alerts.Thresholds()
+	var parts []string
+	if download {
+		parts = append(parts, fmt.Sprintf("↓ %s > %s", m.formatRate(m.currentDownload), m.formatRate(thresholds.Download)))
+	}
+	if upload {
+		parts = append(parts, fmt.Sprintf("↑ %s > %s", m.formatRate(m.currentUpload), m.formatRate(thresholds.Upload)))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return style.Reverse(m.alertFlash%2 == 1).Render("Alert: " + strings.Join(parts, " "))
+}
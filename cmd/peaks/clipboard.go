@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/muesli/termenv"
+
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// clipboardOutput is the terminal the OSC52 copy sequence is written to
+var clipboardOutput io.Writer = os.Stdout
+
+// statsSummary returns a one-line plain text summary of the current, peak and
+// total rates, suitable for pasting into a chat
+func (m model) statsSummary(at time.Time) string {
+	stats := m.ui.GetStats()
+	name := "peaks"
+	if m.ifaceFocus != "" {
+		name += " " + m.ifaceFocus
+	}
+	return fmt.Sprintf("%s @ %s: now ↓%s ↑%s | peak ↓%s ↑%s | total ↓%s ↑%s over %s",
+		name, at.Format("15:04:05"),
+		m.formatRate(m.currentDownload), m.formatRate(m.currentUpload),
+		m.formatRate(stats.PeakDownload), m.formatRate(stats.PeakUpload),
+		m.formatTotal(stats.TotalDownload), m.formatTotal(stats.TotalUpload),
+		ui.FormatDuration(stats.GetUptime()))
+}
+
+// copyStats copies the stats summary to the system clipboard. OSC52 asks the
+// terminal itself to set the clipboard, so it works over SSH too; terminals
+// without OSC52 support ignore it.
+func (m *model) copyStats() {
+	summary := m.statsSummary(time.Now())
+	termenv.NewOutput(clipboardOutput).Copy(summary)
+	m.keyNote = "Copied stats"
+	m.updateStatusbar()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/chart"
+)
+
+// Directions accepted by peaks compare
+const (
+	directionDownload = "down"
+	directionUpload   = "up"
+	directionBoth     = "both"
+)
+
+// parseTimeFlag parses an RFC 3339 time, or a duration meaning that long before now
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (expected RFC 3339 or a duration such as 2h)", value)
+	}
+	return t, nil
+}
+
+// runCompare implements "peaks compare", drawing every host in the history
+// on one chart so their traffic can be compared over the same window
+func runCompare(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	historyDir := fs.String("history", autoPath, "history directory to read (\"auto\" for the user data directory)")
+	from := fs.String("from", "", "start of the window: RFC 3339 time or a duration ago, e.g. 2h (default: earliest sample)")
+	to := fs.String("to", "", "end of the window (default: latest sample)")
+	direction := fs.String("direction", directionDownload, "series to compare: down, up or both")
+	stacked := fs.Bool("stack", false, "stack hosts on top of each other instead of overlaying them")
+	width := fs.Int("width", 0, "chart width in cells (default: terminal width)")
+	height := fs.Int("height", 12, "chart height in lines")
+	glyphs := fs.String("glyphs", chart.GlyphNameAuto, "cell glyphs: auto, braille, block or ascii")
+	plain := fs.Bool("plain", false, "disable colors")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch *direction {
+	case directionDownload, directionUpload, directionBoth:
+	default:
+		return fmt.Errorf("unknown direction %q (expected down, up or both)", *direction)
+	}
+
+	now := time.Now()
+	start, err := parseTimeFlag(*from, now)
+	if err != nil {
+		return err
+	}
+	end, err := parseTimeFlag(*to, now)
+	if err != nil {
+		return err
+	}
+
+	dir, err := resolveHistoryDir(*historyDir)
+	if err != nil {
+		return err
+	}
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	points, err := historyPoints(history, *direction)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("no samples in %s", dir)
+	}
+
+	mc := chart.NewMultiChart(*width, *height)
+	mc.SetGlyphSet(options{glyphs: *glyphs}.glyphSet())
+	columns := *width
+	if columns <= 0 {
+		columns = getTerminalWidth() / mc.CellWidth()
+		mc.SetWidth(columns)
+	}
+	mc.SetStacked(*stacked)
+	mc.SetPlainOutput(*plain)
+
+	if start.IsZero() {
+		start = points[0].time
+	}
+	if end.IsZero() {
+		end = points[len(points)-1].time
+	}
+	series := bucketHosts(points, start, end, columns)
+
+	fmt.Fprintf(stdout, "%s → %s (%s, peak per column)\n",
+		start.Local().Format("2006-01-02 15:04:05"), end.Local().Format("2006-01-02 15:04:05"), *direction)
+	fmt.Fprintln(stdout, mc.Render(series))
+	return nil
+}
+
+// hostPoint is one host's rate at a point in time
+type hostPoint struct {
+	host  string
+	time  time.Time
+	value uint64
+}
+
+// historyPoints flattens the history's minute aggregates and raw samples
+// into rates for the chosen direction, oldest first. Minutes contribute their
+// peak rates, matching how the chart downsamples.
+func historyPoints(history *accounting.History, direction string) ([]hostPoint, error) {
+	pick := func(upload, download uint64) uint64 {
+		switch direction {
+		case directionUpload:
+			return upload
+		case directionBoth:
+			return upload + download
+		default:
+			return download
+		}
+	}
+
+	minutes, err := history.Minutes()
+	if err != nil {
+		return nil, err
+	}
+	samples, err := history.Samples()
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]hostPoint, 0, len(minutes)+len(samples))
+	for _, m := range minutes {
+		points = append(points, hostPoint{host: m.Host, time: m.Time, value: pick(m.PeakUpload, m.PeakDownload)})
+	}
+	for _, s := range samples {
+		points = append(points, hostPoint{host: s.Host, time: s.Time, value: pick(s.Upload, s.Download)})
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].time.Before(points[j].time) })
+	return points, nil
+}
+
+// bucketHosts splits [start, end] into columns and keeps each host's peak per
+// column. Hosts are ordered by name so colors are stable between runs.
+func bucketHosts(points []hostPoint, start, end time.Time, columns int) []chart.HostSeries {
+	span := end.Sub(start)
+	byHost := make(map[string][]uint64)
+	for _, p := range points {
+		if p.time.Before(start) || p.time.After(end) {
+			continue
+		}
+		values, ok := byHost[p.host]
+		if !ok {
+			values = make([]uint64, columns)
+			byHost[p.host] = values
+		}
+		column := columns - 1
+		if span > 0 {
+			column = min(int(float64(p.time.Sub(start))/float64(span)*float64(columns)), columns-1)
+		}
+		values[column] = max(values[column], p.value)
+	}
+
+	series := make([]chart.HostSeries, 0, len(byHost))
+	for host, values := range byHost {
+		if host == "" {
+			host = "local"
+		}
+		series = append(series, chart.HostSeries{Name: host, Values: values})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	return series
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/alerts"
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/config"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// displayModes are the display modes m cycles through, as named in the config
+var displayModes = []string{"split", "overlay", "side-by-side"}
+
+// loadConfig resolves --config ("auto" for the user config directory) and
+// reads it; an empty path disables the config file
+func loadConfig(path string) (string, config.Config, error) {
+	if path == "" {
+		return "", config.Config{}, nil
+	}
+	if path == autoPath {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return "", config.Config{}, err
+		}
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return "", config.Config{}, err
+	}
+	return path, cfg, validateConfig(cfg)
+}
+
+// validateConfig checks the values the config package leaves to peaks
+func validateConfig(cfg config.Config) error {
+	if cfg.DisplayMode != "" {
+		valid := false
+		for _, mode := range displayModes {
+			valid = valid || mode == cfg.DisplayMode
+		}
+		if !valid {
+			return fmt.Errorf("config: unknown display_mode %q (expected split, overlay or side-by-side)", cfg.DisplayMode)
+		}
+	}
+	if cfg.Scaling != "" {
+		if _, err := chart.ParseScalingMode(cfg.Scaling); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	if cfg.TimeScale != "" {
+		if _, err := chart.ParseTimeScale(cfg.TimeScale); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	if fastest, slowest := refreshRates[0], refreshRates[len(refreshRates)-1]; cfg.Interval != 0 && (cfg.Interval < fastest || cfg.Interval > slowest) {
+		return fmt.Errorf("config: interval must be between %s and %s", fastest, slowest)
+	}
+	if cfg.Theme != "" {
+		if _, err := chart.ParseTheme(cfg.Theme); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	for _, rate := range []string{cfg.AlertUp, cfg.AlertDown} {
+		if _, err := alerts.ParseRate(rate); rate != "" && err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	if _, err := accounting.ParseSize(cfg.Quota, false); cfg.Quota != "" && err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := accounting.ParseQuotaPeriod(cfg.QuotaPeriod); cfg.QuotaPeriod != "" && err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	if _, err := ui.ParsePrefixes(cfg.Prefixes); cfg.Prefixes != "" && err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
+
+// applyConfig starts the model with the saved preferences. Flags given on
+// the command line win: --side-by-side overrides display_mode.
+func (m *model) applyConfig(cfg config.Config, sideBySide bool) {
+	m.config = cfg
+	if cfg.DisplayMode != "" && !sideBySide {
+		m.setDisplayMode(cfg.DisplayMode)
+	}
+	if mode, err := chart.ParseScalingMode(cfg.Scaling); err == nil {
+		m.chart.SetScalingMode(mode)
+	}
+	if scale, err := chart.ParseTimeScale(cfg.TimeScale); err == nil {
+		m.chart.SetTimeScale(scale)
+	}
+	if cfg.Interval > 0 {
+		m.interval = cfg.Interval
+		m.resizeHistory()
+		m.ui.GetStats().SetUpdateInterval(m.tickSpan())
+	}
+	if cfg.UploadColor != "" || cfg.DownloadColor != "" {
+		m.chart.SetColors(lipgloss.Color(cfg.UploadColor), lipgloss.Color(cfg.DownloadColor))
+	}
+	if len(cfg.UploadGradient)+len(cfg.DownloadGradient)+len(cfg.OverlapGradient) > 0 {
+		m.chart.SetGradientStops(colorStops(cfg.UploadGradient), colorStops(cfg.DownloadGradient), colorStops(cfg.OverlapGradient))
+	}
+}
+
+// colorStops converts a config gradient's colors for the chart
+func colorStops(colors []string) []lipgloss.Color {
+	stops := make([]lipgloss.Color, len(colors))
+	for i, color := range colors {
+		stops[i] = lipgloss.Color(color)
+	}
+	return stops
+}
+
+// currentConfig captures the settings W saves. Interfaces, colors, gradients and alert
+// thresholds have no keys, so they are kept as loaded.
+func (m model) currentConfig() config.Config {
+	cfg := m.config
+	cfg.Theme = ""
+	if name := m.chart.ThemeName(); name != chart.DefaultThemeName {
+		cfg.Theme = name
+	}
+	cfg.DisplayMode = m.displayMode
+	cfg.Scaling = m.chart.GetScalingMode().String()
+	cfg.TimeScale = m.chart.GetTimeScale().String()
+	cfg.Interval = 0
+	if m.sampleInterval() != updateInterval {
+		cfg.Interval = m.sampleInterval()
+	}
+	return cfg
+}
+
+// saveConfig writes the current settings to the config file, bound to W
+func (m *model) saveConfig() {
+	cfg := m.currentConfig()
+	if m.configPath == "" {
+		m.keyNote = "Config: disabled"
+	} else if err := config.Save(m.configPath, cfg); err != nil {
+		m.keyNote = "Config: save failed"
+	} else {
+		m.config = cfg
+		m.keyNote = "Saved " + filepath.Base(m.configPath) + " at " + m.clock().Format(time.TimeOnly)
+	}
+	m.updateStatusbar()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// maxConnectionRows is how many of the busiest connections the list shows
+const maxConnectionRows = 8
+
+// toggleConnections shows or hides the per-connection list beside the
+// chart, starting the packet capture behind it the first time
+func (m *model) toggleConnections() {
+	m.showConnections = !m.showConnections
+	if m.showConnections && m.connections == nil && m.connectionErr == nil {
+		stats := monitor.NewConnectionStats()
+		if err := m.packets.Add(stats.Add); err != nil {
+			m.connectionErr = err
+		} else {
+			m.connections = stats
+		}
+	}
+	m.resizeChart()
+}
+
+// refreshConnections takes the latest per-connection rates while the list
+// is shown
+func (m *model) refreshConnections() {
+	if !m.showConnections || m.connections == nil {
+		return
+	}
+	m.connectionRates, m.connectionErr = m.connections.Rates(m.processSort)
+}
+
+// connectionView renders the busiest connections for the sidebar, each as
+// its remote end over its process and rates
+func (m model) connectionView() []string {
+	lines := []string{paneTitleStyle.Render("Connections · by " + m.processSort.String())}
+	switch {
+	case errors.Is(m.connectionErr, monitor.ErrCaptureUnsupported):
+		return append(lines, paneErrorStyle.Render("Needs Linux"))
+	case m.connections == nil && m.connectionErr != nil:
+		return append(lines, paneErrorStyle.Render(m.connectionErr.Error()))
+	case len(m.connectionRates) == 0:
+		return append(lines, "No TCP/UDP traffic")
+	}
+	for _, c := range m.connectionRates[:min(len(m.connectionRates), maxConnectionRows)] {
+		name := c.Name
+		if c.PID != 0 {
+			name = fmt.Sprintf("%s %d", c.Name, c.PID)
+		}
+		lines = append(lines,
+			c.ProtocolName()+" "+c.Remote.String(),
+			fmt.Sprintf("  %-13s ↓%5s ↑%5s",
+				ui.Truncate(name, 13), m.formatRateShort(c.Download), m.formatRateShort(c.Upload)))
+	}
+	return lines
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// dashboardMinTileWidth is the narrowest a dashboard tile gets before fewer
+// columns are used
+const dashboardMinTileWidth = 30
+
+// dashboardHelp is the dashboard's bottom row
+const dashboardHelp = "tab/←→: focus · enter: zoom · q: quit"
+
+// dashboardTile is one host's chart on the dashboard
+type dashboardTile struct {
+	label            string
+	collector        monitor.Collector
+	chart            *chart.BrailleChart
+	upload, download uint64
+	peakUp, peakDown uint64
+	err              error
+}
+
+// dashboardModel tiles a chart per host in one terminal, each titled with
+// its current and peak rates. The focused tile can be zoomed to fill it.
+type dashboardModel struct {
+	tiles         []*dashboardTile
+	width, height int
+	focus         int
+	zoomed        bool
+	columns       int // 0 picks as many as fit
+}
+
+// dashboardSource resolves a dashboard argument, [NAME=]SOURCE, to a label
+// and a source. SSH targets, agents and the local sources are used as they
+// are; anything else is taken as a peaks agent's HOST[:PORT].
+func dashboardSource(arg string) (label, source string) {
+	label, source, named := strings.Cut(arg, "=")
+	if !named {
+		source = arg
+	}
+	local := []string{monitor.SourceNetwork, monitor.SourceDisk, monitor.SourceCPU, monitor.SourceWindowsHost, monitor.SourceDemo}
+	if !monitor.IsSSHSource(source) && !monitor.IsAgentSource(source) && !slices.Contains(local, source) {
+		source = monitor.SourceAgentPrefix + source
+	}
+	if !named {
+		label = strings.TrimPrefix(strings.TrimPrefix(source, monitor.SourceAgentPrefix), monitor.SourceSSHPrefix)
+		if slices.Contains(local, source) {
+			host, _ := os.Hostname()
+			label = host + " " + source
+		}
+	}
+	return label, source
+}
+
+// runDashboard implements "peaks dashboard": one terminal watching several
+// hosts, reached over SSH or through peaks agents
+func runDashboard(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	columns := fs.Int("columns", 0, "tiles per row (default: as many as fit)")
+	glyphs := fs.String("glyphs", chart.GlyphNameAuto, "cell glyphs: auto, braille, block or ascii")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: peaks dashboard [--columns N] [NAME=]HOST... (user@host, peaks://host:port or an agent's host:port)")
+	}
+	if *columns < 0 {
+		return fmt.Errorf("--columns must not be negative")
+	}
+	if _, _, err := chart.ParseGlyphSet(*glyphs); err != nil {
+		return err
+	}
+
+	dm := dashboardModel{columns: *columns}
+	opts := options{glyphs: *glyphs, seed: 1}
+	for _, arg := range fs.Args() {
+		label, source := dashboardSource(arg)
+		opts.source = source
+		collector, err := newCollector(opts)
+		if err != nil {
+			dm.close()
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		dm.addTile(label, collector, opts.glyphSet())
+	}
+	_, err := tea.NewProgram(dm, tea.WithAltScreen(), tea.WithOutput(stdout)).Run()
+	dm.close()
+	return err
+}
+
+// close releases every tile's collector
+func (dm dashboardModel) close() {
+	for _, tile := range dm.tiles {
+		closeCollector(tile.collector)
+	}
+}
+
+// addTile adds a host's chart to the dashboard
+func (dm *dashboardModel) addTile(label string, collector monitor.Collector, glyphs chart.GlyphSet) {
+	ch := chart.NewBrailleChart(defaultDataPoints)
+	ch.SetGlyphSet(glyphs)
+	ch.SetMinHeight(2)
+	dm.tiles = append(dm.tiles, &dashboardTile{label: label, collector: collector, chart: ch})
+}
+
+// Init implements tea.Model
+func (dm dashboardModel) Init() tea.Cmd {
+	return tickCmd(updateInterval, 0)
+}
+
+// Update implements tea.Model
+func (dm dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		dm.width, dm.height = msg.Width, msg.Height
+		dm.resize()
+	case tickMsg:
+		dm.sample()
+		return dm, tickCmd(updateInterval, 0)
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return dm, tea.Quit
+		case "tab", "right", "l":
+			dm.focus = (dm.focus + 1) % len(dm.tiles)
+		case "shift+tab", "left", "h":
+			dm.focus = (dm.focus + len(dm.tiles) - 1) % len(dm.tiles)
+		case "enter", "z":
+			dm.zoomed = !dm.zoomed
+		case "esc":
+			dm.zoomed = false
+		default:
+			return dm, nil
+		}
+		dm.resize()
+	}
+	return dm, nil
+}
+
+// sample adds every host's rates to its chart. A host that can't be reached
+// is drawn as a gap, and its error replaces its rates.
+func (dm *dashboardModel) sample() {
+	for _, tile := range dm.tiles {
+		series, err := tile.collector.Sample()
+		tile.err = err
+		if err != nil {
+			tile.chart.AddGap()
+			if errors.Is(err, monitor.ErrSampleGap) {
+				tile.err = nil
+			}
+			continue
+		}
+		tile.upload, tile.download = monitor.SplitSeries(series)
+		tile.peakUp, tile.peakDown = max(tile.peakUp, tile.upload), max(tile.peakDown, tile.download)
+		tile.chart.AddDataPoint(tile.upload, tile.download)
+	}
+}
+
+// grid returns how many tile columns and rows the dashboard uses
+func (dm dashboardModel) grid() (columns, rows int) {
+	if dm.zoomed {
+		return 1, 1
+	}
+	columns = dm.columns
+	if columns == 0 {
+		// As square as the width allows
+		columns = int(math.Ceil(math.Sqrt(float64(len(dm.tiles)))))
+		columns = max(1, min(columns, dm.width/dashboardMinTileWidth))
+	}
+	columns = min(columns, len(dm.tiles))
+	return columns, (len(dm.tiles) + columns - 1) / columns
+}
+
+// tileSize returns the size of each tile's chart, leaving a title row per
+// tile, a separator column between tiles and the help row
+func (dm dashboardModel) tileSize() (width, height int) {
+	columns, rows := dm.grid()
+	width = (dm.width - (columns - 1)) / columns
+	height = (dm.height-1)/rows - 1
+	return width, height
+}
+
+// resize sizes the charts to their tiles
+func (dm *dashboardModel) resize() {
+	width, height := dm.tileSize()
+	for _, tile := range dm.tiles {
+		tile.chart.FitWidth(width)
+		tile.chart.SetHeight(height)
+	}
+}
+
+// title names a tile with its current and peak rates, or its error
+func (dm dashboardModel) title(i, width int) string {
+	tile := dm.tiles[i]
+	rates := fmt.Sprintf("↓ %s ↑ %s  peak ↓ %s ↑ %s",
+		ui.FormatBandwidth(tile.download), ui.FormatBandwidth(tile.upload),
+		ui.FormatBandwidth(tile.peakDown), ui.FormatBandwidth(tile.peakUp))
+	if tile.err != nil {
+		rates = tile.err.Error()
+	}
+	if i == dm.focus {
+		return ui.Truncate(stackFocusStyle.Render("▸ "+tile.label+"  "+rates), width)
+	}
+	return ui.Truncate(stackTitleStyle.Render("  "+tile.label+"  "+rates), width)
+}
+
+// tileView renders a tile as a block exactly width columns wide
+func (dm dashboardModel) tileView(i, width int) string {
+	return lipgloss.NewStyle().Width(width).MaxWidth(width).Render(dm.title(i, width) + "\n" + dm.tiles[i].chart.Render())
+}
+
+// View implements tea.Model
+func (dm dashboardModel) View() string {
+	if dm.width == 0 {
+		return "\n  Initializing..."
+	}
+	width, _ := dm.tileSize()
+	if dm.zoomed {
+		return dm.tileView(dm.focus, width) + "\n" + dm.helpView()
+	}
+	var rows []string
+	columns, _ := dm.grid()
+	for start := 0; start < len(dm.tiles); start += columns {
+		var row []string
+		for i := start; i < min(start+columns, len(dm.tiles)); i++ {
+			if i > start {
+				row = append(row, " ")
+			}
+			row = append(row, dm.tileView(i, width))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	}
+	return strings.Join(rows, "\n") + "\n" + dm.helpView()
+}
+
+// helpView is the dashboard's key help row
+func (dm dashboardModel) helpView() string {
+	return stackTitleStyle.Render(ui.Truncate(dashboardHelp, dm.width))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+// startDebugServer exposes net/http/pprof profiles and runtime metrics on addr
+// (e.g. ":6060") so performance issues can be profiled in place.
+// The listener is opened synchronously so address errors are reported before
+// the TUI takes over the terminal; requests are then served in the background.
+func startDebugServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to start pprof server: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// expvar publishes memstats and cmdline; add a few cheap runtime gauges
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	go http.Serve(listener, mux)
+	return nil
+}
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("version", expvar.Func(func() any {
+		return version
+	}))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// errorReporter is implemented by collectors that count interface errors
+// and drops
+type errorReporter interface {
+	ErrorStats() monitor.ErrorStats
+}
+
+// markDroppedSample flags the newest chart column when packets were dropped
+// over the last sample interval, with --mark-drops
+func (m *model) markDroppedSample() {
+	reporter, ok := m.collector.(errorReporter)
+	if !m.markDrops || !ok {
+		return
+	}
+	if reporter.ErrorStats().DropRate > 0 {
+		m.chart.AddMarker()
+	}
+}
+
+// errorStatus formats the errors and drops since start with their current
+// rates, in red while they are rising. It is empty until there are any.
+func (m model) errorStatus() string {
+	reporter, ok := m.collector.(errorReporter)
+	if !ok {
+		return ""
+	}
+	stats := reporter.ErrorStats()
+	if stats.Errors == 0 && stats.Drops == 0 {
+		return ""
+	}
+	status := fmt.Sprintf("Errors: %d (%.1f/s) Drops: %d (%.1f/s)", stats.Errors, stats.ErrorRate, stats.Drops, stats.DropRate)
+	if stats.ErrorRate > 0 || stats.DropRate > 0 {
+		alertStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+			Bold(true)
+		return alertStyle.Render(status)
+	}
+	return status
+}
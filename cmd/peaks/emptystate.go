@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// emptyStateInterval is how often the empty-state screen re-reads link states
+const emptyStateInterval = 5 * time.Second
+
+// linkReporter is implemented by collectors that can list the link status
+// of the interfaces they monitor
+type linkReporter interface {
+	Links() ([]monitor.LinkStatus, error)
+}
+
+var (
+	emptyTitleStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#60A5FA", Light: "#2563EB"}).
+			Bold(true)
+	emptyTextStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#9CA3AF", Light: "#6B7280"})
+)
+
+// showEmptyState reports whether the chart area shows the empty-state screen
+// instead of the chart: nothing but zero rates since start (or since focusing
+// an interface), the collector failing, or the focused interface being down
+func (m model) showEmptyState() bool {
+	if m.paused || m.isTiny() {
+		return false
+	}
+	return !m.sawData || m.sampleErr != nil || m.focusedLinkDown()
+}
+
+// focusedLinkDown reports whether the interface focused with n/N is down
+func (m model) focusedLinkDown() bool {
+	for _, link := range m.links {
+		if link.Name == m.ifaceFocus && !link.Up {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshLinks re-reads the monitored interfaces' link states while the
+// empty-state screen can show them
+func (m *model) refreshLinks(now time.Time) {
+	reporter, ok := m.collector.(linkReporter)
+	if !ok || now.Sub(m.linksChecked) < emptyStateInterval {
+		return
+	}
+	if m.sawData && m.sampleErr == nil && m.ifaceFocus == "" {
+		return
+	}
+	m.linksChecked = now
+	m.links, _ = reporter.Links()
+}
+
+// emptyStateView renders the empty-state screen in place of the chart: why
+// nothing is drawn yet, the monitored interfaces and a hint on choosing them
+func (m model) emptyStateView(height int) string {
+	var lines []string
+	switch {
+	case m.sampleErr != nil && os.IsPermission(m.sampleErr):
+		lines = append(lines, emptyTitleStyle.Render("Permission denied reading counters"),
+			emptyTextStyle.Render(m.sampleErr.Error()),
+			emptyTextStyle.Render("Run peaks as a user that can read them, or pick another --source"))
+	case m.sampleErr != nil:
+		lines = append(lines, emptyTitleStyle.Render("No data from the collector"),
+			emptyTextStyle.Render(m.sampleErr.Error()))
+	case m.focusedLinkDown():
+		lines = append(lines, emptyTitleStyle.Render(m.ifaceFocus+" is down"))
+	case m.ifaceFocus != "":
+		lines = append(lines, emptyTitleStyle.Render("Waiting for traffic on "+m.ifaceFocus+"…"))
+	case m.inputPath != "" && m.remote == nil:
+		lines = append(lines, emptyTitleStyle.Render("Waiting for lines in "+m.inputPath+"…"),
+			emptyTextStyle.Render("Append \"timestamp value1 [value2]\" lines, e.g. 1718000000 0.42"))
+	case m.sourceNote == sourceNote(monitor.SourceStdin):
+		lines = append(lines, emptyTitleStyle.Render("Waiting for values on stdin…"),
+			emptyTextStyle.Render("Pipe in \"timestamp value1 [value2]\" lines, e.g. 1718000000 0.42"))
+	default:
+		lines = append(lines, emptyTitleStyle.Render("Waiting for traffic…"))
+	}
+
+	if len(m.links) > 0 {
+		lines = append(lines, "")
+		for _, link := range m.links {
+			state := "up"
+			if speed := formatLinkSpeed(link); speed != "" {
+				state = speed
+			}
+			if !link.Up {
+				state = "down"
+			}
+			lines = append(lines, emptyTextStyle.Render(fmt.Sprintf("%-12s %s", link.Name, state)))
+		}
+		lines = append(lines, "", emptyTextStyle.Render("n/N focuses one interface; --group NAME=IFACE,… charts a set of them"))
+	}
+
+	for i, line := range lines {
+		lines[i] = ui.Truncate(line, m.chartAreaWidth())
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lipgloss.Place(m.chartAreaWidth(), height, lipgloss.Center, lipgloss.Center, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/chart"
+)
+
+// exportExtensions names export files by their --output format
+var exportExtensions = map[string]string{outputCSV: ".csv", outputJSON: ".jsonl"}
+
+// saveExport writes the chart's samples to dir in the same CSV or JSON Lines
+// format as --output, so the same scripts can read both. Gaps are left out.
+// It returns the path of the file.
+func saveExport(dir, format string, points []chart.DataPoint, interval time.Duration, at time.Time) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	ext := exportExtensions[format]
+	path := filepath.Join(dir, "peaks-"+at.Format(screenshotLayout)+ext)
+	// Several exports in one second get a numeric suffix
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("peaks-%s-%d%s", at.Format(screenshotLayout), n, ext))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	write, err := newRecordWriter(format, file)
+	if err != nil {
+		file.Close()
+		return "", err
+	}
+	var totalUpload, totalDownload uint64
+	for _, p := range points {
+		if p.Gap {
+			continue
+		}
+		totalUpload += uint64(float64(p.Upload) * interval.Seconds())
+		totalDownload += uint64(float64(p.Download) * interval.Seconds())
+		if err := write(outputRecord{
+			Time:          p.Time.UTC(),
+			Upload:        p.Upload,
+			Download:      p.Download,
+			TotalUpload:   totalUpload,
+			TotalDownload: totalDownload,
+		}); err != nil {
+			file.Close()
+			return "", err
+		}
+	}
+	return path, file.Close()
+}
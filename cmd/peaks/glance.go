@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// glanceStale is how old the newest sample may be before peaks glance warns
+// that nothing is recording any more
+const glanceStale = 10 * time.Second
+
+// runGlance implements "peaks glance": one static frame of this host's recent
+// history and its latest rates, for tmux display-popup or a zellij floating
+// pane bound to a hotkey. It needs a peaks (or peaks --compact) recording
+// with --history.
+func runGlance(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("glance", flag.ContinueOnError)
+	historyDir := fs.String("history", autoPath, "history directory to read (\"auto\" for the user data directory)")
+	window := fs.Duration("window", 5*time.Minute, "how much recent history to chart")
+	width := fs.Int("width", 0, "chart width in cells (default: terminal width)")
+	height := fs.Int("height", chart.MinChartHeight, "chart height in lines")
+	glyphs := fs.String("glyphs", chart.GlyphNameAuto, "cell glyphs: auto, braille, block or ascii")
+	plain := fs.Bool("plain", false, "disable colors")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dir, err := resolveHistoryDir(*historyDir)
+	if err != nil {
+		return err
+	}
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+	now := time.Now()
+	recent, err := history.RecentSamples(now.Add(-*window))
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	if len(recent) == 0 {
+		return fmt.Errorf("no samples from the last %s in %s (record them with peaks --history)", *window, dir)
+	}
+
+	ch := chart.NewBrailleChart(defaultDataPoints)
+	ch.SetGlyphSet(options{glyphs: *glyphs}.glyphSet())
+	ch.SetPlainOutput(*plain)
+	ch.SetMinHeight(2) // popups are small
+	ch.SetHeight(*height)
+	columns := *width
+	if columns <= 0 {
+		columns = getTerminalWidth() / ch.CellWidth()
+	}
+	ch.SetWidth(columns)
+	upload, download := bucketSamples(recent, now.Add(-*window), now, ch.GetWidth())
+	for i := range upload {
+		ch.AddDataPoint(upload[i], download[i])
+	}
+
+	latest := recent[len(recent)-1]
+	rates := fmt.Sprintf("↓ %s  ↑ %s  at %s", ui.FormatBandwidth(latest.Download), ui.FormatBandwidth(latest.Upload),
+		latest.Time.Local().Format("15:04:05"))
+	if age := now.Sub(latest.Time); age > glanceStale {
+		rates += fmt.Sprintf(" (nothing recorded for %s)", ui.FormatDuration(age))
+	}
+	fmt.Fprintf(stdout, "peaks · %s · last %s\n", host, *window)
+	fmt.Fprintln(stdout, ch.Render())
+	fmt.Fprintln(stdout, rates)
+	return nil
+}
+
+// bucketSamples splits [start, end] into columns and keeps each column's
+// peak upload and download rates
+func bucketSamples(samples []accounting.Sample, start, end time.Time, columns int) (upload, download []uint64) {
+	upload, download = make([]uint64, columns), make([]uint64, columns)
+	span := end.Sub(start)
+	for _, s := range samples {
+		column := columns - 1
+		if span > 0 {
+			column = max(min(int(float64(s.Time.Sub(start))/float64(span)*float64(columns)), columns-1), 0)
+		}
+		upload[column] = max(upload[column], s.Upload)
+		download[column] = max(download[column], s.Download)
+	}
+	return upload, download
+}
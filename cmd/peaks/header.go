@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// defaultHeaderFormat lays out the --header bar unless --header-format is given
+const defaultHeaderFormat = "{host} · {iface} {link} · {ip} · {time}"
+
+// headerInfoInterval is how often the header's link speed and address are
+// looked up again; the clock updates every second
+const headerInfoInterval = 30 * time.Second
+
+// headerFields are the placeholders a header format can use
+var headerFields = map[string]bool{"host": true, "iface": true, "link": true, "ip": true, "time": true}
+
+// placeholderPattern matches a {name} placeholder
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// headerTickMsg refreshes the header bar
+type headerTickMsg struct{}
+
+// headerTickCmd schedules the next header refresh
+func headerTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return headerTickMsg{} })
+}
+
+// headerStyle draws the header bar
+var headerStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Dark: "#D1D5DB", Light: "#374151"}).
+	Background(lipgloss.AdaptiveColor{Dark: "#1F2937", Light: "#E5E7EB"})
+
+// parseHeaderFormat checks that a header format only uses known placeholders
+func parseHeaderFormat(format string) (string, error) {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(format, -1) {
+		if !headerFields[match[1]] {
+			return "", fmt.Errorf("unknown header placeholder {%s} (expected {host}, {iface}, {link}, {ip} or {time})", match[1])
+		}
+	}
+	return format, nil
+}
+
+// expandHeader fills in a header format's placeholders. Separators left
+// around empty values collapse, so an unknown link speed leaves no gap.
+func expandHeader(format string, values map[string]string) string {
+	line := placeholderPattern.ReplaceAllStringFunc(format, func(placeholder string) string {
+		return values[placeholder[1:len(placeholder)-1]]
+	})
+	for strings.Contains(line, "  ") {
+		line = strings.ReplaceAll(line, "  ", " ")
+	}
+	return strings.TrimSpace(line)
+}
+
+// headerInterface returns the interface the header describes: the one
+// focused with n/N, else the one carrying the default route
+func (m model) headerInterface() string {
+	if m.ifaceFocus != "" {
+		return m.ifaceFocus
+	}
+	if m.routes != nil {
+		return m.routes.Current().Interface
+	}
+	return ""
+}
+
+// refreshHeader updates the header bar's values. The clock always updates;
+// host, link and address lookups only when stale or the interface changed.
+func (m *model) refreshHeader(now time.Time) {
+	if m.header == nil {
+		m.header = make(map[string]string, len(headerFields))
+	}
+	m.header["time"] = now.Format("15:04:05")
+	iface := m.headerInterface()
+	if iface == m.header["iface"] && now.Sub(m.headerRefreshed) < headerInfoInterval {
+		return
+	}
+	m.headerRefreshed = now
+	m.header["host"], _ = os.Hostname()
+	m.header["iface"] = iface
+	m.header["link"], m.header["ip"] = "", ""
+	if iface == "" {
+		return
+	}
+	if statuses, err := monitor.LinkStatuses(); err == nil {
+		for _, status := range statuses {
+			if status.Name == iface {
+				m.header["link"] = formatLinkSpeed(status)
+			}
+		}
+	}
+	m.header["ip"] = interfaceAddress(iface)
+}
+
+// formatLinkSpeed describes a link's negotiated speed, e.g. "1 Gb/s"
+func formatLinkSpeed(status monitor.LinkStatus) string {
+	switch {
+	case !status.Up:
+		return "link down"
+	case status.Speed >= 1e9:
+		return spokenNumber(float64(status.Speed)/1e9) + " Gb/s"
+	case status.Speed > 0:
+		return spokenNumber(float64(status.Speed)/1e6) + " Mb/s"
+	}
+	return ""
+}
+
+// interfaceAddress returns an interface's first IPv4 address, or its first
+// other address, without the prefix length
+func interfaceAddress(name string) string {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return ""
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return ""
+	}
+	var fallback string
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+		if fallback == "" && !ipNet.IP.IsLinkLocalUnicast() {
+			fallback = ipNet.IP.String()
+		}
+	}
+	return fallback
+}
+
+// headerView renders the header bar across the full width
+func (m model) headerView() string {
+	line := " " + expandHeader(m.headerFormat, m.header)
+	return headerStyle.Width(m.width).Render(ui.Truncate(line, m.width))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+)
+
+// resolveHistoryDir expands the "auto" history directory
+func resolveHistoryDir(dir string) (string, error) {
+	if dir == autoPath {
+		return accounting.DefaultHistoryDir()
+	}
+	return dir, nil
+}
+
+// runImport implements "peaks import [--history DIR] [--host NAME] FILE...",
+// merging samples recorded by another peaks instance into the local history.
+// "-" reads standard input.
+func runImport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	historyDir := fs.String("history", autoPath, "history directory to import into (\"auto\" for the user data directory)")
+	host := fs.String("host", "", "host to attribute samples without one to (default: the file name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: peaks import [--history DIR] [--host NAME] FILE...")
+	}
+
+	dir, err := resolveHistoryDir(*historyDir)
+	if err != nil {
+		return err
+	}
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	for _, path := range fs.Args() {
+		name := *host
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			r = file
+		}
+
+		added, skipped, err := history.Import(r, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(stdout, "%s: imported %d samples, skipped %d duplicates\n", path, added, skipped)
+	}
+	return nil
+}
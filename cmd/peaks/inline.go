@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// defaultInlineWidth is how many cells wide the --compact-stdout chart is
+const defaultInlineWidth = 20
+
+// inlineChart draws the one line chart of --compact-stdout: overlaid, so
+// both directions share the line's four dot rows
+type inlineChart struct {
+	chart      *chart.BrailleChart
+	width      int
+	formatRate func(uint64) string
+}
+
+// newInlineChart creates a one line chart width cells wide
+func newInlineChart(opts options, width int, plain bool) *inlineChart {
+	ch := chart.NewBrailleChart(defaultDataPoints)
+	ch.SetOverlayMode(true)
+	ch.SetGlyphSet(opts.glyphSet())
+	ch.SetTheme(opts.chartTheme())
+	ch.SetPlainOutput(plain)
+	ch.SetWidth(width) // scale to the columns shown
+	ch.SetFixedMaxValue(opts.maxScale)
+	ch.SetDecimalUnits(opts.prefixes == ui.PrefixesSI)
+	formatRate := ui.FormatBandwidth
+	if opts.units == ui.UnitsBits {
+		formatRate = ui.FormatBitrate
+		ch.SetBitUnits(true)
+	}
+	if opts.source == monitor.SourceCPU {
+		formatRate = ui.FormatCPU
+		ch.SetLabelFormatter(ui.FormatCPU)
+	}
+	return &inlineChart{chart: ch, width: width, formatRate: formatRate}
+}
+
+// line renders the chart followed by the given rates, download first as in
+// the full-screen statusbar. The chart is left out until it has data.
+func (c *inlineChart) line(upload, download uint64) string {
+	rates := fmt.Sprintf("↓ %s ↑ %s", c.formatRate(download), c.formatRate(upload))
+	if c.chart.GetDataLength() == 0 {
+		return rates
+	}
+	return c.chart.RenderCompactWithSize(c.width*c.chart.CellWidth(), 1) + " " + rates
+}
+
+// runInlineMode implements --compact-stdout: a single line chart and the
+// current rates printed to stdout, for tmux status-right, shell prompts and
+// starship segments, which run a command and show what it prints.
+//
+// By default one line is printed and peaks exits. The chart is drawn from
+// the samples a running peaks records with --history (the auto directory
+// unless --history is given); without any, peaks samples for one interval
+// and prints only the rates. With stream, a line is printed for every sample
+// until interrupted, and samples are recorded like in the other modes.
+func runInlineMode(opts options, window time.Duration, width int, plain, stream bool, out io.Writer) error {
+	if width < chart.MinChartWidth {
+		return fmt.Errorf("--compact-width must be at least %d", chart.MinChartWidth)
+	}
+	c := newInlineChart(opts, width, plain)
+	if stream {
+		return streamInline(opts, c, out)
+	}
+
+	historyDir := opts.historyDir
+	if historyDir == "" {
+		historyDir = autoPath
+	}
+	if line, ok := inlineFromHistory(c, historyDir, window, time.Now()); ok {
+		_, err := fmt.Fprintln(out, line)
+		return err
+	}
+
+	collector, err := newCollector(opts)
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	// The first sample only primes the counters
+	collector.Sample()
+	time.Sleep(updateInterval)
+	series, err := collector.Sample()
+	if errors.Is(err, monitor.ErrSampleGap) {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	upload, download := monitor.SplitSeries(series)
+	_, err = fmt.Fprintln(out, c.line(upload, download))
+	return err
+}
+
+// inlineFromHistory draws the last window of this host's recorded history
+// into the chart and returns its line with the latest recorded rates. It
+// reports false when nothing recent was recorded.
+func inlineFromHistory(c *inlineChart, historyDir string, window time.Duration, now time.Time) (string, bool) {
+	dir, err := resolveHistoryDir(historyDir)
+	if err != nil {
+		return "", false
+	}
+	// Runs on every status line refresh, so don't create or lock anything
+	history, err := accounting.OpenHistoryReadOnly(dir)
+	if err != nil {
+		return "", false
+	}
+	recent, err := history.RecentSamples(now.Add(-window))
+	if err != nil {
+		return "", false
+	}
+	if len(recent) == 0 || now.Sub(recent[len(recent)-1].Time) > glanceStale {
+		// A snapshot from a recording that stopped would look live
+		return "", false
+	}
+	upload, download := bucketSamples(recent, now.Add(-window), now, c.width)
+	for i := range upload {
+		c.chart.AddDataPoint(upload[i], download[i])
+	}
+	latest := recent[len(recent)-1]
+	return c.line(latest.Upload, latest.Download), true
+}
+
+// streamInline prints a line for every sample until interrupted, for status
+// bars reading a pipe
+func streamInline(opts options, c *inlineChart, out io.Writer) error {
+	collector, err := newCollector(opts)
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	ledger, err := newLedger(opts)
+	if err != nil {
+		return err
+	}
+	if ledger != nil {
+		defer ledger.Flush()
+	}
+	history, closeHistory, err := openHistory(opts)
+	if err != nil {
+		return err
+	}
+	defer closeHistory()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, terminationSignals...)
+	defer signal.Stop(sigChan)
+
+	ticker := time.NewTicker(updateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case at := <-ticker.C:
+			series, err := collector.Sample()
+			if errors.Is(err, monitor.ErrSampleGap) {
+				c.chart.AddGap()
+				continue
+			} else if err != nil {
+				continue
+			}
+			upload, download := monitor.SplitSeries(series)
+			c.chart.AddDataPoint(upload, download)
+			if history != nil {
+				history.Record(at, upload, download)
+			}
+			if ledger != nil {
+				ledger.Add(at,
+					uint64(float64(upload)*updateInterval.Seconds()),
+					uint64(float64(download)*updateInterval.Seconds()))
+			}
+			if _, err := fmt.Fprintln(out, c.line(upload, download)); err != nil {
+				return err
+			}
+		case <-sigChan:
+			return nil
+		}
+	}
+}
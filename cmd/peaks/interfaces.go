@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// interfaceTotalsInterval is how often --interface-totals is rewritten
+const interfaceTotalsInterval = time.Minute
+
+// interfaceTotaler is implemented by collectors that keep per-interface totals
+type interfaceTotaler interface {
+	InterfaceTotals() []monitor.InterfaceTotals
+}
+
+// interfaceTotalsColumns is the CSV header of an interface totals export
+var interfaceTotalsColumns = []string{
+	"interface", "session_upload", "session_download", "today_upload", "today_download",
+	"peak_upload", "peak_download", "errors", "drops",
+}
+
+// writeInterfaceTotals exports the collector's per-interface totals to path,
+// as JSON for a .json file and CSV otherwise. The file is replaced atomically
+// so readers never see a partial export.
+func writeInterfaceTotals(path string, collector monitor.Collector) error {
+	totaler, ok := collector.(interfaceTotaler)
+	if !ok {
+		return fmt.Errorf("--interface-totals needs the network source")
+	}
+	totals := totaler.InterfaceTotals()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = encodeInterfaceTotalsJSON(tmp, totals)
+	} else {
+		err = encodeInterfaceTotalsCSV(tmp, totals)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// encodeInterfaceTotalsJSON writes the totals as an indented JSON array
+func encodeInterfaceTotalsJSON(w io.Writer, totals []monitor.InterfaceTotals) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(totals)
+}
+
+// encodeInterfaceTotalsCSV writes the totals as CSV with a header row
+func encodeInterfaceTotalsCSV(w io.Writer, totals []monitor.InterfaceTotals) error {
+	writer := csv.NewWriter(w)
+	writer.Write(interfaceTotalsColumns)
+	for _, t := range totals {
+		row := []string{t.Name}
+		for _, n := range []uint64{
+			t.SessionUpload, t.SessionDownload, t.TodayUpload, t.TodayDownload,
+			t.PeakUpload, t.PeakDownload, t.Errors, t.Drops,
+		} {
+			row = append(row, strconv.FormatUint(n, 10))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// interfaceFocuser is implemented by collectors whose rates can be narrowed
+// to a single interface
+type interfaceFocuser interface {
+	interfaceTotaler
+	SetFocus(name string)
+	AllRates() monitor.BandwidthRates
+}
+
+// cycleInterface focuses the next (step 1) or previous (step -1) interface,
+// going through all interfaces together between the last and the first.
+// The chart restarts for the new selection; Peak and Total pick up the
+// interface's own figures since peaks started.
+func (m *model) cycleInterface(step int) {
+	focuser, ok := m.collector.(interfaceFocuser)
+	if !ok {
+		return
+	}
+	totals := focuser.InterfaceTotals()
+	names := make([]string, 1, len(totals)+1) // "" is all interfaces
+	current := 0
+	for _, t := range totals {
+		if t.Name == m.ifaceFocus {
+			current = len(names)
+		}
+		names = append(names, t.Name)
+	}
+	current = (current + step + len(names)) % len(names)
+	m.ifaceFocus = names[current]
+	focuser.SetFocus(m.ifaceFocus)
+	if m.headerFormat != "" {
+		m.refreshHeader(m.clock())
+	}
+
+	m.chart.Reset()
+	m.pausedSamples.reset()
+	m.sawData = false
+	m.linksChecked = time.Time{}
+	m.refreshLinks(m.clock())
+	m.currentUpload, m.currentDownload = 0, 0
+	stats := m.ui.GetStats()
+	stats.Reset()
+	if current > 0 {
+		t := totals[current-1]
+		stats.TotalUpload, stats.TotalDownload = t.SessionUpload, t.SessionDownload
+		stats.PeakUpload, stats.PeakDownload = t.PeakUpload, t.PeakDownload
+	}
+	m.updateStatusbar()
+}
+
+// recordedRates returns the rates the ledger and history record: every
+// monitored interface, even while the display is focused on one
+func (m model) recordedRates(upload, download uint64) (uint64, uint64) {
+	if focuser, ok := m.collector.(interfaceFocuser); ok && m.ifaceFocus != "" {
+		all := focuser.AllRates()
+		return all.Upload, all.Download
+	}
+	return upload, download
+}
@@ -0,0 +1,71 @@
+package main
+
+import "github.com/marcodenic/peaks/internal/chart"
+
+// layout records which decorations around the chart fit the terminal
+type layout struct {
+	header    bool // --header bar
+	ruler     bool // --ruler time ticks
+	timeAxis  bool // --time-axis labels
+	statusbar bool
+	title     bool // title row, with the controls help when there is room
+	help      bool
+	chartRows int
+}
+
+// rows counts the rows the decorations take
+func (l layout) rows() int {
+	rows := 0
+	for _, shown := range []bool{l.header, l.ruler, l.timeAxis, l.statusbar, l.title} {
+		if shown {
+			rows++
+		}
+	}
+	return rows
+}
+
+// minChartRows is the fewest rows the chart is given before decorations
+// are dropped; tiny terminals go down to one row per direction
+func (m model) minChartRows() int {
+	if m.isTiny() {
+		return 2
+	}
+	return chart.MinChartHeight
+}
+
+// layout decides which decorations fit. As the terminal gets shorter they
+// are dropped in priority order (help, title, ruler, time axis, header,
+// statusbar) so the chart keeps at least minChartRows. The help goes one row
+// before the title, as dropping it alone frees no rows.
+func (m model) layout() layout {
+	tiny := m.isTiny()
+	l := layout{
+		header:    m.headerFormat != "" && !tiny,
+		ruler:     m.ruler && !tiny,
+		timeAxis:  m.timeAxis && !tiny,
+		statusbar: m.showStatusbar,
+		title:     !tiny,
+		help:      !tiny,
+	}
+	minRows := m.minChartRows()
+	if m.height-l.rows() <= minRows {
+		l.help = false
+	}
+	for _, decoration := range []*bool{&l.title, &l.ruler, &l.timeAxis, &l.header, &l.statusbar} {
+		if m.height-l.rows() < minRows {
+			*decoration = false
+		}
+	}
+	l.chartRows = m.height - l.rows()
+	return l
+}
+
+// showHeader reports whether the --header bar is shown
+func (m model) showHeader() bool {
+	return m.layout().header
+}
+
+// showRuler reports whether the time tick ruler is drawn under the chart
+func (m model) showRuler() bool {
+	return m.layout().ruler
+}
@@ -12,26 +12,79 @@
 //
 // Usage:
 //
-//	peaks
+//	peaks [--source net|disk|cpu|winhost|demo] [--glyphs auto|braille|block|ascii] [--theme NAME]
+//	peaks [--color auto|truecolor|256|16|none] [--no-color]
+//	peaks --demo [--seed 1]
+//	COMMAND | peaks --stdin
+//	peaks --input FILE
+//	peaks --prom-url URL --query PROMQL [--query-up PROMQL]
+//	peaks --snmp HOST[:161] [--community public] --ifindex N
+//	peaks --remote user@host|winhost|prometheus|SOURCE [--prom-url URL --query PROMQL]
+//	peaks [--pane NAME=COMMAND ...] [--pane-interval 10s]
+//	peaks --record [--seed 1]
+//	peaks --replay 2h|FROM..TO [--history DIR]
+//	peaks [--axis-gutter none|left|right|both] [--gridlines] [--ruler] [--time-axis] [--side-by-side] [--labels=false]
+//	peaks --max-scale 100MB
+//	peaks --accessible [--announce 10s]
+//	peaks --output json|csv
+//	peaks [--config FILE]
+//	peaks --charts eth0,wlan0
+//	peaks --alert-down 50MB/s [--alert-up RATE] [--alert-log FILE] [--alert-notify] [--alert-cmd CMD]
+//	peaks --header [--header-format "{host} · {iface} {link} · {ip} · {time}"]
+//	peaks import [--history DIR] [--host NAME] FILE...
+//	peaks glance [--history DIR] [--window 5m] [--width N] [--height 8]
+//	peaks compare [--history DIR] [--from T] [--to T] [--direction down|up|both] [--stack]
+//	peaks agent [--listen 127.0.0.1:7070] [--source net|disk|cpu]
+//	peaks connect HOST[:PORT] [flags]
+//	peaks dashboard [--columns N] [NAME=]HOST...
+//	peaks report [--markdown] [--history DIR] [--ledger FILE] [--host NAME] [--from T] [--to T]
+//	peaks usage [--period hourly|daily|monthly] [--count N] [--history DIR] [--host NAME]
 //
 // Controls:
 //
 //	q/Ctrl+C: Quit
-//	p/Space:  Pause/Resume
+//	p/Space:  Pause/Resume the display (sampling continues and is backfilled)
+//	P:        Stop/Resume sampling (the stopped time is left as a gap)
+//	M/A:      Drop a labeled marker / list the markers
 //	r:        Reset chart and statistics
 //	s:        Toggle statusbar
-//	m:        Toggle display mode (split/overlay)
+//	m:        Cycle display mode (split/overlay/side-by-side)
 //	l:        Cycle scaling mode (linear/logarithmic/square root)
-//	t:        Cycle time scale (1/3/5/10/15/30/60 minutes)
+//	i:        Toggle smoothing of the drawn chart (data is unchanged)
+//	b:        Show rates in bits or bytes per second
+//	x:        Run a speed test, marking it on the chart and popping up the result
+//	+/-:      Sample faster/slower (100ms … 2s)
+//	←/→:      Move a column cursor reading out its time and rates (Esc hides it)
+//	n/N:      Focus the next/previous interface (then all again)
+//	o/O:      Show/hide per-process rates beside the chart / change their sort
+//	C:        Show/hide per-connection rates beside the chart (O sorts them too)
+//	B:        Show/hide the bandwidth share by protocol and service beside the chart
+//	u/U:      Show/hide the usage tab of --history totals / cycle daily, monthly, hourly
+//	w:        Show/hide the Wi-Fi network, signal strength and link rate beside the chart
+//	g:        Show/hide the previous session behind the chart (needs --history)
+//	f:        Lock the scale at its current maximum, or unlock it
+//	a:        Show/hide average, median and 95th percentile rates beside the chart
+//	c:        Save a screenshot of the current frame (ANSI and plain text)
+//	e/E:      Export the focused chart's samples to a CSV/JSON Lines file
+//	y:        Copy a one-line stats summary to the clipboard (OSC52)
+//	t:        Cycle time scale (1/3/5/10/15/30/60 minutes, 3/6/12/24 hours)
+//	W:        Save the display, scaling, time scale and rate to the --config file
+//	Tab:      Focus the next chart of --charts (the cursor reads out the focused one)
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
 	"runtime/debug"
+	"slices"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -40,8 +93,12 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mistakenelf/teacup/statusbar"
+	"github.com/muesli/termenv"
 
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/alerts"
 	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/config"
 	"github.com/marcodenic/peaks/internal/monitor"
 	"github.com/marcodenic/peaks/internal/ui"
 )
@@ -60,8 +117,17 @@ var version = getVersion()
 const (
 	// Update frequency for bandwidth monitoring
 	updateInterval = 500 * time.Millisecond
+	// Samples buffered while paused, a day at the default rate; older ones
+	// are dropped
+	maxPausedSamples = 24 * 60 * 60 * 2
+	// Tick interval while the terminal is unfocused, short enough for the
+	// collectors to still trust the rate (monitor.MaxSampleInterval)
+	blurredTickInterval = 2 * time.Second
 	// Default data points for initial chart creation
 	defaultDataPoints = 200
+	// Longest time scale drawn from raw samples; the longer ones come from
+	// the chart's downsampled history
+	rawHistorySpan = time.Hour
 )
 
 // calculateMaxDataPoints calculates the optimal number of data points
@@ -76,19 +142,166 @@ func calculateMaxDataPoints(terminalWidth int) int {
 	return int(float64(terminalWidth) * 1.5)
 }
 
-// tickMsg represents a tick message for updating the display
-type tickMsg time.Time
+// pausedSample is a sample taken while paused, replayed into the chart on resume
+type pausedSample struct {
+	upload, download uint64
+	gap              bool
+}
+
+// pausedBuffer holds the samples taken while paused, in a ring of up to
+// maxPausedSamples: once full, each sample overwrites the oldest
+type pausedBuffer struct {
+	samples []pausedSample
+	head    int // Oldest sample once the ring is full
+}
+
+// add buffers a sample, dropping the oldest beyond maxPausedSamples
+func (b *pausedBuffer) add(sample pausedSample) {
+	if len(b.samples) < maxPausedSamples {
+		b.samples = append(b.samples, sample)
+		return
+	}
+	b.samples[b.head] = sample
+	b.head = (b.head + 1) % len(b.samples)
+}
+
+// len returns how many samples are buffered
+func (b *pausedBuffer) len() int {
+	return len(b.samples)
+}
+
+// replay draws the buffered samples on a chart, oldest first, and empties
+// the buffer
+func (b *pausedBuffer) replay(ch *chart.BrailleChart) {
+	for _, sample := range b.samples[b.head:] {
+		addSample(ch, sample)
+	}
+	for _, sample := range b.samples[:b.head] {
+		addSample(ch, sample)
+	}
+	b.reset()
+}
 
-// tickCmd creates a command that sends tick messages at regular intervals
-func tickCmd() tea.Cmd {
-	return tea.Tick(updateInterval, func(t time.Time) tea.Msg {
-		return tickMsg(t)
+// reset empties the buffer, keeping its storage
+func (b *pausedBuffer) reset() {
+	b.samples, b.head = b.samples[:0], 0
+}
+
+// refreshRates are the sampling intervals +/- step through
+var refreshRates = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	updateInterval,
+	time.Second,
+	2 * time.Second,
+}
+
+// tickMsg represents a tick message for updating the display.
+// The generation lets the model discard ticks from a superseded tick chain.
+type tickMsg struct {
+	time       time.Time
+	generation int
+}
+
+// tickCmd creates a command that sends a tick message after the given interval
+func tickCmd(interval time.Duration, generation int) tea.Cmd {
+	return tea.Tick(interval, func(t time.Time) tea.Msg {
+		return tickMsg{time: t, generation: generation}
 	})
 }
 
+// Terminals smaller than this get the tiny layout: no title/help row, a
+// single-segment statusbar and a chart allowed below chart.MinChartHeight
+const (
+	tinyLayoutRows    = 8
+	tinyLayoutColumns = 40
+)
+
+// dnsResultMsg carries the outcome of a DNS probe
+type dnsResultMsg struct {
+	latency time.Duration
+	err     error
+}
+
+// dnsTickMsg triggers the next DNS probe
+type dnsTickMsg struct{}
+
+// speedTestResultMsg carries the outcome of a speed test
+type speedTestResultMsg struct {
+	result monitor.SpeedTestResult
+	err    error
+}
+
+// speedTestTickMsg triggers a scheduled speed test
+type speedTestTickMsg struct{}
+
+// iperfStartMsg starts the iperf3 run once the chart has a baseline
+type iperfStartMsg struct{}
+
+// iperfResultMsg carries the outcome of an iperf3 run
+type iperfResultMsg struct {
+	result monitor.IperfResult
+	err    error
+}
+
+// iperfStartDelay leaves a few samples of normal traffic before the test
+const iperfStartDelay = 2 * time.Second
+
+// conntrackTickMsg triggers a conntrack table check
+type conntrackTickMsg struct{}
+
+// conntrackCheckInterval is how often the conntrack table size is sampled
+const conntrackCheckInterval = 5 * time.Second
+
+// projectionTickMsg triggers a billing cycle projection update
+type projectionTickMsg struct{}
+
+// projectionInterval is how often the billing cycle projection is updated
+const projectionInterval = time.Minute
+
+// interfaceTotalsTickMsg triggers a rewrite of the --interface-totals export
+type interfaceTotalsTickMsg struct{}
+
+// historyRecordedMsg carries the outcome of recording a sample to the history
+type historyRecordedMsg struct {
+	err error
+}
+
+// recordHistory appends a sample to the history off the Update goroutine, so
+// a slow disk doesn't hold up the chart
+func recordHistory(history *accounting.History, at time.Time, upload, download uint64) tea.Cmd {
+	return func() tea.Msg {
+		return historyRecordedMsg{err: history.Record(at, upload, download)}
+	}
+}
+
+// ghostLoadedMsg carries the previous session read for the ghost overlay
+type ghostLoadedMsg struct {
+	samples []accounting.Sample
+	err     error
+}
+
+// reportErrorMsg reports a failure to mail a scheduled usage report
+type reportErrorMsg struct {
+	err error
+}
+
+// routeTickMsg triggers a default route check
+type routeTickMsg struct{}
+
+// routeCheckInterval is how often the default route is polled
+const routeCheckInterval = 5 * time.Second
+
+// frameCache holds the last rendered view so unchanged frames are not rebuilt.
+// It is shared by pointer because Bubble Tea passes the model by value.
+type frameCache struct {
+	view  string
+	dirty bool
+}
+
 // model represents the application state for the Bubble Tea framework
 type model struct {
-	monitor   *monitor.BandwidthMonitor
+	collector monitor.Collector
 	chart     *chart.BrailleChart
 	ui        *ui.Components
 	keys      ui.KeyMap
@@ -98,26 +311,544 @@ type model struct {
 	ready     bool
 	quitting  bool
 	paused    bool
+	// While paused, sampling continues into pausedSamples and the frame
+	// stays frozen as of pausedAt; resuming backfills the chart
+	pausedAt      time.Time
+	pausedSamples pausedBuffer
+	// While stopped, ticks take no samples at all; resuming draws a gap
+	stopped   bool
+	stoppedAt time.Time
+	// Labeled markers dropped with M, the label being typed while
+	// annotating, and whether A lists them beside the chart
+	markers     []accounting.Annotation
+	annotating  bool
+	markerInput []rune
+	showMarkers bool
 	// Optimization: cache current rates to avoid repeated calculations
 	currentUpload   uint64
 	currentDownload uint64
 	// UI state
 	showStatusbar bool
-	displayMode   string // "split" or "overlay"
+	displayMode   string // "split", "overlay" or "side-by-side"
+	// Overlay rendering of the chart's data beside it in side-by-side mode,
+	// or the --remote source's chart
+	compare *chart.BrailleChart
+	// Second source charted on the right with --remote
+	remote         monitor.Collector
+	remoteChart    *chart.BrailleChart
+	remoteErr      error
+	remoteUpload   uint64
+	remoteDownload uint64
+	formatRemote   func(uint64) string
+	remoteSource   string
+	pausedRemote   pausedBuffer
+	// Collector source name, as given to --source, and the file --input
+	// follows instead of stdin
+	source    string
+	inputPath string
+	// Crash recovery snapshot file, and a crashed session offered for restoring
+	sessionPath  string
+	restoreOffer *sessionSnapshot
+	// Rolling average and percentile rates shown beside the chart with a
+	showStats bool
+	// Packet capture on --capture-iface shared by every breakdown that
+	// needs one, started by the first
+	packets *monitor.SharedCapture
+	// Per-process list shown beside the chart with o, captured once first
+	// shown
+	showProcesses bool
+	processes     *monitor.ProcessStats
+	processRates  []monitor.ProcessRates
+	processErr    error
+	processSort   monitor.ProcessSort
+	// Per-connection list shown beside the chart with C, sorted like the
+	// process list
+	showConnections bool
+	connections     *monitor.ConnectionStats
+	connectionRates []monitor.ConnectionRates
+	connectionErr   error
+	// Bandwidth share of each protocol and service shown beside the chart
+	// with b
+	showProtocols bool
+	protocols     *monitor.ProtocolStats
+	protocolRates []monitor.ClassRates
+	protocolErr   error
+	// Top remote hosts by traffic shown beside the chart with --capture,
+	// named by reverse DNS unless numericHosts (d)
+	talkers      *monitor.HostStats
+	talkerRates  []monitor.HostRates
+	resolver     *monitor.Resolver
+	numericHosts bool
+	// --pane plugins shown beside the chart, refreshed every paneInterval
+	panes        []pane
+	paneInterval time.Duration
+	// Formatters for the active collector's units
+	formatRate  func(uint64) string
+	formatTotal func(uint64) string
+	// Rates in bits rather than bytes per second (b, --units), and the
+	// matching short formatter of the sidebar panels
+	bitUnits        bool
+	formatRateShort func(uint64) string
+	// Render scheduling: redraw only when data, size or focus change
+	frame          *frameCache
+	focused        bool
+	tickGeneration int
+	// Environment note shown next to the title, e.g. under WSL2
+	sourceNote string
+	// Recorded history played back by --replay, nil when sampling live
+	replay *replayCollector
+	// Preferences as loaded or last saved, and the file W saves them to
+	config     config.Config
+	configPath string
+	// Threshold alerts (nil without thresholds), the statusbar flash
+	// countdown and the last failed alert action
+	alerts      *alerts.Watcher
+	alertLog    string
+	alertCmd    string
+	alertNotify bool
+	alertFlash  int
+	alertErr    error
+	// Default route watcher (network source only) and the last change seen
+	routes    *monitor.RouteWatcher
+	routeNote string
+	// Interface the chart and stats are narrowed to with n/N ("" for all)
+	ifaceFocus string
+	// Per-interface charts stacked under the main chart, and the chart
+	// focused with tab (0 for the main chart)
+	stack      []*stackedChart
+	chartFocus int
+	// Where screenshots are saved, and the result of the last screenshot
+	// or clipboard copy
+	screenshotDir string
+	keyNote       string
+	// Optional DNS latency probe and its latest result
+	dns         *monitor.DNSProbe
+	dnsInterval time.Duration
+	dnsAlert    time.Duration
+	dnsLatency  time.Duration
+	dnsErr      error
+	dnsProbed   bool
+	// Speed tests, run on a schedule or keypress and marked on the chart
+	speedTester    *monitor.SpeedTester
+	speedTestEvery time.Duration
+	speedTestLog   string
+	speedTesting   bool
+	speedTestNote  string
+	// Speed test started with x, whose result pops up over the chart
+	speedTestPopup  bool
+	showSpeedTest   bool
+	speedTestResult speedTestResultMsg
+	// iperf3 client run, annotated on the chart at its start and end
+	iperf     *monitor.IperfRunner
+	iperfNote string
+	// Connection tracking table usage, sampled on Linux routers
+	conntrackDir   string
+	conntrack      monitor.ConntrackStatus
+	conntrackAlert float64
+	conntrackAlarm bool
+	// Mark chart columns where packets were dropped
+	markDrops bool
+	// Wi-Fi panel (w) and the latest status, read in the background while
+	// it is shown
+	showWifi    bool
+	wifi        monitor.WifiStatus
+	wifiErr     error
+	wifiReading bool
+	// Per-DSCP-class rates from packet capture
+	dscp      *monitor.DSCPStats
+	dscpRates []monitor.ClassRates
+	// Daily traffic totals persisted across runs
+	ledger    *accounting.Ledger
+	ledgerErr error
+	// Raw sample history, compacted in the background
+	history    *accounting.History
+	historyErr error
+	// Header bar layout ("" to hide it) and the values it shows
+	headerFormat    string
+	header          map[string]string
+	headerRefreshed time.Time
+	// Draw a row of time ticks under the chart
+	ruler bool
+	// Label the time before now along the bottom of the chart
+	timeAxis bool
+	// Source of the time shown on screen: the wall clock, or the virtual
+	// clock of a --record session
+	clock        func() time.Time
+	virtualClock *virtualClock
+	// Empty-state screen: whether any traffic has been seen, the last
+	// collector error and the monitored interfaces' link states
+	sawData      bool
+	sampleErr    error
+	links        []monitor.LinkStatus
+	linksChecked time.Time
+	// When this session started, and the previous session drawn behind the chart
+	startedAt    time.Time
+	ghostNote    string
+	ghostLoading bool
+	// Last failure to mail a scheduled report
+	reportErr error
+	// Usage tab shown in place of the chart with u: hourly, daily or monthly
+	// totals rolled up from the history
+	showUsage   bool
+	usagePeriod string
+	usage       []accounting.UsageTotal
+	usageErr    error
+	// Billing cycle usage and its projected total
+	cycleStart       int
+	projectionMethod string
+	projection       accounting.Projection
+	// Data cap per billing cycle or day, and that period's projected usage
+	quota       uint64
+	quotaPeriod string
+	quotaUsage  accounting.Projection
+	// Sampling interval chosen with +/-; zero means updateInterval
+	interval time.Duration
+	// Per-interface totals export, rewritten every interfaceTotalsInterval
+	interfaceTotalsPath string
+	interfaceTotalsErr  error
+}
+
+// options holds command-line configuration shared by all run modes
+type options struct {
+	source    string // collector source: net, disk, cpu or winhost
+	pprofAddr string // address for the pprof/debug endpoint, empty to disable
+	glyphs    string // glyph set name: auto, braille, block or ascii
+	theme     string // chart color theme from --theme, empty for the config's
+	color     string // color depth: auto, truecolor, 256, 16 or none
+	// DNS latency probe: host to resolve (empty to disable), how often, and
+	// the latency above which it is flagged
+	dnsHost     string
+	dnsInterval time.Duration
+	dnsAlert    time.Duration
+	// Speed tests: endpoint, schedule (0 for on keypress only) and JSON Lines log
+	speedTestURL   string
+	speedTestEvery time.Duration
+	speedTestLog   string
+	// iperf3 server to drive a client test against, and extra iperf3 arguments
+	iperfHost string
+	iperfArgs string
+	// Conntrack table usage (percent) above which the statusbar alerts
+	conntrackAlert float64
+	// Mark chart columns where interfaces dropped packets
+	markDrops bool
+	// Start with the Wi-Fi panel shown
+	wifi bool
+	// Interface groups summed into named series; the first one is charted
+	groups []monitor.InterfaceGroup
+	// Packet capture: DSCP class breakdown on an interface (all if empty)
+	dscp         bool
+	captureIface string
+	// Packet capture: top-talkers panel of remote hosts, by address only
+	// with numeric
+	capture bool
+	numeric bool
+	// Daily totals file, closed out at local midnight ("auto" for the default path)
+	ledgerPath string
+	// Sample history directory and how long each tier is kept
+	historyDir string
+	retention  accounting.Retention
+	// Scheduled email reports of the ledger: daily or weekly (empty for none)
+	report     string
+	reportHTML bool
+	smtp       accounting.SMTPConfig
+	// Billing cycle start day of month and how its usage is projected
+	cycleStart int
+	projection string
+	// Data cap in bytes (zero for none) and whether it is monthly or daily
+	quota       uint64
+	quotaPeriod string
+	// CSV or JSON file the per-interface totals are exported to
+	interfaceTotals string
+	// Directory the screenshot key saves frames to
+	screenshotDir string
+	// Windows of the rolling statistics shown with a
+	statsWindows []time.Duration
+	// Header bar layout, empty without --header
+	headerFormat string
+	// Start with the chart smoothed
+	smooth bool
+	// Rate units, bytes or bits per second, and byte prefixes
+	units    string
+	prefixes string
+	// Start in the side-by-side split/overlay layout
+	sideBySide bool
+	// Draw a time tick ruler under the chart
+	ruler bool
+	// Label the time before now under the chart, e.g. "-5m … now"
+	timeAxis bool
+	// Label the download and upload halves of the split chart
+	labels bool
+	// Reproducible session for recordings: virtual clock, seeded demo traffic
+	record bool
+	seed   uint64
+	// Source charted beside the local one (e.g. winhost or prometheus)
+	remote string
+	// Interfaces charted under the main chart, one chart each
+	stack []string
+	// Snapshot file for crash recovery ("auto" for the user cache
+	// directory), empty to disable
+	sessionPath string
+	// Plugin panes shown beside the chart and how often they are refreshed
+	panes        []paneSpec
+	paneInterval time.Duration
+	// Prometheus server and PromQL queries charted by the prometheus source
+	promURL     string
+	promQuery   string
+	promQueryUp string
+	// File followed by the stdin source instead of stdin, from --input
+	inputPath string
+	// SNMP agent, community and interface index charted by the snmp source
+	snmpTarget    string
+	snmpCommunity string
+	snmpIfIndex   int
+	// Sides of the chart labelled with its scale
+	gutter chart.GutterPlacement
+	// Faint gridlines at round scale values, labelled in the gutter
+	gridlines bool
+	// Scale maximum the chart is locked at (0 to follow the data)
+	maxScale uint64
+	// Range of the --history to play back instead of sampling, e.g. 2h
+	replay string
+	// Preferences loaded from the config file, saved back with W
+	config     config.Config
+	configPath string
+	// Rate thresholds that raise an alert, and what an alert does besides
+	// flashing the statusbar
+	alertThresholds alerts.Thresholds
+	alertLog        string
+	alertCmd        string
+	alertNotify     bool
+}
+
+// autoPath selects the default location for --ledger and --history
+const autoPath = "auto"
+
+// openHistory opens the sample history selected by the options, or returns
+// nil, and starts its background compaction
+func openHistory(opts options) (*accounting.History, func(), error) {
+	if opts.historyDir == "" {
+		return nil, func() {}, nil
+	}
+	dir, err := resolveHistoryDir(opts.historyDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	history, err := accounting.OpenHistory(dir, opts.retention, updateInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	stop := history.StartCompaction(accounting.DefaultCompactionInterval, nil)
+	return history, func() {
+		stop()
+		history.Close()
+	}, nil
+}
+
+// newLedger creates the daily accounting ledger selected by the options, or nil
+func newLedger(opts options) (*accounting.Ledger, error) {
+	path := opts.ledgerPath
+	if path == "" {
+		return nil, nil
+	}
+	if path == autoPath {
+		var err error
+		if path, err = accounting.DefaultStorePath(); err != nil {
+			return nil, err
+		}
+	}
+	return accounting.NewLedger(accounting.NewFileStore(path)), nil
+}
+
+// smtpPasswordEnv names the environment variable holding the SMTP password,
+// so it stays out of the process list and shell history
+const smtpPasswordEnv = "PEAKS_SMTP_PASSWORD"
+
+// startReports mails the ledger's totals on the schedule selected by the
+// options until the returned stop function is called
+func startReports(opts options, onError func(error)) (func(), error) {
+	if opts.report == "" {
+		return func() {}, nil
+	}
+	path := opts.ledgerPath
+	if path == autoPath {
+		var err error
+		if path, err = accounting.DefaultStorePath(); err != nil {
+			return nil, err
+		}
+	}
+	reporter, err := accounting.NewReporter(accounting.NewFileStore(path), opts.smtp, opts.report, opts.reportHTML, ui.FormatBytes)
+	if err != nil {
+		return nil, err
+	}
+	return reporter.Start(onError), nil
+}
+
+// groupFlag collects repeated --group NAME=IFACE[,IFACE...] flags
+type groupFlag []monitor.InterfaceGroup
+
+// String implements flag.Value
+func (g *groupFlag) String() string {
+	names := make([]string, len(*g))
+	for i, group := range *g {
+		names[i] = group.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// Set implements flag.Value
+func (g *groupFlag) Set(value string) error {
+	group, err := monitor.ParseInterfaceGroup(value)
+	if err != nil {
+		return err
+	}
+	*g = append(*g, group)
+	return nil
+}
+
+// newCollector creates the collector selected by the options
+func newCollector(opts options) (monitor.Collector, error) {
+	switch opts.source {
+	case monitor.SourceDemo:
+		return monitor.NewDemoCollector(opts.seed), nil
+	case monitor.SourcePrometheus:
+		return monitor.NewPrometheusCollector(opts.promURL, opts.promQuery, opts.promQueryUp, monitor.DefaultPrometheusInterval)
+	case monitor.SourceStdin:
+		if opts.inputPath != "" {
+			return monitor.NewFileCollector(opts.inputPath)
+		}
+	case monitor.SourceSNMP:
+		src, err := monitor.NewSNMPSource(opts.snmpTarget, opts.snmpCommunity, opts.snmpIfIndex)
+		if err != nil {
+			return nil, err
+		}
+		return monitor.NewBandwidthMonitorWithSource(src), nil
+	}
+	collector, err := monitor.NewCollector(opts.source)
+	if err != nil {
+		return nil, err
+	}
+	bm, ok := collector.(*monitor.BandwidthMonitor)
+	if len(opts.groups) > 0 {
+		if !ok {
+			return nil, fmt.Errorf("--group only applies to network sources")
+		}
+		bm.SetInterfaceGroups(opts.groups)
+	} else if interfaces := opts.config.Interfaces; ok && len(interfaces) > 0 {
+		bm.SetInterfaceFilter(func(name string) bool { return slices.Contains(interfaces, name) })
+	}
+	return collector, nil
+}
+
+// closeCollector releases what a collector holds open, such as an SSH session
+func closeCollector(collector monitor.Collector) {
+	if closer, ok := collector.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+// glyphSet resolves the configured glyph set, detecting terminal support for "auto".
+// The name has already been validated by main.
+func (o options) glyphSet() chart.GlyphSet {
+	set, auto, _ := chart.ParseGlyphSet(o.glyphs)
+	if auto {
+		return chart.DetectGlyphSet(os.Getenv, runtime.GOOS)
+	}
+	return set
+}
+
+// applyColorProfile forces the color depth lipgloss renders with, unless it is
+// "auto" and the depth detected from the terminal (and NO_COLOR) is kept.
+// The name has already been validated by main.
+func (o options) applyColorProfile() {
+	if profile, auto, err := chart.ParseColorProfile(o.color); err == nil && !auto {
+		lipgloss.SetColorProfile(profile)
+	}
+}
+
+// monochrome reports whether colors are off, by --color none or NO_COLOR,
+// so charts need other ways to tell upload and download apart
+func (o options) monochrome() bool {
+	profile, auto, _ := chart.ParseColorProfile(o.color)
+	if auto {
+		return termenv.EnvNoColor()
+	}
+	return profile == termenv.Ascii
+}
+
+// chartTheme resolves the chart colors: --theme, else the config file's
+// theme, else the default. The names have already been validated by main.
+func (o options) chartTheme() chart.Theme {
+	name := o.theme
+	if name == "" {
+		name = o.config.Theme
+	}
+	if theme, err := chart.ParseTheme(name); err == nil {
+		return theme
+	}
+	return chart.Themes[0]
+}
+
+// sourceFormatters returns the rate and total formatters for a collector source
+func sourceFormatters(source string) (rate, total func(uint64) string) {
+	switch source {
+	case monitor.SourceCPU:
+		return ui.FormatCPU, ui.FormatCPUTime
+	case monitor.SourceStdin:
+		return ui.FormatValue, ui.FormatValue
+	}
+	return ui.FormatBandwidth, ui.FormatBytes
+}
+
+// sourceNote explains what the active source measures where that is not obvious.
+// Under WSL2 the Linux interfaces only carry the VM's own traffic.
+func sourceNote(source string) string {
+	switch {
+	case source == monitor.SourceWindowsHost:
+		return "Windows host adapters"
+	case source == monitor.SourceDemo:
+		return "demo: synthetic traffic"
+	case source == monitor.SourceStdin:
+		return "values from stdin"
+	case source == monitor.SourcePrometheus:
+		return "prometheus query"
+	case source == monitor.SourceSNMP:
+		return "snmp"
+	case monitor.IsSSHSource(source):
+		return strings.TrimPrefix(source, monitor.SourceSSHPrefix) + " over ssh"
+	case monitor.IsAgentSource(source):
+		return "peaks agent on " + strings.TrimPrefix(source, monitor.SourceAgentPrefix)
+	case (source == monitor.SourceNetwork || source == "") && monitor.IsWSL():
+		return "WSL2 VM only (--source winhost for host)"
+	}
+	return ""
 }
 
 // initialModel creates and initializes the application model
-func initialModel() model {
+func initialModel(opts options, collector monitor.Collector) model {
 	chart := chart.NewBrailleChart(defaultDataPoints)
 	// Always store 60 minutes of data to support any time scale
-	maxDataPoints := 60 * 60 * 2 // 60 minutes * 60 seconds * 2 points per second  
-	chart.SetMaxPoints(maxDataPoints)
+	chart.SetMaxPoints(maxDataPoints(updateInterval))
+	chart.SetGlyphSet(opts.glyphSet())
+	chart.SetTheme(opts.chartTheme())
+	chart.SetMonochrome(opts.monochrome())
+	chart.SetSmoothing(opts.smooth)
+	chart.SetDecimalUnits(opts.prefixes == ui.PrefixesSI)
+	chart.SetGutter(opts.gutter)
+	chart.SetGridlines(opts.gridlines)
+	chart.SetFixedMaxValue(opts.maxScale)
+	switch opts.source {
+	case monitor.SourceCPU:
+		chart.SetLabelFormatter(ui.FormatCPU)
+	case monitor.SourceStdin:
+		chart.SetLabelFormatter(ui.FormatValue)
+	}
+	chart.SetDirectionLabels(opts.labels)
 	
 	m := model{
-		monitor: monitor.NewBandwidthMonitor(),
-		chart:   chart,
-		ui:      ui.NewComponents(),
-		keys:    ui.DefaultKeyMap(),
+		collector: collector,
+		chart:     chart,
+		ui:        ui.NewComponents(),
+		keys:      ui.DefaultKeyMap(),
 	}
 
 	// Create statusbar with 4 sections - no background colors to avoid conflicts with styled text
@@ -142,12 +873,737 @@ func initialModel() model {
 
 	m.showStatusbar = true
 	m.displayMode = "split" // Default to split axis mode
+	if opts.sideBySide {
+		m.setDisplayMode("side-by-side")
+	}
+	m.frame = &frameCache{dirty: true}
+	m.focused = true
+	m.clock = time.Now
+	if opts.record {
+		m.virtualClock = &virtualClock{now: recordEpoch}
+		m.clock = m.virtualClock.Now
+		m.chart.SetClock(m.clock)
+		m.ui.GetStats().SetClock(m.clock)
+	}
+	m.startedAt = m.clock()
+	m.source = opts.source
+	m.formatRate, m.formatTotal = sourceFormatters(opts.source)
+	m.formatRateShort = ui.FormatBandwidthShort
+	m.sourceNote = sourceNote(opts.source)
+	if opts.source == monitor.SourceStdin && opts.inputPath != "" {
+		m.inputPath = opts.inputPath
+		m.sourceNote = "values from " + filepath.Base(opts.inputPath)
+	}
+	if opts.source == monitor.SourceNetwork || opts.source == "" {
+		m.routes = monitor.NewRouteWatcher()
+		if _, err := monitor.ReadConntrack(monitor.ConntrackDir); err == nil {
+			m.conntrackDir = monitor.ConntrackDir
+			m.conntrackAlert = opts.conntrackAlert
+		}
+	}
+	m.markDrops = opts.markDrops
+	m.showWifi, m.wifiReading = opts.wifi, opts.wifi
+	m.speedTester = monitor.NewSpeedTester(opts.speedTestURL)
+	m.speedTestEvery = opts.speedTestEvery
+	m.cycleStart, m.projectionMethod = opts.cycleStart, opts.projection
+	m.quota, m.quotaPeriod = opts.quota, opts.quotaPeriod
+	m.interfaceTotalsPath = opts.interfaceTotals
+	m.ruler, m.timeAxis = opts.ruler, opts.timeAxis
+	if m.headerFormat = opts.headerFormat; m.headerFormat != "" {
+		m.refreshHeader(m.clock())
+	}
+	m.screenshotDir = opts.screenshotDir
+	if opts.statsWindows != nil {
+		m.ui.GetStats().SetWindows(opts.statsWindows)
+	}
+	m.speedTestLog = opts.speedTestLog
+	if opts.iperfHost != "" {
+		m.iperf = monitor.NewIperfRunner(opts.iperfHost, strings.Fields(opts.iperfArgs)...)
+		m.iperfNote = "iperf: starting…"
+	}
+	for _, spec := range opts.panes {
+		m.panes = append(m.panes, pane{paneSpec: spec})
+	}
+	m.paneInterval = opts.paneInterval
+	m.packets = monitor.NewSharedCapture(opts.captureIface)
+	m.numericHosts = opts.numeric
+	if opts.units == ui.UnitsBits {
+		m.setBitUnits(true)
+	}
+	if opts.dnsHost != "" {
+		m.dns = monitor.NewDNSProbe(opts.dnsHost)
+		m.dnsInterval = opts.dnsInterval
+		m.dnsAlert = opts.dnsAlert
+	}
+	m.configPath = opts.configPath
+	m.applyConfig(opts.config, opts.sideBySide)
+	if opts.alertThresholds != (alerts.Thresholds{}) {
+		m.alerts = alerts.NewWatcher(opts.alertThresholds)
+		m.alertLog, m.alertCmd, m.alertNotify = opts.alertLog, opts.alertCmd, opts.alertNotify
+	}
+	if len(opts.stack) > 0 {
+		m.attachStack(opts.stack)
+	}
 	return m
 }
 
 // Init initializes the application
 func (m model) Init() tea.Cmd {
-	return tickCmd()
+	cmds := []tea.Cmd{tickCmd(updateInterval, m.tickGeneration)}
+	if m.routes != nil {
+		cmds = append(cmds, routeTickCmd())
+	}
+	if m.dns != nil {
+		cmds = append(cmds, dnsProbeCmd(m.dns))
+	}
+	if m.wifiReading {
+		cmds = append(cmds, wifiCmd())
+	}
+	if m.speedTestEvery > 0 {
+		cmds = append(cmds, speedTestTickCmd(m.speedTestEvery))
+	}
+	if m.conntrackDir != "" {
+		cmds = append(cmds, func() tea.Msg { return conntrackTickMsg{} })
+	}
+	if m.ledger != nil {
+		cmds = append(cmds, func() tea.Msg { return projectionTickMsg{} })
+	}
+	if m.interfaceTotalsPath != "" {
+		cmds = append(cmds, tea.Tick(interfaceTotalsInterval, func(time.Time) tea.Msg { return interfaceTotalsTickMsg{} }))
+	}
+	if m.headerFormat != "" {
+		cmds = append(cmds, headerTickCmd())
+	}
+	if m.iperf != nil {
+		cmds = append(cmds, tea.Tick(iperfStartDelay, func(time.Time) tea.Msg { return iperfStartMsg{} }))
+	}
+	for i, p := range m.panes {
+		cmds = append(cmds, runPaneCmd(i, p.paneSpec, m.paneInterval))
+	}
+	if m.sessionPath != "" {
+		cmds = append(cmds, sessionSnapshotTickCmd())
+	}
+	return tea.Batch(cmds...)
+}
+
+// startIperf marks the start of the iperf3 run and runs it in the background
+func (m *model) startIperf() tea.Cmd {
+	m.chart.AddMarker()
+	m.iperfNote = "iperf: testing " + m.iperf.Host() + "…"
+	m.updateStatusbar()
+
+	runner := m.iperf
+	return func() tea.Msg {
+		result, err := runner.Run()
+		return iperfResultMsg{result: result, err: err}
+	}
+}
+
+// finishIperf marks the end of the iperf3 run and summarizes its throughput
+func (m *model) finishIperf(msg iperfResultMsg) {
+	m.chart.AddMarker()
+	if msg.err != nil {
+		m.iperfNote = "iperf: failed"
+		m.updateStatusbar()
+		return
+	}
+
+	// The receiver's rate is what actually made it across the link
+	direction := "↑"
+	if msg.result.Reverse {
+		direction = "↓"
+	}
+	m.iperfNote = fmt.Sprintf("iperf: %s%s in %s", direction,
+		m.formatRate(msg.result.Received), ui.FormatDuration(msg.result.Duration))
+	m.updateStatusbar()
+}
+
+// speedTestTickCmd schedules the next speed test
+func speedTestTickCmd(every time.Duration) tea.Cmd {
+	return tea.Tick(every, func(time.Time) tea.Msg {
+		return speedTestTickMsg{}
+	})
+}
+
+// startSpeedTest runs a speed test in the background unless one is running,
+// marking its start on the chart
+func (m *model) startSpeedTest() tea.Cmd {
+	if m.speedTesting {
+		return nil
+	}
+	m.speedTesting = true
+	m.chart.AddMarker()
+	m.speedTestNote = "Speed: testing…"
+	m.updateStatusbar()
+
+	tester := m.speedTester
+	return func() tea.Msg {
+		result, err := tester.Run()
+		return speedTestResultMsg{result: result, err: err}
+	}
+}
+
+// finishSpeedTest records a speed test result: its end is marked on the
+// chart, it is noted in the statusbar, popped up if started with x and
+// appended to the log file if one is configured
+func (m *model) finishSpeedTest(msg speedTestResultMsg) {
+	m.speedTesting = false
+	m.chart.AddMarker()
+	if m.speedTestPopup {
+		m.speedTestPopup = false
+		m.showSpeedTest = true
+		m.speedTestResult = msg
+	}
+	if msg.err != nil {
+		m.speedTestNote = "Speed: failed"
+		m.updateStatusbar()
+		return
+	}
+
+	m.speedTestNote = fmt.Sprintf("Speed: ↓%s ↑%s @%s",
+		m.formatRate(msg.result.Download),
+		m.formatRate(msg.result.Upload),
+		msg.result.Time.Format("15:04"))
+	if m.speedTestLog != "" {
+		if err := monitor.AppendSpeedTestLog(m.speedTestLog, msg.result); err != nil {
+			m.speedTestNote += " (log failed)"
+		}
+	}
+	m.updateStatusbar()
+}
+
+// dnsProbeCmd runs one DNS probe in the background
+func dnsProbeCmd(probe *monitor.DNSProbe) tea.Cmd {
+	return func() tea.Msg {
+		latency, err := probe.Probe()
+		return dnsResultMsg{latency: latency, err: err}
+	}
+}
+
+// dnsStatus formats the latest DNS probe result for the statusbar,
+// highlighting failures and latencies above the alert threshold
+func (m model) dnsStatus() string {
+	if m.dns == nil || !m.dnsProbed {
+		return ""
+	}
+	alertStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+		Bold(true)
+
+	if m.dnsErr != nil {
+		return alertStyle.Render("DNS: fail")
+	}
+	status := "DNS: " + m.dnsLatency.Round(time.Millisecond).String()
+	if m.dnsAlert > 0 && m.dnsLatency > m.dnsAlert {
+		return alertStyle.Render(status + "!")
+	}
+	return status
+}
+
+// checkConntrack samples the conntrack table, marking the chart when usage
+// first crosses the alert threshold
+func (m *model) checkConntrack() {
+	status, err := monitor.ReadConntrack(m.conntrackDir)
+	if err != nil {
+		return
+	}
+	m.conntrack = status
+
+	alarm := m.conntrackAlert > 0 && status.Usage()*100 >= m.conntrackAlert
+	if alarm && !m.conntrackAlarm {
+		m.chart.AddMarker()
+	}
+	m.conntrackAlarm = alarm
+	m.updateStatusbar()
+}
+
+// conntrackStatus formats conntrack table usage for the statusbar,
+// highlighting a table approaching its limit
+func (m model) conntrackStatus() string {
+	if m.conntrackDir == "" || m.conntrack.Max == 0 {
+		return ""
+	}
+	status := fmt.Sprintf("CT: %d/%d", m.conntrack.Count, m.conntrack.Max)
+	if m.conntrackAlarm {
+		alertStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+			Bold(true)
+		return alertStyle.Render(fmt.Sprintf("%s (%.0f%%)!", status, m.conntrack.Usage()*100))
+	}
+	return status
+}
+
+// tunnelReporter is implemented by collectors that can tell tunnelled (VPN)
+// traffic apart from direct traffic
+type tunnelReporter interface {
+	TunnelRates() (monitor.BandwidthRates, bool)
+}
+
+// vpnStatus formats how much of the current traffic goes through a tunnel.
+// It is empty unless a tunnel interface is being monitored.
+func (m model) vpnStatus() string {
+	reporter, ok := m.collector.(tunnelReporter)
+	if !ok {
+		return ""
+	}
+	tunnel, active := reporter.TunnelRates()
+	if !active {
+		return ""
+	}
+
+	// Tunnelled bytes also cross the physical interface, so the traffic that
+	// actually left the machine is the total less the tunnel's own count
+	tunnelTotal := tunnel.Upload + tunnel.Download
+	total := m.currentUpload + m.currentDownload
+	share := 0.0
+	if total > tunnelTotal {
+		share = min(float64(tunnelTotal)/float64(total-tunnelTotal)*100, 100)
+	}
+	return fmt.Sprintf("VPN: ↓%s ↑%s (%.0f%%)",
+		m.formatRate(tunnel.Download), m.formatRate(tunnel.Upload), share)
+}
+
+// groupReporter is implemented by collectors that sum interfaces into groups
+type groupReporter interface {
+	GroupRates() []monitor.GroupRates
+}
+
+// groupStatus formats the current rates of each interface group
+func (m model) groupStatus() string {
+	reporter, ok := m.collector.(groupReporter)
+	if !ok {
+		return ""
+	}
+	groups := reporter.GroupRates()
+	parts := make([]string, len(groups))
+	for i, group := range groups {
+		parts[i] = fmt.Sprintf("%s ↓%s ↑%s", group.Name, m.formatRate(group.Download), m.formatRate(group.Upload))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// ledgerStatus formats today's traffic for the statusbar, or flags a failure
+// to record history
+func (m model) ledgerStatus() string {
+	if m.ledger == nil && m.historyErr == nil {
+		return ""
+	}
+	if m.ledgerErr != nil || m.historyErr != nil {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+			Bold(true).
+			Render("Today: history error")
+	}
+	if m.reportErr != nil {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+			Bold(true).
+			Render("Today: report failed")
+	}
+	today := m.ledger.Today()
+	return fmt.Sprintf("Today: ↓%s ↑%s", m.formatTotal(today.Download), m.formatTotal(today.Upload))
+}
+
+// projectionStatus formats the billing cycle's usage and projected total
+func (m model) projectionStatus() string {
+	if m.ledger == nil || m.projection.End.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("Cycle: %s, on track for %s",
+		m.formatTotal(m.projection.Used), m.formatTotal(m.projection.Projected))
+}
+
+// quotaBarWidth is the width of the quota progress bar in the statusbar
+const quotaBarWidth = 10
+
+// quotaStatus formats the --quota period's usage as a progress bar, with its
+// projected total, highlighted once that is on track to pass the cap
+func (m model) quotaStatus() string {
+	if m.ledger == nil || m.quota == 0 || m.quotaUsage.End.IsZero() {
+		return ""
+	}
+	filled := min(int(m.quotaUsage.Used*quotaBarWidth/m.quota), quotaBarWidth)
+	status := fmt.Sprintf("Quota: %s%s %d%% of %s, on track for %s",
+		strings.Repeat("█", filled), strings.Repeat("░", quotaBarWidth-filled),
+		m.quotaUsage.Used*100/m.quota, m.formatTotal(m.quota), m.formatTotal(m.quotaUsage.Projected))
+	if m.quotaUsage.Projected > m.quota {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+			Bold(true).
+			Render(status)
+	}
+	return status
+}
+
+// interfaceTotalsStatus flags a failure to write the interface totals export
+func (m model) interfaceTotalsStatus() string {
+	if m.interfaceTotalsErr == nil {
+		return ""
+	}
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"}).
+		Bold(true).
+		Render("Totals: export failed")
+}
+
+// maxDSCPClasses bounds how many traffic classes the statusbar lists
+const maxDSCPClasses = 3
+
+// dscpStatus formats the busiest DSCP classes for the statusbar
+func (m model) dscpStatus() string {
+	if m.dscp == nil {
+		return ""
+	}
+	if len(m.dscpRates) == 0 {
+		return "DSCP: idle"
+	}
+	parts := make([]string, 0, maxDSCPClasses)
+	for _, class := range m.dscpRates[:min(len(m.dscpRates), maxDSCPClasses)] {
+		parts = append(parts, fmt.Sprintf("%s ↓%s ↑%s", class.Name, m.formatRate(class.Download), m.formatRate(class.Upload)))
+	}
+	return "DSCP: " + strings.Join(parts, ", ")
+}
+
+// routeTickCmd schedules the next default route check
+func routeTickCmd() tea.Cmd {
+	return tea.Tick(routeCheckInterval, func(time.Time) tea.Msg {
+		return routeTickMsg{}
+	})
+}
+
+// checkRoute records a default route change with a chart marker and statusbar note
+func (m *model) checkRoute() {
+	change, changed := m.routes.Check()
+	if !changed {
+		return
+	}
+	m.chart.AddMarker()
+	m.routeNote = fmt.Sprintf("Route: %s @%s", change, change.Time.Format("15:04:05"))
+	m.updateStatusbar()
+	m.frame.dirty = true
+}
+
+// tickInterval returns how often the model should tick. While the terminal
+// is unfocused nothing is drawn, so sampling slows to blurredTickInterval to
+// save power; recordings keep their fixed pace.
+func (m model) tickInterval() time.Duration {
+	if m.replay != nil {
+		return m.sampleInterval() / time.Duration(m.replay.speed)
+	}
+	if !m.focused && m.virtualClock == nil {
+		return max(m.sampleInterval(), blurredTickInterval)
+	}
+	return m.sampleInterval()
+}
+
+// tickSteps returns how many chart columns one tick fills, so the time axis
+// keeps its scale while unfocused ticks span several sample intervals
+func (m model) tickSteps() int {
+	return max(int(m.tickInterval()/m.sampleInterval()), 1)
+}
+
+// tickSpan returns how much sampled time one tick covers; a sped-up
+// replay covers more than it takes
+func (m model) tickSpan() time.Duration {
+	return m.sampleInterval() * time.Duration(m.tickSteps())
+}
+
+// applyTickInterval restarts the tick chain after a focus change or a new
+// sampling rate, and tells the stats and history how long each tick spans
+func (m *model) applyTickInterval() tea.Cmd {
+	m.ui.GetStats().SetUpdateInterval(m.tickSpan())
+	if m.history != nil {
+		m.history.SetInterval(m.tickSpan())
+	}
+	return m.restartTicks()
+}
+
+// plotSample draws a tick's sample on a chart, or buffers it while paused,
+// once per column the tick covers. A gap is drawn once.
+func (m *model) plotSample(ch *chart.BrailleChart, buffered *pausedBuffer, sample pausedSample) {
+	steps := m.tickSteps()
+	if sample.gap {
+		steps = 1
+	}
+	for range steps {
+		if m.paused {
+			buffered.add(sample)
+		} else {
+			addSample(ch, sample)
+		}
+	}
+}
+
+// takeScreenshot saves the frame currently on screen and notes the result
+func (m *model) takeScreenshot() {
+	frame := m.frame.view
+	if frame == "" {
+		frame = m.renderView()
+	}
+	if path, err := saveScreenshot(m.screenshotDir, frame, time.Now()); err != nil {
+		m.keyNote = "Screenshot: failed"
+	} else {
+		m.keyNote = "Saved " + filepath.Base(path)
+	}
+	m.updateStatusbar()
+}
+
+// toggleScaleLock locks the scale at its current maximum, so bursts coming
+// and going don't rescale the chart, or unlocks it again. Stacked, remote and
+// mirrored charts follow the main chart's lock.
+func (m *model) toggleScaleLock() {
+	if m.chart.FixedMaxValue() > 0 {
+		m.chart.SetFixedMaxValue(0)
+		m.keyNote = "Scale unlocked"
+	} else {
+		m.chart.SetFixedMaxValue(m.chart.GetMaxValue())
+		m.keyNote = "Scale locked at " + m.formatRate(m.chart.GetMaxValue())
+	}
+	m.updateStatusbar()
+}
+
+// cycleTheme switches the chart colors to the next built-in theme; charts
+// drawn beside the main one follow it
+func (m *model) cycleTheme() {
+	m.chart.SetTheme(chart.NextTheme(m.chart.ThemeName()))
+	m.keyNote = "Theme: " + m.chart.ThemeName()
+	m.updateStatusbar()
+}
+
+// scaleName names the scaling mode for the statusbar, with the maximum it is
+// locked at
+func (m model) scaleName() string {
+	if locked := m.chart.FixedMaxValue(); locked > 0 {
+		return m.chart.GetScalingModeName() + " ≤ " + m.formatRate(locked)
+	}
+	return m.chart.GetScalingModeName()
+}
+
+// exportData saves the focused chart's samples to the working directory in
+// the given --output format and notes the result
+func (m *model) exportData(format string) {
+	points := m.focusedChart().ExportData(m.sampleInterval())
+	if path, err := saveExport(".", format, points, m.sampleInterval(), time.Now()); err != nil {
+		m.keyNote = "Export: failed"
+	} else {
+		m.keyNote = "Saved " + filepath.Base(path)
+	}
+	m.updateStatusbar()
+}
+
+// setDisplayMode switches between split, overlay and side-by-side, where the
+// chart is drawn split on the left and mirrored in overlay on the right
+func (m *model) setDisplayMode(mode string) {
+	m.displayMode = mode
+	m.chart.SetOverlayMode(mode == "overlay")
+	for _, s := range m.stack {
+		s.chart.SetOverlayMode(mode == "overlay")
+	}
+	if m.remoteChart != nil {
+		// The local and remote charts are always side by side
+		m.remoteChart.SetOverlayMode(mode == "overlay")
+		m.compare = m.remoteChart
+	} else if mode == "side-by-side" {
+		m.compare = m.chart.Mirror()
+		m.compare.SetOverlayMode(true)
+	} else {
+		m.compare = nil
+	}
+	m.resizeChart()
+}
+
+// modeName describes the display mode in the statusbar
+func (m model) modeName() string {
+	if m.chart.IsSmoothing() {
+		return m.displayMode + ", smooth"
+	}
+	return m.displayMode
+}
+
+// cursorStep is how many columns a cursor key moves: 10 with shift
+func cursorStep(msg tea.KeyMsg) int {
+	if strings.HasPrefix(msg.String(), "shift+") {
+		return 10
+	}
+	return 1
+}
+
+// moveCursor moves the column cursor step columns back in time (negative
+// steps move forward); moving past the newest column hides it
+func (m *model) moveCursor(step int) {
+	cursor := m.chart.Cursor()
+	switch {
+	case cursor < 0 && step > 0:
+		cursor = step - 1
+	case cursor >= 0:
+		cursor += step
+	}
+	m.chart.SetCursor(max(cursor, -1))
+	m.updateStatusbar()
+}
+
+// cursorStatus reads out the cursor column's time and rates
+func (m model) cursorStatus() string {
+	ch := m.focusedChart()
+	upload, download, samplesAgo, ok := ch.CursorColumn()
+	if !ok {
+		if m.chart.Cursor() >= 0 {
+			return "Cursor: no data"
+		}
+		return ""
+	}
+	at := ch.GetLastSampleTime().Add(-time.Duration(samplesAgo) * m.sampleInterval())
+	return fmt.Sprintf("Cursor %s ↓%s ↑%s", at.Format("15:04:05"), m.formatRate(download), m.formatRate(upload))
+}
+
+// toggleGhost draws the previous recorded session behind the chart, lined up
+// with the start of this one (or the last reset), or removes it again. The
+// history is read in the background, and pressing g again while it is
+// cancels the ghost.
+func (m *model) toggleGhost() tea.Cmd {
+	defer m.updateStatusbar()
+	if m.chart.HasGhost() || m.ghostLoading {
+		m.chart.SetGhost(nil, nil)
+		m.ghostNote, m.ghostLoading = "", false
+		return nil
+	}
+	if m.history == nil {
+		m.ghostNote = "Ghost: needs --history"
+		return nil
+	}
+	m.ghostNote, m.ghostLoading = "Ghost: loading…", true
+	history, startedAt := m.history, m.startedAt
+	return func() tea.Msg {
+		samples, err := history.LastSession(startedAt)
+		return ghostLoadedMsg{samples: samples, err: err}
+	}
+}
+
+// showGhost draws the previous session once it has been read, unless the
+// ghost was cancelled in the meantime
+func (m *model) showGhost(msg ghostLoadedMsg) {
+	if !m.ghostLoading {
+		return
+	}
+	defer m.updateStatusbar()
+	m.ghostLoading = false
+	if msg.err != nil || len(msg.samples) == 0 {
+		m.ghostNote = "Ghost: no previous session"
+		return
+	}
+	m.chart.SetGhost(ghostSeries(msg.samples, m.sampleInterval()))
+	m.ghostNote = "Ghost: " + msg.samples[0].Time.Local().Format("Jan 2 15:04")
+}
+
+// ghostSeries lays recorded samples out one per chart sample, by time since
+// the first. Samples recorded at a slower rate cover several chart samples;
+// where several fall on one, the peak is kept.
+func ghostSeries(samples []accounting.Sample, interval time.Duration) (upload, download []uint64) {
+	start := samples[0].Time
+	for _, sample := range samples {
+		first := int((sample.Time.Sub(start) + interval/2) / interval)
+		count := max(int((sample.Duration(updateInterval)+interval/2)/interval), 1)
+		for len(upload) < first+count {
+			upload, download = append(upload, 0), append(download, 0)
+		}
+		for i := first; i < first+count; i++ {
+			upload[i] = max(upload[i], sample.Upload)
+			download[i] = max(download[i], sample.Download)
+		}
+	}
+	return upload, download
+}
+
+// togglePause freezes the display, or resumes it and backfills the chart
+// with the samples taken in the meantime
+func (m *model) togglePause() {
+	m.paused = !m.paused
+	if m.paused {
+		m.pausedAt = m.clock()
+	} else {
+		m.pausedSamples.replay(m.chart)
+		m.pausedRemote.replay(m.remoteChart)
+		for _, s := range m.stack {
+			s.paused.replay(s.chart)
+		}
+	}
+	m.updateStatusbar()
+}
+
+// toggleStopped stops sampling altogether, or resumes it. The time spent
+// stopped isn't measured, so resuming draws a gap rather than averaging the
+// traffic of the whole stop into one sample.
+func (m *model) toggleStopped() {
+	m.stopped = !m.stopped
+	if m.stopped {
+		m.stoppedAt = m.clock()
+	} else {
+		// Restart the rates from now
+		m.collector.Sample()
+		m.plotSample(m.chart, &m.pausedSamples, pausedSample{gap: true})
+		m.sampleStack(true)
+		if m.remote != nil {
+			m.remote.Sample()
+			m.plotSample(m.remoteChart, &m.pausedRemote, pausedSample{gap: true})
+		}
+		m.currentUpload, m.currentDownload = 0, 0
+	}
+	m.updateStatusbar()
+}
+
+// addSample draws a sample, or a gap, on a chart
+func addSample(ch *chart.BrailleChart, sample pausedSample) {
+	if sample.gap {
+		ch.AddGap()
+	} else {
+		ch.AddDataPoint(sample.upload, sample.download)
+	}
+}
+
+// sampleInterval returns the time between samples while running
+func (m model) sampleInterval() time.Duration {
+	if m.interval == 0 {
+		return updateInterval
+	}
+	return m.interval
+}
+
+// stepRefreshRate moves the sampling interval one step along refreshRates,
+// faster for a negative step, and restarts the tick chain at the new rate
+func (m *model) stepRefreshRate(step int) tea.Cmd {
+	current := sort.Search(len(refreshRates), func(i int) bool { return refreshRates[i] >= m.sampleInterval() })
+	next := min(max(current+step, 0), len(refreshRates)-1)
+	if refreshRates[next] == m.sampleInterval() {
+		return nil
+	}
+	m.interval = refreshRates[next]
+	m.resizeHistory()
+	m.updateStatusbar()
+	return m.applyTickInterval()
+}
+
+// maxDataPoints returns how many samples at the given interval cover rawHistorySpan
+func maxDataPoints(interval time.Duration) int {
+	return max(int(rawHistorySpan/interval), defaultDataPoints)
+}
+
+// resizeHistory keeps rawHistorySpan of samples in every chart at the
+// current interval, so a faster rate still fills the 60 minute scale
+func (m *model) resizeHistory() {
+	points := maxDataPoints(m.sampleInterval())
+	m.chart.SetMaxPoints(points)
+	if m.remoteChart != nil {
+		m.remoteChart.SetMaxPoints(points)
+	}
+	for _, s := range m.stack {
+		s.chart.SetMaxPoints(points)
+	}
+}
+
+// restartTicks starts a new tick chain at the current interval, superseding any pending tick
+func (m *model) restartTicks() tea.Cmd {
+	m.tickGeneration++
+	return tickCmd(m.tickInterval(), m.tickGeneration)
 }
 
 // Update handles messages and updates the application state
@@ -155,103 +1611,472 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case tea.FocusMsg:
+		// The chart kept filling in the background, so it is current at once
+		m.focused = true
+		m.updateStatusbar()
+		m.frame.dirty = true
+		cmd = m.applyTickInterval()
+
+	case tea.BlurMsg:
+		// Keep sampling in the background, more slowly, but stop redrawing
+		// until focus returns
+		m.focused = false
+		cmd = m.applyTickInterval()
+
 	case tea.WindowSizeMsg:
+		m.frame.dirty = true
 		m.width = msg.Width
 		m.height = msg.Height
 		m.ready = true
 
-		// Always store 60 minutes of data (regardless of selected time scale)
-		// This ensures we have enough data for any time scale selection
-		maxDataPoints := 60 * 60 * 2 // 60 minutes * 60 seconds * 2 points per second
-		m.chart.SetMaxPoints(maxDataPoints)
-
 		// Update chart dimensions (always responsive to terminal width)
-		// Account for: help text (1 line) + status bar (1 line if shown)
-		chartHeight := m.height - 1 // Leave room for help text
-		if m.showStatusbar {
-			chartHeight -= 1 // Leave room for statusbar
-		}
-		if chartHeight < chart.MinChartHeight {
-			chartHeight = chart.MinChartHeight
-		}
-
-		m.chart.SetWidth(m.width)
-		m.chart.SetHeight(chartHeight)
+		m.resizeChart()
 
 		// Update statusbar width
 		m.statusbar.SetSize(m.width)
 
 	case tea.KeyMsg:
+		m.frame.dirty = true
 		switch {
+		case m.annotating:
+			// Every key goes to the marker label until Enter or Esc
+			m.markerKey(msg)
+
+		case m.showSpeedTest:
+			// Any key closes the speed test popup
+			m.showSpeedTest = false
+
+		case m.restoreOffer != nil && msg.Type == tea.KeyEnter:
+			m.restoreSession()
+
+		case m.chart.Cursor() >= 0 && key.Matches(msg, m.keys.HideCursor):
+			// Esc leaves the cursor before it quits
+			m.chart.SetCursor(-1)
+			m.updateStatusbar()
+
 		case key.Matches(msg, m.keys.Quit):
 			m.quitting = true
 			return m, tea.Quit
 
 		case key.Matches(msg, m.keys.Pause):
-			m.paused = !m.paused
+			m.togglePause()
+
+		case key.Matches(msg, m.keys.Stop):
+			m.toggleStopped()
+
+		case key.Matches(msg, m.keys.Marker):
+			m.startMarker()
+
+		case key.Matches(msg, m.keys.Markers):
+			m.toggleMarkers()
 
 		case key.Matches(msg, m.keys.Reset):
 			m.chart.Reset()
+			if m.remoteChart != nil {
+				m.remoteChart.Reset()
+			}
+			for _, s := range m.stack {
+				s.chart.Reset()
+			}
 			m.ui.GetStats().Reset()
 
 		case key.Matches(msg, m.keys.Stats):
 			m.showStatusbar = !m.showStatusbar
-			// Recalculate chart height (same logic as WindowSizeMsg)
-			chartHeight := m.height - 1 // Leave room for help text
-			if m.showStatusbar {
-				chartHeight -= 1 // Leave room for statusbar
-			}
-			if chartHeight < chart.MinChartHeight {
-				chartHeight = chart.MinChartHeight
-			}
-			m.chart.SetHeight(chartHeight)
+			m.resizeChart()
 
 		case key.Matches(msg, m.keys.DisplayMode):
-			// Toggle display mode
-			if m.displayMode == "split" {
-				m.displayMode = "overlay"
-				m.chart.SetOverlayMode(true)
-			} else {
-				m.displayMode = "split"
-				m.chart.SetOverlayMode(false)
+			// Cycle display mode
+			switch m.displayMode {
+			case "split":
+				m.setDisplayMode("overlay")
+			case "overlay":
+				if m.remoteChart != nil {
+					m.setDisplayMode("split")
+					break
+				}
+				m.setDisplayMode("side-by-side")
+			default:
+				m.setDisplayMode("split")
 			}
+			m.updateStatusbar()
 
 		case key.Matches(msg, m.keys.ScalingMode):
 			// Cycle through scaling modes
 			m.chart.CycleScalingMode()
 
+		case key.Matches(msg, m.keys.LockScale):
+			m.toggleScaleLock()
+
+		case key.Matches(msg, m.keys.Smooth):
+			m.chart.ToggleSmoothing()
+			m.updateStatusbar()
+
+		case key.Matches(msg, m.keys.SpeedTest):
+			if cmd = m.startSpeedTest(); cmd != nil {
+				m.speedTestPopup = true
+			}
+
+		case key.Matches(msg, m.keys.Screenshot):
+			m.takeScreenshot()
+
+		case key.Matches(msg, m.keys.ExportCSV):
+			m.exportData(outputCSV)
+
+		case key.Matches(msg, m.keys.ExportJSON):
+			m.exportData(outputJSON)
+
+		case key.Matches(msg, m.keys.Ghost):
+			cmd = m.toggleGhost()
+
+		case key.Matches(msg, m.keys.Processes):
+			m.toggleProcesses()
+
+		case key.Matches(msg, m.keys.Connections):
+			m.toggleConnections()
+
+		case key.Matches(msg, m.keys.Usage):
+			m.toggleUsage()
+
+		case key.Matches(msg, m.keys.UsagePeriod):
+			m.cycleUsagePeriod()
+
+		case key.Matches(msg, m.keys.Protocols):
+			m.toggleProtocols()
+
+		case key.Matches(msg, m.keys.Units):
+			m.toggleUnits()
+
+		case key.Matches(msg, m.keys.HostNames):
+			m.toggleHostNames()
+
+		case key.Matches(msg, m.keys.Wifi):
+			cmd = m.toggleWifi()
+
+		case key.Matches(msg, m.keys.StatsPanel):
+			m.toggleStatsPanel()
+		case key.Matches(msg, m.keys.Theme):
+			m.cycleTheme()
+
+		case key.Matches(msg, m.keys.SaveConfig):
+			m.saveConfig()
+
+		case key.Matches(msg, m.keys.NextChart):
+			m.cycleChartFocus()
+
+		case key.Matches(msg, m.keys.ProcessSort):
+			m.cycleProcessSort()
+
+		case key.Matches(msg, m.keys.Copy):
+			m.copyStats()
+
+		case key.Matches(msg, m.keys.CursorLeft):
+			m.moveCursor(cursorStep(msg))
+
+		case key.Matches(msg, m.keys.CursorRight):
+			m.moveCursor(-cursorStep(msg))
+
+		case key.Matches(msg, m.keys.NextIface):
+			m.cycleInterface(1)
+
+		case key.Matches(msg, m.keys.PrevIface):
+			m.cycleInterface(-1)
+
+		case key.Matches(msg, m.keys.Faster) && m.replay != nil:
+			cmd = m.stepReplaySpeed(1)
+
+		case key.Matches(msg, m.keys.Slower) && m.replay != nil:
+			cmd = m.stepReplaySpeed(-1)
+
+		case key.Matches(msg, m.keys.Faster):
+			cmd = m.stepRefreshRate(-1)
+
+		case key.Matches(msg, m.keys.Slower):
+			cmd = m.stepRefreshRate(1)
+
 		case key.Matches(msg, m.keys.TimeScale):
 			// Cycle through time scales
 			m.chart.CycleTimeScale()
 			// No need to change max points - we always store 60 minutes of data
 		}
 
-	case tickMsg:
-		if !m.paused {
-			// Get current bandwidth rates
-			upload, download, err := m.monitor.GetCurrentRates()
-			if err == nil {
-				m.currentUpload = upload
-				m.currentDownload = download
-
-				// Update chart with new data
-				m.chart.AddDataPoint(upload, download)
+	case routeTickMsg:
+		m.checkRoute()
+		cmd = routeTickCmd()
+
+	case headerTickMsg:
+		m.refreshHeader(m.clock())
+		if !m.paused {
+			m.frame.dirty = true
+		}
+		cmd = headerTickCmd()
+
+	case conntrackTickMsg:
+		m.checkConntrack()
+		m.frame.dirty = true
+		cmd = tea.Tick(conntrackCheckInterval, func(time.Time) tea.Msg { return conntrackTickMsg{} })
+
+	case projectionTickMsg:
+		if m.ledger != nil {
+			var err error
+			if m.projection, err = m.ledger.Project(time.Now(), m.cycleStart, m.projectionMethod); err != nil {
+				m.ledgerErr = err
+			}
+			m.quotaUsage = m.projection
+			if m.quotaPeriod == accounting.QuotaDaily {
+				if m.quotaUsage, err = m.ledger.ProjectDay(time.Now()); err != nil {
+					m.ledgerErr = err
+				}
+			}
+		}
+		if m.showUsage {
+			m.loadUsage()
+		}
+		m.frame.dirty = true
+		cmd = tea.Tick(projectionInterval, func(time.Time) tea.Msg { return projectionTickMsg{} })
+
+	case interfaceTotalsTickMsg:
+		m.interfaceTotalsErr = writeInterfaceTotals(m.interfaceTotalsPath, m.collector)
+		m.frame.dirty = true
+		cmd = tea.Tick(interfaceTotalsInterval, func(time.Time) tea.Msg { return interfaceTotalsTickMsg{} })
+
+	case alertActionMsg:
+		if msg.err != nil {
+			m.alertErr = msg.err
+			m.updateStatusbar()
+			m.frame.dirty = true
+		}
+
+	case reportErrorMsg:
+		m.reportErr = msg.err
+		m.frame.dirty = true
+
+	case historyRecordedMsg:
+		m.historyErr = msg.err
+
+	case ghostLoadedMsg:
+		m.showGhost(msg)
+		m.frame.dirty = true
+
+	case dnsResultMsg:
+		m.dnsLatency, m.dnsErr, m.dnsProbed = msg.latency, msg.err, true
+		if m.focused {
+			m.updateStatusbar()
+			m.frame.dirty = true
+		}
+		cmd = tea.Tick(m.dnsInterval, func(time.Time) tea.Msg { return dnsTickMsg{} })
+
+	case dnsTickMsg:
+		cmd = dnsProbeCmd(m.dns)
+
+	case wifiResultMsg:
+		cmd = m.wifiResult(msg)
+
+	case wifiTickMsg:
+		if m.showWifi {
+			cmd = wifiCmd()
+		} else {
+			m.wifiReading = false
+		}
+
+	case paneResultMsg:
+		m.panes[msg.index].lines, m.panes[msg.index].err = msg.lines, msg.err
+		if m.focused {
+			m.frame.dirty = true
+		}
+		cmd = tea.Tick(m.paneInterval, func(time.Time) tea.Msg { return paneTickMsg{index: msg.index} })
+
+	case sessionSnapshotTickMsg:
+		m.saveSessionSnapshot()
+		cmd = sessionSnapshotTickCmd()
+
+	case paneTickMsg:
+		cmd = runPaneCmd(msg.index, m.panes[msg.index].paneSpec, m.paneInterval)
+
+	case speedTestTickMsg:
+		cmd = tea.Batch(m.startSpeedTest(), speedTestTickCmd(m.speedTestEvery))
+		m.frame.dirty = true
+
+	case speedTestResultMsg:
+		m.finishSpeedTest(msg)
+		m.frame.dirty = true
+
+	case iperfStartMsg:
+		cmd = m.startIperf()
+		m.frame.dirty = true
+
+	case iperfResultMsg:
+		m.finishIperf(msg)
+		m.frame.dirty = true
+
+	case tickMsg:
+		if msg.generation != m.tickGeneration {
+			// Stale tick from a superseded chain
+			return m, nil
+		}
+		if m.virtualClock != nil {
+			m.virtualClock.Advance(m.sampleInterval())
+		}
+		if m.replay != nil && m.replay.Done() {
+			// Leave the replayed chart up for inspecting; no more ticks
+			m.updateStatusbar()
+			m.frame.dirty = true
+			return m, nil
+		}
+
+		if m.stopped {
+			return m, tickCmd(m.tickInterval(), m.tickGeneration)
+		}
 
-				// Update statistics
-				m.ui.GetStats().Update(upload, download)
+		// Sampling continues while paused; only the display is frozen
+		var alertCmd, recordCmd tea.Cmd
+		series, err := m.collector.Sample()
+		if errors.Is(err, monitor.ErrSampleGap) {
+			// Resumed from suspend or the clock jumped: mark the gap, not a spike
+			m.currentUpload = 0
+			m.currentDownload = 0
+			m.plotSample(m.chart, &m.pausedSamples, pausedSample{gap: true})
+			m.sampleStack(true)
+			if m.focused && !m.paused {
+				m.updateStatusbar()
+				m.frame.dirty = true
+			}
+		} else if err == nil {
+			upload, download := monitor.SplitSeries(series)
+			m.currentUpload = upload
+			m.currentDownload = download
+
+			// Update chart with new data
+			m.plotSample(m.chart, &m.pausedSamples, pausedSample{upload: upload, download: download})
+			m.sampleStack(false)
+			m.markDroppedSample()
+
+			// Update statistics
+			m.ui.GetStats().Update(upload, download)
+			alertCmd = m.checkAlerts(upload, download)
+			if m.dscp != nil {
+				m.dscpRates = m.dscp.Rates()
+			}
+			recordUpload, recordDownload := m.recordedRates(upload, download)
+			if m.history != nil {
+				recordCmd = recordHistory(m.history, m.clock(), recordUpload, recordDownload)
+			}
+			if m.ledger != nil {
+				m.ledgerErr = m.ledger.Add(m.clock(),
+					uint64(float64(recordUpload)*m.tickInterval().Seconds()),
+					uint64(float64(recordDownload)*m.tickInterval().Seconds()))
+			}
 
-				// Update statusbar
+			// Update statusbar and redraw only while someone can see it
+			if m.focused && !m.paused {
 				m.updateStatusbar()
+				m.frame.dirty = true
 			}
+			m.sampleErr = nil
+			m.sawData = m.sawData || upload > 0 || download > 0
+		} else {
+			m.sampleErr = err
+			m.frame.dirty = true
 		}
+		m.sampleRemote()
+		m.refreshProcesses()
+		m.refreshConnections()
+		m.refreshProtocols()
+		m.refreshTalkers()
+		m.refreshLinks(m.clock())
 
 		// Schedule next update
-		cmd = tickCmd()
+		cmd = tea.Batch(tickCmd(m.tickInterval(), m.tickGeneration), alertCmd, recordCmd)
 	}
 
 	return m, cmd
 }
 
+// isTiny reports whether the terminal is too small for the regular layout
+// (e.g. a tmux mini-pane)
+func (m model) isTiny() bool {
+	return m.height < tinyLayoutRows || m.width < tinyLayoutColumns
+}
+
+// rulerView renders the time tick ruler under each chart
+func (m model) rulerView() string {
+	ruler := m.chart.RenderRuler(m.sampleInterval())
+	if m.compare != nil {
+		ruler += " " + m.compare.RenderRuler(m.sampleInterval())
+	}
+	return ruler
+}
+
+// timeAxisView renders the time axis labels under each chart
+func (m model) timeAxisView() string {
+	axis := m.chart.RenderTimeAxis(m.sampleInterval())
+	if m.compare != nil {
+		axis += " " + m.compare.RenderTimeAxis(m.sampleInterval())
+	}
+	return axis
+}
+
+// resizeChart fits the chart to the rows the layout leaves it.
+// Tiny terminals may shrink the chart below MinChartHeight instead of overflowing.
+func (m *model) resizeChart() {
+	chartHeight := m.layout().chartRows
+	if len(m.stack) > 0 {
+		chartHeight = m.resizeStack(chartHeight)
+	}
+	minHeight := m.minChartRows()
+	m.chart.SetMinHeight(minHeight)
+
+	// Wide (e.g. CJK ambiguous-width) glyphs fit fewer cells per line, and
+	// the axis gutters take their share first
+	width := m.chartAreaWidth()
+	if m.compare != nil {
+		// Two halves with a one column divider between them
+		left := (width - 1) / 2
+		m.chart.FitWidth(left)
+		m.compare.SetMinHeight(minHeight)
+		m.compare.FitWidth(width - 1 - left)
+		m.compare.SetHeight(chartHeight)
+	} else {
+		m.chart.FitWidth(width)
+	}
+	m.chart.SetHeight(chartHeight)
+}
+
+// sideBySideView joins the split chart and its overlay mirror line by line
+func (m model) sideBySideView() string {
+	left := strings.Split(m.chart.Render(), "\n")
+	right := strings.Split(m.compare.Render(), "\n")
+	for i := range left {
+		if i < len(right) {
+			left[i] += " " + right[i]
+		}
+	}
+	return strings.Join(left, "\n")
+}
+
+// pausedStyle highlights the paused indicator
+var pausedStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Dark: "#F59E0B", Light: "#B45309"}).
+	Bold(true)
+
+// compactStatus renders the statusbar as a single rates segment for tiny terminals
+func (m model) compactStatus() string {
+	uploadStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"})
+	downloadStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Dark: "#10B981", Light: "#047857"})
+
+	status := fmt.Sprintf("%s %s",
+		downloadStyle.Render("↓"+m.formatRate(m.currentDownload)),
+		uploadStyle.Render("↑"+m.formatRate(m.currentUpload)))
+	if m.stopped {
+		status = pausedStyle.Render("STOPPED") + " " + status
+	} else if m.paused {
+		status = pausedStyle.Render("PAUSED") + " " + status
+	}
+	return ui.Truncate(status, m.width)
+}
+
 // updateStatusbar updates the statusbar with current statistics
 func (m *model) updateStatusbar() {
 	stats := m.ui.GetStats()
@@ -281,32 +2106,102 @@ func (m *model) updateStatusbar() {
 		Foreground(lipgloss.AdaptiveColor{Dark: "#059669", Light: "#065F46"}) // Same muted green as peaks
 
 	// Format current rates with colored arrows and values
-	uploadFormatted := ui.FormatBandwidth(m.currentUpload)
-	downloadFormatted := ui.FormatBandwidth(m.currentDownload)
+	uploadFormatted := m.formatRate(m.currentUpload)
+	downloadFormatted := m.formatRate(m.currentDownload)
 	currentRates := fmt.Sprintf("%s%s %s%s", 
 		downloadArrowStyle.Render("↓"), currentDownloadStyle.Render(fmt.Sprintf("%11s", downloadFormatted)),
 		uploadArrowStyle.Render("↑"), currentUploadStyle.Render(fmt.Sprintf("%11s", uploadFormatted)))
 
 	// Format peak values with colored arrows and values
-	peakUploadFormatted := ui.FormatBandwidth(stats.PeakUpload)
-	peakDownloadFormatted := ui.FormatBandwidth(stats.PeakDownload)
+	peakUploadFormatted := m.formatRate(stats.PeakUpload)
+	peakDownloadFormatted := m.formatRate(stats.PeakDownload)
 	peakValues := fmt.Sprintf("Peak: %s %s %s %s", 
 		downloadArrowStyle.Render("↓"), peakDownloadStyle.Render(fmt.Sprintf("%9s", peakDownloadFormatted)),
 		uploadArrowStyle.Render("↑"), peakUploadStyle.Render(fmt.Sprintf("%9s", peakUploadFormatted)))
 
 	// Format totals with colored arrows and values
-	totalUploadFormatted := ui.FormatBytes(stats.TotalUpload)
-	totalDownloadFormatted := ui.FormatBytes(stats.TotalDownload)
+	totalUploadFormatted := m.formatTotal(stats.TotalUpload)
+	totalDownloadFormatted := m.formatTotal(stats.TotalDownload)
 	totalValues := fmt.Sprintf("Total: %s %s %s %s", 
 		downloadArrowStyle.Render("↓"), totalDownloadStyle.Render(fmt.Sprintf("%8s", totalDownloadFormatted)),
 		uploadArrowStyle.Render("↑"), totalUploadStyle.Render(fmt.Sprintf("%8s", totalUploadFormatted)))
 
 	// Format uptime and display mode and scaling mode and time scale
-	uptimeValue := fmt.Sprintf("Up: %s | Mode: %s | Scale: %s | Time: %s",
+	var pausedValue string
+	if m.stopped {
+		pausedValue = pausedStyle.Render("STOPPED at "+m.stoppedAt.Format("15:04:05")) + " | "
+	} else if m.paused {
+		pausedValue = pausedStyle.Render("PAUSED at "+m.pausedAt.Format("15:04:05")) + " | "
+	}
+	if m.restoreOffer != nil {
+		pausedValue += m.restoreStatus() + " | "
+	}
+	if alert := m.alertStatus(); alert != "" {
+		pausedValue += alert + " | "
+	}
+	if m.ifaceFocus != "" {
+		pausedValue += "Iface: " + m.ifaceFocus + " | "
+	}
+	if m.remote != nil {
+		pausedValue += m.remoteStatus() + " | "
+	}
+	if m.replay != nil {
+		pausedValue += m.replayStatus() + " | "
+	}
+	if cursor := m.cursorStatus(); cursor != "" {
+		pausedValue += pausedStyle.Render(cursor) + " | "
+	}
+	uptimeValue := pausedValue + fmt.Sprintf("Up: %s | Mode: %s | Scale: %s | Time: %s | Rate: %s",
 		ui.FormatDuration(stats.GetUptime()),
-		m.displayMode,
-		m.chart.GetScalingModeName(),
-		m.chart.GetTimeScaleName())
+		m.modeName(),
+		m.scaleName(),
+		m.chart.GetTimeScaleName(),
+		m.sampleInterval())
+
+	if today := m.ledgerStatus(); today != "" {
+		uptimeValue += " | " + today
+	}
+	if quota := m.quotaStatus(); quota != "" {
+		uptimeValue += " | " + quota
+	} else if cycle := m.projectionStatus(); cycle != "" {
+		uptimeValue += " | " + cycle
+	}
+	if export := m.interfaceTotalsStatus(); export != "" {
+		uptimeValue += " | " + export
+	}
+	if dns := m.dnsStatus(); dns != "" {
+		uptimeValue += " | " + dns
+	}
+	if dscp := m.dscpStatus(); dscp != "" {
+		uptimeValue += " | " + dscp
+	}
+	if groups := m.groupStatus(); groups != "" {
+		uptimeValue += " | " + groups
+	}
+	if vpn := m.vpnStatus(); vpn != "" {
+		uptimeValue += " | " + vpn
+	}
+	if conntrack := m.conntrackStatus(); conntrack != "" {
+		uptimeValue += " | " + conntrack
+	}
+	if errs := m.errorStatus(); errs != "" {
+		uptimeValue += " | " + errs
+	}
+	if m.speedTestNote != "" {
+		uptimeValue += " | " + m.speedTestNote
+	}
+	if m.iperfNote != "" {
+		uptimeValue += " | " + m.iperfNote
+	}
+	if m.routeNote != "" {
+		uptimeValue += " | " + m.routeNote
+	}
+	if m.ghostNote != "" {
+		uptimeValue += " | " + m.ghostNote
+	}
+	if m.keyNote != "" {
+		uptimeValue += " | " + m.keyNote
+	}
 
 	m.statusbar.SetContent(currentRates, peakValues, totalValues, uptimeValue)
 }
@@ -321,20 +2216,70 @@ func (m model) View() string {
 		return "\n  Goodbye!\n"
 	}
 
+	// Reuse the previous frame when nothing visible has changed
+	if !m.frame.dirty {
+		return m.frame.view
+	}
+	m.frame.view = m.renderView()
+	m.frame.dirty = false
+	return m.frame.view
+}
+
+// renderView builds the full application frame
+func (m model) renderView() string {
 	var view strings.Builder
+	layout := m.layout()
+
+	// Header bar
+	if layout.header {
+		view.WriteString(m.headerView())
+		view.WriteString("\n")
+	}
 
 	// Chart
 	chartView := m.chart.Render()
+	if m.showUsage {
+		chartView = m.usageView(m.chart.GetHeight())
+	} else if m.showEmptyState() {
+		chartView = m.emptyStateView(m.chart.GetHeight())
+	} else if m.compare != nil {
+		chartView = m.sideBySideView()
+	}
+	if len(m.stack) > 0 {
+		chartView = m.stackView(chartView)
+	}
+	if m.showPanes() {
+		chartView = m.withPanes(chartView)
+	}
+	if m.showSpeedTest {
+		chartView = m.speedTestPopupView(chartView)
+	}
 	view.WriteString(chartView)
+	if layout.ruler {
+		view.WriteString("\n")
+		view.WriteString(m.rulerView())
+	}
+	if layout.timeAxis {
+		view.WriteString("\n")
+		view.WriteString(m.timeAxisView())
+	}
 
-	// Statusbar
-	if m.showStatusbar {
+	// Statusbar, which the marker label prompt takes over
+	if layout.statusbar && m.annotating {
+		view.WriteString("\n")
+		view.WriteString(ui.Truncate(m.markerPrompt(), m.width))
+	} else if layout.statusbar && m.isTiny() {
+		view.WriteString("\n")
+		view.WriteString(m.compactStatus())
+	} else if layout.statusbar {
 		view.WriteString("\n")
-		view.WriteString(m.statusbar.View())
+		// The statusbar measures its arrows as single-width; trim in case the
+		// terminal draws them wide so the line never wraps
+		view.WriteString(ui.Truncate(m.statusbar.View(), m.width))
 	}
 
 	// Title and controls help
-	if m.height > 10 { // Only show if we have enough space
+	if layout.title {
 		view.WriteString("\n")
 		
 		// Create title
@@ -346,18 +2291,24 @@ func (m model) View() string {
 		// Create help text
 		helpStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#6B7280"))
-		controls := "r: reset • p: pause • s: statusbar • m: mode • l: scaling • t: time • q: quit"
+		if m.sourceNote != "" {
+			title += helpStyle.Render(" · " + m.sourceNote)
+		}
+		controls := "r: reset • p: pause • s: statusbar • m: mode • l: scaling • i: smooth • t: time • +/-: rate • x: speed • ←/→: cursor • n/N: iface • o: procs • g: ghost • c: capture • y: copy • W: save • q: quit"
 		if m.paused {
-			controls = "r: reset • p: resume • s: statusbar • m: mode • l: scaling • t: time • q: quit"
+			controls = "r: reset • p: resume • s: statusbar • m: mode • l: scaling • i: smooth • t: time • +/-: rate • x: speed • ←/→: cursor • n/N: iface • o: procs • g: ghost • c: capture • y: copy • W: save • q: quit"
+		}
+		if len(m.stack) > 0 {
+			controls = strings.Replace(controls, " • q: quit", " • tab: focus • q: quit", 1)
 		}
 		help := helpStyle.Render(controls)
 		
 		// Calculate spacing to right-align help
-		titleWidth := lipgloss.Width(title)
-		helpWidth := lipgloss.Width(help)
+		titleWidth := ui.StringWidth(title)
+		helpWidth := ui.StringWidth(help)
 		availableWidth := m.width
 		
-		if titleWidth + helpWidth < availableWidth {
+		if layout.help && titleWidth + helpWidth < availableWidth {
 			// Right-align help text
 			spacingWidth := availableWidth - titleWidth - helpWidth
 			spacing := strings.Repeat(" ", spacingWidth)
@@ -365,7 +2316,7 @@ func (m model) View() string {
 			view.WriteString(bottomLine)
 		} else {
 			// Fall back to just showing title if not enough space
-			view.WriteString(title)
+			view.WriteString(ui.Truncate(title, availableWidth))
 		}
 	}
 
@@ -377,7 +2328,7 @@ func (m model) View() string {
 
 // runCompactMode runs the bandwidth monitor in compact mode (2-line header)
 // This forks to background and sets up scroll regions
-func runCompactMode(overlay bool, timeMinutes int, size int) {
+func runCompactMode(opts options, overlay bool, timeMinutes int, size int) {
 	// Validate and clamp size (1-5, representing bars per direction)
 	if size < 1 {
 		size = 1
@@ -391,24 +2342,19 @@ func runCompactMode(overlay bool, timeMinutes int, size int) {
 	
 	// Check if we're already the background daemon
 	isDaemon := os.Getenv("PEAKS_DAEMON") == "1"
+
+	// The Windows console only draws the escape sequences below once VT
+	// processing is on; the daemon shares the parent's console
+	if err := enableVirtualTerminal(); err != nil && !isDaemon {
+		fmt.Fprintf(os.Stderr, "Error: --compact: %v\n", err)
+		os.Exit(1)
+	}
 	
 	if !isDaemon {
 		// We're the parent - fork to background
 		env := append(os.Environ(), "PEAKS_DAEMON=1")
 		
-		// Build command with flags
-		args := []string{"--compact"}
-		if overlay {
-			args = append(args, "--overlay")
-		}
-		if timeMinutes != 1 {
-			args = append(args, "--time", fmt.Sprintf("%d", timeMinutes))
-		}
-		if size != 1 {
-			args = append(args, "--size", fmt.Sprintf("%d", size))
-		}
-		
-		cmd := exec.Command(os.Args[0], args...)
+		cmd := exec.Command(os.Args[0], compactDaemonArgs(opts, overlay, timeMinutes, size)...)
 		cmd.Env = env
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -440,8 +2386,9 @@ func runCompactMode(overlay bool, timeMinutes int, size int) {
 		fmt.Printf("\033[%d;%dr", totalLines+1, termHeight)  // Set scroll region from line (totalLines+1) to bottom
 		fmt.Printf("\033[%d;1H", totalLines+1)               // Move to line (totalLines+1), column 1
 		
-		// Save PID for cleanup (user can find it with: pgrep peaks)
-		pidFile := fmt.Sprintf("/tmp/peaks-%d.pid", os.Getpid())
+		// Save PID for cleanup (user can find it with: pgrep peaks, and
+		// --stop uses it on Windows)
+		pidFile := compactPIDFile(cmd.Process.Pid)
 		os.WriteFile(pidFile, []byte(fmt.Sprintf("%d", cmd.Process.Pid)), 0644)
 		
 		// Parent exits, returns control to shell
@@ -449,17 +2396,152 @@ func runCompactMode(overlay bool, timeMinutes int, size int) {
 	}
 	
 	// We're the daemon - do the actual monitoring
-	runCompactDaemon(overlay, timeMinutes, totalLines)
+	if err := runCompactDaemon(opts, overlay, timeMinutes, totalLines); err != nil {
+		fatalf(resetScrollRegion, "%v", err)
+	}
+}
+
+// compactDaemonArgs returns the command line the compact mode daemon is
+// started with, forwarding the flags it needs
+func compactDaemonArgs(opts options, overlay bool, timeMinutes, size int) []string {
+	args := []string{"--compact"}
+	if overlay {
+		args = append(args, "--overlay")
+	}
+	if timeMinutes != 1 {
+		args = append(args, "--time", fmt.Sprintf("%d", timeMinutes))
+	}
+	if size != 1 {
+		args = append(args, "--size", fmt.Sprintf("%d", size))
+	}
+	if opts.source == monitor.SourcePrometheus {
+		args = append(args, "--prom-url", opts.promURL, "--query", opts.promQuery, "--query-up", opts.promQueryUp)
+	} else if opts.source == monitor.SourceSNMP {
+		args = append(args, "--snmp", opts.snmpTarget, "--community", opts.snmpCommunity, "--ifindex", fmt.Sprint(opts.snmpIfIndex))
+	} else if opts.source != monitor.SourceNetwork {
+		args = append(args, "--source", opts.source)
+	}
+	if opts.pprofAddr != "" {
+		args = append(args, "--pprof", opts.pprofAddr)
+	}
+	if opts.glyphs != chart.GlyphNameAuto {
+		args = append(args, "--glyphs", opts.glyphs)
+	}
+	if opts.theme != "" {
+		args = append(args, "--theme", opts.theme)
+	}
+	if opts.color != chart.ColorNameAuto {
+		args = append(args, "--color", opts.color)
+	}
+	if opts.gutter != chart.GutterNone {
+		args = append(args, "--axis-gutter", opts.gutter.String())
+	}
+	if opts.maxScale > 0 {
+		args = append(args, "--max-scale", fmt.Sprintf("%d", opts.maxScale))
+	}
+	for _, group := range opts.groups {
+		args = append(args, "--group", group.String())
+	}
+	if opts.ledgerPath != "" {
+		args = append(args, "--ledger", opts.ledgerPath)
+	}
+	// Always, since the config may give the daemon another default
+	args = append(args, "--prefixes", opts.prefixes)
+	if opts.units != ui.UnitsBytes {
+		args = append(args, "--units", opts.units)
+	}
+	args = append(args, "--config", opts.configPath)
+	if opts.historyDir != "" {
+		args = append(args, "--history", opts.historyDir,
+			"--retain-raw", accounting.FormatRetention(opts.retention.Raw),
+			"--retain-minutes", accounting.FormatRetention(opts.retention.Minutes))
+	}
+	if opts.interfaceTotals != "" {
+		args = append(args, "--interface-totals", opts.interfaceTotals)
+	}
+	if opts.report != "" {
+		args = append(args, "--report", opts.report, "--smtp", opts.smtp.Addr,
+			"--smtp-user", opts.smtp.Username, "--smtp-from", opts.smtp.From,
+			"--smtp-to", strings.Join(opts.smtp.To, ","))
+		if opts.reportHTML {
+			args = append(args, "--report-html")
+		}
+	}
+	return args
+}
+
+// compactDaemonPattern matches the command line the compact mode daemon is
+// started with (always --compact first), as an extended regular expression.
+// It doesn't match --compact-stdout or --compact-width, so status line
+// streamers are left alone by --stop.
+const compactDaemonPattern = `peaks(\.exe)? --compact( |$)`
+
+// compactPIDFile is where the compact mode daemon with the given pid is
+// recorded
+func compactPIDFile(pid int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("peaks-%d.pid", pid))
 }
 
 // runCompactDaemon runs as a background daemon
-func runCompactDaemon(overlay bool, timeMinutes int, totalLines int) {
-	// Initialize monitor and chart
-	mon := monitor.NewBandwidthMonitor()
+func runCompactDaemon(opts options, overlay bool, timeMinutes int, totalLines int) error {
+	// The parent already reserved the header lines with a scroll region;
+	// never leave it behind, even on a panic
+	defer recoverTerminal(resetScrollRegion)
+	defer os.Remove(compactPIDFile(os.Getpid()))
+
+	// Initialize collector and chart
+	collector, err := newCollector(opts)
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	if opts.pprofAddr != "" {
+		if err := startDebugServer(opts.pprofAddr); err != nil {
+			return err
+		}
+	}
+	ledger, err := newLedger(opts)
+	if err != nil {
+		return err
+	}
+	if ledger != nil {
+		defer ledger.Flush()
+	}
+	history, closeHistory, err := openHistory(opts)
+	if err != nil {
+		return err
+	}
+	defer closeHistory()
+	stopReports, err := startReports(opts, func(err error) {
+		fmt.Fprintf(os.Stderr, "peaks: email report: %v\n", err)
+	})
+	if err != nil {
+		return err
+	}
+	defer stopReports()
+	var totalsTicker <-chan time.Time
+	if opts.interfaceTotals != "" {
+		if err := writeInterfaceTotals(opts.interfaceTotals, collector); err != nil {
+			return err
+		}
+		defer writeInterfaceTotals(opts.interfaceTotals, collector)
+		ticker := time.NewTicker(interfaceTotalsInterval)
+		defer ticker.Stop()
+		totalsTicker = ticker.C
+	}
 	ch := chart.NewBrailleChart(defaultDataPoints)
 	
 	// Set overlay mode if requested
 	ch.SetOverlayMode(overlay)
+	ch.SetGlyphSet(opts.glyphSet())
+	ch.SetTheme(opts.chartTheme())
+	ch.SetGutter(opts.gutter)
+	ch.SetDecimalUnits(opts.prefixes == ui.PrefixesSI)
+	ch.SetBitUnits(opts.units == ui.UnitsBits && byteRates(opts.source))
+	ch.SetFixedMaxValue(opts.maxScale)
+	if opts.source == monitor.SourceCPU {
+		ch.SetLabelFormatter(ui.FormatCPU)
+	}
 	
 	// Map time minutes to TimeScale
 	var timeScale chart.TimeScale
@@ -474,6 +2556,14 @@ func runCompactDaemon(overlay bool, timeMinutes int, totalLines int) {
 		timeScale = chart.TimeScale30Min
 	case 60:
 		timeScale = chart.TimeScale60Min
+	case 180:
+		timeScale = chart.TimeScale3Hour
+	case 360:
+		timeScale = chart.TimeScale6Hour
+	case 720:
+		timeScale = chart.TimeScale12Hour
+	case 1440:
+		timeScale = chart.TimeScale24Hour
 	default:
 		timeScale = chart.TimeScale1Min
 	}
@@ -491,13 +2581,13 @@ func runCompactDaemon(overlay bool, timeMinutes int, totalLines int) {
 	termWidth := getTerminalWidth()
 	termHeight := getTerminalHeight()
 
-	// Set up signal handling for Ctrl+C
+	// Set up signal handling for Ctrl+C, kill and hangup
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigChan, terminationSignals...)
 	
 	defer func() {
 		// Cleanup: restore normal scroll region and clear top lines
-		fmt.Printf("\033[1;%dr", termHeight)      // Reset scroll region to full screen
+		restoreTerminal(resetScrollRegion)             // Reset scroll region, cursor and attributes
 		for i := 1; i <= totalLines; i++ {
 			fmt.Printf("\033[%d;1H\033[2K", i)    // Clear each line
 		}
@@ -508,13 +2598,32 @@ func runCompactDaemon(overlay bool, timeMinutes int, totalLines int) {
 	ticker := time.NewTicker(updateInterval)
 	defer ticker.Stop()
 
+	// Mark default route changes on the chart (network source only)
+	var routes *monitor.RouteWatcher
+	routeTicker := time.NewTicker(routeCheckInterval)
+	defer routeTicker.Stop()
+	if opts.source == monitor.SourceNetwork || opts.source == "" {
+		routes = monitor.NewRouteWatcher()
+	}
+
 	for {
 		select {
-		case <-ticker.C:
-			// Get current bandwidth
-			upload, download, err := mon.GetCurrentRates()
-			if err == nil {
+		case now := <-ticker.C:
+			// Get current rates
+			series, err := collector.Sample()
+			if errors.Is(err, monitor.ErrSampleGap) {
+				ch.AddGap()
+			} else if err == nil {
+				upload, download := monitor.SplitSeries(series)
 				ch.AddDataPoint(upload, download)
+				if history != nil {
+					history.Record(now, upload, download)
+				}
+				if ledger != nil {
+					ledger.Add(now,
+						uint64(float64(upload)*updateInterval.Seconds()),
+						uint64(float64(download)*updateInterval.Seconds()))
+				}
 			}
 
 			// Check for terminal resize
@@ -535,43 +2644,41 @@ func runCompactDaemon(overlay bool, timeMinutes int, totalLines int) {
 			lines := strings.Split(compactView, "\n")
 			for i := 0; i < totalLines && i < len(lines); i++ {
 				fmt.Printf("\033[%d;1H\033[2K", i+1) // Move to line i+1 and clear entire line
-				fmt.Print(lines[i])                   // Draw the line
+				fmt.Print(ui.Truncate(lines[i], termWidth)) // Draw the line, never wrapping
 			}
 			
 			fmt.Print("\0338")                    // Restore cursor position
 
+		case <-totalsTicker:
+			writeInterfaceTotals(opts.interfaceTotals, collector)
+
+		case <-routeTicker.C:
+			if routes != nil {
+				if _, changed := routes.Check(); changed {
+					ch.AddMarker()
+				}
+			}
+
 		case <-sigChan:
-			return
+			return nil
 		}
 	}
 }
 
 // stopCompactMode stops any running compact mode daemon
 func stopCompactMode() {
-	// Find all peaks processes
-	cmd := exec.Command("pgrep", "-f", "peaks.*--compact")
-	output, err := cmd.Output()
-	if err != nil {
-		fmt.Println("No running compact mode daemon found")
-		return
-	}
-
-	pids := strings.Split(strings.TrimSpace(string(output)), "\n")
-	currentPID := fmt.Sprintf("%d", os.Getpid())
-	
+	currentPID := os.Getpid()
 	stopped := false
-	for _, pid := range pids {
-		pid = strings.TrimSpace(pid)
-		if pid == "" || pid == currentPID {
+	for _, pid := range compactDaemonPIDs() {
+		if pid == currentPID {
 			continue
 		}
 		
-		// Try to kill the process
-		killCmd := exec.Command("kill", pid)
-		if err := killCmd.Run(); err != nil {
-			fmt.Printf("Failed to stop process %s: %v\n", pid, err)
+		// Try to stop the process
+		if err := stopCompactDaemon(pid); err != nil {
+			fmt.Printf("Failed to stop process %d: %v\n", pid, err)
 		} else {
-			fmt.Printf("Stopped compact mode daemon (PID: %s)\n", pid)
+			fmt.Printf("Stopped compact mode daemon (PID: %d)\n", pid)
 			stopped = true
 		}
 	}
@@ -582,13 +2689,132 @@ func stopCompactMode() {
 }
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		subcommands := map[string]func([]string, io.Writer) error{
+			"import":    runImport,
+			"compare":   runCompare,
+			"report":    runReport,
+			"usage":     runUsage,
+			"glance":    runGlance,
+			"agent":     runAgent,
+			"dashboard": runDashboard,
+		}
+		if os.Args[1] == "connect" {
+			args, err := connectArgs(os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Args = append(os.Args[:1], args...)
+		}
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// Parse command-line flags
 	compactMode := flag.Bool("compact", false, "run in compact mode (2-line display at top of terminal)")
 	compactOverlay := flag.Bool("overlay", false, "use overlay mode in compact view (both bars from bottom)")
 	compactTime := flag.Int("time", 1, "time window in minutes for compact mode (1, 5, 10, 30, 60)")
 	compactSize := flag.Int("size", 1, "number of bars per direction (1-5: 1=2 lines, 2=4 lines, 3=6 lines, etc.)")
+	compactStdout := flag.Bool("compact-stdout", false, "print a one line chart and the rates, then exit: for tmux status-right, shell prompts and starship (see --stream)")
+	compactWidth := flag.Int("compact-width", defaultInlineWidth, "chart width in cells for --compact-stdout")
+	stream := flag.Bool("stream", false, "with --compact-stdout, keep sampling and print a line every sample instead of exiting")
+	plain := flag.Bool("plain", false, "disable colors in --compact-stdout lines (tmux status lines don't show ANSI colors)")
+	accessible := flag.Bool("accessible", false, "screen reader mode: no chart, print a spoken-style summary of the rates every --announce")
+	announce := flag.Duration("announce", 10*time.Second, "how often --accessible prints a summary")
+	output := flag.String("output", "", "headless mode: no TUI, write each sample to stdout as json (JSON Lines) or csv")
 	showVersion := flag.Bool("version", false, "show version information")
 	stopDaemon := flag.Bool("stop", false, "stop any running compact mode daemon")
+	source := flag.String("source", monitor.SourceNetwork, "data source to chart (net, disk, cpu, winhost under WSL, user@host or ssh://[user@]host[:port] for a Linux server, peaks://host[:port] for a peaks agent, nft:TABLE/OUT,TABLE/IN or iptables:CHAIN/OUT,CHAIN/IN)")
+	record := flag.Bool("record", false, "reproducible session for asciinema/VHS recordings: a virtual clock stepped per sample, and the seeded --demo traffic unless --source is given")
+	seed := flag.Uint64("seed", 1, "random seed of the --demo traffic")
+	stdin := flag.Bool("stdin", false, "chart \"timestamp value1 [value2]\" lines (or JSON) piped in on stdin, e.g. any streaming metric")
+	input := flag.String("input", "", "like --stdin, but chart the lines appended to this file as they are written (like tail -f)")
+	charts := flag.String("charts", "", "stack a chart per interface under the main chart, e.g. eth0,wlan0 (tab cycles focus)")
+	remote := flag.String("remote", "", "also chart this source on the right, sampled in step with the local one (e.g. user@host over ssh, winhost, or prometheus with --prom-url)")
+	promURL := flag.String("prom-url", "", "chart a PromQL --query polled from this Prometheus server (e.g. http://localhost:9090)")
+	promQuery := flag.String("query", "", "PromQL query drawn above the axis with --prom-url, e.g. 'sum(rate(node_network_receive_bytes_total[1m]))'")
+	promQueryUp := flag.String("query-up", "", "optional PromQL query drawn below the axis with --prom-url")
+	snmpTarget := flag.String("snmp", "", "chart a router or switch interface polled over SNMPv2c from this agent, e.g. 192.168.1.1:161 (with --ifindex)")
+	snmpCommunity := flag.String("community", "public", "SNMPv2c community for --snmp")
+	snmpIfIndex := flag.Int("ifindex", 0, "interface index (ifIndex) charted with --snmp")
+	demo := flag.Bool("demo", false, "chart synthetic traffic (idle, bursts, a sustained download, saturation) instead of real interfaces, e.g. for recordings")
+	dnsHost := flag.String("dns", "", "time DNS lookups of this host name against the configured resolver (e.g. example.com)")
+	dnsInterval := flag.Duration("dns-interval", 10*time.Second, "how often to run the DNS probe")
+	dnsAlert := flag.Duration("dns-alert", 250*time.Millisecond, "flag DNS lookups slower than this (0 to disable)")
+	speedTestURL := flag.String("speedtest-url", monitor.DefaultSpeedTestURL, "speed test endpoint (Cloudflare /__down and /__up protocol)")
+	speedTestEvery := flag.Duration("speedtest-every", 0, "run a speed test on this schedule, e.g. 1h (0 for on keypress only)")
+	speedTestLog := flag.String("speedtest-log", "", "append speed test results to this JSON Lines file")
+	alertUp := flag.String("alert-up", "", "alert when the upload rate exceeds this, e.g. 10MB/s (overrides alert_up in the config)")
+	alertDown := flag.String("alert-down", "", "alert when the download rate exceeds this, e.g. 50MB/s (overrides alert_down in the config)")
+	alertLog := flag.String("alert-log", "", "append alert events to this JSON Lines file")
+	alertNotify := flag.Bool("alert-notify", false, "show a desktop notification when an alert is raised")
+	alertCmd := flag.String("alert-cmd", "", "run this shell command when an alert is raised or clears, with the event in $PEAKS_ALERT_*")
+	wifi := flag.Bool("wifi", false, "show the Wi-Fi network, signal strength and link rate beside the chart (toggle with w)")
+	markDrops := flag.Bool("mark-drops", false, "mark chart columns where the charted interfaces dropped packets")
+	conntrackAlert := flag.Float64("conntrack-alert", 90, "flag the conntrack table above this percent full on Linux (0 to disable)")
+	ledgerPath := flag.String("ledger", "", "record daily traffic totals in this file, closed out at local midnight (\"auto\" for the user config directory)")
+	historyDir := flag.String("history", "", "record sample history in this directory (\"auto\" for the user data directory), compacted per --retain-*")
+	replay := flag.String("replay", "", "play back this machine's --history (default: auto) instead of sampling: a duration ago such as 2h, or FROM..TO")
+	retention := accounting.DefaultRetention
+	flag.Func("retain-raw", "keep raw samples this long, e.g. 48h, 7d or forever (default 48h)", func(s string) (err error) {
+		retention.Raw, err = accounting.ParseRetention(s)
+		return err
+	})
+	flag.Func("retain-minutes", "keep minute aggregates this long (default 90d)", func(s string) (err error) {
+		retention.Minutes, err = accounting.ParseRetention(s)
+		return err
+	})
+	labels := flag.Bool("labels", true, "label the download and upload halves of the split chart in its corners (--labels=false to hide)")
+	ruler := flag.Bool("ruler", false, "draw a row of time ticks under the chart (every 10s … 24h depending on the time scale)")
+	timeAxis := flag.Bool("time-axis", false, "label the time before now along the bottom of the chart, e.g. \"-20s  -10s  now\" (follows the time scale)")
+	sideBySide := flag.Bool("side-by-side", false, "start with the chart drawn split and overlaid side by side (cycle modes with m)")
+	gridlines := flag.Bool("gridlines", false, "draw faint gridlines at round rates (decades on the log scale), labelled in the axis gutter (left unless --axis-gutter is set)")
+	maxScale := flag.String("max-scale", "", "lock the top of the scale at this rate, e.g. 100MB, instead of rescaling to the data (toggle with f)")
+	axisGutter := flag.String("axis-gutter", "none", "label the chart's scale in a gutter on the left, right or both sides (none to hide)")
+	prefixes := flag.String("prefixes", "", prefixesUsage+" (overrides prefixes in the config; default binary)")
+	units := flag.String("units", ui.UnitsBytes, "show rates in bytes or bits per second (toggle with b)")
+	smooth := flag.Bool("smooth", false, "smooth the full-screen chart for presentations (toggle with i); stats and exports keep the raw data")
+	header := flag.Bool("header", false, "show a header bar with the host name, interface, link speed, IP address and time")
+	headerFormat := flag.String("header-format", defaultHeaderFormat, "layout of the --header bar, using {host}, {iface}, {link}, {ip} and {time}")
+	statsWindows := flag.String("stats-windows", "1m,5m,15m", "windows the a key's average, median and 95th percentile rates are taken over")
+	screenshotDir := flag.String("screenshot-dir", ".", "directory the c key saves screenshots of the current frame to")
+	interfaceTotals := flag.String("interface-totals", "", "export per-interface session/daily totals, peaks and errors to this CSV or .json file, every minute and on exit")
+	cycleStart := flag.Int("cycle-start", 1, "day of the month the billing cycle starts, for the --ledger usage projection")
+	projection := flag.String("projection", accounting.ProjectLinear, "how to project the cycle's usage: linear (cycle so far) or recent (last 7 days' average)")
+	quota := flag.String("quota", "", "data cap to track against the --ledger, e.g. 500GB (overrides quota in the config)")
+	quotaPeriod := flag.String("quota-period", "", "whether the --quota is per billing cycle (monthly, from --cycle-start) or daily (overrides quota_period in the config; default monthly)")
+	report := flag.String("report", "", "email the --ledger totals daily or weekly, shortly after local midnight")
+	reportHTML := flag.Bool("report-html", false, "send email reports as HTML instead of plain text")
+	smtpAddr := flag.String("smtp", "", "SMTP server for email reports, as HOST:PORT (STARTTLS is used when offered)")
+	smtpUser := flag.String("smtp-user", "", "SMTP user name; the password is read from $"+smtpPasswordEnv)
+	smtpFrom := flag.String("smtp-from", "", "sender address of email reports")
+	smtpTo := flag.String("smtp-to", "", "comma-separated recipients of email reports")
+	dscp := flag.Bool("dscp", false, "capture packets and break traffic down by DSCP/QoS class (Linux, needs root or CAP_NET_RAW)")
+	capture := flag.Bool("capture", false, "capture packets and show the top remote hosts by traffic beside the chart (Linux, needs root or CAP_NET_RAW)")
+	numeric := flag.Bool("numeric", false, "show addresses in the --capture top-talkers panel without looking up host names")
+	captureIface := flag.String("capture-iface", "", "interface to capture packets on for --capture, --dscp and the o process list (default: all)")
+	var groups groupFlag
+	var panes paneFlag
+	flag.Var(&groups, "group", "sum interfaces into a named group, e.g. LAN=eth1,eth2,wlan0 (repeatable; the first group is charted)")
+	flag.Var(&panes, "pane", "show a plugin pane beside the chart: NAME=COMMAND, a shell command printing lines or JSON rows (repeatable)")
+	paneInterval := flag.Duration("pane-interval", 10*time.Second, "how often to re-run the --pane commands")
+	sessionPath := flag.String("session", autoPath, "snapshot the chart, stats and settings to this file every 30s, to restore after a crash (\"auto\" for the user cache directory, \"\" to disable)")
+	iperfHost := flag.String("iperf", "", "run an iperf3 client test against this server and chart it live")
+	iperfArgs := flag.String("iperf-args", "", "extra iperf3 arguments, e.g. \"-R -t 20\"")
+	glyphs := flag.String("glyphs", chart.GlyphNameAuto, "cell glyphs: auto (detect terminal support), braille, block or ascii")
+	flag.StringVar(glyphs, "charset", chart.GlyphNameAuto, "cell glyphs (alias for --glyphs)")
+	theme := flag.String("theme", "", "chart colors: "+strings.Join(chart.ThemeNames(), ", ")+" (T cycles them)")
+	color := flag.String("color", chart.ColorNameAuto, "color depth: auto (detect terminal support), truecolor, 256, 16 or none")
+	noColor := flag.Bool("no-color", false, "disable colors, the same as --color none (NO_COLOR is honored too)")
+	configPath := flag.String("config", autoPath, "preferences file W saves to and peaks starts with (\"auto\" for config.toml in the user config directory, empty to disable)")
+	pprofAddr := flag.String("pprof", "", "serve net/http/pprof and runtime metrics on this address (e.g. :6060)")
 	flag.BoolVar(showVersion, "v", false, "show version information (shorthand)")
 	flag.Parse()
 
@@ -604,17 +2830,450 @@ func main() {
 		return
 	}
 
-	// Run in compact mode or full mode
-	if *compactMode {
-		runCompactMode(*compactOverlay, *compactTime, *compactSize)
+	sourceSet := false
+	flag.Visit(func(f *flag.Flag) { sourceSet = sourceSet || f.Name == "source" })
+	if *demo || (*record && !sourceSet) {
+		*source = monitor.SourceDemo
+	}
+	if *stdin || *input != "" {
+		*source = monitor.SourceStdin
+	}
+	if *promURL != "" {
+		if *promQuery == "" {
+			fmt.Fprintf(os.Stderr, "Error: --prom-url needs a --query\n")
+			os.Exit(1)
+		}
+		if *remote != monitor.SourcePrometheus {
+			*source = monitor.SourcePrometheus
+		}
+	} else if *promQuery != "" || *promQueryUp != "" {
+		fmt.Fprintf(os.Stderr, "Error: --query and --query-up need a --prom-url\n")
+		os.Exit(1)
+	}
+	if *snmpTarget != "" {
+		if *snmpIfIndex <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --snmp needs an --ifindex (list them with snmpwalk -v2c -c COMMUNITY HOST ifName)\n")
+			os.Exit(1)
+		}
+		if *remote != monitor.SourceSNMP {
+			*source = monitor.SourceSNMP
+		}
+	} else if *snmpIfIndex != 0 {
+		fmt.Fprintf(os.Stderr, "Error: --ifindex needs an --snmp agent\n")
+		os.Exit(1)
+	}
+	if len(panes) > 0 && *paneInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --pane-interval must be positive\n")
+		os.Exit(1)
+	}
+	if *remote != "" && *compactMode {
+		fmt.Fprintf(os.Stderr, "Error: --remote and --compact are mutually exclusive\n")
+		os.Exit(1)
+	}
+	if *charts != "" && (*source != monitor.SourceNetwork || *remote != "" || *replay != "" || *compactMode || *accessible) {
+		fmt.Fprintf(os.Stderr, "Error: --charts needs the live network source in the full-screen chart, without --remote\n")
+		os.Exit(1)
+	}
+	if *replay != "" && (sourceSet || *demo || *stdin || *input != "" || *promURL != "" || *snmpTarget != "" || *remote != "" || *record || *compactMode || *accessible) {
+		fmt.Fprintf(os.Stderr, "Error: --replay plays back recorded history in the full-screen chart; it replaces the live source\n")
+		os.Exit(1)
+	}
+	if *source == monitor.SourceStdin && *compactMode {
+		fmt.Fprintf(os.Stderr, "Error: --stdin and --input can't be used with --compact\n")
+		os.Exit(1)
+	}
+
+	configFile, cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := options{
+		config:      cfg,
+		configPath:  configFile,
+		source:      *source,
+		pprofAddr:   *pprofAddr,
+		glyphs:      *glyphs,
+		theme:       *theme,
+		color:       *color,
+		dnsHost:     *dnsHost,
+		dnsInterval: *dnsInterval,
+		dnsAlert:    *dnsAlert,
+
+		speedTestURL:   *speedTestURL,
+		speedTestEvery: *speedTestEvery,
+		speedTestLog:   *speedTestLog,
+
+		iperfHost: *iperfHost,
+		iperfArgs: *iperfArgs,
+
+		conntrackAlert: *conntrackAlert,
+		markDrops:      *markDrops,
+		wifi:           *wifi,
+		groups:         groups,
+		panes:          panes,
+		paneInterval:   *paneInterval,
+		sessionPath:    *sessionPath,
+
+		dscp:         *dscp,
+		captureIface: *captureIface,
+		capture:      *capture,
+		numeric:      *numeric,
+		ledgerPath:   *ledgerPath,
+		historyDir:   *historyDir,
+		replay:       *replay,
+		retention:    retention,
+
+		cycleStart:      *cycleStart,
+		projection:      *projection,
+		interfaceTotals: *interfaceTotals,
+		screenshotDir:   *screenshotDir,
+		smooth:          *smooth,
+		units:           *units,
+		sideBySide:      *sideBySide,
+		ruler:           *ruler,
+		timeAxis:        *timeAxis,
+		labels:          *labels,
+		record:          *record,
+		seed:            *seed,
+		remote:          *remote,
+		stack:           parseInterfaceList(*charts),
+		promURL:         *promURL,
+		promQuery:       *promQuery,
+		promQueryUp:     *promQueryUp,
+		inputPath:       *input,
+		snmpTarget:      *snmpTarget,
+		snmpCommunity:   *snmpCommunity,
+		snmpIfIndex:     *snmpIfIndex,
+		report:          *report,
+		reportHTML:      *reportHTML,
+		smtp: accounting.SMTPConfig{
+			Addr:     *smtpAddr,
+			Username: *smtpUser,
+			Password: os.Getenv(smtpPasswordEnv),
+			From:     *smtpFrom,
+		},
+	}
+	for _, to := range strings.Split(*smtpTo, ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			opts.smtp.To = append(opts.smtp.To, to)
+		}
+	}
+	if gutter, err := chart.ParseGutter(*axisGutter); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	} else {
-		p := tea.NewProgram(
-			initialModel(),
-			tea.WithAltScreen(),
-		)
-		if _, err := p.Run(); err != nil {
-			fmt.Printf("Error running program: %v", err)
+		opts.gutter = gutter
+	}
+	opts.gridlines = *gridlines
+	if opts.statsWindows, err = ui.ParseStatsWindows(*statsWindows); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --stats-windows: %v\n", err)
+		os.Exit(1)
+	}
+	if *maxScale != "" {
+		if opts.maxScale, err = alerts.ParseRate(*maxScale); err != nil || opts.maxScale == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --max-scale: invalid rate %q (expected e.g. 100MB/s)\n", *maxScale)
+			os.Exit(1)
+		}
+	}
+	gutterSet := false
+	flag.Visit(func(f *flag.Flag) { gutterSet = gutterSet || f.Name == "axis-gutter" })
+	if *gridlines && !gutterSet {
+		// The gridlines are read off their labels in the gutter
+		opts.gutter = chart.GutterLeft
+	}
+	if *header {
+		var err error
+		if opts.headerFormat, err = parseHeaderFormat(*headerFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if opts.cycleStart < 1 || opts.cycleStart > 31 {
+		fmt.Fprintf(os.Stderr, "Error: --cycle-start must be a day of the month (1-31)\n")
+		os.Exit(1)
+	}
+	if _, err := accounting.ParseProjection(opts.projection); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *quota == "" {
+		*quota = opts.config.Quota
+	}
+	if *quotaPeriod == "" {
+		*quotaPeriod = opts.config.QuotaPeriod
+	}
+	if *quotaPeriod == "" {
+		*quotaPeriod = accounting.QuotaMonthly
+	}
+	if opts.quotaPeriod, err = accounting.ParseQuotaPeriod(*quotaPeriod); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *prefixes == "" {
+		*prefixes = opts.config.Prefixes
+	}
+	if *prefixes == "" {
+		*prefixes = ui.PrefixesBinary
+	}
+	if err := applyPrefixes(*prefixes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --prefixes: %v\n", err)
+		os.Exit(1)
+	}
+	opts.prefixes = *prefixes
+	if *quota != "" {
+		if opts.quota, err = accounting.ParseSize(*quota, opts.prefixes == ui.PrefixesSI); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --quota: %v\n", err)
+			os.Exit(1)
+		}
+		if opts.ledgerPath == "" {
+			fmt.Fprintf(os.Stderr, "Error: --quota needs --ledger to keep the usage across runs\n")
+			os.Exit(1)
+		}
+	}
+	if _, err := ui.ParseUnits(opts.units); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --units: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.report != "" && opts.ledgerPath == "" {
+		fmt.Fprintf(os.Stderr, "Error: --report needs --ledger to record daily totals\n")
+		os.Exit(1)
+	}
+	if opts.dnsInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --dns-interval must be positive\n")
+		os.Exit(1)
+	}
+	if *accessible && *compactMode {
+		fmt.Fprintf(os.Stderr, "Error: --accessible and --compact are mutually exclusive\n")
+		os.Exit(1)
+	}
+	if *output != "" {
+		if *output != outputJSON && *output != outputCSV {
+			fmt.Fprintf(os.Stderr, "Error: --output must be json or csv\n")
+			os.Exit(1)
+		}
+		if *compactMode || *accessible || *replay != "" || *remote != "" {
+			fmt.Fprintf(os.Stderr, "Error: --output replaces the display; it can't be combined with --compact, --accessible, --replay or --remote\n")
+			os.Exit(1)
+		}
+	}
+	if *compactStdout {
+		if *compactMode || *accessible || *output != "" || *replay != "" || *remote != "" || *charts != "" {
+			fmt.Fprintf(os.Stderr, "Error: --compact-stdout replaces the display; it can't be combined with --compact, --accessible, --output, --replay, --remote or --charts\n")
+			os.Exit(1)
 		}
+	} else if *stream || *plain {
+		fmt.Fprintf(os.Stderr, "Error: --stream and --plain need --compact-stdout\n")
+		os.Exit(1)
+	}
+	if *alertUp == "" {
+		*alertUp = opts.config.AlertUp
+	}
+	if *alertDown == "" {
+		*alertDown = opts.config.AlertDown
+	}
+	for _, threshold := range []struct {
+		flag, value string
+		rate        *uint64
+	}{{"alert-up", *alertUp, &opts.alertThresholds.Upload}, {"alert-down", *alertDown, &opts.alertThresholds.Download}} {
+		if threshold.value == "" {
+			continue
+		}
+		if *threshold.rate, err = alerts.ParseRate(threshold.value); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --%s: %v\n", threshold.flag, err)
+			os.Exit(1)
+		}
+	}
+	if (*alertLog != "" || *alertNotify || *alertCmd != "") && opts.alertThresholds == (alerts.Thresholds{}) {
+		fmt.Fprintf(os.Stderr, "Error: --alert-log, --alert-notify and --alert-cmd need --alert-up or --alert-down\n")
+		os.Exit(1)
+	}
+	opts.alertLog, opts.alertCmd, opts.alertNotify = *alertLog, *alertCmd, *alertNotify
+	if *accessible && *announce <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --announce must be positive\n")
+		os.Exit(1)
+	}
+	if opts.iperfHost != "" && (*compactMode || *accessible || (opts.source != monitor.SourceNetwork && opts.source != "")) {
+		fmt.Fprintf(os.Stderr, "Error: --iperf needs the full-screen network chart\n")
+		os.Exit(1)
+	}
+	if opts.capture && (*compactMode || *accessible) {
+		fmt.Fprintf(os.Stderr, "Error: --capture needs the full-screen chart\n")
+		os.Exit(1)
+	}
+	if opts.wifi && (*compactMode || *accessible) {
+		fmt.Fprintf(os.Stderr, "Error: --wifi needs the full-screen chart\n")
+		os.Exit(1)
+	}
+	if _, _, err := chart.ParseGlyphSet(opts.glyphs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := chart.ParseTheme(opts.theme); opts.theme != "" && err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, _, err := chart.ParseColorProfile(opts.color); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *noColor {
+		if _, auto, _ := chart.ParseColorProfile(opts.color); !auto && opts.color != chart.ColorNameNone {
+			fmt.Fprintf(os.Stderr, "Error: --no-color and --color %s are contradictory\n", opts.color)
+			os.Exit(1)
+		}
+		opts.color = chart.ColorNameNone
+	}
+	opts.applyColorProfile()
+
+	// Run in headless, inline, accessible, compact or full mode
+	if *compactStdout {
+		window := time.Duration(*compactTime) * time.Minute
+		if err := runInlineMode(opts, window, *compactWidth, *plain, *stream, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *output != "" {
+		if err := runOutputMode(opts, *output, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *accessible {
+		if err := runAccessibleMode(opts, *announce, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *compactMode {
+		runCompactMode(opts, *compactOverlay, *compactTime, *compactSize)
+	} else if err := runFullScreen(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 }
 
+// runFullScreen runs the full-screen chart until it is quit. Errors are
+// returned rather than exiting, so the deferred cleanups always run.
+func runFullScreen(opts options) error {
+	// Deferred first so it runs last, after the other cleanups
+	defer recoverTerminal(resetFullScreen)
+
+	var replay *replayCollector
+	var collector monitor.Collector
+	var err error
+	if opts.replay != "" {
+		replay, err = newReplayCollector(opts, time.Now())
+		collector = replay
+	} else {
+		collector, err = newCollector(opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	if opts.pprofAddr != "" {
+		if err := startDebugServer(opts.pprofAddr); err != nil {
+			return err
+		}
+	}
+
+	if opts.interfaceTotals != "" {
+		// Fail early on an unwritable path or a source without interfaces
+		if err := writeInterfaceTotals(opts.interfaceTotals, collector); err != nil {
+			return err
+		}
+	}
+
+	m := initialModel(opts, collector)
+	if replay != nil {
+		m.attachReplay(replay)
+		// Played back samples are already recorded
+		opts.sessionPath, opts.ledgerPath, opts.historyDir, opts.report = "", "", "", ""
+	}
+	if opts.remote != "" {
+		remote, err := newRemoteCollector(opts)
+		if err != nil {
+			return fmt.Errorf("--remote: %w", err)
+		}
+		defer closeCollector(remote)
+		m.attachRemote(remote, opts.remote)
+	}
+	var sessionFile string
+	if opts.sessionPath != "" && !opts.record {
+		if sessionFile, err = resolveSessionPath(opts.sessionPath); err == nil {
+			err = m.openSession(sessionFile)
+		}
+		if err != nil {
+			return fmt.Errorf("--session: %w", err)
+		}
+	}
+	if m.ledger, err = newLedger(opts); err != nil {
+		return err
+	}
+	history, closeHistory, err := openHistory(opts)
+	if err != nil {
+		return err
+	}
+	defer closeHistory()
+	if m.history = history; history != nil {
+		history.SetInterval(m.tickSpan())
+		if err := m.loadMarkers(); err != nil {
+			return err
+		}
+	}
+	defer m.packets.Close()
+	if opts.dscp {
+		m.dscp = monitor.NewDSCPStats()
+		if err := m.packets.Add(m.dscp.Add); err != nil {
+			return err
+		}
+	}
+	if opts.capture {
+		if err := m.startTalkers(); err != nil {
+			return err
+		}
+	}
+
+	programOpts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithReportFocus()}
+	if (opts.source == monitor.SourceStdin && opts.inputPath == "") || opts.remote == monitor.SourceStdin {
+		// stdin carries the data, so read keys from the terminal
+		programOpts = append(programOpts, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(m, programOpts...)
+	stopReports, err := startReports(opts, func(err error) {
+		p.Send(reportErrorMsg{err: err})
+	})
+	if err != nil {
+		return err
+	}
+	defer stopReports()
+	// Bubble Tea quits on SIGTERM but not on a hangup, which would kill the
+	// process before the ledger below is flushed
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		if _, ok := <-hangup; ok {
+			p.Quit()
+		}
+	}()
+	_, err = p.Run()
+	if err == nil && sessionFile != "" {
+		// A clean exit leaves nothing to recover
+		removeSession(sessionFile)
+	}
+	if m.ledger != nil {
+		// Keep today's partial total for the next run
+		m.ledger.Flush()
+	}
+	if opts.interfaceTotals != "" {
+		writeInterfaceTotals(opts.interfaceTotals, collector)
+	}
+	if err != nil {
+		// Bubble Tea restores the terminal itself; repeat it in case it
+		// failed part way through
+		restoreTerminal(resetFullScreen)
+		return fmt.Errorf("running program: %w", err)
+	}
+	return nil
+}
@@ -1,12 +1,42 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/termenv"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/alerts"
 	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/config"
 	"github.com/marcodenic/peaks/internal/monitor"
 	"github.com/marcodenic/peaks/internal/ui"
+	"github.com/marcodenic/peaks/pkg/bandwidth"
+	"github.com/marcodenic/peaks/pkg/braillechart"
 )
 
 func TestNewBandwidthMonitor(t *testing.T) {
@@ -22,87 +52,4848 @@ func TestNewBandwidthMonitor(t *testing.T) {
 	}
 }
 
-func TestNewBrailleChart(t *testing.T) {
-	c := chart.NewBrailleChart(100)
-	if c == nil {
-		t.Fatal("NewBrailleChart returned nil")
+// procNetDev builds /proc/net/dev content with n veth interfaces plus loopback and eth0
+func procNetDev(n int, tick uint64) []byte {
+	var sb strings.Builder
+	sb.WriteString("Inter-|   Receive                                                |  Transmit\n")
+	sb.WriteString(" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n")
+	fmt.Fprintf(&sb, "    lo: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", tick*100, tick*100)
+	fmt.Fprintf(&sb, "  eth0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", tick*2000, tick*1000)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "veth%04x: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", i, tick*uint64(i+1), tick*uint64(i+2))
 	}
+	return []byte(sb.String())
+}
 
-	// Test adding data points
-	c.AddDataPoint(1024, 2048)
-	c.AddDataPoint(2048, 4096)
+// fakeCounterSource parses in-memory /proc/net/dev content
+type fakeCounterSource struct {
+	data []byte
+}
 
-	// Test rendering
-	output := c.Render()
-	if output == "" {
-		t.Fatal("Chart render returned empty string")
+func (f *fakeCounterSource) ReadCounters(dst []monitor.InterfaceCounters, skip func(name []byte) bool) ([]monitor.InterfaceCounters, error) {
+	return monitor.ParseProcNetDev(f.data, dst, skip), nil
+}
+
+func TestParseProcNetDev(t *testing.T) {
+	counters := monitor.ParseProcNetDev(procNetDev(2, 1), nil, nil)
+	if len(counters) != 4 {
+		t.Fatalf("Expected 4 interfaces, got %d", len(counters))
+	}
+	if counters[1].Name != "eth0" || counters[1].BytesRecv != 2000 || counters[1].BytesSent != 1000 {
+		t.Errorf("Unexpected eth0 counters: %+v", counters[1])
 	}
 
-	// Test reset
-	c.Reset()
+	// Skipped interfaces are listed by name only
+	counters = monitor.ParseProcNetDev(procNetDev(2, 1), counters[:0], func(name []byte) bool {
+		return strings.HasPrefix(string(name), "veth")
+	})
+	if len(counters) != 4 || counters[2].Name != "veth0000" || counters[2].BytesRecv != 0 || counters[1].BytesRecv != 2000 {
+		t.Errorf("Expected the veths listed without counters, got %+v", counters)
+	}
 }
 
-func TestUIComponents(t *testing.T) {
-	components := ui.NewComponents()
-	if components == nil {
-		t.Fatal("NewComponents returned nil")
+func TestBandwidthMonitorWithSource(t *testing.T) {
+	src := &fakeCounterSource{data: procNetDev(50, 1)}
+	m := monitor.NewBandwidthMonitorWithSource(src)
+	m.SetInterfaceFilter(func(name string) bool {
+		return name == "eth0"
+	})
+
+	// First sample establishes the baseline
+	time.Sleep(20 * time.Millisecond)
+	if _, _, err := m.GetCurrentRates(); err != nil {
+		t.Fatalf("GetCurrentRates failed: %v", err)
 	}
 
-	stats := components.GetStats()
-	if stats == nil {
-		t.Fatal("GetStats returned nil")
+	time.Sleep(20 * time.Millisecond)
+	src.data = procNetDev(50, 2)
+	upload, download, err := m.GetCurrentRates()
+	if err != nil {
+		t.Fatalf("GetCurrentRates failed: %v", err)
+	}
+	if upload == 0 || download == 0 {
+		t.Error("Expected non-zero rates for eth0")
+	}
+	if download <= upload {
+		t.Errorf("Expected download (%d) above upload (%d) for eth0", download, upload)
 	}
 
-	// Test updating stats
-	stats.Update(1024, 2048)
-	if stats.PeakUpload != 1024 {
-		t.Errorf("Expected PeakUpload 1024, got %d", stats.PeakUpload)
+	// Filtered interfaces that disappear are forgotten too, so they do not
+	// come back when the filter is widened
+	time.Sleep(20 * time.Millisecond)
+	src.data = procNetDev(2, 3)
+	if _, _, err := m.GetCurrentRates(); err != nil {
+		t.Fatalf("GetCurrentRates failed: %v", err)
 	}
-	if stats.PeakDownload != 2048 {
-		t.Errorf("Expected PeakDownload 2048, got %d", stats.PeakDownload)
+	m.SetInterfaceFilter(func(string) bool { return true })
+	if totals := m.InterfaceTotals(); len(totals) != 4 {
+		t.Errorf("Expected only the 4 remaining interfaces, got %d", len(totals))
 	}
 }
 
-func TestFormatters(t *testing.T) {
-	tests := []struct {
-		input    uint64
-		expected string
+func TestCollectorInterface(t *testing.T) {
+	var c monitor.Collector = monitor.NewBandwidthMonitor()
+
+	series, err := c.Sample()
+	if err != nil {
+		t.Logf("Sample error (expected on some systems): %v", err)
+		return
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(series))
+	}
+	if series[0].Name != monitor.SeriesUpload || series[1].Name != monitor.SeriesDownload {
+		t.Errorf("Unexpected series names: %q, %q", series[0].Name, series[1].Name)
+	}
+
+	upload, download := monitor.SplitSeries([]monitor.Series{{Value: 1}, {Value: 2}})
+	if upload != 1 || download != 2 {
+		t.Errorf("SplitSeries = (%d, %d), expected (1, 2)", upload, download)
+	}
+	upload, download = monitor.SplitSeries(nil)
+	if upload != 0 || download != 0 {
+		t.Errorf("SplitSeries(nil) = (%d, %d), expected (0, 0)", upload, download)
+	}
+}
+
+func TestNewCollector(t *testing.T) {
+	for _, source := range []string{monitor.SourceNetwork, monitor.SourceDisk, monitor.SourceCPU, monitor.SourceDemo} {
+		c, err := monitor.NewCollector(source)
+		if err != nil {
+			t.Fatalf("NewCollector(%q) returned error: %v", source, err)
+		}
+		series, err := c.Sample()
+		if err != nil {
+			t.Logf("Sample error for %s (expected on some systems): %v", source, err)
+			continue
+		}
+		if len(series) != 2 {
+			t.Errorf("Source %s returned %d series, expected 2", source, len(series))
+		}
+	}
+
+	if _, err := monitor.NewCollector("bogus"); err == nil {
+		t.Error("Expected error for unknown source")
+	}
+	if !monitor.IsWSL() {
+		if _, err := monitor.NewCollector(monitor.SourceWindowsHost); err == nil {
+			t.Error("Expected error for Windows host source outside WSL")
+		}
+	}
+}
+
+func TestFirewallCounters(t *testing.T) {
+	nft := []byte(`{"nftables": [
+		{"metainfo": {"version": "1.0.6"}},
+		{"counter": {"family": "inet", "name": "vlan10_out", "table": "filter", "handle": 3, "packets": 12, "bytes": 3456}},
+		{"counter": {"family": "inet", "name": "vlan10_in", "table": "filter", "handle": 4, "packets": 40, "bytes": 78901}}
+	]}`)
+	if n, err := monitor.ParseNftCounter(nft, "filter", "vlan10_in"); err != nil || n != 78901 {
+		t.Errorf("ParseNftCounter = %d, %v; expected 78901", n, err)
+	}
+	if _, err := monitor.ParseNftCounter(nft, "filter", "missing"); err == nil {
+		t.Error("Expected an error for a missing nft counter")
+	}
+
+	iptables := []byte(`Chain FORWARD (policy ACCEPT 10 packets, 1000 bytes)
+    pkts      bytes target     prot opt in     out     source               destination
+     120    45000            all  --  *      *       10.0.0.5             0.0.0.0/0            /* host-a-out */
+     300   900000            all  --  *      *       0.0.0.0/0            10.0.0.5             /* host-a-in */
+`)
+	for _, tc := range []struct {
+		comment  string
+		expected uint64
+	}{{"", 946000}, {"host-a-out", 45000}, {"host-a-in", 900000}} {
+		if n, err := monitor.ParseIptablesChain(iptables, tc.comment); err != nil || n != tc.expected {
+			t.Errorf("ParseIptablesChain(%q) = %d, %v; expected %d", tc.comment, n, err, tc.expected)
+		}
+	}
+	if _, err := monitor.ParseIptablesChain(iptables, "host-b"); err == nil {
+		t.Error("Expected an error for an unknown rule comment")
+	}
+
+	for _, source := range []string{"nft:filter/only_one", "nft:bad,filter/x", "iptables:,FORWARD"} {
+		if !monitor.IsFirewallSource(source) {
+			t.Errorf("Expected %q to be a firewall source", source)
+		}
+		if _, err := monitor.NewCollector(source); err == nil {
+			t.Errorf("Expected an error for malformed source %q", source)
+		}
+	}
+}
+
+// ipv4Frame builds an Ethernet frame carrying a UDP/IPv4 packet with the given DSCP
+func ipv4Frame(dscp uint8, vlan bool, payload int) []byte {
+	frame := make([]byte, 12, 64+payload)
+	if vlan {
+		frame = append(frame, 0x81, 0x00, 0x00, 0x0a)
+	}
+	frame = append(frame, 0x08, 0x00)
+	ip := []byte{0x45, dscp << 2, 0, 0, 0, 0, 0x40, 0, 64, monitor.ProtocolUDP, 0, 0, 192, 168, 1, 10, 1, 1, 1, 1}
+	udp := []byte{0xd4, 0x31, 0x00, 0x35, 0, 0, 0, 0}
+	frame = append(append(frame, ip...), udp...)
+	return append(frame, make([]byte, payload)...)
+}
+
+func TestPacketParsing(t *testing.T) {
+	var p monitor.PacketInfo
+	frame := ipv4Frame(46, true, 100)
+	if !monitor.ParseEthernetFrame(frame, &p) {
+		t.Fatal("Failed to parse a VLAN-tagged IPv4 frame")
+	}
+	if p.Version != 4 || p.DSCP != 46 || p.Protocol != monitor.ProtocolUDP || p.DstPort != 53 || p.SrcPort != 54321 ||
+		p.Src.String() != "192.168.1.10" || p.Dst.String() != "1.1.1.1" || p.Length != len(frame) {
+		t.Errorf("Unexpected IPv4 packet info %+v", p)
+	}
+
+	ipv6 := make([]byte, 48)
+	ipv6[0], ipv6[1] = 0x60|34>>2, (34&0x3)<<6 // version 6, traffic class AF41
+	ipv6[6] = monitor.ProtocolTCP
+	ipv6[23], ipv6[39] = 1, 2
+	ipv6[40], ipv6[41], ipv6[42], ipv6[43] = 0x01, 0xbb, 0xc0, 0x00
+	if !monitor.ParseIPPacket(ipv6, &p) {
+		t.Fatal("Failed to parse an IPv6 packet")
+	}
+	if p.Version != 6 || p.DSCP != 34 || p.SrcPort != 443 || p.Dst.String() != "::2" {
+		t.Errorf("Unexpected IPv6 packet info %+v", p)
+	}
+
+	if monitor.ParseEthernetFrame([]byte{1, 2, 3}, &p) || monitor.ParseIPPacket([]byte{0x45, 0}, &p) {
+		t.Error("Expected truncated packets to be rejected")
+	}
+	arp := ipv4Frame(0, false, 0)
+	arp[12], arp[13] = 0x08, 0x06
+	if monitor.ParseEthernetFrame(arp, &p) {
+		t.Error("Expected non-IP frames to be rejected")
+	}
+
+	capture, err := monitor.StartCapture("", func(*monitor.PacketInfo) {})
+	if err != nil {
+		t.Logf("Packet capture unavailable (expected without privileges): %v", err)
+	} else {
+		capture.Close()
+	}
+}
+
+func TestDSCPBreakdown(t *testing.T) {
+	for dscp, name := range map[uint8]string{0: "BE", 46: "EF", 34: "AF41", 8: "CS1", 5: "DSCP5"} {
+		if got := monitor.DSCPName(dscp); got != name {
+			t.Errorf("DSCPName(%d) = %q, expected %q", dscp, got, name)
+		}
+	}
+
+	stats := monitor.NewDSCPStats()
+	var p monitor.PacketInfo
+	for i := 0; i < 10; i++ {
+		monitor.ParseEthernetFrame(ipv4Frame(46, false, 958), &p)
+		p.Outgoing = true
+		stats.Add(&p)
+		monitor.ParseEthernetFrame(ipv4Frame(0, false, 9958), &p)
+		p.Outgoing = false
+		stats.Add(&p)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	rates := stats.Rates()
+	if len(rates) != 2 || rates[0].Name != "BE" || rates[1].Name != "EF" {
+		t.Fatalf("Unexpected class rates %+v", rates)
+	}
+	if rates[0].Download == 0 || rates[0].Upload != 0 || rates[1].Upload == 0 || rates[1].Download != 0 {
+		t.Errorf("Expected BE download and EF upload only, got %+v", rates)
+	}
+
+	m, _ := newTestModel(t)
+	m.dscp = stats
+	m.dscpRates = rates
+	if status := m.dscpStatus(); !strings.HasPrefix(status, "DSCP: BE ↓") || !strings.Contains(status, ", EF ↓") {
+		t.Errorf("Unexpected DSCP status %q", status)
+	}
+}
+
+func TestLedgerMidnightRollover(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Time zone database unavailable: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "daily.jsonl")
+	store := accounting.NewFileStore(path)
+	ledger := accounting.NewLedger(store)
+	ledger.SetLocation(loc)
+
+	// DST starts at 02:00 on 2025-03-09, so that day is only 23 hours long
+	ledger.Add(time.Date(2025, 3, 9, 0, 0, 1, 0, loc), 100, 1000)
+	ledger.Add(time.Date(2025, 3, 9, 23, 59, 59, 0, loc), 100, 1000)
+	if today := ledger.Today(); today.Date != "2025-03-09" || today.Download != 2000 {
+		t.Errorf("Unexpected day total %+v", today)
+	}
+	if err := ledger.Add(time.Date(2025, 3, 10, 0, 0, 1, 0, loc), 5, 50); err != nil {
+		t.Fatal(err)
+	}
+
+	days, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 || days[0] != (accounting.DayTotal{Date: "2025-03-09", Upload: 200, Download: 2000}) {
+		t.Errorf("Expected the closed-out day in the store, got %+v", days)
+	}
+
+	// A restart later the same day carries on from the flushed partial total
+	if err := ledger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	restarted := accounting.NewLedger(store)
+	restarted.SetLocation(loc)
+	restarted.Add(time.Date(2025, 3, 10, 9, 0, 0, 0, loc), 5, 50)
+	if today := restarted.Today(); today.Upload != 10 || today.Download != 100 {
+		t.Errorf("Expected today's total to include the earlier run, got %+v", today)
+	}
+
+	// Moving east across midnight starts the next day without waiting for 00:00 here
+	restarted.SetLocation(time.FixedZone("UTC+14", 14*3600))
+	restarted.Add(time.Date(2025, 3, 10, 12, 0, 0, 0, loc), 1, 1)
+	if today := restarted.Today(); today.Date != "2025-03-11" {
+		t.Errorf("Expected the time zone change to roll the day over, got %+v", today)
+	}
+	restarted.Flush()
+	days, _ = store.Load()
+	if len(days) != 3 || days[1] != (accounting.DayTotal{Date: "2025-03-10", Upload: 10, Download: 100}) {
+		t.Errorf("Unexpected stored days %+v", days)
+	}
+
+	// A running peaks flushes every FlushInterval, so a kill loses little of
+	// the day, and the next day start merges the flushes into one record
+	killed := accounting.NewLedger(store)
+	killed.SetLocation(time.UTC)
+	start := time.Date(2025, 3, 12, 8, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		killed.Add(start.Add(time.Duration(i)*accounting.FlushInterval), 1, 10)
+	}
+	days, _ = store.Load()
+	if last := days[len(days)-1]; last != (accounting.DayTotal{Date: "2025-03-12", Upload: 3, Download: 30}) {
+		t.Errorf("Expected the periodic flushes in the store without a final Flush, got %+v", last)
+	}
+	next := accounting.NewLedger(store)
+	next.SetLocation(time.UTC)
+	next.Add(start.Add(24*time.Hour), 1, 1)
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != len(days) {
+		t.Errorf("Expected one record per day after compacting, got %d lines for %d days", lines, len(days))
+	}
+
+	m, _ := newTestModel(t)
+	m.ledger = restarted
+	if status := m.ledgerStatus(); status != "Today: ↓"+m.formatTotal(1)+" ↑"+m.formatTotal(1) {
+		t.Errorf("Unexpected ledger status %q", status)
+	}
+}
+
+// fakeSMTPServer accepts one message on a local port and returns its data
+func fakeSMTPServer(t *testing.T) (addr string, message <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "220 localhost ESMTP\r\n")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				received <- data.String()
+				fmt.Fprintf(conn, "250 queued\r\n")
+			case inData:
+				data.WriteString(line)
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprintf(conn, "354 go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprintf(conn, "221 bye\r\n")
+				return
+			default:
+				fmt.Fprintf(conn, "250 ok\r\n")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestEmailReport(t *testing.T) {
+	now := time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC) // A Monday, in any time zone
+	days := []accounting.DayTotal{
+		{Date: "2025-06-02", Upload: 1 << 20, Download: 2 << 20},
+		{Date: "2025-06-08", Upload: 3 << 20, Download: 4 << 20},
+		{Date: "2025-06-09", Upload: 1, Download: 1}, // Today: not reported yet
+	}
+
+	daily := accounting.BuildReport(days, accounting.ReportDaily, now)
+	if len(daily.Days) != 1 || daily.Days[0].Date != "2025-06-08" || daily.Total.Download != 4<<20 {
+		t.Errorf("Unexpected daily report %+v", daily)
+	}
+	weekly := accounting.BuildReport(days, accounting.ReportWeekly, now)
+	if len(weekly.Days) != 7 || weekly.Days[0].Date != "2025-06-02" || weekly.Days[3].Download != 0 {
+		t.Errorf("Unexpected weekly report days %+v", weekly.Days)
+	}
+	if weekly.Total.Upload != 4<<20 || weekly.Total.Download != 6<<20 {
+		t.Errorf("Unexpected weekly total %+v", weekly.Total)
+	}
+	text := weekly.Text(ui.FormatBytes)
+	if !strings.Contains(text, "2025-06-02 to 2025-06-08") || !strings.Contains(text, "6.00 MB") {
+		t.Errorf("Unexpected text report:\n%s", text)
+	}
+	html, err := weekly.HTML(ui.FormatBytes)
+	if err != nil || !strings.Contains(html, "<td>Total</td>") {
+		t.Errorf("Unexpected HTML report (%v):\n%s", err, html)
+	}
+
+	store := accounting.NewFileStore(filepath.Join(t.TempDir(), "daily.jsonl"))
+	for _, day := range days {
+		if err := store.Append(day); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr, message := fakeSMTPServer(t)
+	config := accounting.SMTPConfig{Addr: addr, From: "peaks@example.com", To: []string{"me@example.com"}}
+	reporter, err := accounting.NewReporter(store, config, accounting.ReportDaily, false, ui.FormatBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.Send(now); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-message:
+		if !strings.Contains(msg, "Subject: PEAKS daily usage") || !strings.Contains(msg, "4.00 MB") {
+			t.Errorf("Unexpected message:\n%s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No message received")
+	}
+
+	if _, err := accounting.NewReporter(store, config, "monthly", false, ui.FormatBytes); err == nil {
+		t.Error("Expected an error for an unknown period")
+	}
+	config.To = nil
+	if _, err := accounting.NewReporter(store, config, accounting.ReportDaily, false, ui.FormatBytes); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+}
+
+func TestUsageProjection(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		now        time.Time
+		startDay   int
+		start, end time.Time
 	}{
-		{1024, "1.00 KB/s"},
-		{1048576, "1.00 MB/s"},
-		{1073741824, "1.00 GB/s"},
+		{date(2025, 6, 11), 1, date(2025, 6, 1), date(2025, 7, 1)},
+		{date(2025, 3, 5), 15, date(2025, 2, 15), date(2025, 3, 15)},
+		{date(2025, 2, 20), 31, date(2025, 1, 31), date(2025, 2, 28)}, // Clamped to short months
+		{date(2025, 12, 31), 31, date(2025, 12, 31), date(2026, 1, 31)},
+	} {
+		start, end := accounting.CycleBounds(tc.now, tc.startDay)
+		if !start.Equal(tc.start) || !end.Equal(tc.end) {
+			t.Errorf("CycleBounds(%v, %d) = %v, %v; want %v, %v", tc.now, tc.startDay, start, end, tc.start, tc.end)
+		}
 	}
 
-	for _, test := range tests {
-		result := ui.FormatBandwidth(test.input)
-		if result != test.expected {
-			t.Errorf("FormatBandwidth(%d) = %s, expected %s", test.input, result, test.expected)
+	// Ten days into a 30-day cycle: 10 GB a day, rising to 20 GB for the last three
+	const gb = 1 << 30
+	var days []accounting.DayTotal
+	for d := 1; d <= 10; d++ {
+		total := uint64(10 * gb)
+		if d > 7 {
+			total = 20 * gb
 		}
+		days = append(days, accounting.DayTotal{Date: fmt.Sprintf("2025-06-%02d", d), Upload: total / 4, Download: total - total/4})
+	}
+	now := date(2025, 6, 11)
+	linear := accounting.Project(days, now, 1, accounting.ProjectLinear)
+	if linear.Used != 130*gb || linear.Projected != 390*gb {
+		t.Errorf("Linear projection = %d / %d GB, want 130 / 390", linear.Used/gb, linear.Projected/gb)
+	}
+	recent := accounting.Project(days, now, 1, accounting.ProjectRecent)
+	if want := 130.0 + 100.0/7*20; math.Abs(float64(recent.Projected)/gb-want) > 0.01 {
+		t.Errorf("Recent projection = %.2f GB, want %.2f", float64(recent.Projected)/gb, want)
+	}
+	// Without a complete day recorded, recent falls back to linear
+	firstDay := accounting.Project(days[:1], date(2025, 6, 1).Add(12*time.Hour), 1, accounting.ProjectRecent)
+	if firstDay.Projected != 600*gb {
+		t.Errorf("First-day projection = %d GB, want 600", firstDay.Projected/gb)
+	}
+	if _, err := accounting.ParseProjection("quadratic"); err == nil {
+		t.Error("Expected an error for an unknown projection")
 	}
 
-	// Test duration formatting
-	duration := 125 * time.Second
-	result := ui.FormatDuration(duration)
-	expected := "2m5s"
-	if result != expected {
-		t.Errorf("FormatDuration(%v) = %s, expected %s", duration, result, expected)
+	m, _ := newTestModel(t)
+	store := accounting.NewFileStore(filepath.Join(t.TempDir(), "daily.jsonl"))
+	for _, day := range days {
+		if err := store.Append(day); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.ledger = accounting.NewLedger(store)
+	m.ledger.SetLocation(time.UTC)
+	updated, _ := m.Update(projectionTickMsg{})
+	m = updated.(model)
+	if !strings.Contains(m.projectionStatus(), ", on track for ") {
+		t.Errorf("Expected a projection in the statusbar, got %q", m.projectionStatus())
 	}
 }
 
-func TestKeyMap(t *testing.T) {
-	keys := ui.DefaultKeyMap()
+func TestQuota(t *testing.T) {
+	const gb = 1 << 30
+	for input, expected := range map[string]uint64{"500GB": 500 * gb, "1.5T": 1536 * gb, "800GiB": 800 * gb, "2048": 2048} {
+		if size, err := accounting.ParseSize(input, false); err != nil || size != expected {
+			t.Errorf("ParseSize(%q) = %d, %v; expected %d", input, size, err, expected)
+		}
+	}
+	for _, bad := range []string{"", "0", "-5GB", "lots", "5PB"} {
+		if _, err := accounting.ParseSize(bad, false); err == nil {
+			t.Errorf("Expected ParseSize(%q) to fail", bad)
+		}
+	}
+	// With --prefixes si sizes are decimal, unless the suffix says otherwise
+	for input, expected := range map[string]uint64{"500GB": 500e9, "1.5T": 1.5e12, "800GiB": 800 * gb} {
+		if size, err := accounting.ParseSize(input, true); err != nil || size != expected {
+			t.Errorf("ParseSize(%q, decimal) = %d, %v; expected %d", input, size, err, expected)
+		}
+	}
+	if _, err := accounting.ParseQuotaPeriod("weekly"); err == nil {
+		t.Error("Expected an error for an unknown quota period")
+	}
 
-	// Test that all keys are properly initialized
-	if len(keys.Reset.Keys()) == 0 {
-		t.Error("Reset key binding not initialized")
+	// Half way through the day with 2 GB used, the day is on track for 4 GB
+	store := accounting.NewFileStore(filepath.Join(t.TempDir(), "daily.jsonl"))
+	ledger := accounting.NewLedger(store)
+	ledger.SetLocation(time.UTC)
+	noon := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	if err := ledger.Add(noon, gb/2, 3*gb/2); err != nil {
+		t.Fatal(err)
 	}
-	if len(keys.Pause.Keys()) == 0 {
-		t.Error("Pause key binding not initialized")
+	day, err := ledger.ProjectDay(noon)
+	if err != nil || day.Used != 2*gb || day.Projected != 4*gb || !day.End.Equal(noon.Add(12*time.Hour)) {
+		t.Errorf("ProjectDay = %+v, %v, want 2 GB used of 4 GB projected", day, err)
 	}
-	if len(keys.Stats.Keys()) == 0 {
-		t.Error("Stats key binding not initialized")
+	// Another peaks recording to the same ledger counts once it has flushed
+	other := accounting.NewLedger(store)
+	other.SetLocation(time.UTC)
+	other.Add(noon, gb, 0)
+	other.Add(noon.Add(accounting.FlushInterval), 0, gb)
+	if both, _ := ledger.ProjectDay(noon); both.Used != 4*gb {
+		t.Errorf("Expected the other run's flushed usage in the quota, got %+v", both)
 	}
-	if len(keys.Quit.Keys()) == 0 {
-		t.Error("Quit key binding not initialized")
+
+	m, _ := newTestModel(t)
+	m.ledger, m.quota, m.quotaPeriod = ledger, 8*gb, accounting.QuotaDaily
+	m.quotaUsage = day
+	if status := m.quotaStatus(); status != "Quota: ██░░░░░░░░ 25% of 8.00 GB, on track for 4.00 GB" {
+		t.Errorf("Unexpected quota status %q", status)
+	}
+	m.quota = 3 * gb
+	if status := m.quotaStatus(); !strings.Contains(status, "██████░░░░ 66% of 3.00 GB") {
+		t.Errorf("Unexpected quota status %q", status)
+	}
+	// The monthly quota follows the billing cycle projection, and replaces it
+	// in the statusbar
+	if err := ledger.Add(time.Now(), gb, gb); err != nil {
+		t.Fatal(err)
+	}
+	m.quotaPeriod = accounting.QuotaMonthly
+	updated, _ := m.Update(projectionTickMsg{})
+	m = updated.(model)
+	if m.quotaUsage != m.projection || m.quotaUsage.Used < 2*gb {
+		t.Errorf("Expected the monthly quota to use the cycle projection, got %+v", m.quotaUsage)
+	}
+	updated, _ = m.Update(tea.WindowSizeMsg{Width: 250, Height: 24})
+	m = updated.(model)
+	m.updateStatusbar()
+	if status := ansi.Strip(m.View()); !strings.Contains(status, "Quota: ") || strings.Contains(status, "Cycle: ") {
+		t.Errorf("Expected the quota in place of the cycle projection:\n%s", status)
+	}
+}
+
+func TestUsageAccounting(t *testing.T) {
+	if _, err := accounting.ParseUsagePeriod("weekly"); err == nil {
+		t.Error("Expected an error for an unknown usage period")
+	}
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.Retention{Raw: time.Hour, Minutes: 48 * time.Hour}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	for _, s := range []struct {
+		at               time.Time
+		upload, download uint64
+	}{
+		{time.Date(2025, 6, 8, 10, 0, 30, 0, time.UTC), 100, 1000}, // Folded into an hourly total
+		{time.Date(2025, 6, 10, 23, 59, 59, 0, time.UTC), 10, 20},  // Folded into a minute aggregate
+		{time.Date(2025, 6, 11, 11, 30, 0, 0, time.UTC), 1, 2},     // Still raw
+	} {
+		if err := history.Record(s.at, s.upload, s.download); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := history.Compact(now); err != nil {
+		t.Fatal(err)
+	}
+	if minutes, _ := history.Minutes(); len(minutes) != 1 {
+		t.Errorf("Expected only the recent minute to be kept, got %+v", minutes)
+	}
+
+	host := history.Host()
+	days, err := history.Usage(host, accounting.UsageDaily, now, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]uint64{{100, 1000}, {0, 0}, {10, 20}, {1, 2}}
+	for i, day := range days {
+		if day.Start.Day() != 8+i || day.Upload != want[i][0] || day.Download != want[i][1] {
+			t.Errorf("Day %d = %+v, want %v on June %d", i, day, want[i], 8+i)
+		}
+	}
+	if months, _ := history.Usage(host, accounting.UsageMonthly, now, 2); months[1].Upload != 111 || months[1].Download != 1022 || months[0].Start.Month() != time.May {
+		t.Errorf("Expected June's traffic in the second month, got %+v", months)
+	}
+	if hours, _ := history.Usage(host, accounting.UsageHourly, now, 2); hours[0].Start.Hour() != 11 || hours[0].Download != 2 || hours[1].Download != 0 {
+		t.Errorf("Expected the 11:00 hour's traffic, got %+v", hours)
+	}
+
+	lines := usageLines(days, accounting.UsageDaily, 80, ui.FormatBytes)
+	for i, want := range []string{
+		"Day               Download      Upload       Total",
+		"2025-06-08 Sun      1000 B       100 B     1.07 KB  █████████████████████████▒▒▒",
+		"2025-06-09 Mon         0 B         0 B         0 B",
+		"Total               1022 B       111 B     1.11 KB",
+	} {
+		if got := lines[[]int{0, 1, 2, 5}[i]]; got != want {
+			t.Errorf("Usage line:\n got %q\nwant %q", got, want)
+		}
+	}
+
+	m, _ := newTestModel(t)
+	m.clock = func() time.Time { return now }
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	if m = next.(model); !m.showUsage || !strings.Contains(ansi.Strip(m.renderView()), "Needs --history") {
+		t.Error("Expected u to show the usage tab, asking for --history")
+	}
+	m.history = history
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("U")})
+	m = next.(model)
+	if view := ansi.Strip(m.renderView()); m.usagePeriod != accounting.UsageMonthly || !strings.Contains(view, "Usage · monthly") || !strings.Contains(view, "2025-06 ") {
+		t.Errorf("Expected U to switch to monthly totals:\n%s", view)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	if m = next.(model); m.showUsage {
+		t.Error("Expected u to return to the chart")
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	for input, expected := range map[string]time.Duration{"48h": 48 * time.Hour, "90d": 90 * 24 * time.Hour, "forever": 0} {
+		if d, err := accounting.ParseRetention(input); err != nil || d != expected {
+			t.Errorf("ParseRetention(%q) = %v, %v; expected %v", input, d, err, expected)
+		}
+		if d, _ := accounting.ParseRetention(accounting.FormatRetention(expected)); d != expected {
+			t.Errorf("FormatRetention(%v) does not round-trip", expected)
+		}
+	}
+	for _, input := range []string{"", "-1h", "xd", "0s"} {
+		if _, err := accounting.ParseRetention(input); err == nil {
+			t.Errorf("Expected an error for retention %q", input)
+		}
+	}
+
+	history, err := accounting.OpenHistory(t.TempDir(), accounting.Retention{Raw: time.Hour, Minutes: 24 * time.Hour}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	// Two days ago: dropped entirely; three hours ago: folded into a minute
+	for _, at := range []time.Time{now.Add(-48 * time.Hour), now.Add(-3 * time.Hour), now.Add(-3*time.Hour + time.Second)} {
+		history.Record(at, 100, 1000)
+	}
+	history.Record(now.Add(-time.Minute), 5, 50)
+
+	if err := history.Compact(now); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := history.Samples()
+	if err != nil || len(samples) != 1 || samples[0].Upload != 5 {
+		t.Errorf("Expected only the recent raw sample to remain, got %+v (%v)", samples, err)
+	}
+	minutes, err := history.Minutes()
+	if err != nil || len(minutes) != 1 {
+		t.Fatalf("Expected one minute aggregate, got %+v (%v)", minutes, err)
+	}
+	if m := minutes[0]; !m.Time.Equal(now.Add(-3*time.Hour)) || m.Upload != 200 || m.Download != 2000 || m.PeakDownload != 1000 {
+		t.Errorf("Unexpected minute aggregate %+v", m)
+	}
+
+	// Recording carries on into the trimmed file
+	if err := history.Record(now, 7, 70); err != nil {
+		t.Fatal(err)
+	}
+	if samples, _ := history.Samples(); len(samples) != 2 {
+		t.Errorf("Expected 2 raw samples after compaction, got %d", len(samples))
+	}
+
+	// A day later the minute aggregate has aged out as well
+	if err := history.Compact(now.Add(24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	minutes, _ = history.Minutes()
+	if len(minutes) != 1 || !minutes[0].Time.Equal(now) || minutes[0].Upload != 7 {
+		t.Errorf("Expected only the newest minute to survive, got %+v", minutes)
+	}
+}
+
+func TestImportHistory(t *testing.T) {
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	history.Record(now, 1, 10)
+	history.Close()
+
+	recording := filepath.Join(t.TempDir(), "server.ndjson")
+	data := fmt.Sprintf(`{"t":%q,"up":100,"down":1000}
+{"t":%q,"up":200,"down":2000}
+{"t":%q,"host":"laptop","up":5,"down":50}
+`, now.Format(time.RFC3339Nano), now.Add(time.Second).Format(time.RFC3339Nano), now.Add(-time.Second).Format(time.RFC3339Nano))
+	if err := os.WriteFile(recording, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := runImport([]string{"--history", dir, recording}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "imported 3 samples, skipped 0 duplicates") {
+		t.Errorf("Unexpected import output %q", out.String())
+	}
+
+	// Importing the same recording again is a no-op
+	out.Reset()
+	if err := runImport([]string{"--history", dir, recording}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "imported 0 samples, skipped 3 duplicates") {
+		t.Errorf("Expected duplicates to be skipped, got %q", out.String())
+	}
+
+	history, err = accounting.OpenHistory(dir, accounting.DefaultRetention, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	samples, err := history.Samples()
+	if err != nil || len(samples) != 4 {
+		t.Fatalf("Expected 4 samples, got %+v (%v)", samples, err)
+	}
+	if samples[0].Host != "laptop" || samples[1].Host == "server" || samples[2].Host != "server" {
+		t.Errorf("Unexpected sample order or hosts %+v", samples)
+	}
+
+	if err := runImport([]string{"--history", dir}, io.Discard); err == nil {
+		t.Error("Expected a usage error without files")
+	}
+	if err := os.WriteFile(recording, []byte("not json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runImport([]string{"--history", dir, recording}, io.Discard); err == nil {
+		t.Error("Expected an error for a malformed recording")
+	}
+}
+
+func TestCompareHosts(t *testing.T) {
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var data strings.Builder
+	for i := 0; i < 10; i++ {
+		at := start.Add(time.Duration(i) * time.Second).Format(time.RFC3339Nano)
+		fmt.Fprintf(&data, `{"t":%q,"host":"alpha","up":1,"down":100}`+"\n", at)
+		fmt.Fprintf(&data, `{"t":%q,"host":"beta","up":1,"down":%d}`+"\n", at, i*10)
+	}
+	if _, _, err := history.Import(strings.NewReader(data.String()), ""); err != nil {
+		t.Fatal(err)
+	}
+	history.Close()
+
+	args := []string{"--history", dir, "--width", "10", "--height", "2", "--glyphs", "braille", "--plain"}
+	var overlaid, stacked strings.Builder
+	if err := runCompare(args, &overlaid); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimRight(overlaid.String(), "\n"), "\n")
+	if len(lines) != 4 || lines[3] != "■ alpha  ■ beta" {
+		t.Fatalf("Unexpected compare output:\n%s", overlaid.String())
+	}
+	// alpha is the tallest host when overlaid, filling every column
+	if lines[1] != strings.Repeat("⣿", 10) {
+		t.Errorf("Expected a full top row, got %q", lines[1])
+	}
+
+	if err := runCompare(append(args, "--stack"), &stacked); err != nil {
+		t.Fatal(err)
+	}
+	// Stacked, the scale is alpha+beta's peak, so the first column is half height
+	if got := strings.Split(stacked.String(), "\n")[1]; []rune(got)[0] != ' ' && []rune(got)[0] != '⠀' {
+		t.Errorf("Expected an empty top-left cell when stacked, got %q", got)
+	}
+
+	if err := runCompare(append(args, "--direction", "sideways"), io.Discard); err == nil {
+		t.Error("Expected an error for an unknown direction")
+	}
+	if err := runCompare([]string{"--history", t.TempDir()}, io.Discard); err == nil {
+		t.Error("Expected an error for an empty history")
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var data strings.Builder
+	for i := 0; i < 600; i++ {
+		at := start.Add(time.Duration(i) * updateInterval).Format(time.RFC3339Nano)
+		fmt.Fprintf(&data, `{"t":%q,"host":"box","up":%d,"down":%d}`+"\n", at, 1024, i*1024)
+	}
+	if _, _, err := history.Import(strings.NewReader(data.String()), ""); err != nil {
+		t.Fatal(err)
+	}
+	history.Close()
+	ledger := filepath.Join(t.TempDir(), "daily.jsonl")
+	if err := accounting.NewFileStore(ledger).Append(accounting.DayTotal{Date: "2025-06-01", Upload: 1 << 30, Download: 2 << 30}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := []string{"--history", dir, "--ledger", ledger, "--host", "box", "--width", "20", "--height", "6",
+		"--from", start.Format(time.RFC3339), "--to", start.Add(600 * updateInterval).Format(time.RFC3339)}
+	var out strings.Builder
+	if err := runReport(append(args, "--markdown"), &out); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"# PEAKS report: box\n",
+		"```text\n",
+		"|  | Download | Upload |\n|---|---:|---:|\n",
+		"| Peak | 599.00 KB/s | 1.00 KB/s |",
+		"| Average | 299.50 KB/s | 1.00 KB/s |",
+		"## Daily totals",
+		"| 2025-06-01 | 2.00 GB | 1.00 GB |",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Markdown report lacks %q:\n%s", want, report)
+		}
+	}
+	chartLines := strings.Split(report[strings.Index(report, "```text\n")+8:strings.LastIndex(report, "```")], "\n")
+	if len(chartLines) < 6 || !strings.HasSuffix(chartLines[0], "#") || strings.HasPrefix(chartLines[0], "#") {
+		t.Errorf("Expected an ASCII chart peaking on the right, got %q", chartLines)
+	}
+
+	out.Reset()
+	if err := runReport(args, &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "```") || !strings.Contains(out.String(), "PEAKS report: box\n=================\n") {
+		t.Errorf("Unexpected plain text report:\n%s", out.String())
+	}
+
+	if err := runReport([]string{"--history", dir, "--host", "other"}, io.Discard); err == nil {
+		t.Error("Expected an error for a host without samples")
+	}
+}
+
+func TestSourceNote(t *testing.T) {
+	if note := sourceNote(monitor.SourceWindowsHost); note == "" {
+		t.Error("Expected a note for the Windows host source")
+	}
+	if note := sourceNote(monitor.SourceDisk); note != "" {
+		t.Errorf("Expected no note for disk source, got %q", note)
+	}
+	if monitor.IsWSL() && sourceNote(monitor.SourceNetwork) == "" {
+		t.Error("Expected a WSL2 note for the network source under WSL")
+	}
+}
+
+func TestLinkStatuses(t *testing.T) {
+	statuses, err := monitor.LinkStatuses()
+	if err != nil {
+		t.Skipf("LinkStatuses unavailable: %v", err)
+	}
+	if len(statuses) == 0 {
+		t.Skip("No network interfaces")
+	}
+
+	// The default filter excludes loopback from the monitored links
+	links, err := monitor.NewBandwidthMonitor().Links()
+	if err != nil {
+		t.Fatalf("Links failed: %v", err)
+	}
+	for _, link := range links {
+		if link.Name == "lo" {
+			t.Error("Expected loopback to be filtered from monitored links")
+		}
+	}
+}
+
+func TestTunnelTraffic(t *testing.T) {
+	for name, expected := range map[string]bool{"wg0": true, "tun0": true, "utun3": true, "tailscale0": true, "eth0": false, "wlan0": false} {
+		if monitor.IsTunnelInterface(name) != expected {
+			t.Errorf("IsTunnelInterface(%q) = %v, expected %v", name, !expected, expected)
+		}
+	}
+
+	// eth0 carries 1000 B/s direct plus wg0's 1000 B/s encrypted
+	counters := func(tick uint64) []byte {
+		return []byte(fmt.Sprintf("Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n"+
+			"  eth0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n"+
+			"   wg0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", tick*1500, tick*500, tick*750, tick*250))
+	}
+	src := &fakeCounterSource{data: counters(1)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	now := time.Now()
+	bm.SetClock(func() time.Time { return now })
+	now = now.Add(time.Second)
+	if _, _, err := bm.GetCurrentRates(); err != nil {
+		t.Fatalf("GetCurrentRates failed: %v", err)
+	}
+	if _, active := bm.TunnelRates(); !active {
+		t.Error("Expected wg0 to be reported as a tunnel")
+	}
+
+	now = now.Add(time.Second)
+	src.data = counters(2)
+	m, _ := newTestModel(t)
+	m.collector = bm
+	var updated tea.Model = m
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+	status := updated.(model).vpnStatus()
+	if !strings.Contains(status, "(50%)") {
+		t.Errorf("Expected half the traffic to go through the tunnel, got %q", status)
+	}
+
+	m, _ = newTestModel(t)
+	if status := m.vpnStatus(); status != "" {
+		t.Errorf("Expected no VPN status without a tunnel, got %q", status)
+	}
+}
+
+func TestInterfaceTotals(t *testing.T) {
+	// eth0 gains 3 receive errors and a transmit drop per tick
+	counters := func(tick, recv uint64) []byte {
+		return []byte(fmt.Sprintf("Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n"+
+			"  eth0: %d 10 %d 0 0 0 0 0 %d 10 0 %d 0 0 0 0\n"+
+			" wlan0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", recv, tick*3, tick*1000, tick, tick*10, tick*10))
+	}
+	src := &fakeCounterSource{data: counters(1, 2000)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	now := time.Date(2025, 6, 1, 23, 59, 57, 0, time.Local)
+	bm.SetClock(func() time.Time { return now })
+	now = now.Add(time.Second)
+	if _, _, err := bm.GetCurrentRates(); err != nil {
+		t.Fatal(err)
+	}
+	for tick, recv := range []uint64{6000, 7000} {
+		now = now.Add(time.Second)
+		src.data = counters(uint64(tick+2), recv)
+		if _, _, err := bm.GetCurrentRates(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	totals := bm.InterfaceTotals()
+	if len(totals) != 2 || totals[0].Name != "eth0" || totals[1].Name != "wlan0" {
+		t.Fatalf("Unexpected interfaces %+v", totals)
+	}
+	eth0 := totals[0]
+	if eth0.SessionDownload != 5000 || eth0.SessionUpload != 2000 || eth0.PeakDownload != 4000 {
+		t.Errorf("Unexpected eth0 session totals %+v", eth0)
+	}
+	// The second sample was after local midnight
+	if eth0.TodayDownload != 1000 || eth0.TodayUpload != 1000 {
+		t.Errorf("Expected today's totals to reset at midnight, got %+v", eth0)
+	}
+	if eth0.Errors != 6 || eth0.Drops != 2 {
+		t.Errorf("Expected errors and drops since start, got %+v", eth0)
+	}
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "interfaces.csv")
+	if err := writeInterfaceTotals(csvPath, bm); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "interface,session_upload") || lines[1] != "eth0,2000,5000,1000,1000,1000,4000,6,2" {
+		t.Errorf("Unexpected CSV export:\n%s", data)
+	}
+
+	jsonPath := filepath.Join(dir, "interfaces.json")
+	if err := writeInterfaceTotals(jsonPath, bm); err != nil {
+		t.Fatal(err)
+	}
+	var exported []monitor.InterfaceTotals
+	if data, err = os.ReadFile(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &exported); err != nil || len(exported) != 2 || exported[0] != eth0 {
+		t.Errorf("Unexpected JSON export (%v):\n%s", err, data)
+	}
+
+	collector, _ := newTestModel(t)
+	if err := writeInterfaceTotals(jsonPath, collector.collector); err == nil {
+		t.Error("Expected an error for a collector without interfaces")
+	}
+}
+
+func TestInterfaceGroups(t *testing.T) {
+	wan, err := monitor.ParseInterfaceGroup("WAN=ppp0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lan, err := monitor.ParseInterfaceGroup("LAN = eth*, wlan0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lan.String() != "LAN=eth*,wlan0" || !lan.Contains("eth2") || lan.Contains("ppp0") {
+		t.Errorf("Unexpected LAN group %v", lan)
+	}
+	for _, spec := range []string{"WAN", "=eth0", "LAN=", "LAN=eth["} {
+		if _, err := monitor.ParseInterfaceGroup(spec); err == nil {
+			t.Errorf("Expected an error for group %q", spec)
+		}
+	}
+
+	counters := func(tick uint64) []byte {
+		return []byte(fmt.Sprintf("Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n"+
+			"  ppp0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n"+
+			"  eth1: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n"+
+			" wlan0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n"+
+			" wlan1: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", tick*4000, tick*1000, tick*300, tick*2000, tick*200, tick*1500, tick*9999, tick*9999))
+	}
+	src := &fakeCounterSource{data: counters(1)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	bm.SetInterfaceGroups([]monitor.InterfaceGroup{wan, lan})
+	now := time.Now()
+	bm.SetClock(func() time.Time { return now })
+	now = now.Add(time.Second)
+	if _, err := bm.Sample(); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Second)
+	src.data = counters(2)
+	series, err := bm.Sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []monitor.Series{
+		{Name: monitor.SeriesUpload, Value: 1000}, {Name: monitor.SeriesDownload, Value: 4000},
+		{Name: "WAN upload", Value: 1000}, {Name: "WAN download", Value: 4000},
+		{Name: "LAN upload", Value: 3500}, {Name: "LAN download", Value: 500},
+	}
+	if fmt.Sprint(series) != fmt.Sprint(expected) {
+		t.Errorf("Sample = %v, expected %v", series, expected)
+	}
+
+	m, _ := newTestModel(t)
+	m.collector = bm
+	if status := m.groupStatus(); status != "WAN ↓"+m.formatRate(4000)+" ↑"+m.formatRate(1000)+" | LAN ↓"+m.formatRate(500)+" ↑"+m.formatRate(3500) {
+		t.Errorf("Unexpected group status %q", status)
+	}
+}
+
+func TestSampleGapAfterSuspend(t *testing.T) {
+	src := &fakeCounterSource{data: procNetDev(0, 1)}
+	m := monitor.NewBandwidthMonitorWithSource(src)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+
+	// Baseline, then a normal half-second sample
+	now = now.Add(500 * time.Millisecond)
+	src.data = procNetDev(0, 2)
+	if _, err := m.Sample(); err != nil {
+		t.Fatalf("Sample failed: %v", err)
+	}
+
+	// An hour of suspend with a large counter jump must not produce a spike
+	now = now.Add(time.Hour)
+	src.data = procNetDev(0, 1000000)
+	series, err := m.Sample()
+	if !errors.Is(err, monitor.ErrSampleGap) {
+		t.Fatalf("Expected ErrSampleGap after a long interval, got %v", err)
+	}
+	if upload, download := monitor.SplitSeries(series); upload != 0 || download != 0 {
+		t.Errorf("Expected zero rates across a gap, got %d/%d", upload, download)
+	}
+
+	// The following sample is measured from the new baseline
+	now = now.Add(500 * time.Millisecond)
+	src.data = procNetDev(0, 1000001)
+	series, err = m.Sample()
+	if err != nil {
+		t.Fatalf("Sample after gap failed: %v", err)
+	}
+	if _, download := monitor.SplitSeries(series); download != 4000 {
+		t.Errorf("Expected 4000 B/s after resume, got %d", download)
+	}
+}
+
+func TestCounterWraparound(t *testing.T) {
+	line := func(recv, sent uint64) []byte {
+		return []byte(fmt.Sprintf("  eth0: %d 0 0 0 0 0 0 0 %d 0 0 0 0 0 0 0\n", recv, sent))
+	}
+
+	src := &fakeCounterSource{data: line(4294967000, 1<<40)}
+	m := monitor.NewBandwidthMonitorWithSource(src)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+	now = now.Add(500 * time.Millisecond)
+	if _, _, err := m.GetCurrentRates(); err != nil {
+		t.Fatalf("GetCurrentRates failed: %v", err)
+	}
+
+	// 32-bit receive counter wraps; 64-bit transmit counter resets
+	now = now.Add(500 * time.Millisecond)
+	src.data = line(704, 1000)
+	upload, download, err := m.GetCurrentRates()
+	if err != nil {
+		t.Fatalf("GetCurrentRates failed: %v", err)
+	}
+	if download != 2000 {
+		t.Errorf("Expected 2000 B/s across a 32-bit wrap, got %d", download)
+	}
+	if upload != 0 {
+		t.Errorf("Expected counter reset to report 0 B/s, got %d", upload)
+	}
+}
+
+func TestChartGapMarker(t *testing.T) {
+	c := chart.NewBrailleChart(1000)
+	c.SetWidth(20)
+	c.SetHeight(4)
+	c.SetPlainOutput(true)
+
+	for i := 0; i < 5; i++ {
+		c.AddDataPoint(2048, 4096)
+	}
+	c.AddGap()
+	if !strings.Contains(c.Render(), "┊") {
+		t.Error("Expected gap marker after AddGap")
+	}
+
+	// Once the gap scrolls out of view the marker disappears
+	for i := 0; i < 20; i++ {
+		c.AddDataPoint(2048, 4096)
+	}
+	if strings.Contains(c.Render(), "┊") {
+		t.Error("Expected gap marker to scroll out of view")
+	}
+}
+
+func TestNewBrailleChart(t *testing.T) {
+	c := chart.NewBrailleChart(100)
+	if c == nil {
+		t.Fatal("NewBrailleChart returned nil")
+	}
+
+	// Test adding data points
+	c.AddDataPoint(1024, 2048)
+	c.AddDataPoint(2048, 4096)
+
+	// Test rendering
+	output := c.Render()
+	if output == "" {
+		t.Fatal("Chart render returned empty string")
+	}
+
+	// Test reset
+	c.Reset()
+}
+
+func TestDeterministicRender(t *testing.T) {
+	fixed := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	render := func() string {
+		c := chart.NewBrailleChart(100)
+		c.SetDeterministic(func() time.Time { return fixed })
+		c.SetWidth(20)
+		c.SetHeight(8)
+		for i := uint64(0); i < 30; i++ {
+			c.AddDataPoint(i*1024, (30-i)*1024)
+		}
+		if !c.GetLastSampleTime().Equal(fixed) {
+			t.Errorf("Expected injected clock timestamp %v, got %v", fixed, c.GetLastSampleTime())
+		}
+		return c.Render() + "\n" + c.RenderCompactWithSize(20, 2)
+	}
+
+	first := render()
+	if first != render() {
+		t.Error("Deterministic render produced different frames for identical input")
+	}
+	if strings.Contains(first, "\x1b") {
+		t.Error("Deterministic render contains ANSI escape sequences")
+	}
+
+	lines := strings.Split(first, "\n")
+	if len(lines) != 10 {
+		t.Fatalf("Expected 10 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if n := len([]rune(line)); n != 20 {
+			t.Errorf("Line %d has %d glyphs, expected 20", i, n)
+		}
+	}
+}
+
+func TestDetectGlyphSet(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(name string) string { return vars[name] }
+	}
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		goos string
+		want chart.GlyphSet
+	}{
+		{"utf8 xterm", map[string]string{"TERM": "xterm-256color", "LANG": "en_US.UTF-8"}, "linux", chart.GlyphBraille},
+		{"unset locale", map[string]string{"TERM": "xterm"}, "darwin", chart.GlyphBraille},
+		{"C locale", map[string]string{"TERM": "xterm", "LANG": "C"}, "linux", chart.GlyphASCII},
+		{"LC_ALL overrides LANG", map[string]string{"LC_ALL": "POSIX", "LANG": "en_US.UTF-8"}, "linux", chart.GlyphASCII},
+		{"linux console", map[string]string{"TERM": "linux", "LANG": "en_US.UTF-8"}, "linux", chart.GlyphASCII},
+		{"legacy windows console", map[string]string{}, "windows", chart.GlyphBlock},
+		{"windows terminal", map[string]string{"WT_SESSION": "1"}, "windows", chart.GlyphBraille},
+	}
+	for _, tt := range tests {
+		if got := chart.DetectGlyphSet(env(tt.vars), tt.goos); got != tt.want {
+			t.Errorf("%s: DetectGlyphSet = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, _, err := chart.ParseGlyphSet("sixel"); err == nil {
+		t.Error("Expected error for unknown glyph set")
+	}
+	if set, _, err := chart.ParseGlyphSet("blocks"); err != nil || set != chart.GlyphBlock {
+		t.Errorf("Expected blocks to select the block glyphs, got %v (%v)", set, err)
+	}
+}
+
+func TestGlyphSetFallback(t *testing.T) {
+	c := chart.NewBrailleChart(100)
+	c.SetWidth(40)
+	c.SetHeight(10)
+	c.SetPlainOutput(true)
+	for i := 0; i < 40; i++ {
+		c.AddDataPoint(uint64(i)*1024, uint64(40-i)*1024)
+	}
+
+	for _, set := range []chart.GlyphSet{chart.GlyphBlock, chart.GlyphASCII} {
+		c.SetGlyphSet(set)
+		out := c.Render()
+		for _, r := range out {
+			if r >= 0x2800 && r <= 0x28FF {
+				t.Fatalf("Glyph set %v rendered braille rune %q", set, r)
+			}
+			if set == chart.GlyphASCII && r > 0x7F {
+				t.Fatalf("ASCII glyph set rendered non-ASCII rune %q", r)
+			}
+		}
+		if strings.TrimSpace(out) == "" {
+			t.Errorf("Glyph set %v rendered an empty chart", set)
+		}
+		// Block elements fill cells to the eighth, as braille does to the dot row
+		if set == chart.GlyphBlock && strings.Count(out, "▁")+strings.Count(out, "▃")+strings.Count(out, "▅")+strings.Count(out, "▇") == 0 {
+			t.Errorf("Expected the block glyph set to draw eighths, got:\n%s", out)
+		}
+	}
+}
+
+func TestRenderCacheConsistency(t *testing.T) {
+	newChart := func() *chart.BrailleChart {
+		c := chart.NewBrailleChart(500)
+		c.SetPlainOutput(true)
+		c.SetWidth(40)
+		c.SetHeight(10)
+		for i := uint64(0); i < 200; i++ {
+			c.AddDataPoint((i%17)*4096, (i%23)*8192)
+		}
+		return c
+	}
+
+	// A chart that has been through mode and size changes must render
+	// exactly like a freshly built one
+	cached := newChart()
+	cached.Render()
+	cached.SetOverlayMode(true)
+	cached.Render()
+	cached.SetHeight(16)
+	cached.Render()
+	cached.SetOverlayMode(false)
+	cached.SetHeight(10)
+
+	if got, want := cached.Render(), newChart().Render(); got != want {
+		t.Errorf("Cached render differs from fresh render:\n%s\n---\n%s", got, want)
+	}
+}
+
+func TestSetGradients(t *testing.T) {
+	c := chart.NewBrailleChart(100)
+	c.SetWidth(20)
+	c.AddDataPoint(4096, 8192)
+	before := c.Render()
+
+	// Empty gradients fall back to base colors and must not break rendering
+	c.SetGradients(chart.ColorGradient{}, chart.ColorGradient{}, chart.ColorGradient{})
+	c.SetOverlayMode(true)
+	c.Render()
+	c.SetOverlayMode(false)
+	if after := c.Render(); len(strings.Split(after, "\n")) != len(strings.Split(before, "\n")) {
+		t.Error("Render after SetGradients changed the chart layout")
+	}
+}
+
+// fakeCollector is a deterministic Collector for model tests
+type fakeCollector struct {
+	upload, download uint64
+}
+
+func (f *fakeCollector) Sample() ([]monitor.Series, error) {
+	return []monitor.Series{
+		{Name: monitor.SeriesUpload, Value: f.upload},
+		{Name: monitor.SeriesDownload, Value: f.download},
+	}, nil
+}
+
+// newTestModel creates a sized model fed by a fake collector
+func newTestModel(t *testing.T) (model, *fakeCollector) {
+	t.Helper()
+	collector := &fakeCollector{}
+	var m tea.Model = initialModel(options{source: monitor.SourceNetwork}, collector)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	return m.(model), collector
+}
+
+func TestRouteChangeMarker(t *testing.T) {
+	route := monitor.DefaultRoute{Interface: "eth0", Gateway: net.IPv4(192, 168, 1, 1)}
+	watcher := monitor.NewRouteWatcherWithReader(func() (monitor.DefaultRoute, error) {
+		return route, nil
+	})
+	if _, changed := watcher.Check(); changed {
+		t.Fatal("Expected no change for an unchanged route")
+	}
+
+	m, collector := newTestModel(t)
+	m.routes = watcher
+	collector.upload, collector.download = 1024, 4096
+	var updated tea.Model = m
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+
+	// Fail over from ethernet to Wi-Fi
+	route = monitor.DefaultRoute{Interface: "wlan0", Gateway: net.IPv4(10, 0, 0, 1)}
+	updated, _ = updated.Update(routeTickMsg{})
+	m = updated.(model)
+	if !strings.Contains(m.routeNote, "eth0 via 192.168.1.1 → wlan0 via 10.0.0.1") {
+		t.Errorf("Unexpected route note %q", m.routeNote)
+	}
+
+	m.chart.SetPlainOutput(true)
+	if !strings.Contains(m.chart.Render(), "▾") {
+		t.Error("Expected a route change marker on the chart")
+	}
+}
+
+func TestConntrackAlert(t *testing.T) {
+	dir := t.TempDir()
+	writeConntrack := func(count int) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "nf_conntrack_count"), []byte(fmt.Sprintf("%d\n", count)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nf_conntrack_max"), []byte("1000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeConntrack(200)
+
+	status, err := monitor.ReadConntrack(dir)
+	if err != nil || status.Count != 200 || status.Max != 1000 {
+		t.Fatalf("ReadConntrack = %+v, %v", status, err)
+	}
+	if _, err := monitor.ReadConntrack(t.TempDir()); err == nil {
+		t.Error("Expected an error without conntrack counters")
+	}
+
+	m, collector := newTestModel(t)
+	m.conntrackDir = dir
+	m.conntrackAlert = 90
+	collector.upload, collector.download = 1024, 4096
+
+	var updated tea.Model = m
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+	updated, cmd := updated.Update(conntrackTickMsg{})
+	if cmd == nil {
+		t.Error("Expected the next conntrack check to be scheduled")
+	}
+	if status := updated.(model).conntrackStatus(); status != "CT: 200/1000" {
+		t.Errorf("Unexpected conntrack status %q", status)
+	}
+
+	writeConntrack(950)
+	updated, _ = updated.Update(conntrackTickMsg{})
+	m = updated.(model)
+	if status := m.conntrackStatus(); !strings.Contains(status, "CT: 950/1000 (95%)!") {
+		t.Errorf("Expected a nearly full table to be flagged, got %q", status)
+	}
+	m.chart.SetPlainOutput(true)
+	if !strings.Contains(m.chart.Render(), "▾") {
+		t.Error("Expected the conntrack alert to be marked on the chart")
+	}
+}
+
+// droppingCollector is a fakeCollector whose interfaces report errors and drops
+type droppingCollector struct {
+	*fakeCollector
+	stats monitor.ErrorStats
+}
+
+func (d *droppingCollector) ErrorStats() monitor.ErrorStats {
+	return d.stats
+}
+
+func TestPacketErrors(t *testing.T) {
+	// eth0 gains 3 receive errors and 2 transmit drops per tick; wlan0 is clean
+	counters := func(tick uint64) []byte {
+		return []byte(fmt.Sprintf("Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n"+
+			"  eth0: 1000 10 %d 0 0 0 0 0 1000 10 0 %d 0 0 0 0\n"+
+			" wlan0: 1000 10 0 0 0 0 0 0 1000 10 0 0 0 0 0 0\n", 100+tick*3, 50+tick*2))
+	}
+	src := &fakeCounterSource{data: counters(0)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	bm.SetClock(func() time.Time { return now })
+	now = now.Add(time.Second)
+	if _, _, err := bm.GetCurrentRates(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := bm.ErrorStats(); stats != (monitor.ErrorStats{}) {
+		t.Errorf("Expected no errors before any were counted, got %+v", stats)
+	}
+	for tick := range uint64(2) {
+		now = now.Add(2 * time.Second)
+		src.data = counters(tick + 1)
+		if _, _, err := bm.GetCurrentRates(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := bm.ErrorStats(); stats != (monitor.ErrorStats{Errors: 6, Drops: 4, ErrorRate: 1.5, DropRate: 1}) {
+		t.Errorf("Unexpected error stats %+v", stats)
+	}
+	// Narrowed to wlan0, only its own (clean) counters count
+	bm.SetFocus("wlan0")
+	now = now.Add(time.Second)
+	if _, _, err := bm.GetCurrentRates(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := bm.ErrorStats(); stats != (monitor.ErrorStats{}) {
+		t.Errorf("Expected the focused interface's errors only, got %+v", stats)
+	}
+
+	m, collector := newTestModel(t)
+	dropping := &droppingCollector{fakeCollector: collector}
+	m.collector, m.markDrops = dropping, true
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 250, Height: 24})
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+	m = updated.(model)
+	m.chart.SetPlainOutput(true)
+	if strings.Contains(m.chart.Render(), "▾") || strings.Contains(ansi.Strip(m.View()), "Errors:") {
+		t.Error("Expected no drop marker or error status without errors")
+	}
+	dropping.stats = monitor.ErrorStats{Errors: 6, Drops: 4, ErrorRate: 1.5, DropRate: 1}
+	updated, _ = m.Update(tickMsg{generation: m.tickGeneration})
+	m = updated.(model)
+	if !strings.Contains(m.chart.Render(), "▾") {
+		t.Error("Expected a marker on the column with drops")
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "Errors: 6 (1.5/s) Drops: 4 (1.0/s)") {
+		t.Errorf("Expected errors and drops in the statusbar:\n%s", view)
+	}
+}
+
+func TestDNSLatencyStatus(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.dns = monitor.NewDNSProbe("localhost")
+	m.dnsInterval = time.Second
+	m.dnsAlert = 250 * time.Millisecond
+
+	if _, err := m.dns.Probe(); err != nil {
+		t.Logf("DNS probe error (expected on some systems): %v", err)
+	}
+
+	var updated tea.Model = m
+	updated, cmd := updated.Update(dnsResultMsg{latency: 30 * time.Millisecond})
+	if cmd == nil {
+		t.Error("Expected the next DNS probe to be scheduled")
+	}
+	if status := updated.(model).dnsStatus(); !strings.Contains(status, "DNS: 30ms") || strings.Contains(status, "!") {
+		t.Errorf("Unexpected DNS status %q", status)
+	}
+
+	updated, _ = updated.Update(dnsResultMsg{latency: 400 * time.Millisecond})
+	if status := updated.(model).dnsStatus(); !strings.Contains(status, "DNS: 400ms!") {
+		t.Errorf("Expected slow DNS to be flagged, got %q", status)
+	}
+
+	updated, _ = updated.Update(dnsResultMsg{err: errors.New("timeout")})
+	if status := updated.(model).dnsStatus(); !strings.Contains(status, "DNS: fail") {
+		t.Errorf("Expected failed DNS to be flagged, got %q", status)
+	}
+}
+
+func TestSpeedTestMarker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/__down":
+			var n int64
+			fmt.Sscan(r.URL.Query().Get("bytes"), &n)
+			w.Write(make([]byte, n))
+		case "/__up":
+			io.Copy(io.Discard, r.Body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tester := monitor.NewSpeedTester(server.URL)
+	tester.SetSizes(64*1024, 32*1024)
+	result, err := tester.Run()
+	if err != nil {
+		t.Fatalf("Speed test failed: %v", err)
+	}
+	if result.Download == 0 || result.Upload == 0 {
+		t.Errorf("Expected nonzero throughput, got %+v", result)
+	}
+
+	m, collector := newTestModel(t)
+	m.speedTestLog = filepath.Join(t.TempDir(), "speedtest.jsonl")
+	m.speedTesting = true
+	collector.upload, collector.download = 1024, 4096
+
+	var updated tea.Model = m
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+	updated, _ = updated.Update(speedTestResultMsg{result: result})
+	got := updated.(model)
+	got.chart.SetPlainOutput(true)
+	if got.speedTesting || !strings.HasPrefix(got.speedTestNote, "Speed: ↓") {
+		t.Errorf("Unexpected speed test note %q", got.speedTestNote)
+	}
+	if !strings.Contains(got.chart.Render(), "▾") {
+		t.Error("Expected the speed test to be marked on the chart")
+	}
+	if data, err := os.ReadFile(m.speedTestLog); err != nil || !strings.Contains(string(data), "download_bytes_per_sec") {
+		t.Errorf("Expected the result to be logged, got %q (%v)", data, err)
+	}
+
+	updated, _ = updated.Update(speedTestResultMsg{err: errors.New("offline")})
+	if note := updated.(model).speedTestNote; note != "Speed: failed" {
+		t.Errorf("Expected failed speed test note, got %q", note)
+	}
+}
+
+func TestSpeedTestPopup(t *testing.T) {
+	m, collector := newTestModel(t)
+	collector.upload, collector.download = 1024, 4096
+
+	var updated tea.Model = m
+	updated, _ = updated.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+	updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	got := updated.(model)
+	got.chart.SetPlainOutput(true)
+	if cmd == nil || !got.speedTesting {
+		t.Fatal("Expected x to start a speed test")
+	}
+	if !strings.Contains(got.chart.Render(), "▾") {
+		t.Error("Expected the start of the speed test to be marked on the chart")
+	}
+
+	result := monitor.SpeedTestResult{Time: time.Now(), Latency: 23 * time.Millisecond, Download: 12_500_000, Upload: 1_250_000}
+	updated, _ = updated.Update(speedTestResultMsg{result: result})
+	view := ansi.Strip(updated.(model).renderView())
+	for _, want := range []string{"Speed test · speed.cloudflare.com", "Latency   23ms", "Download  ↓11.92 MB/s", "Upload    ↑1.19 MB/s", "Any key to close"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the popup, got:\n%s", want, view)
+		}
+	}
+
+	// The key that closes the popup does nothing else
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	got = updated.(model)
+	if got.showSpeedTest || got.paused {
+		t.Errorf("Expected a key to close the popup only, got shown %v paused %v", got.showSpeedTest, got.paused)
+	}
+
+	// Scheduled tests only note their result in the statusbar
+	updated, _ = updated.Update(speedTestTickMsg{})
+	updated, _ = updated.Update(speedTestResultMsg{result: result})
+	if updated.(model).showSpeedTest {
+		t.Error("Expected a scheduled speed test not to pop up")
+	}
+}
+
+func TestIperfRun(t *testing.T) {
+	report := []byte(`{
+		"start": {"timestamp": {"timesecs": 1700000000}, "test_start": {"reverse": 1}},
+		"end": {
+			"sum_sent": {"seconds": 10.0, "bits_per_second": 96000000},
+			"sum_received": {"seconds": 10.0, "bits_per_second": 94400000}
+		}
+	}`)
+	result, err := monitor.ParseIperfJSON(report)
+	if err != nil {
+		t.Fatalf("Failed to parse iperf3 report: %v", err)
+	}
+	if result.Sent != 12000000 || result.Received != 11800000 || !result.Reverse || result.Duration != 10*time.Second {
+		t.Errorf("Unexpected iperf3 summary %+v", result)
+	}
+	if _, err := monitor.ParseIperfJSON([]byte(`{"error": "unable to connect to server"}`)); err == nil {
+		t.Error("Expected iperf3 errors to be reported")
+	}
+
+	m, collector := newTestModel(t)
+	m.iperf = monitor.NewIperfRunner("iperf.example.net")
+	collector.upload, collector.download = 1024, 4096
+
+	var updated tea.Model = m
+	updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+	updated, cmd := updated.Update(iperfStartMsg{})
+	if cmd == nil {
+		t.Fatal("Expected the iperf3 run to start")
+	}
+	updated, _ = updated.Update(iperfResultMsg{result: result})
+	m = updated.(model)
+	if m.iperfNote != "iperf: ↓"+m.formatRate(result.Received)+" in 10s" {
+		t.Errorf("Unexpected iperf note %q", m.iperfNote)
+	}
+	m.chart.SetPlainOutput(true)
+	if !strings.Contains(m.chart.Render(), "▾") {
+		t.Error("Expected the iperf3 run to be marked on the chart")
+	}
+}
+
+func TestTinyTerminalLayout(t *testing.T) {
+	m, collector := newTestModel(t)
+	collector.upload, collector.download = 1024, 4096
+
+	for _, size := range []tea.WindowSizeMsg{{Width: 30, Height: 6}, {Width: 80, Height: 4}, {Width: 36, Height: 20}} {
+		var updated tea.Model = m
+		updated, _ = updated.Update(size)
+		updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+		view := updated.View()
+
+		lines := strings.Split(view, "\n")
+		if len(lines) > size.Height {
+			t.Errorf("%dx%d: rendered %d lines", size.Width, size.Height, len(lines))
+		}
+		for i, line := range lines {
+			if w := ui.StringWidth(line); w > size.Width {
+				t.Errorf("%dx%d: line %d is %d cells wide", size.Width, size.Height, i, w)
+			}
+		}
+		if strings.Contains(view, "PEAKS") {
+			t.Errorf("%dx%d: expected title/help to be dropped", size.Width, size.Height)
+		}
+	}
+}
+
+func TestSteadyFramesSkipRendering(t *testing.T) {
+	c := chart.NewBrailleChart(500)
+	c.SetWidth(20)
+	for i := 0; i < 50; i++ {
+		c.AddDataPoint(0, 0)
+	}
+	c.Render()
+
+	c.AddDataPoint(0, 0)
+	if c.IsFrameDirty() {
+		t.Error("Identical sample over a steady window should not dirty the frame")
+	}
+	c.AddDataPoint(1024, 0)
+	if !c.IsFrameDirty() {
+		t.Error("New sample value should dirty the frame")
+	}
+}
+
+func TestPausedAndBlurredModelSkipsRedraw(t *testing.T) {
+	m, collector := newTestModel(t)
+	m.View()
+
+	// While unfocused the model keeps sampling but does not redraw
+	next, _ := m.Update(tea.BlurMsg{})
+	m = next.(model)
+	collector.download = 4096
+	next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+	m = next.(model)
+	if m.frame.dirty {
+		t.Error("Tick while unfocused should not mark the frame dirty")
+	}
+	// One slowed tick spans, and fills, four sample intervals
+	if m.chart.GetDataLength() != 4 {
+		t.Errorf("Expected sampling to continue while unfocused, got %d points", m.chart.GetDataLength())
+	}
+
+	// While paused, samples are buffered and the frame stays frozen
+	next, _ = m.Update(tea.FocusMsg{})
+	m = next.(model)
+	next, _ = m.Update(tea.WindowSizeMsg{Width: 200, Height: 24})
+	m = next.(model)
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = next.(model)
+	if !strings.Contains(m.View(), "PAUSED at "+m.pausedAt.Format("15:04:05")) {
+		t.Error("Expected the statusbar to show when the display was paused")
+	}
+	for range 3 {
+		next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+		m = next.(model)
+		if m.frame.dirty {
+			t.Error("Tick while paused should not mark the frame dirty")
+		}
+	}
+	if m.chart.GetDataLength() != 4 || m.pausedSamples.len() != 3 {
+		t.Errorf("Expected 3 buffered samples and a frozen chart, got %d buffered and %d charted",
+			m.pausedSamples.len(), m.chart.GetDataLength())
+	}
+
+	// Resuming backfills the chart instead of leaving a gap
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = next.(model)
+	if m.chart.GetDataLength() != 7 || m.pausedSamples.len() != 0 {
+		t.Errorf("Expected the paused samples to be charted on resume, got %d points", m.chart.GetDataLength())
+	}
+	if strings.Contains(m.View(), "PAUSED") {
+		t.Error("Expected the paused indicator to clear on resume")
+	}
+
+	// A long pause keeps the latest maxPausedSamples, replayed oldest first
+	var buffer pausedBuffer
+	for i := range maxPausedSamples + 3 {
+		buffer.add(pausedSample{upload: uint64(i)})
+	}
+	long := chart.NewBrailleChart(maxPausedSamples)
+	buffer.replay(long)
+	if data := long.ExportData(time.Second); len(data) != maxPausedSamples || data[0].Upload != 3 || data[len(data)-1].Upload != maxPausedSamples+2 || buffer.len() != 0 {
+		t.Errorf("Expected the last %d samples in order, got %d from %d", maxPausedSamples, len(data), data[0].Upload)
+	}
+
+	// Stopping takes no samples at all, and resuming leaves a gap
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("P")})
+	m = next.(model)
+	if !strings.Contains(m.View(), "STOPPED at "+m.stoppedAt.Format("15:04:05")) {
+		t.Error("Expected the statusbar to show when sampling was stopped")
+	}
+	for range 3 {
+		var cmd tea.Cmd
+		next, cmd = m.Update(tickMsg{generation: m.tickGeneration})
+		m = next.(model)
+		if cmd == nil {
+			t.Fatal("Expected ticks to keep coming while stopped")
+		}
+	}
+	if m.chart.GetDataLength() != 7 || m.pausedSamples.len() != 0 {
+		t.Errorf("Expected no samples while stopped, got %d charted and %d buffered", m.chart.GetDataLength(), m.pausedSamples.len())
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("P")})
+	m = next.(model)
+	if m.chart.GetDataLength() != 8 || strings.Contains(m.View(), "STOPPED") {
+		t.Errorf("Expected resuming to draw one gap and clear the indicator, got %d points", m.chart.GetDataLength())
+	}
+
+	// Stopping while paused goes through the same ring: the gap is replayed
+	// in order with the samples around it
+	for _, key := range []string{"p", "P", "P"} {
+		next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = next.(model)
+	}
+	next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+	m = next.(model)
+	if m.pausedSamples.len() != 2 || m.chart.GetDataLength() != 8 {
+		t.Errorf("Expected the gap and a sample buffered while paused, got %d", m.pausedSamples.len())
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	m = next.(model)
+	if data := m.chart.ExportData(updateInterval); len(data) != 10 || !data[8].Gap || data[9].Gap {
+		t.Errorf("Expected the buffered gap then the sample on resume, got %d points", len(data))
+	}
+
+	// Restarting the tick chain drops ticks from the old one
+	oldGeneration := m.tickGeneration
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	m = next.(model)
+	if _, cmd := m.Update(tickMsg{generation: oldGeneration}); cmd != nil {
+		t.Error("Stale tick should not schedule another tick")
+	}
+}
+
+func TestRefreshRateKeys(t *testing.T) {
+	m, _ := newTestModel(t)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	m = next.(model)
+	press := func(r string) tea.Cmd {
+		t.Helper()
+		oldGeneration := m.tickGeneration
+		next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(r)})
+		m = next.(model)
+		if cmd != nil && m.tickGeneration == oldGeneration {
+			t.Errorf("Expected %q to restart the tick chain", r)
+		}
+		return cmd
+	}
+
+	press("+")
+	if cmd := press("+"); cmd == nil || m.tickInterval() != 100*time.Millisecond {
+		t.Errorf("Expected two steps faster to sample every 100ms, got %v", m.tickInterval())
+	}
+	if !strings.Contains(m.View(), "Rate: 100ms") {
+		t.Error("Expected the statusbar to show the refresh rate")
+	}
+	if m.chart.GetMaxPoints() != 36000 {
+		t.Errorf("Expected an hour of 100ms samples to fit the 60m scale, got room for %d", m.chart.GetMaxPoints())
+	}
+	next, _ = m.Update(tea.WindowSizeMsg{Width: 200, Height: 24})
+	if m = next.(model); m.chart.GetMaxPoints() != 36000 {
+		t.Errorf("Expected a resize to keep the capacity for the interval, got %d", m.chart.GetMaxPoints())
+	}
+	if cmd := press("+"); cmd != nil {
+		t.Error("Expected no change beyond the fastest rate")
+	}
+	for range 5 {
+		press("-")
+	}
+	if m.tickInterval() != 2*time.Second {
+		t.Errorf("Expected the slowest rate to be 2s, got %v", m.tickInterval())
+	}
+
+	// Totals follow the interval the samples were taken at
+	m.ui.GetStats().Reset()
+	m.ui.GetStats().Update(0, 1000)
+	if total := m.ui.GetStats().TotalDownload; total != 2000 {
+		t.Errorf("Expected 2s of 1000 B/s to total 2000 bytes, got %d", total)
+	}
+
+	// History samples taken at a non-default rate record their interval
+	history, err := accounting.OpenHistory(t.TempDir(), accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	m.history = history
+	press("+")
+	history.Record(time.Now(), 0, 1000)
+	samples, err := history.Samples()
+	if err != nil || len(samples) != 1 || samples[0].Duration(updateInterval) != time.Second {
+		t.Errorf("Expected a 1s sample, got %+v (%v)", samples, err)
+	}
+}
+
+func TestTieredHistoryLongTimeScales(t *testing.T) {
+	c := chart.NewBrailleChart(100) // raw buffer holds only 50 seconds
+	c.SetPlainOutput(true)
+	c.SetWidth(100) // narrowest scale (3h) covers 150 minutes at this width
+
+	// Two hours of samples with a burst early on that has left the raw buffer
+	for i := 0; i < 2*60*60*2; i++ {
+		var download uint64 = 1024
+		if i > 1000 && i < 1100 {
+			download = 50 * 1024 * 1024
+		}
+		c.AddDataPoint(0, download)
+	}
+
+	for _, scale := range []chart.TimeScale{chart.TimeScale3Hour, chart.TimeScale6Hour, chart.TimeScale12Hour, chart.TimeScale24Hour} {
+		c.SetTimeScale(scale)
+		// The idle baseline renders blank, so any glyph means the burst survived
+		if strings.TrimSpace(c.Render()) == "" {
+			t.Errorf("Time scale %s lost the early burst", c.GetTimeScaleName())
+		}
+	}
+}
+
+func TestDebugServer(t *testing.T) {
+	if err := startDebugServer("127.0.0.1:0"); err != nil {
+		t.Fatalf("startDebugServer failed: %v", err)
+	}
+	if err := startDebugServer("invalid-address"); err == nil {
+		t.Error("Expected error for invalid pprof address")
+	}
+}
+
+func TestSteadyStateRenderAllocations(t *testing.T) {
+	c := chart.NewBrailleChart(1000)
+	c.SetWidth(120)
+	c.SetHeight(30)
+
+	// Warm up so data buffers, frame buffers and column caches reach steady size
+	sample := func(i int) (uint64, uint64) {
+		return uint64(i%7) * 4096, uint64(i%11) * 8192
+	}
+	for i := 0; i < 2000; i++ {
+		c.AddDataPoint(sample(i))
+		c.Render()
+	}
+
+	buf := make([]byte, 0, 64*1024)
+	i := 2000
+	allocs := testing.AllocsPerRun(200, func() {
+		c.AddDataPoint(sample(i))
+		buf = c.AppendRender(buf[:0])
+		i++
+	})
+	if allocs != 0 {
+		t.Errorf("Steady-state frame allocated %.1f times, expected 0", allocs)
+	}
+}
+
+func TestUIComponents(t *testing.T) {
+	components := ui.NewComponents()
+	if components == nil {
+		t.Fatal("NewComponents returned nil")
+	}
+
+	stats := components.GetStats()
+	if stats == nil {
+		t.Fatal("GetStats returned nil")
+	}
+
+	// Test updating stats
+	stats.Update(1024, 2048)
+	if stats.PeakUpload != 1024 {
+		t.Errorf("Expected PeakUpload 1024, got %d", stats.PeakUpload)
+	}
+	if stats.PeakDownload != 2048 {
+		t.Errorf("Expected PeakDownload 2048, got %d", stats.PeakDownload)
+	}
+}
+
+func TestWidthAwareLayout(t *testing.T) {
+	if w := ui.StringWidth("\x1b[31mab\x1b[0m"); w != 2 {
+		t.Errorf("StringWidth ignoring ANSI = %d, want 2", w)
+	}
+	if w := ui.StringWidth("日本"); w != 4 {
+		t.Errorf("StringWidth of wide characters = %d, want 4", w)
+	}
+
+	truncated := ui.Truncate("\x1b[32m日本語\x1b[0m", 5)
+	if w := ui.StringWidth(truncated); w != 4 {
+		t.Errorf("Truncate split a wide character: width %d, want 4", w)
+	}
+	if ui.Truncate("short", 10) != "short" {
+		t.Error("Truncate modified a string that already fits")
+	}
+
+	// On CJK locales ambiguous-width arrows and block elements are double-width
+	saved := runewidth.DefaultCondition.EastAsianWidth
+	runewidth.DefaultCondition.EastAsianWidth = true
+	defer func() { runewidth.DefaultCondition.EastAsianWidth = saved }()
+
+	if w := ui.StringWidth("↓"); w != 2 {
+		t.Errorf("StringWidth of ambiguous arrow on CJK locale = %d, want 2", w)
+	}
+	c := chart.NewBrailleChart(100)
+	c.SetGlyphSet(chart.GlyphBlock)
+	if w := c.CellWidth(); w != 2 {
+		t.Errorf("Block CellWidth on CJK locale = %d, want 2", w)
+	}
+	c.SetGlyphSet(chart.GlyphBraille)
+	if w := c.CellWidth(); w != 1 {
+		t.Errorf("Braille CellWidth = %d, want 1", w)
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		input    uint64
+		expected string
+	}{
+		{1024, "1.00 KB/s"},
+		{1048576, "1.00 MB/s"},
+		{1073741824, "1.00 GB/s"},
+	}
+
+	for _, test := range tests {
+		result := ui.FormatBandwidth(test.input)
+		if result != test.expected {
+			t.Errorf("FormatBandwidth(%d) = %s, expected %s", test.input, result, test.expected)
+		}
+	}
+
+	if result := ui.FormatCPU(250000); result != "25.0%" {
+		t.Errorf("FormatCPU(250000) = %s, expected 25.0%%", result)
+	}
+
+	// Test duration formatting
+	duration := 125 * time.Second
+	result := ui.FormatDuration(duration)
+	expected := "2m5s"
+	if result != expected {
+		t.Errorf("FormatDuration(%v) = %s, expected %s", duration, result, expected)
+	}
+}
+
+func TestKeyMap(t *testing.T) {
+	keys := ui.DefaultKeyMap()
+
+	// Test that all keys are properly initialized
+	if len(keys.Reset.Keys()) == 0 {
+		t.Error("Reset key binding not initialized")
+	}
+	if len(keys.Pause.Keys()) == 0 {
+		t.Error("Pause key binding not initialized")
+	}
+	if len(keys.Stats.Keys()) == 0 {
+		t.Error("Stats key binding not initialized")
+	}
+	if len(keys.Quit.Keys()) == 0 {
+		t.Error("Quit key binding not initialized")
+	}
+}
+
+// newBenchmarkChart builds a full-screen sized chart with truecolor styling enabled
+func newBenchmarkChart(b *testing.B) *chart.BrailleChart {
+	b.Helper()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	b.Cleanup(func() { lipgloss.SetColorProfile(termenv.Ascii) })
+
+	c := chart.NewBrailleChart(1000)
+	c.SetWidth(200)
+	c.SetHeight(50)
+	for i := uint64(0); i < 400; i++ {
+		c.AddDataPoint((i%37)*65536, (i%53)*131072)
+	}
+	return c
+}
+
+// BenchmarkRender measures a steady-state frame: one new sample, one render
+func BenchmarkRender(b *testing.B) {
+	c := newBenchmarkChart(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.AddDataPoint(uint64(i%37)*65536, uint64(i%53)*131072)
+		c.Render()
+	}
+}
+
+// BenchmarkRenderRestyle measures a frame where every glyph must be restyled,
+// exercising the ANSI emission path
+func BenchmarkRenderRestyle(b *testing.B) {
+	c := newBenchmarkChart(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.InvalidateStyleCache()
+		c.Render()
+	}
+}
+
+// BenchmarkAppendRender measures a steady-state frame rendered into a reused buffer
+func BenchmarkAppendRender(b *testing.B) {
+	c := newBenchmarkChart(b)
+	buf := make([]byte, 0, 1024*1024)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.AddDataPoint(uint64(i%37)*65536, uint64(i%53)*131072)
+		buf = c.AppendRender(buf[:0])
+	}
+}
+
+// BenchmarkBandwidthMonitor measures one sample on a host with dozens of veth
+// interfaces (typical of container hosts), only eth0 being counted
+func BenchmarkBandwidthMonitor(b *testing.B) {
+	src := &fakeCounterSource{data: procNetDev(64, 1)}
+	m := monitor.NewBandwidthMonitorWithSource(src)
+	m.SetInterfaceFilter(func(name string) bool {
+		return !strings.HasPrefix(name, "veth") && name != "lo"
+	})
+	frames := [][]byte{procNetDev(64, 2), procNetDev(64, 3)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		src.data = frames[i%2]
+		m.Sample()
+	}
+}
+
+func TestScreenshotKey(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.screenshotDir = t.TempDir()
+	frame := m.View()
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	m = next.(model)
+	ansFiles, _ := filepath.Glob(filepath.Join(m.screenshotDir, "peaks-*.ans"))
+	txtFiles, _ := filepath.Glob(filepath.Join(m.screenshotDir, "peaks-*.txt"))
+	if len(ansFiles) != 1 || len(txtFiles) != 1 {
+		t.Fatalf("Expected one .ans and one .txt screenshot, got %v and %v", ansFiles, txtFiles)
+	}
+	raw, _ := os.ReadFile(ansFiles[0])
+	plain, _ := os.ReadFile(txtFiles[0])
+	if string(raw) != frame+"\n" {
+		t.Error("Expected the .ans screenshot to be the frame on screen")
+	}
+	if strings.Contains(string(plain), "\x1b") || !strings.Contains(string(plain), "PEAKS") {
+		t.Errorf("Expected the .txt screenshot to be plain text, got %q", plain)
+	}
+	if m.keyNote != "Saved "+filepath.Base(txtFiles[0]) {
+		t.Errorf("Expected the statusbar to note the saved file, got %q", m.keyNote)
+	}
+
+	// A second screenshot in the same second doesn't overwrite the first
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	m = next.(model)
+	if txtFiles, _ = filepath.Glob(filepath.Join(m.screenshotDir, "peaks-*.txt")); len(txtFiles) != 2 {
+		t.Errorf("Expected two screenshots, got %v", txtFiles)
+	}
+
+	m.screenshotDir = filepath.Join(txtFiles[0], "not-a-dir")
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if note := next.(model).keyNote; note != "Screenshot: failed" {
+		t.Errorf("Expected a failed screenshot to be noted, got %q", note)
+	}
+}
+
+func TestCopyStatsKey(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color") // screen and tmux wrap the sequence
+	var terminal strings.Builder
+	clipboardOutput = &terminal
+	t.Cleanup(func() { clipboardOutput = os.Stdout })
+
+	m, _ := newTestModel(t)
+	m.currentDownload, m.currentUpload = 2048, 1024
+	m.ui.GetStats().Update(1024, 2048)
+
+	summary := m.statsSummary(time.Date(2025, 6, 9, 14, 32, 5, 0, time.UTC))
+	for _, want := range []string{"peaks @ 14:32:05", "now ↓", "peak ↓", "total ↓", " over "} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected the summary to contain %q, got %q", want, summary)
+		}
+	}
+	if strings.Contains(summary, "\x1b") || strings.Contains(summary, "\n") {
+		t.Errorf("Expected a single plain line, got %q", summary)
+	}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = next.(model)
+	sequence := terminal.String()
+	if !strings.HasPrefix(sequence, "\x1b]52;c;") {
+		t.Fatalf("Expected an OSC52 clipboard sequence, got %q", sequence)
+	}
+	payload := strings.TrimRight(strings.TrimPrefix(sequence, "\x1b]52;c;"), "\a")
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || !strings.HasPrefix(string(decoded), "peaks @ ") || !strings.Contains(string(decoded), "now ↓") {
+		t.Errorf("Expected the summary on the clipboard, got %q (%v)", decoded, err)
+	}
+	if m.keyNote != "Copied stats" {
+		t.Errorf("Expected the statusbar to note the copy, got %q", m.keyNote)
+	}
+}
+
+func TestAccessibleAnnouncements(t *testing.T) {
+	a := newAnnouncer(monitor.SourceNetwork)
+	if line := a.announce(); line != "" {
+		t.Errorf("Expected no announcement before any samples, got %q", line)
+	}
+
+	sample := func(upload, download uint64) {
+		a.add([]monitor.Series{{Name: monitor.SeriesUpload, Value: upload}, {Name: monitor.SeriesDownload, Value: download}})
+	}
+	sample(100*1024, 4*1024*1024)
+	sample(140*1024, 44*1024*1024/10)
+	if line, want := a.announce(), "download 4.2 megabytes per second; upload 120 kilobytes per second"; line != want {
+		t.Errorf("Expected %q, got %q", want, line)
+	}
+
+	sample(120*1024, 8*1024*1024)
+	if line, want := a.announce(), "download 8 megabytes per second, rising; upload 120 kilobytes per second, steady"; line != want {
+		t.Errorf("Expected %q, got %q", want, line)
+	}
+	sample(0, 8*1024*1024)
+	if line := a.announce(); !strings.HasSuffix(line, "upload 0 bytes per second, falling") {
+		t.Errorf("Expected upload to be announced as falling, got %q", line)
+	}
+
+	cpu := newAnnouncer(monitor.SourceCPU)
+	cpu.add([]monitor.Series{{Name: "system", Value: 50000}, {Name: "user", Value: 125000}})
+	if line, want := cpu.announce(), "user 12.5 percent; system 5 percent"; line != want {
+		t.Errorf("Expected %q, got %q", want, line)
+	}
+}
+
+func TestInterfaceCycling(t *testing.T) {
+	counters := func(tick uint64) []byte {
+		return []byte(fmt.Sprintf("Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n"+
+			"  eth0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n"+
+			" wlan0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", tick*2000, tick*1000, tick*10, tick*20))
+	}
+	src := &fakeCounterSource{data: counters(1)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	now := time.Now()
+	bm.SetClock(func() time.Time { return now })
+	sample := func(tick uint64) (upload, download uint64) {
+		t.Helper()
+		now = now.Add(time.Second)
+		src.data = counters(tick)
+		series, err := bm.Sample()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return monitor.SplitSeries(series)
+	}
+	sample(1)
+	if upload, download := sample(2); upload != 1020 || download != 2010 {
+		t.Fatalf("Expected both interfaces in the totals, got ↑%d ↓%d", upload, download)
+	}
+
+	var tm tea.Model = initialModel(options{source: monitor.SourceNetwork}, bm)
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	m := tm.(model)
+	press := func(r string) {
+		t.Helper()
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(r)})
+		m = next.(model)
+	}
+
+	press("n")
+	if m.ifaceFocus != "eth0" || bm.Focus() != "eth0" {
+		t.Fatalf("Expected n to focus eth0, got %q", m.ifaceFocus)
+	}
+	if stats := m.ui.GetStats(); stats.PeakDownload != 2000 || stats.TotalDownload != 2000 {
+		t.Errorf("Expected the stats to switch to eth0's own figures, got %+v", stats)
+	}
+	if !strings.Contains(m.View(), "Iface: eth0") {
+		t.Error("Expected the statusbar to show the focused interface")
+	}
+	if upload, download := sample(3); upload != 1000 || download != 2000 {
+		t.Errorf("Expected only eth0's rates while focused, got ↑%d ↓%d", upload, download)
+	}
+	if upload, download := m.recordedRates(1000, 2000); upload != 1020 || download != 2010 {
+		t.Errorf("Expected the ledger and history to keep every interface, got ↑%d ↓%d", upload, download)
+	}
+
+	press("n")
+	if upload, download := sample(4); m.ifaceFocus != "wlan0" || upload != 20 || download != 10 {
+		t.Errorf("Expected n to move on to wlan0, got %q ↑%d ↓%d", m.ifaceFocus, upload, download)
+	}
+	press("n")
+	if m.ifaceFocus != "" || strings.Contains(m.View(), "Iface:") {
+		t.Errorf("Expected n after the last interface to go back to all, got %q", m.ifaceFocus)
+	}
+	press("N")
+	if m.ifaceFocus != "wlan0" {
+		t.Errorf("Expected N to go back to the last interface, got %q", m.ifaceFocus)
+	}
+
+	// Sources without interfaces ignore the keys
+	fm, _ := newTestModel(t)
+	next, _ := fm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if next.(model).ifaceFocus != "" {
+		t.Error("Expected n to do nothing without a network source")
+	}
+}
+
+func TestGhostOverlay(t *testing.T) {
+	history, err := accounting.OpenHistory(t.TempDir(), accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	now := time.Now()
+	record := func(start time.Time, count int, download uint64) {
+		for i := range count {
+			if err := history.Record(start.Add(time.Duration(i)*updateInterval), 0, download); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	record(now.Add(-3*time.Hour), 10, 9000)
+	record(now.Add(-time.Hour), 6, 4000)
+	record(now.Add(time.Second), 2, 100) // this session
+
+	session, err := history.LastSession(now)
+	if err != nil || len(session) != 6 || session[0].Download != 4000 {
+		t.Fatalf("Expected the last session before now to be the 6 samples an hour ago, got %d (%v)", len(session), err)
+	}
+
+	m, _ := newTestModel(t)
+	press := func() tea.Cmd {
+		next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+		m = next.(model)
+		return cmd
+	}
+	press()
+	if m.chart.HasGhost() || m.ghostNote != "Ghost: needs --history" {
+		t.Errorf("Expected the ghost to need --history, got %q", m.ghostNote)
+	}
+
+	// The history is read by a command, not in Update, and pressing g again
+	// before it finishes cancels the ghost
+	m.history, m.startedAt = history, now
+	load := press()
+	if load == nil || m.chart.HasGhost() || m.ghostNote != "Ghost: loading…" {
+		t.Fatalf("Expected the previous session to be loading, got %q", m.ghostNote)
+	}
+	loaded := load()
+	press()
+	next, _ := m.Update(loaded)
+	m = next.(model)
+	if m.chart.HasGhost() || m.ghostNote != "" {
+		t.Errorf("Expected a cancelled ghost to stay hidden, got %q", m.ghostNote)
+	}
+
+	next, _ = m.Update(press()())
+	m = next.(model)
+	if !m.chart.HasGhost() || !strings.HasPrefix(m.ghostNote, "Ghost: ") || m.ghostNote == "Ghost: needs --history" {
+		t.Fatalf("Expected the previous session to be loaded, got %q", m.ghostNote)
+	}
+
+	// With no live traffic every drawn dot belongs to the ghost
+	hasDots := func() bool {
+		for _, r := range m.chart.Render() {
+			if r > 0x2800 && r <= 0x28FF {
+				return true
+			}
+		}
+		return false
+	}
+	for range 4 {
+		m.chart.AddDataPoint(0, 0)
+	}
+	if !hasDots() {
+		t.Error("Expected the ghost to be drawn behind the empty live chart")
+	}
+	press()
+	if m.chart.HasGhost() || m.ghostNote != "" || hasDots() {
+		t.Error("Expected g to remove the ghost")
+	}
+
+	// Slower samples cover several chart samples; jitter doesn't leave holes
+	base := now.Add(-time.Minute)
+	upload, download := ghostSeries([]accounting.Sample{
+		{Time: base, Download: 1},
+		{Time: base.Add(490 * time.Millisecond), Download: 2},
+		{Time: base.Add(time.Second), Download: 3, Interval: 1},
+	}, updateInterval)
+	if len(upload) != 4 || fmt.Sprint(download) != "[1 2 3 3]" {
+		t.Errorf("Unexpected ghost series %v", download)
+	}
+}
+
+func TestHeaderBar(t *testing.T) {
+	if _, err := parseHeaderFormat("{host} {bogus}"); err == nil {
+		t.Error("Expected an unknown placeholder to be rejected")
+	}
+	values := map[string]string{"host": "web-01", "iface": "eth0", "link": "", "ip": "192.168.1.20", "time": "14:32:05"}
+	if line, want := expandHeader(defaultHeaderFormat, values), "web-01 · eth0 · 192.168.1.20 · 14:32:05"; line != want {
+		t.Errorf("Expected %q, got %q", want, line)
+	}
+	for status, want := range map[monitor.LinkStatus]string{
+		{Up: true, Speed: 2_500_000_000}: "2.5 Gb/s",
+		{Up: true, Speed: 100_000_000}:   "100 Mb/s",
+		{Up: false}:                      "link down",
+		{Up: true}:                       "",
+	} {
+		if got := formatLinkSpeed(status); got != want {
+			t.Errorf("Expected %+v to read %q, got %q", status, want, got)
+		}
+	}
+
+	m, _ := newTestModel(t)
+	chartRows := strings.Count(m.View(), "\n")
+	m.headerFormat = "{host} at {time}"
+	m.refreshHeader(time.Date(2025, 6, 9, 14, 32, 5, 0, time.Local))
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = next.(model)
+	view := m.View()
+	host, _ := os.Hostname()
+	if first := ansi.Strip(strings.SplitN(view, "\n", 2)[0]); !strings.HasPrefix(first, " "+host+" at 14:32:05") {
+		t.Errorf("Expected the header bar on the first line, got %q", first)
+	}
+	if rows := strings.Count(view, "\n"); rows != chartRows {
+		t.Errorf("Expected the chart to give up a row for the header, got %d lines instead of %d", rows+1, chartRows+1)
+	}
+}
+
+func TestColumnCursor(t *testing.T) {
+	m, _ := newTestModel(t)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	m = next.(model)
+	last := time.Date(2025, 6, 9, 14, 32, 5, 0, time.Local)
+	m.chart.SetClock(func() time.Time { return last })
+	for i := range 5 {
+		m.chart.AddDataPoint(uint64(i+1)*1024, uint64(i+1)*2048)
+	}
+	m.sawData = true // samples added straight to the chart, not sampled
+	press := func(k tea.KeyType) {
+		next, _ := m.Update(tea.KeyMsg{Type: k})
+		m = next.(model)
+	}
+
+	press(tea.KeyLeft)
+	press(tea.KeyLeft)
+	upload, download, samplesAgo, ok := m.chart.CursorColumn()
+	if !ok || samplesAgo != 1 || upload != 4*1024 || download != 4*2048 {
+		t.Fatalf("Expected the cursor on the second newest sample, got ↑%d ↓%d %d ago", upload, download, samplesAgo)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Cursor 14:32:04 ↓8.00 KB/s ↑4.00 KB/s") {
+		t.Errorf("Expected the statusbar to read out the cursor column, got %q", ansi.Strip(view))
+	}
+	if !strings.Contains(view, "\x1b[7m") {
+		t.Error("Expected the cursor column to be highlighted")
+	}
+
+	// The cursor stays on the same sample as the chart scrolls
+	m.chart.AddDataPoint(0, 0)
+	if _, download, _, _ := m.chart.CursorColumn(); download != 4*2048 {
+		t.Errorf("Expected the cursor to follow its sample, got ↓%d", download)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftLeft})
+	m = next.(model)
+	if _, _, _, ok := m.chart.CursorColumn(); ok || !strings.Contains(m.View(), "Cursor: no data") {
+		t.Error("Expected shift+left to jump past the oldest sample")
+	}
+
+	// Esc hides the cursor rather than quitting
+	press(tea.KeyEsc)
+	if m.chart.Cursor() >= 0 || m.quitting {
+		t.Error("Expected esc to hide the cursor without quitting")
+	}
+	press(tea.KeyLeft)
+	press(tea.KeyRight)
+	if m.chart.Cursor() >= 0 {
+		t.Error("Expected moving past the newest column to hide the cursor")
+	}
+}
+
+func TestChartSmoothing(t *testing.T) {
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetWidth(20)
+	ch.SetHeight(8)
+	ch.SetScalingMode(chart.ScalingLinear)
+	for i := range 20 {
+		var download uint64 = 100 * 1024
+		if i == 10 {
+			download = 400 * 1024 // a single-sample spike
+		}
+		ch.AddDataPoint(0, download)
+	}
+	// Count the filled rows of the spike's column
+	spikeRows := func() int {
+		rows := 0
+		for _, line := range strings.Split(ch.Render(), "\n") {
+			if cells := []rune(line); len(cells) > 10 && cells[10] != ' ' && cells[10] != 0x2800 {
+				rows++
+			}
+		}
+		return rows
+	}
+	raw := spikeRows()
+	ch.ToggleSmoothing()
+	smoothed := spikeRows()
+	if !ch.IsSmoothing() || smoothed >= raw {
+		t.Errorf("Expected smoothing to lower the spike, got %d rows raw and %d smoothed", raw, smoothed)
+	}
+	if ch.GetMaxValue() != 400*1024 {
+		t.Errorf("Expected the scale to keep the raw peak, got %d", ch.GetMaxValue())
+	}
+
+	m, _ := newTestModel(t)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	if m = next.(model); !m.chart.IsSmoothing() || !strings.Contains(m.View(), "Mode: split, smooth") {
+		t.Error("Expected i to turn smoothing on and show it in the statusbar")
+	}
+}
+
+func TestAxisGutter(t *testing.T) {
+	if _, err := chart.ParseGutter("top"); err == nil {
+		t.Error("Expected an unknown gutter placement to be rejected")
+	}
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetHeight(4)
+	ch.SetScalingMode(chart.ScalingLinear)
+	ch.SetGutter(chart.GutterBoth)
+	ch.FitWidth(34)
+	if ch.GetWidth() != 20 {
+		t.Errorf("Expected FitWidth to leave room for both gutters, got %d columns", ch.GetWidth())
+	}
+	for range 20 {
+		ch.AddDataPoint(0, 512*1024)
+	}
+	lines := strings.Split(ch.Render(), "\n")
+	if width := runewidth.StringWidth(ansi.Strip(lines[0])); width != 34 {
+		t.Errorf("Expected gutters and chart to fill 34 columns, got %d", width)
+	}
+	if top := ansi.Strip(lines[0]); !strings.HasPrefix(top, "  512K ") || !strings.HasSuffix(top, " 512K  ") {
+		t.Errorf("Expected the scale maximum on both sides of the top row, got %q", top)
+	}
+
+	ch.SetGutter(chart.GutterLeft)
+	for _, line := range strings.Split(ch.RenderCompactWithSize(30, 2), "\n") {
+		if width := runewidth.StringWidth(ansi.Strip(line)); width != 30 {
+			t.Errorf("Expected the compact chart with its gutter to fit 30 columns, got %d", width)
+		}
+	}
+}
+
+func TestSideBySideLayout(t *testing.T) {
+	m, _ := newTestModel(t)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 81, Height: 24})
+	for range 2 {
+		next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	}
+	m = next.(model)
+	if m.displayMode != "side-by-side" || m.compare == nil {
+		t.Fatalf("Expected m to cycle to side-by-side, got %q", m.displayMode)
+	}
+	if m.chart.IsOverlayMode() || !m.compare.IsOverlayMode() {
+		t.Error("Expected split on the left and overlay on the right")
+	}
+	if m.chart.GetWidth() != 40 || m.compare.GetWidth() != 40 {
+		t.Errorf("Expected two 40 column halves, got %d and %d", m.chart.GetWidth(), m.compare.GetWidth())
+	}
+
+	// Samples added to the chart show up in its mirror
+	for range 10 {
+		m.chart.AddDataPoint(50*1024, 200*1024)
+	}
+	if !strings.ContainsRune(m.compare.Render(), '⣿') || m.compare.GetDataLength() != 10 {
+		t.Errorf("Expected the mirror to draw the chart's samples, got %d", m.compare.GetDataLength())
+	}
+	for _, line := range strings.Split(m.sideBySideView(), "\n") {
+		if width := runewidth.StringWidth(ansi.Strip(line)); width != 81 {
+			t.Errorf("Expected side-by-side rows to fill 81 columns, got %d", width)
+		}
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	if m = next.(model); m.displayMode != "split" || m.compare != nil || m.chart.GetWidth() != 81 {
+		t.Error("Expected m to return to the full-width split chart")
+	}
+}
+
+func TestTimeRuler(t *testing.T) {
+	ch := chart.NewBrailleChart(100)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	ch.SetDeterministic(func() time.Time { return now })
+	ch.SetWidth(40)
+	for range 40 {
+		now = now.Add(500 * time.Millisecond)
+		ch.AddDataPoint(0, 1024)
+	}
+	if step := ch.RulerStep(500 * time.Millisecond); step != 10*time.Second {
+		t.Errorf("Expected 10s ticks on the 1 minute scale, got %v", step)
+	}
+	// 40 columns of 500ms end at 12:00:20, so 12:00:10 and 12:00:20 are ticked
+	ruler := []rune(ch.RenderRuler(500 * time.Millisecond))
+	if len(ruler) != 40 || ruler[19] != '│' || ruler[39] != '│' || strings.Count(string(ruler), "│") != 2 {
+		t.Errorf("Expected ticks in columns 19 and 39, got %q", string(ruler))
+	}
+	ch.SetTimeScale(chart.TimeScale60Min)
+	if step := ch.RulerStep(500 * time.Millisecond); step != 5*time.Minute {
+		t.Errorf("Expected 5m ticks on the 60 minute scale, got %v", step)
+	}
+
+	m, _ := newTestModel(t)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	height := next.(model).chart.GetHeight()
+	m = next.(model)
+	m.ruler = true
+	m.resizeChart()
+	if m.chart.GetHeight() != height-1 {
+		t.Errorf("Expected the ruler to take one row from the chart, got %d (was %d)", m.chart.GetHeight(), height)
+	}
+	if lines := strings.Split(m.View(), "\n"); len(lines) != 24 {
+		t.Errorf("Expected the frame to still fill 24 rows, got %d", len(lines))
+	}
+}
+
+func TestTimeAxis(t *testing.T) {
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetWidth(45)
+	ch.SetGutter(chart.GutterLeft)
+	// 1 minute scale at 500ms: one label every 10s, 20 columns apart
+	if axis := ch.RenderTimeAxis(500 * time.Millisecond); axis != strings.Repeat(" ", 7)+" -20s                -10s                 now" {
+		t.Errorf("Expected labels every 10s ending at now, got %q", axis)
+	}
+	// 60 minute scale: 30s columns, so a label every 5m
+	ch.SetTimeScale(chart.TimeScale60Min)
+	if axis := strings.Fields(ch.RenderTimeAxis(500 * time.Millisecond)); !slices.Equal(axis, []string{"-20m", "-15m", "-10m", "-5m", "now"}) {
+		t.Errorf("Expected the labels to follow the time scale, got %q", axis)
+	}
+
+	m, _ := newTestModel(t)
+	m.timeAxis = true
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	lines := strings.Split(ansi.Strip(next.View()), "\n")
+	if len(lines) != 24 || !strings.HasSuffix(lines[len(lines)-3], "-90s                 -1m                -30s                 now") {
+		t.Errorf("Expected the 3 minute scale's axis above the statusbar, got %q", lines[len(lines)-3])
+	}
+}
+
+func TestDirectionLabels(t *testing.T) {
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetWidth(30)
+	ch.SetHeight(6)
+	ch.SetScalingMode(chart.ScalingLinear)
+	ch.SetDirectionLabels(true)
+	lines := strings.Split(ch.Render(), "\n")
+	if !strings.HasPrefix(lines[0], "▼ download") || !strings.HasPrefix(lines[len(lines)-1], "▲ upload") {
+		t.Errorf("Expected labels in the left corners of the empty chart, got %q and %q", lines[0], lines[len(lines)-1])
+	}
+
+	// Data reaching the top row hides the download label, never the other way round
+	for range 30 {
+		ch.AddDataPoint(1024, 100*1024)
+	}
+	lines = strings.Split(ch.Render(), "\n")
+	if strings.Contains(lines[0], "download") || !strings.HasPrefix(lines[len(lines)-1], "▲ upload") {
+		t.Errorf("Expected data to hide only the download label, got %q and %q", lines[0], lines[len(lines)-1])
+	}
+	if width := runewidth.StringWidth(lines[len(lines)-1]); width != 30 {
+		t.Errorf("Expected the labelled row to stay 30 columns wide, got %d", width)
+	}
+
+	ch.SetOverlayMode(true)
+	if strings.Contains(ch.Render(), "upload") {
+		t.Error("Expected no labels in overlay mode")
+	}
+}
+
+func TestNarrowPaneWidth(t *testing.T) {
+	m, _ := newTestModel(t)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 12, Height: 20})
+	m = next.(model)
+	for range 30 {
+		m.chart.AddDataPoint(10*1024, 40*1024)
+	}
+	if m.chart.GetWidth() != 12 {
+		t.Errorf("Expected the chart to fit a 12 column pane, got %d", m.chart.GetWidth())
+	}
+	for _, line := range strings.Split(m.renderView(), "\n") {
+		if width := ui.StringWidth(line); width > 12 {
+			t.Errorf("Expected no line wider than the pane, got %d: %q", width, ansi.Strip(line))
+		}
+	}
+
+	ch := chart.NewBrailleChart(100)
+	ch.SetWidth(3)
+	if ch.GetWidth() != chart.MinChartWidth {
+		t.Errorf("Expected widths to clamp at %d, got %d", chart.MinChartWidth, ch.GetWidth())
+	}
+	ch.AddDataPoint(1024, 2048)
+	for _, line := range strings.Split(ch.RenderCompactWithSize(9, 2), "\n") {
+		if width := ui.StringWidth(line); width != 9 {
+			t.Errorf("Expected a 9 column compact chart, got %d", width)
+		}
+	}
+}
+
+// failingCollector fails every sample and reports fixed link states
+type failingCollector struct {
+	err   error
+	links []monitor.LinkStatus
+}
+
+func (f *failingCollector) Sample() ([]monitor.Series, error) { return nil, f.err }
+
+func (f *failingCollector) Links() ([]monitor.LinkStatus, error) { return f.links, nil }
+
+func TestEmptyState(t *testing.T) {
+	m, collector := newTestModel(t)
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "Waiting for traffic…") {
+		t.Errorf("Expected the empty-state screen before any traffic, got %q", view)
+	}
+	collector.upload, collector.download = 1024, 4096
+	next, _ := m.Update(tickMsg{generation: m.tickGeneration})
+	if view := ansi.Strip(next.View()); strings.Contains(view, "Waiting for traffic") {
+		t.Error("Expected the chart once traffic arrives")
+	}
+
+	failing := &failingCollector{
+		err: &os.PathError{Op: "open", Path: "/proc/net/dev", Err: os.ErrPermission},
+		links: []monitor.LinkStatus{
+			{Name: "eth0", Up: true, Speed: 1e9},
+			{Name: "wlan0"},
+		},
+	}
+	var updated tea.Model = initialModel(options{source: monitor.SourceNetwork}, failing)
+	updated, _ = updated.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+	updated, _ = updated.Update(tickMsg{generation: updated.(model).tickGeneration})
+	view := ansi.Strip(updated.View())
+	for _, want := range []string{"Permission denied reading counters", "/proc/net/dev", "eth0         1 Gb/s", "wlan0        down", "--group"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected the empty-state screen to show %q, got %q", want, view)
+		}
+	}
+	if lines := strings.Split(updated.View(), "\n"); len(lines) != 24 {
+		t.Errorf("Expected the empty-state screen to keep the frame at 24 rows, got %d", len(lines))
+	}
+}
+
+func TestLayoutDropsDecorationsByPriority(t *testing.T) {
+	m, collector := newTestModel(t)
+	collector.upload, collector.download = 1024, 4096
+	m.headerFormat, m.ruler = defaultHeaderFormat, true
+
+	tests := []struct {
+		height                                int
+		header, ruler, statusbar, title, help bool
+	}{
+		{24, true, true, true, true, true},
+		{12, true, true, true, true, false},
+		{11, true, true, true, false, false},
+		{10, true, false, true, false, false},
+		{9, false, false, true, false, false},
+		{8, false, false, false, false, false},
+	}
+	for _, tt := range tests {
+		var updated tea.Model = m
+		updated, _ = updated.Update(tea.WindowSizeMsg{Width: 240, Height: tt.height})
+		updated, _ = updated.Update(tickMsg{generation: m.tickGeneration})
+		l := updated.(model).layout()
+		if l.header != tt.header || l.ruler != tt.ruler || l.statusbar != tt.statusbar || l.title != tt.title || l.help != tt.help {
+			t.Errorf("height %d: got %+v", tt.height, l)
+		}
+		if l.chartRows < chart.MinChartHeight {
+			t.Errorf("height %d: chart squeezed to %d rows", tt.height, l.chartRows)
+		}
+		view := updated.View()
+		if lines := strings.Split(view, "\n"); len(lines) > tt.height {
+			t.Errorf("height %d: rendered %d lines", tt.height, len(lines))
+		}
+		if strings.Contains(view, "PEAKS") != tt.title || strings.Contains(view, "q: quit") != tt.help {
+			t.Errorf("height %d: title/help shown does not match the layout", tt.height)
+		}
+	}
+}
+
+func TestDemoCollector(t *testing.T) {
+	collect := func() (phases []monitor.DemoPhase, uploads, downloads []uint64) {
+		demo := monitor.NewDemoCollector(7)
+		for range 6 * 60 {
+			phases = append(phases, demo.Phase())
+			series, err := demo.Sample()
+			if err != nil {
+				t.Fatalf("Sample returned error: %v", err)
+			}
+			uploads = append(uploads, series[0].Value)
+			downloads = append(downloads, series[1].Value)
+		}
+		return phases, uploads, downloads
+	}
+	phases, uploads, downloads := collect()
+	if _, _, again := collect(); !slices.Equal(downloads, again) {
+		t.Error("Expected the same seed to generate the same traffic")
+	}
+
+	peak := func(phase monitor.DemoPhase) (highest uint64) {
+		for i, p := range phases {
+			if p == phase {
+				highest = max(highest, downloads[i])
+			}
+		}
+		return highest
+	}
+	if idle := peak(monitor.DemoIdle); idle > 16*1024 {
+		t.Errorf("Expected idle traffic of a few KB/s, got %d", idle)
+	}
+	if peak(monitor.DemoBurst) < 1024*1024 || peak(monitor.DemoSustained) < 1024*1024 {
+		t.Error("Expected bursts and the sustained download to reach MB/s")
+	}
+	if saturated := peak(monitor.DemoSaturation); saturated != 100*1000*1000/8 {
+		t.Errorf("Expected saturation to pin the download at 100 Mb/s, got %d", saturated)
+	}
+	// The wave swings between about a fifth and nearly twice its mean
+	wave := slices.Index(phases, monitor.DemoWave)
+	if low, high := slices.Min(downloads[wave:wave+20]), slices.Max(downloads[wave:wave+20]); low > 1024*1024 || high < 5*1024*1024 {
+		t.Errorf("Expected the wave to swing between low and high, got %d to %d", low, high)
+	}
+	ramp := slices.Index(phases, monitor.DemoRamp)
+	if start, end := uploads[ramp], uploads[len(uploads)-1]; start > 128*1024 || end < 3*1024*1024 {
+		t.Errorf("Expected the upload to ramp up to MB/s, got %d to %d", start, end)
+	}
+	if phases[len(phases)-1] != monitor.DemoRamp {
+		t.Errorf("Expected the phases to run idle, burst, sustained, saturation, wave, ramp, ended on %v", phases[len(phases)-1])
+	}
+}
+
+func TestRecordModeIsReproducible(t *testing.T) {
+	session := func() string {
+		opts := options{source: monitor.SourceDemo, record: true, seed: 3, headerFormat: "{time}"}
+		collector, err := newCollector(opts)
+		if err != nil {
+			t.Fatalf("newCollector returned error: %v", err)
+		}
+		var m tea.Model = initialModel(opts, collector)
+		m, _ = m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+		for range 100 {
+			m, _ = m.Update(tickMsg{generation: m.(model).tickGeneration})
+			time.Sleep(time.Millisecond) // the wall clock moves; the screen must not care
+		}
+		m, _ = m.Update(headerTickMsg{})
+		return m.View()
+	}
+	first := session()
+	if second := session(); first != second {
+		t.Error("Expected two --record sessions to render identical frames")
+	}
+	view := ansi.Strip(first)
+	if !strings.Contains(view, "12:00:50") || !strings.Contains(view, "Up: 50s") {
+		t.Errorf("Expected the virtual clock to stand 50s after the epoch, got %q", view)
+	}
+
+	// The ledger is stamped by the virtual clock too, not the wall clock
+	opts := options{source: monitor.SourceDemo, record: true, seed: 3}
+	collector, err := newCollector(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(opts, collector)
+	m.ledger = accounting.NewLedger(accounting.NewFileStore(filepath.Join(t.TempDir(), "daily.jsonl")))
+	m.ledger.SetLocation(time.UTC)
+	next, _ := m.Update(tickMsg{generation: m.tickGeneration})
+	if today := next.(model).ledger.Today(); today.Date != recordEpoch.Format(accounting.DateLayout) {
+		t.Errorf("Expected the ledger day of the virtual clock, got %q", today.Date)
+	}
+}
+
+func TestGlance(t *testing.T) {
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := range 20 {
+		at := now.Add(time.Duration(i-20) * time.Second)
+		if err := history.Record(at, 1024, uint64(i+1)*1024*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An hour old sample falls outside the window
+	if err := history.Record(now.Add(-time.Hour), 0, 500*1024*1024); err != nil {
+		t.Fatal(err)
+	}
+	history.Close()
+
+	var out strings.Builder
+	args := []string{"--history", dir, "--window", "1m", "--width", "30", "--height", "4", "--glyphs", "braille", "--plain"}
+	if err := runGlance(args, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 1+4+1 || !strings.Contains(lines[0], "last 1m0s") {
+		t.Fatalf("Expected a title, the chart and a rates line, got:\n%s", out.String())
+	}
+	if rates := lines[len(lines)-1]; !strings.HasPrefix(rates, "↓ 20.00 MB/s  ↑ 1.00 KB/s  at") || strings.Contains(rates, "nothing recorded") {
+		t.Errorf("Expected the latest rates, got %q", rates)
+	}
+	if top := []rune(lines[1]); len(top) != 30 || top[29] != '⣿' {
+		t.Errorf("Expected the newest column to reach the scale maximum, got %q", lines[1])
+	}
+
+	if err := runGlance([]string{"--history", t.TempDir()}, io.Discard); err == nil {
+		t.Error("Expected an error without recent samples")
+	}
+
+	// Only the tail of a long history is read, across chunk boundaries
+	long, err := accounting.OpenHistory(t.TempDir(), accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer long.Close()
+	for i := range 3000 {
+		if err := long.Record(now.Add(time.Duration(i-3000)*time.Second), uint64(i), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent, err := long.RecentSamples(now.Add(-40 * time.Minute))
+	if err != nil || len(recent) != 2400 || recent[0].Upload != 600 || recent[2399].Upload != 2999 {
+		t.Errorf("Expected the last 40 minutes of samples in order, got %d (%v)", len(recent), err)
+	}
+}
+
+func TestCompactStdout(t *testing.T) {
+	// --stop only finds the compact daemon, not status line streamers
+	daemon := regexp.MustCompile(compactDaemonPattern)
+	for cmdline, want := range map[string]bool{
+		"/usr/local/bin/peaks --compact":                      true,
+		"./peaks --compact --time 5 --config /tmp/peaks.toml": true,
+		"peaks --compact-stdout --plain --compact-width 16":   false,
+		"peaks --compact-stdout --stream":                     false,
+	} {
+		if daemon.MatchString(cmdline) != want {
+			t.Errorf("compactDaemonPattern matching %q = %v, want %v", cmdline, !want, want)
+		}
+	}
+	// The daemon gets the display flags it needs to draw like the parent
+	args := compactDaemonArgs(options{source: monitor.SourceNetwork, glyphs: chart.GlyphNameAuto, color: chart.ColorNameAuto, prefixes: ui.PrefixesSI, units: ui.UnitsBits}, false, 1, 1)
+	if cmdline := "peaks " + strings.Join(args, " "); !daemon.MatchString(cmdline) || !strings.Contains(cmdline, " --prefixes si") || !strings.Contains(cmdline, " --units bits") {
+		t.Errorf("Unexpected compact daemon command line %q", cmdline)
+	}
+
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i := range 20 {
+		at := now.Add(time.Duration(i-20) * time.Second)
+		if err := history.Record(at, 1024, uint64(i+1)*1024*1024); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history.Close()
+
+	opts := options{glyphs: chart.GlyphNameBraille}
+	var out strings.Builder
+	if err := runInlineMode(options{glyphs: chart.GlyphNameBraille, historyDir: dir}, time.Minute, 12, true, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSuffix(out.String(), "\n")
+	if strings.Contains(line, "\n") || !strings.HasSuffix(line, " ↓ 20.00 MB/s ↑ 1.00 KB/s") {
+		t.Fatalf("Expected one line ending in the latest rates, got %q", out.String())
+	}
+	if cells := []rune(line)[:12]; cells[11] != '⣿' || strings.ContainsRune(line, '\033') {
+		t.Errorf("Expected a plain one line chart reaching the maximum in its newest cell, got %q", line)
+	}
+
+	// Status line refreshes only read the history: nothing is created or locked
+	empty := t.TempDir()
+	if _, ok := inlineFromHistory(newInlineChart(opts, 12, true), empty, time.Minute, now); ok {
+		t.Error("Expected nothing to show from an empty history")
+	}
+	if entries, _ := os.ReadDir(empty); len(entries) != 0 {
+		t.Errorf("Expected the status line to leave the history directory alone, got %d files", len(entries))
+	}
+	if readOnly, err := accounting.OpenHistoryReadOnly(dir); err != nil || readOnly.Record(now, 1, 1) != os.ErrClosed {
+		t.Errorf("Expected a read-only history to refuse recording (%v)", err)
+	}
+
+	// A recording that stopped isn't shown as a snapshot
+	if _, ok := inlineFromHistory(newInlineChart(opts, 12, true), dir, time.Minute, now.Add(time.Minute)); ok {
+		t.Error("Expected stale history to be skipped")
+	}
+
+	c := newInlineChart(opts, 12, true)
+	if line := c.line(1024, 2048); line != "↓ 2.00 KB/s ↑ 1.00 KB/s" {
+		t.Errorf("Expected only the rates before any data, got %q", line)
+	}
+	if err := runInlineMode(opts, time.Minute, 4, true, false, io.Discard); err == nil {
+		t.Error("Expected an error for a chart narrower than the minimum")
+	}
+}
+
+func TestExportData(t *testing.T) {
+	m, _ := newTestModel(t)
+	start := time.Date(2025, 6, 9, 14, 32, 0, 0, time.UTC)
+	now := start
+	m.chart.SetClock(func() time.Time { return now })
+	m.chart.AddDataPoint(1024, 2048)
+	now = now.Add(500 * time.Millisecond)
+	m.chart.AddGap()
+	now = now.Add(500 * time.Millisecond)
+	m.chart.AddDataPoint(4096, 8192)
+
+	points := m.chart.ExportData(500 * time.Millisecond)
+	if len(points) != 3 || !points[1].Gap || points[2].Download != 8192 {
+		t.Fatalf("Expected two samples around a gap, got %+v", points)
+	}
+	if !points[0].Time.Equal(start) || !points[2].Time.Equal(now) {
+		t.Errorf("Expected samples stamped back from the newest, got %v and %v", points[0].Time, points[2].Time)
+	}
+
+	dir := t.TempDir()
+	path, err := saveExport(dir, outputCSV, points, 500*time.Millisecond, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "timestamp,up,down,total_up,total_down\n" +
+		"2025-06-09T14:32:00Z,1024,2048,512,1024\n" +
+		"2025-06-09T14:32:01Z,4096,8192,2560,5120\n"
+	if filepath.Base(path) != "peaks-20250609-143201.csv" || string(data) != want {
+		t.Errorf("Expected the --output CSV columns without the gap in %s, got:\n%s", filepath.Base(path), data)
+	}
+	path, err = saveExport(dir, outputJSON, points, 500*time.Millisecond, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); filepath.Ext(path) != ".jsonl" || strings.Count(string(data), "\n") != 2 {
+		t.Errorf("Expected two JSON Lines in %s, got:\n%s", path, data)
+	}
+}
+
+func TestStdinCollector(t *testing.T) {
+	tests := []struct {
+		line   string
+		values []float64
+		ok     bool
+	}{
+		{"1718000000 0.42", []float64{0.42}, true},
+		{"2024-06-10T12:00:00Z 120 80", []float64{120, 80}, true},
+		{`{"t": 1718000000, "value": 3}`, []float64{3}, true},
+		{`{"t": "2024-06-10T12:00:00Z", "up": 80, "down": 120}`, []float64{120, 80}, true},
+		{"1718000000", nil, false},
+		{"yesterday 1", nil, false},
+		{"1718000000 -1", nil, false},
+		{"1718000000 1 2 3", nil, false},
+		{`{"t": 1718000000}`, nil, false},
+	}
+	for _, tt := range tests {
+		_, values, err := monitor.ParseValueLine(tt.line)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseValueLine(%q) error = %v, want ok %v", tt.line, err, tt.ok)
+			continue
+		}
+		if tt.ok && !slices.Equal(values, tt.values) {
+			t.Errorf("ParseValueLine(%q) = %v, want %v", tt.line, values, tt.values)
+		}
+	}
+
+	input := "# load average\n1718000000 0.5 2\n\nnot a line\n1718000001 1.25 1\n1718000002 0.75\n"
+	collector := monitor.NewStdinCollector(strings.NewReader(input))
+	deadline := time.Now().Add(time.Second)
+	for !collector.Done() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !collector.Done() {
+		t.Fatal("Expected the collector to finish reading its input")
+	}
+	if skipped := collector.Skipped(); skipped != 1 {
+		t.Errorf("Expected 1 skipped line, got %d", skipped)
+	}
+
+	// The first sample reports the peak of everything read since the start
+	series, _ := collector.Sample()
+	upload, download := monitor.SplitSeries(series)
+	if download != 1250 || upload != 2000 {
+		t.Errorf("Expected the peaks 1.25 above and 2 below the axis in thousandths, got %d and %d", download, upload)
+	}
+	// With nothing new, the last line's values are held
+	series, _ = collector.Sample()
+	upload, download = monitor.SplitSeries(series)
+	if download != 750 || upload != 0 {
+		t.Errorf("Expected the last values 0.75 and 0 to be held, got %d and %d", download, upload)
+	}
+}
+
+func TestInputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.log")
+	if err := os.WriteFile(path, []byte("1718000000 99\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{source: monitor.SourceStdin, inputPath: path}
+	collector, err := newCollector(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(opts, collector)
+	if m.sourceNote != "values from metrics.log" {
+		t.Errorf("Expected the title to name the file, got %q", m.sourceNote)
+	}
+
+	// Lines already in the file are skipped, and appended ones are charted
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fmt.Fprintln(file, "1718000001 0.5 2")
+	var download uint64
+	for deadline := time.Now().Add(2 * time.Second); download == 0; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the appended line to be charted")
+		}
+		series, _ := collector.Sample()
+		_, download = monitor.SplitSeries(series)
+	}
+	if download != 500 {
+		t.Errorf("Expected only the appended line's 0.5, got %d thousandths", download)
+	}
+	if collector.(*monitor.StdinCollector).Done() {
+		t.Error("Expected the file to be followed past its end")
+	}
+	if _, err := newCollector(options{source: monitor.SourceStdin, inputPath: path + ".missing"}); err == nil {
+		t.Error("Expected a missing file to be reported")
+	}
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	tests := []struct {
+		body  string
+		value float64
+		ok    bool
+	}{
+		{`{"status":"success","data":{"resultType":"vector","result":[` +
+			`{"metric":{"device":"eth0"},"value":[1718000000,"1500.5"]},` +
+			`{"metric":{"device":"eth1"},"value":[1718000000,"499.5"]}]}}`, 2000, true},
+		{`{"status":"success","data":{"resultType":"scalar","result":[1718000000,"42"]}}`, 42, true},
+		{`{"status":"success","data":{"resultType":"vector","result":[{"value":[1718000000,"NaN"]}]}}`, 0, true},
+		{`{"status":"success","data":{"resultType":"matrix","result":[]}}`, 0, false},
+		{`{"status":"error","errorType":"bad_data","error":"parse error"}`, 0, false},
+		{`not json`, 0, false},
+	}
+	for _, tt := range tests {
+		value, err := monitor.ParsePrometheusResponse(strings.NewReader(tt.body))
+		if (err == nil) != tt.ok {
+			t.Errorf("ParsePrometheusResponse(%s) error = %v, want ok %v", tt.body, err, tt.ok)
+		} else if tt.ok && value != tt.value {
+			t.Errorf("ParsePrometheusResponse(%s) = %v, want %v", tt.body, value, tt.value)
+		}
+	}
+
+	if _, err := monitor.NewPrometheusCollector("localhost:9090", "up", "", 0); err == nil {
+		t.Error("Expected a URL without a scheme to be rejected")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := "1000"
+		if strings.Contains(r.URL.Query().Get("query"), "transmit") {
+			value = "250"
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"value":[1718000000,%q]}]}}`, value)
+	}))
+	defer server.Close()
+	collector, err := monitor.NewPrometheusCollector(server.URL,
+		"rate(node_network_receive_bytes_total[1m])", "rate(node_network_transmit_bytes_total[1m])", time.Minute)
+	if err != nil {
+		t.Fatalf("NewPrometheusCollector returned error: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		series, err := collector.Sample()
+		upload, download := monitor.SplitSeries(series)
+		if err == nil && download == 1000 && upload == 250 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 1000 down and 250 up from the server, got %d and %d (%v)", download, upload, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRemoteSideBySide(t *testing.T) {
+	m, local := newTestModel(t)
+	remote := &fakeCollector{}
+	m.attachRemote(remote, monitor.SourceWindowsHost)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	m = next.(model)
+	if m.compare != m.remoteChart || m.chart.GetWidth() != 119 || m.remoteChart.GetWidth() != 120 {
+		t.Fatalf("Expected the local and remote charts to share the width, got %d and %d",
+			m.chart.GetWidth(), m.remoteChart.GetWidth())
+	}
+	if !strings.Contains(m.sourceNote, "Windows host adapters") {
+		t.Errorf("Expected the title to name the remote source, got %q", m.sourceNote)
+	}
+
+	// Both sources are sampled on every tick, so their axes stay aligned
+	local.download, remote.download = 100*1024, 3*1024*1024
+	for range 5 {
+		next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+		m = next.(model)
+	}
+	if m.chart.GetDataLength() != 5 || m.remoteChart.GetDataLength() != 5 {
+		t.Errorf("Expected 5 samples on each chart, got %d and %d", m.chart.GetDataLength(), m.remoteChart.GetDataLength())
+	}
+	if !strings.Contains(ansi.Strip(m.statusbar.View()), "Remote: ↓ 3.00 MB/s") {
+		t.Errorf("Expected the remote rates in the statusbar, got %q", ansi.Strip(m.statusbar.View()))
+	}
+
+	// The remote chart follows the local chart's view settings and mode
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m = next.(model)
+	m.remoteChart.Render()
+	if m.remoteChart.GetScalingMode() != m.chart.GetScalingMode() || !m.remoteChart.IsOverlayMode() {
+		t.Error("Expected the remote chart to follow the scaling and overlay mode")
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	if m = next.(model); m.displayMode != "split" || m.compare != m.remoteChart {
+		t.Errorf("Expected m to skip the mirrored side-by-side mode, got %q", m.displayMode)
+	}
+
+	// While paused the remote samples are buffered like the local ones
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	next, _ = next.Update(tickMsg{generation: m.tickGeneration})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if m = next.(model); m.remoteChart.GetDataLength() != 6 {
+		t.Errorf("Expected the paused remote sample to be backfilled, got %d samples", m.remoteChart.GetDataLength())
+	}
+}
+
+func TestAgentConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := &fakeCollector{upload: 1000, download: 3000}
+	done := make(chan error, 1)
+	go func() { done <- serveAgent(ln, collector, monitor.SourceNetwork, io.Discard, 20*time.Millisecond) }()
+
+	// peaks connect charts the agent through the usual flags
+	args, err := connectArgs([]string{ln.Addr().String(), "--smooth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"--source", "peaks://" + ln.Addr().String(), "--smooth"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+	remote, err := newCollector(options{source: args[1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Until the first sample arrives, the agent's rates are a gap
+	if _, err := remote.Sample(); !errors.Is(err, monitor.ErrSampleGap) {
+		t.Errorf("Expected a gap before the first sample, got %v", err)
+	}
+	var series []monitor.Series
+	for deadline := time.Now().Add(2 * time.Second); len(series) == 0; time.Sleep(30 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected samples from the agent")
+		}
+		series, err = remote.Sample()
+		if err != nil && !errors.Is(err, monitor.ErrSampleGap) {
+			t.Fatal(err)
+		}
+	}
+	if up, down := monitor.SplitSeries(series); up != 1000 || down != 3000 {
+		t.Errorf("Expected the agent's rates, got ↑ %d ↓ %d", up, down)
+	}
+	if host, _ := os.Hostname(); remote.(*monitor.AgentCollector).Host() != host {
+		t.Errorf("Expected the agent to introduce itself as %q", host)
+	}
+	if note := sourceNote(args[1]); note != "peaks agent on "+ln.Addr().String() {
+		t.Errorf("Expected the title to name the agent, got %q", note)
+	}
+
+	ln.Close()
+	if err := <-done; err == nil {
+		t.Error("Expected the agent to stop when its listener closes")
+	}
+	if _, err := connectArgs(nil); err == nil {
+		t.Error("Expected peaks connect without an address to fail")
+	}
+	if addr, _ := monitor.AgentAddress("server"); addr != "server:7070" {
+		t.Errorf("Expected the default port, got %q", addr)
+	}
+}
+
+// pipeListener hands in-memory connections to a server
+type pipeListener chan net.Conn
+
+func (l pipeListener) Accept() (net.Conn, error) {
+	if conn, ok := <-l; ok {
+		return conn, nil
+	}
+	return nil, net.ErrClosed
+}
+func (l pipeListener) Close() error   { close(l); return nil }
+func (l pipeListener) Addr() net.Addr { return &net.UnixAddr{Name: "pipe", Net: "pipe"} }
+
+func TestAgentStalledClient(t *testing.T) {
+	server := monitor.NewAgentServer("agent", monitor.SourceNetwork)
+	ln := make(pipeListener)
+	defer ln.Close()
+	go server.Serve(ln)
+
+	// One client never reads, not even the hello; the other reads late
+	stalled, stalledServer := net.Pipe()
+	defer stalled.Close()
+	ln <- stalledServer
+	client, clientServer := net.Pipe()
+	defer client.Close()
+	ln <- clientServer
+	for deadline := time.Now().Add(2 * time.Second); server.Clients() != 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected both clients to connect, got %d", server.Clients())
+		}
+	}
+
+	start := time.Now()
+	for i := range 100 {
+		server.Publish(monitor.AgentSample{Upload: uint64(i)})
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected publishing to never wait on clients, took %v", elapsed)
+	}
+
+	// The late reader gets the hello, then the oldest queued samples in order
+	scanner := bufio.NewScanner(client)
+	var hello monitor.AgentHello
+	if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &hello) != nil || hello.Host != "agent" {
+		t.Fatalf("Expected the hello first, got %q", scanner.Text())
+	}
+	for i := range 3 {
+		var sample monitor.AgentSample
+		if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &sample) != nil || sample.Upload != uint64(i) {
+			t.Fatalf("Expected sample %d, got %q", i, scanner.Text())
+		}
+	}
+
+	// The stalled client is dropped once its write times out
+	for deadline := time.Now().Add(3 * time.Second); server.Clients() != 1; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the stalled client to be dropped, got %d clients", server.Clients())
+		}
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	for arg, want := range map[string][2]string{
+		"edge1:7070":               {"edge1:7070", "peaks://edge1:7070"},
+		"admin@db":                 {"admin@db", "admin@db"},
+		"web=ssh://admin@web:2222": {"web", "ssh://admin@web:2222"},
+		"lab=peaks://lab":          {"lab", "peaks://lab"},
+	} {
+		if label, source := dashboardSource(arg); label != want[0] || source != want[1] {
+			t.Errorf("dashboardSource(%q) = %q, %q, want %q, %q", arg, label, source, want[0], want[1])
+		}
+	}
+
+	var tiles dashboardModel
+	collectors := []*fakeCollector{{upload: 1024, download: 2048}, {download: 4096}, {}}
+	for i, c := range collectors {
+		tiles.addTile(fmt.Sprintf("host%d", i+1), c, chart.GlyphBraille)
+	}
+	var dm tea.Model = tiles
+	dm, _ = dm.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+	dm, _ = dm.Update(tickMsg{})
+	collectors[1].download = 1024
+	dm, _ = dm.Update(tickMsg{})
+
+	// Three hosts fit two to a row at 100 columns
+	view := ansi.Strip(dm.View())
+	if lines := strings.Split(view, "\n"); len(lines) != 23 {
+		t.Errorf("Expected two rows of 11 line tiles and the help, got %d lines", len(lines))
+	}
+	for _, want := range []string{"▸ host1  ↓ 2.00 KB/s ↑ 1.00 KB/s", "host2  ↓ 1.00 KB/s ↑ 0 B/s  peak ↓ 4.00 KB/s", "host3"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the dashboard:\n%s", want, view)
+		}
+	}
+
+	// Tab focuses the next host and enter zooms into it
+	dm, _ = dm.Update(tea.KeyMsg{Type: tea.KeyTab})
+	dm, _ = dm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	view = ansi.Strip(dm.View())
+	if !strings.Contains(view, "▸ host2") || strings.Contains(view, "host1") || strings.Contains(view, "host3") {
+		t.Errorf("Expected only host2 when zoomed:\n%s", view)
+	}
+	if width := dm.(dashboardModel).tiles[1].chart.GetWidth(); width != 100 {
+		t.Errorf("Expected the zoomed chart to span the terminal, got %d cells", width)
+	}
+	dm, _ = dm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !strings.Contains(ansi.Strip(dm.View()), "host3") {
+		t.Error("Expected esc to return to every host")
+	}
+}
+
+func TestSNMPSource(t *testing.T) {
+	// tlv encodes a BER tag, short length and value
+	tlv := func(tag byte, value ...byte) []byte { return append([]byte{tag, byte(len(value))}, value...) }
+	// next splits the first short-length TLV off data
+	next := func(data []byte) (value, rest []byte) { return data[2 : 2+data[1]], data[2+data[1]:] }
+
+	// A fake agent answering every GetRequest with growing Counter64 values
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	requests := make(chan []byte, 16)
+	go func() {
+		buf := make([]byte, 1500)
+		for polls := byte(1); ; polls++ {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			requests <- slices.Clone(buf[:n])
+			message, _ := next(buf[:n])
+			_, message = next(message) // version
+			community, message := next(message)
+			pdu, _ := next(message)
+			requestID, pdu := next(pdu)
+			_, pdu = next(pdu)
+			_, pdu = next(pdu)
+			varbinds, _ := next(pdu)
+			var answers []byte
+			for i := byte(0); len(varbinds) > 0; i++ {
+				var varbind []byte
+				varbind, varbinds = next(varbinds)
+				oid, _ := next(varbind)
+				// Past 4 GiB, with a leading zero byte as agents send it; out
+				// grows twice as fast as in
+				value := []byte{0, 0, 0, 0, 1, 0, 0, polls * (i + 1) * 3, 0xE8}
+				answers = append(answers, tlv(0x30, append(tlv(0x06, oid...), tlv(0x46, value...)...)...)...)
+			}
+			response := tlv(0x02, requestID...)
+			response = append(response, 0x02, 1, 0, 0x02, 1, 0)
+			response = append(response, tlv(0x30, answers...)...)
+			message = append(append(tlv(0x02, 1), tlv(0x04, community...)...), tlv(0xA2, response...)...)
+			conn.WriteTo(tlv(0x30, message...), addr)
+		}
+	}()
+
+	src, err := monitor.NewSNMPSource(conn.LocalAddr().String(), "secret", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := <-requests
+	if !strings.Contains(string(request), "secret") {
+		t.Errorf("Expected the request to carry the community, got % x", request)
+	}
+	// ifHCInOctets.3 and ifHCOutOctets.3
+	for _, oid := range [][]byte{{0x2B, 6, 1, 2, 1, 31, 1, 1, 1, 6, 3}, {0x2B, 6, 1, 2, 1, 31, 1, 1, 1, 10, 3}} {
+		if !strings.Contains(string(request), string(tlv(0x06, oid...))) {
+			t.Errorf("Expected the request to ask for % x, got % x", oid, request)
+		}
+	}
+
+	var counters []monitor.InterfaceCounters
+	for deadline := time.Now().Add(2 * time.Second); len(counters) == 0; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected counters from the agent")
+		}
+		if counters, err = src.ReadCounters(counters[:0], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := counters[0]
+	if c.Name != "if3" || c.BytesRecv < 1<<32 || c.BytesSent <= c.BytesRecv {
+		t.Errorf("Expected if3's 64-bit counters, got %+v", c)
+	}
+
+	if _, err := monitor.NewSNMPSource("router", "public", 0); err == nil {
+		t.Error("Expected an interface index to be required")
+	}
+	if _, _, err := monitor.ParseSNMPResponse(tlv(0x30, append(append(tlv(0x02, 1), tlv(0x04)...),
+		tlv(0xA2, append(append(tlv(0x02, 7), 0x02, 1, 0, 0x02, 1, 0), tlv(0x30, tlv(0x30, append(tlv(0x06, 0x2B), 0x81, 0)...)...)...)...)...)...)); err == nil {
+		t.Error("Expected a missing interface to be reported")
+	}
+}
+
+func TestSSHSource(t *testing.T) {
+	for source, want := range map[string]bool{
+		"admin@server": true, "ssh://server": true, "ssh://admin@server:2222": true,
+		"winhost": false, "nft:inet/out,inet/in": false, "me@host:22": false,
+	} {
+		if got := monitor.IsSSHSource(source); got != want {
+			t.Errorf("IsSSHSource(%q) = %v, want %v", source, got, want)
+		}
+	}
+	if note := sourceNote("ssh://admin@server"); note != "admin@server over ssh" {
+		t.Errorf("Expected the title to name the host, got %q", note)
+	}
+
+	// A fake ssh client prints its arguments' host and two snapshots of
+	// /proc/net/dev, the second after the first sample is taken
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" > " + args + "\n" +
+		"printf 'Inter-|   Receive\\n  eth0: 1000 0 0 0 0 0 0 0 500 0 0 0 0 0 0 0\\n\\n'\n" +
+		"sleep 0.3\n" +
+		"printf '  eth0: 3048 0 0 0 0 0 0 0 1524 0 0 0 0 0 0 0\\n\\n'\n" +
+		"sleep 0.3\n" +
+		"echo 'Permission denied (publickey).' >&2\n" +
+		"exit 255\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	src, err := monitor.NewSSHSource("ssh://admin@server:2222")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counters []monitor.InterfaceCounters
+	for deadline := time.Now().Add(2 * time.Second); len(counters) == 0 || counters[0].BytesRecv != 3048; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the second snapshot's counters, got %+v", counters)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if counters, err = src.ReadCounters(counters[:0], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counters[0].Name != "eth0" || counters[0].BytesSent != 1524 {
+		t.Errorf("Expected eth0's counters, got %+v", counters)
+	}
+	if got, _ := os.ReadFile(args); !strings.HasPrefix(string(got), "-T -o BatchMode=yes -o ServerAliveInterval=15 -p 2222 admin@server ") {
+		t.Errorf("Expected ssh to reach admin@server on port 2222 without prompting, got %q", got)
+	}
+
+	// When the session ends, ssh's reason is reported
+	for deadline := time.Now().Add(2 * time.Second); err == nil; time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected an error once the session ended")
+		}
+		_, err = src.ReadCounters(nil, nil)
+	}
+	if err.Error() != "ssh admin@server: Permission denied (publickey)." {
+		t.Errorf("Expected ssh's own error, got %q", err)
+	}
+
+	// Closing the collector ends a session that would otherwise run on
+	hang := t.TempDir()
+	script = "#!/bin/sh\nexec sleep 60\n"
+	if err := os.WriteFile(filepath.Join(hang, "ssh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", hang+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if src, err = monitor.NewSSHSource("admin@server"); err != nil {
+		t.Fatal(err)
+	}
+	closeCollector(monitor.NewBandwidthMonitorWithSource(src))
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if _, err = src.ReadCounters(nil, nil); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected closing to end the session")
+		}
+	}
+	if _, err := monitor.NewSSHSource("ssh://admin@"); err == nil {
+		t.Error("Expected a source without a host to be rejected")
+	}
+}
+
+func TestBlurredTicksSlowDown(t *testing.T) {
+	m, collector := newTestModel(t)
+	if m.tickInterval() != updateInterval {
+		t.Fatalf("Expected focused ticks every %s, got %s", updateInterval, m.tickInterval())
+	}
+	next, cmd := m.Update(tea.BlurMsg{})
+	m = next.(model)
+	if m.tickInterval() != blurredTickInterval || cmd == nil {
+		t.Fatalf("Expected blurring to restart the ticks every %s, got %s", blurredTickInterval, m.tickInterval())
+	}
+
+	// Totals count the whole span of a slowed tick
+	collector.download = 1000
+	next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+	m = next.(model)
+	if total := m.ui.GetStats().TotalDownload; total != 2000 {
+		t.Errorf("Expected 2 seconds at 1000 B/s to total 2000 B, got %d", total)
+	}
+
+	// Focus restores the sampling rate at once
+	generation := m.tickGeneration
+	next, cmd = m.Update(tea.FocusMsg{})
+	m = next.(model)
+	if m.tickInterval() != updateInterval || m.tickGeneration == generation || cmd == nil || !m.frame.dirty {
+		t.Error("Expected focus to restart fast ticks and redraw")
+	}
+	next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+	if total := next.(model).ui.GetStats().TotalDownload; total != 2500 {
+		t.Errorf("Expected a focused tick to add half a second, got a total of %d", total)
+	}
+}
+
+func TestPluginPanes(t *testing.T) {
+	var panes paneFlag
+	if err := panes.Set("VPN=wg show wg0 endpoints"); err != nil || panes[0].command != "wg show wg0 endpoints" {
+		t.Fatalf("Expected NAME=COMMAND to parse, got %v %v", panes, err)
+	}
+	if err := panes.Set("no command"); err == nil {
+		t.Error("Expected a pane without a command to be rejected")
+	}
+
+	tests := []struct {
+		out  string
+		want []string
+	}{
+		{"endpoint nl-ams-01\n\tlatency 12ms\n", []string{"endpoint nl-ams-01", "    latency 12ms"}},
+		{`{"blocked": 1234, "ratio": "8.2%", "top": ["ads"]}`, []string{"blocked: 1234", "ratio: 8.2%", `top: ["ads"]`}},
+		{`[{"host": "a", "rtt": 12}, {"host": "b", "rtt": 30}, "done"]`, []string{"a  12", "b  30", "done"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		lines, err := parsePaneOutput([]byte(tt.out))
+		if err != nil || !slices.Equal(lines, tt.want) {
+			t.Errorf("parsePaneOutput(%q) = %q, %v, want %q", tt.out, lines, err, tt.want)
+		}
+	}
+	if _, err := parsePaneOutput([]byte(`{"broken":`)); err == nil {
+		t.Error("Expected malformed JSON to be reported")
+	}
+
+	collector := &fakeCollector{}
+	opts := options{source: monitor.SourceNetwork, panes: []paneSpec{{name: "Pi-hole", command: "pihole"}}, paneInterval: time.Minute}
+	var m tea.Model = initialModel(opts, collector)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+	m, cmd := m.Update(paneResultMsg{index: 0, lines: []string{"blocked: 1234"}})
+	if cmd == nil {
+		t.Error("Expected a pane result to schedule the next run")
+	}
+	view := ansi.Strip(m.(model).renderView())
+	if width := m.(model).chart.GetWidth(); width != 100-paneWidth-1 {
+		t.Errorf("Expected the chart to make room for the panes, got %d columns", width)
+	}
+	for _, want := range []string{"Pi-hole", "blocked: 1234"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q beside the chart:\n%s", want, view)
+		}
+	}
+
+	// Narrow terminals give the chart the whole width
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 60, Height: 24})
+	if m.(model).showPanes() || m.(model).chart.GetWidth() != 60 {
+		t.Error("Expected the panes to be hidden when the chart would get too narrow")
+	}
+}
+
+func TestSessionRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	// A session charts a few samples, including a gap and a marker, and
+	// is snapshotted before it crashes
+	crashed, _ := newTestModel(t)
+	crashed.chart.SetScalingMode(chart.ScalingLinear)
+	for i := range 6 {
+		if i == 2 {
+			crashed.chart.AddGap()
+			continue
+		}
+		crashed.chart.AddDataPoint(1024, uint64(i+1)*1024)
+	}
+	crashed.chart.AddMarker()
+	crashed.ui.GetStats().Update(1024, 6*1024)
+	if err := crashed.openSession(path); err != nil || crashed.restoreOffer != nil {
+		t.Fatalf("Expected no snapshot to offer yet, got %v", err)
+	}
+	crashed.saveSessionSnapshot()
+
+	// The next launch offers it, and Enter restores it before its own data
+	m, collector := newTestModel(t)
+	collector.download = 500
+	next, _ := m.Update(tickMsg{generation: m.tickGeneration})
+	m = next.(model)
+	if err := m.openSession(path); err != nil || m.restoreOffer == nil {
+		t.Fatalf("Expected the crashed session to be offered, got %v", err)
+	}
+	if !strings.Contains(ansi.Strip(m.statusbar.View()), "session from") {
+		t.Errorf("Expected the statusbar to offer the session, got %q", ansi.Strip(m.statusbar.View()))
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if m.restoreOffer != nil || m.chart.GetDataLength() != 8 {
+		t.Fatalf("Expected 6 restored samples, a gap and the new sample, got %d", m.chart.GetDataLength())
+	}
+	if m.chart.GetScalingMode() != chart.ScalingLinear {
+		t.Error("Expected the crashed session's scaling to be restored")
+	}
+	if total := m.ui.GetStats().TotalDownload; total != 3*1024+250 {
+		t.Errorf("Expected the totals to add up, got %d", total)
+	}
+	restored := m.chart.Snapshot()
+	if !slices.Equal(restored.Download, []uint64{1024, 2048, 0, 4096, 5120, 6144, 0, 500}) ||
+		!slices.Equal(restored.Gaps, []int{2, 6}) || !slices.Equal(restored.Markers, []int{5}) {
+		t.Errorf("Expected the samples, gaps and marker in order, got %v gaps %v markers %v",
+			restored.Download, restored.Gaps, restored.Markers)
+	}
+
+	// A clean exit leaves nothing behind to offer
+	if err := removeSession(path); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot, err := loadSession(path); snapshot != nil || err != nil {
+		t.Errorf("Expected no snapshot after a clean exit, got %v", err)
+	}
+}
+
+func TestProcessBreakdown(t *testing.T) {
+	table := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n" +
+		"   0: 0100007F:0277 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 23456 1 0000000000000000 100 0 0 10 0\n" +
+		"   1: 0F02000A:C350 5DB8D822:01BB 01 00000000:00000000 00:00000000 00000000  1000        0 34567 1 0000000000000000 20 4 30 10 -1\n" +
+		"   2: 0F02000A:C351 5DB8D822:01BB 06 00000000:00000000 00:00000000 00000000     0        0 0 3 0000000000000000\n"
+	inodes, err := monitor.ParseSocketTable(strings.NewReader(table))
+	if err != nil || len(inodes) != 2 || inodes[631] != 23456 || inodes[50000] != 34567 {
+		t.Fatalf("Expected the two live sockets by port, got %v (%v)", inodes, err)
+	}
+
+	now := time.Unix(1718000000, 0)
+	stats := monitor.NewProcessStats()
+	stats.SetOwnerResolver(func() (map[monitor.SocketKey]monitor.Process, error) {
+		return map[monitor.SocketKey]monitor.Process{
+			{Protocol: monitor.ProtocolTCP, Port: 50000}: {PID: 42, Name: "firefox"},
+			{Protocol: monitor.ProtocolUDP, Port: 5353}:  {PID: 7, Name: "avahi"},
+		}, nil
+	}, func() time.Time { return now })
+	packet := func(outgoing bool, protocol uint8, local uint16, length int) *monitor.PacketInfo {
+		p := &monitor.PacketInfo{Outgoing: outgoing, Protocol: protocol, Length: length, SrcPort: 443, DstPort: local}
+		if outgoing {
+			p.SrcPort, p.DstPort = local, 443
+		}
+		return p
+	}
+	stats.Add(packet(false, monitor.ProtocolTCP, 50000, 8000))
+	stats.Add(packet(true, monitor.ProtocolTCP, 50000, 1000))
+	stats.Add(packet(true, monitor.ProtocolUDP, 5353, 3000))
+	stats.Add(packet(false, monitor.ProtocolTCP, 60000, 500))
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolICMP, Length: 100})
+	now = now.Add(time.Second)
+
+	rates, err := stats.Rates(monitor.SortByTotal)
+	if err != nil || len(rates) != 3 {
+		t.Fatalf("Expected firefox, avahi and unknown traffic, got %+v (%v)", rates, err)
+	}
+	if rates[0].Name != "firefox" || rates[0].Download != 8000 || rates[0].Upload != 1000 {
+		t.Errorf("Expected firefox first at 8000 down and 1000 up, got %+v", rates[0])
+	}
+	if rates[2].Name != "unknown" || rates[2].PID != 0 {
+		t.Errorf("Expected unattributed traffic last under unknown, got %+v", rates[2])
+	}
+
+	stats.Add(packet(false, monitor.ProtocolTCP, 50000, 100))
+	stats.Add(packet(true, monitor.ProtocolUDP, 5353, 400))
+	now = now.Add(time.Second)
+	if rates, _ = stats.Rates(monitor.SortByUpload); len(rates) != 2 || rates[0].Name != "avahi" {
+		t.Errorf("Expected only the new traffic, sorted by upload, got %+v", rates)
+	}
+
+	// macOS tallies nettop's per-process counters instead
+	nettop := "time,,bytes_in,bytes_out,\n" +
+		"10:00:00.000001,Google Chrome H.512,1000,100,\n" +
+		"10:00:00.000002,ssh.77,50,50,\n" +
+		"time,,bytes_in,bytes_out,\n" +
+		"10:00:01.000001,Google Chrome H.512,9000,1100,\n" +
+		"10:00:01.000002,ssh.77,50,50,\n" +
+		"10:00:01.000003,curl.90,700,10,\n"
+	var samples []map[monitor.Process][2]uint64
+	if err := monitor.ParseNettop(strings.NewReader(nettop), func(sample map[monitor.Process][2]uint64) {
+		samples = append(samples, sample)
+	}); err != nil || len(samples) != 2 || samples[1][monitor.Process{PID: 512, Name: "Google Chrome H"}] != [2]uint64{1100, 9000} {
+		t.Fatalf("Expected two nettop samples by process, got %v (%v)", samples, err)
+	}
+	counted := monitor.NewProcessStats()
+	counted.SetOwnerResolver(nil, func() time.Time { return now })
+	counted.AddCounters(samples[0])
+	now = now.Add(2 * time.Second)
+	counted.AddCounters(samples[1])
+	rates, err = counted.Rates(monitor.SortByTotal)
+	if err != nil || len(rates) != 1 || rates[0].Name != "Google Chrome H" || rates[0].Download != 4000 || rates[0].Upload != 500 {
+		t.Errorf("Expected only Chrome's new traffic over 2s, got %+v (%v)", rates, err)
+	}
+	if again, _ := counted.Rates(monitor.SortByTotal); len(again) != 1 || again[0] != rates[0] {
+		t.Errorf("Expected the rates to hold until the next nettop sample, got %+v", again)
+	}
+
+	m, _ := newTestModel(t)
+	m.processes, m.showProcesses = stats, true
+	m.processRates = []monitor.ProcessRates{
+		{Process: monitor.Process{PID: 42, Name: "firefox"}, BandwidthRates: monitor.BandwidthRates{Upload: 1024, Download: 1536 * 1024}},
+	}
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	m = next.(model)
+	view := ansi.Strip(m.renderView())
+	if !strings.Contains(view, "Processes · by total") || !strings.Contains(view, "firefox 42      ↓ 1.5M ↑ 1.0K") {
+		t.Errorf("Expected the process list beside the chart:\n%s", view)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("O")})
+	if m = next.(model); m.processSort != monitor.SortByDownload {
+		t.Errorf("Expected O to sort by download, got %v", m.processSort)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if m = next.(model); m.showProcesses || m.chart.GetWidth() != 120 {
+		t.Error("Expected o to hide the process list and give the chart its width back")
+	}
+}
+
+func TestConnectionList(t *testing.T) {
+	now := time.Unix(1718000000, 0)
+	stats := monitor.NewConnectionStats()
+	stats.SetOwnerResolver(func() (map[monitor.SocketKey]monitor.Process, error) {
+		return map[monitor.SocketKey]monitor.Process{
+			{Protocol: monitor.ProtocolTCP, Port: 50000}: {PID: 42, Name: "firefox"},
+		}, nil
+	}, func() time.Time { return now })
+	local, cdn, dns := netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("1.1.1.1")
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolTCP, Length: 9000, Src: cdn, SrcPort: 443, Dst: local, DstPort: 50000})
+	stats.Add(&monitor.PacketInfo{Outgoing: true, Protocol: monitor.ProtocolTCP, Length: 1000, Src: local, SrcPort: 50000, Dst: cdn, DstPort: 443})
+	stats.Add(&monitor.PacketInfo{Outgoing: true, Protocol: monitor.ProtocolUDP, Length: 80, Src: local, SrcPort: 40000, Dst: dns, DstPort: 53})
+	now = now.Add(time.Second)
+
+	rates, err := stats.Rates(monitor.SortByTotal)
+	if err != nil || len(rates) != 2 {
+		t.Fatalf("Expected two connections, got %+v (%v)", rates, err)
+	}
+	if c := rates[0]; c.Remote.String() != "93.184.216.34:443" || c.Name != "firefox" || c.Download != 9000 || c.Upload != 1000 {
+		t.Errorf("Expected the firefox connection first, got %+v", c)
+	}
+	if c := rates[1]; c.ProtocolName() != "udp" || c.Remote.String() != "1.1.1.1:53" || c.Name != "unknown" {
+		t.Errorf("Expected the DNS query under unknown, got %+v", c)
+	}
+	// Quiet connections drop off
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolTCP, Length: 500, Src: cdn, SrcPort: 443, Dst: local, DstPort: 50000})
+	now = now.Add(time.Second)
+	if rates, _ = stats.Rates(monitor.SortByTotal); len(rates) != 1 || rates[0].Download != 500 {
+		t.Errorf("Expected only the active connection, got %+v", rates)
+	}
+
+	m, _ := newTestModel(t)
+	m.connections, m.showConnections = stats, true
+	m.connectionRates = []monitor.ConnectionRates{{
+		ConnectionKey:  monitor.ConnectionKey{Protocol: monitor.ProtocolTCP, LocalPort: 50000, Remote: netip.AddrPortFrom(cdn, 443)},
+		Process:        monitor.Process{PID: 42, Name: "firefox"},
+		BandwidthRates: monitor.BandwidthRates{Upload: 1024, Download: 1536 * 1024},
+	}}
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	m = next.(model)
+	view := ansi.Strip(m.renderView())
+	for _, want := range []string{"Connections · by total", "tcp 93.184.216.34:443", "firefox 42    ↓ 1.5M ↑ 1.0K"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the connection list:\n%s", want, view)
+		}
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("C")})
+	if m = next.(model); m.showConnections || m.chart.GetWidth() != 120 {
+		t.Error("Expected C to hide the connection list and give the chart its width back")
+	}
+}
+
+func TestTopTalkers(t *testing.T) {
+	now := time.Unix(1718000000, 0)
+	stats := monitor.NewHostStats()
+	stats.SetClock(func() time.Time { return now })
+	local, cdn, dns := netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("1.1.1.1")
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolTCP, Length: 9000, Src: cdn, SrcPort: 443, Dst: local, DstPort: 50000})
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolUDP, Length: 3000, Src: cdn, SrcPort: 443, Dst: local, DstPort: 50001})
+	stats.Add(&monitor.PacketInfo{Outgoing: true, Protocol: monitor.ProtocolUDP, Length: 4000, Src: local, SrcPort: 40000, Dst: dns, DstPort: 53})
+	now = now.Add(time.Second)
+
+	rates := stats.Rates(monitor.SortByTotal)
+	if len(rates) != 2 || rates[0].Addr != cdn || rates[0].Download != 12000 || rates[1].Addr != dns || rates[1].Upload != 4000 {
+		t.Fatalf("Expected both flows from the CDN summed ahead of DNS, got %+v", rates)
+	}
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolICMP, Length: 100, Src: dns, Dst: local})
+	now = now.Add(time.Second)
+	if rates = stats.Rates(monitor.SortByUpload); len(rates) != 1 || rates[0].Addr != dns || rates[0].Download != 100 {
+		t.Errorf("Expected only the active host, got %+v", rates)
+	}
+
+	m, _ := newTestModel(t)
+	m.talkers = stats
+	m.talkerRates = []monitor.HostRates{{Addr: cdn, BandwidthRates: monitor.BandwidthRates{Upload: 1024, Download: 1536 * 1024}}}
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	m = next.(model)
+	if m.chart.GetWidth() == 120 {
+		t.Error("Expected the top-talkers panel to take room beside the chart")
+	}
+	view := ansi.Strip(m.renderView())
+	for _, want := range []string{"Top talkers · by total", "93.184.216.34   ↓ 1.5M ↑ 1.0K"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the top-talkers panel:\n%s", want, view)
+		}
+	}
+
+	// Host names replace addresses once resolved in the background
+	lookups := 0
+	m.resolver = monitor.NewResolver()
+	m.resolver.SetLookup(func(ctx context.Context, addr string) ([]string, error) {
+		lookups++
+		return []string{"server-93-184-216-34.fra56.r.cloudfront.net."}, nil
+	}, func() time.Time { return now })
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(ansi.Strip(m.renderView()), "…cloudfront.net ↓ 1.5M") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the resolved host name, keeping its domain:\n%s", ansi.Strip(m.renderView()))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.renderView()
+	if lookups != 1 {
+		t.Errorf("Expected the name to be cached after one lookup, got %d", lookups)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if m = next.(model); !m.numericHosts || !strings.Contains(ansi.Strip(m.renderView()), "93.184.216.34   ↓ 1.5M") {
+		t.Error("Expected d to show numeric addresses")
+	}
+}
+
+func TestProtocolBreakdown(t *testing.T) {
+	local, remote := netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("93.184.216.34")
+	for _, tc := range []struct {
+		packet monitor.PacketInfo
+		want   string
+	}{
+		{monitor.PacketInfo{Protocol: monitor.ProtocolTCP, SrcPort: 443, DstPort: 50000}, "HTTPS"},
+		{monitor.PacketInfo{Protocol: monitor.ProtocolUDP, SrcPort: 50000, DstPort: 443}, "QUIC"},
+		{monitor.PacketInfo{Protocol: monitor.ProtocolTCP, SrcPort: 22, DstPort: 8080}, "SSH"},
+		{monitor.PacketInfo{Protocol: monitor.ProtocolUDP, SrcPort: 40000, DstPort: 53}, "DNS"},
+		{monitor.PacketInfo{Protocol: monitor.ProtocolTCP, SrcPort: 40000, DstPort: 40001}, "TCP other"},
+		{monitor.PacketInfo{Protocol: monitor.ProtocolICMPv6}, "ICMP"},
+		{monitor.PacketInfo{Protocol: 47}, "IP 47"},
+	} {
+		if got := monitor.ServiceName(&tc.packet); got != tc.want {
+			t.Errorf("Expected %+v to be %s, got %s", tc.packet, tc.want, got)
+		}
+	}
+
+	now := time.Unix(1718000000, 0)
+	stats := monitor.NewProtocolStats()
+	stats.SetClock(func() time.Time { return now })
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolTCP, Length: 6000, Src: remote, SrcPort: 443, Dst: local, DstPort: 50000})
+	stats.Add(&monitor.PacketInfo{Outgoing: true, Protocol: monitor.ProtocolTCP, Length: 1500, Src: local, SrcPort: 50000, Dst: remote, DstPort: 443})
+	stats.Add(&monitor.PacketInfo{Protocol: monitor.ProtocolTCP, Length: 2500, Src: remote, SrcPort: 22, Dst: local, DstPort: 50001})
+	now = now.Add(time.Second)
+	rates := stats.Rates()
+	if len(rates) != 2 || rates[0].Name != "HTTPS" || rates[0].Download != 6000 || rates[0].Upload != 1500 || rates[1].Name != "SSH" {
+		t.Fatalf("Expected HTTPS ahead of SSH, got %+v", rates)
+	}
+	now = now.Add(time.Second)
+	if rates = stats.Rates(); len(rates) != 0 {
+		t.Errorf("Expected quiet services to be left out, got %+v", rates)
+	}
+
+	m, _ := newTestModel(t)
+	m.protocols, m.showProtocols = stats, true
+	m.protocolRates = []monitor.ClassRates{
+		{Name: "HTTPS", BandwidthRates: monitor.BandwidthRates{Download: 3 * 1024 * 1024}},
+		{Name: "SSH", BandwidthRates: monitor.BandwidthRates{Upload: 1024 * 1024}},
+	}
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	m = next.(model)
+	view := ansi.Strip(m.renderView())
+	for _, want := range []string{"Protocols", strings.Repeat("█", 22) + strings.Repeat("▓", 7), "█ HTTPS     75% ↓ 3.0M", "▓ SSH       25%"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the protocol breakdown:\n%s", want, view)
+		}
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("B")})
+	if m = next.(model); m.showProtocols || m.chart.GetWidth() != 120 {
+		t.Error("Expected B to hide the protocol breakdown and give the chart its width back")
+	}
+}
+
+func TestWifiPanel(t *testing.T) {
+	iw := "Connected to aa:bb:cc:dd:ee:ff (on wlan0)\n\tSSID: home\n\tfreq: 5180\n\tsignal: -52 dBm\n" +
+		"\trx bitrate: 866.7 MBit/s VHT-MCS 9 80MHz short GI VHT-NSS 2\n\ttx bitrate: 780.0 MBit/s VHT-MCS 8 80MHz VHT-NSS 2\n"
+	status := monitor.ParseIwLink(iw)
+	if want := (monitor.WifiStatus{Connected: true, SSID: "home", Signal: -52, Quality: 96, RxRate: 866_700_000, TxRate: 780_000_000}); status != want {
+		t.Errorf("ParseIwLink = %+v, want %+v", status, want)
+	}
+	if monitor.ParseIwLink("Not connected.\n").Connected {
+		t.Error("Expected iw's \"Not connected.\" to parse as disconnected")
+	}
+	airport := "     agrCtlRSSI: -61\n    agrCtlNoise: -92\n          state: running\n     lastTxRate: 585\n" +
+		"           BSSID: aa:bb:cc:dd:ee:ff\n            SSID: café\n"
+	if got, want := monitor.ParseAirport(airport), (monitor.WifiStatus{Connected: true, SSID: "café", Signal: -61, Quality: 78, TxRate: 585_000_000}); got != want {
+		t.Errorf("ParseAirport = %+v, want %+v", got, want)
+	}
+	netsh := "\nThere is 2 interfaces on the system:\n\n    Name                   : Wi-Fi\n    State                  : connected\n" +
+		"    SSID                   : home\n    BSSID                  : aa:bb:cc:dd:ee:ff\n    Signal                 : 92%\n" +
+		"    Receive rate (Mbps)    : 1201\n    Transmit rate (Mbps)   : 864.5\n\n    Name                   : Wi-Fi 2\n    State                  : disconnected\n"
+	if got, want := monitor.ParseNetshWlan(netsh), (monitor.WifiStatus{Interface: "Wi-Fi", Connected: true, SSID: "home", Signal: -54, Quality: 92, RxRate: 1_201_000_000, TxRate: 864_500_000}); got != want {
+		t.Errorf("ParseNetshWlan = %+v, want %+v", got, want)
+	}
+
+	m, _ := newTestModel(t)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	m = updated.(model)
+	if !m.showWifi || !m.wifiReading || cmd == nil {
+		t.Fatal("Expected w to show the Wi-Fi panel and start reading")
+	}
+	if view := ansi.Strip(m.renderView()); !strings.Contains(view, "Reading…") {
+		t.Errorf("Expected the panel to wait for the first read:\n%s", view)
+	}
+	status.Interface = "wlan0"
+	updated, cmd = m.Update(wifiResultMsg{status: status})
+	m = updated.(model)
+	if cmd == nil {
+		t.Error("Expected the next read to be scheduled while the panel is shown")
+	}
+	view := ansi.Strip(m.renderView())
+	for _, want := range []string{"Wi-Fi · wlan0", "home", "Signal ▂▄▆█ -52 dBm 96%", "Link   ↓866.7 Mb/s ↑780 Mb/s"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the Wi-Fi panel:\n%s", want, view)
+		}
+	}
+	updated, _ = m.Update(wifiResultMsg{err: fmt.Errorf("iw: %w", &exec.Error{Name: "iw", Err: exec.ErrNotFound})})
+	m = updated.(model)
+	if view := ansi.Strip(m.renderView()); !strings.Contains(view, "Needs iw") {
+		t.Errorf("Expected a missing iw to be named:\n%s", view)
+	}
+
+	// Hidden, the pending tick ends the reads
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	updated, cmd = updated.Update(wifiTickMsg{})
+	m = updated.(model)
+	if m.showWifi || m.wifiReading || cmd != nil {
+		t.Error("Expected w to hide the Wi-Fi panel and stop reading")
+	}
+}
+
+func TestHistoryReplay(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if start, end, err := parseReplayRange("2h", now); err != nil || !start.Equal(now.Add(-2*time.Hour)) || !end.Equal(now) {
+		t.Errorf("Expected 2h to replay the last two hours, got %v..%v (%v)", start, end, err)
+	}
+	if start, end, err := parseReplayRange("3h..1h", now); err != nil || end.Sub(start) != 2*time.Hour {
+		t.Errorf("Expected 3h..1h to span two hours, got %v..%v (%v)", start, end, err)
+	}
+	for _, bad := range []string{"", "..1h", "1h..3h", "yesterday"} {
+		if _, _, err := parseReplayRange(bad, now); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	start := now.Add(-10 * time.Minute)
+	for i := 1; i <= 4; i++ {
+		history.Record(start.Add(time.Duration(i)*updateInterval), uint64(i*100), uint64(i*1000))
+	}
+	// After a pause, a sample recorded at 1s covers two columns
+	history.SetInterval(time.Second)
+	history.Record(start.Add(time.Minute), 50, 5000)
+	host, _ := os.Hostname()
+	records, err := loadReplay(history, host, start, now)
+	if err != nil || len(records) != 5 {
+		t.Fatalf("Expected the five recorded samples, got %d (%v)", len(records), err)
+	}
+
+	points := replaySeries(records, updateInterval)
+	if len(points) != 7 || points[3].download != 4000 || !points[4].gap || points[6].download != 5000 {
+		t.Fatalf("Expected four samples, a gap and two columns of the slower sample, got %+v", points)
+	}
+
+	m, _ := newTestModel(t)
+	replay := newReplay(points, updateInterval)
+	m.collector = replay
+	m.attachReplay(replay)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	m = next.(model)
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	if m = next.(model); replay.speed != 2 || m.tickInterval() != updateInterval/2 || m.sampleInterval() != updateInterval {
+		t.Errorf("Expected + to double the replay speed, not the sample rate, got %d× at %v", replay.speed, m.tickInterval())
+	}
+
+	var cmd tea.Cmd
+	for range points {
+		next, cmd = m.Update(tickMsg{generation: m.tickGeneration})
+		m = next.(model)
+	}
+	if cmd == nil || !m.clock().Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the clock to follow the recording to its end, got %v", m.clock())
+	}
+	if stats := m.ui.GetStats(); stats.PeakDownload != 5000 || stats.TotalDownload != 10000 {
+		t.Errorf("Expected replayed stats over recorded time, got peak %d total %d", stats.PeakDownload, stats.TotalDownload)
+	}
+	next, cmd = m.Update(tickMsg{generation: m.tickGeneration})
+	m = next.(model)
+	if cmd != nil || !strings.Contains(ansi.Strip(m.statusbar.View()), "Replay finished") {
+		t.Errorf("Expected the replay to stop ticking when it runs out:\n%s", ansi.Strip(m.statusbar.View()))
+	}
+}
+
+func TestHeadlessOutput(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	stream := func(format string) string {
+		t.Helper()
+		var out strings.Builder
+		write, err := newRecordWriter(format, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		collector := newReplay([]replayPoint{
+			{pausedSample{upload: 1000, download: 4000}, start},
+			{pausedSample{upload: 1000, download: 8000}, start.Add(updateInterval)},
+		}, updateInterval)
+		ticks, stop := make(chan time.Time), make(chan os.Signal)
+		var recorded int
+		done := make(chan error)
+		go func() {
+			done <- streamRecords(collector, write, ticks, stop, func(time.Time, uint64, uint64) { recorded++ })
+		}()
+		ticks <- start
+		ticks <- start.Add(updateInterval)
+		stop <- os.Interrupt
+		if err := <-done; err != nil || recorded != 2 {
+			t.Fatalf("Expected two recorded samples, got %d (%v)", recorded, err)
+		}
+		return out.String()
+	}
+
+	lines := strings.Split(strings.TrimSpace(stream(outputJSON)), "\n")
+	var last outputRecord
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[1]), &last) != nil {
+		t.Fatalf("Expected two JSON lines, got %q", lines)
+	}
+	if !last.Time.Equal(start.Add(updateInterval)) || last.Download != 8000 || last.TotalDownload != 6000 || last.TotalUpload != 1000 {
+		t.Errorf("Expected the second sample with running totals, got %+v", last)
+	}
+
+	want := "timestamp,up,down,total_up,total_down\n" +
+		"2025-03-01T12:00:00Z,1000,4000,500,2000\n" +
+		"2025-03-01T12:00:00.5Z,1000,8000,1000,6000\n"
+	if got := stream(outputCSV); got != want {
+		t.Errorf("Expected CSV with a header row:\n%s\ngot:\n%s", want, got)
+	}
+	if _, err := newRecordWriter("xml", io.Discard); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestConfigPreferences(t *testing.T) {
+	if _, err := config.Parse(strings.NewReader("scaling = \"log\"\ncolour = \"red\"\n")); err == nil || !strings.Contains(err.Error(), `2: unknown key "colour"`) {
+		t.Errorf("Expected an unknown key to be reported with its line, got %v", err)
+	}
+	if _, err := config.Parse(strings.NewReader(`upload_color = "red"`)); err == nil {
+		t.Error("Expected a color that isn't #RRGGBB to be rejected")
+	}
+
+	path := filepath.Join(t.TempDir(), "peaks", "config.toml")
+	if _, cfg, err := loadConfig(path); err != nil || !reflect.DeepEqual(cfg, config.Config{}) {
+		t.Fatalf("Expected a missing config file to load empty, got %+v (%v)", cfg, err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte("display_mode = \"sideways\"\n"), 0o644)
+	if _, _, err := loadConfig(path); err == nil {
+		t.Error("Expected an unknown display mode to be rejected")
+	}
+	os.WriteFile(path, []byte(`# Mine
+display_mode = "overlay"   # Both halves from the bottom
+scaling = "log"
+time_scale = "5m"
+interval = "250ms"
+interfaces = ["eth0", "wlan0",]
+download_color = "#3B82F6"
+`), 0o644)
+	_, cfg, err := loadConfig(path)
+	if err != nil || cfg.Interval != 250*time.Millisecond || !slices.Equal(cfg.Interfaces, []string{"eth0", "wlan0"}) {
+		t.Fatalf("Expected the config to load, got %+v (%v)", cfg, err)
+	}
+
+	var m tea.Model = initialModel(options{source: monitor.SourceNetwork, config: cfg, configPath: path}, &fakeCollector{})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	started := m.(model)
+	if started.displayMode != "overlay" || started.chart.GetScalingMode() != chart.ScalingLogarithmic ||
+		started.chart.GetTimeScale() != chart.TimeScale5Min || started.sampleInterval() != 250*time.Millisecond {
+		t.Errorf("Expected the model to start with the saved preferences, got %s, %s, %s at %s", started.displayMode,
+			started.chart.GetScalingMode(), started.chart.GetTimeScale(), started.sampleInterval())
+	}
+	sideBySide := initialModel(options{source: monitor.SourceNetwork, config: cfg, sideBySide: true}, &fakeCollector{})
+	if sideBySide.displayMode != "side-by-side" {
+		t.Errorf("Expected --side-by-side to win over display_mode, got %s", sideBySide.displayMode)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("W")})
+	if note := m.(model).keyNote; !strings.HasPrefix(note, "Saved config.toml") {
+		t.Errorf("Expected W to report the save, got %q", note)
+	}
+	saved, err := config.Load(path)
+	want := config.Config{DisplayMode: "overlay", Scaling: "sqrt", TimeScale: "5m", Interval: 250 * time.Millisecond,
+		Interfaces: []string{"eth0", "wlan0"}, DownloadColor: "#3B82F6"}
+	if err != nil || !reflect.DeepEqual(saved, want) {
+		t.Errorf("Expected W to save the current settings and keep the rest, got %+v (%v)", saved, err)
+	}
+}
+
+func TestThemes(t *testing.T) {
+	if _, err := chart.ParseTheme("neon"); err == nil || !strings.Contains(err.Error(), "default, solarized, gruvbox, monochrome, colorblind") {
+		t.Errorf("Expected an unknown theme to list the built-in ones, got %v", err)
+	}
+	if _, err := config.Parse(strings.NewReader(`theme = gruvbox`)); err == nil {
+		t.Error("Expected an unquoted theme to be rejected")
+	}
+	if err := validateConfig(config.Config{Theme: "neon"}); err == nil {
+		t.Error("Expected an unknown config theme to be rejected")
+	}
+
+	// --theme wins over the config file's theme
+	cfg := config.Config{Theme: "solarized"}
+	if name := initialModel(options{source: monitor.SourceNetwork, config: cfg}, &fakeCollector{}).chart.ThemeName(); name != "solarized" {
+		t.Errorf("Expected the config's theme, got %s", name)
+	}
+	path := filepath.Join(t.TempDir(), "config.toml")
+	var m tea.Model = initialModel(options{source: monitor.SourceNetwork, theme: "gruvbox", config: cfg, configPath: path, stack: []string{"eth0"}}, &fakeCollector{})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 40})
+	if name := m.(model).chart.ThemeName(); name != "gruvbox" {
+		t.Errorf("Expected --theme to win, got %s", name)
+	}
+
+	// T cycles the themes, and stacked charts follow
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("T")})
+	m.View()
+	if note := m.(model).keyNote; note != "Theme: monochrome" {
+		t.Errorf("Expected T to switch to the next theme, got %q", note)
+	}
+	if name := m.(model).stack[0].chart.ThemeName(); name != "monochrome" {
+		t.Errorf("Expected the stacked chart to follow the theme, got %s", name)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("W")})
+	if saved, err := config.Load(path); err != nil || saved.Theme != "monochrome" {
+		t.Errorf("Expected W to save the theme, got %+v (%v)", saved, err)
+	}
+	if next := chart.NextTheme("colorblind"); next.Name != chart.DefaultThemeName {
+		t.Errorf("Expected the themes to wrap around, got %s", next.Name)
+	}
+}
+
+func TestConfigGradients(t *testing.T) {
+	for _, text := range []string{`upload_gradient = []`, `upload_gradient = ["#7F1D1D", "red"]`, `overlap_gradient = "#FCD34D"`} {
+		if _, err := config.Parse(strings.NewReader(text)); err == nil {
+			t.Errorf("Expected %s to be rejected", text)
+		}
+	}
+
+	// A few stops are blended to a full gradient; unusable stops are skipped
+	gradient := chart.GradientFromStops("#000000", "red", "#FFFFFF")
+	if len(gradient.Steps) != 8 || gradient.Steps[0] != "#000000" || gradient.Steps[7] != "#FFFFFF" {
+		t.Errorf("Expected a blended 8 step gradient, got %v", gradient.Steps)
+	}
+	if steps := chart.GradientFromStops("#112233").Steps; len(steps) != 6 || steps[4] != "#112233" {
+		t.Errorf("Expected a single stop to be shaded, got %v", steps)
+	}
+	if steps := chart.GradientFromStops("blue").Steps; len(steps) != 0 {
+		t.Errorf("Expected no usable stops to give an empty gradient, got %v", steps)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte(`download_gradient = ["#0B3D91", "#4FC3F7", "#E1F5FE"]
+overlap_gradient = ["#FFEB3B"]
+`), 0o644)
+	_, cfg, err := loadConfig(path)
+	if err != nil || len(cfg.DownloadGradient) != 3 {
+		t.Fatalf("Expected the gradients to load, got %+v (%v)", cfg, err)
+	}
+	m := initialModel(options{source: monitor.SourceNetwork, theme: "gruvbox", config: cfg, configPath: path}, &fakeCollector{})
+	upload, download, overlap := m.chart.Gradients()
+	gruvbox, _ := chart.ParseTheme("gruvbox")
+	if !slices.Equal(upload.Steps, gruvbox.Upload.Steps) {
+		t.Errorf("Expected the upload gradient to fall back to the theme, got %v", upload.Steps)
+	}
+	if download.Steps[0] != "#0B3D91" || download.Steps[len(download.Steps)-1] != "#E1F5FE" || len(overlap.Steps) != 6 {
+		t.Errorf("Expected the config's gradients, got %v and %v", download.Steps, overlap.Steps)
+	}
+
+	// W keeps the gradients as written
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("W")})
+	if saved, err := config.Load(path); err != nil || !slices.Equal(saved.DownloadGradient, cfg.DownloadGradient) || !slices.Equal(saved.OverlapGradient, []string{"#FFEB3B"}) {
+		t.Errorf("Expected W to keep the gradients, got %+v (%v)", saved, err)
+	}
+}
+
+func TestColorDepth(t *testing.T) {
+	if _, _, err := chart.ParseColorProfile("8bit"); err == nil {
+		t.Error("Expected an unknown color depth to be rejected")
+	}
+	t.Cleanup(func() { lipgloss.SetColorProfile(termenv.Ascii) })
+
+	// Forced depths downsample the gradients instead of emitting truecolor
+	render := func(color string) string {
+		options{color: color}.applyColorProfile()
+		ch := chart.NewBrailleChart(defaultDataPoints)
+		ch.SetWidth(10)
+		ch.SetHeight(4)
+		for range 10 {
+			ch.AddDataPoint(1000, 2000)
+		}
+		return ch.Render()
+	}
+	if out := render("truecolor"); !strings.Contains(out, "38;2;") {
+		t.Errorf("Expected truecolor sequences, got %q", out)
+	}
+	if out := render("256"); !strings.Contains(out, "38;5;") || strings.Contains(out, "38;2;") {
+		t.Errorf("Expected only 256-color sequences, got %q", out)
+	}
+	if out := render("none"); strings.Contains(out, "\x1b[") {
+		t.Errorf("Expected no escape sequences, got %q", out)
+	}
+
+	if (options{color: "auto"}).monochrome() || !(options{color: "none"}).monochrome() {
+		t.Error("Expected only --color none to turn colors off")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !(options{color: "auto"}).monochrome() {
+		t.Error("Expected NO_COLOR to turn colors off")
+	}
+
+	// Without colors, overlay mode draws upload in the left dot column and
+	// download in the right one where only one of them reaches
+	ch := chart.NewBrailleChart(defaultDataPoints)
+	ch.SetOverlayMode(true)
+	ch.SetMonochrome(true)
+	ch.SetScalingMode(chart.ScalingLinear)
+	ch.SetWidth(10)
+	ch.SetHeight(4)
+	for range 10 {
+		ch.AddDataPoint(4000, 1000)
+	}
+	out := ch.Render()
+	var left, right, full bool
+	for _, r := range out {
+		switch dots := r - 0x2800; {
+		case dots == 0xFF:
+			full = true
+		case dots > 0 && dots < 0x100 && dots&0xB8 == 0:
+			left = true
+		case dots > 0 && dots < 0x100 && dots&0x47 == 0:
+			right = true
+		}
+	}
+	if !left || right || !full {
+		t.Errorf("Expected upload alone in the left dot column above solid overlap, got\n%s", out)
+	}
+}
+
+func TestMarkers(t *testing.T) {
+	dir := t.TempDir()
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer history.Close()
+	m, collector := newTestModel(t)
+	m.history = history
+	collector.download = 2048
+	next, _ := m.Update(tickMsg{generation: m.tickGeneration})
+
+	// M prompts for a label; every key is typed into it until Enter
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("M")},
+		{Type: tea.KeyRunes, Runes: []rune("started")},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyRunes, Runes: []rune("backupq")},
+		{Type: tea.KeyBackspace},
+	} {
+		var cmd tea.Cmd
+		if next, cmd = next.Update(msg); cmd != nil {
+			t.Fatalf("Expected %q to be typed into the label", msg)
+		}
+	}
+	if view := next.View(); !strings.Contains(view, "Marker label: started backup") {
+		t.Errorf("Expected the label prompt in the statusbar, got\n%s", view)
+	}
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if len(m.markers) != 1 || m.markers[0].Label != "started backup" || m.keyNote != "Marked: started backup" {
+		t.Fatalf("Expected a labeled marker, got %+v (%q)", m.markers, m.keyNote)
+	}
+	if !strings.Contains(m.chart.Render(), "▾") {
+		t.Error("Expected the marker on the chart")
+	}
+
+	// Esc cancels a marker
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("M")})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("oops")})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m = next.(model); len(m.markers) != 1 || m.annotating {
+		t.Errorf("Expected Esc to cancel the marker, got %+v", m.markers)
+	}
+
+	// A lists the markers beside the chart
+	next, _ = next.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("A")})
+	if view := next.View(); !strings.Contains(view, "Markers") || !strings.Contains(view, "started backup") {
+		t.Errorf("Expected the markers panel, got\n%s", view)
+	}
+
+	// The marker is kept with the history, and in session snapshots
+	saved, err := history.Annotations(time.Time{}, time.Time{})
+	if err != nil || len(saved) != 1 || saved[0].Label != "started backup" {
+		t.Fatalf("Expected the marker in the history, got %+v (%v)", saved, err)
+	}
+	restarted, _ := newTestModel(t)
+	restarted.history = history
+	if err := restarted.loadMarkers(); err != nil || len(restarted.markers) != 1 {
+		t.Errorf("Expected a restart to list the saved marker, got %+v (%v)", restarted.markers, err)
+	}
+	snapshot := next.(model).sessionSnapshot()
+	restarted.restoreOffer = &snapshot
+	restarted.restoreSession()
+	if len(restarted.markers) != 1 {
+		t.Errorf("Expected restoring a session not to repeat its markers, got %+v", restarted.markers)
+	}
+}
+
+func TestThresholdAlerts(t *testing.T) {
+	for input, want := range map[string]uint64{"50MB/s": 50 << 20, "800KiB": 800 << 10, "1.5g": 3 << 29, "4096": 4096} {
+		if got, err := alerts.ParseRate(input); err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d (%v), want %d", input, got, err, want)
+		}
+	}
+	if _, err := alerts.ParseRate("fast"); err == nil {
+		t.Error("Expected an invalid rate to be rejected")
+	}
+
+	watcher := alerts.NewWatcher(alerts.Thresholds{Download: 1000})
+	var states []bool
+	for _, rate := range []uint64{500, 1200, 950, 1100, 850, 1000} {
+		for _, event := range watcher.Check(time.Now(), 5000, rate) {
+			states = append(states, event.Above)
+		}
+	}
+	if !slices.Equal(states, []bool{true, false}) {
+		t.Errorf("Expected one raise and one clear below 90%% of the threshold, got %v", states)
+	}
+
+	dir := t.TempDir()
+	logPath, hookPath := filepath.Join(dir, "alerts.jsonl"), filepath.Join(dir, "hook")
+	collector := &fakeCollector{upload: 100, download: 2 << 20}
+	var m tea.Model = initialModel(options{source: monitor.SourceNetwork, alertThresholds: alerts.Thresholds{Download: 1 << 20},
+		alertLog: logPath, alertCmd: `echo "$PEAKS_ALERT_STATE $PEAKS_ALERT_DIRECTION $PEAKS_ALERT_RATE" > ` + hookPath}, collector)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 240, Height: 24})
+	m, cmd := m.Update(tickMsg{generation: m.(model).tickGeneration})
+	if status := ansi.Strip(m.View()); !strings.Contains(status, "Alert: ↓ 2.00 MB/s > 1.00 MB/s") {
+		t.Errorf("Expected the statusbar to show the alert, got %q", status)
+	}
+	if m.(model).alertFlash != alertFlashTicks {
+		t.Errorf("Expected the statusbar to flash, got %d", m.(model).alertFlash)
+	}
+	if m.(model).alertErr != nil {
+		t.Fatalf("Expected the event to be logged, got %v", m.(model).alertErr)
+	}
+	// The batch holds the next tick and the hook; run the hook
+	for _, c := range cmd().(tea.BatchMsg) {
+		if msg, ok := c().(alertActionMsg); ok && msg.err != nil {
+			t.Fatalf("Expected the hook to run, got %v", msg.err)
+		}
+	}
+	if hook, err := os.ReadFile(hookPath); err != nil || strings.TrimSpace(string(hook)) != "above down 2097152" {
+		t.Errorf("Expected the hook to get the event, got %q (%v)", hook, err)
+	}
+
+	collector.download = 100
+	m, _ = m.Update(tickMsg{generation: m.(model).tickGeneration})
+	if status := ansi.Strip(m.View()); strings.Contains(status, "Alert:") {
+		t.Errorf("Expected the alert to clear, got %q", status)
+	}
+	data, err := os.ReadFile(logPath)
+	var events []alerts.Event
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event alerts.Event
+		if json.Unmarshal([]byte(line), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	if err != nil || len(events) != 2 || !events[0].Above || events[1].Above || events[0].Rate != 2<<20 {
+		t.Errorf("Expected the raise and clear to be logged, got %+v (%v)", events, err)
+	}
+}
+
+func TestStackedCharts(t *testing.T) {
+	counters := func(tick uint64) []byte {
+		return []byte(fmt.Sprintf("Inter-|   Receive |  Transmit\n face |bytes packets|bytes packets\n"+
+			"  eth0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n"+
+			" wlan0: %d 10 0 0 0 0 0 0 %d 10 0 0 0 0 0 0\n", tick*2000, tick*1000, tick*10, tick*20))
+	}
+	src := &fakeCounterSource{data: counters(1)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	now := time.Now()
+	bm.SetClock(func() time.Time { return now })
+
+	var tm tea.Model = initialModel(options{source: monitor.SourceNetwork, stack: parseInterfaceList("eth0, wlan0,ppp0")}, bm)
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	for tick := uint64(1); tick <= 3; tick++ {
+		now = now.Add(time.Second)
+		src.data = counters(tick)
+		tm, _ = tm.Update(tickMsg{generation: tm.(model).tickGeneration})
+	}
+	m := tm.(model)
+	if len(m.stack) != 3 || m.stack[0].chart.GetDataLength() != 3 || m.stack[1].upload != 20 || m.stack[0].download != 2000 {
+		t.Fatalf("Expected each stacked chart to get its interface's rates, got %d charts", len(m.stack))
+	}
+	if m.stack[2].seen || m.stack[2].chart.GetDataLength() != 3 {
+		t.Error("Expected a missing interface to be drawn as gaps, keeping the time axes aligned")
+	}
+
+	// 38 chart rows: four titles, 8 rows per stacked chart and the rest for the main chart
+	if m.chart.GetHeight() != 10 || m.stack[0].chart.GetHeight() != 8 {
+		t.Errorf("Expected the rows split between the charts, got %d and %d", m.chart.GetHeight(), m.stack[0].chart.GetHeight())
+	}
+	view := ansi.Strip(m.View())
+	if lines := strings.Count(view, "\n") + 1; lines != 40 {
+		t.Errorf("Expected the stack to fill the terminal, got %d lines", lines)
+	}
+	for _, title := range []string{"▸ all", "  eth0  ↓", "  wlan0  ↓", "  ppp0  not found"} {
+		if !strings.Contains(view, title) {
+			t.Errorf("Expected a %q title, got:\n%s", title, view)
+		}
+	}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyTab})
+	next, _ = next.Update(tea.KeyMsg{Type: tea.KeyLeft})
+	m = next.(model)
+	if m.chartFocus != 2 || !strings.Contains(ansi.Strip(m.View()), "▸ wlan0") {
+		t.Errorf("Expected tab to move the focus to wlan0, got %d", m.chartFocus)
+	}
+	if status := m.cursorStatus(); !strings.Contains(status, "↓"+m.formatRate(10)) {
+		t.Errorf("Expected the cursor to read out the focused chart, got %q", status)
+	}
+
+	// A short terminal keeps only the charts that fit
+	next, _ = m.Update(tea.WindowSizeMsg{Width: 120, Height: 20})
+	m = next.(model)
+	if _, _, shown := m.stackRows(m.layout().chartRows); shown != 1 || m.chartFocus != 0 {
+		t.Errorf("Expected one stacked chart to fit and the focus to return to the main chart, got %d, %d", shown, m.chartFocus)
+	}
+}
+
+func TestGridlines(t *testing.T) {
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetHeight(12)
+	ch.SetOverlayMode(true)
+	ch.SetGutter(chart.GutterLeft)
+	ch.SetGridlines(true)
+	ch.FitWidth(47)
+	for range 20 {
+		ch.AddDataPoint(0, 500*1024*1024)
+	}
+	lines := strings.Split(ansi.Strip(ch.Render()), "\n")
+	var labels []string
+	for _, line := range lines {
+		label := strings.TrimSpace(line[:6])
+		if strings.Contains(line, "┈") {
+			labels = append(labels, label)
+		}
+		if width := runewidth.StringWidth(line); width != 47 {
+			t.Errorf("Expected gridlines to keep rows 47 columns wide, got %d", width)
+		}
+	}
+	// Log scale decades below the 500M maximum, from the top; 1K falls on the baseline
+	if want := []string{"100M", "10M", "1.0M", "100K", "10K"}; !slices.Equal(labels, want) {
+		t.Errorf("Expected labelled gridlines at %v, got %v:\n%s", want, labels, strings.Join(lines, "\n"))
+	}
+	if strings.Contains(lines[len(lines)-1], "┈") || !strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "0B") {
+		t.Errorf("Expected the baseline to keep its zero label, got %q", lines[len(lines)-1])
+	}
+	// Gridlines stay behind the data: the first 20 columns are filled
+	for _, line := range lines[1:] {
+		if data := []rune(line)[len([]rune(line))-20:]; slices.Contains(data, '┈') {
+			t.Errorf("Expected no gridline over the data, got %q", line)
+		}
+	}
+
+	ch.SetScalingMode(chart.ScalingLinear)
+	ch.SetOverlayMode(false)
+	labels = labels[:0]
+	for _, line := range strings.Split(ansi.Strip(ch.Render()), "\n") {
+		if strings.Contains(line, "┈") {
+			labels = append(labels, strings.TrimSpace(line[:6]))
+		}
+	}
+	// 200M steps, mirrored on both sides of the split axis
+	if want := []string{"400M", "200M", "200M", "400M"}; !slices.Equal(labels, want) {
+		t.Errorf("Expected linear gridlines at %v, got %v", want, labels)
+	}
+}
+
+func TestBitUnits(t *testing.T) {
+	for bps, expected := range map[uint64]string{0: "0 b/s", 100: "800 b/s", 12_500_000: "100.00 Mb/s", 1_250_000_000: "10.00 Gb/s"} {
+		if got := ui.FormatBitrate(bps); got != expected {
+			t.Errorf("FormatBitrate(%d) = %q, expected %q", bps, got, expected)
+		}
+	}
+	for bps, expected := range map[uint64]string{100: "800b", 1_000_000: "8.0Mb", 11_775_000: "94Mb"} {
+		if got := ui.FormatBitrateShort(bps); got != expected {
+			t.Errorf("FormatBitrateShort(%d) = %q, expected %q", bps, got, expected)
+		}
+	}
+	if _, err := ui.ParseUnits("nibbles"); err == nil {
+		t.Error("Expected an error for unknown units")
+	}
+
+	// Scale labels and gridlines fall on round bit rates
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetHeight(12)
+	ch.SetOverlayMode(true)
+	ch.SetGutter(chart.GutterLeft)
+	ch.SetGridlines(true)
+	ch.SetScalingMode(chart.ScalingLinear)
+	ch.SetBitUnits(true)
+	ch.FitWidth(47)
+	for range 20 {
+		ch.AddDataPoint(0, 125_000_000)
+	}
+	lines := strings.Split(ansi.Strip(ch.Render()), "\n")
+	var labels []string
+	for _, line := range lines {
+		if label := strings.TrimSpace(line[:6]); label != "" && !strings.HasPrefix(label, "⠀") {
+			labels = append(labels, label)
+		}
+	}
+	if want := []string{"1.0Gb", "500Mb", "0b"}; !slices.Equal(labels, want) {
+		t.Errorf("Expected bit rate labels %v, got %v:\n%s", want, labels, strings.Join(lines, "\n"))
+	}
+
+	m, _ := newTestModel(t)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	m = updated.(model)
+	m.currentDownload = 12_500_000
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	m = updated.(model)
+	if !m.bitUnits || m.keyNote != "Units: bit/s" || m.formatRateShort(12_500_000) != "100Mb" {
+		t.Errorf("Expected b to switch to bits per second, got %v, %q", m.bitUnits, m.keyNote)
+	}
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "100.00 Mb/s") {
+		t.Errorf("Expected the statusbar in bits per second:\n%s", view)
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	m = updated.(model)
+	if m.bitUnits || m.formatRate(12_500_000) != ui.FormatBandwidth(12_500_000) {
+		t.Error("Expected b again to switch back to bytes per second")
+	}
+}
+
+func TestBytePrefixes(t *testing.T) {
+	t.Cleanup(func() { ui.SetPrefixes(ui.PrefixesBinary) })
+	for _, c := range []struct {
+		prefixes               string
+		rate, total, short     string
+		bps, bytes, shortValue uint64
+	}{
+		{ui.PrefixesBinary, "1.50 KB/s", "1.00 GB", "1.5K", 1536, 1 << 30, 1536},
+		{ui.PrefixesIEC, "1.50 KiB/s", "1.00 GiB", "1.5K", 1536, 1 << 30, 1536},
+		{ui.PrefixesSI, "1.50 MB/s", "2.00 GB", "1.5k", 1_500_000, 2_000_000_000, 1500},
+	} {
+		if err := applyPrefixes(c.prefixes); err != nil {
+			t.Fatal(err)
+		}
+		if rate, total, short := ui.FormatBandwidth(c.bps), ui.FormatBytes(c.bytes), ui.FormatBandwidthShort(c.shortValue); rate != c.rate || total != c.total || short != c.short {
+			t.Errorf("%s: got %q, %q, %q; expected %q, %q, %q", c.prefixes, rate, total, short, c.rate, c.total, c.short)
+		}
+	}
+	if err := applyPrefixes("metric"); err == nil {
+		t.Error("Expected an error for unknown prefixes")
+	}
+	cfg, err := config.Parse(strings.NewReader(`prefixes = "si"`))
+	if err != nil || cfg.Prefixes != ui.PrefixesSI {
+		t.Errorf("Expected prefixes from the config, got %q, %v", cfg.Prefixes, err)
+	}
+	if err := validateConfig(config.Config{Prefixes: "metric"}); err == nil {
+		t.Error("Expected the config's prefixes to be validated")
+	}
+
+	// SI chart labels and gridlines fall on round decimal rates
+	ch := chart.NewBrailleChart(100)
+	ch.SetDeterministic(func() time.Time { return time.Unix(0, 0) })
+	ch.SetHeight(12)
+	ch.SetOverlayMode(true)
+	ch.SetGutter(chart.GutterLeft)
+	ch.SetGridlines(true)
+	ch.SetScalingMode(chart.ScalingLinear)
+	ch.SetDecimalUnits(true)
+	ch.FitWidth(47)
+	for range 20 {
+		ch.AddDataPoint(0, 100_000_000)
+	}
+	var labels []string
+	for _, line := range strings.Split(ansi.Strip(ch.Render()), "\n") {
+		if label := strings.TrimSpace(line[:6]); label != "" && !strings.HasPrefix(label, "⠀") {
+			labels = append(labels, label)
+		}
+	}
+	if want := []string{"100M", "50M", "0B"}; !slices.Equal(labels, want) {
+		t.Errorf("Expected decimal labels %v, got %v", want, labels)
+	}
+}
+
+func TestFixedMaxScale(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.chart.SetScalingMode(chart.ScalingLinear)
+	for range 10 {
+		m.chart.AddDataPoint(0, 10*1024*1024)
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m = next.(model)
+	if got := m.chart.FixedMaxValue(); got != 10*1024*1024 {
+		t.Fatalf("Expected f to lock the scale at the current 10MB/s maximum, got %d", got)
+	}
+	if !strings.Contains(m.keyNote, "Scale locked at 10.00 MB/s") || !strings.Contains(m.scaleName(), "Linear ≤ 10.00 MB/s") {
+		t.Errorf("Expected the lock to be noted, got %q and %q", m.keyNote, m.scaleName())
+	}
+
+	// A burst neither raises the scale nor does quiet traffic lower it
+	m.chart.AddDataPoint(0, 50*1024*1024)
+	for range 200 {
+		m.chart.AddDataPoint(0, 1024)
+	}
+	if got := m.chart.GetMaxValue(); got != 10*1024*1024 {
+		t.Errorf("Expected the locked scale to stay at 10MB/s, got %d", got)
+	}
+	m.chart.Reset()
+	if got := m.chart.GetMaxValue(); got != 10*1024*1024 {
+		t.Errorf("Expected a reset to keep the locked scale, got %d", got)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m = next.(model)
+	m.chart.AddDataPoint(0, 1024*1024)
+	if m.chart.FixedMaxValue() != 0 || m.chart.GetMaxValue() != 1024*1024 {
+		t.Errorf("Expected f again to unlock the scale and follow the data, got %d", m.chart.GetMaxValue())
+	}
+
+	// Followers take the lock with the other view settings
+	follower := chart.NewBrailleChart(100)
+	m.chart.SetFixedMaxValue(100 * 1024 * 1024)
+	follower.Follow(m.chart)
+	follower.AddDataPoint(0, 1024)
+	if got := follower.GetMaxValue(); got != 100*1024*1024 {
+		t.Errorf("Expected a following chart to take the locked scale, got %d", got)
+	}
+}
+
+func TestRollingStats(t *testing.T) {
+	stats := ui.NewStats()
+	now := time.Unix(1000, 0)
+	stats.SetClock(func() time.Time { return now })
+	stats.SetWindows([]time.Duration{time.Minute, 10 * time.Second})
+	// An old sample pruned once outside the longest window
+	stats.Update(0, 999)
+	now = now.Add(2 * time.Minute)
+	for i := uint64(1); i <= 20; i++ {
+		now = now.Add(time.Second)
+		stats.Update(i, i*10)
+	}
+
+	windows := stats.Windowed()
+	if len(windows) != 2 || windows[0].Window != 10*time.Second || windows[1].Samples != 20 {
+		t.Fatalf("Expected the windows shortest first with the last minute's 20 samples, got %+v", windows)
+	}
+	// The last 10 seconds saw 11…20
+	if got := windows[0].Download; got != (ui.RateSummary{Average: 155, Median: 150, P95: 200}) {
+		t.Errorf("Expected the 10s window's average, median and p95, got %+v", got)
+	}
+	if got := windows[1].Upload; got != (ui.RateSummary{Average: 10, Median: 10, P95: 19}) {
+		t.Errorf("Expected the 1m window's average, median and p95, got %+v", got)
+	}
+	stats.Reset()
+	if windows := stats.Windowed(); windows[1].Samples != 0 {
+		t.Errorf("Expected a reset to clear the samples, got %+v", windows)
+	}
+	if _, err := ui.ParseStatsWindows("1m,soon"); err == nil {
+		t.Error("Expected an invalid window to be rejected")
+	}
+
+	m, collector := newTestModel(t)
+	m.width = 100
+	collector.download = 2048
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = next.(model)
+	next, _ = m.Update(tickMsg{generation: m.tickGeneration})
+	m = next.(model)
+	view := ansi.Strip(m.View())
+	for _, want := range []string{"Stats · avg, median, p95", "Last 1m", "Last 15m", "avg    2.00 KB/s"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the stats panel, got:\n%s", want, view)
+		}
+	}
+}
+
+func TestPublicPackages(t *testing.T) {
+	// The public chart wraps the internal one, so a chart embedded
+	// elsewhere draws exactly what peaks draws
+	ch := braillechart.New(braillechart.DefaultMaxPoints)
+	internal := chart.NewBrailleChart(braillechart.DefaultMaxPoints)
+	ch.SetPlainOutput(true)
+	internal.SetPlainOutput(true)
+	ch.SetWidth(20)
+	internal.SetWidth(20)
+	ch.SetHeight(braillechart.MinHeight)
+	internal.SetHeight(braillechart.MinHeight)
+	ch.SetScalingMode(braillechart.ScalingLinear)
+	internal.SetScalingMode(chart.ScalingLinear)
+	var collector bandwidth.Collector = &fakeCollector{upload: 1024, download: 4096}
+	for range 20 {
+		series, err := collector.Sample()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch.AddDataPoint(bandwidth.SplitSeries(series))
+		internal.AddDataPoint(bandwidth.SplitSeries(series))
+	}
+	lines := strings.Split(ch.Render(), "\n")
+	if len(lines) != braillechart.MinHeight || !strings.ContainsRune(lines[0], '⣿') || runewidth.StringWidth(lines[0]) != 20 {
+		t.Errorf("Expected a full-height chart of the samples, got:\n%s", strings.Join(lines, "\n"))
+	}
+	if ch.Render() != internal.Render() || ch.Width() != 20 {
+		t.Errorf("Expected the public chart to draw like the internal one, got:\n%s", ch.Render())
+	}
+	if group, err := bandwidth.ParseInterfaceGroup("LAN=eth0,wlan0"); err != nil || !group.Contains("wlan0") {
+		t.Errorf("Expected the group to parse, got %+v (%v)", group, err)
+	}
+
+	// The constructor takes the baseline, so the first sample has rates
+	now := time.Unix(1718000000, 0)
+	src := &fakeCounterSource{data: procNetDev(0, 1)}
+	bm := monitor.NewBandwidthMonitorWithSource(src)
+	bm.SetClock(func() time.Time { return now })
+	now = now.Add(time.Second)
+	src.data = procNetDev(0, 2)
+	if upload, download, err := bm.GetCurrentRates(); err != nil || upload != 1000 || download != 2000 {
+		t.Errorf("Expected the first sample to report the rates since the baseline, got ↑%d ↓%d (%v)", upload, download, err)
 	}
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+)
+
+// Labeled markers are limited to maxMarkerLabel characters, and the ones
+// made with --history in the last markerLookback are listed on start
+const (
+	maxMarkerLabel = 60
+	markerLookback = 24 * time.Hour
+)
+
+// startMarker prompts for the label of a marker
+func (m *model) startMarker() {
+	m.annotating = true
+	m.markerInput = m.markerInput[:0]
+}
+
+// markerKey edits the marker label being typed: Enter drops the marker and
+// Esc cancels it
+func (m *model) markerKey(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.annotating = false
+		m.dropMarker(string(m.markerInput))
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.annotating = false
+	case tea.KeyBackspace:
+		if len(m.markerInput) > 0 {
+			m.markerInput = m.markerInput[:len(m.markerInput)-1]
+		}
+	case tea.KeySpace:
+		m.markerInput = append(m.markerInput, ' ')
+	case tea.KeyRunes:
+		m.markerInput = append(m.markerInput, msg.Runes...)
+	}
+	if len(m.markerInput) > maxMarkerLabel {
+		m.markerInput = m.markerInput[:maxMarkerLabel]
+	}
+	m.updateStatusbar()
+}
+
+// dropMarker marks the latest sample on the chart and lists the label,
+// saving it with the --history. An empty label cancels.
+func (m *model) dropMarker(label string) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return
+	}
+	m.chart.AddMarker()
+	now := m.clock()
+	annotation := accounting.Annotation{Time: now.UTC(), Label: label}
+	if m.history != nil {
+		var err error
+		if annotation, err = m.history.Annotate(now, label); err != nil {
+			m.historyErr = err
+		}
+	}
+	m.markers = append(m.markers, annotation)
+	m.keyNote = "Marked: " + label
+}
+
+// loadMarkers lists the markers saved with the --history recently
+func (m *model) loadMarkers() error {
+	markers, err := m.history.Annotations(m.clock().Add(-markerLookback), time.Time{})
+	m.markers = append(markers, m.markers...)
+	return err
+}
+
+// toggleMarkers shows or hides the list of markers beside the chart
+func (m *model) toggleMarkers() {
+	m.showMarkers = !m.showMarkers
+	m.resizeChart()
+}
+
+// markerPrompt shows the marker label being typed in place of the statusbar
+func (m model) markerPrompt() string {
+	return pausedStyle.Render("Marker label: "+string(m.markerInput)+"▏") + "  Enter: drop · Esc: cancel"
+}
+
+// markersView renders the markers for the sidebar, newest first
+func (m model) markersView() []string {
+	lines := []string{paneTitleStyle.Render("Markers")}
+	if len(m.markers) == 0 {
+		return append(lines, "None yet, press M to add one")
+	}
+	for i := len(m.markers) - 1; i >= 0; i-- {
+		marker := m.markers[i]
+		at := marker.Time.Local()
+		format := "15:04:05"
+		if !sameDay(at, m.clock()) {
+			format = "Jan 2 15:04"
+		}
+		lines = append(lines, at.Format(format)+"  "+marker.Label)
+	}
+	return lines
+}
+
+// sameDay reports whether two times fall on the same local day
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Local().Date()
+	by, bm, bd := b.Local().Date()
+	return ay == by && am == bm && ad == bd
+}
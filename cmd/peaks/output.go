@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// Headless --output formats
+const (
+	outputJSON = "json"
+	outputCSV  = "csv"
+)
+
+// outputRecord is one sample written by --output: the rates in units per
+// second and the running totals
+type outputRecord struct {
+	Time          time.Time `json:"t"`
+	Upload        uint64    `json:"up"`
+	Download      uint64    `json:"down"`
+	TotalUpload   uint64    `json:"total_up"`
+	TotalDownload uint64    `json:"total_down"`
+}
+
+// csvHeader names the columns of --output csv
+var csvHeader = []string{"timestamp", "up", "down", "total_up", "total_down"}
+
+// newRecordWriter returns a function writing records to out in the given
+// format, one JSON object or CSV row per line, flushed as it is written so
+// pipes see each sample straight away
+func newRecordWriter(format string, out io.Writer) (func(outputRecord) error, error) {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(out)
+		return func(r outputRecord) error { return encoder.Encode(r) }, nil
+	case outputCSV:
+		writer := csv.NewWriter(out)
+		if err := writer.Write(csvHeader); err != nil {
+			return nil, err
+		}
+		writer.Flush()
+		return func(r outputRecord) error {
+			writer.Write([]string{
+				r.Time.Format(time.RFC3339Nano),
+				strconv.FormatUint(r.Upload, 10),
+				strconv.FormatUint(r.Download, 10),
+				strconv.FormatUint(r.TotalUpload, 10),
+				strconv.FormatUint(r.TotalDownload, 10),
+			})
+			writer.Flush()
+			return writer.Error()
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid output format %q (expected json or csv)", format)
+}
+
+// streamRecords writes a record for every sample taken on ticks until stop
+// fires or a write fails. Gaps and failed samples are left out.
+func streamRecords(collector monitor.Collector, write func(outputRecord) error, ticks <-chan time.Time, stop <-chan os.Signal, record func(at time.Time, upload, download uint64)) error {
+	var totalUpload, totalDownload uint64
+	for {
+		select {
+		case at := <-ticks:
+			series, err := collector.Sample()
+			if err != nil {
+				continue
+			}
+			upload, download := monitor.SplitSeries(series)
+			totalUpload += uint64(float64(upload) * updateInterval.Seconds())
+			totalDownload += uint64(float64(download) * updateInterval.Seconds())
+			if record != nil {
+				record(at, upload, download)
+			}
+			if err := write(outputRecord{
+				Time:          at.UTC(),
+				Upload:        upload,
+				Download:      download,
+				TotalUpload:   totalUpload,
+				TotalDownload: totalDownload,
+			}); err != nil {
+				return err
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// runOutputMode samples without a TUI and streams each sample to out as
+// JSON Lines or CSV, for piping into jq, scripts or log collectors
+func runOutputMode(opts options, format string, out io.Writer) error {
+	write, err := newRecordWriter(format, out)
+	if err != nil {
+		return err
+	}
+	collector, err := newCollector(opts)
+	if err != nil {
+		return err
+	}
+	defer closeCollector(collector)
+	ledger, err := newLedger(opts)
+	if err != nil {
+		return err
+	}
+	if ledger != nil {
+		defer ledger.Flush()
+	}
+	history, closeHistory, err := openHistory(opts)
+	if err != nil {
+		return err
+	}
+	defer closeHistory()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, terminationSignals...)
+	defer signal.Stop(sigChan)
+
+	ticker := time.NewTicker(updateInterval)
+	defer ticker.Stop()
+	return streamRecords(collector, write, ticker.C, sigChan, func(at time.Time, upload, download uint64) {
+		if history != nil {
+			history.Record(at, upload, download)
+		}
+		if ledger != nil {
+			ledger.Add(at,
+				uint64(float64(upload)*updateInterval.Seconds()),
+				uint64(float64(download)*updateInterval.Seconds()))
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// Pane sidebar geometry: panes are shown beside the chart only while the
+// chart keeps at least paneMinChartWidth columns
+const (
+	paneWidth         = 30
+	paneMinChartWidth = 40
+)
+
+// paneSpec is one --pane NAME=COMMAND plugin
+type paneSpec struct {
+	name    string
+	command string
+}
+
+// paneFlag collects repeated --pane NAME=COMMAND flags
+type paneFlag []paneSpec
+
+// String implements flag.Value
+func (p *paneFlag) String() string {
+	names := make([]string, len(*p))
+	for i, spec := range *p {
+		names[i] = spec.name
+	}
+	return strings.Join(names, " ")
+}
+
+// Set implements flag.Value
+func (p *paneFlag) Set(value string) error {
+	name, command, ok := strings.Cut(value, "=")
+	name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+	if !ok || name == "" || command == "" {
+		return fmt.Errorf("invalid pane %q (expected NAME=COMMAND)", value)
+	}
+	*p = append(*p, paneSpec{name: name, command: command})
+	return nil
+}
+
+// pane is a plugin pane and the output of its latest run
+type pane struct {
+	paneSpec
+	lines []string
+	err   error
+}
+
+// paneTickMsg asks for the pane at index to be refreshed
+type paneTickMsg struct{ index int }
+
+// paneResultMsg carries the output of one pane run
+type paneResultMsg struct {
+	index int
+	lines []string
+	err   error
+}
+
+var (
+	paneTitleStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#60A5FA", Light: "#2563EB"}).
+			Bold(true)
+	paneErrorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Dark: "#EF4444", Light: "#DC2626"})
+)
+
+// runPaneCmd runs a pane's command through the shell in the background. A
+// run may take at most the refresh interval.
+func runPaneCmd(index int, spec paneSpec, timeout time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		shell, flag := "sh", "-c"
+		if runtime.GOOS == "windows" {
+			shell, flag = "cmd", "/C"
+		}
+		out, err := exec.CommandContext(ctx, shell, flag, spec.command).Output()
+		if err != nil {
+			return paneResultMsg{index: index, err: err}
+		}
+		lines, err := parsePaneOutput(out)
+		return paneResultMsg{index: index, lines: lines, err: err}
+	}
+}
+
+// parsePaneOutput turns a pane command's output into display lines. Output
+// starting with "{" or "[" is JSON: an object becomes one "key: value" line
+// per field, and an array one line per row, with a row object's values
+// separated by two spaces. Anything else is taken as pre-rendered lines,
+// which may carry ANSI colors.
+func parsePaneOutput(out []byte) ([]string, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, nil
+	}
+	switch out[0] {
+	case '{':
+		fields, err := jsonFields(out)
+		if err != nil {
+			return nil, err
+		}
+		lines := make([]string, len(fields))
+		for i, field := range fields {
+			lines[i] = field[0] + ": " + field[1]
+		}
+		return lines, nil
+	case '[':
+		var rows []json.RawMessage
+		if err := json.Unmarshal(out, &rows); err != nil {
+			return nil, err
+		}
+		lines := make([]string, len(rows))
+		for i, row := range rows {
+			if row = bytes.TrimSpace(row); len(row) > 0 && row[0] == '{' {
+				fields, err := jsonFields(row)
+				if err != nil {
+					return nil, err
+				}
+				values := make([]string, len(fields))
+				for j, field := range fields {
+					values[j] = field[1]
+				}
+				lines[i] = strings.Join(values, "  ")
+			} else {
+				lines[i] = jsonText(row)
+			}
+		}
+		return lines, nil
+	}
+	text := strings.NewReplacer("\r\n", "\n", "\t", "    ").Replace(string(out))
+	return strings.Split(text, "\n"), nil
+}
+
+// jsonFields returns a JSON object's keys and values in their original order
+func jsonFields(object []byte) ([][2]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	var fields [][2]string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, [2]string{fmt.Sprint(key), jsonText(value)})
+	}
+	return fields, nil
+}
+
+// jsonText renders a JSON value for display: strings unquoted, anything
+// else compact
+func jsonText(value json.RawMessage) string {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, value) != nil {
+		return string(value)
+	}
+	return compact.String()
+}
+
+// showPanes reports whether the sidebar of plugin panes, the statistics, the
+// process and connection lists, the protocol breakdown, the top talkers and
+// the markers fits beside the chart
+func (m model) showPanes() bool {
+	return (len(m.panes) > 0 || m.showProcesses || m.showConnections || m.showProtocols || m.talkers != nil || m.showStats || m.showWifi || m.showMarkers) && !m.isTiny() && m.width-paneWidth-1 >= paneMinChartWidth
+}
+
+// chartAreaWidth returns the columns left for the chart beside the panes
+func (m model) chartAreaWidth() int {
+	if m.showPanes() {
+		return m.width - paneWidth - 1
+	}
+	return m.width
+}
+
+// paneView renders the sidebar as a column of height lines: the rolling
+// statistics, the process and connection lists, the protocol breakdown, the top talkers and the markers when shown, then each pane's title followed by its latest output, cut off
+// at the bottom when they don't fit
+func (m model) paneView(height int) []string {
+	var lines []string
+	if m.showStats {
+		lines = m.statsView()
+	}
+	if m.showWifi {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.wifiView()...)
+	}
+	if m.showProcesses {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.processView()...)
+	}
+	if m.showConnections {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.connectionView()...)
+	}
+	if m.showProtocols {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.protocolView()...)
+	}
+	if m.talkers != nil {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.talkersView()...)
+	}
+	if m.showMarkers {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.markersView()...)
+	}
+	for _, p := range m.panes {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, paneTitleStyle.Render(p.name))
+		if p.err != nil {
+			lines = append(lines, paneErrorStyle.Render(p.err.Error()))
+		}
+		lines = append(lines, p.lines...)
+	}
+	column := make([]string, height)
+	for i := range column {
+		var line string
+		if i < len(lines) {
+			line = ui.Truncate(lines[i], paneWidth)
+		}
+		column[i] = line
+	}
+	return column
+}
+
+// withPanes sets the panes to the right of the chart area, line by line
+func (m model) withPanes(chartView string) string {
+	left := strings.Split(chartView, "\n")
+	right := m.paneView(len(left))
+	width := m.chartAreaWidth()
+	for i, line := range left {
+		if pad := width - ui.StringWidth(line); pad > 0 {
+			line += strings.Repeat(" ", pad)
+		}
+		left[i] = line + " " + right[i]
+	}
+	return strings.Join(left, "\n")
+}
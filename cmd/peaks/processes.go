@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// maxProcessRows is how many of the busiest processes the list shows
+const maxProcessRows = 10
+
+// toggleProcesses shows or hides the per-process list beside the chart,
+// starting the packet capture (or nettop on macOS) behind it the first time
+func (m *model) toggleProcesses() {
+	m.showProcesses = !m.showProcesses
+	if m.showProcesses && m.processes == nil && m.processErr == nil {
+		stats := monitor.NewProcessStats()
+		counted, err := stats.StartCounters()
+		if err == nil && !counted {
+			err = m.packets.Add(stats.Add)
+		}
+		if err != nil {
+			m.processErr = err
+		} else {
+			m.processes = stats
+		}
+	}
+	m.resizeChart()
+}
+
+// cycleProcessSort orders the process and connection lists by total,
+// download, then upload
+func (m *model) cycleProcessSort() {
+	m.processSort = (m.processSort + 1) % (monitor.SortByUpload + 1)
+	m.refreshProcesses()
+	m.refreshConnections()
+}
+
+// refreshProcesses takes the latest per-process rates while the list is shown
+func (m *model) refreshProcesses() {
+	if !m.showProcesses || m.processes == nil {
+		return
+	}
+	m.processRates, m.processErr = m.processes.Rates(m.processSort)
+}
+
+// processView renders the busiest processes for the sidebar
+func (m model) processView() []string {
+	lines := []string{paneTitleStyle.Render("Processes · by " + m.processSort.String())}
+	switch {
+	case errors.Is(m.processErr, monitor.ErrCaptureUnsupported) || errors.Is(m.processErr, monitor.ErrProcessUnsupported):
+		return append(lines, paneErrorStyle.Render("Needs Linux or macOS"))
+	case m.processes == nil && m.processErr != nil:
+		return append(lines, paneErrorStyle.Render(m.processErr.Error()))
+	case len(m.processRates) == 0:
+		return append(lines, "No TCP/UDP traffic")
+	}
+	for _, p := range m.processRates[:min(len(m.processRates), maxProcessRows)] {
+		name := p.Name
+		if p.PID != 0 {
+			name = fmt.Sprintf("%s %d", p.Name, p.PID)
+		}
+		lines = append(lines, fmt.Sprintf("%-15s ↓%5s ↑%5s",
+			ui.Truncate(name, 15), m.formatRateShort(p.Download), m.formatRateShort(p.Upload)))
+	}
+	return lines
+}
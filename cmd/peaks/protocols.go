@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// maxProtocolRows is how many of the busiest services the protocol
+// breakdown lists and gives a segment of its bar
+const maxProtocolRows = 6
+
+// Segments of the protocol bar differ by glyph as well as color, so they
+// can be told apart without colors
+var (
+	protocolGlyphs = []string{"█", "▓", "▒", "░"}
+	protocolColors = []lipgloss.AdaptiveColor{
+		{Dark: "#60A5FA", Light: "#2563EB"},
+		{Dark: "#34D399", Light: "#059669"},
+		{Dark: "#FBBF24", Light: "#D97706"},
+		{Dark: "#F472B6", Light: "#DB2777"},
+		{Dark: "#A78BFA", Light: "#7C3AED"},
+		{Dark: "#F87171", Light: "#DC2626"},
+	}
+)
+
+// toggleProtocols shows or hides the protocol breakdown beside the chart,
+// starting the packet capture behind it the first time
+func (m *model) toggleProtocols() {
+	m.showProtocols = !m.showProtocols
+	if m.showProtocols && m.protocols == nil && m.protocolErr == nil {
+		stats := monitor.NewProtocolStats()
+		if err := m.packets.Add(stats.Add); err != nil {
+			m.protocolErr = err
+		} else {
+			m.protocols = stats
+		}
+	}
+	m.resizeChart()
+}
+
+// refreshProtocols takes the latest per-service rates while the breakdown
+// is shown
+func (m *model) refreshProtocols() {
+	if !m.showProtocols || m.protocols == nil {
+		return
+	}
+	m.protocolRates = m.protocols.Rates()
+}
+
+// protocolSegment styles the bar segment of the i-th busiest service
+func protocolSegment(i, width int) string {
+	style := lipgloss.NewStyle().Foreground(protocolColors[i%len(protocolColors)])
+	return style.Render(strings.Repeat(protocolGlyphs[i%len(protocolGlyphs)], width))
+}
+
+// protocolView renders the protocol breakdown for the sidebar: a bar split
+// by each service's share of the current bandwidth, then the services with
+// their share and rates
+func (m model) protocolView() []string {
+	lines := []string{paneTitleStyle.Render("Protocols")}
+	switch {
+	case errors.Is(m.protocolErr, monitor.ErrCaptureUnsupported):
+		return append(lines, paneErrorStyle.Render("Needs Linux"))
+	case m.protocols == nil && m.protocolErr != nil:
+		return append(lines, paneErrorStyle.Render(m.protocolErr.Error()))
+	case len(m.protocolRates) == 0:
+		return append(lines, "No IP traffic")
+	}
+
+	var total uint64
+	for _, p := range m.protocolRates {
+		total += p.Upload + p.Download
+	}
+	if total == 0 {
+		return append(lines, "No IP traffic")
+	}
+	shown := m.protocolRates[:min(len(m.protocolRates), maxProtocolRows)]
+	barWidth := paneWidth - 1
+
+	// Cut the bar at the rounded running share so segments add up exactly
+	var bar strings.Builder
+	var cumulative uint64
+	used := 0
+	for i, p := range shown {
+		cumulative += p.Upload + p.Download
+		end := int((cumulative*uint64(barWidth) + total/2) / total)
+		bar.WriteString(protocolSegment(i, end-used))
+		used = end
+	}
+	bar.WriteString(strings.Repeat("·", barWidth-used))
+	lines = append(lines, bar.String())
+
+	for i, p := range shown {
+		share := (p.Upload + p.Download) * 100 / total
+		lines = append(lines, fmt.Sprintf("%s %-9s%3d%% ↓%5s ↑%5s",
+			protocolSegment(i, 1), ui.Truncate(p.Name, 9), share, m.formatRateShort(p.Download), m.formatRateShort(p.Upload)))
+	}
+	return lines
+}
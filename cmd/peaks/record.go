@@ -0,0 +1,24 @@
+package main
+
+import "time"
+
+// recordEpoch is where the virtual clock of a --record session starts. It is
+// in UTC so recordings show the same times whatever the local time zone.
+var recordEpoch = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// virtualClock only moves when advanced: --record steps it by one sample
+// interval per sample, so the times on screen depend on the samples taken
+// rather than on when (or how smoothly) the session ran
+type virtualClock struct {
+	now time.Time
+}
+
+// Now returns the virtual time
+func (c *virtualClock) Now() time.Time {
+	return c.now
+}
+
+// Advance moves the virtual time forward
+func (c *virtualClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// newRemoteCollector creates the --remote collector. Groups only apply to the
+// local source.
+func newRemoteCollector(opts options) (monitor.Collector, error) {
+	opts.source = opts.remote
+	opts.groups = nil
+	return newCollector(opts)
+}
+
+// attachRemote charts a second, usually remote, source on the right of the
+// local chart. Both are sampled on every tick, so their time axes line up,
+// and the remote chart follows the local one's view settings.
+func (m *model) attachRemote(collector monitor.Collector, source string) {
+	m.remote = collector
+	m.remoteChart = chart.NewBrailleChart(defaultDataPoints)
+	m.remoteChart.SetMaxPoints(m.chart.GetMaxPoints())
+	m.remoteChart.SetClock(m.clock)
+	m.remoteChart.Follow(m.chart)
+	m.remoteSource = source
+	m.formatRemote, _ = sourceFormatters(source)
+	if m.bitUnits && byteRates(source) {
+		m.formatRemote = ui.FormatBitrate
+	}
+	if source == monitor.SourceCPU || source == monitor.SourceStdin {
+		m.remoteChart.SetLabelFormatter(m.formatRemote)
+	}
+	note := sourceNote(source)
+	if note == "" {
+		note = source
+	}
+	m.sourceNote = "left: local · right: " + note
+	if m.displayMode == "side-by-side" {
+		m.displayMode = "split"
+	}
+	m.setDisplayMode(m.displayMode)
+}
+
+// sampleRemote adds the remote source's rates to its chart, or buffers them
+// while paused. A failed sample is drawn as a gap so the axes stay aligned.
+func (m *model) sampleRemote() {
+	if m.remote == nil {
+		return
+	}
+	series, err := m.remote.Sample()
+	m.remoteErr = err
+	sample := pausedSample{gap: err != nil}
+	if err == nil {
+		sample.upload, sample.download = monitor.SplitSeries(series)
+		m.remoteUpload, m.remoteDownload = sample.upload, sample.download
+	} else if !errors.Is(err, monitor.ErrSampleGap) {
+		m.frame.dirty = true
+	}
+	m.plotSample(m.remoteChart, &m.pausedRemote, sample)
+}
+
+// remoteStatus describes the remote source's rates for the statusbar
+func (m model) remoteStatus() string {
+	if m.remoteErr != nil && !errors.Is(m.remoteErr, monitor.ErrSampleGap) {
+		return "Remote: " + m.remoteErr.Error()
+	}
+	return "Remote: ↓ " + m.formatRemote(m.remoteDownload) + " ↑ " + m.formatRemote(m.remoteUpload)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// replaySpeeds are the playback speeds +/- step through during --replay
+var replaySpeeds = []int{1, 2, 5, 10, 30, 60}
+
+// errReplayFinished is returned by Sample once every recorded sample has been played
+var errReplayFinished = errors.New("replay finished")
+
+// parseReplayRange parses a --replay range: a duration ago such as 2h, or
+// FROM..TO where either side is an RFC 3339 time or a duration ago. An
+// empty TO is now.
+func parseReplayRange(value string, now time.Time) (start, end time.Time, err error) {
+	from, to, ranged := strings.Cut(value, "..")
+	if start, err = parseTimeFlag(from, now); err != nil {
+		return start, end, err
+	}
+	if start.IsZero() {
+		return start, end, fmt.Errorf("invalid replay range %q (expected 2h or FROM..TO)", value)
+	}
+	end = now
+	if ranged && to != "" {
+		if end, err = parseTimeFlag(to, now); err != nil {
+			return start, end, err
+		}
+	}
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("invalid replay range %q: it ends before it starts", value)
+	}
+	return start, end, nil
+}
+
+// loadReplay reads a host's recorded samples ending in [start, end], oldest
+// first. Minutes already compacted are replayed at their average rate.
+func loadReplay(history *accounting.History, host string, start, end time.Time) ([]accounting.Sample, error) {
+	inRange := func(recordHost string, at time.Time) bool {
+		return recordHost == host && !at.Before(start) && !at.After(end)
+	}
+
+	var records []accounting.Sample
+	minutes, err := history.Minutes()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range minutes {
+		if at := m.Time.Add(time.Minute); inRange(m.Host, at) {
+			records = append(records, accounting.Sample{Time: at, Host: m.Host,
+				Upload: m.Upload / 60, Download: m.Download / 60, Interval: time.Minute.Seconds()})
+		}
+	}
+	samples, err := history.Samples()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range samples {
+		if inRange(s.Host, s.Time) {
+			records = append(records, s)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// replayPoint is one chart sample of a replay and the recorded time it ends at
+type replayPoint struct {
+	pausedSample
+	at time.Time
+}
+
+// replaySeries lays recorded samples out one per chart sample of step, each
+// column taking the peak of the samples it covers. Samples recorded at a
+// slower rate cover several columns. A pause in the recording, when peaks
+// wasn't running, is skipped and drawn as a single gap.
+func replaySeries(records []accounting.Sample, step time.Duration) []replayPoint {
+	var points []replayPoint
+	var t time.Time // End of the last column
+	var pending pausedSample
+	var hasPending bool
+	for _, r := range records {
+		duration := r.Duration(updateInterval)
+		begin, finish := r.Time.Add(-duration), r.Time
+		if t.IsZero() {
+			t = begin
+		}
+		if begin.Sub(t) >= 2*step {
+			if hasPending {
+				t = t.Add(step)
+				points = append(points, replayPoint{pending, t})
+			}
+			points = append(points, replayPoint{pausedSample{gap: true}, begin})
+			t, hasPending = begin, false
+		}
+
+		// Columns ending within half the shorter period of this sample's end are complete
+		slack := min(step, duration) / 2
+		for !t.Add(step).After(finish.Add(slack)) {
+			column := pausedSample{upload: r.Upload, download: r.Download}
+			if hasPending {
+				column.upload = max(column.upload, pending.upload)
+				column.download = max(column.download, pending.download)
+			}
+			t = t.Add(step)
+			points = append(points, replayPoint{column, t})
+			hasPending = false
+		}
+		if finish.After(t) {
+			if !hasPending {
+				pending = pausedSample{}
+			}
+			pending.upload = max(pending.upload, r.Upload)
+			pending.download = max(pending.download, r.Download)
+			hasPending = true
+		}
+	}
+	if hasPending {
+		points = append(points, replayPoint{pending, t.Add(step)})
+	}
+	return points
+}
+
+// replayCollector plays recorded history back as a Collector, one chart
+// sample per Sample call. Its clock is the recorded time being played.
+type replayCollector struct {
+	points []replayPoint
+	next   int
+	now    time.Time
+	speed  int
+}
+
+// newReplayCollector loads this host's history in the --replay range for
+// playback at the chart's sample rate
+func newReplayCollector(opts options, now time.Time) (*replayCollector, error) {
+	start, end, err := parseReplayRange(opts.replay, now)
+	if err != nil {
+		return nil, err
+	}
+	dir := opts.historyDir
+	if dir == "" {
+		dir = autoPath
+	}
+	if dir, err = resolveHistoryDir(dir); err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		return nil, err
+	}
+	defer history.Close()
+
+	records, err := loadReplay(history, host, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no samples for %s between %s and %s in %s", host,
+			start.Local().Format(time.DateTime), end.Local().Format(time.DateTime), dir)
+	}
+	return newReplay(replaySeries(records, updateInterval), updateInterval), nil
+}
+
+// newReplay creates a collector playing points recorded step apart
+func newReplay(points []replayPoint, step time.Duration) *replayCollector {
+	r := &replayCollector{points: points, speed: replaySpeeds[0]}
+	if len(points) > 0 {
+		r.now = points[0].at.Add(-step)
+	}
+	return r
+}
+
+// Sample implements Collector, returning the next recorded rates. A pause in
+// the recording is reported as ErrSampleGap.
+func (r *replayCollector) Sample() ([]monitor.Series, error) {
+	if r.Done() {
+		return nil, errReplayFinished
+	}
+	p := r.points[r.next]
+	r.next++
+	r.now = p.at
+	if p.gap {
+		return nil, monitor.ErrSampleGap
+	}
+	return []monitor.Series{
+		{Name: monitor.SeriesUpload, Value: p.upload},
+		{Name: monitor.SeriesDownload, Value: p.download},
+	}, nil
+}
+
+// Now returns the recorded time played so far
+func (r *replayCollector) Now() time.Time {
+	return r.now
+}
+
+// Done reports whether every recorded sample has been played
+func (r *replayCollector) Done() bool {
+	return r.next >= len(r.points)
+}
+
+// attachReplay runs the model on the replay's recorded time
+func (m *model) attachReplay(r *replayCollector) {
+	m.replay = r
+	m.clock = r.Now
+	m.chart.SetClock(m.clock)
+	m.ui.GetStats().SetClock(m.clock)
+	m.startedAt = m.clock()
+	m.sourceNote = "replay from " + m.startedAt.Local().Format("Jan 2 15:04")
+	m.updateStatusbar()
+}
+
+// stepReplaySpeed plays the replay one step faster or slower along replaySpeeds
+func (m *model) stepReplaySpeed(step int) tea.Cmd {
+	current := sort.SearchInts(replaySpeeds, m.replay.speed)
+	next := min(max(current+step, 0), len(replaySpeeds)-1)
+	if replaySpeeds[next] == m.replay.speed {
+		return nil
+	}
+	m.replay.speed = replaySpeeds[next]
+	m.updateStatusbar()
+	return m.restartTicks()
+}
+
+// replayStatus shows the recorded time being played and the playback speed
+func (m model) replayStatus() string {
+	status := fmt.Sprintf("Replay %d× %s", m.replay.speed, m.clock().Local().Format("Jan 2 15:04:05"))
+	if m.replay.Done() {
+		status = "Replay finished " + m.clock().Local().Format("Jan 2 15:04:05")
+	}
+	return pausedStyle.Render(status)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// defaultReportWindow is how far back peaks report looks without --from
+const defaultReportWindow = 24 * time.Hour
+
+// trafficSummary is one direction's traffic over a report window
+type trafficSummary struct {
+	peak  uint64 // Bytes per second
+	total uint64 // Bytes
+}
+
+// runReport implements "peaks report", summarizing one host's recorded
+// traffic as text or, with --markdown, GitHub-flavored markdown for pasting
+// into issues and incident docs
+func runReport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	markdown := fs.Bool("markdown", false, "write GitHub-flavored markdown with a fenced chart and tables")
+	historyDir := fs.String("history", autoPath, "history directory to read (\"auto\" for the user data directory)")
+	ledgerPath := fs.String("ledger", autoPath, "daily totals file to list (\"auto\" for the user config directory, empty to skip)")
+	host := fs.String("host", "", "host to report on (default: this machine)")
+	from := fs.String("from", "", "start of the window: RFC 3339 time or a duration ago, e.g. 2h (default: 24h)")
+	to := fs.String("to", "", "end of the window (default: now)")
+	width := fs.Int("width", 60, "chart width in columns")
+	height := fs.Int("height", 10, "chart height in lines")
+	prefixes := prefixesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyPrefixes(*prefixes); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	start, err := parseTimeFlag(*from, now)
+	if err != nil {
+		return err
+	}
+	end, err := parseTimeFlag(*to, now)
+	if err != nil {
+		return err
+	}
+	if end.IsZero() {
+		end = now
+	}
+	if start.IsZero() {
+		start = end.Add(-defaultReportWindow)
+	}
+	if !start.Before(end) {
+		return fmt.Errorf("--from must be before --to")
+	}
+	if *host == "" {
+		if *host, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+
+	dir, err := resolveHistoryDir(*historyDir)
+	if err != nil {
+		return err
+	}
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	upload, download, recorded, err := summarizeHistory(history, *host, start, end)
+	if err != nil {
+		return err
+	}
+	if recorded == 0 {
+		return fmt.Errorf("no samples for %s between %s and %s in %s", *host,
+			start.Local().Format(time.DateTime), end.Local().Format(time.DateTime), dir)
+	}
+
+	var days []accounting.DayTotal
+	if *ledgerPath != "" {
+		path := *ledgerPath
+		if path == autoPath {
+			if path, err = accounting.DefaultStorePath(); err != nil {
+				return err
+			}
+		}
+		if days, err = accounting.NewFileStore(path).Load(); err != nil {
+			return err
+		}
+	}
+
+	columns := max(*width, 1)
+	c := chart.NewBrailleChart(columns)
+	c.SetDeterministic(func() time.Time { return end })
+	c.SetGlyphSet(chart.GlyphASCII)
+	c.SetDecimalUnits(*prefixes == ui.PrefixesSI)
+	c.SetWidth(columns)
+	c.SetHeight(*height)
+	up, down, err := hostColumns(history, *host, start, end, columns)
+	if err != nil {
+		return err
+	}
+	for i := range up {
+		c.AddDataPoint(up[i], down[i])
+	}
+
+	w := &reportWriter{out: stdout, markdown: *markdown}
+	w.heading(1, "PEAKS report: "+*host)
+	w.line(fmt.Sprintf("%s → %s (%s)", start.Local().Format("2006-01-02 15:04"),
+		end.Local().Format("2006-01-02 15:04"), end.Sub(start).Round(time.Minute)))
+
+	w.heading(2, "Traffic")
+	w.preformatted(c.Render())
+	w.line(fmt.Sprintf("Download above the axis, upload below; each column is the peak over %s.",
+		(end.Sub(start) / time.Duration(columns)).Round(time.Second)))
+
+	seconds := uint64(recorded / time.Second)
+	w.table([]string{"", "Download", "Upload"}, [][]string{
+		{"Peak", ui.FormatBandwidth(download.peak), ui.FormatBandwidth(upload.peak)},
+		{"Average", ui.FormatBandwidth(download.total / max(seconds, 1)), ui.FormatBandwidth(upload.total / max(seconds, 1))},
+		{"Total", ui.FormatBytes(download.total), ui.FormatBytes(upload.total)},
+	})
+
+	var rows [][]string
+	first, last := start.Local().Format(accounting.DateLayout), end.Local().Format(accounting.DateLayout)
+	for _, day := range days {
+		if day.Date >= first && day.Date <= last {
+			rows = append(rows, []string{day.Date, ui.FormatBytes(day.Download), ui.FormatBytes(day.Upload)})
+		}
+	}
+	if len(rows) > 0 {
+		w.heading(2, "Daily totals")
+		w.table([]string{"Date", "Download", "Upload"}, rows)
+	}
+	return w.err
+}
+
+// summarizeHistory totals a host's recorded traffic in [start, end] and
+// returns how much time the records cover
+func summarizeHistory(history *accounting.History, host string, start, end time.Time) (upload, download trafficSummary, recorded time.Duration, err error) {
+	inWindow := func(recordHost string, at time.Time) bool {
+		return recordHost == host && !at.Before(start) && !at.After(end)
+	}
+
+	minutes, err := history.Minutes()
+	if err != nil {
+		return upload, download, 0, err
+	}
+	for _, m := range minutes {
+		if !inWindow(m.Host, m.Time) {
+			continue
+		}
+		upload.total += m.Upload
+		download.total += m.Download
+		upload.peak = max(upload.peak, m.PeakUpload)
+		download.peak = max(download.peak, m.PeakDownload)
+		recorded += time.Minute
+	}
+
+	samples, err := history.Samples()
+	if err != nil {
+		return upload, download, 0, err
+	}
+	for _, s := range samples {
+		if !inWindow(s.Host, s.Time) {
+			continue
+		}
+		interval := s.Duration(updateInterval)
+		upload.total += uint64(float64(s.Upload) * interval.Seconds())
+		download.total += uint64(float64(s.Download) * interval.Seconds())
+		upload.peak = max(upload.peak, s.Upload)
+		download.peak = max(download.peak, s.Download)
+		recorded += interval
+	}
+	return upload, download, recorded, nil
+}
+
+// hostColumns buckets a host's upload and download peaks into columns
+func hostColumns(history *accounting.History, host string, start, end time.Time, columns int) (upload, download []uint64, err error) {
+	for _, direction := range []string{directionUpload, directionDownload} {
+		points, err := historyPoints(history, direction)
+		if err != nil {
+			return nil, nil, err
+		}
+		values := make([]uint64, columns)
+		for _, series := range bucketHosts(points, start, end, columns) {
+			if series.Name == host {
+				values = series.Values
+			}
+		}
+		if direction == directionUpload {
+			upload = values
+		} else {
+			download = values
+		}
+	}
+	return upload, download, nil
+}
+
+// reportWriter writes report sections as plain text or markdown, keeping the
+// first write error
+type reportWriter struct {
+	out      io.Writer
+	markdown bool
+	started  bool
+	err      error
+}
+
+// printf writes formatted output unless an earlier write failed
+func (w *reportWriter) printf(format string, args ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.out, format, args...)
+	}
+}
+
+// block separates sections with a blank line
+func (w *reportWriter) block() {
+	if w.started {
+		w.printf("\n")
+	}
+	w.started = true
+}
+
+// heading writes a section title
+func (w *reportWriter) heading(level int, title string) {
+	w.block()
+	if w.markdown {
+		w.printf("%s %s\n", strings.Repeat("#", level), title)
+		return
+	}
+	underline := "-"
+	if level == 1 {
+		underline = "="
+	}
+	w.printf("%s\n%s\n", title, strings.Repeat(underline, len([]rune(title))))
+}
+
+// line writes a paragraph
+func (w *reportWriter) line(text string) {
+	w.block()
+	w.printf("%s\n", text)
+}
+
+// preformatted writes text verbatim, fenced in markdown
+func (w *reportWriter) preformatted(text string) {
+	w.block()
+	text = strings.TrimRight(text, "\n")
+	if w.markdown {
+		w.printf("```text\n%s\n```\n", text)
+		return
+	}
+	w.printf("%s\n", text)
+}
+
+// table writes rows under a header. Columns after the first are numbers and
+// are right-aligned.
+func (w *reportWriter) table(header []string, rows [][]string) {
+	w.block()
+	if w.markdown {
+		w.printf("| %s |\n", strings.Join(header, " | "))
+		align := make([]string, len(header))
+		for i := range align {
+			align[i] = "---:"
+		}
+		align[0] = "---"
+		w.printf("|%s|\n", strings.Join(align, "|"))
+		for _, row := range rows {
+			w.printf("| %s |\n", strings.Join(row, " | "))
+		}
+		return
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	for _, row := range append([][]string{header}, rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if i == 0 {
+				cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			} else {
+				cells[i] = fmt.Sprintf("%*s", widths[i], cell)
+			}
+		}
+		w.printf("%s\n", strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+}
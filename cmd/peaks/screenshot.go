@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// screenshotLayout timestamps screenshot file names
+const screenshotLayout = "20060102-150405"
+
+// saveScreenshot writes a rendered frame to dir twice: as-is with its ANSI
+// styling (.ans, viewable with cat or less -R) and stripped to plain text
+// (.txt). It returns the path of the plain text file.
+func saveScreenshot(dir, frame string, at time.Time) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, "peaks-"+at.Format(screenshotLayout))
+	// Several screenshots in one second get a numeric suffix
+	for n := 2; ; n++ {
+		if _, err := os.Stat(base + ".txt"); os.IsNotExist(err) {
+			break
+		}
+		base = filepath.Join(dir, fmt.Sprintf("peaks-%s-%d", at.Format(screenshotLayout), n))
+	}
+
+	if err := os.WriteFile(base+".ans", []byte(frame+"\n"), 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".txt", []byte(ansi.Strip(frame)+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return base + ".txt", nil
+}
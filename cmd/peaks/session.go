@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// Session snapshots are saved every sessionSnapshotInterval. A snapshot left
+// behind by a crash is offered for restoring for restoreOfferTimeout, after
+// which the new session's snapshots replace it.
+const (
+	sessionSnapshotInterval = 30 * time.Second
+	restoreOfferTimeout     = 30 * time.Second
+)
+
+// sessionSnapshot is the state saved for crash recovery: the chart buffers,
+// the stats and the view settings
+type sessionSnapshot struct {
+	Saved       time.Time         `json:"saved"`
+	Source      string            `json:"source"`
+	StartTime   time.Time         `json:"start_time"`
+	Interval    time.Duration     `json:"interval_ns"`
+	DisplayMode string            `json:"display_mode"`
+	Scaling     chart.ScalingMode `json:"scaling"`
+	TimeScale   chart.TimeScale   `json:"time_scale"`
+	Smooth      bool              `json:"smooth"`
+	Totals      [2]uint64         `json:"totals"` // upload, download
+	Peaks       [2]uint64         `json:"peaks"`  // upload, download
+	Chart       chart.Snapshot    `json:"chart"`
+	// Labeled markers, which --history also keeps
+	Markers []accounting.Annotation `json:"markers,omitempty"`
+}
+
+// sessionSnapshotTickMsg triggers saving a session snapshot
+type sessionSnapshotTickMsg struct{}
+
+// sessionSnapshotTickCmd schedules the next session snapshot
+func sessionSnapshotTickCmd() tea.Cmd {
+	return tea.Tick(sessionSnapshotInterval, func(time.Time) tea.Msg { return sessionSnapshotTickMsg{} })
+}
+
+// resolveSessionPath returns the --session snapshot file, defaulting to the
+// user cache directory
+func resolveSessionPath(path string) (string, error) {
+	if path != autoPath {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "peaks", "session.json"), nil
+}
+
+// loadSession reads the snapshot an unclean exit left behind, if any
+func loadSession(path string) (*sessionSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshot sessionSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("reading session snapshot %s: %w", path, err)
+	}
+	return &snapshot, nil
+}
+
+// saveSession writes a snapshot through a temporary file, so a crash while
+// saving never leaves a truncated one
+func saveSession(path string, snapshot sessionSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removeSession deletes the snapshot after a clean exit
+func removeSession(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// openSession saves snapshots to path from now on, and offers the snapshot
+// a crashed session left there if it was sampled the same way
+func (m *model) openSession(path string) error {
+	m.sessionPath = path
+	snapshot, err := loadSession(path)
+	if err != nil || snapshot == nil {
+		return err
+	}
+	if snapshot.Source == m.source && snapshot.Interval == m.interval && len(snapshot.Chart.Upload) > 0 {
+		m.restoreOffer = snapshot
+		m.updateStatusbar()
+	}
+	return nil
+}
+
+// sessionSnapshot captures the model's state
+func (m model) sessionSnapshot() sessionSnapshot {
+	stats := m.ui.GetStats()
+	return sessionSnapshot{
+		Saved:       m.clock(),
+		Source:      m.source,
+		StartTime:   stats.StartTime,
+		Interval:    m.interval,
+		DisplayMode: m.displayMode,
+		Scaling:     m.chart.GetScalingMode(),
+		TimeScale:   m.chart.GetTimeScale(),
+		Smooth:      m.chart.IsSmoothing(),
+		Totals:      [2]uint64{stats.TotalUpload, stats.TotalDownload},
+		Peaks:       [2]uint64{stats.PeakUpload, stats.PeakDownload},
+		Chart:       m.chart.Snapshot(),
+		Markers:     m.markers,
+	}
+}
+
+// saveSessionSnapshot saves the session unless a crashed one is still on
+// offer, which would overwrite it
+func (m *model) saveSessionSnapshot() {
+	if m.restoreOffer != nil {
+		if m.clock().Sub(m.startedAt) < restoreOfferTimeout {
+			return
+		}
+		m.restoreOffer = nil
+		m.updateStatusbar()
+	}
+	if err := saveSession(m.sessionPath, m.sessionSnapshot()); err != nil {
+		m.keyNote = "Session: save failed"
+		m.updateStatusbar()
+	}
+}
+
+// restoreSession puts the offered crashed session's data before this one's,
+// adds up their stats and brings back its view settings
+func (m *model) restoreSession() {
+	snapshot := m.restoreOffer
+	m.restoreOffer = nil
+	m.chart.Prepend(snapshot.Chart)
+	m.chart.SetScalingMode(snapshot.Scaling)
+	m.chart.SetTimeScale(snapshot.TimeScale)
+	m.chart.SetSmoothing(snapshot.Smooth)
+
+	stats := m.ui.GetStats()
+	stats.TotalUpload += snapshot.Totals[0]
+	stats.TotalDownload += snapshot.Totals[1]
+	stats.PeakUpload = max(stats.PeakUpload, snapshot.Peaks[0])
+	stats.PeakDownload = max(stats.PeakDownload, snapshot.Peaks[1])
+	stats.StartTime = snapshot.StartTime
+
+	if snapshot.DisplayMode != "" {
+		m.setDisplayMode(snapshot.DisplayMode)
+	}
+	// Markers saved with the --history are listed already
+	var markers []accounting.Annotation
+	for _, marker := range snapshot.Markers {
+		if !slices.ContainsFunc(m.markers, func(a accounting.Annotation) bool { return a.Time.Equal(marker.Time) }) {
+			markers = append(markers, marker)
+		}
+	}
+	m.markers = append(markers, m.markers...)
+	m.sawData = m.sawData || snapshot.Totals != [2]uint64{}
+	m.keyNote = "Restored session from " + snapshot.Saved.Local().Format("15:04")
+	m.updateStatusbar()
+}
+
+// restoreStatus offers the crashed session for restoring in the statusbar
+func (m model) restoreStatus() string {
+	snapshot := m.restoreOffer
+	span := snapshot.Saved.Sub(snapshot.StartTime)
+	return pausedStyle.Render(fmt.Sprintf("Restore %s session from %s? Enter",
+		ui.FormatDuration(span), snapshot.Saved.Local().Format("15:04")))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+var speedTestPopupStyle = lipgloss.NewStyle().
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.AdaptiveColor{Dark: "#60A5FA", Light: "#2563EB"}).
+	Padding(0, 1)
+
+// speedTestPopupLines returns the contents of the speed test popup: the
+// measured latency and throughput, or why the test failed
+func (m model) speedTestPopupLines() []string {
+	result := m.speedTestResult.result
+	lines := []string{usageTitleStyle.Render("Speed test · " + m.speedTester.Host()), ""}
+	if err := m.speedTestResult.err; err != nil {
+		lines = append(lines, paneErrorStyle.Render("Failed: "+err.Error()))
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("Latency   %s", result.Latency.Round(time.Millisecond)),
+			fmt.Sprintf("Download  ↓%s", m.formatRate(result.Download)),
+			fmt.Sprintf("Upload    ↑%s", m.formatRate(result.Upload)),
+			"",
+			"Tested at "+result.Time.Format("15:04:05"),
+		)
+	}
+	return append(lines, "Any key to close")
+}
+
+// speedTestPopupView draws the speed test popup centered over the chart
+func (m model) speedTestPopupView(chartView string) string {
+	width := m.chartAreaWidth()
+	lines := m.speedTestPopupLines()
+	for i, line := range lines {
+		// Leave room for the border and padding
+		lines[i] = ui.Truncate(line, max(width-4, 1))
+	}
+	box := speedTestPopupStyle.Render(strings.Join(lines, "\n"))
+	x := max((width-lipgloss.Width(box))/2, 0)
+	y := max((m.chart.GetHeight()-lipgloss.Height(box))/2, 0)
+	return ui.Overlay(chartView, box, x, y)
+}
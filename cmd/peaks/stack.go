@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/chart"
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// stackTitleStyle and stackFocusStyle label the stacked charts; the chart
+// focused with tab is highlighted
+var (
+	stackTitleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	stackFocusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#60A5FA")).Bold(true)
+)
+
+// stackedChart is one --charts interface's chart, drawn under the main chart
+type stackedChart struct {
+	name             string
+	chart            *chart.BrailleChart
+	paused           pausedBuffer
+	upload, download uint64
+	seen             bool // the interface has been sampled
+}
+
+// interfaceRater is implemented by collectors that report each interface's
+// own rates
+type interfaceRater interface {
+	InterfaceRates(name string) (monitor.BandwidthRates, bool)
+}
+
+// parseInterfaceList splits a comma-separated --charts list
+func parseInterfaceList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// attachStack adds a chart per interface under the main chart. They are
+// sampled on the same ticks as the main chart and follow its view settings.
+func (m *model) attachStack(names []string) {
+	for _, name := range names {
+		ch := chart.NewBrailleChart(defaultDataPoints)
+		ch.SetMaxPoints(m.chart.GetMaxPoints())
+		ch.SetClock(m.clock)
+		ch.Follow(m.chart)
+		ch.SetOverlayMode(m.chart.IsOverlayMode())
+		m.stack = append(m.stack, &stackedChart{name: name, chart: ch})
+	}
+	m.resizeChart()
+}
+
+// sampleStack adds each stacked interface's rates to its chart, or buffers
+// them while paused. An interface that isn't there (yet) is drawn as a gap.
+func (m *model) sampleStack(gap bool) {
+	rater, _ := m.collector.(interfaceRater)
+	for _, s := range m.stack {
+		sample := pausedSample{gap: gap || rater == nil}
+		if !sample.gap {
+			var rates monitor.BandwidthRates
+			rates, s.seen = rater.InterfaceRates(s.name)
+			sample.gap = !s.seen
+			sample.upload, sample.download = rates.Upload, rates.Download
+		}
+		s.upload, s.download = sample.upload, sample.download
+		m.plotSample(s.chart, &s.paused, sample)
+	}
+}
+
+// stackRows splits the chart rows between the main chart and as many
+// stacked charts as fit, each under a one row title. It returns the main
+// chart's rows, the rows of each stacked chart and how many are shown.
+func (m model) stackRows(rows int) (main, each, shown int) {
+	minRows := m.minChartRows()
+	for shown = len(m.stack); shown > 0; shown-- {
+		charts := shown + 1
+		if each = (rows - charts) / charts; each >= minRows {
+			// The main chart takes the rows left over by the division
+			return rows - charts - each*shown, each, shown
+		}
+	}
+	return rows, 0, 0
+}
+
+// resizeStack sizes the stacked charts that fit into rows and returns the
+// rows left for the main chart
+func (m *model) resizeStack(rows int) int {
+	main, each, shown := m.stackRows(rows)
+	minHeight := m.minChartRows()
+	for _, s := range m.stack[:shown] {
+		s.chart.SetMinHeight(minHeight)
+		s.chart.FitWidth(m.chartAreaWidth())
+		s.chart.SetHeight(each)
+	}
+	if m.chartFocus > shown {
+		// The focused chart no longer fits
+		m.chartFocus = 0
+	}
+	return main
+}
+
+// stackView puts each chart under its title: the main chart first, then the
+// stacked charts that fit
+func (m model) stackView(mainView string) string {
+	_, _, shown := m.stackRows(m.layout().chartRows)
+	if shown == 0 {
+		return mainView
+	}
+	var view strings.Builder
+	view.WriteString(m.stackTitle(0))
+	view.WriteString("\n")
+	view.WriteString(mainView)
+	for i, s := range m.stack[:shown] {
+		view.WriteString("\n")
+		view.WriteString(m.stackTitle(i + 1))
+		view.WriteString("\n")
+		view.WriteString(s.chart.Render())
+	}
+	return view.String()
+}
+
+// stackTitle names chart i (0 for the main chart) with its current rates
+func (m model) stackTitle(i int) string {
+	name, upload, download := "all", m.currentUpload, m.currentDownload
+	if m.ifaceFocus != "" {
+		name = m.ifaceFocus
+	}
+	rates := ""
+	if i > 0 {
+		s := m.stack[i-1]
+		name, upload, download = s.name, s.upload, s.download
+		if !s.seen {
+			rates = "not found"
+		}
+	}
+	if rates == "" {
+		rates = fmt.Sprintf("↓ %s ↑ %s", m.formatRate(download), m.formatRate(upload))
+	}
+	if i == m.chartFocus {
+		return ui.Truncate(stackFocusStyle.Render("▸ "+name+"  "+rates), m.chartAreaWidth())
+	}
+	return ui.Truncate(stackTitleStyle.Render("  "+name+"  "+rates), m.chartAreaWidth())
+}
+
+// cycleChartFocus moves the focus, which the cursor readout follows, to the
+// next chart in the stack
+func (m *model) cycleChartFocus() {
+	if len(m.stack) == 0 {
+		return
+	}
+	_, _, shown := m.stackRows(m.layout().chartRows)
+	m.chartFocus = (m.chartFocus + 1) % (shown + 1)
+	m.updateStatusbar()
+}
+
+// focusedChart returns the chart focused with tab
+func (m model) focusedChart() *chart.BrailleChart {
+	if m.chartFocus > 0 && m.chartFocus <= len(m.stack) {
+		return m.stack[m.chartFocus-1].chart
+	}
+	return m.chart
+}
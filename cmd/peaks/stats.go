@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// toggleStatsPanel shows or hides the rolling statistics beside the chart
+func (m *model) toggleStatsPanel() {
+	m.showStats = !m.showStats
+	m.resizeChart()
+}
+
+// statsView renders the average, median and 95th percentile rates over each
+// --stats-windows window for the sidebar
+func (m model) statsView() []string {
+	lines := []string{paneTitleStyle.Render("Stats · avg, median, p95")}
+	for i, w := range m.ui.GetStats().Windowed() {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		// The arrows head the right-aligned columns below
+		lines = append(lines, fmt.Sprintf("%-15s↓%12s", "Last "+formatWindow(w.Window), "↑"))
+		if w.Samples == 0 {
+			lines = append(lines, "No samples yet")
+			continue
+		}
+		for _, row := range []struct {
+			name             string
+			download, upload uint64
+		}{
+			{"avg", w.Download.Average, w.Upload.Average},
+			{"med", w.Download.Median, w.Upload.Median},
+			{"p95", w.Download.P95, w.Upload.P95},
+		} {
+			lines = append(lines, fmt.Sprintf("%-4s%12s %12s", row.name, m.formatRate(row.download), m.formatRate(row.upload)))
+		}
+	}
+	return lines
+}
+
+// formatWindow writes a window without its zero units, e.g. "5m" or "1h30m"
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// The top-talkers panel shows the maxTalkerRows busiest remote hosts, each
+// name or address cut to maxTalkerName columns beside its rates
+const (
+	maxTalkerRows = 10
+	maxTalkerName = 15
+)
+
+// refreshTalkers takes the latest per-host rates from the --capture tally
+func (m *model) refreshTalkers() {
+	if m.talkers == nil {
+		return
+	}
+	m.talkerRates = m.talkers.Rates(m.processSort)
+}
+
+// toggleHostNames switches the top-talkers panel between host names and
+// numeric addresses
+func (m *model) toggleHostNames() {
+	m.numericHosts = !m.numericHosts
+	if m.numericHosts {
+		m.keyNote = "Host names: off"
+	} else {
+		m.keyNote = "Host names: on"
+	}
+}
+
+// talkerName names a remote host by reverse DNS once resolved, keeping the
+// end of long names where the domain is
+func (m model) talkerName(addr netip.Addr) string {
+	if !m.numericHosts && m.resolver != nil {
+		if name, ok := m.resolver.Name(addr); ok {
+			if runes := []rune(name); len(runes) > maxTalkerName {
+				return "…" + string(runes[len(runes)-maxTalkerName+1:])
+			}
+			return name
+		}
+	}
+	return ui.Truncate(addr.String(), maxTalkerName)
+}
+
+// talkersView renders the busiest remote hosts for the sidebar, sorted like
+// the process list
+func (m model) talkersView() []string {
+	lines := []string{paneTitleStyle.Render("Top talkers · by " + m.processSort.String())}
+	if len(m.talkerRates) == 0 {
+		return append(lines, "No IP traffic")
+	}
+	for _, h := range m.talkerRates[:min(len(m.talkerRates), maxTalkerRows)] {
+		name := m.talkerName(h.Addr)
+		lines = append(lines, fmt.Sprintf("%s%s ↓%5s ↑%5s",
+			name, strings.Repeat(" ", maxTalkerName-ui.StringWidth(name)), m.formatRateShort(h.Download), m.formatRateShort(h.Upload)))
+	}
+	return lines
+}
+
+// startTalkers checks the capture privileges and starts tallying traffic
+// per remote host for the top-talkers panel, naming hosts by reverse DNS
+func (m *model) startTalkers() error {
+	if err := monitor.CheckCapturePrivileges(); err != nil {
+		return err
+	}
+	m.talkers = monitor.NewHostStats()
+	m.resolver = monitor.NewResolver()
+	err := m.packets.Add(m.talkers.Add)
+	if err != nil {
+		m.talkers = nil
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"syscall"
+)
+
+// Escape sequences undoing every terminal mode peaks may change
+const (
+	// resetScrollRegion restores the full-screen scroll region, shows the
+	// cursor and clears text attributes (compact mode)
+	resetScrollRegion = "\033[r\033[?25h\033[0m"
+	// resetFullScreen additionally disables focus reporting and leaves the
+	// alternate screen (full-screen mode)
+	resetFullScreen = resetScrollRegion + "\033[?1004l\033[?1049l"
+)
+
+// terminationSignals are the signals after which the terminal must be restored.
+// SIGHUP covers the controlling terminal (or tmux pane) going away.
+var terminationSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// restoreTerminal writes the given reset sequence to the terminal
+func restoreTerminal(reset string) {
+	fmt.Fprint(os.Stdout, reset)
+}
+
+// recoverTerminal restores the terminal if the calling goroutine panics, then
+// reports the panic and exits. Use as the first deferred call so it runs last:
+//
+//	defer recoverTerminal(resetScrollRegion)
+func recoverTerminal(reset string) {
+	if r := recover(); r != nil {
+		restoreTerminal(reset)
+		fmt.Fprintf(os.Stderr, "\npeaks: panic: %v\n\n%s", r, debug.Stack())
+		os.Exit(2)
+	}
+}
+
+// fatalf restores the terminal, reports an error and exits. Deferred calls
+// don't run, so only call it once they have, e.g. with the error returned
+// from the function holding them.
+func fatalf(reset, format string, args ...any) {
+	restoreTerminal(reset)
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
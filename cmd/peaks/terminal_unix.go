@@ -4,6 +4,9 @@
 package main
 
 import (
+	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 	"unsafe"
 
@@ -75,3 +78,30 @@ func getTerminalWidth() int {
 
 	return int(ws.Col)
 }
+
+// enableVirtualTerminal is a no-op: Unix terminals interpret the escape
+// sequences compact mode draws with
+func enableVirtualTerminal() error {
+	return nil
+}
+
+// compactDaemonPIDs finds running compact mode daemons by their command line
+func compactDaemonPIDs() []int {
+	output, err := exec.Command("pgrep", "-f", compactDaemonPattern).Output()
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(output)) {
+		if pid, err := strconv.Atoi(field); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// stopCompactDaemon asks a compact mode daemon to exit; it restores the
+// terminal itself on SIGTERM
+func stopCompactDaemon(pid int) error {
+	return exec.Command("kill", strconv.Itoa(pid)).Run()
+}
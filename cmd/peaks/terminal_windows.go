@@ -4,9 +4,15 @@
 package main
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"unsafe"
+
+	"golang.org/x/sys/windows"
 )
 
 type coord struct {
@@ -65,3 +71,52 @@ func getTerminalWidth() int {
 
 	return int(csbi.Window.Right - csbi.Window.Left + 1)
 }
+
+// enableVirtualTerminal turns on VT sequence processing for the console, so
+// compact mode's cursor movement and scroll region work as they do on Unix.
+// Windows Terminal and the Windows 10+ console host support it; older
+// consoles return an error.
+func enableVirtualTerminal() error {
+	handle := windows.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return errors.New("stdout is not a console")
+	}
+	mode |= windows.ENABLE_PROCESSED_OUTPUT | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	if err := windows.SetConsoleMode(handle, mode); err != nil {
+		return errors.New("this console does not support VT sequences; use Windows Terminal or Windows 10 or later")
+	}
+	return nil
+}
+
+// compactDaemonPIDs finds running compact mode daemons from the pid files
+// they leave in the temp directory (there is no pgrep on Windows)
+func compactDaemonPIDs() []int {
+	files, _ := filepath.Glob(filepath.Join(os.TempDir(), "peaks-*.pid"))
+	var pids []int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// stopCompactDaemon terminates a compact mode daemon. Windows has no SIGTERM,
+// so the daemon can't clean up after itself: its pid file is removed and the
+// scroll region reset here instead.
+func stopCompactDaemon(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		os.Remove(compactPIDFile(pid))
+		return err
+	}
+	err = process.Kill()
+	os.Remove(compactPIDFile(pid))
+	restoreTerminal(resetScrollRegion)
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// byteRates reports whether a collector source measures bytes per second,
+// which can be shown in bits instead
+func byteRates(source string) bool {
+	return source != monitor.SourceCPU && source != monitor.SourceStdin
+}
+
+// prefixesUsage describes --prefixes
+const prefixesUsage = "byte prefixes of rates and totals: binary (1024, KB), iec (1024, KiB) or si (1000, kB)"
+
+// prefixesFlag adds --prefixes to a subcommand's flags
+func prefixesFlag(fs *flag.FlagSet) *string {
+	return fs.String("prefixes", ui.PrefixesBinary, prefixesUsage)
+}
+
+// applyPrefixes checks --prefixes and formats byte rates and totals with them
+func applyPrefixes(name string) error {
+	if _, err := ui.ParsePrefixes(name); err != nil {
+		return err
+	}
+	ui.SetPrefixes(name)
+	return nil
+}
+
+// setBitUnits shows byte rates in bits or bytes per second: the statusbar,
+// the sidebar panels and the charts' scale labels. Totals stay in bytes, and
+// other sources keep their own units.
+func (m *model) setBitUnits(bits bool) {
+	m.bitUnits = bits
+	rate, short := ui.FormatBandwidth, ui.FormatBandwidthShort
+	if bits {
+		rate, short = ui.FormatBitrate, ui.FormatBitrateShort
+	}
+	if byteRates(m.source) {
+		m.formatRate, m.formatRateShort = rate, short
+	}
+	if m.remote != nil && byteRates(m.remoteSource) {
+		m.formatRemote = rate
+	}
+	// Stacked and remote charts follow the main chart's units
+	m.chart.SetBitUnits(bits)
+	m.updateStatusbar()
+}
+
+// toggleUnits switches rates between bits and bytes per second
+func (m *model) toggleUnits() {
+	m.setBitUnits(!m.bitUnits)
+	if m.bitUnits {
+		m.keyNote = "Units: bit/s"
+	} else {
+		m.keyNote = "Units: B/s"
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/accounting"
+	"github.com/marcodenic/peaks/internal/ui"
+)
+
+// usagePeriods are the periods U cycles the usage tab through
+var usagePeriods = []string{accounting.UsageDaily, accounting.UsageMonthly, accounting.UsageHourly}
+
+// usageCount returns how many periods are listed by default: a day of
+// hours, a month of days or a year of months
+func usageCount(period string) int {
+	switch period {
+	case accounting.UsageHourly:
+		return 24
+	case accounting.UsageMonthly:
+		return 12
+	default:
+		return 30
+	}
+}
+
+// usageLabel returns the column title and time layout of a period's rows
+func usageLabel(period string) (title, layout string) {
+	switch period {
+	case accounting.UsageHourly:
+		return "Hour", "2006-01-02 15:00"
+	case accounting.UsageMonthly:
+		return "Month", "2006-01"
+	default:
+		return "Day", "2006-01-02 Mon"
+	}
+}
+
+// minUsageBar is the narrowest bar worth drawing beside the table
+const minUsageBar = 5
+
+// usageLines renders usage totals as a table, vnstat-style, with a bar per
+// row scaled to the busiest one (download █, upload ▒) in the columns left
+// of width, and a total row
+func usageLines(rows []accounting.UsageTotal, period string, width int, format func(uint64) string) []string {
+	title, layout := usageLabel(period)
+	labelWidth := len(layout)
+	row := func(label, download, upload, total string) string {
+		return fmt.Sprintf("%-*s  %10s  %10s  %10s", labelWidth, label, download, upload, total)
+	}
+	barWidth := width - ui.StringWidth(row("", "", "", "")) - 2
+
+	var peak uint64
+	var sum accounting.UsageTotal
+	for _, r := range rows {
+		peak = max(peak, r.Upload+r.Download)
+		sum.Upload += r.Upload
+		sum.Download += r.Download
+	}
+
+	lines := []string{row(title, "Download", "Upload", "Total")}
+	for _, r := range rows {
+		line := row(r.Start.Format(layout), format(r.Download), format(r.Upload), format(r.Upload+r.Download))
+		if total := r.Upload + r.Download; barWidth >= minUsageBar && total > 0 {
+			length := max(int(total*uint64(barWidth)/peak), 1)
+			download := int((uint64(length)*r.Download + total/2) / total)
+			line += "  " + strings.Repeat("█", download) + strings.Repeat("▒", length-download)
+		}
+		lines = append(lines, line)
+	}
+	return append(lines, row("Total", format(sum.Download), format(sum.Upload), format(sum.Upload+sum.Download)))
+}
+
+// runUsage implements "peaks usage", listing a host's recorded traffic per
+// hour, day or month with a bar chart
+func runUsage(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	historyDir := fs.String("history", autoPath, "history directory to read (\"auto\" for the user data directory)")
+	host := fs.String("host", "", "host to list (default: this machine)")
+	period := fs.String("period", accounting.UsageDaily, "hourly, daily or monthly totals")
+	count := fs.Int("count", 0, "how many periods to list (default: 24 hours, 30 days or 12 months)")
+	width := fs.Int("width", 80, "output width in columns, bars included")
+	markdown := fs.Bool("markdown", false, "write GitHub-flavored markdown")
+	prefixes := prefixesFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := applyPrefixes(*prefixes); err != nil {
+		return err
+	}
+	if _, err := accounting.ParseUsagePeriod(*period); err != nil {
+		return err
+	}
+	if *count <= 0 {
+		*count = usageCount(*period)
+	}
+	var err error
+	if *host == "" {
+		if *host, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+
+	dir, err := resolveHistoryDir(*historyDir)
+	if err != nil {
+		return err
+	}
+	history, err := accounting.OpenHistory(dir, accounting.DefaultRetention, updateInterval)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+	rows, err := history.Usage(*host, *period, time.Now(), *count)
+	if err != nil {
+		return err
+	}
+
+	w := &reportWriter{out: stdout, markdown: *markdown}
+	w.heading(1, fmt.Sprintf("PEAKS %s usage: %s", *period, *host))
+	w.preformatted(strings.Join(usageLines(rows, *period, *width, ui.FormatBytes), "\n"))
+	return w.err
+}
+
+var usageTitleStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Dark: "#60A5FA", Light: "#2563EB"}).
+	Bold(true)
+
+// toggleUsage shows or hides the usage tab in place of the chart
+func (m *model) toggleUsage() {
+	m.showUsage = !m.showUsage
+	if m.showUsage {
+		m.loadUsage()
+	}
+}
+
+// cycleUsagePeriod switches the usage tab between daily, monthly and hourly
+// totals, showing it if hidden
+func (m *model) cycleUsagePeriod() {
+	if m.showUsage {
+		for i, period := range usagePeriods {
+			if period == m.usagePeriod {
+				m.usagePeriod = usagePeriods[(i+1)%len(usagePeriods)]
+				break
+			}
+		}
+	}
+	m.showUsage = true
+	m.loadUsage()
+}
+
+// loadUsage rolls the --history up into the usage tab's totals
+func (m *model) loadUsage() {
+	if m.usagePeriod == "" {
+		m.usagePeriod = usagePeriods[0]
+	}
+	if m.history == nil {
+		return
+	}
+	m.usage, m.usageErr = m.history.Usage(m.history.Host(), m.usagePeriod, m.clock(), usageCount(m.usagePeriod))
+}
+
+// usageView renders the usage tab in place of the chart: the newest totals
+// that fit in height, under a title
+func (m model) usageView(height int) string {
+	width := m.chartAreaWidth()
+	lines := []string{usageTitleStyle.Render(fmt.Sprintf("Usage · %s", m.usagePeriod)) + "  U: hourly/daily/monthly · u: back to the chart", ""}
+	switch {
+	case m.history == nil:
+		lines = append(lines, "Needs --history to record usage")
+	case m.usageErr != nil:
+		lines = append(lines, paneErrorStyle.Render(m.usageErr.Error()))
+	default:
+		// Leave room for the header and total rows
+		fit := min(max(height-len(lines)-2, 0), len(m.usage))
+		rows := m.usage[len(m.usage)-fit:]
+		lines = append(lines, usageLines(rows, m.usagePeriod, width, m.formatTotal)...)
+	}
+	for i, line := range lines {
+		lines[i] = ui.Truncate(line, width)
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, strings.Join(lines, "\n"))
+}
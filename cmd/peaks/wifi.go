@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// wifiResultMsg carries one read of the Wi-Fi status
+type wifiResultMsg struct {
+	status monitor.WifiStatus
+	err    error
+}
+
+// wifiTickMsg triggers the next Wi-Fi status read
+type wifiTickMsg struct{}
+
+// wifiCmd reads the Wi-Fi status in the background, as the tools behind it
+// can be slow
+func wifiCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), monitor.WifiTimeout)
+		defer cancel()
+		status, err := monitor.ReadWifi(ctx)
+		return wifiResultMsg{status: status, err: err}
+	}
+}
+
+// toggleWifi shows or hides the Wi-Fi panel beside the chart, starting its
+// reads unless they are still running from before
+func (m *model) toggleWifi() tea.Cmd {
+	m.showWifi = !m.showWifi
+	m.resizeChart()
+	if !m.showWifi || m.wifiReading {
+		return nil
+	}
+	m.wifiReading = true
+	return wifiCmd()
+}
+
+// wifiResult takes a Wi-Fi status read and schedules the next one a sample
+// interval later while the panel is shown
+func (m *model) wifiResult(msg wifiResultMsg) tea.Cmd {
+	m.wifi, m.wifiErr = msg.status, msg.err
+	if m.focused {
+		m.frame.dirty = true
+	}
+	if !m.showWifi {
+		m.wifiReading = false
+		return nil
+	}
+	return tea.Tick(m.sampleInterval(), func(time.Time) tea.Msg { return wifiTickMsg{} })
+}
+
+// signalBars draws a signal quality as four rising bars, e.g. "▂▄▆·"
+func signalBars(quality int) string {
+	bars := []string{"▂", "▄", "▆", "█"}
+	lit := min(max((quality+24)/25, 0), len(bars))
+	return strings.Join(bars[:lit], "") + strings.Repeat("·", len(bars)-lit)
+}
+
+// wifiView renders the Wi-Fi panel for the sidebar: the network, signal
+// strength and link rates of the first wireless interface
+func (m model) wifiView() []string {
+	title := "Wi-Fi"
+	if m.wifi.Interface != "" {
+		title += " · " + m.wifi.Interface
+	}
+	lines := []string{paneTitleStyle.Render(title)}
+	switch {
+	case errors.Is(m.wifiErr, monitor.ErrNoWifi):
+		return append(lines, "No Wi-Fi interface")
+	case errors.Is(m.wifiErr, monitor.ErrWifiUnsupported):
+		return append(lines, paneErrorStyle.Render("Not supported here"))
+	case errors.Is(m.wifiErr, exec.ErrNotFound):
+		// The error starts with the tool's name, e.g. "iw: exec: …"
+		tool, _, _ := strings.Cut(m.wifiErr.Error(), ":")
+		return append(lines, paneErrorStyle.Render("Needs "+tool))
+	case m.wifiErr != nil:
+		return append(lines, paneErrorStyle.Render(m.wifiErr.Error()))
+	case m.wifi == monitor.WifiStatus{}:
+		return append(lines, "Reading…")
+	case !m.wifi.Connected:
+		return append(lines, "Not connected")
+	}
+
+	lines = append(lines, m.wifi.SSID)
+	if m.wifi.Quality > 0 {
+		lines = append(lines, fmt.Sprintf("Signal %s %d dBm %d%%", signalBars(m.wifi.Quality), m.wifi.Signal, m.wifi.Quality))
+	}
+	var rates []string
+	if m.wifi.RxRate > 0 {
+		rates = append(rates, "↓"+formatLinkSpeed(monitor.LinkStatus{Up: true, Speed: m.wifi.RxRate}))
+	}
+	if m.wifi.TxRate > 0 {
+		rates = append(rates, "↑"+formatLinkSpeed(monitor.LinkStatus{Up: true, Speed: m.wifi.TxRate}))
+	}
+	if len(rates) > 0 {
+		lines = append(lines, "Link   "+strings.Join(rates, " "))
+	}
+	return lines
+}
@@ -0,0 +1,54 @@
+// Package accounting provides labeled annotations kept with the sample history
+package accounting
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Annotation is a labeled moment in the history, e.g. "started backup"
+type Annotation struct {
+	Time  time.Time `json:"t"`
+	Host  string    `json:"host,omitempty"` // Machine the annotation was made on
+	Label string    `json:"label"`
+}
+
+// Annotate appends an annotation made on this host
+func (h *History) Annotate(at time.Time, label string) (Annotation, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	annotation := Annotation{Time: at.UTC(), Host: h.host, Label: strings.TrimSpace(label)}
+	line, err := json.Marshal(annotation)
+	if err != nil {
+		return annotation, err
+	}
+	file, err := os.OpenFile(filepath.Join(h.dir, annotationsFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return annotation, err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return annotation, err
+	}
+	return annotation, file.Close()
+}
+
+// Annotations returns the stored annotations made between from and to,
+// oldest first. A zero from or to leaves that end open.
+func (h *History) Annotations(from, to time.Time) ([]Annotation, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	annotations, err := readJSONLines[Annotation](filepath.Join(h.dir, annotationsFile))
+	kept := annotations[:0]
+	for _, annotation := range annotations {
+		if (from.IsZero() || !annotation.Time.Before(from)) && (to.IsZero() || annotation.Time.Before(to)) {
+			kept = append(kept, annotation)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Time.Before(kept[j].Time) })
+	return kept, err
+}
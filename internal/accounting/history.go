@@ -0,0 +1,813 @@
+// Package accounting provides tiered sample history with retention
+package accounting
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// History file names within the history directory
+const (
+	samplesFile = "samples.jsonl"
+	minutesFile = "minutes.jsonl"
+	// Hourly totals of expired minute aggregates, kept forever
+	hoursFile = "hours.jsonl"
+	// Labeled markers, kept regardless of retention
+	annotationsFile = "annotations.jsonl"
+	// Held shared while appending samples and exclusively while swapping
+	// the sample file, by every peaks using the directory
+	appendLockFile = "samples.lock"
+	// Held for a whole compaction, so only one peaks compacts at a time
+	compactLockFile = "compact.lock"
+)
+
+// Retention says how long each history tier is kept. Minute aggregates are
+// folded into hourly totals as they expire, which, like the daily totals of
+// the Ledger, are kept forever. A zero duration keeps a tier forever too.
+type Retention struct {
+	Raw     time.Duration // Individual samples
+	Minutes time.Duration // Per-minute aggregates
+}
+
+// DefaultRetention keeps raw samples for 48 hours and minute aggregates for 90 days
+var DefaultRetention = Retention{
+	Raw:     48 * time.Hour,
+	Minutes: 90 * 24 * time.Hour,
+}
+
+// DefaultCompactionInterval is how often the background job enforces retention
+const DefaultCompactionInterval = time.Hour
+
+// ParseRetention parses a retention period: a Go duration such as "48h", a
+// number of days such as "90d", or "forever"
+func ParseRetention(s string) (time.Duration, error) {
+	if s == "forever" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid retention %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid retention %q", s)
+	}
+	return d, nil
+}
+
+// FormatRetention formats a retention period in the form ParseRetention accepts
+func FormatRetention(d time.Duration) string {
+	if d == 0 {
+		return "forever"
+	}
+	return d.String()
+}
+
+// DefaultHistoryDir returns the history directory in the user's data
+// directory, moving a history left in the config directory by older versions
+func DefaultHistoryDir() (string, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "peaks", "history")
+	if configDir, err := os.UserConfigDir(); err == nil {
+		legacy := filepath.Join(configDir, "peaks", "history")
+		if _, err := os.Stat(dir); legacy != dir && errors.Is(err, os.ErrNotExist) {
+			if _, err := os.Stat(legacy); err == nil {
+				if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+					return "", err
+				}
+				if err := os.Rename(legacy, dir); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return dir, nil
+}
+
+// userDataDir returns the directory for user data: $XDG_DATA_HOME or
+// ~/.local/share on Unix, %LocalAppData% on Windows and Application Support
+// on macOS
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir, nil
+		}
+		return "", errors.New("%LocalAppData% is not defined")
+	case "darwin", "ios":
+		return os.UserConfigDir()
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// Sample is one raw rate sample, in bytes per second
+type Sample struct {
+	Time     time.Time `json:"t"`
+	Host     string    `json:"host,omitempty"` // Machine the sample was recorded on
+	Upload   uint64    `json:"up"`
+	Download uint64    `json:"down"`
+	// Seconds the sample covers when recorded at a non-default rate
+	Interval float64 `json:"dt,omitempty"`
+}
+
+// Duration returns how long the sample covers; samples recorded at the
+// default rate cover fallback
+func (s Sample) Duration(fallback time.Duration) time.Duration {
+	if s.Interval > 0 {
+		return time.Duration(s.Interval * float64(time.Second))
+	}
+	return fallback
+}
+
+// sampleKey identifies a sample for deduplication
+type sampleKey struct {
+	host string
+	unix int64 // nanoseconds
+}
+
+// key returns the sample's deduplication key
+func (s Sample) key() sampleKey {
+	return sampleKey{host: s.Host, unix: s.Time.UnixNano()}
+}
+
+// MinuteTotal aggregates the samples of one minute
+type MinuteTotal struct {
+	Time         time.Time `json:"t"` // Start of the minute
+	Host         string    `json:"host,omitempty"`
+	Upload       uint64    `json:"up"`   // Bytes
+	Download     uint64    `json:"down"` // Bytes
+	PeakUpload   uint64    `json:"peak_up"`
+	PeakDownload uint64    `json:"peak_down"`
+}
+
+// History records raw samples and compacts them into minute aggregates,
+// dropping each tier once it is older than its retention
+type History struct {
+	dir       string
+	host      string
+	retention Retention
+	interval  time.Duration // Default sample interval, used to turn rates into bytes
+
+	mu      sync.Mutex
+	samples *os.File
+	// Interval of the samples being recorded, if changed from the default
+	recordInterval time.Duration
+
+	// Locks shared with other peaks processes using the directory
+	appendLock  *os.File
+	compactLock *os.File
+	// Serializes compactions within this process
+	compactMu sync.Mutex
+}
+
+// OpenHistory opens (creating if needed) the history stored in dir.
+// interval is the time between recorded samples.
+func OpenHistory(dir string, retention Retention, interval time.Duration) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	h := &History{dir: dir, retention: retention, interval: interval, recordInterval: interval}
+	h.host, _ = os.Hostname()
+	var err error
+	if h.appendLock, err = os.OpenFile(filepath.Join(dir, appendLockFile), os.O_CREATE|os.O_RDWR, 0644); err != nil {
+		return nil, err
+	}
+	if h.compactLock, err = os.OpenFile(filepath.Join(dir, compactLockFile), os.O_CREATE|os.O_RDWR, 0644); err != nil {
+		h.appendLock.Close()
+		return nil, err
+	}
+	if err := h.lockAppends(true); err != nil {
+		h.closeLocks()
+		return nil, err
+	}
+	err = h.openSamples()
+	if err == nil {
+		err = h.endLine()
+	}
+	h.unlockAppends()
+	if err != nil {
+		if h.samples != nil {
+			h.samples.Close()
+		}
+		h.closeLocks()
+		return nil, err
+	}
+	return h, nil
+}
+
+// OpenHistoryReadOnly opens the history stored in dir for reading only, e.g.
+// for a one-shot status line. Nothing is created or locked, so it never
+// holds up a peaks recording to the directory; Record, Import and Compact
+// return os.ErrClosed.
+func OpenHistoryReadOnly(dir string) (*History, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	h := &History{dir: dir, retention: DefaultRetention}
+	h.host, _ = os.Hostname()
+	return h, nil
+}
+
+// openSamples opens the raw sample file for appending
+func (h *History) openSamples() error {
+	file, err := os.OpenFile(filepath.Join(h.dir, samplesFile), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	h.samples = file
+	return nil
+}
+
+// endLine terminates a line left unfinished by a crash mid-write, so the
+// next sample starts a line of its own and only the torn one is lost
+func (h *History) endLine() error {
+	info, err := h.samples.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := h.samples.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err = h.samples.Write([]byte{'\n'})
+	}
+	return err
+}
+
+// followSamples reopens the sample file if another process compacted it,
+// so samples aren't appended to the file it replaced. Appends must be locked.
+func (h *History) followSamples() error {
+	current, err := os.Stat(filepath.Join(h.dir, samplesFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	open, statErr := h.samples.Stat()
+	if statErr != nil {
+		return statErr
+	}
+	if err == nil && os.SameFile(current, open) {
+		return nil
+	}
+	h.samples.Close()
+	h.samples = nil
+	return h.openSamples()
+}
+
+// lockAppends locks the sample file against other processes: shared to
+// append to it, exclusive to replace it
+func (h *History) lockAppends(exclusive bool) error {
+	return lockFile(h.appendLock.Fd(), exclusive)
+}
+
+// unlockAppends releases lockAppends
+func (h *History) unlockAppends() {
+	unlockFile(h.appendLock.Fd())
+}
+
+// closeLocks closes the lock files
+func (h *History) closeLocks() {
+	h.appendLock.Close()
+	h.compactLock.Close()
+}
+
+// Host returns the machine name samples recorded here are attributed to
+func (h *History) Host() string {
+	return h.host
+}
+
+// SetInterval changes the interval of subsequently recorded samples
+func (h *History) SetInterval(interval time.Duration) {
+	h.mu.Lock()
+	h.recordInterval = interval
+	h.mu.Unlock()
+}
+
+// Record appends a raw sample
+func (h *History) Record(at time.Time, upload, download uint64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sample := Sample{Time: at.UTC(), Host: h.host, Upload: upload, Download: download}
+	if h.recordInterval != h.interval {
+		sample.Interval = h.recordInterval.Seconds()
+	}
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	if h.samples == nil {
+		return os.ErrClosed
+	}
+	if err := h.lockAppends(false); err != nil {
+		return err
+	}
+	defer h.unlockAppends()
+	if err := h.followSamples(); err != nil {
+		return err
+	}
+	_, err = h.samples.Write(append(line, '\n'))
+	return err
+}
+
+// Close stops recording
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.samples == nil {
+		return nil
+	}
+	err := h.samples.Close()
+	h.samples = nil
+	h.closeLocks()
+	return err
+}
+
+// Import merges samples recorded elsewhere, read as JSON Lines from r, into
+// the history. Samples already present (same host and timestamp) are skipped;
+// samples without a host are attributed to host.
+func (h *History) Import(r io.Reader, host string) (added, skipped int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.samples == nil {
+		return 0, 0, os.ErrClosed
+	}
+
+	existing, err := readJSONLines[Sample](filepath.Join(h.dir, samplesFile))
+	if err != nil {
+		return 0, 0, err
+	}
+	seen := make(map[sampleKey]bool, len(existing))
+	for _, sample := range existing {
+		seen[sample.key()] = true
+	}
+
+	if err := h.lockAppends(false); err != nil {
+		return 0, 0, err
+	}
+	defer h.unlockAppends()
+	if err := h.followSamples(); err != nil {
+		return 0, 0, err
+	}
+	writer := bufio.NewWriter(h.samples)
+	encoder := json.NewEncoder(writer)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			writer.Flush()
+			return added, skipped, fmt.Errorf("line %d: %w", line, err)
+		}
+		if sample.Time.IsZero() {
+			writer.Flush()
+			return added, skipped, fmt.Errorf("line %d: sample has no timestamp", line)
+		}
+		if sample.Host == "" {
+			sample.Host = host
+		}
+		sample.Time = sample.Time.UTC()
+		if seen[sample.key()] {
+			skipped++
+			continue
+		}
+		seen[sample.key()] = true
+		if err := encoder.Encode(sample); err != nil {
+			return added, skipped, err
+		}
+		added++
+	}
+	if err := scanner.Err(); err != nil {
+		writer.Flush()
+		return added, skipped, err
+	}
+	return added, skipped, writer.Flush()
+}
+
+// StartCompaction enforces retention every interval in the background until
+// the returned stop function is called
+func (h *History) StartCompaction(interval time.Duration, onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := h.Compact(time.Now()); err != nil && onError != nil {
+				onError(err)
+			}
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Compact folds raw samples older than the raw retention into minute
+// aggregates, and minute aggregates older than their retention into hourly
+// totals. Samples keep being recorded while it runs: only the swap of the
+// trimmed sample file holds up Record.
+func (h *History) Compact(now time.Time) error {
+	h.compactMu.Lock()
+	defer h.compactMu.Unlock()
+	h.mu.Lock()
+	closed := h.samples == nil
+	h.mu.Unlock()
+	if closed {
+		return os.ErrClosed
+	}
+	if err := lockFile(h.compactLock.Fd(), true); err != nil {
+		return err
+	}
+	defer unlockFile(h.compactLock.Fd())
+
+	// Snapshot the samples recorded so far; later ones are carried over
+	// when the trimmed file is swapped in
+	path := filepath.Join(h.dir, samplesFile)
+	h.mu.Lock()
+	var end int64
+	err := h.lockAppends(true)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(path); err == nil {
+			end = info.Size()
+		} else if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		h.unlockAppends()
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	samples, err := readJSONLinesUpTo[Sample](path, end)
+	if err != nil {
+		return err
+	}
+	minutes, err := readJSONLines[MinuteTotal](filepath.Join(h.dir, minutesFile))
+	if err != nil {
+		return err
+	}
+
+	keep := samples
+	if h.retention.Raw > 0 {
+		// Only whole minutes are folded so an aggregate is never split
+		cutoff := now.Add(-h.retention.Raw).Truncate(time.Minute)
+		keep = samples[:0:0]
+		var expired []Sample
+		for _, sample := range samples {
+			if sample.Time.Before(cutoff) {
+				expired = append(expired, sample)
+			} else {
+				keep = append(keep, sample)
+			}
+		}
+		minutes = mergeMinutes(minutes, h.aggregate(expired))
+	}
+
+	if h.retention.Minutes > 0 {
+		cutoff := now.Add(-h.retention.Minutes)
+		kept := minutes[:0]
+		var expired []MinuteTotal
+		for _, minute := range minutes {
+			if minute.Time.Before(cutoff) {
+				expired = append(expired, minute)
+			} else {
+				kept = append(kept, minute)
+			}
+		}
+		minutes = kept
+		if len(expired) > 0 {
+			hours, err := readJSONLines[MinuteTotal](filepath.Join(h.dir, hoursFile))
+			if err != nil {
+				return err
+			}
+			if err := writeJSONLines(filepath.Join(h.dir, hoursFile), mergeMinutes(hours, hourly(expired))); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := writeJSONLines(filepath.Join(h.dir, minutesFile), minutes); err != nil {
+		return err
+	}
+	if len(keep) == len(samples) {
+		return nil
+	}
+
+	tmp, err := writeTemp(path, keep)
+	if err != nil {
+		return err
+	}
+	return h.swapSamples(tmp, end)
+}
+
+// swapSamples replaces the sample file with tmp, which holds the trimmed
+// samples of its first end bytes, carrying over the samples recorded since
+func (h *History) swapSamples(tmp *os.File, end int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.lockAppends(true); err != nil {
+		discardTemp(tmp)
+		return err
+	}
+	defer h.unlockAppends()
+
+	path := filepath.Join(h.dir, samplesFile)
+	current, err := os.Open(path)
+	if err == nil {
+		_, err = io.Copy(tmp, io.NewSectionReader(current, end, 1<<62))
+		current.Close()
+	}
+	if err != nil {
+		discardTemp(tmp)
+		return err
+	}
+	// Reopen the file for appending once it has been replaced
+	if h.samples != nil {
+		h.samples.Close()
+	}
+	err = commitTemp(tmp, path)
+	if h.samples != nil {
+		if openErr := h.openSamples(); err == nil {
+			err = openErr
+		}
+	}
+	return err
+}
+
+// aggregate sums samples into per-minute totals
+func (h *History) aggregate(samples []Sample) []MinuteTotal {
+	byMinute := make(map[sampleKey]*MinuteTotal)
+	for _, sample := range samples {
+		start := sample.Time.Truncate(time.Minute)
+		key := sampleKey{host: sample.Host, unix: start.UnixNano()}
+		minute, ok := byMinute[key]
+		if !ok {
+			minute = &MinuteTotal{Time: start, Host: sample.Host}
+			byMinute[key] = minute
+		}
+		seconds := sample.Duration(h.interval).Seconds()
+		minute.Upload += uint64(float64(sample.Upload) * seconds)
+		minute.Download += uint64(float64(sample.Download) * seconds)
+		minute.PeakUpload = max(minute.PeakUpload, sample.Upload)
+		minute.PeakDownload = max(minute.PeakDownload, sample.Download)
+	}
+	result := make([]MinuteTotal, 0, len(byMinute))
+	for _, minute := range byMinute {
+		result = append(result, *minute)
+	}
+	return result
+}
+
+// hourly sums minute aggregates into hourly ones
+func hourly(minutes []MinuteTotal) []MinuteTotal {
+	hours := make([]MinuteTotal, len(minutes))
+	for i, minute := range minutes {
+		minute.Time = minute.Time.Truncate(time.Hour)
+		hours[i] = minute
+	}
+	return mergeMinutes(nil, hours)
+}
+
+// mergeMinutes combines two sets of minute (or hourly) aggregates, sorted by
+// time
+func mergeMinutes(existing, added []MinuteTotal) []MinuteTotal {
+	index := make(map[sampleKey]int, len(existing))
+	for i, minute := range existing {
+		index[minute.key()] = i
+	}
+	for _, minute := range added {
+		if i, ok := index[minute.key()]; ok {
+			e := &existing[i]
+			e.Upload += minute.Upload
+			e.Download += minute.Download
+			e.PeakUpload = max(e.PeakUpload, minute.PeakUpload)
+			e.PeakDownload = max(e.PeakDownload, minute.PeakDownload)
+			continue
+		}
+		index[minute.key()] = len(existing)
+		existing = append(existing, minute)
+	}
+	sort.Slice(existing, func(i, j int) bool { return existing[i].Time.Before(existing[j].Time) })
+	return existing
+}
+
+// key returns the aggregate's merge key
+func (m MinuteTotal) key() sampleKey {
+	return sampleKey{host: m.Host, unix: m.Time.UnixNano()}
+}
+
+// Samples returns the stored raw samples, oldest first
+func (h *History) Samples() ([]Sample, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples, err := readJSONLines[Sample](filepath.Join(h.dir, samplesFile))
+	// Imported samples are appended after local ones
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, err
+}
+
+// tailChunk is how much of the sample file RecentSamples reads at a time
+const tailChunk = 64 << 10
+
+// RecentSamples returns the samples recorded on this host since start, oldest
+// first. Unlike Samples it reads the file backwards from its end, a chunk at
+// a time, and stops at the first chunk holding only older samples of this
+// host, so its cost follows the window rather than the whole history.
+func (h *History) RecentSamples(start time.Time) ([]Sample, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	file, err := os.Open(filepath.Join(h.dir, samplesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var recent []Sample
+	var partial []byte // Start of the line cut off at the previous chunk's beginning
+	for end := info.Size(); end > 0; {
+		begin := max(end-tailChunk, 0)
+		chunk := make([]byte, end-begin, end-begin+int64(len(partial)))
+		if _, err := file.ReadAt(chunk, begin); err != nil {
+			return nil, err
+		}
+		lines := bytes.Split(append(chunk, partial...), []byte{'\n'})
+		if partial = nil; begin > 0 {
+			partial, lines = lines[0], lines[1:]
+		}
+		older, newer := false, false
+		for _, line := range lines {
+			var sample Sample
+			if len(line) == 0 || json.Unmarshal(line, &sample) != nil {
+				continue
+			}
+			if sample.Host != h.host && sample.Host != "" {
+				continue
+			}
+			if sample.Time.Before(start) {
+				older = true
+			} else {
+				recent, newer = append(recent, sample), true
+			}
+		}
+		if older && !newer {
+			break
+		}
+		end = begin
+	}
+	// Several processes may have recorded, and imports are appended last
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].Time.Before(recent[j].Time) })
+	return recent, nil
+}
+
+// Minutes returns the stored minute aggregates, oldest first
+func (h *History) Minutes() ([]MinuteTotal, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return readJSONLines[MinuteTotal](filepath.Join(h.dir, minutesFile))
+}
+
+// sessionGap separates recording sessions: a longer pause between two
+// samples means peaks wasn't running
+const sessionGap = time.Minute
+
+// LastSession returns the raw samples of the most recent session recorded on
+// this host before the given time, oldest first, or none. A session is a run
+// of samples without a pause longer than a minute.
+func (h *History) LastSession(before time.Time) ([]Sample, error) {
+	samples, err := h.Samples()
+	if err != nil {
+		return nil, err
+	}
+	var local []Sample
+	for _, sample := range samples {
+		if sample.Host == h.host && sample.Time.Before(before) {
+			local = append(local, sample)
+		}
+	}
+	start := len(local) - 1
+	for start > 0 && local[start].Time.Sub(local[start-1].Time) <= sessionGap {
+		start--
+	}
+	return local[max(start, 0):], nil
+}
+
+// readJSONLines reads every record of a JSON Lines file; a missing file is
+// empty. Lines that don't parse, e.g. one torn by a crash mid-write, are
+// skipped.
+func readJSONLines[T any](path string) ([]T, error) {
+	return readJSONLinesUpTo[T](path, -1)
+}
+
+// readJSONLinesUpTo reads the records in the first end bytes of a JSON Lines
+// file, or all of them if end is negative
+func readJSONLinesUpTo[T any](path string, end int64) ([]T, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if end >= 0 {
+		r = io.LimitReader(file, end)
+	}
+	var records []T
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// writeJSONLines atomically replaces a JSON Lines file
+func writeJSONLines[T any](path string, records []T) error {
+	tmp, err := writeTemp(path, records)
+	if err != nil {
+		return err
+	}
+	return commitTemp(tmp, path)
+}
+
+// writeTemp writes records to a new temporary file beside path, left open
+// for commitTemp or discardTemp
+func writeTemp[T any](path string, records []T) (*os.File, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			discardTemp(tmp)
+			return nil, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		discardTemp(tmp)
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// commitTemp closes a file from writeTemp and renames it over path
+func commitTemp(tmp *os.File, path string) error {
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// discardTemp closes and removes a file from writeTemp
+func discardTemp(tmp *os.File) {
+	tmp.Close()
+	os.Remove(tmp.Name())
+}
@@ -0,0 +1,155 @@
+// Package accounting keeps per-day traffic totals that survive restarts
+//
+// The Ledger accumulates the current local day's traffic and, at local
+// midnight, closes the day out into a persistent Store. Day boundaries follow
+// the system time zone, including DST transitions and time zone changes made
+// while peaks is running.
+package accounting
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// DateLayout formats the calendar date a DayTotal belongs to
+const DateLayout = "2006-01-02"
+
+// DayTotal is the traffic of one local calendar day, in bytes
+type DayTotal struct {
+	Date     string `json:"date"`
+	Upload   uint64 `json:"upload"`
+	Download uint64 `json:"download"`
+}
+
+// Add returns the sum of two totals for the same day
+func (d DayTotal) Add(other DayTotal) DayTotal {
+	d.Upload += other.Upload
+	d.Download += other.Download
+	return d
+}
+
+// locationRefresh is how often the system time zone is re-read
+const locationRefresh = time.Minute
+
+// FlushInterval is how often Add writes the day's running total to the
+// store, so a crash or kill loses at most this much of it
+const FlushInterval = 30 * time.Second
+
+// Ledger accumulates traffic into per-day totals
+type Ledger struct {
+	store Store
+	// Traffic recorded earlier today, by a previous run
+	base DayTotal
+	// Traffic recorded by this run since the day started or was last flushed
+	today DayTotal
+	// When Add last flushed
+	flushed time.Time
+
+	loc        *time.Location
+	fixedLoc   bool
+	locChecked time.Time
+}
+
+// NewLedger creates a ledger recording into store
+func NewLedger(store Store) *Ledger {
+	return &Ledger{store: store}
+}
+
+// SetLocation pins day boundaries to loc instead of following the system
+// time zone (nil restores the system time zone)
+func (l *Ledger) SetLocation(loc *time.Location) {
+	l.loc, l.fixedLoc = loc, loc != nil
+	l.locChecked = time.Time{}
+}
+
+// location returns the time zone days are counted in. The system zone is
+// re-read periodically, since time.Local is only loaded once per process.
+func (l *Ledger) location(at time.Time) *time.Location {
+	if l.fixedLoc {
+		return l.loc
+	}
+	if l.loc == nil || at.Sub(l.locChecked) >= locationRefresh || at.Before(l.locChecked) {
+		l.loc = systemLocation()
+		l.locChecked = at
+	}
+	return l.loc
+}
+
+// systemLocation loads the current system time zone from TZ or /etc/localtime
+func systemLocation() *time.Location {
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		if loc, err := time.LoadLocation(strings.TrimPrefix(tz, ":")); err == nil {
+			return loc
+		}
+		return time.Local
+	}
+	if data, err := os.ReadFile("/etc/localtime"); err == nil {
+		if loc, err := time.LoadLocationFromTZData("Local", data); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// Add records traffic observed at the given time, closing out the previous
+// day into the store first if a local midnight has passed, and flushing the
+// running total every FlushInterval
+func (l *Ledger) Add(at time.Time, upload, download uint64) error {
+	date := at.In(l.location(at)).Format(DateLayout)
+	var err error
+	if date != l.today.Date {
+		err = l.startDay(date)
+		l.flushed = at
+	}
+	l.today.Upload += upload
+	l.today.Download += download
+	if at.Sub(l.flushed) >= FlushInterval || at.Before(l.flushed) {
+		l.flushed = at
+		if flushErr := l.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+// startDay closes out the current day and starts counting date. A store
+// that can merges the previous days' flushes into one record each.
+func (l *Ledger) startDay(date string) error {
+	err := l.Flush()
+	if compacter, ok := l.store.(interface{ Compact() error }); ok && err == nil {
+		err = compacter.Compact()
+	}
+
+	l.today = DayTotal{Date: date}
+	l.base = DayTotal{Date: date}
+	if days, loadErr := l.store.Load(); loadErr == nil {
+		for _, day := range days {
+			if day.Date == date {
+				l.base = l.base.Add(day)
+			}
+		}
+	} else if err == nil {
+		err = loadErr
+	}
+	return err
+}
+
+// Flush writes the traffic recorded so far today to the store, e.g. before
+// exiting. Days are merged on load, so flushing repeatedly is safe.
+func (l *Ledger) Flush() error {
+	if l.today.Upload == 0 && l.today.Download == 0 {
+		return nil
+	}
+	if err := l.store.Append(l.today); err != nil {
+		return err
+	}
+	l.base = l.base.Add(l.today)
+	l.today.Upload, l.today.Download = 0, 0
+	return nil
+}
+
+// Today returns the current day's total, including earlier runs
+func (l *Ledger) Today() DayTotal {
+	return l.base.Add(l.today)
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !linux && !freebsd && !openbsd && !netbsd && !windows
+
+package accounting
+
+// lockFile is a no-op where file locks aren't supported; only one peaks
+// should then use a history directory at a time
+func lockFile(fd uintptr, exclusive bool) error {
+	return nil
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(fd uintptr) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build darwin || linux || freebsd || openbsd || netbsd
+// +build darwin linux freebsd openbsd netbsd
+
+package accounting
+
+import "golang.org/x/sys/unix"
+
+// lockFile takes an advisory lock on the open file fd, waiting for other holders
+func lockFile(fd uintptr, exclusive bool) error {
+	how := unix.LOCK_SH
+	if exclusive {
+		how = unix.LOCK_EX
+	}
+	return unix.Flock(int(fd), how)
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(fd uintptr) error {
+	return unix.Flock(int(fd), unix.LOCK_UN)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package accounting
+
+import "golang.org/x/sys/windows"
+
+// lockFile takes an advisory lock on the open file fd, waiting for other holders
+func lockFile(fd uintptr, exclusive bool) error {
+	var flags uint32
+	if exclusive {
+		flags = windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	return windows.LockFileEx(windows.Handle(fd), flags, 0, 1, 0, &windows.Overlapped{})
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(fd uintptr) error {
+	return windows.UnlockFileEx(windows.Handle(fd), 0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,109 @@
+// Package accounting provides billing cycle usage projection
+package accounting
+
+import (
+	"fmt"
+	"time"
+)
+
+// Projection methods
+const (
+	// ProjectLinear extrapolates the cycle's usage so far over the whole cycle
+	ProjectLinear = "linear"
+	// ProjectRecent adds the last seven days' daily average for each
+	// remaining day, following recent changes in usage faster
+	ProjectRecent = "recent"
+)
+
+// recentDays is how many complete days ProjectRecent averages
+const recentDays = 7
+
+// ParseProjection validates a projection method name
+func ParseProjection(method string) (string, error) {
+	switch method {
+	case ProjectLinear, ProjectRecent:
+		return method, nil
+	}
+	return "", fmt.Errorf("unknown projection %q (expected linear or recent)", method)
+}
+
+// Projection is the usage of the current billing cycle and its projected
+// end-of-cycle total, in bytes of both directions
+type Projection struct {
+	Start, End time.Time
+	Used       uint64
+	Projected  uint64
+}
+
+// CycleBounds returns the billing cycle containing now, starting at local
+// midnight on startDay of the month. Days past the end of a short month
+// start the cycle on its last day.
+func CycleBounds(now time.Time, startDay int) (start, end time.Time) {
+	cycleStart := func(year int, month time.Month) time.Time {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, now.Location()).Day()
+		return time.Date(year, month, min(max(startDay, 1), last), 0, 0, 0, 0, now.Location())
+	}
+	start = cycleStart(now.Year(), now.Month())
+	if now.Before(start) {
+		start = cycleStart(now.Year(), now.Month()-1)
+	}
+	return start, cycleStart(start.Year(), start.Month()+1)
+}
+
+// Project estimates the current cycle's total usage from the daily totals,
+// which should include today's traffic so far
+func Project(days []DayTotal, now time.Time, startDay int, method string) Projection {
+	start, end := CycleBounds(now, startDay)
+	p := Projection{Start: start, End: end}
+	first, today := start.Format(DateLayout), now.Format(DateLayout)
+	byDate := make(map[string]uint64, len(days))
+	earliest := today
+	for _, day := range days {
+		total := day.Upload + day.Download
+		byDate[day.Date] = total
+		if day.Date >= first && day.Date <= today {
+			p.Used += total
+		}
+		earliest = min(earliest, day.Date)
+	}
+
+	if method == ProjectRecent {
+		// Average the complete days before today, ignoring days before the
+		// ledger's first record
+		var sum uint64
+		var count int
+		noon := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, now.Location())
+		for i := 1; i <= recentDays; i++ {
+			date := noon.AddDate(0, 0, -i).Format(DateLayout)
+			if date < earliest {
+				break
+			}
+			sum += byDate[date]
+			count++
+		}
+		if count > 0 {
+			remaining := end.Sub(now).Hours() / 24
+			p.Projected = p.Used + uint64(float64(sum)/float64(count)*remaining)
+			return p
+		}
+	}
+
+	// Linear, and recent until a complete day has been recorded
+	if elapsed := now.Sub(start); elapsed > 0 {
+		p.Projected = uint64(float64(p.Used) * float64(end.Sub(start)) / float64(elapsed))
+	}
+	return p
+}
+
+// Project projects the current billing cycle's usage from the store and
+// today's traffic so far, counting days in the ledger's time zone
+func (l *Ledger) Project(at time.Time, startDay int, method string) (Projection, error) {
+	days, err := l.store.Load()
+	if err != nil {
+		return Projection{}, err
+	}
+	if l.today.Date != "" {
+		days = MergeDays(append(days, l.today))
+	}
+	return Project(days, at.In(l.location(at)), startDay, method), nil
+}
@@ -0,0 +1,76 @@
+// Package accounting provides data cap tracking
+package accounting
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota periods
+const (
+	// QuotaMonthly caps the billing cycle, which starts on the cycle day
+	QuotaMonthly = "monthly"
+	// QuotaDaily caps each local day
+	QuotaDaily = "daily"
+)
+
+// ParseQuotaPeriod validates a quota period name
+func ParseQuotaPeriod(period string) (string, error) {
+	switch period {
+	case QuotaMonthly, QuotaDaily:
+		return period, nil
+	}
+	return "", fmt.Errorf("unknown quota period %q (expected monthly or daily)", period)
+}
+
+// sizePowers are the powers of the size suffixes
+var sizePowers = map[string]int{"": 0, "K": 1, "M": 2, "G": 3, "T": 4}
+
+// ParseSize parses a data cap in bytes, such as 500GB, 1.5T, 800GiB or a
+// plain number of bytes. Suffixes are read in the units totals are shown
+// in: 1000-based when decimal (--prefixes si), otherwise 1024-based. An
+// explicit binary suffix such as GiB is always 1024-based.
+func ParseSize(s string, decimal bool) (uint64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	binary := strings.HasSuffix(value, "I")
+	value = strings.TrimSuffix(value, "I")
+	number := strings.TrimRight(value, "KMGT")
+	power, ok := sizePowers[value[len(number):]]
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 500GB, 1.5T or 800G)", s)
+	}
+	base := 1024.0
+	if decimal && !binary {
+		base = 1000
+	}
+	return uint64(n * math.Pow(base, float64(power))), nil
+}
+
+// ProjectDay projects today's total usage from its traffic so far,
+// extrapolated over the day in the ledger's time zone. Like Project, it reads
+// the store, so the running totals other peaks flush to it count as well.
+func (l *Ledger) ProjectDay(at time.Time) (Projection, error) {
+	days, err := l.store.Load()
+	if err != nil {
+		return Projection{}, err
+	}
+	if l.today.Date != "" {
+		days = MergeDays(append(days, l.today))
+	}
+	at = at.In(l.location(at))
+	start := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	p := Projection{Start: start, End: start.AddDate(0, 0, 1)}
+	for _, day := range days {
+		if day.Date == at.Format(DateLayout) {
+			p.Used = day.Upload + day.Download
+		}
+	}
+	if elapsed := at.Sub(start); elapsed > 0 {
+		p.Projected = uint64(float64(p.Used) * float64(p.End.Sub(start)) / float64(elapsed))
+	}
+	return p, nil
+}
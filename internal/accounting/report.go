@@ -0,0 +1,221 @@
+// Package accounting provides scheduled usage reports by email
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Report periods
+const (
+	ReportDaily  = "daily"
+	ReportWeekly = "weekly"
+)
+
+// reportDelay is how long after local midnight reports are sent, giving the
+// ledger time to close out the previous day
+const reportDelay = 5 * time.Minute
+
+// Report summarizes the daily totals of a reporting period
+type Report struct {
+	Period string
+	Host   string
+	Days   []DayTotal // One per calendar day, oldest first, zero if not recorded
+	Total  DayTotal
+}
+
+// BuildReport summarizes the complete days of the period ending before now's
+// local date: yesterday for daily reports, the last seven days for weekly ones
+func BuildReport(days []DayTotal, period string, now time.Time) Report {
+	count := 1
+	if period == ReportWeekly {
+		count = 7
+	}
+	byDate := make(map[string]DayTotal, len(days))
+	for _, day := range days {
+		byDate[day.Date] = day
+	}
+
+	report := Report{Period: period}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, now.Location())
+	for i := count; i >= 1; i-- {
+		date := today.AddDate(0, 0, -i).Format(DateLayout)
+		day := byDate[date]
+		day.Date = date
+		report.Days = append(report.Days, day)
+		report.Total = report.Total.Add(day)
+	}
+	report.Total.Date = ""
+	return report
+}
+
+// Subject returns the email subject line of the report
+func (r Report) Subject() string {
+	span := r.Days[0].Date
+	if len(r.Days) > 1 {
+		span += " to " + r.Days[len(r.Days)-1].Date
+	}
+	if r.Host != "" {
+		return fmt.Sprintf("PEAKS %s usage for %s: %s", r.Period, r.Host, span)
+	}
+	return fmt.Sprintf("PEAKS %s usage: %s", r.Period, span)
+}
+
+// Text renders the report as plain text, formatting byte counts with format
+func (r Report) Text(format func(uint64) string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n\n", r.Subject())
+	fmt.Fprintf(&sb, "%-10s  %12s  %12s\n", "Date", "Download", "Upload")
+	for _, day := range r.Days {
+		fmt.Fprintf(&sb, "%-10s  %12s  %12s\n", day.Date, format(day.Download), format(day.Upload))
+	}
+	if len(r.Days) > 1 {
+		fmt.Fprintf(&sb, "%-10s  %12s  %12s\n", "Total", format(r.Total.Download), format(r.Total.Upload))
+	}
+	return sb.String()
+}
+
+// reportHTML lays out a report as an HTML table
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+<h2>{{.Subject}}</h2>
+<table cellpadding="6" style="border-collapse: collapse">
+<tr><th align="left">Date</th><th align="right">Download</th><th align="right">Upload</th></tr>
+{{range .Days}}<tr><td>{{.Date}}</td><td align="right">{{.Download}}</td><td align="right">{{.Upload}}</td></tr>
+{{end}}{{if .Total}}<tr style="font-weight: bold"><td>Total</td><td align="right">{{.Total.Download}}</td><td align="right">{{.Total.Upload}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+// HTML renders the report as an HTML document, formatting byte counts with format
+func (r Report) HTML(format func(uint64) string) (string, error) {
+	type row struct{ Date, Download, Upload string }
+	view := struct {
+		Subject string
+		Days    []row
+		Total   *row
+	}{Subject: r.Subject()}
+	for _, day := range r.Days {
+		view.Days = append(view.Days, row{day.Date, format(day.Download), format(day.Upload)})
+	}
+	if len(r.Days) > 1 {
+		view.Total = &row{"", format(r.Total.Download), format(r.Total.Upload)}
+	}
+
+	var buf bytes.Buffer
+	if err := reportHTML.Execute(&buf, view); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SMTPConfig is where and as whom reports are mailed. The connection is
+// upgraded with STARTTLS when the server offers it; credentials are only
+// sent over TLS or to localhost.
+type SMTPConfig struct {
+	Addr     string // host:port
+	Username string // Empty to send without authenticating
+	Password string
+	From     string
+	To       []string
+}
+
+// Reporter mails the ledger's totals on a daily or weekly schedule
+type Reporter struct {
+	store  Store
+	smtp   SMTPConfig
+	period string
+	html   bool
+	format func(uint64) string
+	host   string
+}
+
+// NewReporter creates a reporter for the totals in store. period is
+// ReportDaily or ReportWeekly; format renders byte counts.
+func NewReporter(store Store, config SMTPConfig, period string, html bool, format func(uint64) string) (*Reporter, error) {
+	if period != ReportDaily && period != ReportWeekly {
+		return nil, fmt.Errorf("unknown report period %q (expected daily or weekly)", period)
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		return nil, fmt.Errorf("invalid SMTP server %q: %w", config.Addr, err)
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("email reports need a sender and at least one recipient")
+	}
+	host, _ := os.Hostname()
+	return &Reporter{store: store, smtp: config, period: period, html: html, format: format, host: host}, nil
+}
+
+// Send mails the report for the period ending before now's local date
+func (r *Reporter) Send(now time.Time) error {
+	days, err := r.store.Load()
+	if err != nil {
+		return err
+	}
+	report := BuildReport(days, r.period, now.In(systemLocation()))
+	report.Host = r.host
+
+	contentType, body := "text/plain", report.Text(r.format)
+	if r.html {
+		contentType = "text/html"
+		if body, err = report.HTML(r.format); err != nil {
+			return err
+		}
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", r.smtp.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(r.smtp.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", report.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=UTF-8\r\n\r\n", contentType)
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if r.smtp.Username != "" {
+		host, _, _ := net.SplitHostPort(r.smtp.Addr)
+		auth = smtp.PlainAuth("", r.smtp.Username, r.smtp.Password, host)
+	}
+	return smtp.SendMail(r.smtp.Addr, auth, r.smtp.From, r.smtp.To, msg.Bytes())
+}
+
+// nextReport returns when the report after now is due: shortly after every
+// local midnight for daily reports, after Sunday's midnight for weekly ones
+func (r *Reporter) nextReport(now time.Time) time.Time {
+	loc := systemLocation()
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc).Add(reportDelay)
+	for !next.After(now) || (r.period == ReportWeekly && next.Weekday() != time.Monday) {
+		day := next.AddDate(0, 0, 1)
+		next = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc).Add(reportDelay)
+	}
+	return next
+}
+
+// Start sends reports on schedule in the background until the returned stop
+// function is called. Reports due while peaks wasn't running are not sent.
+func (r *Reporter) Start(onError func(error)) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(r.nextReport(time.Now())))
+			select {
+			case <-timer.C:
+				if err := r.Send(time.Now()); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
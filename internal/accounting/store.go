@@ -0,0 +1,146 @@
+// Package accounting provides persistent storage of daily totals
+package accounting
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Store persists closed-out daily totals
+type Store interface {
+	// Append records traffic for a day; several records for one day add up
+	Append(day DayTotal) error
+	// Load returns one merged total per day, oldest first
+	Load() ([]DayTotal, error)
+}
+
+// FileStore keeps daily totals in a JSON Lines file
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store backed by path; the file and its directory
+// are created on first write
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// DefaultStorePath returns the ledger file in the user's config directory
+func DefaultStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "peaks", "daily.jsonl"), nil
+}
+
+// Path returns the file backing the store
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Append implements Store
+func (s *FileStore) Append(day DayTotal) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(day); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Compact merges the records of each day into one, since a running peaks
+// flushes the day's total every FlushInterval
+func (s *FileStore) Compact() error {
+	if _, err := os.Stat(s.path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	days, err := s.Load()
+	if err != nil || len(days) == 0 {
+		return err
+	}
+	return writeJSONLines(s.path, days)
+}
+
+// lock keeps other peaks processes sharing the file from appending to it
+// while it is compacted
+func (s *FileStore) lock() (unlock func(), err error) {
+	file, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file.Fd(), true); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(file.Fd())
+		file.Close()
+	}, nil
+}
+
+// Load implements Store. A missing file is an empty ledger.
+func (s *FileStore) Load() ([]DayTotal, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []DayTotal
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var day DayTotal
+		if err := json.Unmarshal(scanner.Bytes(), &day); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.path, line, err)
+		}
+		records = append(records, day)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return MergeDays(records), nil
+}
+
+// MergeDays sums records for the same day and sorts the result by date
+func MergeDays(records []DayTotal) []DayTotal {
+	byDate := make(map[string]DayTotal, len(records))
+	for _, day := range records {
+		existing := byDate[day.Date]
+		existing.Date = day.Date
+		byDate[day.Date] = existing.Add(day)
+	}
+	days := make([]DayTotal, 0, len(byDate))
+	for _, day := range byDate {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
@@ -0,0 +1,105 @@
+// Package accounting provides hourly, daily and monthly usage totals
+package accounting
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Usage periods
+const (
+	UsageHourly  = "hourly"
+	UsageDaily   = "daily"
+	UsageMonthly = "monthly"
+)
+
+// ParseUsagePeriod validates a usage period name
+func ParseUsagePeriod(period string) (string, error) {
+	switch period {
+	case UsageHourly, UsageDaily, UsageMonthly:
+		return period, nil
+	}
+	return "", fmt.Errorf("unknown usage period %q (expected hourly, daily or monthly)", period)
+}
+
+// UsageTotal is the traffic of one hour, day or month, in bytes
+type UsageTotal struct {
+	Start    time.Time
+	Upload   uint64
+	Download uint64
+}
+
+// periodStart returns the start of the hour, day or month containing t, in
+// t's location
+func periodStart(t time.Time, period string) time.Time {
+	switch period {
+	case UsageHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case UsageMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// shiftPeriod returns the start of the period n periods after start
+func shiftPeriod(start time.Time, period string, n int) time.Time {
+	switch period {
+	case UsageHourly:
+		return time.Date(start.Year(), start.Month(), start.Day(), start.Hour()+n, 0, 0, 0, start.Location())
+	case UsageMonthly:
+		return start.AddDate(0, n, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// Usage rolls a host's recorded traffic up into the last count hours, days
+// or months up to now, in now's location, oldest first. Periods without
+// traffic are zero. Hourly totals of long-expired minutes count towards the
+// hour they were recorded in.
+func (h *History) Usage(host, period string, now time.Time, count int) ([]UsageTotal, error) {
+	h.mu.Lock()
+	hours, err := readJSONLines[MinuteTotal](filepath.Join(h.dir, hoursFile))
+	var minutes []MinuteTotal
+	var samples []Sample
+	if err == nil {
+		minutes, err = readJSONLines[MinuteTotal](filepath.Join(h.dir, minutesFile))
+	}
+	if err == nil {
+		samples, err = readJSONLines[Sample](filepath.Join(h.dir, samplesFile))
+	}
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	current := periodStart(now, period)
+	totals := make([]UsageTotal, count)
+	index := make(map[int64]int, count)
+	for i := range totals {
+		totals[i].Start = shiftPeriod(current, period, i-count+1)
+		index[totals[i].Start.Unix()] = i
+	}
+	add := func(at time.Time, upload, download uint64) {
+		if i, ok := index[periodStart(at.In(now.Location()), period).Unix()]; ok {
+			totals[i].Upload += upload
+			totals[i].Download += download
+		}
+	}
+	for _, aggregates := range [][]MinuteTotal{hours, minutes} {
+		for _, a := range aggregates {
+			if a.Host == host {
+				add(a.Time, a.Upload, a.Download)
+			}
+		}
+	}
+	for _, s := range samples {
+		if s.Host == host {
+			seconds := s.Duration(h.interval).Seconds()
+			add(s.Time, uint64(float64(s.Upload)*seconds), uint64(float64(s.Download)*seconds))
+		}
+	}
+	return totals, nil
+}
@@ -0,0 +1,172 @@
+// Package alerts provides rate threshold alerts and their notifications
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alert directions
+const (
+	Upload   = "up"
+	Download = "down"
+)
+
+// clearRatio is the fraction of its threshold a rate must fall below to
+// clear an alert, so a rate hovering at the threshold doesn't flap
+const clearRatio = 0.9
+
+// hookTimeout bounds how long a hook command may run
+const hookTimeout = 30 * time.Second
+
+// Thresholds are the rates, in bytes per second, that raise an alert; zero
+// disables a direction
+type Thresholds struct {
+	Upload   uint64
+	Download uint64
+}
+
+// Event is a rate crossing its threshold, or falling back below it
+type Event struct {
+	Time      time.Time `json:"t"`
+	Direction string    `json:"direction"` // up or down
+	Rate      uint64    `json:"rate"`
+	Threshold uint64    `json:"threshold"`
+	Above     bool      `json:"above"` // false when the alert cleared
+}
+
+// Watcher raises an Event each time a rate crosses its threshold. An alert
+// clears once the rate falls below 90% of the threshold.
+type Watcher struct {
+	thresholds Thresholds
+	above      [2]bool // upload, download
+}
+
+// NewWatcher creates a watcher for the given thresholds
+func NewWatcher(thresholds Thresholds) *Watcher {
+	return &Watcher{thresholds: thresholds}
+}
+
+// Check compares a sample with the thresholds and returns the crossings
+func (w *Watcher) Check(at time.Time, upload, download uint64) []Event {
+	var events []Event
+	for i, d := range []struct {
+		direction string
+		rate      uint64
+		threshold uint64
+	}{
+		{Upload, upload, w.thresholds.Upload},
+		{Download, download, w.thresholds.Download},
+	} {
+		if d.threshold == 0 {
+			continue
+		}
+		above := d.rate > d.threshold
+		if w.above[i] && !above {
+			// Stay raised until the rate is clearly back below
+			above = float64(d.rate) >= float64(d.threshold)*clearRatio
+		}
+		if above != w.above[i] {
+			w.above[i] = above
+			events = append(events, Event{Time: at, Direction: d.direction, Rate: d.rate, Threshold: d.threshold, Above: above})
+		}
+	}
+	return events
+}
+
+// Raised reports which directions are currently above their thresholds
+func (w *Watcher) Raised() (upload, download bool) {
+	return w.above[0], w.above[1]
+}
+
+// Thresholds returns the watched thresholds
+func (w *Watcher) Thresholds() Thresholds {
+	return w.thresholds
+}
+
+// rateUnits are the binary multipliers ParseRate accepts, matching the units
+// rates are displayed in
+var rateUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseRate parses a threshold in bytes per second, such as 50MB, 50MB/s,
+// 1.5G, 800KiB or a plain number of bytes
+func ParseRate(s string) (uint64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "/S")
+	value = strings.TrimSuffix(value, "B")
+	value = strings.TrimSuffix(value, "I")
+	number := strings.TrimRight(value, "KMGT")
+	unit := value[len(number):]
+	multiplier, ok := rateUnits[unit]
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if !ok || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rate %q (expected e.g. 50MB/s, 800K or 1.5G)", s)
+	}
+	return uint64(n * float64(multiplier)), nil
+}
+
+// AppendLog appends an event to a JSON Lines log
+func AppendLog(path string, event Event) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(event)
+}
+
+// Notify shows a desktop notification: notify-send on Linux and the BSDs,
+// osascript on macOS and a PowerShell balloon tip on Windows
+func Notify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e",
+			fmt.Sprintf("display notification %s with title %s", strconv.Quote(body), strconv.Quote(title)))
+	case "windows":
+		script := "[reflection.assembly]::loadwithpartialname('System.Windows.Forms') | Out-Null;" +
+			"$n = New-Object System.Windows.Forms.NotifyIcon; $n.Icon = [System.Drawing.SystemIcons]::Information;" +
+			"$n.Visible = $true; $n.ShowBalloonTip(10000, $env:PEAKS_TITLE, $env:PEAKS_BODY, 'Warning'); Start-Sleep 10; $n.Dispose()"
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+		cmd.Env = append(os.Environ(), "PEAKS_TITLE="+title, "PEAKS_BODY="+body)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=peaks", title, body)
+	}
+	return cmd.Run()
+}
+
+// RunHook runs a shell command for an event, passing it in PEAKS_ALERT_STATE
+// (above or cleared), PEAKS_ALERT_DIRECTION (up or down), PEAKS_ALERT_RATE
+// and PEAKS_ALERT_THRESHOLD (bytes per second)
+func RunHook(command string, event Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	state := "above"
+	if !event.Above {
+		state = "cleared"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Env = append(os.Environ(),
+		"PEAKS_ALERT_STATE="+state,
+		"PEAKS_ALERT_DIRECTION="+event.Direction,
+		"PEAKS_ALERT_RATE="+strconv.FormatUint(event.Rate, 10),
+		"PEAKS_ALERT_THRESHOLD="+strconv.FormatUint(event.Threshold, 10),
+	)
+	return cmd.Run()
+}
@@ -6,7 +6,7 @@
 package chart
 
 import (
-	"strings"
+	"time"
 )
 
 // BrailleChart creates beautiful braille-based charts for terminal display
@@ -17,13 +17,13 @@ type BrailleChart struct {
 	uploadData   []uint64
 	downloadData []uint64
 	maxValue     uint64
+	fixedMax     uint64 // locked scale maximum (0 to follow the data)
 	minHeight    int
 	// Optimization: track current max without full recalculation
 	currentMax uint64
-	// Optimization: pre-allocated string builder for rendering
-	builder strings.Builder
-	// Optimization: pre-allocated slice for lines to avoid repeated allocations
-	lines []strings.Builder
+	// Optimization: reused frame buffer and per-column glyph references
+	frame        []byte
+	frameColumns [][]string
 	// Display mode: false = split axis, true = overlay mode
 	overlayMode bool
 	// Scaling mode: how the data is scaled (linear, logarithmic, square root)
@@ -33,6 +33,69 @@ type BrailleChart struct {
 	// Cached column data for stability
 	columnCache map[int][]string // windowIndex -> rendered column lines
 	lastCompleteWindow int       // last window index that was completed
+	// Rendered columns keyed by quantized dot heights, so unchanged columns are never restyled
+	renderCache map[columnKey][]string
+	// Per-chart color gradients and the bounded cache of glyphs styled with them
+	uploadGradient   ColorGradient
+	downloadGradient ColorGradient
+	overlapGradient  ColorGradient
+	styleCache       *styleCache
+	// Theme the gradients came from, and which SetGradients call set them
+	themeName string
+	palette   int
+	// Precomputed ANSI sequences per gradient step, bypassing lipgloss per cell
+	ansi ansiTable
+	// Characters cells are drawn with, as a braille dot pattern translation table
+	glyphSet GlyphSet
+	glyphs   *[maxBrailleChars]string
+	// Overlay mode without colors: directions told apart by dot column
+	monochrome bool
+	// Deterministic rendering: plain glyphs without ANSI styling and an injectable clock
+	plainOutput    bool
+	clock          func() time.Time
+	lastSampleTime time.Time
+	// Downsampled history for multi-hour time scales, plus per-column scratch buffers
+	history         *tieredHistory
+	historyUpload   []uint64
+	historyDownload []uint64
+	// Frame cache: the last rendered frame is reused until something visible changes
+	lastFrame        string
+	frameDirty       bool
+	frameValid       bool
+	frameStringValid bool
+	steadyCount      int // consecutive samples identical to their predecessor
+	// Interrupted intervals (suspend/resume, clock jumps), as absolute sample indices
+	sampleTotal int
+	gaps        []int
+	// Event markers (e.g. route changes), as absolute sample indices
+	markers []int
+	// Previous session drawn behind the data, by absolute sample index
+	ghostUpload   []uint64
+	ghostDownload []uint64
+	// Keyboard cursor, as columns left of the newest (-1 when hidden)
+	cursor int
+	// Display smoothing and its per-column scratch buffer
+	smoothing     bool
+	smoothColumns []smoothColumn
+	// Axis gutter placement and its scale label format; byte rates are
+	// labelled in bits per second with bitUnits, and with 1000-based
+	// prefixes with decimalUnits
+	gutter       GutterPlacement
+	labelFormat  func(uint64) string
+	bitUnits     bool
+	decimalUnits bool
+	// Corner direction labels and their styled cells (built on first use)
+	directionLabels bool
+	labelCells      [2][]string
+	// Gridlines, the scale value of each gridline row (0 for none) and the
+	// styled gridline cell (built on first use)
+	gridlines      bool
+	gridLabels     []uint64
+	gridCellStyled string
+	// Chart whose data buffer this one draws (nil unless made by Mirror)
+	source *BrailleChart
+	// Chart whose view settings this one takes (nil unless set by Follow)
+	leader *BrailleChart
 }
 
 // NewBrailleChart creates a new braille chart
@@ -47,31 +110,62 @@ func NewBrailleChart(maxPoints int) *BrailleChart {
 		maxValue:     1024, // Start with 1KB minimum scale
 		minHeight:    MinChartHeight,
 		currentMax:   0,
-		// Optimization: pre-allocate string builders
-		lines:       make([]strings.Builder, 0, defaultMaxPoints), // Pre-allocate for typical chart heights
 		overlayMode: false,                                        // Default to split axis mode
 		scalingMode: ScalingLogarithmic,                          // Default to logarithmic scaling
 		timeScale:   TimeScale1Min,                               // Default to 1 minute time scale
 		// Initialize caching for stability
 		columnCache: make(map[int][]string),
 		lastCompleteWindow: -1,
+		renderCache:        make(map[columnKey][]string),
+		uploadGradient:     defaultTheme.Upload,
+		downloadGradient:   defaultTheme.Download,
+		overlapGradient:    defaultTheme.Overlap,
+		themeName:          DefaultThemeName,
+		styleCache:         newStyleCache(defaultStyleCacheSize),
+		ansi:               newANSITable(defaultTheme.Upload, defaultTheme.Download, defaultTheme.Overlap),
+		clock:              time.Now,
+		glyphs:             &brailleGlyphs,
+		frameDirty:         true,
+		history:            newTieredHistory(),
+		cursor:             -1,
 	}
 }
 
 // SetWidth sets the chart width
 func (bc *BrailleChart) SetWidth(width int) {
+	oldWidth := bc.width
 	bc.width = width
-	if bc.width < 20 {
-		bc.width = 20
+	if bc.width < MinChartWidth {
+		bc.width = MinChartWidth
+	}
+	if bc.width != oldWidth {
+		bc.frameDirty = true
 	}
 }
 
 // SetHeight sets the chart height
 func (bc *BrailleChart) SetHeight(height int) {
+	oldHeight := bc.height
 	bc.height = height
 	if bc.height < bc.minHeight {
 		bc.height = bc.minHeight
 	}
+	// Cached window columns were rendered for the old height
+	if bc.height != oldHeight {
+		bc.invalidateColumnCache()
+	}
+}
+
+// SetMinHeight sets the smallest height SetHeight will accept (at least 2 rows,
+// one per direction in split mode). Tiny terminals lower it below MinChartHeight.
+func (bc *BrailleChart) SetMinHeight(height int) {
+	if height < 2 {
+		height = 2
+	}
+	bc.minHeight = height
+	if bc.height < height {
+		bc.SetHeight(height)
+	}
 }
 
 // SetOverlayMode sets the display mode
@@ -85,7 +179,7 @@ func (bc *BrailleChart) SetOverlayMode(enabled bool) {
 
 // ToggleOverlayMode toggles between split axis and overlay mode
 func (bc *BrailleChart) ToggleOverlayMode() {
-	bc.overlayMode = !bc.overlayMode
+	bc.SetOverlayMode(!bc.overlayMode)
 }
 
 // IsOverlayMode returns true if overlay mode is enabled
@@ -93,42 +187,88 @@ func (bc *BrailleChart) IsOverlayMode() bool {
 	return bc.overlayMode
 }
 
+// SetPlainOutput disables all color/ANSI styling so the chart renders pure glyphs
+func (bc *BrailleChart) SetPlainOutput(enabled bool) {
+	if bc.plainOutput != enabled {
+		bc.plainOutput = enabled
+		bc.invalidateColumnCache()
+	}
+}
+
+// IsPlainOutput returns true if color output is disabled
+func (bc *BrailleChart) IsPlainOutput() bool {
+	return bc.plainOutput
+}
+
+// SetClock injects the time source used for sample timestamps.
+// Passing nil restores the wall clock.
+func (bc *BrailleChart) SetClock(clock func() time.Time) {
+	if clock == nil {
+		clock = time.Now
+	}
+	bc.clock = clock
+}
+
+// SetDeterministic configures the chart for reproducible output: plain glyphs
+// and a fixed clock, suitable for golden-file comparisons in tests
+func (bc *BrailleChart) SetDeterministic(clock func() time.Time) {
+	bc.SetPlainOutput(true)
+	bc.SetClock(clock)
+}
+
+// GetLastSampleTime returns the timestamp of the most recent data point
+func (bc *BrailleChart) GetLastSampleTime() time.Time {
+	return bc.lastSampleTime
+}
+
 // GetWidth returns the chart width
 func (bc *BrailleChart) GetWidth() int {
 	return bc.width
 }
 
+// GetHeight returns the chart height
+func (bc *BrailleChart) GetHeight() int {
+	return bc.height
+}
+
 // Render renders the braille chart as a string
 func (bc *BrailleChart) Render() string {
-	if len(bc.uploadData) == 0 && len(bc.downloadData) == 0 {
-		return bc.renderEmptyChart()
+	// Reuse the previous frame string when nothing visible has changed
+	if bc.refreshFrame() || !bc.frameStringValid {
+		bc.lastFrame = string(bc.frame)
+		bc.frameStringValid = true
 	}
+	return bc.lastFrame
+}
 
-	// Update scaling based on currently visible data before rendering
-	bc.updateMaxValue()
-
-	// Reset and prepare string builder
-	bc.builder.Reset()
-	bc.builder.Grow(bc.width * bc.height * 4) // Estimate capacity
+// AppendRender appends the rendered chart to dst and returns the extended buffer.
+// Once the internal buffers have grown to size, steady-state frames are rendered
+// without any heap allocations when the caller reuses dst.
+func (bc *BrailleChart) AppendRender(dst []byte) []byte {
+	bc.refreshFrame()
+	return append(dst, bc.frame...)
+}
 
-	// Ensure we have enough pre-allocated line builders
-	for len(bc.lines) < bc.height {
-		bc.lines = append(bc.lines, strings.Builder{})
+// refreshFrame re-renders the frame buffer if anything visible changed,
+// reporting whether it did
+func (bc *BrailleChart) refreshFrame() bool {
+	if bc.source != nil {
+		bc.syncSource()
+	} else if bc.leader != nil {
+		bc.syncView(bc.leader)
 	}
-
-	// Reset line builders
-	for i := 0; i < bc.height; i++ {
-		bc.lines[i].Reset()
-		bc.lines[i].Grow(bc.width * 4) // Estimate capacity for styled characters
+	if !bc.frameDirty && bc.frameValid {
+		return false
 	}
+	bc.frame = bc.appendFrame(bc.frame[:0])
+	bc.frameDirty = false
+	bc.frameValid = true
+	bc.frameStringValid = false
+	return true
+}
 
-	// Calculate chart dimensions
-	chartWidth := bc.width
-	chartHeight := bc.height
-
-	// Calculate the center line (split between upload and download)
-	centerLine := chartHeight / 2
-
+// appendFrame renders the chart unconditionally, appending it to dst
+func (bc *BrailleChart) appendFrame(dst []byte) []byte {
 	// Calculate data points per character
 	dataLen := len(bc.uploadData)
 	downloadLen := len(bc.downloadData)
@@ -137,11 +277,34 @@ func (bc *BrailleChart) Render() string {
 	}
 
 	if dataLen == 0 {
-		return bc.renderEmptyChart()
+		return bc.appendEmptyChart(dst)
 	}
 
+	// Update scaling based on currently visible data before rendering
+	bc.updateMaxValue()
+	bc.updateGrid()
+
+	// Collect references to each column's rendered glyphs; the glyphs themselves
+	// live in the column caches so no per-cell strings are built
+	bc.frameColumns = bc.frameColumns[:0]
+
+	// Calculate chart dimensions
+	chartWidth := bc.width
+	chartHeight := bc.height
+
+	// Calculate the center line (split between upload and download)
+	centerLine := chartHeight / 2
+
 	// Use different rendering approaches based on time scale
-	if bc.timeScale == TimeScale1Min {
+	if tier := bc.historyTier(); tier != nil {
+		// Multi-hour scales render pre-aggregated columns from tiered history
+		bc.loadHistoryColumns(tier)
+		for x := 0; x < chartWidth; x++ {
+			bc.renderColumn(bc.historyUpload[x], bc.historyDownload[x], centerLine)
+		}
+	} else if bc.smoothing {
+		bc.renderSmoothed(chartWidth, centerLine)
+	} else if bc.timeScale == TimeScale1Min {
 		// Original 1:1 rendering for 1-minute scale (no aggregation)
 		for x := 0; x < chartWidth; x++ {
 			// Calculate which data point this column represents (scrolling from right)
@@ -156,42 +319,74 @@ func (bc *BrailleChart) Render() string {
 				download = bc.downloadData[dataIndex]
 			}
 
+			// Mark interrupted intervals instead of drawing their (empty) sample
+			if dataIndex >= 0 && bc.hasGap(dataIndex, dataIndex+1) {
+				bc.frameColumns = append(bc.frameColumns, bc.gapColumn())
+				continue
+			}
+
 			// Render this column based on display mode
-			if bc.overlayMode {
-				bc.renderColumnOverlay(x, upload, download)
-			} else {
-				bc.renderColumn(x, upload, download, centerLine)
+			if dataIndex >= 0 {
+				bc.frameColumns = append(bc.frameColumns, bc.dataColumn(upload, download, dataIndex, dataIndex+1, centerLine))
+				continue
 			}
+			bc.renderColumn(upload, download, centerLine)
 		}
 	} else {
 		// Window-based aggregation for larger time scales
 		bc.renderWithTimeWindows(chartWidth, centerLine)
 	}
 
-	// Combine all lines into final output
-	for i := 0; i < bc.height; i++ {
-		if i > 0 {
-			bc.builder.WriteString("\n")
+	bc.highlightCursor()
+
+	// Emit the columns row by row
+	for y := 0; y < bc.height; y++ {
+		if y > 0 {
+			dst = append(dst, '\n')
+		}
+		if bc.hasLeftGutter() {
+			dst = bc.appendGutter(dst, y, bc.height, true)
+		}
+		label := bc.cornerLabel(y)
+		dst = appendCells(dst, label)
+		for _, column := range bc.frameColumns[len(label):] {
+			if y < len(column) && column[y] != " " {
+				dst = append(dst, column[y]...)
+			} else {
+				dst = append(dst, bc.gridCell(y)...)
+			}
+		}
+		if bc.hasRightGutter() {
+			dst = bc.appendGutter(dst, y, bc.height, false)
 		}
-		bc.builder.WriteString(bc.lines[i].String())
 	}
 
-	return bc.builder.String()
+	return dst
 }
 
-// renderEmptyChart renders an empty chart placeholder
-func (bc *BrailleChart) renderEmptyChart() string {
-	bc.builder.Reset()
-
+// appendEmptyChart renders an empty chart placeholder
+func (bc *BrailleChart) appendEmptyChart(dst []byte) []byte {
+	bc.frameColumns = bc.frameColumns[:0]
+	bc.gridLabels = bc.gridLabels[:0]
 	for y := 0; y < bc.height; y++ {
 		if y > 0 {
-			bc.builder.WriteString("\n")
+			dst = append(dst, '\n')
 		}
 		// Empty space - no center line
-		bc.builder.WriteString(strings.Repeat(" ", bc.width))
+		if bc.hasLeftGutter() {
+			dst = bc.appendGutter(dst, y, bc.height, true)
+		}
+		label := bc.cornerLabel(y)
+		dst = appendCells(dst, label)
+		for x := len(label); x < bc.width; x++ {
+			dst = append(dst, ' ')
+		}
+		if bc.hasRightGutter() {
+			dst = bc.appendGutter(dst, y, bc.height, false)
+		}
 	}
 
-	return bc.builder.String()
+	return dst
 }
 
 // renderWithTimeWindows renders the chart using fixed time windows for larger time scales
@@ -212,11 +407,7 @@ func (bc *BrailleChart) renderWithTimeWindows(chartWidth, centerLine int) {
 	if dataLen == 0 {
 		// No data, render empty columns
 		for x := 0; x < chartWidth; x++ {
-			if bc.overlayMode {
-				bc.renderColumnOverlay(x, 0, 0)
-			} else {
-				bc.renderColumn(x, 0, 0, centerLine)
-			}
+			bc.renderColumn(0, 0, centerLine)
 		}
 		return
 	}
@@ -240,20 +431,14 @@ func (bc *BrailleChart) renderWithTimeWindows(chartWidth, centerLine int) {
 		// Check if this window is beyond our data (negative index means no data)
 		if windowIndex < 0 || windowIndex >= totalWindows {
 			// No data for this column
-			if bc.overlayMode {
-				bc.renderColumnOverlay(x, 0, 0)
-			} else {
-				bc.renderColumn(x, 0, 0, centerLine)
-			}
+			bc.renderColumn(0, 0, centerLine)
 			continue
 		}
 
 		// Use cached column if available (for completed windows)
 		if cachedColumn, exists := bc.columnCache[windowIndex]; exists && windowIndex < totalCompleteWindows {
 			// Use cached rendering for stability
-			for y := 0; y < len(cachedColumn) && y < bc.height; y++ {
-				bc.lines[y].WriteString(cachedColumn[y])
-			}
+			bc.frameColumns = append(bc.frameColumns, cachedColumn)
 			continue
 		}
 
@@ -266,11 +451,13 @@ func (bc *BrailleChart) renderWithTimeWindows(chartWidth, centerLine int) {
 		
 		// Skip empty windows
 		if windowStartIndex >= windowEndIndex {
-			if bc.overlayMode {
-				bc.renderColumnOverlay(x, 0, 0)
-			} else {
-				bc.renderColumn(x, 0, 0, centerLine)
-			}
+			bc.renderColumn(0, 0, centerLine)
+			continue
+		}
+
+		// Mark windows containing an interrupted interval
+		if bc.hasGap(windowStartIndex, windowEndIndex) {
+			bc.frameColumns = append(bc.frameColumns, bc.gapColumn())
 			continue
 		}
 		
@@ -292,11 +479,7 @@ func (bc *BrailleChart) renderWithTimeWindows(chartWidth, centerLine int) {
 		}
 
 		// Render this column based on display mode
-		if bc.overlayMode {
-			bc.renderColumnOverlay(x, upload, download)
-		} else {
-			bc.renderColumn(x, upload, download, centerLine)
-		}
+		bc.frameColumns = append(bc.frameColumns, bc.dataColumn(upload, download, windowStartIndex, windowEndIndex, centerLine))
 	}
 }
 
@@ -325,86 +508,35 @@ func (bc *BrailleChart) updateColumnCache(totalCompleteWindows, windowSize, cent
 		}
 
 		// Render this window to cache
-		cachedColumn := bc.renderColumnToCache(upload, download, centerLine)
-		bc.columnCache[windowIndex] = cachedColumn
-	}
-	
-	bc.lastCompleteWindow = totalCompleteWindows - 1
-}
-
-// renderColumnToCache renders a column and returns the result as a slice of strings
-func (bc *BrailleChart) renderColumnToCache(upload, download uint64, centerLine int) []string {
-	// Create temporary builders for this column
-	tempLines := make([]strings.Builder, bc.height)
-	
-	if bc.overlayMode {
-		// Overlay mode rendering
-		fullHeight := bc.height * brailleDots
-		fullHeightFloat := float64(fullHeight)
-
-		// Apply scaling to the values
-		uploadScale := bc.scaleValue(upload, bc.maxValue)
-		downloadScale := bc.scaleValue(download, bc.maxValue)
-
-		uploadHeight := int(uploadScale * fullHeightFloat)
-		downloadHeight := int(downloadScale * fullHeightFloat)
-
-		// Clamp values
-		if uploadHeight > fullHeight {
-			uploadHeight = fullHeight
-		}
-		if downloadHeight > fullHeight {
-			downloadHeight = fullHeight
-		}
-
-		// Render each row in this column for overlay mode
-		for y := 0; y < bc.height; y++ {
-			char := bc.createBrailleCharForOverlay(y, uploadHeight, downloadHeight, fullHeight, uploadScale, downloadScale)
-			tempLines[y].WriteString(char)
-		}
-	} else {
-		// Split mode rendering
-		halfHeight := centerLine * brailleDots
-		halfHeightFloat := float64(halfHeight)
-
-		// Apply scaling to the values
-		uploadScale := bc.scaleValue(upload, bc.maxValue)
-		downloadScale := bc.scaleValue(download, bc.maxValue)
-
-		uploadHeight := int(uploadScale * halfHeightFloat)
-		downloadHeight := int(downloadScale * halfHeightFloat)
-
-		// Clamp values
-		if uploadHeight > halfHeight {
-			uploadHeight = halfHeight
-		}
-		if downloadHeight > halfHeight {
-			downloadHeight = halfHeight
-		}
-
-		// Render each row in this column for split mode
-		for y := 0; y < bc.height; y++ {
-			char := bc.createBrailleCharForLineSplit(y, uploadHeight, downloadHeight, halfHeight, uploadScale, downloadScale)
-			tempLines[y].WriteString(char)
+		if bc.hasGap(windowStartIndex, windowEndIndex) {
+			bc.columnCache[windowIndex] = bc.gapColumn()
+			continue
 		}
+		bc.columnCache[windowIndex] = bc.dataColumn(upload, download, windowStartIndex, windowEndIndex, centerLine)
 	}
 	
-	// Convert to strings
-	result := make([]string, bc.height)
-	for y := 0; y < bc.height; y++ {
-		result[y] = tempLines[y].String()
-	}
-	
-	return result
+	bc.lastCompleteWindow = totalCompleteWindows - 1
 }
 
 // SetTimeScale sets the time scale directly (for debugging)
 func (bc *BrailleChart) SetTimeScale(timeScale TimeScale) {
-	bc.timeScale = timeScale
+	if bc.timeScale != timeScale {
+		bc.timeScale = timeScale
+		bc.invalidateColumnCache()
+	}
 }
 
 // invalidateColumnCache clears all cached column data to force re-rendering
 func (bc *BrailleChart) invalidateColumnCache() {
 	bc.columnCache = make(map[int][]string)
 	bc.lastCompleteWindow = -1
+	bc.renderCache = make(map[columnKey][]string)
+	bc.labelCells = [2][]string{}
+	bc.gridCellStyled = ""
+	bc.frameDirty = true
+}
+
+// IsFrameDirty reports whether the next Render will differ from the previous one
+func (bc *BrailleChart) IsFrameDirty() bool {
+	return bc.frameDirty
 }
@@ -0,0 +1,86 @@
+// Package chart provides caching functionality for styled braille characters
+package chart
+
+import "container/list"
+
+// defaultStyleCacheSize bounds the number of styled glyphs kept per chart
+const defaultStyleCacheSize = 2048
+
+// styleKind identifies which gradient a glyph is styled with
+type styleKind uint8
+
+const (
+	styleUpload styleKind = iota
+	styleDownload
+	styleOverlap
+	styleBackground
+)
+
+// styleKey identifies a styled glyph by gradient, step and character
+type styleKey struct {
+	kind styleKind
+	step int
+	char rune
+}
+
+// styleEntry is a cached styled glyph
+type styleEntry struct {
+	key    styleKey
+	styled string
+}
+
+// styleCache is a bounded least-recently-used cache of styled glyphs
+type styleCache struct {
+	capacity int
+	entries  map[styleKey]*list.Element
+	order    *list.List // front = most recently used
+}
+
+// newStyleCache creates a style cache holding at most capacity glyphs
+func newStyleCache(capacity int) *styleCache {
+	if capacity <= 0 {
+		capacity = defaultStyleCacheSize
+	}
+	return &styleCache{
+		capacity: capacity,
+		entries:  make(map[styleKey]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// get returns a cached styled glyph and marks it as recently used
+func (c *styleCache) get(key styleKey) (string, bool) {
+	if elem, exists := c.entries[key]; exists {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*styleEntry).styled, true
+	}
+	return "", false
+}
+
+// put stores a styled glyph, evicting the least recently used entry when full
+func (c *styleCache) put(key styleKey, styled string) {
+	if elem, exists := c.entries[key]; exists {
+		elem.Value.(*styleEntry).styled = styled
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*styleEntry).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&styleEntry{key: key, styled: styled})
+}
+
+// len returns the number of cached glyphs
+func (c *styleCache) len() int {
+	return c.order.Len()
+}
+
+// clear removes all cached glyphs
+func (c *styleCache) clear() {
+	c.entries = make(map[styleKey]*list.Element, c.capacity)
+	c.order.Init()
+}
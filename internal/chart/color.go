@@ -0,0 +1,61 @@
+// Package chart provides terminal color depth selection
+package chart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muesli/termenv"
+)
+
+// Color depth names accepted by ParseColorProfile
+const (
+	ColorNameAuto      = "auto"
+	ColorNameTrueColor = "truecolor"
+	ColorName256       = "256"
+	ColorName16        = "16"
+	ColorNameNone      = "none"
+)
+
+// ParseColorProfile parses a color depth name. "auto" reports auto as true so
+// the caller keeps the profile detected from the terminal.
+func ParseColorProfile(name string) (profile termenv.Profile, auto bool, err error) {
+	switch strings.ToLower(name) {
+	case ColorNameAuto, "":
+		return termenv.TrueColor, true, nil
+	case ColorNameTrueColor, "24bit":
+		return termenv.TrueColor, false, nil
+	case ColorName256:
+		return termenv.ANSI256, false, nil
+	case ColorName16:
+		return termenv.ANSI, false, nil
+	case ColorNameNone:
+		return termenv.Ascii, false, nil
+	default:
+		return termenv.TrueColor, false, fmt.Errorf("unknown color depth %q (expected %s, %s, %s, %s or %s)",
+			name, ColorNameAuto, ColorNameTrueColor, ColorName256, ColorName16, ColorNameNone)
+	}
+}
+
+// Braille dots of a cell's left and right columns
+const (
+	leftColumnDots  = 0x01 | 0x02 | 0x04 | 0x40
+	rightColumnDots = 0x08 | 0x10 | 0x20 | 0x80
+)
+
+// SetMonochrome draws overlay mode for terminals without colors: where only
+// one direction reaches, upload fills the left dot column of each cell and
+// download the right one, so the two can be told apart. Where both reach,
+// cells stay solid.
+func (bc *BrailleChart) SetMonochrome(enabled bool) {
+	if bc.monochrome != enabled {
+		bc.monochrome = enabled
+		bc.InvalidateStyleCache()
+	}
+}
+
+// IsMonochrome reports whether overlay mode tells the directions apart by
+// dot column rather than color
+func (bc *BrailleChart) IsMonochrome() bool {
+	return bc.monochrome
+}
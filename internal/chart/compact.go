@@ -2,8 +2,6 @@ package chart
 
 import (
 	"strings"
-
-	"github.com/charmbracelet/lipgloss"
 )
 
 // RenderCompact renders a 2-line compact braille chart for terminal header use
@@ -13,8 +11,19 @@ func (bc *BrailleChart) RenderCompact(terminalWidth int) string {
 	return bc.RenderCompactWithSize(terminalWidth, 2)
 }
 
-// RenderCompactWithSize renders a compact braille chart with custom height
+// RenderCompactWithSize renders a compact braille chart with custom height,
+// filling terminalWidth columns with the axis gutters (if any) and as many
+// cells as fit beside them
 func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight int) string {
+	columns := (terminalWidth - bc.GutterWidth()) / bc.CellWidth()
+	if bc.gutter != GutterNone {
+		return bc.addGutters(strings.Split(bc.renderCompactColumns(columns, compactHeight), "\n"))
+	}
+	return bc.renderCompactColumns(columns, compactHeight)
+}
+
+// renderCompactColumns renders the compact chart's data columns
+func (bc *BrailleChart) renderCompactColumns(terminalWidth int, compactHeight int) string {
 	if len(bc.uploadData) == 0 && len(bc.downloadData) == 0 {
 		return bc.renderEmptyCompact(terminalWidth, compactHeight)
 	}
@@ -24,8 +33,8 @@ func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight i
 
 	chartWidth := terminalWidth // Use full terminal width
 
-	if chartWidth < 10 {
-		chartWidth = 10
+	if chartWidth < MinChartWidth {
+		chartWidth = MinChartWidth
 	}
 
 	// Prepare line builders based on height
@@ -45,12 +54,6 @@ func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight i
 		return bc.renderEmptyCompact(terminalWidth, compactHeight)
 	}
 
-	// Define colors (same as full mode)
-	uploadColor := lipgloss.Color("#EF4444")   // Red
-	downloadColor := lipgloss.Color("#10B981") // Green
-	overlapColor := lipgloss.Color("#EAB308")  // Yellow for overlap
-	bgColor := lipgloss.Color("#374151")       // Grey background
-
 	// Render each column (same logic as full chart)
 	for x := 0; x < chartWidth; x++ {
 		// Calculate which data point this column represents (scrolling from right)
@@ -74,7 +77,7 @@ func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight i
 		// In split mode: top half shows download, bottom half shows upload
 		// In overlay mode: all lines show both colors from bottom
 		if bc.overlayMode {
-			maxHeight := compactHeight * 4 // 4 dots per line
+			maxHeight := compactHeight * bc.cellDots()
 			uploadHeight := int(uploadScaled * float64(maxHeight))
 			downloadHeight := int(downloadScaled * float64(maxHeight))
 
@@ -86,11 +89,11 @@ func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight i
 			}
 
 			// Render column for overlay mode - all lines from bottom
-			bc.renderCompactColumnOverlayMultiLine(x, uploadHeight, downloadHeight, maxHeight, compactHeight, lines, uploadColor, downloadColor, overlapColor, bgColor)
+			bc.renderCompactColumnOverlayMultiLine(x, uploadHeight, downloadHeight, maxHeight, compactHeight, lines)
 		} else {
 			// Split mode: top half (compactHeight/2) for download, bottom half for upload
 			halfLines := compactHeight / 2
-			maxHeightPerHalf := halfLines * 4 // 4 dots per line
+			maxHeightPerHalf := halfLines * bc.cellDots()
 			
 			uploadHeight := int(uploadScaled * float64(maxHeightPerHalf))
 			downloadHeight := int(downloadScaled * float64(maxHeightPerHalf))
@@ -103,7 +106,7 @@ func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight i
 			}
 
 			// Split mode: download in top half, upload in bottom half
-			bc.renderCompactColumnSplitMultiLine(x, uploadHeight, downloadHeight, halfLines, lines, uploadColor, downloadColor, bgColor)
+			bc.renderCompactColumnSplitMultiLine(x, uploadHeight, downloadHeight, halfLines, lines)
 		}
 	}
 
@@ -119,19 +122,15 @@ func (bc *BrailleChart) RenderCompactWithSize(terminalWidth int, compactHeight i
 }
 
 // renderCompactColumnOverlayMultiLine renders a column in overlay mode with multiple lines
-func (bc *BrailleChart) renderCompactColumnOverlayMultiLine(x, uploadHeight, downloadHeight, maxHeight, compactHeight int, lines []strings.Builder, uploadColor, downloadColor, overlapColor, bgColor lipgloss.Color) {
-	uploadStyle := lipgloss.NewStyle().Foreground(uploadColor)
-	downloadStyle := lipgloss.NewStyle().Foreground(downloadColor)
-	overlapStyle := lipgloss.NewStyle().Foreground(overlapColor)
-	bgStyle := lipgloss.NewStyle().Foreground(bgColor)
-	
+// Colors come from the chart's shared ANSI table, using each gradient's middle step.
+func (bc *BrailleChart) renderCompactColumnOverlayMultiLine(x, uploadHeight, downloadHeight, maxHeight, compactHeight int, lines []strings.Builder) {
 	// Render from bottom to top (line index compactHeight-1 is bottom)
 	for lineIdx := 0; lineIdx < compactHeight; lineIdx++ {
 		// Calculate which dots this line represents (from bottom)
 		// Bottom line (lineIdx = compactHeight-1) has dots 0-3
 		// Next line up has dots 4-7, etc.
 		lineFromBottom := compactHeight - 1 - lineIdx
-		dotStart := lineFromBottom * 4
+		dotStart := lineFromBottom * bc.cellDots()
 		
 		// Check if upload/download reach this line
 		uploadInLine := uploadHeight > dotStart
@@ -139,26 +138,22 @@ func (bc *BrailleChart) renderCompactColumnOverlayMultiLine(x, uploadHeight, dow
 		
 		if !uploadInLine && !downloadInLine {
 			// Empty line
-			lines[lineIdx].WriteString(bgStyle.Render("⠀"))
+			lines[lineIdx].WriteString(bc.getSolidStyledChar(brailleBase, styleBackground))
 			continue
 		}
 		
-		// Calculate how many dots to fill in this line (0-4)
+		// Calculate how many dots to fill in this line (up to cellDots)
 		uploadDotsInLine := 0
 		downloadDotsInLine := 0
 		
 		if uploadInLine {
 			uploadDotsInLine = uploadHeight - dotStart
-			if uploadDotsInLine > 4 {
-				uploadDotsInLine = 4
-			}
+			uploadDotsInLine = min(uploadDotsInLine, bc.cellDots())
 		}
 		
 		if downloadInLine {
 			downloadDotsInLine = downloadHeight - dotStart
-			if downloadDotsInLine > 4 {
-				downloadDotsInLine = 4
-			}
+			downloadDotsInLine = min(downloadDotsInLine, bc.cellDots())
 		}
 		
 		// Use the max for the character
@@ -167,28 +162,24 @@ func (bc *BrailleChart) renderCompactColumnOverlayMultiLine(x, uploadHeight, dow
 			dotsToFill = downloadDotsInLine
 		}
 		
-		char := bc.getBrailleChar(dotsToFill, 0, 4)
+		char := bc.compactChar(dotsToFill, false)
 		
 		// Determine color based on overlap
-		var style lipgloss.Style
+		var kind styleKind
 		if uploadInLine && downloadInLine {
-			style = overlapStyle // Both present = yellow
+			kind = styleOverlap // Both present = yellow
 		} else if uploadInLine {
-			style = uploadStyle // Upload only = red
+			kind = styleUpload // Upload only = red
 		} else {
-			style = downloadStyle // Download only = green
+			kind = styleDownload // Download only = green
 		}
 		
-		lines[lineIdx].WriteString(style.Render(string(char)))
+		lines[lineIdx].WriteString(bc.getSolidStyledChar(char, kind))
 	}
 }
 
 // renderCompactColumnSplitMultiLine renders a column in split mode with multiple lines
-func (bc *BrailleChart) renderCompactColumnSplitMultiLine(x, uploadHeight, downloadHeight, halfLines int, lines []strings.Builder, uploadColor, downloadColor, bgColor lipgloss.Color) {
-	uploadStyle := lipgloss.NewStyle().Foreground(uploadColor)
-	downloadStyle := lipgloss.NewStyle().Foreground(downloadColor)
-	bgStyle := lipgloss.NewStyle().Foreground(bgColor)
-	
+func (bc *BrailleChart) renderCompactColumnSplitMultiLine(x, uploadHeight, downloadHeight, halfLines int, lines []strings.Builder) {
 	totalLines := halfLines * 2
 	
 	// Top half: download (green) - grows UPWARD from center (line halfLines-1) toward top (line 0)
@@ -196,21 +187,19 @@ func (bc *BrailleChart) renderCompactColumnSplitMultiLine(x, uploadHeight, downl
 		// Line halfLines-1 is at the center (axis), line 0 is at the top
 		// Calculate distance from center axis
 		distanceFromCenter := (halfLines - 1) - lineIdx
-		dotStart := distanceFromCenter * 4
+		dotStart := distanceFromCenter * bc.cellDots()
 		
 		// Check if download reaches this line (growing away from center)
 		if downloadHeight > dotStart {
-			// Calculate how many dots to fill (0-4)
+			// Calculate how many dots to fill (up to cellDots)
 			dotsInLine := downloadHeight - dotStart
-			if dotsInLine > 4 {
-				dotsInLine = 4
-			}
+			dotsInLine = min(dotsInLine, bc.cellDots())
 			
 			// Use normal braille (fills from bottom up) since we're growing upward from center
-			char := bc.getBrailleChar(dotsInLine, 0, 4)
-			lines[lineIdx].WriteString(downloadStyle.Render(string(char)))
+			char := bc.compactChar(dotsInLine, false)
+			lines[lineIdx].WriteString(bc.getSolidStyledChar(char, styleDownload))
 		} else {
-			lines[lineIdx].WriteString(bgStyle.Render("⠀"))
+			lines[lineIdx].WriteString(bc.getSolidStyledChar(brailleBase, styleBackground))
 		}
 	}
 	
@@ -219,25 +208,38 @@ func (bc *BrailleChart) renderCompactColumnSplitMultiLine(x, uploadHeight, downl
 		// Line halfLines is at the center (axis), line totalLines-1 is at the bottom
 		// Calculate distance from center axis
 		distanceFromCenter := lineIdx - halfLines
-		dotStart := distanceFromCenter * 4
+		dotStart := distanceFromCenter * bc.cellDots()
 		
 		// Check if upload reaches this line (growing away from center)
 		if uploadHeight > dotStart {
-			// Calculate how many dots to fill (0-4)
+			// Calculate how many dots to fill (up to cellDots)
 			dotsInLine := uploadHeight - dotStart
-			if dotsInLine > 4 {
-				dotsInLine = 4
-			}
+			dotsInLine = min(dotsInLine, bc.cellDots())
 			
 			// Use inverted braille (fills from top down) since we're growing downward from center
-			char := bc.getBrailleCharInverted(dotsInLine, 0, 4)
-			lines[lineIdx].WriteString(uploadStyle.Render(string(char)))
+			char := bc.compactChar(dotsInLine, true)
+			lines[lineIdx].WriteString(bc.getSolidStyledChar(char, styleUpload))
 		} else {
-			lines[lineIdx].WriteString(bgStyle.Render("⠀"))
+			lines[lineIdx].WriteString(bc.getSolidStyledChar(brailleBase, styleBackground))
 		}
 	}
 }
 
+// compactChar returns the character for a compact cell with filled of its
+// cellDots rows set, counted from the bottom or from the top
+func (bc *BrailleChart) compactChar(filled int, fromTop bool) rune {
+	switch {
+	case bc.glyphSet == GlyphBlock && fromTop:
+		return upperBlocks[filled]
+	case bc.glyphSet == GlyphBlock:
+		return lowerBlocks[filled]
+	case fromTop:
+		return bc.getBrailleCharInverted(filled, 0, brailleDots)
+	default:
+		return bc.getBrailleChar(filled, 0, brailleDots)
+	}
+}
+
 // getBrailleChar returns a braille character for a given height within a range
 // height: 0-8, startDot: starting position, endDot: ending position
 // Fills from BOTTOM upward
@@ -299,15 +301,16 @@ func (bc *BrailleChart) getBrailleCharInverted(height, startDot, endDot int) run
 
 // renderEmptyCompact renders an empty compact chart
 func (bc *BrailleChart) renderEmptyCompact(terminalWidth int, compactHeight int) string {
-	bgColor := lipgloss.Color("#374151")
-	bgStyle := lipgloss.NewStyle().Foreground(bgColor)
-	
 	chartWidth := terminalWidth // Use full width
-	if chartWidth < 10 {
-		chartWidth = 10
+	if chartWidth < MinChartWidth {
+		chartWidth = MinChartWidth
 	}
 
-	emptyLine := bgStyle.Render(strings.Repeat("⠀", chartWidth))
+	emptyLine := strings.Repeat("⠀", chartWidth)
+	if !bc.plainOutput {
+		background := bc.ansi.background[0]
+		emptyLine = background.prefix + emptyLine + background.suffix
+	}
 	lines := make([]string, compactHeight)
 	for i := 0; i < compactHeight; i++ {
 		lines[i] = emptyLine
@@ -0,0 +1,105 @@
+// Package chart provides the keyboard column cursor for braille charts
+package chart
+
+// cursorGlyph draws the cursor through the empty cells of its column
+const cursorGlyph = '│'
+
+// Reverse video around the cursor column's data cells
+const (
+	reverseOn  = "\x1b[7m"
+	reverseOff = "\x1b[27m"
+)
+
+// SetCursor highlights the column offset columns left of the newest one; a
+// negative offset hides the cursor. The offset is clamped to the chart.
+func (bc *BrailleChart) SetCursor(offset int) {
+	offset = min(offset, bc.width-1)
+	if offset != bc.cursor {
+		bc.cursor = offset
+		bc.frameDirty = true
+	}
+}
+
+// Cursor returns the cursor's offset from the newest column, or -1
+func (bc *BrailleChart) Cursor() int {
+	return bc.cursor
+}
+
+// CursorColumn returns the rates drawn in the cursor column (the peak of the
+// samples it covers) and how many samples before the newest it ends. ok is
+// false without a cursor or when the column has no data yet.
+func (bc *BrailleChart) CursorColumn() (upload, download uint64, samplesAgo int, ok bool) {
+	dataLen := bc.GetDataLength()
+	if bc.cursor < 0 || dataLen == 0 {
+		return 0, 0, 0, false
+	}
+	windowSize := max(bc.GetTimeScaleSeconds()/60, 1)
+
+	if tier := bc.historyTier(); tier != nil {
+		bc.loadHistoryColumns(tier)
+		x := bc.width - 1 - bc.cursor
+		if bc.cursor*windowSize >= tier.total*tier.resolution {
+			return 0, 0, 0, false
+		}
+		return bc.historyUpload[x], bc.historyDownload[x], bc.cursor * windowSize, true
+	}
+
+	if bc.timeScale == TimeScale1Min {
+		windowSize = 1
+	}
+	totalWindows := (dataLen + windowSize - 1) / windowSize
+	window := totalWindows - 1 - bc.cursor
+	if window < 0 {
+		return 0, 0, 0, false
+	}
+	start := window * windowSize
+	end := min(start+windowSize, dataLen)
+	for i := start; i < end; i++ {
+		if i < len(bc.uploadData) {
+			upload = max(upload, bc.uploadData[i])
+		}
+		if i < len(bc.downloadData) {
+			download = max(download, bc.downloadData[i])
+		}
+	}
+	return upload, download, dataLen - end, true
+}
+
+// followCursor keeps the cursor on the same data as a new sample scrolls
+// the chart, hiding it once its column scrolls off the left edge. Multi-hour
+// scales scroll too slowly to need it.
+func (bc *BrailleChart) followCursor() {
+	if bc.cursor < 0 || bc.historyTier() != nil {
+		return
+	}
+	windowSize := max(bc.GetTimeScaleSeconds()/60, 1)
+	if bc.timeScale == TimeScale1Min {
+		windowSize = 1
+	}
+	// A new column starts with the first sample of each window
+	if (bc.GetDataLength()-1)%windowSize != 0 {
+		return
+	}
+	if bc.cursor++; bc.cursor >= bc.width {
+		bc.cursor = -1
+	}
+}
+
+// highlightCursor replaces the cursor's column in the frame with a
+// highlighted copy: data cells in reverse video, a line through the rest
+func (bc *BrailleChart) highlightCursor() {
+	x := len(bc.frameColumns) - 1 - bc.cursor
+	if bc.cursor < 0 || x < 0 {
+		return
+	}
+	column := append([]string(nil), bc.frameColumns[x]...)
+	for y, cell := range column {
+		switch {
+		case cell == " ":
+			column[y] = bc.getSolidStyledChar(cursorGlyph, styleOverlap)
+		case !bc.plainOutput:
+			column[y] = reverseOn + cell + reverseOff
+		}
+	}
+	bc.frameColumns[x] = column
+}
@@ -1,14 +1,32 @@
 // Package chart provides data management functionality for braille charts
 package chart
 
+import "time"
+
 // AddDataPoint adds a new data point to the chart
 func (bc *BrailleChart) AddDataPoint(upload, download uint64) {
+	bc.lastSampleTime = bc.clock()
+
+	// Track runs of identical samples; once a run covers the whole visible
+	// window, scrolling in another identical sample leaves the frame unchanged
+	if n := len(bc.uploadData); n > 0 && bc.uploadData[n-1] == upload && bc.downloadData[n-1] == download {
+		bc.steadyCount++
+	} else {
+		bc.steadyCount = 0
+	}
+	if bc.steadyCount < bc.visibleSampleCount() || len(bc.uploadData) < bc.visibleSampleCount() || bc.HasGhost() {
+		bc.frameDirty = true
+	}
+
 	// Update current max efficiently
 	bc.updateCurrentMax(upload, download)
 
 	// Add new data points
 	bc.uploadData = append(bc.uploadData, upload)
 	bc.downloadData = append(bc.downloadData, download)
+	bc.history.add(upload, download)
+	bc.sampleTotal++
+	bc.followCursor()
 
 	// Manage data size
 	bc.trimDataIfNeeded()
@@ -17,6 +35,82 @@ func (bc *BrailleChart) AddDataPoint(upload, download uint64) {
 	bc.updateMaxValue()
 }
 
+// AddGap records an interrupted sample interval (e.g. a suspend/resume or clock
+// jump). It occupies one empty sample and is drawn as a dimmed marker column.
+func (bc *BrailleChart) AddGap() {
+	bc.gaps = append(bc.gaps, bc.sampleTotal)
+	bc.AddDataPoint(0, 0)
+	// The marker scrolls like changing data until it leaves the window
+	bc.steadyCount = 0
+	bc.frameDirty = true
+}
+
+// AddMarker flags the most recent sample as the moment an event happened
+// (e.g. a default route change); its column is drawn with a marker on top
+func (bc *BrailleChart) AddMarker() {
+	if bc.GetDataLength() == 0 {
+		return
+	}
+	bc.markers = append(bc.markers, bc.sampleTotal-1)
+	bc.steadyCount = 0
+	bc.frameDirty = true
+	// Cached window columns don't carry the marker yet
+	bc.columnCache = make(map[int][]string)
+	bc.lastCompleteWindow = -1
+}
+
+// hasGap reports whether any sample in data indices [start, end) is a gap
+func (bc *BrailleChart) hasGap(start, end int) bool {
+	return bc.hasEvent(bc.gaps, start, end)
+}
+
+// hasMarker reports whether any sample in data indices [start, end) is marked
+func (bc *BrailleChart) hasMarker(start, end int) bool {
+	return bc.hasEvent(bc.markers, start, end)
+}
+
+// hasEvent reports whether any absolute sample index in events falls within
+// data indices [start, end)
+func (bc *BrailleChart) hasEvent(events []int, start, end int) bool {
+	if len(events) == 0 {
+		return false
+	}
+	offset := bc.sampleTotal - bc.GetDataLength()
+	for _, event := range events {
+		if index := event - offset; index >= start && index < end {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneEvents forgets gaps and markers that have scrolled out of the stored data
+func (bc *BrailleChart) pruneEvents() {
+	oldest := bc.sampleTotal - bc.GetDataLength()
+	bc.gaps = pruneBefore(bc.gaps, oldest)
+	bc.markers = pruneBefore(bc.markers, oldest)
+}
+
+// pruneBefore removes indices older than oldest in place
+func pruneBefore(events []int, oldest int) []int {
+	kept := events[:0]
+	for _, event := range events {
+		if event >= oldest {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// visibleSampleCount returns how many raw samples the visible chart covers
+func (bc *BrailleChart) visibleSampleCount() int {
+	windowSize := bc.GetTimeScaleSeconds() / 60
+	if windowSize < 1 {
+		windowSize = 1
+	}
+	return bc.width * windowSize
+}
+
 // updateCurrentMax efficiently tracks the current maximum value
 func (bc *BrailleChart) updateCurrentMax(upload, download uint64) {
 	if upload > bc.currentMax {
@@ -29,9 +123,12 @@ func (bc *BrailleChart) updateCurrentMax(upload, download uint64) {
 
 // trimDataIfNeeded removes old data points when exceeding capacity
 func (bc *BrailleChart) trimDataIfNeeded() {
+	// Shift in place rather than reslicing so the backing array keeps its
+	// capacity and steady-state appends never reallocate
 	if len(bc.uploadData) > bc.maxPoints {
 		removedUpload := bc.uploadData[0]
-		bc.uploadData = bc.uploadData[1:]
+		copy(bc.uploadData, bc.uploadData[1:])
+		bc.uploadData = bc.uploadData[:len(bc.uploadData)-1]
 
 		// If we removed the max value, recalculate
 		if removedUpload == bc.currentMax {
@@ -41,13 +138,18 @@ func (bc *BrailleChart) trimDataIfNeeded() {
 
 	if len(bc.downloadData) > bc.maxPoints {
 		removedDownload := bc.downloadData[0]
-		bc.downloadData = bc.downloadData[1:]
+		copy(bc.downloadData, bc.downloadData[1:])
+		bc.downloadData = bc.downloadData[:len(bc.downloadData)-1]
 
 		// If we removed the max value, recalculate
 		if removedDownload == bc.currentMax {
 			bc.recalculateMax()
 		}
 	}
+
+	if len(bc.gaps) > 0 || len(bc.markers) > 0 {
+		bc.pruneEvents()
+	}
 }
 
 // recalculateMax recalculates the maximum value after removing data
@@ -67,6 +169,10 @@ func (bc *BrailleChart) recalculateMax() {
 
 // updateMaxValue updates the chart's maximum value for scaling based on visible data
 func (bc *BrailleChart) updateMaxValue() {
+	if bc.fixedMax > 0 {
+		bc.maxValue = bc.fixedMax
+		return
+	}
 	visibleMax := bc.getVisibleDataMax()
 	
 	// Ensure minimum scale
@@ -119,6 +225,20 @@ func (bc *BrailleChart) getVisibleDataMax() uint64 {
 		return 0
 	}
 
+	// Multi-hour scales use the pre-aggregated history columns
+	if tier := bc.historyTier(); tier != nil {
+		bc.loadHistoryColumns(tier)
+		for x := range bc.historyUpload {
+			if bc.historyUpload[x] > maxVal {
+				maxVal = bc.historyUpload[x]
+			}
+			if bc.historyDownload[x] > maxVal {
+				maxVal = bc.historyDownload[x]
+			}
+		}
+		return maxVal
+	}
+
 	// For time scale aggregation, calculate max based on window aggregates
 	if bc.timeScale != TimeScale1Min {
 		// Calculate window size
@@ -168,6 +288,10 @@ func (bc *BrailleChart) getVisibleDataMax() uint64 {
 				}
 			}
 		}
+
+		// Keep the ghost on the same scale
+		ghostUpload, ghostDownload := bc.ghostMax(firstVisibleWindow*windowSize, dataLen)
+		maxVal = max(maxVal, ghostUpload, ghostDownload)
 	} else {
 		// For 1-minute scale, use simple approach (rightmost points)
 		startIndex := 0
@@ -188,6 +312,8 @@ func (bc *BrailleChart) getVisibleDataMax() uint64 {
 				maxVal = bc.downloadData[i]
 			}
 		}
+		ghostUpload, ghostDownload := bc.ghostMax(startIndex, dataLen)
+		maxVal = max(maxVal, ghostUpload, ghostDownload)
 	}
 
 	return maxVal
@@ -197,8 +323,16 @@ func (bc *BrailleChart) getVisibleDataMax() uint64 {
 func (bc *BrailleChart) Reset() {
 	bc.uploadData = bc.uploadData[:0]
 	bc.downloadData = bc.downloadData[:0]
-	bc.maxValue = 1024
+	bc.maxValue = max(bc.fixedMax, 1024)
 	bc.currentMax = 0
+	bc.lastSampleTime = time.Time{}
+	bc.steadyCount = 0
+	bc.sampleTotal = 0
+	bc.gaps = bc.gaps[:0]
+	bc.markers = bc.markers[:0]
+	bc.cursor = -1
+	bc.history.reset()
+	bc.invalidateColumnCache()
 }
 
 // SetMaxPoints updates the maximum number of data points to maintain
@@ -223,6 +357,8 @@ func (bc *BrailleChart) SetMaxPoints(maxPoints int) {
 		}
 		// Recalculate max value after trimming
 		bc.recalculateMax()
+		bc.pruneEvents()
+		bc.frameDirty = true
 	}
 
 	// Update the capacity of the pre-allocated slices if needed
@@ -242,6 +378,11 @@ func (bc *BrailleChart) GetMaxValue() uint64 {
 	return bc.maxValue
 }
 
+// GetMaxPoints returns the maximum number of data points maintained
+func (bc *BrailleChart) GetMaxPoints() int {
+	return bc.maxPoints
+}
+
 // GetDataLength returns the number of data points currently stored
 func (bc *BrailleChart) GetDataLength() int {
 	uploadLen := len(bc.uploadData)
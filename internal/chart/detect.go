@@ -0,0 +1,69 @@
+// Package chart provides terminal glyph capability detection
+package chart
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Glyph set names accepted by ParseGlyphSet
+const (
+	GlyphNameAuto    = "auto"
+	GlyphNameBraille = "braille"
+	GlyphNameBlock   = "block"
+	GlyphNameASCII   = "ascii"
+)
+
+// ParseGlyphSet parses a glyph set name. "auto" reports auto as true so the
+// caller can run DetectGlyphSet.
+func ParseGlyphSet(name string) (set GlyphSet, auto bool, err error) {
+	switch strings.ToLower(name) {
+	case GlyphNameAuto, "":
+		return GlyphBraille, true, nil
+	case GlyphNameBraille:
+		return GlyphBraille, false, nil
+	case GlyphNameBlock, "blocks":
+		return GlyphBlock, false, nil
+	case GlyphNameASCII:
+		return GlyphASCII, false, nil
+	default:
+		return GlyphBraille, false, fmt.Errorf("unknown glyph set %q (expected %s, %s, %s or %s)",
+			name, GlyphNameAuto, GlyphNameBraille, GlyphNameBlock, GlyphNameASCII)
+	}
+}
+
+// DetectGlyphSet guesses which glyphs the terminal renders correctly from its
+// environment, so fonts without braille show blocks instead of rows of tofu.
+//
+//   - an explicitly non-UTF-8 locale, TERM=dumb or the Linux console get ASCII
+//   - the legacy Windows console (no Windows Terminal session) gets blocks,
+//     since its default fonts lack braille
+//   - everything else gets braille
+func DetectGlyphSet(getenv func(string) string, goos string) GlyphSet {
+	switch getenv("TERM") {
+	case "dumb", "linux":
+		return GlyphASCII
+	}
+
+	// The first set variable of LC_ALL, LC_CTYPE and LANG decides the charset
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := getenv(name); locale != "" {
+			if !isUTF8Locale(locale) {
+				return GlyphASCII
+			}
+			break
+		}
+	}
+
+	if goos == "windows" && getenv("WT_SESSION") == "" && getenv("TERM_PROGRAM") == "" {
+		return GlyphBlock
+	}
+	return GlyphBraille
+}
+
+// isUTF8Locale reports whether a locale name selects UTF-8, e.g. "en_US.UTF-8".
+// "C.UTF-8" counts; plain "C"/"POSIX" do not.
+func isUTF8Locale(locale string) bool {
+	locale = strings.ToLower(locale)
+	return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+}
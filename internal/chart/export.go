@@ -0,0 +1,28 @@
+// Package chart provides exports of the chart's samples
+package chart
+
+import "time"
+
+// ExportData copies the stored samples, oldest first. The chart doesn't keep
+// a time per sample, so they are stamped back from the newest one every
+// interval, the time between samples.
+func (bc *BrailleChart) ExportData(interval time.Duration) []DataPoint {
+	n := bc.GetDataLength()
+	points := make([]DataPoint, n)
+	for i := range points {
+		points[i].Time = bc.lastSampleTime.Add(-time.Duration(n-1-i) * interval)
+		if i < len(bc.uploadData) {
+			points[i].Upload = bc.uploadData[i]
+		}
+		if i < len(bc.downloadData) {
+			points[i].Download = bc.downloadData[i]
+		}
+	}
+	first := bc.sampleTotal - n
+	for _, gap := range bc.gaps {
+		if index := gap - first; index >= 0 && index < n {
+			points[index].Gap = true
+		}
+	}
+	return points
+}
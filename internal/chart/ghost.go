@@ -0,0 +1,117 @@
+// Package chart provides ghost series rendering for braille charts
+package chart
+
+// SetGhost draws a previous session behind the live data as a dimmed
+// series, aligned by elapsed time: ghost sample i lines up with the i-th
+// sample added since the chart was created or last reset. Passing nil
+// removes it. Multi-hour time scales and plain output don't draw the ghost.
+func (bc *BrailleChart) SetGhost(upload, download []uint64) {
+	bc.ghostUpload = upload
+	bc.ghostDownload = download
+	bc.invalidateColumnCache()
+}
+
+// HasGhost reports whether a ghost series is set
+func (bc *BrailleChart) HasGhost() bool {
+	return len(bc.ghostUpload) > 0 || len(bc.ghostDownload) > 0
+}
+
+// ghostMax returns the ghost's peak rates over data indices [start, end)
+func (bc *BrailleChart) ghostMax(start, end int) (upload, download uint64) {
+	if !bc.HasGhost() || bc.plainOutput {
+		return 0, 0
+	}
+	offset := bc.sampleTotal - bc.GetDataLength()
+	for i := max(start+offset, 0); i < end+offset; i++ {
+		if i < len(bc.ghostUpload) {
+			upload = max(upload, bc.ghostUpload[i])
+		}
+		if i < len(bc.ghostDownload) {
+			download = max(download, bc.ghostDownload[i])
+		}
+	}
+	return upload, download
+}
+
+// dataColumn returns the glyphs of the data column covering data indices
+// [start, end), with its event marker and the ghost behind it
+func (bc *BrailleChart) dataColumn(upload, download uint64, start, end, centerLine int) []string {
+	marked := bc.hasMarker(start, end)
+	ghostUpload, ghostDownload := bc.ghostMax(start, end)
+	if ghostUpload == 0 && ghostDownload == 0 {
+		if marked {
+			return bc.markedColumn(upload, download, centerLine)
+		}
+		return bc.renderedColumn(upload, download, centerLine)
+	}
+
+	uploadHeight, downloadHeight, span := bc.columnHeights(upload, download, centerLine)
+	ghostUploadHeight, ghostDownloadHeight, _ := bc.columnHeights(ghostUpload, ghostDownload, centerLine)
+	key := columnKey{
+		marker:              marked,
+		overlay:             bc.overlayMode,
+		height:              bc.height,
+		uploadHeight:        uploadHeight,
+		downloadHeight:      downloadHeight,
+		ghostUploadHeight:   ghostUploadHeight,
+		ghostDownloadHeight: ghostDownloadHeight,
+	}
+	if column, exists := bc.renderCache[key]; exists {
+		return column
+	}
+
+	var live []string
+	if marked {
+		live = bc.markedColumn(upload, download, centerLine)
+	} else {
+		live = bc.renderedColumn(upload, download, centerLine)
+	}
+	// The live data stays in front; the ghost fills the cells it leaves empty
+	column := append([]string(nil), live...)
+	// The ghost is drawn in dot rows whatever the glyph set
+	scale := bc.cellDots() / brailleDots
+	for y := range column {
+		if column[y] != " " {
+			continue
+		}
+		if char := bc.ghostCell(y, ghostUploadHeight/scale, ghostDownloadHeight/scale, span/scale); char != 0 {
+			column[y] = bc.getSolidStyledChar(char, styleBackground)
+		}
+	}
+
+	if len(bc.renderCache) >= maxRenderCacheEntries {
+		bc.renderCache = make(map[columnKey][]string)
+	}
+	bc.renderCache[key] = column
+	return column
+}
+
+// ghostCell returns the braille character for one row of a ghost column, or
+// 0 if the ghost doesn't reach it. span is the number of dots available to
+// each series, as returned by columnHeights.
+func (bc *BrailleChart) ghostCell(line, uploadHeight, downloadHeight, span int) rune {
+	var dots int
+	lineTop := line * brailleDots
+	for dotRow := 0; dotRow < brailleDots; dotRow++ {
+		position := lineTop + dotRow
+		var filled bool
+		switch {
+		case bc.overlayMode:
+			// Both series fill from the bottom
+			filled = span-position <= max(uploadHeight, downloadHeight)
+		case position < span:
+			// Download above the axis
+			filled = span-position <= downloadHeight
+		default:
+			// Upload below the axis
+			filled = position-span < uploadHeight
+		}
+		if filled {
+			dots |= dotPatterns[dotRow]
+		}
+	}
+	if dots == 0 {
+		return 0
+	}
+	return rune(brailleBase + dots)
+}
@@ -0,0 +1,127 @@
+// Package chart provides horizontal gridlines for braille charts
+package chart
+
+import "math"
+
+// gridGlyph draws a gridline through empty cells
+const gridGlyph = '┈'
+
+// SetGridlines draws faint horizontal lines at round scale values (decades
+// on the log scale) through the empty cells behind the data, and labels
+// them in the axis gutter
+func (bc *BrailleChart) SetGridlines(enabled bool) {
+	if bc.gridlines != enabled {
+		bc.gridlines = enabled
+		bc.frameDirty = true
+	}
+}
+
+// HasGridlines reports whether gridlines are drawn
+func (bc *BrailleChart) HasGridlines() bool {
+	return bc.gridlines
+}
+
+// gridValues returns the round values below the scale maximum that get a
+// gridline: powers of ten of each unit (1K, 10K, 100K, 1M, …) on the log
+// scale, otherwise about three evenly spaced 1, 2 or 5 steps. Byte rates
+// step in binary units, or in decimal ones with decimalUnits or as bit rates
+// with bitUnits; values with their own label format step in decimal ones.
+func (bc *BrailleChart) gridValues() []uint64 {
+	// Steps are rounded in the labelled unit, scale times the byte value
+	base, scale := uint64(1024), uint64(1)
+	switch {
+	case bc.labelFormat != nil:
+		base = 1000
+	case bc.bitUnits:
+		base, scale = 1000, 8
+	case bc.decimalUnits:
+		base = 1000
+	}
+	maxValue := bc.maxValue * scale
+	var values []uint64
+	if bc.scalingMode == ScalingLogarithmic {
+		for unit := uint64(1); unit <= maxValue && unit <= 1<<50; unit *= base {
+			for _, decade := range []uint64{1, 10, 100} {
+				if value := unit * decade; value < maxValue && float64(value/scale) > minLogValue {
+					values = append(values, value/scale)
+				}
+			}
+		}
+		return values
+	}
+	unit := uint64(1)
+	for unit*base <= maxValue && unit < 1<<50 {
+		unit *= base
+	}
+	step := niceStep(float64(maxValue) / float64(unit) / 3)
+	for i := 1.0; ; i++ {
+		value := uint64(i * step * float64(unit))
+		if value == 0 || value >= maxValue {
+			return values
+		}
+		values = append(values, value/scale)
+	}
+}
+
+// niceStep rounds a step up to 1, 2 or 5 times a power of ten
+func niceStep(step float64) float64 {
+	if step <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, nice := range []float64{1, 2, 5} {
+		if step <= nice*magnitude {
+			return nice * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// updateGrid works out which rows get a gridline for the current scale and
+// size. A row already labelled with the maximum or zero, or taken by a
+// smaller value, is skipped.
+func (bc *BrailleChart) updateGrid() {
+	bc.gridLabels = bc.gridLabels[:0]
+	if !bc.gridlines || bc.height < 3 {
+		return
+	}
+	for range bc.height {
+		bc.gridLabels = append(bc.gridLabels, 0)
+	}
+	mark := func(row int, value uint64) {
+		if row > 0 && row < bc.height-1 && bc.gridLabels[row] == 0 {
+			bc.gridLabels[row] = value
+		}
+	}
+	centerLine := bc.height / 2
+	for _, value := range bc.gridValues() {
+		uploadHeight, _, span := bc.columnHeights(value, 0, centerLine)
+		if uploadHeight == 0 {
+			continue
+		}
+		if bc.overlayMode {
+			// The top dot of a bar reaching the value
+			mark((span-uploadHeight)/bc.cellDots(), value)
+			continue
+		}
+		// Download grows up from the axis and upload down from it
+		mark((span-uploadHeight)/bc.cellDots(), value)
+		mark((span+uploadHeight-1)/bc.cellDots(), value)
+	}
+}
+
+// gridCell returns the cell drawn in row y of an empty column: a dimmed
+// gridline on gridline rows, otherwise a space
+func (bc *BrailleChart) gridCell(y int) string {
+	if y >= len(bc.gridLabels) || bc.gridLabels[y] == 0 {
+		return " "
+	}
+	if bc.gridCellStyled == "" {
+		bc.gridCellStyled = bc.glyphString(gridGlyph)
+		if !bc.plainOutput {
+			pair := bc.ansi.background[0]
+			bc.gridCellStyled = pair.prefix + bc.gridCellStyled + pair.suffix
+		}
+	}
+	return bc.gridCellStyled
+}
@@ -0,0 +1,228 @@
+// Package chart provides the axis gutter for braille charts
+package chart
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GutterPlacement selects which sides of the chart get an axis gutter
+type GutterPlacement int
+
+const (
+	GutterNone GutterPlacement = iota
+	GutterLeft
+	GutterRight
+	GutterBoth
+)
+
+// gutterLabelWidth is the widest scale label, e.g. "1023K" or "100.0%"
+const gutterLabelWidth = 6
+
+// ParseGutter parses a gutter placement name: none, left, right or both
+func ParseGutter(name string) (GutterPlacement, error) {
+	switch name {
+	case "none", "":
+		return GutterNone, nil
+	case "left":
+		return GutterLeft, nil
+	case "right":
+		return GutterRight, nil
+	case "both":
+		return GutterBoth, nil
+	}
+	return GutterNone, fmt.Errorf("unknown axis gutter %q (expected none, left, right or both)", name)
+}
+
+// String returns the placement's name as accepted by ParseGutter
+func (p GutterPlacement) String() string {
+	switch p {
+	case GutterLeft:
+		return "left"
+	case GutterRight:
+		return "right"
+	case GutterBoth:
+		return "both"
+	}
+	return "none"
+}
+
+// SetGutter places the axis gutter, which labels the chart's scale. The
+// gutter is drawn beside the data columns: SetWidth still counts data
+// columns, and FitWidth leaves room for the gutter.
+func (bc *BrailleChart) SetGutter(placement GutterPlacement) {
+	if bc.gutter != placement {
+		bc.gutter = placement
+		bc.frameDirty = true
+	}
+}
+
+// GetGutter returns the axis gutter placement
+func (bc *BrailleChart) GetGutter() GutterPlacement {
+	return bc.gutter
+}
+
+// SetLabelFormatter sets how scale labels are written; labels longer than
+// six characters are cut. The default abbreviates byte rates, e.g. "512K".
+func (bc *BrailleChart) SetLabelFormatter(format func(uint64) string) {
+	bc.labelFormat = format
+	bc.frameDirty = true
+}
+
+// SetBitUnits labels byte rates in SI bits per second, e.g. "80Mb", with
+// gridlines on round bit rates. A label formatter set with SetLabelFormatter
+// wins.
+func (bc *BrailleChart) SetBitUnits(enabled bool) {
+	if bc.bitUnits != enabled {
+		bc.bitUnits = enabled
+		bc.frameDirty = true
+	}
+}
+
+// SetDecimalUnits labels byte rates with 1000-based prefixes, e.g. "1.5M"
+// for 1.5 MB/s, with gridlines on round decimal values
+func (bc *BrailleChart) SetDecimalUnits(enabled bool) {
+	if bc.decimalUnits != enabled {
+		bc.decimalUnits = enabled
+		bc.frameDirty = true
+	}
+}
+
+// GutterWidth returns how many terminal columns the gutters take in total
+func (bc *BrailleChart) GutterWidth() int {
+	switch bc.gutter {
+	case GutterLeft, GutterRight:
+		return gutterLabelWidth + 1
+	case GutterBoth:
+		return 2 * (gutterLabelWidth + 1)
+	}
+	return 0
+}
+
+// FitWidth sizes the chart to fill a number of terminal columns, leaving
+// room for the gutters and allowing for wide cell glyphs
+func (bc *BrailleChart) FitWidth(columns int) {
+	bc.SetWidth((columns - bc.GutterWidth()) / bc.CellWidth())
+}
+
+// formatAxisLabel abbreviates a byte rate for the gutter, e.g. "9.8K"
+func formatAxisLabel(bps uint64) string {
+	return abbreviateRate(bps, 1024)
+}
+
+// formatAxisDecimal abbreviates a byte rate with 1000-based prefixes
+func formatAxisDecimal(bps uint64) string {
+	return abbreviateRate(bps, 1000)
+}
+
+// abbreviateRate abbreviates a byte rate in steps of base
+func abbreviateRate(bps, base uint64) string {
+	if bps < base {
+		return strconv.FormatUint(bps, 10) + "B"
+	}
+	value, unit := float64(bps), 0
+	for value >= float64(base) && unit < 5 {
+		value /= float64(base)
+		unit++
+	}
+	precision := 0
+	if value < 10 {
+		precision = 1
+	}
+	return strconv.FormatFloat(value, 'f', precision, 64) + string("BKMGTP"[unit])
+}
+
+// formatAxisBits abbreviates a byte rate as a bit rate in SI units, e.g.
+// "100Mb" or "2.5Gb"
+func formatAxisBits(bps uint64) string {
+	value, unit := float64(bps)*8, 0
+	for value >= 1000 && unit < 5 {
+		value /= 1000
+		unit++
+	}
+	precision := 0
+	if value < 10 && unit > 0 {
+		precision = 1
+	}
+	prefix := ""
+	if unit > 0 {
+		prefix = string("KMGTP"[unit-1])
+	}
+	return strconv.FormatFloat(value, 'f', precision, 64) + prefix + "b"
+}
+
+// gutterLabel returns the scale label for a row of the chart, or "". The
+// top row is labelled with the scale's maximum; the bottom row too in split
+// mode, where upload grows downwards, and with zero in overlay mode. Rows
+// with a gridline are labelled with its value.
+func (bc *BrailleChart) gutterLabel(row, rows int) string {
+	format := bc.labelFormat
+	switch {
+	case format != nil:
+	case bc.bitUnits:
+		format = formatAxisBits
+	case bc.decimalUnits:
+		format = formatAxisDecimal
+	default:
+		format = formatAxisLabel
+	}
+	if rows == len(bc.gridLabels) && bc.gridLabels[row] != 0 {
+		return format(bc.gridLabels[row])
+	}
+	switch {
+	case row == 0 || (row == rows-1 && !bc.overlayMode):
+		return format(bc.maxValue)
+	case row == rows-1:
+		return format(0)
+	}
+	return ""
+}
+
+// appendGutter appends one row's gutter cell for the given side
+func (bc *BrailleChart) appendGutter(dst []byte, row, rows int, left bool) []byte {
+	label := bc.gutterLabel(row, rows)
+	if len(label) > gutterLabelWidth {
+		label = label[:gutterLabelWidth]
+	}
+	padding := strings.Repeat(" ", gutterLabelWidth-len(label))
+	if left {
+		label = padding + label + " "
+	} else {
+		label = " " + label + padding
+	}
+	if bc.plainOutput || strings.TrimSpace(label) == "" {
+		return append(dst, label...)
+	}
+	pair := bc.ansi.background[0]
+	dst = append(dst, pair.prefix...)
+	dst = append(dst, label...)
+	return append(dst, pair.suffix...)
+}
+
+// hasLeftGutter and hasRightGutter report which sides are drawn
+func (bc *BrailleChart) hasLeftGutter() bool {
+	return bc.gutter == GutterLeft || bc.gutter == GutterBoth
+}
+
+func (bc *BrailleChart) hasRightGutter() bool {
+	return bc.gutter == GutterRight || bc.gutter == GutterBoth
+}
+
+// addGutters adds the gutters to each line of a rendered chart
+func (bc *BrailleChart) addGutters(lines []string) string {
+	var out []byte
+	for y, line := range lines {
+		if y > 0 {
+			out = append(out, '\n')
+		}
+		if bc.hasLeftGutter() {
+			out = bc.appendGutter(out, y, len(lines), true)
+		}
+		out = append(out, line...)
+		if bc.hasRightGutter() {
+			out = bc.appendGutter(out, y, len(lines), false)
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,76 @@
+// Package chart provides the direction labels drawn in split chart corners
+package chart
+
+// Direction labels drawn in the left corners of the split chart
+var (
+	downloadLabel = []rune("▼ download")
+	uploadLabel   = []rune("▲ upload")
+)
+
+// SetDirectionLabels labels the download (top) and upload (bottom) halves of
+// the split chart in their left corners. A label is only drawn while the
+// cells under it are empty, so it never hides data.
+func (bc *BrailleChart) SetDirectionLabels(enabled bool) {
+	if bc.directionLabels != enabled {
+		bc.directionLabels = enabled
+		bc.frameDirty = true
+	}
+}
+
+// HasDirectionLabels reports whether direction labels are enabled
+func (bc *BrailleChart) HasDirectionLabels() bool {
+	return bc.directionLabels
+}
+
+// cornerLabel returns the styled label cells for row y of the frame, or nil
+// when the row has no label or data is drawn where it would go
+func (bc *BrailleChart) cornerLabel(y int) []string {
+	if !bc.directionLabels || bc.overlayMode || bc.CellWidth() != 1 {
+		return nil
+	}
+	index := 0
+	switch y {
+	case 0:
+	case bc.height - 1:
+		index = 1
+	default:
+		return nil
+	}
+	if bc.labelCells[index] == nil {
+		bc.labelCells = [2][]string{bc.styleLabel(downloadLabel), bc.styleLabel(uploadLabel)}
+	}
+	cells := bc.labelCells[index]
+	if len(cells) > bc.width {
+		return nil
+	}
+	for _, column := range bc.frameColumns[:min(len(cells), len(bc.frameColumns))] {
+		if y < len(column) && column[y] != " " {
+			return nil
+		}
+	}
+	return cells
+}
+
+// styleLabel dims each character of a label as its own cell
+func (bc *BrailleChart) styleLabel(label []rune) []string {
+	cells := make([]string, len(label))
+	pair := bc.ansi.background[0]
+	for i, char := range label {
+		cells[i] = string(char)
+		if char == '▼' || char == '▲' {
+			cells[i] = bc.glyphString(char)
+		}
+		if !bc.plainOutput && char != ' ' {
+			cells[i] = pair.prefix + cells[i] + pair.suffix
+		}
+	}
+	return cells
+}
+
+// appendCells appends pre-styled cells to dst
+func appendCells(dst []byte, cells []string) []byte {
+	for _, cell := range cells {
+		dst = append(dst, cell...)
+	}
+	return dst
+}
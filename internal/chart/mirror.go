@@ -0,0 +1,68 @@
+// Package chart provides mirrored charts for side-by-side layouts
+package chart
+
+// Mirror returns a chart that draws this chart's data buffer at its own size
+// and display mode, e.g. overlay beside a split chart. Samples are only added
+// to the source: each render picks up its data along with its scaling, time
+// scale, smoothing, glyphs, colors, gutter, gridlines, labels, cursor and
+// ghost.
+func (bc *BrailleChart) Mirror() *BrailleChart {
+	mirror := NewBrailleChart(bc.maxPoints)
+	mirror.source = bc
+	mirror.history = bc.history
+	mirror.syncSource()
+	return mirror
+}
+
+// syncSource picks up the source chart's data and view settings
+func (bc *BrailleChart) syncSource() {
+	src := bc.source
+	// A reset, or a marker on an already drawn sample, changes cached columns
+	if src.sampleTotal < bc.sampleTotal || len(src.markers) != len(bc.markers) {
+		bc.invalidateColumnCache()
+	}
+	if src.sampleTotal != bc.sampleTotal {
+		bc.frameDirty = true
+	}
+	bc.uploadData, bc.downloadData = src.uploadData, src.downloadData
+	bc.sampleTotal, bc.gaps, bc.markers = src.sampleTotal, src.gaps, src.markers
+	bc.lastSampleTime = src.lastSampleTime
+	bc.currentMax = src.currentMax
+	bc.maxPoints = src.maxPoints
+	if len(src.ghostUpload) != len(bc.ghostUpload) || len(src.ghostDownload) != len(bc.ghostDownload) {
+		bc.SetGhost(src.ghostUpload, src.ghostDownload)
+	}
+	bc.labelFormat = src.labelFormat
+	bc.syncView(src)
+}
+
+// Follow makes this chart, which keeps its own data, take the leader's
+// scaling (and a locked maximum), time scale, smoothing, glyphs, colors,
+// gutter, gridlines, labels and cursor on each render, e.g. a remote
+// source's chart beside the local one.
+// Sampled on the same ticks, the two keep their time axes aligned.
+func (bc *BrailleChart) Follow(leader *BrailleChart) {
+	bc.leader = leader
+	bc.syncView(leader)
+}
+
+// syncView picks up another chart's view settings
+func (bc *BrailleChart) syncView(src *BrailleChart) {
+	bc.SetScalingMode(src.scalingMode)
+	bc.SetFixedMaxValue(src.fixedMax)
+	bc.SetTimeScale(src.timeScale)
+	bc.SetSmoothing(src.smoothing)
+	bc.SetGlyphSet(src.glyphSet)
+	bc.SetPlainOutput(src.plainOutput)
+	bc.SetMonochrome(src.monochrome)
+	bc.SetGutter(src.gutter)
+	bc.SetBitUnits(src.bitUnits)
+	bc.SetDecimalUnits(src.decimalUnits)
+	bc.SetGridlines(src.gridlines)
+	bc.SetDirectionLabels(src.directionLabels)
+	bc.SetCursor(src.cursor)
+	if bc.palette != src.palette {
+		bc.SetGradients(src.uploadGradient, src.downloadGradient, src.overlapGradient)
+		bc.themeName, bc.palette = src.themeName, src.palette
+	}
+}
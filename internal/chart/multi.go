@@ -0,0 +1,216 @@
+// Package chart provides multi-host comparison charts
+package chart
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// HostSeries is one host's rates, one value per chart column
+type HostSeries struct {
+	Name   string
+	Values []uint64
+}
+
+// hostPalette colors hosts in a MultiChart, in order
+var hostPalette = []lipgloss.AdaptiveColor{
+	{Dark: "#60A5FA", Light: "#2563EB"}, // Blue
+	{Dark: "#F59E0B", Light: "#B45309"}, // Amber
+	{Dark: "#A78BFA", Light: "#6D28D9"}, // Violet
+	{Dark: "#34D399", Light: "#047857"}, // Emerald
+	{Dark: "#F472B6", Light: "#BE185D"}, // Pink
+	{Dark: "#94A3B8", Light: "#475569"}, // Slate
+}
+
+// HostColor returns the color of the i-th host in a MultiChart
+func HostColor(i int) lipgloss.AdaptiveColor {
+	return hostPalette[i%len(hostPalette)]
+}
+
+// MultiChart draws several hosts' series on one chart, either overlaid
+// (each from the bottom, shorter columns in front) or stacked on top of each
+// other, with a per-host color and a legend
+type MultiChart struct {
+	width, height int
+	stacked       bool
+	glyphs        *[maxBrailleChars]string
+	rows          int // Vertical resolution of a cell, as cellDots
+	plainOutput   bool
+}
+
+// NewMultiChart creates a comparison chart of the given size in cells
+func NewMultiChart(width, height int) *MultiChart {
+	return &MultiChart{
+		width:  max(width, 1),
+		height: max(height, 1),
+		glyphs: &brailleGlyphs,
+		rows:   brailleDots,
+	}
+}
+
+// SetWidth sets the chart width in cells
+func (mc *MultiChart) SetWidth(width int) {
+	mc.width = max(width, 1)
+}
+
+// SetStacked selects stacked (true) or overlaid (false) series
+func (mc *MultiChart) SetStacked(stacked bool) {
+	mc.stacked = stacked
+}
+
+// SetGlyphSet selects the characters cells are drawn with
+func (mc *MultiChart) SetGlyphSet(set GlyphSet) {
+	mc.glyphs = glyphTable(set)
+	mc.rows = brailleDots
+	if set == GlyphBlock {
+		mc.rows = blockEighths
+	}
+}
+
+// CellWidth returns how many terminal columns one chart cell occupies
+func (mc *MultiChart) CellWidth() int {
+	if w := runewidth.StringWidth(mc.glyphs[maxBrailleChars-1]); w > 1 {
+		return w
+	}
+	return 1
+}
+
+// SetPlainOutput disables colors, e.g. for tests or piping to a file
+func (mc *MultiChart) SetPlainOutput(enabled bool) {
+	mc.plainOutput = enabled
+}
+
+// Render draws the series followed by a legend line. Series longer than the
+// chart width are cut to their most recent values.
+func (mc *MultiChart) Render(series []HostSeries) string {
+	span := mc.height * mc.rows
+	scale := mc.maxValue(series)
+
+	// heights[host][column] in dots
+	heights := make([][]int, len(series))
+	for i, s := range series {
+		heights[i] = make([]int, mc.width)
+		// Right-align so the most recent values end at the right edge
+		values := s.Values[max(len(s.Values)-mc.width, 0):]
+		offset := mc.width - len(values)
+		for x, v := range values {
+			if scale > 0 {
+				heights[i][offset+x] = int(float64(v) / float64(scale) * float64(span))
+			}
+		}
+	}
+
+	styles := make([]lipgloss.Style, len(series))
+	for i := range series {
+		styles[i] = lipgloss.NewStyle().Foreground(HostColor(i))
+	}
+
+	var sb strings.Builder
+	owners := make([]int, len(series))
+	for y := 0; y < mc.height; y++ {
+		for x := 0; x < mc.width; x++ {
+			dots, filled, owner := mc.cell(heights, x, y, span, owners)
+			glyph := mc.glyphs[dots]
+			if mc.rows == blockEighths {
+				glyph = string(lowerBlocks[filled])
+			}
+			if filled == 0 || mc.plainOutput {
+				sb.WriteString(glyph)
+			} else {
+				sb.WriteString(styles[owner].Render(glyph))
+			}
+		}
+		sb.WriteByte('\n')
+	}
+
+	for i, s := range series {
+		if i > 0 {
+			sb.WriteString("  ")
+		}
+		if mc.plainOutput {
+			sb.WriteString("■ " + s.Name)
+		} else {
+			sb.WriteString(styles[i].Render("■") + " " + s.Name)
+		}
+	}
+	return sb.String()
+}
+
+// maxValue returns the value mapped to the full chart height: the largest
+// single value when overlaid, the largest column total when stacked
+func (mc *MultiChart) maxValue(series []HostSeries) uint64 {
+	var peak uint64
+	for x := 0; x < mc.width; x++ {
+		var column uint64
+		for _, s := range series {
+			i := len(s.Values) - mc.width + x
+			if i < 0 {
+				continue
+			}
+			if mc.stacked {
+				column += s.Values[i]
+			} else {
+				column = max(column, s.Values[i])
+			}
+		}
+		peak = max(peak, column)
+	}
+	return peak
+}
+
+// cell returns the dot pattern of one character cell, how many of its rows
+// are filled (always from the bottom) and the host whose color it takes: the
+// front-most host when overlaid, the host filling most of the cell when
+// stacked. owners is scratch space, one entry per host.
+func (mc *MultiChart) cell(heights [][]int, x, y, span int, owners []int) (dots, filled, owner int) {
+	clear(owners)
+	owner = -1
+	for row := 0; row < mc.rows; row++ {
+		// Dot rows count up from the bottom of the chart
+		level := span - (y*mc.rows + row)
+		base, rowFilled := 0, false
+		for host := range heights {
+			h := heights[host][x]
+			reached := false
+			if mc.stacked {
+				reached = level > base && level <= base+h
+				base += h
+			} else {
+				reached = level <= h
+			}
+			if !reached {
+				continue
+			}
+			if mc.rows == brailleDots {
+				dots |= dotPatterns[row]
+			}
+			rowFilled = true
+			owners[host]++
+			if mc.stacked {
+				break
+			}
+		}
+		if rowFilled {
+			filled++
+		}
+	}
+
+	// Overlaid: the shortest column covering the cell is drawn in front.
+	// Stacked: the host with the most dots in the cell wins.
+	for host, count := range owners {
+		if count == 0 {
+			continue
+		}
+		switch {
+		case owner < 0:
+			owner = host
+		case mc.stacked && count > owners[owner]:
+			owner = host
+		case !mc.stacked && heights[host][x] < heights[owner][x]:
+			owner = host
+		}
+	}
+	return dots, filled, max(owner, 0)
+}
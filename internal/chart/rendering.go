@@ -1,60 +1,127 @@
 // Package chart provides rendering functionality for braille charts
 package chart
 
-// renderColumn renders a single column of the chart
-func (bc *BrailleChart) renderColumn(x int, upload, download uint64, centerLine int) {
-	// Calculate heights for upload and download using new scaling
-	halfHeight := centerLine * brailleDots
-	halfHeightFloat := float64(halfHeight)
+// columnKey identifies a rendered column by its quantized dot heights.
+// Columns only differ visually when these differ, so equal keys can share output.
+type columnKey struct {
+	gap            bool
+	marker         bool
+	overlay        bool
+	height         int
+	uploadHeight   int
+	downloadHeight int
+	// Heights of the ghost series drawn behind the data, if any
+	ghostUploadHeight   int
+	ghostDownloadHeight int
+}
 
-	// Apply scaling to the values
-	uploadScale := bc.scaleValue(upload, bc.maxValue)
-	downloadScale := bc.scaleValue(download, bc.maxValue)
+// maxRenderCacheEntries bounds the rendered column cache
+const maxRenderCacheEntries = 4096
+
+// columnHeights converts upload/download values into dot heights for the current display mode.
+// span is the number of dots available to each series (half height in split mode).
+// With block elements the heights are in eighths of a cell instead.
+func (bc *BrailleChart) columnHeights(upload, download uint64, centerLine int) (uploadHeight, downloadHeight, span int) {
+	if bc.overlayMode {
+		span = bc.height * bc.cellDots()
+	} else {
+		span = centerLine * bc.cellDots()
+	}
+	spanFloat := float64(span)
 
-	uploadHeight := int(uploadScale * halfHeightFloat)
-	downloadHeight := int(downloadScale * halfHeightFloat)
+	// Apply scaling to the values
+	uploadHeight = int(bc.scaleValue(upload, bc.maxValue) * spanFloat)
+	downloadHeight = int(bc.scaleValue(download, bc.maxValue) * spanFloat)
 
 	// Clamp values
-	if uploadHeight > halfHeight {
-		uploadHeight = halfHeight
+	if uploadHeight > span {
+		uploadHeight = span
+	}
+	if downloadHeight > span {
+		downloadHeight = span
 	}
-	if downloadHeight > halfHeight {
-		downloadHeight = halfHeight
+	return uploadHeight, downloadHeight, span
+}
+
+// renderedColumn returns the styled glyphs for a column, rendering them only on a cache miss
+func (bc *BrailleChart) renderedColumn(upload, download uint64, centerLine int) []string {
+	uploadHeight, downloadHeight, span := bc.columnHeights(upload, download, centerLine)
+
+	key := columnKey{
+		overlay:        bc.overlayMode,
+		height:         bc.height,
+		uploadHeight:   uploadHeight,
+		downloadHeight: downloadHeight,
+	}
+	if column, exists := bc.renderCache[key]; exists {
+		return column
 	}
 
-	// Render each row in this column
+	column := make([]string, bc.height)
 	for y := 0; y < bc.height; y++ {
-		char := bc.createBrailleCharForLineSplit(y, uploadHeight, downloadHeight, halfHeight, uploadScale, downloadScale)
-		bc.lines[y].WriteString(char)
+		if bc.glyphSet == GlyphBlock {
+			column[y] = bc.createBlockChar(y, uploadHeight, downloadHeight, span)
+		} else if bc.overlayMode {
+			column[y] = bc.createBrailleCharForOverlay(y, uploadHeight, downloadHeight, span, 0, 0)
+		} else {
+			column[y] = bc.createBrailleCharForLineSplit(y, uploadHeight, downloadHeight, span, 0, 0)
+		}
 	}
-}
 
-// renderColumnOverlay renders a single column in overlay mode
-func (bc *BrailleChart) renderColumnOverlay(x int, upload, download uint64) {
-	// Calculate heights for upload and download from bottom of chart using new scaling
-	fullHeight := bc.height * brailleDots
-	fullHeightFloat := float64(fullHeight)
+	// Keep the cache bounded; a full reset is cheap since columns repeat quickly
+	if len(bc.renderCache) >= maxRenderCacheEntries {
+		bc.renderCache = make(map[columnKey][]string)
+	}
+	bc.renderCache[key] = column
 
-	// Apply scaling to the values
-	uploadScale := bc.scaleValue(upload, bc.maxValue)
-	downloadScale := bc.scaleValue(download, bc.maxValue)
+	return column
+}
 
-	uploadHeight := int(uploadScale * fullHeightFloat)
-	downloadHeight := int(downloadScale * fullHeightFloat)
+// gapGlyph marks columns covering an interrupted sample interval
+const gapGlyph = '┊'
 
-	// Clamp values
-	if uploadHeight > fullHeight {
-		uploadHeight = fullHeight
+// gapColumn returns the dimmed vertical marker drawn where sampling was interrupted
+func (bc *BrailleChart) gapColumn() []string {
+	key := columnKey{gap: true, height: bc.height}
+	if column, exists := bc.renderCache[key]; exists {
+		return column
 	}
-	if downloadHeight > fullHeight {
-		downloadHeight = fullHeight
+
+	column := make([]string, bc.height)
+	glyph := bc.getSolidStyledChar(gapGlyph, styleBackground)
+	for y := range column {
+		column[y] = glyph
 	}
+	bc.renderCache[key] = column
+	return column
+}
 
-	// Render each row in this column
-	for y := 0; y < bc.height; y++ {
-		char := bc.createBrailleCharForOverlay(y, uploadHeight, downloadHeight, fullHeight, uploadScale, downloadScale)
-		bc.lines[y].WriteString(char)
+// markerGlyph flags columns where an event (e.g. a route change) happened
+const markerGlyph = '▾'
+
+// markedColumn returns a data column with an event marker in its top row
+func (bc *BrailleChart) markedColumn(upload, download uint64, centerLine int) []string {
+	uploadHeight, downloadHeight, _ := bc.columnHeights(upload, download, centerLine)
+	key := columnKey{
+		marker:         true,
+		overlay:        bc.overlayMode,
+		height:         bc.height,
+		uploadHeight:   uploadHeight,
+		downloadHeight: downloadHeight,
+	}
+	if column, exists := bc.renderCache[key]; exists {
+		return column
 	}
+
+	column := append([]string(nil), bc.renderedColumn(upload, download, centerLine)...)
+	column[0] = bc.getSolidStyledChar(markerGlyph, styleOverlap)
+	bc.renderCache[key] = column
+	return column
+}
+
+// renderColumn adds a single column of the chart in the current display mode to the frame
+func (bc *BrailleChart) renderColumn(upload, download uint64, centerLine int) {
+	bc.frameColumns = append(bc.frameColumns, bc.renderedColumn(upload, download, centerLine))
 }
 
 // createBrailleCharForLineSplit creates a braille character for a specific line with split axis
@@ -133,7 +200,7 @@ func (bc *BrailleChart) createBrailleCharForLineSplit(line, uploadHeight, downlo
 		return bc.getStyledCharWithGradient(char, downloadGradientPos, false)
 	}
 
-	return string(char)
+	return bc.glyphString(char)
 }
 
 // createBrailleCharForOverlay creates a braille character for overlay mode
@@ -178,6 +245,12 @@ func (bc *BrailleChart) createBrailleCharForOverlay(line, uploadHeight, download
 		return " "
 	}
 
+	// Without colors, rows only one direction reaches keep to its dot column
+	if bc.monochrome {
+		uploadDots, downloadDots = overlapDots|(uploadDots&^downloadDots&leftColumnDots),
+			overlapDots|(downloadDots&^uploadDots&rightColumnDots)
+	}
+
 	// Create the character with all dots
 	char := rune(base + (uploadDots | downloadDots))
 
@@ -212,3 +285,49 @@ func (bc *BrailleChart) createBrailleCharForOverlay(line, uploadHeight, download
 
 	return " "
 }
+
+// createBlockChar creates a block element character for a line, filled to the
+// eighth. Heights and span are in eighths, as columnHeights returns them.
+func (bc *BrailleChart) createBlockChar(line, uploadHeight, downloadHeight, span int) string {
+	lineTop := line * blockEighths
+	fill := func(height, from int) int {
+		return min(max(height-from, 0), blockEighths)
+	}
+	gradient := func(distance int) float64 {
+		return min(max(float64(distance)/float64(span-1), 0), 1)
+	}
+
+	if bc.overlayMode {
+		// Both series fill from the bottom
+		lineBottom := span - lineTop - blockEighths
+		upload, download := fill(uploadHeight, lineBottom), fill(downloadHeight, lineBottom)
+		if upload == 0 && download == 0 {
+			return " "
+		}
+		char := lowerBlocks[max(upload, download)]
+		gradientPos := gradient(lineBottom + blockEighths/2)
+		switch {
+		case upload > 0 && download > 0:
+			return bc.getStyledCharWithOverlapGradient(char, gradientPos)
+		case upload > 0:
+			return bc.getStyledCharWithGradient(char, gradientPos, true)
+		default:
+			return bc.getStyledCharWithGradient(char, gradientPos, false)
+		}
+	}
+
+	if lineTop < span {
+		// Download grows up from the axis
+		distance := span - lineTop - blockEighths
+		if download := fill(downloadHeight, distance); download > 0 {
+			return bc.getStyledCharWithGradient(lowerBlocks[download], gradient(distance), false)
+		}
+		return " "
+	}
+	// Upload grows down from the axis
+	distance := lineTop - span
+	if upload := fill(uploadHeight, distance); upload > 0 {
+		return bc.getStyledCharWithGradient(upperBlocks[upload], gradient(distance+upload-1), true)
+	}
+	return " "
+}
@@ -0,0 +1,79 @@
+// Package chart provides the time tick ruler drawn under braille charts
+package chart
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// rulerSteps are the tick spacings a ruler picks from
+var rulerSteps = []time.Duration{
+	10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// rulerMinSpacing is the fewest columns between two ticks
+const rulerMinSpacing = 10
+
+// RulerStep returns the tick spacing for the current time scale, given the
+// time between samples: the shortest step that keeps ticks rulerMinSpacing
+// columns apart
+func (bc *BrailleChart) RulerStep(interval time.Duration) time.Duration {
+	column := bc.columnDuration(interval)
+	for _, step := range rulerSteps {
+		if step >= rulerMinSpacing*column {
+			return step
+		}
+	}
+	return rulerSteps[len(rulerSteps)-1]
+}
+
+// columnDuration returns how much time one chart column covers
+func (bc *BrailleChart) columnDuration(interval time.Duration) time.Duration {
+	windowSize := max(bc.GetTimeScaleSeconds()/60, 1)
+	if bc.timeScale == TimeScale1Min {
+		windowSize = 1
+	}
+	return time.Duration(windowSize) * interval
+}
+
+// RenderRuler renders a single row the width of the chart (and its gutters)
+// with a tick under each column where a RulerStep boundary of wall-clock time
+// falls, e.g. every minute. interval is the time between samples.
+func (bc *BrailleChart) RenderRuler(interval time.Duration) string {
+	column := bc.columnDuration(interval)
+	step := bc.RulerStep(interval)
+	newest := bc.lastSampleTime
+	if newest.IsZero() {
+		newest = bc.clock()
+	}
+
+	blank := strings.Repeat(" ", bc.CellWidth())
+	tick := bc.glyphString(cursorGlyph)
+	tick += strings.Repeat(" ", max(bc.CellWidth()-runewidth.StringWidth(tick), 0))
+
+	var row strings.Builder
+	if bc.hasLeftGutter() {
+		row.WriteString(strings.Repeat(" ", gutterLabelWidth+1))
+	}
+	for x := 0; x < bc.width; x++ {
+		// Column x covers (end-column, end]; tick it if a step boundary is inside
+		end := newest.Add(-time.Duration(bc.width-1-x) * column)
+		if end.Truncate(step) != end.Add(-column).Truncate(step) {
+			row.WriteString(tick)
+		} else {
+			row.WriteString(blank)
+		}
+	}
+	if bc.hasRightGutter() {
+		row.WriteString(strings.Repeat(" ", gutterLabelWidth+1))
+	}
+	if bc.plainOutput {
+		return row.String()
+	}
+	pair := bc.ansi.background[0]
+	return pair.prefix + row.String() + pair.suffix
+}
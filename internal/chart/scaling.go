@@ -1,7 +1,10 @@
 // Package chart provides scaling functionality for braille charts
 package chart
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 // scaleValue applies the current scaling mode to a value
 func (bc *BrailleChart) scaleValue(value uint64, maxValue uint64) float64 {
@@ -43,6 +46,23 @@ func (bc *BrailleChart) SetScalingMode(mode ScalingMode) {
 	}
 }
 
+// SetFixedMaxValue locks the top of the scale at maxValue, so the chart
+// doesn't rescale as bursts come and go; taller columns are clipped. 0
+// returns to scaling to the visible data.
+func (bc *BrailleChart) SetFixedMaxValue(maxValue uint64) {
+	if bc.fixedMax != maxValue {
+		bc.fixedMax = maxValue
+		bc.updateMaxValue()
+		bc.invalidateColumnCache()
+	}
+}
+
+// FixedMaxValue returns the locked scale maximum, or 0 when the scale
+// follows the data
+func (bc *BrailleChart) FixedMaxValue() uint64 {
+	return bc.fixedMax
+}
+
 // GetScalingMode returns the current scaling mode
 func (bc *BrailleChart) GetScalingMode() ScalingMode {
 	return bc.scalingMode
@@ -84,6 +104,43 @@ func (bc *BrailleChart) GetScalingModeName() string {
 	}
 }
 
+// ParseScalingMode parses a scaling mode name: linear, log or sqrt
+func ParseScalingMode(name string) (ScalingMode, error) {
+	for _, mode := range []ScalingMode{ScalingLinear, ScalingLogarithmic, ScalingSquareRoot} {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return ScalingLinear, fmt.Errorf("unknown scaling %q (expected linear, log or sqrt)", name)
+}
+
+// String returns the mode's name as accepted by ParseScalingMode
+func (m ScalingMode) String() string {
+	switch m {
+	case ScalingLogarithmic:
+		return "log"
+	case ScalingSquareRoot:
+		return "sqrt"
+	default:
+		return "linear"
+	}
+}
+
+// ParseTimeScale parses a time scale name as shown in the statusbar, 1m … 24h
+func ParseTimeScale(name string) (TimeScale, error) {
+	for scale := TimeScale1Min; scale <= TimeScale24Hour; scale++ {
+		if scale.String() == name {
+			return scale, nil
+		}
+	}
+	return TimeScale1Min, fmt.Errorf("unknown time scale %q (expected 1m, 3m, 5m, 10m, 15m, 30m, 60m, 3h, 6h, 12h or 24h)", name)
+}
+
+// String returns the time scale's name as accepted by ParseTimeScale
+func (t TimeScale) String() string {
+	return (&BrailleChart{timeScale: t}).GetTimeScaleName()
+}
+
 // GetTimeScale returns the current time scale
 func (bc *BrailleChart) GetTimeScale() TimeScale {
 	return bc.timeScale
@@ -106,6 +163,14 @@ func (bc *BrailleChart) CycleTimeScale() TimeScale {
 	case TimeScale30Min:
 		bc.timeScale = TimeScale60Min
 	case TimeScale60Min:
+		bc.timeScale = TimeScale3Hour
+	case TimeScale3Hour:
+		bc.timeScale = TimeScale6Hour
+	case TimeScale6Hour:
+		bc.timeScale = TimeScale12Hour
+	case TimeScale12Hour:
+		bc.timeScale = TimeScale24Hour
+	case TimeScale24Hour:
 		bc.timeScale = TimeScale1Min
 	default:
 		bc.timeScale = TimeScale1Min
@@ -136,6 +201,14 @@ func (bc *BrailleChart) GetTimeScaleName() string {
 		return "30m"
 	case TimeScale60Min:
 		return "60m"
+	case TimeScale3Hour:
+		return "3h"
+	case TimeScale6Hour:
+		return "6h"
+	case TimeScale12Hour:
+		return "12h"
+	case TimeScale24Hour:
+		return "24h"
 	default:
 		return "1m"
 	}
@@ -158,6 +231,14 @@ func (bc *BrailleChart) GetTimeScaleSeconds() int {
 		return 1800
 	case TimeScale60Min:
 		return 3600
+	case TimeScale3Hour:
+		return 10800
+	case TimeScale6Hour:
+		return 21600
+	case TimeScale12Hour:
+		return 43200
+	case TimeScale24Hour:
+		return 86400
 	default:
 		return 60
 	}
@@ -168,3 +249,25 @@ func (bc *BrailleChart) GetTimeScaleSeconds() int {
 func (bc *BrailleChart) GetTimeScaleMaxPoints() int {
 	return bc.GetTimeScaleSeconds() * 2 // 2 data points per second (500ms intervals)
 }
+
+// historyTier returns the downsampled history tier backing the current time scale,
+// or nil when the scale is rendered from raw samples
+func (bc *BrailleChart) historyTier() *historyTier {
+	if bc.timeScale <= TimeScale60Min {
+		return nil
+	}
+	return bc.history.tierFor(bc.GetTimeScaleSeconds() / 60)
+}
+
+// loadHistoryColumns fills the per-column scratch buffers from the history tier
+func (bc *BrailleChart) loadHistoryColumns(tier *historyTier) {
+	if cap(bc.historyUpload) < bc.width {
+		bc.historyUpload = make([]uint64, bc.width)
+		bc.historyDownload = make([]uint64, bc.width)
+	}
+	bc.historyUpload = bc.historyUpload[:bc.width]
+	bc.historyDownload = bc.historyDownload[:bc.width]
+
+	windowSize := bc.GetTimeScaleSeconds() / 60
+	tier.columns(windowSize/tier.resolution, bc.historyUpload, bc.historyDownload)
+}
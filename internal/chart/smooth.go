@@ -0,0 +1,86 @@
+// Package chart provides display smoothing for braille charts
+package chart
+
+// SetSmoothing enables smoothing of the drawn columns. Each column is blended
+// with its neighbours (1:2:1), which takes the edge off single-sample spikes
+// for presentations. Only the drawing changes: the stored samples, peaks and
+// scale are untouched.
+func (bc *BrailleChart) SetSmoothing(enabled bool) {
+	if bc.smoothing != enabled {
+		bc.smoothing = enabled
+		bc.invalidateColumnCache()
+	}
+}
+
+// ToggleSmoothing switches smoothing on or off
+func (bc *BrailleChart) ToggleSmoothing() {
+	bc.SetSmoothing(!bc.smoothing)
+}
+
+// IsSmoothing reports whether columns are smoothed
+func (bc *BrailleChart) IsSmoothing() bool {
+	return bc.smoothing
+}
+
+// smoothColumn holds one column's peak rates before smoothing
+type smoothColumn struct {
+	upload, download uint64
+	start, end       int // data indices covered; empty when there is no data
+	gap              bool
+}
+
+// renderSmoothed renders the sample-based time scales with smoothing: column
+// values are collected first, then blended with their neighbours. Gaps and
+// columns without data are never blended into.
+func (bc *BrailleChart) renderSmoothed(chartWidth, centerLine int) {
+	dataLen := bc.GetDataLength()
+	windowSize := 1
+	if bc.timeScale != TimeScale1Min {
+		windowSize = max(bc.GetTimeScaleSeconds()/60, 1)
+	}
+	totalWindows := (dataLen + windowSize - 1) / windowSize
+
+	if cap(bc.smoothColumns) < chartWidth {
+		bc.smoothColumns = make([]smoothColumn, chartWidth)
+	}
+	columns := bc.smoothColumns[:chartWidth]
+	for x := range columns {
+		column := smoothColumn{}
+		if window := totalWindows - (chartWidth - x); window >= 0 {
+			column.start = window * windowSize
+			column.end = min(column.start+windowSize, dataLen)
+			column.gap = bc.hasGap(column.start, column.end)
+			for i := column.start; i < column.end; i++ {
+				if i < len(bc.uploadData) {
+					column.upload = max(column.upload, bc.uploadData[i])
+				}
+				if i < len(bc.downloadData) {
+					column.download = max(column.download, bc.downloadData[i])
+				}
+			}
+		}
+		columns[x] = column
+	}
+
+	for x, column := range columns {
+		if column.gap {
+			bc.frameColumns = append(bc.frameColumns, bc.gapColumn())
+			continue
+		}
+		if column.end <= column.start {
+			bc.renderColumn(0, 0, centerLine)
+			continue
+		}
+		upload, download := column.upload*2, column.download*2
+		for _, neighbour := range []int{x - 1, x + 1} {
+			if neighbour < 0 || neighbour >= len(columns) || columns[neighbour].gap || columns[neighbour].end <= columns[neighbour].start {
+				upload += column.upload
+				download += column.download
+				continue
+			}
+			upload += columns[neighbour].upload
+			download += columns[neighbour].download
+		}
+		bc.frameColumns = append(bc.frameColumns, bc.dataColumn(upload/4, download/4, column.start, column.end, centerLine))
+	}
+}
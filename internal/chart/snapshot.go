@@ -0,0 +1,116 @@
+// Package chart provides snapshots of the chart's data for session recovery
+package chart
+
+import "slices"
+
+// Snapshot is a copy of a chart's samples, events and multi-hour history,
+// e.g. saved to disk so a crashed session can be restored
+type Snapshot struct {
+	Upload   []uint64 `json:"upload"`
+	Download []uint64 `json:"download"`
+	// Gaps and markers as indices into Upload and Download
+	Gaps    []int `json:"gaps,omitempty"`
+	Markers []int `json:"markers,omitempty"`
+	// Downsampled history tiers, finest first
+	Tiers []TierSnapshot `json:"tiers"`
+}
+
+// TierSnapshot is a copy of one tiered history level's completed buckets
+type TierSnapshot struct {
+	Upload   []uint64 `json:"upload"`
+	Download []uint64 `json:"download"`
+	Total    int      `json:"total"`
+}
+
+// Snapshot copies the chart's data
+func (bc *BrailleChart) Snapshot() Snapshot {
+	first := bc.sampleTotal - bc.GetDataLength()
+	s := Snapshot{
+		Upload:   slices.Clone(bc.uploadData),
+		Download: slices.Clone(bc.downloadData),
+		Gaps:     relativeEvents(bc.gaps, first),
+		Markers:  relativeEvents(bc.markers, first),
+	}
+	for _, tier := range bc.history.tiers {
+		s.Tiers = append(s.Tiers, TierSnapshot{
+			Upload:   slices.Clone(tier.upload),
+			Download: slices.Clone(tier.download),
+			Total:    tier.total,
+		})
+	}
+	return s
+}
+
+// relativeEvents converts absolute sample indices to indices from first
+func relativeEvents(events []int, first int) []int {
+	var relative []int
+	for _, event := range events {
+		relative = append(relative, event-first)
+	}
+	return relative
+}
+
+// Prepend draws a snapshot's samples before the chart's own, separated by a
+// gap for the time in between. Samples beyond the chart's capacity are dropped
+// from the oldest end.
+func (bc *BrailleChart) Prepend(s Snapshot) {
+	if len(s.Upload) != len(s.Download) {
+		return
+	}
+	first := bc.sampleTotal - bc.GetDataLength()
+	offset := len(s.Upload) + 1
+
+	gaps := slices.Clone(s.Gaps)
+	gaps = append(gaps, len(s.Upload))
+	for _, gap := range bc.gaps {
+		gaps = append(gaps, gap-first+offset)
+	}
+	markers := slices.Clone(s.Markers)
+	for _, marker := range bc.markers {
+		markers = append(markers, marker-first+offset)
+	}
+
+	upload := append(append(slices.Clone(s.Upload), 0), bc.uploadData...)
+	download := append(append(slices.Clone(s.Download), 0), bc.downloadData...)
+	if excess := len(upload) - bc.maxPoints; excess > 0 {
+		upload, download = upload[excess:], download[excess:]
+		for i := range gaps {
+			gaps[i] -= excess
+		}
+		for i := range markers {
+			markers[i] -= excess
+		}
+	}
+
+	// Keep absolute indices non-negative by counting the restored samples
+	bc.sampleTotal += offset
+	first = bc.sampleTotal - len(upload)
+	bc.uploadData, bc.downloadData = upload, download
+	bc.gaps, bc.markers = bc.gaps[:0], bc.markers[:0]
+	for _, gap := range gaps {
+		bc.gaps = append(bc.gaps, first+gap)
+	}
+	for _, marker := range markers {
+		bc.markers = append(bc.markers, first+marker)
+	}
+	bc.pruneEvents()
+
+	for i, tier := range bc.history.tiers {
+		if i >= len(s.Tiers) || len(s.Tiers[i].Upload) != len(s.Tiers[i].Download) {
+			break
+		}
+		restored := s.Tiers[i]
+		tier.upload = append(slices.Clone(restored.Upload), tier.upload...)
+		tier.download = append(slices.Clone(restored.Download), tier.download...)
+		if excess := len(tier.upload) - tier.capacity; excess > 0 {
+			tier.upload, tier.download = tier.upload[excess:], tier.download[excess:]
+		}
+		tier.total += restored.Total
+	}
+
+	bc.cursor = -1
+	bc.steadyCount = 0
+	bc.recalculateMax()
+	bc.updateMaxValue()
+	bc.invalidateColumnCache()
+}
@@ -4,63 +4,213 @@ package chart
 import (
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 )
 
 var (
 	// Chart-specific styles for braille characters - base colors
 	baseUploadColor   = lipgloss.Color("#F87171") // Red for upload
 	baseDownloadColor = lipgloss.Color("#34D399") // Green for download
-
-	// Color gradients for height-based shading (darker at top, lighter at bottom)
-	uploadGradient = ColorGradient{
-		Steps: []lipgloss.Color{
-			lipgloss.Color("#7F1D1D"), // Dark red (top/darkest)
-			lipgloss.Color("#B91C1C"), // Medium-dark red
-			lipgloss.Color("#DC2626"), // Medium red
-			lipgloss.Color("#EF4444"), // Medium-light red
-			lipgloss.Color("#F87171"), // Light red
-			lipgloss.Color("#FCA5A5"), // Very light red (bottom/lightest)
-		},
-	}
-
-	downloadGradient = ColorGradient{
-		Steps: []lipgloss.Color{
-			lipgloss.Color("#064E3B"), // Dark green (top/darkest)
-			lipgloss.Color("#047857"), // Medium-dark green
-			lipgloss.Color("#059669"), // Medium green
-			lipgloss.Color("#10B981"), // Medium-light green
-			lipgloss.Color("#34D399"), // Light green
-			lipgloss.Color("#6EE7B7"), // Very light green (bottom/lightest)
-		},
-	}
-
-	// Yellow gradient for overlay overlap areas (dark to light from top to bottom)
-	overlapGradient = ColorGradient{
-		Steps: []lipgloss.Color{
-			lipgloss.Color("#713F12"), // Very dark yellow/brown (top/darkest)
-			lipgloss.Color("#92400E"), // Dark yellow
-			lipgloss.Color("#B45309"), // Medium-dark yellow
-			lipgloss.Color("#D97706"), // Medium yellow
-			lipgloss.Color("#F59E0B"), // Medium-light yellow
-			lipgloss.Color("#FBBF24"), // Light yellow
-			lipgloss.Color("#FCD34D"), // Very light yellow
-			lipgloss.Color("#FDE68A"), // Extremely light yellow (bottom/lightest)
-		},
-	}
+	backgroundColor   = lipgloss.Color("#374151") // Grey for empty compact cells
 
 	// Overlap style for overlay mode (fallback style)
 	overlapStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FCD34D")). // Yellow for overlap
 			Bold(true)
+)
+
+// ansiPair holds the raw escape sequences emitted around a styled glyph
+type ansiPair struct {
+	prefix string
+	suffix string
+}
+
+// ansiTable holds precomputed escape sequences for every gradient step.
+// It is shared by the full and compact renderers.
+type ansiTable struct {
+	upload     []ansiPair
+	download   []ansiPair
+	overlap    []ansiPair
+	background []ansiPair
+}
 
-	// Optimization: character cache for styled braille characters
-	uploadCharCache   = make(map[string]string, 1536) // 6 gradient steps * 256 chars
-	downloadCharCache = make(map[string]string, 1536) // 6 gradient steps * 256 chars
-	overlapCharCache  = make(map[rune]string, 256)
+// pairs returns the escape sequences for a style kind
+func (t *ansiTable) pairs(kind styleKind) []ansiPair {
+	switch kind {
+	case styleUpload:
+		return t.upload
+	case styleDownload:
+		return t.download
+	case styleOverlap:
+		return t.overlap
+	default:
+		return t.background
+	}
+}
+
+// brailleGlyphs maps every braille dot pattern to its glyph string, so rendering
+// never converts runes to strings per cell
+var brailleGlyphs = func() (glyphs [maxBrailleChars]string) {
+	for dots := range glyphs {
+		glyphs[dots] = string(rune(brailleBase + dots))
+	}
+	return glyphs
+}()
+
+// blockGlyphs and asciiGlyphs translate braille dot patterns for terminals whose
+// fonts lack braille. The renderer only fills whole dot rows, so each pattern
+// reduces to which of the cell's four rows are filled.
+var (
+	blockGlyphs = fallbackGlyphs(
+		[5]string{" ", "▂", "▄", "▆", "█"}, // rows filled from the bottom
+		[5]string{" ", "▔", "▀", "▀", "█"}, // rows filled from the top
+	)
+	asciiGlyphs = fallbackGlyphs(
+		[5]string{" ", "_", ".", "o", "#"},
+		[5]string{" ", "~", "\"", "*", "#"},
+	)
 )
 
+// lowerBlocks and upperBlocks draw a cell filled to a number of eighths from
+// the bottom or from the top. Unicode only has upper blocks for one eighth
+// and a half, so cells filled from the top round to those.
+var (
+	lowerBlocks = [blockEighths + 1]rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+	upperBlocks = [blockEighths + 1]rune{' ', '▔', '▔', '▀', '▀', '▀', '▀', '█', '█'}
+)
+
+// cellDots returns the vertical resolution of one cell: braille dot rows, or
+// eighths for block elements, which have a glyph for each
+func (bc *BrailleChart) cellDots() int {
+	if bc.glyphSet == GlyphBlock {
+		return blockEighths
+	}
+	return brailleDots
+}
+
+// fallbackGlyphs builds a dot pattern translation table from per-row-count glyphs
+func fallbackGlyphs(fromBottom, fromTop [5]string) (glyphs [maxBrailleChars]string) {
+	for dots := range glyphs {
+		var rows, count int
+		for row, pattern := range dotPatterns {
+			if dots&pattern != 0 {
+				rows |= 1 << row
+				count++
+			}
+		}
+
+		// Rows grow from the top when the top row is set but not the bottom one
+		if rows&1 != 0 && rows&(1<<(brailleDots-1)) == 0 {
+			glyphs[dots] = fromTop[count]
+		} else {
+			glyphs[dots] = fromBottom[count]
+		}
+	}
+	return glyphs
+}
+
+// symbolFallbacks replace chart symbols in ASCII mode, or when the terminal
+// would draw them at a different width than the chart cells
+var symbolFallbacks = map[rune]string{
+	gapGlyph:    "|",
+	markerGlyph: "v",
+	cursorGlyph: "|",
+	gridGlyph:   "-",
+	'▼':         "v",
+	'▲':         "^",
+}
+
+// glyphTable returns the dot pattern translation table for a glyph set
+func glyphTable(set GlyphSet) *[maxBrailleChars]string {
+	switch set {
+	case GlyphBlock:
+		return &blockGlyphs
+	case GlyphASCII:
+		return &asciiGlyphs
+	default:
+		return &brailleGlyphs
+	}
+}
+
+// glyphString returns the string for a rune, translating braille patterns
+// through the chart's glyph set
+func (bc *BrailleChart) glyphString(char rune) string {
+	if dots := int(char - brailleBase); dots >= 0 && dots < maxBrailleChars {
+		return bc.glyphs[dots]
+	}
+	if fallback, ok := symbolFallbacks[char]; ok && (bc.glyphSet == GlyphASCII || runewidth.RuneWidth(char) != bc.CellWidth()) {
+		return fallback
+	}
+	return string(char)
+}
+
+// SetGlyphSet selects the characters cells are drawn with
+func (bc *BrailleChart) SetGlyphSet(set GlyphSet) {
+	if bc.glyphSet == set {
+		return
+	}
+	bc.glyphSet = set
+	bc.glyphs = glyphTable(set)
+	bc.InvalidateStyleCache()
+}
+
+// CellWidth returns how many terminal columns one chart cell occupies. Block
+// elements are East Asian ambiguous-width, so CJK locales draw them double-width;
+// callers divide the terminal width by this to size the chart.
+func (bc *BrailleChart) CellWidth() int {
+	if w := runewidth.StringWidth(bc.glyphs[maxBrailleChars-1]); w > 1 {
+		return w
+	}
+	return 1
+}
+
+// GetGlyphSet returns the characters cells are drawn with
+func (bc *BrailleChart) GetGlyphSet() GlyphSet {
+	return bc.glyphSet
+}
+
+// ansiMarker is a placeholder rendered through lipgloss to discover its escape sequences
+const ansiMarker = "X"
+
+// newANSITable precomputes escape sequences for the given gradients.
+// Empty gradients fall back to a single step in the fallback color.
+func newANSITable(upload, download, overlap ColorGradient) ansiTable {
+	return ansiTable{
+		upload:     ansiGradient(upload, baseUploadColor),
+		download:   ansiGradient(download, baseDownloadColor),
+		overlap:    ansiGradient(overlap, lipgloss.Color("#FCD34D")),
+		background: ansiGradient(ColorGradient{}, backgroundColor),
+	}
+}
+
+// ansiGradient precomputes the escape sequences for each step of a gradient
+func ansiGradient(gradient ColorGradient, fallback lipgloss.Color) []ansiPair {
+	steps := gradient.Steps
+	if len(steps) == 0 {
+		steps = []lipgloss.Color{fallback}
+	}
+
+	pairs := make([]ansiPair, len(steps))
+	for i, color := range steps {
+		pairs[i] = ansiSequences(lipgloss.NewStyle().Foreground(color).Bold(true))
+	}
+	return pairs
+}
+
+// ansiSequences extracts the prefix and suffix lipgloss emits around text for a style,
+// honoring the detected terminal color profile
+func ansiSequences(style lipgloss.Style) ansiPair {
+	rendered := style.Render(ansiMarker)
+	idx := strings.Index(rendered, ansiMarker)
+	if idx < 0 {
+		return ansiPair{}
+	}
+	return ansiPair{prefix: rendered[:idx], suffix: rendered[idx+len(ansiMarker):]}
+}
+
 // clampPercent clamps a value to the 0-1 range
 func clampPercent(value float64) float64 {
 	return math.Max(0, math.Min(1, value))
@@ -79,11 +229,125 @@ func getGradientStepIndex(heightPercent float64, stepCount int) int {
 	return stepIndex
 }
 
+// paletteCount numbers the gradients set on any chart, so following charts
+// can tell when their leader's colors changed
+var paletteCount int
+
+// SetGradients replaces the chart's color gradients and invalidates all styled caches
+func (bc *BrailleChart) SetGradients(upload, download, overlap ColorGradient) {
+	bc.uploadGradient = upload
+	bc.downloadGradient = download
+	bc.overlapGradient = overlap
+	bc.ansi = newANSITable(upload, download, overlap)
+	paletteCount++
+	bc.palette = paletteCount
+	bc.InvalidateStyleCache()
+}
+
+// Gradients returns the chart's upload, download and overlap gradients
+func (bc *BrailleChart) Gradients() (upload, download, overlap ColorGradient) {
+	return bc.uploadGradient, bc.downloadGradient, bc.overlapGradient
+}
+
+// SetColors shades the upload and download gradients from single #RRGGBB
+// colors, which become the light end of each; an empty color keeps the default
+func (bc *BrailleChart) SetColors(upload, download lipgloss.Color) {
+	uploadSteps, downloadSteps := bc.uploadGradient, bc.downloadGradient
+	if upload != "" {
+		uploadSteps = shadeGradient(upload)
+	}
+	if download != "" {
+		downloadSteps = shadeGradient(download)
+	}
+	bc.SetGradients(uploadSteps, downloadSteps, bc.overlapGradient)
+}
+
+// shadeMix is how far each gradient step is mixed toward black (negative:
+// white), darkest at the top like the built-in gradients
+var shadeMix = []float64{0.6, 0.4, 0.25, 0.1, 0, -0.35}
+
+// shadeGradient builds a gradient around a #RRGGBB color
+func shadeGradient(base lipgloss.Color) ColorGradient {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(string(base), "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return ColorGradient{Steps: []lipgloss.Color{base}}
+	}
+	mix := func(c uint8, amount float64) uint8 {
+		if amount < 0 {
+			return uint8(float64(c) + (255-float64(c))*-amount)
+		}
+		return uint8(float64(c) * (1 - amount))
+	}
+	gradient := ColorGradient{Steps: make([]lipgloss.Color, len(shadeMix))}
+	for i, amount := range shadeMix {
+		gradient.Steps[i] = lipgloss.Color(fmt.Sprintf("#%02X%02X%02X", mix(r, amount), mix(g, amount), mix(b, amount)))
+	}
+	return gradient
+}
+
+// gradientSteps is how many steps a gradient blended from a few stops gets
+const gradientSteps = 8
+
+// GradientFromStops builds a gradient from #RRGGBB color stops ordered from
+// the top of the chart to the axis. Stops that aren't #RRGGBB colors are
+// skipped; a single stop is shaded like SetColors, and fewer stops than
+// gradientSteps are blended evenly. No usable stops gives an empty gradient.
+func GradientFromStops(stops ...lipgloss.Color) ColorGradient {
+	var valid []lipgloss.Color
+	var rgb [][3]float64
+	for _, stop := range stops {
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(string(stop), "#%02x%02x%02x", &r, &g, &b); err == nil && len(stop) == 7 {
+			valid = append(valid, stop)
+			rgb = append(rgb, [3]float64{float64(r), float64(g), float64(b)})
+		}
+	}
+	switch {
+	case len(rgb) == 0:
+		return ColorGradient{}
+	case len(rgb) == 1:
+		return shadeGradient(valid[0])
+	}
+
+	count := max(len(rgb), gradientSteps)
+	gradient := ColorGradient{Steps: make([]lipgloss.Color, count)}
+	for i := range gradient.Steps {
+		pos := float64(i) / float64(count-1) * float64(len(rgb)-1)
+		from := min(int(pos), len(rgb)-2)
+		t := pos - float64(from)
+		var c [3]uint8
+		for j := range c {
+			c[j] = uint8(math.Round(rgb[from][j] + (rgb[from+1][j]-rgb[from][j])*t))
+		}
+		gradient.Steps[i] = lipgloss.Color(fmt.Sprintf("#%02X%02X%02X", c[0], c[1], c[2]))
+	}
+	return gradient
+}
+
+// SetGradientStops replaces the chart's gradients with ones built from color
+// stops by GradientFromStops. Areas without usable stops keep their colors.
+func (bc *BrailleChart) SetGradientStops(upload, download, overlap []lipgloss.Color) {
+	gradients := []ColorGradient{bc.uploadGradient, bc.downloadGradient, bc.overlapGradient}
+	for i, stops := range [][]lipgloss.Color{upload, download, overlap} {
+		if gradient := GradientFromStops(stops...); len(gradient.Steps) > 0 {
+			gradients[i] = gradient
+		}
+	}
+	bc.SetGradients(gradients[0], gradients[1], gradients[2])
+}
+
+// InvalidateStyleCache drops all cached styled glyphs and rendered columns.
+// Call this after anything that changes how glyphs are colored.
+func (bc *BrailleChart) InvalidateStyleCache() {
+	bc.styleCache.clear()
+	bc.invalidateColumnCache()
+}
+
 // getGradientColor returns a color from the gradient based on height percentage
 func (bc *BrailleChart) getGradientColor(heightPercent float64, isUpload bool) lipgloss.Color {
-	gradient := downloadGradient
+	gradient := bc.downloadGradient
 	if isUpload {
-		gradient = uploadGradient
+		gradient = bc.uploadGradient
 	}
 
 	// Check if gradient is available
@@ -102,57 +366,60 @@ func (bc *BrailleChart) getGradientColor(heightPercent float64, isUpload bool) l
 
 // getStyledCharWithGradient returns a styled character with gradient coloring
 func (bc *BrailleChart) getStyledCharWithGradient(char rune, heightPercent float64, isUpload bool) string {
-	color := bc.getGradientColor(heightPercent, isUpload)
-
-	// Create cache key
-	var cacheKey string
-	if isUpload {
-		cacheKey = fmt.Sprintf("u_%c_%.2f", char, heightPercent)
-	} else {
-		cacheKey = fmt.Sprintf("d_%c_%.2f", char, heightPercent)
+	if bc.plainOutput {
+		return bc.glyphString(char)
 	}
 
-	// Check cache first
-	var cache map[string]string
+	kind := styleDownload
 	if isUpload {
-		cache = uploadCharCache
-	} else {
-		cache = downloadCharCache
+		kind = styleUpload
 	}
 
-	if cached, exists := cache[cacheKey]; exists {
-		return cached
+	pairs := bc.ansi.pairs(kind)
+	return bc.styleGlyph(kind, getGradientStepIndex(heightPercent, len(pairs)), char)
+}
+
+// getStyledCharWithOverlapGradient returns a styled character with yellow overlap gradient coloring
+func (bc *BrailleChart) getStyledCharWithOverlapGradient(char rune, heightPercent float64) string {
+	if bc.plainOutput {
+		return bc.glyphString(char)
 	}
 
-	// Create styled character
-	style := lipgloss.NewStyle().Foreground(color).Bold(true)
-	styled := style.Render(string(char))
+	return bc.styleGlyph(styleOverlap, getGradientStepIndex(heightPercent, len(bc.ansi.overlap)), char)
+}
 
-	// Cache the result
-	cache[cacheKey] = styled
+// getSolidStyledChar returns a glyph styled with the representative (middle) step
+// of a gradient, as used by the compact renderer
+func (bc *BrailleChart) getSolidStyledChar(char rune, kind styleKind) string {
+	if bc.plainOutput {
+		return bc.glyphString(char)
+	}
 
-	return styled
+	return bc.styleGlyph(kind, len(bc.ansi.pairs(kind))/2, char)
 }
 
-// getStyledCharWithOverlapGradient returns a styled character with yellow overlap gradient coloring
-func (bc *BrailleChart) getStyledCharWithOverlapGradient(char rune, heightPercent float64) string {
-	// Check if gradient is available
-	stepCount := len(overlapGradient.Steps)
-	if stepCount == 0 {
-		return overlapStyle.Render(string(char))
+// styleGlyph wraps a glyph in the precomputed escape sequences for a gradient step.
+// Keying on the step rather than the raw position means positions that map to the
+// same step share a cache entry.
+func (bc *BrailleChart) styleGlyph(kind styleKind, step int, char rune) string {
+	key := styleKey{kind: kind, step: step, char: char}
+	if cached, exists := bc.styleCache.get(key); exists {
+		return cached
 	}
 
-	// Get gradient step index and color
-	stepIndex := getGradientStepIndex(heightPercent, stepCount)
-	color := overlapGradient.Steps[stepIndex]
-	
-	// Create and return styled character
-	style := lipgloss.NewStyle().Foreground(color).Bold(true)
-	return style.Render(string(char))
+	pair := bc.ansi.pairs(kind)[step]
+	styled := pair.prefix + bc.glyphString(char) + pair.suffix
+
+	bc.styleCache.put(key, styled)
+	return styled
 }
 
 // getStyledChar returns a cached styled character or creates and caches it
 func (bc *BrailleChart) getStyledChar(char rune, isUpload bool) string {
+	if bc.plainOutput {
+		return string(char)
+	}
+
 	// Create basic styling without gradient for legacy support
 	var style lipgloss.Style
 	if isUpload {
@@ -166,6 +433,10 @@ func (bc *BrailleChart) getStyledChar(char rune, isUpload bool) string {
 
 // getStyledCharOverlay returns a cached styled character for overlay mode
 func (bc *BrailleChart) getStyledCharOverlay(char rune, mode string) string {
+	if bc.plainOutput {
+		return string(char)
+	}
+
 	var style lipgloss.Style
 
 	switch mode {
@@ -0,0 +1,122 @@
+// Package chart provides color themes for braille charts
+package chart
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is a named set of gradients for the upload, download and overlap
+// areas of a chart, each ordered from the top (darkest) to the axis
+type Theme struct {
+	Name     string
+	Upload   ColorGradient
+	Download ColorGradient
+	Overlap  ColorGradient
+}
+
+// DefaultThemeName names the theme charts start with
+const DefaultThemeName = "default"
+
+// defaultTheme is the original red, green and yellow palette
+var defaultTheme = Theme{
+	Name: DefaultThemeName,
+	Upload: ColorGradient{
+		Steps: []lipgloss.Color{
+			lipgloss.Color("#7F1D1D"), // Dark red (top/darkest)
+			lipgloss.Color("#B91C1C"), // Medium-dark red
+			lipgloss.Color("#DC2626"), // Medium red
+			lipgloss.Color("#EF4444"), // Medium-light red
+			lipgloss.Color("#F87171"), // Light red
+			lipgloss.Color("#FCA5A5"), // Very light red (bottom/lightest)
+		},
+	},
+	Download: ColorGradient{
+		Steps: []lipgloss.Color{
+			lipgloss.Color("#064E3B"), // Dark green (top/darkest)
+			lipgloss.Color("#047857"), // Medium-dark green
+			lipgloss.Color("#059669"), // Medium green
+			lipgloss.Color("#10B981"), // Medium-light green
+			lipgloss.Color("#34D399"), // Light green
+			lipgloss.Color("#6EE7B7"), // Very light green (bottom/lightest)
+		},
+	},
+	// Yellow gradient for overlay overlap areas (dark to light from top to bottom)
+	Overlap: ColorGradient{
+		Steps: []lipgloss.Color{
+			lipgloss.Color("#713F12"), // Very dark yellow/brown (top/darkest)
+			lipgloss.Color("#92400E"), // Dark yellow
+			lipgloss.Color("#B45309"), // Medium-dark yellow
+			lipgloss.Color("#D97706"), // Medium yellow
+			lipgloss.Color("#F59E0B"), // Medium-light yellow
+			lipgloss.Color("#FBBF24"), // Light yellow
+			lipgloss.Color("#FCD34D"), // Very light yellow
+			lipgloss.Color("#FDE68A"), // Extremely light yellow (bottom/lightest)
+		},
+	},
+}
+
+// Themes lists the built-in themes in the order they are cycled through.
+// Apart from the default, each is shaded from one color per area.
+var Themes = []Theme{
+	defaultTheme,
+	shadedTheme("solarized", "#DC322F", "#2AA198", "#B58900"),
+	shadedTheme("gruvbox", "#FB4934", "#B8BB26", "#FABD2F"),
+	shadedTheme("monochrome", "#9E9E9E", "#E0E0E0", "#FFFFFF"),
+	// Okabe-Ito orange, sky blue and yellow stay distinct with any color
+	// vision deficiency
+	shadedTheme("colorblind", "#E69F00", "#56B4E9", "#F0E442"),
+}
+
+// shadedTheme builds a theme shading each area from a single #RRGGBB color
+func shadedTheme(name string, upload, download, overlap lipgloss.Color) Theme {
+	return Theme{
+		Name:     name,
+		Upload:   shadeGradient(upload),
+		Download: shadeGradient(download),
+		Overlap:  shadeGradient(overlap),
+	}
+}
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, len(Themes))
+	for i, theme := range Themes {
+		names[i] = theme.Name
+	}
+	return names
+}
+
+// ParseTheme looks up a built-in theme by name
+func ParseTheme(name string) (Theme, error) {
+	for _, theme := range Themes {
+		if strings.EqualFold(theme.Name, name) {
+			return theme, nil
+		}
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q (expected %s)", name, strings.Join(ThemeNames(), ", "))
+}
+
+// NextTheme returns the built-in theme after the named one, wrapping around
+func NextTheme(name string) Theme {
+	for i, theme := range Themes {
+		if theme.Name == name {
+			return Themes[(i+1)%len(Themes)]
+		}
+	}
+	return Themes[0]
+}
+
+// SetTheme colors the chart with a theme
+func (bc *BrailleChart) SetTheme(theme Theme) {
+	bc.SetGradients(theme.Upload, theme.Download, theme.Overlap)
+	bc.themeName = theme.Name
+}
+
+// ThemeName returns the name of the chart's theme. Colors set with SetColors
+// keep the name of the theme they were set over.
+func (bc *BrailleChart) ThemeName() string {
+	return bc.themeName
+}
@@ -0,0 +1,140 @@
+// Package chart provides tiered-resolution history for long time scales
+package chart
+
+// historyTier holds samples downsampled to a fixed resolution, keeping the
+// maximum of each bucket so short bursts stay visible at coarse resolutions
+type historyTier struct {
+	resolution int // raw samples per bucket
+	factor     int // buckets of the finer tier per bucket of this tier
+	capacity   int // maximum buckets retained
+	upload     []uint64
+	download   []uint64
+	total      int // buckets ever completed, used for stable column alignment
+	// Bucket currently being filled
+	pendingCount    int
+	pendingUpload   uint64
+	pendingDownload uint64
+}
+
+// tieredHistory cascades raw 500ms samples into progressively coarser tiers
+// (1s → 10s → 1m) so multi-hour windows stay available with bounded memory
+type tieredHistory struct {
+	tiers []*historyTier
+}
+
+// newTieredHistory creates the default tier layout:
+// 1s buckets for 1 hour, 10s buckets for 12 hours and 1m buckets for 24 hours
+func newTieredHistory() *tieredHistory {
+	layout := []struct{ factor, capacity int }{
+		{2, 3600},  // 2 x 500ms = 1s, 1 hour
+		{10, 4320}, // 10 x 1s = 10s, 12 hours
+		{6, 1440},  // 6 x 10s = 1m, 24 hours
+	}
+
+	h := &tieredHistory{}
+	resolution := 1
+	for _, l := range layout {
+		resolution *= l.factor
+		h.tiers = append(h.tiers, &historyTier{
+			resolution: resolution,
+			factor:     l.factor,
+			capacity:   l.capacity,
+			upload:     make([]uint64, 0, l.capacity),
+			download:   make([]uint64, 0, l.capacity),
+		})
+	}
+	return h
+}
+
+// add feeds a raw sample into the first tier, cascading completed buckets downward
+func (h *tieredHistory) add(upload, download uint64) {
+	for _, tier := range h.tiers {
+		if upload > tier.pendingUpload {
+			tier.pendingUpload = upload
+		}
+		if download > tier.pendingDownload {
+			tier.pendingDownload = download
+		}
+		tier.pendingCount++
+		if tier.pendingCount < tier.factor {
+			return
+		}
+
+		// Bucket complete: store it and pass it on to the next tier
+		upload, download = tier.pendingUpload, tier.pendingDownload
+		tier.push(upload, download)
+		tier.pendingCount = 0
+		tier.pendingUpload = 0
+		tier.pendingDownload = 0
+	}
+}
+
+// push appends a completed bucket, dropping the oldest when at capacity
+func (t *historyTier) push(upload, download uint64) {
+	if len(t.upload) >= t.capacity {
+		copy(t.upload, t.upload[1:])
+		copy(t.download, t.download[1:])
+		t.upload = t.upload[:len(t.upload)-1]
+		t.download = t.download[:len(t.download)-1]
+	}
+	t.upload = append(t.upload, upload)
+	t.download = append(t.download, download)
+	t.total++
+}
+
+// tierFor returns the coarsest tier whose resolution evenly divides a column
+// of windowSize raw samples, or nil if none does
+func (h *tieredHistory) tierFor(windowSize int) *historyTier {
+	for i := len(h.tiers) - 1; i >= 0; i-- {
+		if windowSize%h.tiers[i].resolution == 0 {
+			return h.tiers[i]
+		}
+	}
+	return nil
+}
+
+// columns aggregates a tier into right-aligned per-column maxima.
+// Columns are aligned on absolute bucket indices so they don't shift as data scrolls.
+func (t *historyTier) columns(bucketsPerColumn int, upload, download []uint64) {
+	for i := range upload {
+		upload[i] = 0
+		download[i] = 0
+	}
+
+	stored := len(t.upload)
+	if stored == 0 || len(upload) == 0 {
+		return
+	}
+
+	// Absolute index of the oldest stored bucket; the partial pending bucket is
+	// not shown so completed columns never change after being drawn
+	firstAbsolute := t.total - stored
+	lastColumn := (t.total - 1) / bucketsPerColumn
+	width := len(upload)
+
+	for i := 0; i < stored; i++ {
+		column := (firstAbsolute + i) / bucketsPerColumn
+		x := width - 1 - (lastColumn - column)
+		if x < 0 {
+			continue
+		}
+		if t.upload[i] > upload[x] {
+			upload[x] = t.upload[i]
+		}
+		if t.download[i] > download[x] {
+			download[x] = t.download[i]
+		}
+	}
+}
+
+// reset clears all tiers
+func (h *tieredHistory) reset() {
+	for _, tier := range h.tiers {
+		tier.upload = tier.upload[:0]
+		tier.download = tier.download[:0]
+		tier.total = 0
+		tier.pendingCount = 0
+		tier.pendingUpload = 0
+		tier.pendingDownload = 0
+	}
+}
@@ -0,0 +1,72 @@
+// Package chart provides the time axis drawn under braille charts
+package chart
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeAxisNow labels the newest column of the time axis
+const timeAxisNow = "now"
+
+// RenderTimeAxis renders a single row the width of the chart (and its
+// gutters) labelling how long ago its columns were sampled, e.g.
+// "-20s  -10s  now", spaced by RulerStep for the current time scale.
+// interval is the time between samples.
+func (bc *BrailleChart) RenderTimeAxis(interval time.Duration) string {
+	column := bc.columnDuration(interval)
+	step := bc.RulerStep(interval)
+	cellWidth := bc.CellWidth()
+
+	// Labels are right-aligned to their column, newest first, and dropped
+	// once they would run into the previous one or off the left edge
+	row := []byte(strings.Repeat(" ", bc.width*cellWidth))
+	next := len(row) // first column taken by the label to the right
+	for k := 0; ; k++ {
+		label := timeAxisNow
+		ago := time.Duration(k) * step
+		if k > 0 {
+			label = formatAgo(ago)
+		}
+		x := bc.width - 1 - int((ago+column/2)/column)
+		if x < 0 {
+			break
+		}
+		end := (x + 1) * cellWidth
+		start := end - len(label)
+		if start < 0 {
+			break
+		}
+		if k > 0 && end >= next {
+			continue
+		}
+		copy(row[start:end], label)
+		next = start
+	}
+
+	var axis strings.Builder
+	if bc.hasLeftGutter() {
+		axis.WriteString(strings.Repeat(" ", gutterLabelWidth+1))
+	}
+	axis.Write(row)
+	if bc.hasRightGutter() {
+		axis.WriteString(strings.Repeat(" ", gutterLabelWidth+1))
+	}
+	if bc.plainOutput {
+		return axis.String()
+	}
+	pair := bc.ansi.background[0]
+	return pair.prefix + axis.String() + pair.suffix
+}
+
+// formatAgo writes a time before now in its largest whole unit, e.g. "-5m"
+func formatAgo(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("-%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("-%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("-%ds", d/time.Second)
+}
@@ -1,12 +1,18 @@
 // Package chart provides braille chart rendering functionality
 package chart
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 const (
 	// Chart configuration constants
 	MinChartHeight = 8                 // Minimum chart height in rows
+	MinChartWidth  = 8                 // Minimum chart width in columns, for narrow split panes
 	brailleDots    = 4                 // Braille has 4 vertical dots per character
+	blockEighths   = 8                 // Block elements fill a character in eighths
 	brailleBase    = 0x2800            // Base braille character code
 	maxScaleLimit  = 100 * 1024 * 1024 // 100MB/s maximum scale
 
@@ -30,6 +36,15 @@ const (
 	ScalingSquareRoot
 )
 
+// GlyphSet defines which characters cells are drawn with
+type GlyphSet int
+
+const (
+	GlyphBraille GlyphSet = iota // Unicode braille patterns (highest resolution)
+	GlyphBlock                   // Unicode block elements, for fonts without braille
+	GlyphASCII                   // Plain ASCII, for non-UTF-8 terminals
+)
+
 // TimeScale defines the time window for data display
 type TimeScale int
 
@@ -41,6 +56,10 @@ const (
 	TimeScale15Min                  // 15 minutes (900 seconds)
 	TimeScale30Min                  // 30 minutes (1800 seconds)
 	TimeScale60Min                  // 60 minutes (3600 seconds)
+	TimeScale3Hour                  // 3 hours (10800 seconds), served from tiered history
+	TimeScale6Hour                  // 6 hours (21600 seconds), served from tiered history
+	TimeScale12Hour                 // 12 hours (43200 seconds), served from tiered history
+	TimeScale24Hour                 // 24 hours (86400 seconds), served from tiered history
 )
 
 // ColorGradient represents a color gradient configuration
@@ -48,10 +67,13 @@ type ColorGradient struct {
 	Steps []lipgloss.Color
 }
 
-// DataPoint represents a single measurement point
+// DataPoint represents a single measurement point, as exported by ExportData
 type DataPoint struct {
-	Upload   uint64
-	Download uint64
+	Time     time.Time `json:"t"`
+	Upload   uint64    `json:"up"`
+	Download uint64    `json:"down"`
+	// Gap marks an interrupted interval, which has no rates
+	Gap bool `json:"gap,omitempty"`
 }
 
 // Optimization: pre-calculated dot patterns as package constants
@@ -0,0 +1,269 @@
+// Package config loads and saves persisted preferences
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the preferences read from config.toml. Empty fields keep the
+// built-in defaults.
+type Config struct {
+	DisplayMode   string        // split, overlay or side-by-side
+	Scaling       string        // linear, log or sqrt
+	TimeScale     string        // 1m … 24h
+	Interval      time.Duration // Sample interval
+	Interfaces    []string      // Interfaces to chart (default: all but loopback)
+	UploadColor   string        // #RRGGBB color of the upload half
+	DownloadColor string        // #RRGGBB color of the download half
+	Theme         string        // Chart color theme, e.g. gruvbox
+	AlertUp       string        // Upload rate that raises an alert, e.g. 10MB/s
+	AlertDown     string        // Download rate that raises an alert
+	Quota         string        // Data cap tracked against the ledger, e.g. 500GB
+	QuotaPeriod   string        // monthly (billing cycle) or daily
+	Prefixes      string        // Byte prefixes: binary, iec or si
+
+	// #RRGGBB gradient stops, from the top of the chart to the axis
+	UploadGradient   []string
+	DownloadGradient []string
+	OverlapGradient  []string
+}
+
+// DefaultPath returns config.toml in the user's config directory
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "peaks", "config.toml"), nil
+}
+
+// Load reads the config file at path; a missing file is an empty Config
+func Load(path string) (Config, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+	cfg, err := Parse(file)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s:%w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse reads the flat subset of TOML peaks writes: key = value lines,
+// where values are "strings", numbers, booleans or ["string", …] arrays,
+// and # comments. Unknown keys are errors, so typos don't go unnoticed.
+func Parse(r io.Reader) (Config, error) {
+	var cfg Config
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(stripComment(scanner.Text()))
+		if text == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return cfg, fmt.Errorf("%d: expected key = value", line)
+		}
+		if err := cfg.set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return cfg, fmt.Errorf("%d: %w", line, err)
+		}
+	}
+	return cfg, scanner.Err()
+}
+
+// set assigns one key's raw TOML value
+func (c *Config) set(key, value string) error {
+	var err error
+	switch key {
+	case "display_mode":
+		c.DisplayMode, err = parseString(value)
+	case "scaling":
+		c.Scaling, err = parseString(value)
+	case "time_scale":
+		c.TimeScale, err = parseString(value)
+	case "interval":
+		var s string
+		if s, err = parseString(value); err == nil {
+			if c.Interval, err = time.ParseDuration(s); err == nil && c.Interval <= 0 {
+				err = fmt.Errorf("interval must be positive")
+			}
+		}
+	case "interfaces":
+		c.Interfaces, err = parseStrings(value)
+	case "upload_color":
+		c.UploadColor, err = parseColor(value)
+	case "download_color":
+		c.DownloadColor, err = parseColor(value)
+	case "theme":
+		c.Theme, err = parseString(value)
+	case "upload_gradient":
+		c.UploadGradient, err = parseColors(value)
+	case "download_gradient":
+		c.DownloadGradient, err = parseColors(value)
+	case "overlap_gradient":
+		c.OverlapGradient, err = parseColors(value)
+	case "alert_up":
+		c.AlertUp, err = parseString(value)
+	case "alert_down":
+		c.AlertDown, err = parseString(value)
+	case "quota":
+		c.Quota, err = parseString(value)
+	case "quota_period":
+		c.QuotaPeriod, err = parseString(value)
+	case "prefixes":
+		c.Prefixes, err = parseString(value)
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// stripComment drops a # comment that isn't inside a string
+func stripComment(line string) string {
+	inString := false
+	for i, r := range line {
+		switch {
+		case r == '"' && (i == 0 || line[i-1] != '\\'):
+			inString = !inString
+		case r == '#' && !inString:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseString parses a quoted TOML string
+func parseString(value string) (string, error) {
+	s, err := strconv.Unquote(value)
+	if err != nil || !strings.HasPrefix(value, `"`) {
+		return "", fmt.Errorf("expected a quoted string, got %s", value)
+	}
+	return s, nil
+}
+
+// parseStrings parses a TOML array of strings
+func parseStrings(value string) ([]string, error) {
+	inner, ok := strings.CutPrefix(value, "[")
+	if inner, ok = strings.CutSuffix(inner, "]"); !ok {
+		return nil, fmt.Errorf("expected an array of strings, got %s", value)
+	}
+	var values []string
+	for _, item := range strings.Split(inner, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue // Trailing comma
+		}
+		s, err := parseString(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// parseColor parses a quoted #RRGGBB color
+func parseColor(value string) (string, error) {
+	s, err := parseString(value)
+	if err != nil {
+		return "", err
+	}
+	return s, checkColor(s)
+}
+
+// parseColors parses a non-empty TOML array of #RRGGBB colors
+func parseColors(value string) ([]string, error) {
+	colors, err := parseStrings(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("expected at least one color")
+	}
+	for _, color := range colors {
+		if err := checkColor(color); err != nil {
+			return nil, err
+		}
+	}
+	return colors, nil
+}
+
+// checkColor checks that s is a #RRGGBB color
+func checkColor(s string) error {
+	if _, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32); err != nil || len(s) != 7 || s[0] != '#' {
+		return fmt.Errorf("expected a #RRGGBB color, got %q", s)
+	}
+	return nil
+}
+
+// Write writes the config as TOML, leaving out unset fields
+func (c Config) Write(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# peaks preferences; press W in peaks to save the current settings here\n")
+	line := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s = %s\n", key, strconv.Quote(value))
+		}
+	}
+	array := func(key string, values []string) {
+		if len(values) > 0 {
+			quoted := make([]string, len(values))
+			for i, value := range values {
+				quoted[i] = strconv.Quote(value)
+			}
+			fmt.Fprintf(&b, "%s = [%s]\n", key, strings.Join(quoted, ", "))
+		}
+	}
+	line("display_mode", c.DisplayMode)
+	line("scaling", c.Scaling)
+	line("time_scale", c.TimeScale)
+	if c.Interval > 0 {
+		line("interval", c.Interval.String())
+	}
+	array("interfaces", c.Interfaces)
+	line("upload_color", c.UploadColor)
+	line("download_color", c.DownloadColor)
+	line("theme", c.Theme)
+	array("upload_gradient", c.UploadGradient)
+	array("download_gradient", c.DownloadGradient)
+	array("overlap_gradient", c.OverlapGradient)
+	line("alert_up", c.AlertUp)
+	line("alert_down", c.AlertDown)
+	line("quota", c.Quota)
+	line("quota_period", c.QuotaPeriod)
+	line("prefixes", c.Prefixes)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Save writes the config to path through a temporary file, creating its
+// directory if needed
+func Save(path string, c Config) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	if err := c.Write(&b); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
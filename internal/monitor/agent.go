@@ -0,0 +1,261 @@
+// Package monitor provides the peaks agent protocol, streaming samples from
+// one peaks to another over TCP
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceAgentPrefix names a peaks agent to chart, as peaks://HOST[:PORT]
+const SourceAgentPrefix = "peaks://"
+
+// DefaultAgentPort is the port peaks agent listens on unless told otherwise
+const DefaultAgentPort = "7070"
+
+// agentRetry is how long AgentCollector waits before reconnecting
+const agentRetry = 2 * time.Second
+
+// agentWriteTimeout is how long a client may stall before the agent drops it
+const agentWriteTimeout = time.Second
+
+// agentClientBuffer is how many samples wait for a slow client before newer
+// ones are dropped for it
+const agentClientBuffer = 16
+
+// AgentHello is the first line an agent sends on every connection
+type AgentHello struct {
+	Host   string `json:"host"`
+	Source string `json:"source"`
+}
+
+// AgentSample is one sample as streamed by an agent, one JSON object per
+// line. Gap marks an interrupted interval, which carries no rates.
+type AgentSample struct {
+	Time     time.Time `json:"t"`
+	Upload   uint64    `json:"up"`
+	Download uint64    `json:"down"`
+	Gap      bool      `json:"gap,omitempty"`
+}
+
+// IsAgentSource reports whether a source names a peaks agent
+func IsAgentSource(source string) bool {
+	return strings.HasPrefix(source, SourceAgentPrefix)
+}
+
+// AgentAddress returns the TCP address of an agent, adding the default port
+// when none is given
+func AgentAddress(target string) (string, error) {
+	target = strings.TrimPrefix(target, SourceAgentPrefix)
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, DefaultAgentPort)
+	}
+	if host, port, err := net.SplitHostPort(target); err != nil || host == "" || port == "" {
+		return "", fmt.Errorf("invalid agent address %q (expected HOST[:PORT])", target)
+	}
+	return target, nil
+}
+
+// AgentServer streams samples to every connected client as JSON Lines. It
+// only ever sends, so a client can't change anything on the agent's host.
+type AgentServer struct {
+	hello []byte
+
+	mu      sync.Mutex
+	clients map[net.Conn]chan []byte
+}
+
+// NewAgentServer creates a server introducing itself as host's source
+func NewAgentServer(host, source string) *AgentServer {
+	hello, _ := json.Marshal(AgentHello{Host: host, Source: source})
+	return &AgentServer{
+		hello:   append(hello, '\n'),
+		clients: make(map[net.Conn]chan []byte),
+	}
+}
+
+// Serve accepts clients until the listener is closed
+func (s *AgentServer) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		lines := make(chan []byte, agentClientBuffer)
+		s.mu.Lock()
+		s.clients[conn] = lines
+		s.mu.Unlock()
+		go s.send(conn, lines)
+	}
+}
+
+// send writes the hello and then the client's queued samples, dropping the
+// client once it stalls or goes away
+func (s *AgentServer) send(conn net.Conn, lines <-chan []byte) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	line := s.hello
+	for {
+		conn.SetWriteDeadline(time.Now().Add(agentWriteTimeout))
+		if _, err := conn.Write(line); err != nil {
+			return
+		}
+		line = <-lines
+	}
+}
+
+// Publish queues a sample for every client without waiting on any of them.
+// A client whose queue is full misses the sample, so a stalled one delays
+// neither the sampling loop nor the others.
+func (s *AgentServer) Publish(sample AgentSample) {
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, lines := range s.clients {
+		select {
+		case lines <- line:
+		default:
+		}
+	}
+}
+
+// Clients returns how many clients are connected
+func (s *AgentServer) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// AgentCollector charts the samples streamed by a peaks agent on another
+// machine or container. It reconnects in the background whenever the
+// connection drops. Sample averages the samples that arrived since the
+// previous call, so ticks that drift against the agent's don't show as
+// spikes or dips.
+type AgentCollector struct {
+	addr string
+
+	mu       sync.Mutex
+	host     string
+	sum      [2]uint64
+	count    int
+	last     [2]uint64
+	received time.Time
+	gap      bool
+	err      error
+	series   []Series
+}
+
+// NewAgentCollector starts streaming from the agent named by source
+func NewAgentCollector(source string) (*AgentCollector, error) {
+	addr, err := AgentAddress(source)
+	if err != nil {
+		return nil, err
+	}
+	c := &AgentCollector{
+		addr: addr,
+		series: []Series{
+			{Name: SeriesUpload},
+			{Name: SeriesDownload},
+		},
+	}
+	go c.run()
+	return c, nil
+}
+
+// run keeps a connection to the agent open
+func (c *AgentCollector) run() {
+	for {
+		err := c.stream()
+		c.mu.Lock()
+		c.err = fmt.Errorf("agent %s: %v", c.addr, err)
+		c.mu.Unlock()
+		time.Sleep(agentRetry)
+	}
+}
+
+// stream reads samples until the connection ends
+func (c *AgentCollector) stream() error {
+	conn, err := net.DialTimeout("tcp", c.addr, MaxSampleInterval)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		return fmt.Errorf("no greeting (is this a peaks agent?)")
+	}
+	var hello AgentHello
+	if err := json.Unmarshal(scanner.Bytes(), &hello); err != nil || hello.Source == "" {
+		return fmt.Errorf("unexpected greeting (is this a peaks agent?)")
+	}
+	c.mu.Lock()
+	c.host, c.err = hello.Host, nil
+	// The interval spanning the reconnect is unknown
+	c.gap = true
+	c.mu.Unlock()
+
+	for scanner.Scan() {
+		var sample AgentSample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			continue
+		}
+		c.mu.Lock()
+		c.received = time.Now()
+		if sample.Gap {
+			c.gap = true
+		} else {
+			c.sum[0] += sample.Upload
+			c.sum[1] += sample.Download
+			c.count++
+		}
+		c.mu.Unlock()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("connection closed")
+}
+
+// Sample implements Collector. It holds the last rates while none are due,
+// and reports a gap when the agent has gone quiet.
+func (c *AgentCollector) Sample() ([]Series, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.gap {
+		c.gap = false
+		c.sum, c.count = [2]uint64{}, 0
+		return nil, ErrSampleGap
+	}
+	if c.count > 0 {
+		c.last = [2]uint64{c.sum[0] / uint64(c.count), c.sum[1] / uint64(c.count)}
+		c.sum, c.count = [2]uint64{}, 0
+	} else if time.Since(c.received) > MaxSampleInterval {
+		return nil, ErrSampleGap
+	}
+	c.series[0].Value = c.last[0]
+	c.series[1].Value = c.last[1]
+	return c.series, nil
+}
+
+// Host returns the agent's host name, once connected
+func (c *AgentCollector) Host() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.host
+}
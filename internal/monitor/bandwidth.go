@@ -1,23 +1,58 @@
 // Package monitor provides cross-platform bandwidth monitoring functionality
 //
 // This package provides bandwidth monitoring capabilities using the gopsutil
-// library to gather network interface statistics across different platforms.
+// library (or /proc/net/dev directly on Linux) to gather network interface
+// statistics across different platforms.
 package monitor
 
 import (
-	"fmt"
+	"errors"
+	"io"
+	"strings"
 	"time"
-
-	"github.com/shirou/gopsutil/v4/net"
 )
 
 // BandwidthMonitor handles cross-platform bandwidth monitoring
 type BandwidthMonitor struct {
-	lastStats    map[string]net.IOCountersStat
+	source       CounterSource
+	interfaces   map[string]*interfaceState
+	filter       func(name string) bool
+	skip         func(name []byte) bool // bound skipInterface, created once
 	lastTime     time.Time
+	now          func() time.Time
 	currentRates BandwidthRates
-	// Optimization: reuse slice to avoid allocations
-	statsBuffer  []net.IOCountersStat
+	// Share of the totals carried by tunnel interfaces (VPNs)
+	tunnelRates BandwidthRates
+	tunnels     bool
+	// Interface groups; when set, the first group is reported as the totals
+	groups     []InterfaceGroup
+	groupRates []GroupRates
+	// Interface the rates are narrowed to ("" for all), and the rates of
+	// everything monitored regardless of it
+	focus    string
+	allRates BandwidthRates
+	// Errors and drops of the interfaces in currentRates
+	errorStats ErrorStats
+	// Optimization: reuse buffers to avoid allocations
+	counters []InterfaceCounters
+	series   []Series
+	// Sample generation, used to prune interfaces that disappeared
+	generation uint64
+	// Local day of the last sample, to reset the interfaces' daily totals
+	day int
+}
+
+// interfaceState tracks the previous counters of one interface
+type interfaceState struct {
+	last       InterfaceCounters
+	filtered   bool   // excluded by the interface filter; its counters are not parsed
+	tunnel     bool   // a VPN or tunnel interface, see IsTunnelInterface
+	groups     []int  // indices of the interface groups it belongs to
+	generation uint64 // last sample generation this interface was seen in
+	totals     InterfaceTotals
+	rates      BandwidthRates // rates over the last sample interval
+	// Error and drop counters when first seen, so totals count this session
+	baseErrors, baseDrops uint64
 }
 
 // BandwidthRates represents current upload/download rates
@@ -26,13 +61,32 @@ type BandwidthRates struct {
 	Download uint64 // bytes per second
 }
 
-// NewBandwidthMonitor creates a new bandwidth monitor
+// ErrorStats are the receive and transmit errors and drops of the
+// interfaces in the current rates
+type ErrorStats struct {
+	Errors    uint64  // since start
+	Drops     uint64  // since start
+	ErrorRate float64 // per second over the last sample interval
+	DropRate  float64
+}
+
+// NewBandwidthMonitor creates a new bandwidth monitor using the platform's default counter source
 func NewBandwidthMonitor() *BandwidthMonitor {
+	return NewBandwidthMonitorWithSource(defaultCounterSource())
+}
+
+// NewBandwidthMonitorWithSource creates a new bandwidth monitor reading counters from source
+func NewBandwidthMonitorWithSource(source CounterSource) *BandwidthMonitor {
 	monitor := &BandwidthMonitor{
-		lastStats:   make(map[string]net.IOCountersStat),
-		lastTime:    time.Now(),
-		statsBuffer: make([]net.IOCountersStat, 0, 10), // Pre-allocate for typical interface count
+		source:     source,
+		interfaces: make(map[string]*interfaceState, 16),
+		filter:     DefaultInterfaceFilter,
+		lastTime:   time.Now(),
+		now:        time.Now,
+		counters:   make([]InterfaceCounters, 0, 16), // Pre-allocate for typical interface count
+		series:     make([]Series, 2),
 	}
+	monitor.skip = monitor.skipInterface
 
 	// Initialize with first reading
 	monitor.updateStats()
@@ -40,7 +94,157 @@ func NewBandwidthMonitor() *BandwidthMonitor {
 	return monitor
 }
 
-// GetCurrentRates returns the current upload and download rates
+// Close releases the counter source, such as an SSH session, when it holds one
+func (bm *BandwidthMonitor) Close() error {
+	if closer, ok := bm.source.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// DefaultInterfaceFilter accepts every interface except loopback
+func DefaultInterfaceFilter(name string) bool {
+	return name != "lo" && name != "Loopback"
+}
+
+// tunnelPrefixes name the interfaces VPNs and tunnels create: WireGuard,
+// OpenVPN and other tun/tap users, macOS utun, IPsec and Tailscale
+var tunnelPrefixes = []string{"wg", "tun", "tap", "utun", "ipsec", "tailscale"}
+
+// IsTunnelInterface reports whether an interface carries tunnelled (VPN) traffic
+func IsTunnelInterface(name string) bool {
+	for _, prefix := range tunnelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetInterfaceFilter sets which interfaces are included in the totals.
+// The filter is evaluated once per interface name and the decision cached.
+func (bm *BandwidthMonitor) SetInterfaceFilter(filter func(name string) bool) {
+	if filter == nil {
+		filter = DefaultInterfaceFilter
+	}
+	bm.filter = filter
+	for name, state := range bm.interfaces {
+		state.filtered = !filter(name)
+	}
+}
+
+// SetInterfaceGroups sums interfaces into named groups. Only group members are
+// monitored; the first group becomes the upload/download totals and every
+// group is also reported as its own pair of series.
+func (bm *BandwidthMonitor) SetInterfaceGroups(groups []InterfaceGroup) {
+	bm.groups = groups
+	bm.groupRates = make([]GroupRates, len(groups))
+	bm.series = make([]Series, 2, 2+2*len(groups))
+	for i, group := range groups {
+		bm.groupRates[i].Name = group.Name
+		bm.series = append(bm.series,
+			Series{Name: group.Name + " " + SeriesUpload},
+			Series{Name: group.Name + " " + SeriesDownload})
+	}
+
+	if len(groups) == 0 {
+		bm.SetInterfaceFilter(nil)
+	} else {
+		bm.SetInterfaceFilter(func(name string) bool {
+			return bm.groupsOf(name) != nil
+		})
+	}
+	for name, state := range bm.interfaces {
+		state.groups = bm.groupsOf(name)
+	}
+}
+
+// groupsOf returns the indices of the groups an interface belongs to
+func (bm *BandwidthMonitor) groupsOf(name string) []int {
+	var indices []int
+	for i, group := range bm.groups {
+		if group.Contains(name) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// SetFocus narrows the reported rates to a single interface, or back to all
+// monitored interfaces for "". Every interface keeps being sampled, so the
+// switch takes effect from the next sample.
+func (bm *BandwidthMonitor) SetFocus(name string) {
+	bm.focus = name
+}
+
+// Focus returns the interface the rates are narrowed to, or ""
+func (bm *BandwidthMonitor) Focus() string {
+	return bm.focus
+}
+
+// AllRates returns the current rates of every monitored interface (or the
+// first group), ignoring the focus
+func (bm *BandwidthMonitor) AllRates() BandwidthRates {
+	return bm.allRates
+}
+
+// InterfaceRates returns the current rates of one monitored interface, and
+// whether it is monitored; unlike the totals they ignore the focus
+func (bm *BandwidthMonitor) InterfaceRates(name string) (BandwidthRates, bool) {
+	state, ok := bm.interfaces[name]
+	if !ok || state.filtered {
+		return BandwidthRates{}, false
+	}
+	return state.rates, true
+}
+
+// GroupRates returns the current rates of each interface group
+func (bm *BandwidthMonitor) GroupRates() []GroupRates {
+	return bm.groupRates
+}
+
+// SetClock overrides the clock used to time samples (nil restores time.Now)
+func (bm *BandwidthMonitor) SetClock(clock func() time.Time) {
+	if clock == nil {
+		clock = time.Now
+	}
+	bm.now = clock
+	bm.lastTime = clock()
+}
+
+// Links returns the link status of the interfaces included in the totals
+func (bm *BandwidthMonitor) Links() ([]LinkStatus, error) {
+	statuses, err := LinkStatuses()
+	if err != nil {
+		return nil, err
+	}
+	included := statuses[:0]
+	for _, status := range statuses {
+		if bm.filter(status.Name) {
+			included = append(included, status)
+		}
+	}
+	return included, nil
+}
+
+// TunnelRates returns the part of the current rates carried by tunnel
+// interfaces, and whether any tunnel interface is being monitored.
+//
+// Tunnelled traffic is counted twice in the totals: once on the tunnel and
+// again, encrypted, on the physical interface carrying it. Traffic that went
+// direct is therefore roughly the totals minus twice the tunnel rates.
+func (bm *BandwidthMonitor) TunnelRates() (BandwidthRates, bool) {
+	return bm.tunnelRates, bm.tunnels
+}
+
+// ErrorStats returns the errors and drops of the interfaces in the current
+// rates: the counts since start and their rates over the last interval
+func (bm *BandwidthMonitor) ErrorStats() ErrorStats {
+	return bm.errorStats
+}
+
+// GetCurrentRates returns the current upload and download rates.
+// After an interrupted interval it returns zero rates and ErrSampleGap.
 func (bm *BandwidthMonitor) GetCurrentRates() (uint64, uint64, error) {
 	err := bm.updateStats()
 	if err != nil {
@@ -50,63 +254,182 @@ func (bm *BandwidthMonitor) GetCurrentRates() (uint64, uint64, error) {
 	return bm.currentRates.Upload, bm.currentRates.Download, nil
 }
 
+// Sample implements Collector, reporting upload and download rates
+func (bm *BandwidthMonitor) Sample() ([]Series, error) {
+	upload, download, err := bm.GetCurrentRates()
+	if err != nil && !errors.Is(err, ErrSampleGap) {
+		return nil, err
+	}
+
+	bm.series[0] = Series{Name: SeriesUpload, Value: upload}
+	bm.series[1] = Series{Name: SeriesDownload, Value: download}
+	for i, group := range bm.groupRates {
+		bm.series[2+2*i].Value = group.Upload
+		bm.series[3+2*i].Value = group.Download
+	}
+	return bm.series, err
+}
+
+// skipInterface reports whether an interface's counters need not be parsed.
+// Indexing the map with a converted byte slice does not allocate.
+func (bm *BandwidthMonitor) skipInterface(name []byte) bool {
+	if state, exists := bm.interfaces[string(name)]; exists {
+		return state.filtered
+	}
+	return false
+}
+
 // updateStats fetches new network statistics and calculates rates
 func (bm *BandwidthMonitor) updateStats() error {
 	// Get network interface statistics
-	stats, err := net.IOCounters(true) // true = per interface
+	counters, err := bm.source.ReadCounters(bm.counters[:0], bm.skip)
 	if err != nil {
-		return fmt.Errorf("failed to get network stats: %w", err)
+		return err
 	}
+	bm.counters = counters
 
-	currentTime := time.Now()
+	currentTime := bm.now()
 	timeDiff := currentTime.Sub(bm.lastTime).Seconds()
 
+	// After a suspend or clock jump the byte delta spans an unknown interval:
+	// re-baseline every interface and report the gap instead of a spike
+	gap := sampleGap(bm.lastTime, currentTime)
+
+	// The first reading only takes the baseline, whatever the interval
+	baseline := bm.generation == 0
+
 	// Skip if time difference is too small to avoid division by zero
-	if timeDiff < 0.01 {
+	if timeDiff < 0.01 && !gap && !baseline {
 		return nil
 	}
 
 	var totalUpload, totalDownload uint64
+	var allUpload, allDownload uint64
+	var tunnelUpload, tunnelDownload uint64
+	tunnels := false
+	var errorStats ErrorStats
+	var newErrors, newDrops uint64
+	for i := range bm.groupRates {
+		bm.groupRates[i].BandwidthRates = BandwidthRates{}
+	}
+
+	if day := dayKey(currentTime); day != bm.day {
+		bm.day = day
+		for _, state := range bm.interfaces {
+			state.totals.TodayUpload, state.totals.TodayDownload = 0, 0
+		}
+	}
 
 	// Optimization: calculate rates more efficiently
 	timeDiffRecip := 1.0 / timeDiff // Calculate reciprocal once
+	bm.generation++
 
 	// Calculate rates for all interfaces
-	for _, stat := range stats {
-		// Skip loopback interfaces
-		if stat.Name == "lo" || stat.Name == "Loopback" {
+	for _, stat := range counters {
+		state, exists := bm.interfaces[stat.Name]
+		if !exists {
+			state = &interfaceState{
+				filtered: !bm.filter(stat.Name),
+				tunnel:   IsTunnelInterface(stat.Name),
+				groups:   bm.groupsOf(stat.Name),
+				totals:   InterfaceTotals{Name: stat.Name},
+			}
+			state.baseErrors, state.baseDrops = stat.Errors, stat.Drops
+			bm.interfaces[stat.Name] = state
+		}
+		state.generation = bm.generation
+		if state.filtered {
 			continue
 		}
+		tunnels = tunnels || state.tunnel
 
-		if lastStat, exists := bm.lastStats[stat.Name]; exists {
-			// Calculate bytes transferred since last measurement
-			bytesSent := stat.BytesSent - lastStat.BytesSent
-			bytesRecv := stat.BytesRecv - lastStat.BytesRecv
+		// With groups configured only the first group feeds the totals;
+		// a focused interface replaces them
+		counted := len(bm.groups) == 0 || (len(state.groups) > 0 && state.groups[0] == 0)
+		charted := (bm.focus == "" && counted) || stat.Name == bm.focus
 
-			// Handle counter rollover (unlikely with 64-bit counters)
-			if stat.BytesSent < lastStat.BytesSent {
-				bytesSent = stat.BytesSent
-			}
-			if stat.BytesRecv < lastStat.BytesRecv {
-				bytesRecv = stat.BytesRecv
-			}
+		state.rates = BandwidthRates{}
+		if exists && !gap {
+			lastStat := state.last
+
+			// Calculate bytes transferred since last measurement,
+			// accounting for 32-bit counter wraparound
+			bytesSent := counterDelta(stat.BytesSent, lastStat.BytesSent)
+			bytesRecv := counterDelta(stat.BytesRecv, lastStat.BytesRecv)
 
 			// Convert to rate (bytes per second) - use reciprocal for efficiency
 			uploadRate := uint64(float64(bytesSent) * timeDiffRecip)
 			downloadRate := uint64(float64(bytesRecv) * timeDiffRecip)
+			state.totals.addTraffic(bytesSent, bytesRecv, uploadRate, downloadRate)
+			state.rates = BandwidthRates{Upload: uploadRate, Download: downloadRate}
+
+			for _, g := range state.groups {
+				bm.groupRates[g].Upload += uploadRate
+				bm.groupRates[g].Download += downloadRate
+			}
+
+			if counted {
+				allUpload += uploadRate
+				allDownload += downloadRate
+			}
+			if charted {
+				totalUpload += uploadRate
+				totalDownload += downloadRate
+				if state.tunnel {
+					tunnelUpload += uploadRate
+					tunnelDownload += downloadRate
+				}
+			}
+		}
 
-			totalUpload += uploadRate
-			totalDownload += downloadRate
+		// Counters that went backwards were reset with the interface
+		previousErrors, previousDrops := state.totals.Errors, state.totals.Drops
+		if stat.Errors < state.baseErrors || stat.Drops < state.baseDrops {
+			state.baseErrors, state.baseDrops = 0, 0
+			previousErrors, previousDrops = 0, 0
+		}
+		state.totals.Errors = stat.Errors - state.baseErrors
+		state.totals.Drops = stat.Drops - state.baseDrops
+		if charted {
+			errorStats.Errors += state.totals.Errors
+			errorStats.Drops += state.totals.Drops
+			if exists && !gap {
+				newErrors += state.totals.Errors - previousErrors
+				newDrops += state.totals.Drops - previousDrops
+			}
 		}
 
 		// Update last stats
-		bm.lastStats[stat.Name] = stat
+		state.last = stat
+	}
+
+	// Forget interfaces that disappeared (e.g. removed veth pairs); filtered
+	// ones are still listed by name, so they are pruned the same way
+	if len(bm.interfaces) > len(counters) {
+		for name, state := range bm.interfaces {
+			if state.generation != bm.generation {
+				delete(bm.interfaces, name)
+			}
+		}
 	}
 
 	// Update current rates
 	bm.currentRates.Upload = totalUpload
 	bm.currentRates.Download = totalDownload
+	bm.allRates.Upload = allUpload
+	bm.allRates.Download = allDownload
+	bm.tunnelRates.Upload = tunnelUpload
+	bm.tunnelRates.Download = tunnelDownload
+	bm.tunnels = tunnels
+	if !gap && !baseline {
+		errorStats.ErrorRate = float64(newErrors) * timeDiffRecip
+		errorStats.DropRate = float64(newDrops) * timeDiffRecip
+	}
+	bm.errorStats = errorStats
 	bm.lastTime = currentTime
 
+	if gap {
+		return ErrSampleGap
+	}
 	return nil
 }
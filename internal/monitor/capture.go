@@ -0,0 +1,170 @@
+// Package monitor provides packet capture parsing
+package monitor
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrCaptureUnsupported is returned by StartCapture on platforms without a
+// packet capture backend
+var ErrCaptureUnsupported = errors.New("packet capture is not supported on this platform")
+
+// PacketInfo summarizes the headers of one captured IP packet
+type PacketInfo struct {
+	Outgoing bool  // Sent by this host rather than received
+	Length   int   // Bytes on the wire, including the link-layer header
+	Version  uint8 // IP version, 4 or 6
+	DSCP     uint8 // Differentiated Services code point (0-63)
+	Protocol uint8 // IP protocol number, e.g. 6 for TCP
+	Src, Dst netip.Addr
+	SrcPort  uint16 // TCP/UDP ports; zero for other protocols
+	DstPort  uint16
+}
+
+// SharedCapture runs a single packet capture for several consumers, such as
+// the DSCP, protocol and process breakdowns, passing each packet to all of
+// them. The capture starts when the first consumer is added.
+type SharedCapture struct {
+	iface    string
+	mu       sync.Mutex
+	capture  *Capture
+	handlers atomic.Pointer[[]func(*PacketInfo)]
+}
+
+// NewSharedCapture prepares a capture on iface (every interface if empty)
+func NewSharedCapture(iface string) *SharedCapture {
+	return &SharedCapture{iface: iface}
+}
+
+// Add passes every captured packet to handle from now on, starting the
+// capture if needed
+func (s *SharedCapture) Add(handle func(*PacketInfo)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var handlers []func(*PacketInfo)
+	if current := s.handlers.Load(); current != nil {
+		handlers = append(handlers, *current...)
+	}
+	handlers = append(handlers, handle)
+	s.handlers.Store(&handlers)
+	if s.capture != nil {
+		return nil
+	}
+	capture, err := StartCapture(s.iface, s.dispatch)
+	if err != nil {
+		s.handlers.Store(nil)
+		return err
+	}
+	s.capture = capture
+	return nil
+}
+
+// dispatch passes a packet to every consumer
+func (s *SharedCapture) dispatch(p *PacketInfo) {
+	for _, handle := range *s.handlers.Load() {
+		handle(p)
+	}
+}
+
+// Close stops the capture, if it was started
+func (s *SharedCapture) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.capture == nil {
+		return nil
+	}
+	err := s.capture.Close()
+	s.capture = nil
+	return err
+}
+
+// EtherType values understood by ParseEthernetFrame
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86DD
+	etherTypeVLAN = 0x8100
+	etherTypeQinQ = 0x88A8
+)
+
+// IP protocol numbers whose ports are parsed
+const (
+	ProtocolICMP   = 1
+	ProtocolTCP    = 6
+	ProtocolUDP    = 17
+	ProtocolICMPv6 = 58
+)
+
+// ParseEthernetFrame parses an Ethernet frame carrying IP, skipping any VLAN
+// tags. It reports false for non-IP or truncated frames.
+func ParseEthernetFrame(frame []byte, p *PacketInfo) bool {
+	if len(frame) < 14 {
+		return false
+	}
+	offset := 12
+	etherType := binary.BigEndian.Uint16(frame[offset:])
+	for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+		offset += 4
+		if len(frame) < offset+2 {
+			return false
+		}
+		etherType = binary.BigEndian.Uint16(frame[offset:])
+	}
+	if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+		return false
+	}
+	if !ParseIPPacket(frame[offset+2:], p) {
+		return false
+	}
+	p.Length = len(frame)
+	return true
+}
+
+// ParseIPPacket parses a raw IPv4 or IPv6 packet, as seen on tun and ppp
+// interfaces. It reports false for anything else or truncated headers.
+func ParseIPPacket(packet []byte, p *PacketInfo) bool {
+	if len(packet) < 1 {
+		return false
+	}
+
+	var payload []byte
+	switch packet[0] >> 4 {
+	case 4:
+		headerLen := int(packet[0]&0x0f) * 4
+		if headerLen < 20 || len(packet) < headerLen {
+			return false
+		}
+		p.Version = 4
+		p.DSCP = packet[1] >> 2
+		p.Protocol = packet[9]
+		p.Src = netip.AddrFrom4([4]byte(packet[12:16]))
+		p.Dst = netip.AddrFrom4([4]byte(packet[16:20]))
+		// Only the first fragment carries the transport header
+		if binary.BigEndian.Uint16(packet[6:])&0x1fff == 0 {
+			payload = packet[headerLen:]
+		}
+	case 6:
+		if len(packet) < 40 {
+			return false
+		}
+		p.Version = 6
+		p.DSCP = uint8(binary.BigEndian.Uint16(packet[0:]) >> 6 & 0x3f)
+		p.Protocol = packet[6]
+		p.Src = netip.AddrFrom16([16]byte(packet[8:24]))
+		p.Dst = netip.AddrFrom16([16]byte(packet[24:40]))
+		payload = packet[40:]
+	default:
+		return false
+	}
+
+	p.SrcPort, p.DstPort = 0, 0
+	if (p.Protocol == ProtocolTCP || p.Protocol == ProtocolUDP) && len(payload) >= 4 {
+		p.SrcPort = binary.BigEndian.Uint16(payload[0:])
+		p.DstPort = binary.BigEndian.Uint16(payload[2:])
+	}
+	p.Length = len(packet)
+	return true
+}
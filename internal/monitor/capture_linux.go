@@ -0,0 +1,138 @@
+//go:build linux
+
+// Package monitor provides packet capture through AF_PACKET sockets on Linux
+package monitor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// Capture reads packets from a raw AF_PACKET socket
+type Capture struct {
+	fd     int
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+// htons converts a 16-bit value to network byte order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+// capNetRaw is the CAP_NET_RAW capability bit
+const capNetRaw = 13
+
+// CheckCapturePrivileges reports whether this process may open a capture
+// socket, i.e. runs as root or holds CAP_NET_RAW, so a missing privilege is
+// caught before the UI starts
+func CheckCapturePrivileges() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err == nil && caps&(1<<capNetRaw) != 0 {
+			return nil
+		}
+		break
+	}
+	return errors.New("packet capture requires root or CAP_NET_RAW (run with sudo or setcap cap_net_raw+ep on the binary)")
+}
+
+// StartCapture captures packets on iface (every interface if empty) and
+// passes each IP packet to handle from a background goroutine. The
+// PacketInfo is reused between calls. Capturing requires root or CAP_NET_RAW.
+func StartCapture(iface string, handle func(*PacketInfo)) (*Capture, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, int(htons(syscall.ETH_P_ALL)))
+	if err != nil {
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+			return nil, fmt.Errorf("packet capture requires root or CAP_NET_RAW: %w", err)
+		}
+		return nil, fmt.Errorf("failed to open capture socket: %w", err)
+	}
+
+	if iface != "" {
+		ifi, err := net.InterfaceByName(iface)
+		if err != nil {
+			syscall.Close(fd)
+			return nil, err
+		}
+		addr := &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ALL), Ifindex: ifi.Index}
+		if err := syscall.Bind(fd, addr); err != nil {
+			syscall.Close(fd)
+			return nil, fmt.Errorf("failed to bind capture to %s: %w", iface, err)
+		}
+	}
+
+	// Wake up periodically so Close can stop the reader
+	timeout := syscall.Timeval{Usec: 500000}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	c := &Capture{fd: fd, done: make(chan struct{})}
+	go c.read(handle)
+	return c, nil
+}
+
+// read delivers packets until the capture is closed
+func (c *Capture) read(handle func(*PacketInfo)) {
+	defer close(c.done)
+	defer syscall.Close(c.fd)
+
+	buf := make([]byte, 65536)
+	var info PacketInfo
+	for !c.closed.Load() {
+		n, from, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return
+		}
+		link, ok := from.(*syscall.SockaddrLinklayer)
+		if !ok || link.Hatype == syscall.ARPHRD_LOOPBACK {
+			continue
+		}
+
+		// Ethernet-like links carry a link-layer header; tun, ppp and
+		// WireGuard interfaces deliver bare IP packets
+		frame := buf[:n]
+		if link.Hatype == syscall.ARPHRD_ETHER {
+			ok = ParseEthernetFrame(frame, &info)
+		} else {
+			ok = ParseIPPacket(frame, &info)
+		}
+		if !ok {
+			continue
+		}
+		info.Outgoing = link.Pkttype == syscall.PACKET_OUTGOING
+		handle(&info)
+	}
+}
+
+// Close stops the capture and waits for the reader to exit
+func (c *Capture) Close() error {
+	c.closed.Store(true)
+	<-c.done
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+// Package monitor provides the packet capture fallback for other platforms
+package monitor
+
+// Capture is a packet capture; only Linux has a backend
+type Capture struct{}
+
+// StartCapture reports ErrCaptureUnsupported outside Linux
+func StartCapture(iface string, handle func(*PacketInfo)) (*Capture, error) {
+	return nil, ErrCaptureUnsupported
+}
+
+// CheckCapturePrivileges reports ErrCaptureUnsupported outside Linux
+func CheckCapturePrivileges() error {
+	return ErrCaptureUnsupported
+}
+
+// Close implements the Linux Capture API
+func (c *Capture) Close() error {
+	return nil
+}
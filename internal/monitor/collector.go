@@ -0,0 +1,117 @@
+// Package monitor provides data collection functionality
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Conventional series names used by the built-in collectors
+const (
+	SeriesUpload   = "upload"
+	SeriesDownload = "download"
+)
+
+// Source names accepted by NewCollector
+const (
+	SourceNetwork = "net"
+	SourceDisk    = "disk"
+	SourceCPU     = "cpu"
+	// SourceWindowsHost charts the Windows host's adapters from inside WSL
+	SourceWindowsHost = "winhost"
+	// SourceDemo generates synthetic traffic instead of reading interfaces
+	SourceDemo = "demo"
+	// SourceStdin charts values piped in on stdin
+	SourceStdin = "stdin"
+	// SourcePrometheus charts PromQL query results; it is configured by
+	// NewPrometheusCollector rather than NewCollector
+	SourcePrometheus = "prometheus"
+	// SourceSNMP charts a router or switch interface polled over SNMP; it is
+	// configured by NewSNMPSource rather than NewCollector
+	SourceSNMP = "snmp"
+)
+
+// Series represents a single named rate reported by a collector
+type Series struct {
+	Name  string // Human-readable label, e.g. "upload" or "download"
+	Value uint64 // Rate in units per second
+}
+
+// Collector is a source of rate samples that can feed the chart.
+//
+// Implementations return the rates observed since the previous call to
+// Sample. By convention the first series is drawn below the axis (upload)
+// and the second above it (download), so any dual-series source such as a
+// packet capture, SNMP poller or synthetic generator plugs in uniformly.
+type Collector interface {
+	Sample() ([]Series, error)
+}
+
+// NewCollector creates the built-in collector for the named source
+func NewCollector(source string) (Collector, error) {
+	switch source {
+	case SourceNetwork, "":
+		return NewBandwidthMonitor(), nil
+	case SourceDisk:
+		return NewDiskMonitor(), nil
+	case SourceCPU:
+		return NewCPUMonitor(), nil
+	case SourceDemo:
+		return NewDemoCollector(1), nil
+	case SourceStdin:
+		return NewStdinCollector(os.Stdin), nil
+	case SourceWindowsHost:
+		src, err := NewWindowsHostSource()
+		if err != nil {
+			return nil, err
+		}
+		monitor := NewBandwidthMonitorWithSource(src)
+		// Hyper-V virtual switches relay the VM's traffic through a physical
+		// adapter, so counting them too would double count it
+		monitor.SetInterfaceFilter(func(name string) bool {
+			return !strings.HasPrefix(name, "vEthernet")
+		})
+		return monitor, nil
+	default:
+		if IsFirewallSource(source) {
+			return NewFirewallMonitor(source)
+		}
+		if IsSSHSource(source) {
+			src, err := NewSSHSource(source)
+			if err != nil {
+				return nil, err
+			}
+			return NewBandwidthMonitorWithSource(src), nil
+		}
+		if IsAgentSource(source) {
+			return NewAgentCollector(source)
+		}
+		return nil, fmt.Errorf("unknown source %q (expected %s, %s, %s, %s, %s, %s, %s..., %s..., %s... or user@host)", source,
+			SourceNetwork, SourceDisk, SourceCPU, SourceWindowsHost, SourceDemo, SourceStdin, SourceNftPrefix, SourceIptablesPrefix, SourceAgentPrefix)
+	}
+}
+
+// SplitSeries returns the first two series values as an upload/download pair.
+// Missing series are reported as zero.
+func SplitSeries(series []Series) (upload, download uint64) {
+	if len(series) > 0 {
+		upload = series[0].Value
+	}
+	if len(series) > 1 {
+		download = series[1].Value
+	}
+	return upload, download
+}
+
+// Compile-time checks that the built-in collectors satisfy Collector
+var (
+	_ Collector = (*BandwidthMonitor)(nil)
+	_ Collector = (*DiskMonitor)(nil)
+	_ Collector = (*CPUMonitor)(nil)
+	_ Collector = (*FirewallMonitor)(nil)
+	_ Collector = (*DemoCollector)(nil)
+	_ Collector = (*StdinCollector)(nil)
+	_ Collector = (*PrometheusCollector)(nil)
+	_ Collector = (*AgentCollector)(nil)
+)
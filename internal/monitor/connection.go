@@ -0,0 +1,145 @@
+// Package monitor provides per-connection traffic rates
+package monitor
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ConnectionKey identifies a TCP or UDP flow by its local port and the
+// remote end
+type ConnectionKey struct {
+	Protocol  uint8
+	LocalPort uint16
+	Remote    netip.AddrPort
+}
+
+// ProtocolName names the connection's protocol, "tcp" or "udp"
+func (k ConnectionKey) ProtocolName() string {
+	if k.Protocol == ProtocolTCP {
+		return "tcp"
+	}
+	return "udp"
+}
+
+// ConnectionRates are the current rates of one connection and the process
+// owning its local socket
+type ConnectionRates struct {
+	ConnectionKey
+	Process
+	BandwidthRates
+}
+
+// ConnectionStats tallies captured TCP and UDP traffic per connection, like
+// iftop, naming the process behind each one as ProcessStats does. Add is
+// safe to call from the capture goroutine while Rates is called from the UI.
+type ConnectionStats struct {
+	mu       sync.Mutex
+	bytes    map[ConnectionKey]*[2]uint64 // cumulative upload, download
+	last     map[ConnectionKey][2]uint64
+	lastTime time.Time
+	owners   socketOwners
+	now      func() time.Time
+}
+
+// NewConnectionStats creates an empty per-connection tally using the
+// platform's socket owner table
+func NewConnectionStats() *ConnectionStats {
+	return &ConnectionStats{
+		bytes:    make(map[ConnectionKey]*[2]uint64),
+		last:     make(map[ConnectionKey][2]uint64),
+		lastTime: time.Now(),
+		owners:   socketOwners{resolve: readSocketOwners},
+		now:      time.Now,
+	}
+}
+
+// SetOwnerResolver replaces the socket owner lookup and clock (for testing)
+func (s *ConnectionStats) SetOwnerResolver(resolve func() (map[SocketKey]Process, error), now func() time.Time) {
+	s.owners.resolve = resolve
+	s.now = now
+	s.lastTime = now()
+}
+
+// Add counts a captured packet against its connection; it matches
+// StartCapture's handler signature
+func (s *ConnectionStats) Add(p *PacketInfo) {
+	if p.Protocol != ProtocolTCP && p.Protocol != ProtocolUDP {
+		return
+	}
+	key := ConnectionKey{Protocol: p.Protocol, LocalPort: p.DstPort, Remote: netip.AddrPortFrom(p.Src, p.SrcPort)}
+	direction := 1
+	if p.Outgoing {
+		key.LocalPort, key.Remote, direction = p.SrcPort, netip.AddrPortFrom(p.Dst, p.DstPort), 0
+	}
+	s.mu.Lock()
+	counts, ok := s.bytes[key]
+	if !ok {
+		counts = new([2]uint64)
+		s.bytes[key] = counts
+	}
+	counts[direction] += uint64(p.Length)
+	s.mu.Unlock()
+}
+
+// Rates returns the per-connection rates since the previous call, ordered by
+// the given sort, busiest first. Connections without traffic are left out,
+// and forgotten so closed ones don't pile up.
+func (s *ConnectionStats) Rates(order ProcessSort) ([]ConnectionRates, error) {
+	s.mu.Lock()
+	current := make(map[ConnectionKey][2]uint64, len(s.bytes))
+	for key, counts := range s.bytes {
+		if *counts == s.last[key] {
+			delete(s.bytes, key)
+			continue
+		}
+		current[key] = *counts
+	}
+	s.mu.Unlock()
+
+	now := s.now()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	if elapsed <= 0 {
+		return nil, nil
+	}
+
+	connections := make([]ConnectionRates, 0, len(current))
+	for key, counts := range current {
+		previous := s.last[key]
+		connections = append(connections, ConnectionRates{
+			ConnectionKey: key,
+			Process:       s.owners.lookup(SocketKey{Protocol: key.Protocol, Port: key.LocalPort}, now),
+			BandwidthRates: BandwidthRates{
+				Upload:   uint64(float64(counts[0]-previous[0]) / elapsed),
+				Download: uint64(float64(counts[1]-previous[1]) / elapsed),
+			},
+		})
+	}
+	s.last = current
+	s.lastTime = now
+
+	sortConnections(connections, order)
+	return connections, s.owners.err
+}
+
+// sortConnections orders connections busiest first, ties by remote address
+func sortConnections(connections []ConnectionRates, order ProcessSort) {
+	value := func(c ConnectionRates) uint64 {
+		switch order {
+		case SortByDownload:
+			return c.Download
+		case SortByUpload:
+			return c.Upload
+		default:
+			return c.Upload + c.Download
+		}
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		if a, b := value(connections[i]), value(connections[j]); a != b {
+			return a > b
+		}
+		return connections[i].Remote.String() < connections[j].Remote.String()
+	})
+}
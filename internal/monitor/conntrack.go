@@ -0,0 +1,57 @@
+// Package monitor provides conntrack table usage sampling
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConntrackDir holds the Linux connection tracking table counters
+const ConntrackDir = "/proc/sys/net/netfilter"
+
+// ConntrackStatus is the size of the connection tracking table and its limit.
+// A full table silently drops new connections, which shows up as traffic that
+// stalls while bandwidth looks fine.
+type ConntrackStatus struct {
+	Count uint64
+	Max   uint64
+}
+
+// Usage returns the fraction of the table in use, from 0 to 1
+func (s ConntrackStatus) Usage() float64 {
+	if s.Max == 0 {
+		return 0
+	}
+	return float64(s.Count) / float64(s.Max)
+}
+
+// ReadConntrack reads the table counters from dir (normally ConntrackDir).
+// It fails where connection tracking is unavailable, e.g. without the
+// nf_conntrack module or on other platforms.
+func ReadConntrack(dir string) (ConntrackStatus, error) {
+	count, err := readProcUint(filepath.Join(dir, "nf_conntrack_count"))
+	if err != nil {
+		return ConntrackStatus{}, err
+	}
+	max, err := readProcUint(filepath.Join(dir, "nf_conntrack_max"))
+	if err != nil {
+		return ConntrackStatus{}, err
+	}
+	return ConntrackStatus{Count: count, Max: max}, nil
+}
+
+// readProcUint reads a single unsigned integer from a proc file
+func readProcUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unreadable %s: %w", path, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,194 @@
+// Package monitor provides interface counter sources
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// InterfaceCounters holds the cumulative byte counters of one network interface
+type InterfaceCounters struct {
+	Name      string
+	BytesSent uint64
+	BytesRecv uint64
+	Errors    uint64 // receive and transmit errors
+	Drops     uint64 // receive and transmit drops
+}
+
+// CounterSource reads cumulative per-interface counters.
+//
+// ReadCounters appends one entry per interface to dst (reusing its capacity)
+// and may skip parsing interfaces for which skip returns true, though they
+// are still listed by name so the caller can tell when they disappear.
+type CounterSource interface {
+	ReadCounters(dst []InterfaceCounters, skip func(name []byte) bool) ([]InterfaceCounters, error)
+}
+
+// defaultCounterSource returns the cheapest counter source for this platform
+func defaultCounterSource() CounterSource {
+	if runtime.GOOS == "linux" {
+		if src, err := NewProcNetDevSource("/proc/net/dev"); err == nil {
+			return src
+		}
+	}
+	return gopsutilSource{}
+}
+
+// gopsutilSource reads counters through gopsutil (all platforms)
+type gopsutilSource struct{}
+
+// ReadCounters implements CounterSource
+func (gopsutilSource) ReadCounters(dst []InterfaceCounters, skip func(name []byte) bool) ([]InterfaceCounters, error) {
+	stats, err := net.IOCounters(true) // true = per interface
+	if err != nil {
+		return dst, fmt.Errorf("failed to get network stats: %w", err)
+	}
+	for _, stat := range stats {
+		dst = append(dst, InterfaceCounters{
+			Name:      stat.Name,
+			BytesSent: stat.BytesSent,
+			BytesRecv: stat.BytesRecv,
+			Errors:    stat.Errin + stat.Errout,
+			Drops:     stat.Dropin + stat.Dropout,
+		})
+	}
+	return dst, nil
+}
+
+// ProcNetDevSource reads counters from a Linux /proc/net/dev formatted file,
+// keeping the file open and reusing its read buffer between samples
+type ProcNetDevSource struct {
+	file *os.File
+	buf  []byte
+}
+
+// NewProcNetDevSource opens a /proc/net/dev formatted file
+func NewProcNetDevSource(path string) (*ProcNetDevSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ProcNetDevSource{file: file, buf: make([]byte, 0, 4096)}, nil
+}
+
+// ReadCounters implements CounterSource
+func (p *ProcNetDevSource) ReadCounters(dst []InterfaceCounters, skip func(name []byte) bool) ([]InterfaceCounters, error) {
+	// procfs regenerates the file on every read from offset 0
+	p.buf = p.buf[:0]
+	for {
+		if len(p.buf) == cap(p.buf) {
+			p.buf = append(p.buf, 0)[:len(p.buf)]
+		}
+		n, err := p.file.ReadAt(p.buf[len(p.buf):cap(p.buf)], int64(len(p.buf)))
+		p.buf = p.buf[:len(p.buf)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dst, fmt.Errorf("failed to read network stats: %w", err)
+		}
+	}
+	return ParseProcNetDev(p.buf, dst, skip), nil
+}
+
+// ParseProcNetDev parses /proc/net/dev formatted data, appending counters to dst.
+// Existing names in dst's backing array are reused so steady-state parsing does
+// not allocate; interfaces for which skip returns true are listed by name with
+// zero counters and not parsed further.
+func ParseProcNetDev(data []byte, dst []InterfaceCounters, skip func(name []byte) bool) []InterfaceCounters {
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			data = nil
+		}
+
+		// Interface lines look like "  eth0: rx_bytes rx_packets ... tx_bytes ..."
+		colon := bytes.IndexByte(line, ':')
+		if colon < 0 {
+			continue // header line
+		}
+		name := bytes.TrimSpace(line[:colon])
+		if skip != nil && skip(name) {
+			dst = append(dst, InterfaceCounters{Name: reuseName(dst, name)})
+			continue
+		}
+
+		var fields [12]uint64 // rx: bytes packets errs drop fifo frame compressed multicast, tx: bytes packets errs drop
+		rest := line[colon+1:]
+		parsed := 0
+		for parsed < len(fields) {
+			rest = bytes.TrimLeft(rest, " ")
+			if len(rest) == 0 {
+				break
+			}
+			end := bytes.IndexByte(rest, ' ')
+			if end < 0 {
+				end = len(rest)
+			}
+			fields[parsed] = parseDecimal(rest[:end])
+			rest = rest[end:]
+			parsed++
+		}
+		if parsed < len(fields) {
+			continue // malformed line
+		}
+
+		var entry InterfaceCounters
+		entry.Name = reuseName(dst, name)
+		entry.BytesRecv = fields[0]
+		entry.BytesSent = fields[8]
+		entry.Errors = fields[2] + fields[10]
+		entry.Drops = fields[3] + fields[11]
+		dst = append(dst, entry)
+	}
+	return dst
+}
+
+// reuseName returns the previous name string at the next position in dst's
+// backing array when it matches, so steady-state parsing does not allocate
+func reuseName(dst []InterfaceCounters, name []byte) string {
+	if len(dst) < cap(dst) {
+		if prev := dst[:len(dst)+1][len(dst)]; prev.Name == string(name) {
+			return prev.Name
+		}
+	}
+	return string(name)
+}
+
+// parseDecimal parses an unsigned decimal counter without allocating.
+// Non-digit bytes end the number.
+func parseDecimal(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return n
+}
+
+// counterDelta returns how far a cumulative counter advanced since last.
+//
+// Some drivers expose 32-bit counters that wrap every 4 GiB. A backwards step
+// from a value that fits in 32 bits, by more than half the 32-bit range, is
+// treated as such a wrap. Any other backwards step is a counter reset (e.g. a
+// re-created interface) whose delta is unknown, so it reports zero rather than
+// a spike.
+func counterDelta(current, last uint64) uint64 {
+	if current >= last {
+		return current - last
+	}
+	if last <= math.MaxUint32 && last-current > math.MaxUint32/2 {
+		return current + (math.MaxUint32 + 1) - last
+	}
+	return 0
+}
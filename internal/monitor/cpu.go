@@ -0,0 +1,91 @@
+// Package monitor provides CPU usage monitoring functionality
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+)
+
+// Series names reported by CPUMonitor
+const (
+	SeriesCPUSystem = "system"
+	SeriesCPUUser   = "user"
+)
+
+// CPUMonitor samples aggregate CPU usage across all cores.
+//
+// Values are reported as CPU microseconds consumed per wall-clock second, so a
+// single fully busy core reads 1,000,000 and the chart scaling behaves the
+// same way it does for byte rates.
+type CPUMonitor struct {
+	lastTimes cpu.TimesStat
+	lastTime  time.Time
+	primed    bool
+	series    []Series
+}
+
+// NewCPUMonitor creates a new CPU usage monitor
+func NewCPUMonitor() *CPUMonitor {
+	monitor := &CPUMonitor{
+		lastTime: time.Now(),
+		series: []Series{
+			{Name: SeriesCPUSystem},
+			{Name: SeriesCPUUser},
+		},
+	}
+
+	// Initialize with first reading
+	monitor.Sample()
+
+	return monitor
+}
+
+// Sample implements Collector, reporting system and user CPU time rates
+func (cm *CPUMonitor) Sample() ([]Series, error) {
+	times, err := cpu.Times(false) // false = aggregate over all CPUs
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cpu stats: %w", err)
+	}
+	if len(times) == 0 {
+		return nil, fmt.Errorf("failed to get cpu stats: no data")
+	}
+
+	currentTime := time.Now()
+	timeDiff := currentTime.Sub(cm.lastTime).Seconds()
+
+	// Skip if time difference is too small to avoid division by zero
+	if timeDiff < 0.01 {
+		return cm.series, nil
+	}
+
+	// Re-baseline after a suspend or clock jump rather than reporting a spike
+	gap := sampleGap(cm.lastTime, currentTime)
+
+	current := times[0]
+	if gap {
+		cm.series[0].Value = 0
+		cm.series[1].Value = 0
+	} else if cm.primed {
+		cm.series[0].Value = cpuRate(current.System+current.Irq+current.Softirq, cm.lastTimes.System+cm.lastTimes.Irq+cm.lastTimes.Softirq, timeDiff)
+		cm.series[1].Value = cpuRate(current.User+current.Nice, cm.lastTimes.User+cm.lastTimes.Nice, timeDiff)
+	}
+
+	cm.lastTimes = current
+	cm.lastTime = currentTime
+	cm.primed = true
+
+	if gap {
+		return cm.series, ErrSampleGap
+	}
+	return cm.series, nil
+}
+
+// cpuRate converts a CPU seconds delta into CPU microseconds per second
+func cpuRate(current, last, timeDiff float64) uint64 {
+	if current <= last {
+		return 0
+	}
+	return uint64((current - last) / timeDiff * 1e6)
+}
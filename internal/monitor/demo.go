@@ -0,0 +1,94 @@
+// Package monitor provides a synthetic traffic generator for demos
+package monitor
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// DemoPhase is one stage of the demo traffic pattern
+type DemoPhase int
+
+const (
+	DemoIdle       DemoPhase = iota // background chatter of a few KB/s
+	DemoBurst                       // short download spikes over idle traffic
+	DemoSustained                   // a steady large download
+	DemoSaturation                  // the download pinned at the link's capacity
+	DemoWave                        // a download swelling and ebbing like adaptive video
+	DemoRamp                        // an upload climbing steadily, like a backup starting up
+)
+
+// demoPhases is how many phases the pattern cycles through
+const demoPhases = 6
+
+// demoPhaseSamples is how many samples each phase lasts (30s at 500ms)
+const demoPhaseSamples = 60
+
+// demoWaveSamples is the period of the DemoWave sine wave (10s at 500ms)
+const demoWaveSamples = 20
+
+// demoLinkCapacity is the saturated download rate: a 100 Mb/s link
+const demoLinkCapacity = 100 * 1000 * 1000 / 8
+
+// DemoCollector generates synthetic network traffic that cycles through
+// idle, burst, sustained download, saturation, sine wave and ramp phases,
+// so each chart shape can be seen without real traffic. It never touches
+// real interfaces, and its output depends only on the seed and the number of
+// samples taken, so recordings and tests are reproducible.
+type DemoCollector struct {
+	rng     *rand.Rand
+	samples int
+	series  []Series
+}
+
+// NewDemoCollector creates a demo traffic generator
+func NewDemoCollector(seed uint64) *DemoCollector {
+	return &DemoCollector{
+		rng: rand.New(rand.NewPCG(seed, seed)),
+		series: []Series{
+			{Name: SeriesUpload},
+			{Name: SeriesDownload},
+		},
+	}
+}
+
+// Phase returns the phase the next sample belongs to
+func (d *DemoCollector) Phase() DemoPhase {
+	return DemoPhase(d.samples / demoPhaseSamples % demoPhases)
+}
+
+// Sample implements Collector, returning the next synthetic rates
+func (d *DemoCollector) Sample() ([]Series, error) {
+	upload, download := d.jitter(2*1024, 0.5), d.jitter(6*1024, 0.5)
+	switch d.Phase() {
+	case DemoBurst:
+		if d.rng.IntN(5) == 0 {
+			download = d.jitter(8*1024*1024, 0.6)
+			upload = download / 40 // acknowledgements
+		}
+	case DemoSustained:
+		download = d.jitter(6*1024*1024, 0.1)
+		upload = download / 40
+	case DemoSaturation:
+		download = d.jitter(demoLinkCapacity, 0.01)
+		download = min(download, demoLinkCapacity)
+		upload = download / 40
+	case DemoWave:
+		angle := 2 * math.Pi * float64(d.samples%demoWaveSamples) / demoWaveSamples
+		download = d.jitter(uint64(3*1024*1024*(1+0.8*math.Sin(angle))), 0.05)
+		upload = download / 40
+	case DemoRamp:
+		progress := float64(d.samples%demoPhaseSamples+1) / demoPhaseSamples
+		upload = d.jitter(uint64(4*1024*1024*progress), 0.05)
+		download = max(download, upload/40)
+	}
+	d.samples++
+	d.series[0].Value = upload
+	d.series[1].Value = download
+	return d.series, nil
+}
+
+// jitter returns base varied randomly by up to ±spread of itself
+func (d *DemoCollector) jitter(base uint64, spread float64) uint64 {
+	return uint64(float64(base) * (1 + spread*(2*d.rng.Float64()-1)))
+}
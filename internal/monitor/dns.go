@@ -0,0 +1,46 @@
+// Package monitor provides DNS resolution latency probing
+package monitor
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DefaultDNSTimeout bounds a single DNS probe
+const DefaultDNSTimeout = 5 * time.Second
+
+// DNSProbe times lookups of a host name against the system's configured
+// resolver. Slow DNS often masquerades as "slow internet" while bandwidth
+// looks fine.
+type DNSProbe struct {
+	host     string
+	timeout  time.Duration
+	resolver *net.Resolver
+}
+
+// NewDNSProbe creates a probe resolving host. The pure Go resolver is used so
+// every probe sends a real query to the resolvers in /etc/resolv.conf (or the
+// platform equivalent) instead of being answered by an in-process cache.
+func NewDNSProbe(host string) *DNSProbe {
+	return &DNSProbe{
+		host:     host,
+		timeout:  DefaultDNSTimeout,
+		resolver: &net.Resolver{PreferGo: true},
+	}
+}
+
+// Host returns the name being resolved
+func (p *DNSProbe) Host() string {
+	return p.host
+}
+
+// Probe resolves the host once and returns how long the lookup took
+func (p *DNSProbe) Probe() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.resolver.LookupHost(ctx, p.host)
+	return time.Since(start), err
+}
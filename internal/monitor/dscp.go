@@ -0,0 +1,95 @@
+// Package monitor provides DSCP (QoS class) traffic breakdown
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// dscpNames are the standard per-hop behaviour names (RFC 2474, 2597, 3246, 5865)
+var dscpNames = map[uint8]string{
+	0: "BE", 8: "CS1", 16: "CS2", 24: "CS3", 32: "CS4", 40: "CS5", 48: "CS6", 56: "CS7",
+	10: "AF11", 12: "AF12", 14: "AF13", 18: "AF21", 20: "AF22", 22: "AF23",
+	26: "AF31", 28: "AF32", 30: "AF33", 34: "AF41", 36: "AF42", 38: "AF43",
+	1: "LE", 44: "VA", 46: "EF",
+}
+
+// DSCPName returns the per-hop behaviour name of a code point, or its number
+func DSCPName(dscp uint8) string {
+	if name, ok := dscpNames[dscp]; ok {
+		return name
+	}
+	return fmt.Sprintf("DSCP%d", dscp)
+}
+
+// ClassRates are the current rates of one traffic class
+type ClassRates struct {
+	Name string
+	BandwidthRates
+}
+
+// DSCPStats tallies captured traffic by DSCP marking, so people running
+// SQM/QoS can verify their marking actually works. Add is safe to call from
+// the capture goroutine while Rates is called from the UI.
+type DSCPStats struct {
+	mu       sync.Mutex
+	bytes    [64][2]uint64 // cumulative bytes per code point: upload, download
+	last     [64][2]uint64
+	lastTime time.Time
+	rates    []ClassRates
+}
+
+// NewDSCPStats creates an empty DSCP tally
+func NewDSCPStats() *DSCPStats {
+	return &DSCPStats{lastTime: time.Now()}
+}
+
+// Add counts a captured packet; it matches StartCapture's handler signature
+func (d *DSCPStats) Add(p *PacketInfo) {
+	direction := 1
+	if p.Outgoing {
+		direction = 0
+	}
+	d.mu.Lock()
+	d.bytes[p.DSCP&0x3f][direction] += uint64(p.Length)
+	d.mu.Unlock()
+}
+
+// Rates returns the per-class rates since the previous call, busiest first.
+// Classes without traffic are left out.
+func (d *DSCPStats) Rates() []ClassRates {
+	d.mu.Lock()
+	current := d.bytes
+	d.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(d.lastTime).Seconds()
+	if elapsed < 0.01 {
+		return d.rates
+	}
+
+	d.rates = d.rates[:0]
+	for dscp := range current {
+		upload := current[dscp][0] - d.last[dscp][0]
+		download := current[dscp][1] - d.last[dscp][1]
+		if upload == 0 && download == 0 {
+			continue
+		}
+		d.rates = append(d.rates, ClassRates{
+			Name: DSCPName(uint8(dscp)),
+			BandwidthRates: BandwidthRates{
+				Upload:   uint64(float64(upload) / elapsed),
+				Download: uint64(float64(download) / elapsed),
+			},
+		})
+	}
+	sort.SliceStable(d.rates, func(i, j int) bool {
+		return d.rates[i].Upload+d.rates[i].Download > d.rates[j].Upload+d.rates[j].Download
+	})
+
+	d.last = current
+	d.lastTime = now
+	return d.rates
+}
@@ -0,0 +1,240 @@
+// Package monitor provides firewall rule counter collection
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source prefixes selecting firewall counters, e.g.
+//
+//	nft:filter/vlan10_out,filter/vlan10_in
+//	iptables:FORWARD/host-a-out,FORWARD/host-a-in
+const (
+	SourceNftPrefix      = "nft:"
+	SourceIptablesPrefix = "iptables:"
+)
+
+// firewallCounter reads the byte count of one configured counter
+type firewallCounter func() (uint64, error)
+
+// FirewallMonitor samples the rates of two firewall byte counters, so traffic
+// matching specific rules (a VLAN, a host behind a router) gets its own chart.
+// Reading the counters needs root or CAP_NET_ADMIN.
+type FirewallMonitor struct {
+	counters [2]firewallCounter
+	last     [2]uint64
+	lastTime time.Time
+	series   []Series
+}
+
+// IsFirewallSource reports whether a source names firewall counters
+func IsFirewallSource(source string) bool {
+	return strings.HasPrefix(source, SourceNftPrefix) || strings.HasPrefix(source, SourceIptablesPrefix)
+}
+
+// NewFirewallMonitor creates a monitor for a firewall source. The source lists
+// the upload counter then the download counter, separated by a comma:
+//
+//   - nft:TABLE/COUNTER names nftables named counters, in any address family
+//   - iptables:[TABLE.]CHAIN[/COMMENT] sums the chain's rule counters, or only
+//     the rules tagged with "-m comment --comment COMMENT"; TABLE defaults to filter
+func NewFirewallMonitor(source string) (*FirewallMonitor, error) {
+	var kind, list string
+	switch {
+	case strings.HasPrefix(source, SourceNftPrefix):
+		kind, list = "nft", strings.TrimPrefix(source, SourceNftPrefix)
+	case strings.HasPrefix(source, SourceIptablesPrefix):
+		kind, list = "iptables", strings.TrimPrefix(source, SourceIptablesPrefix)
+	default:
+		return nil, fmt.Errorf("unknown firewall source %q", source)
+	}
+
+	specs := strings.Split(list, ",")
+	if len(specs) != 2 {
+		return nil, fmt.Errorf("firewall source %q needs an upload and a download counter separated by a comma", source)
+	}
+
+	fm := &FirewallMonitor{series: make([]Series, 2)}
+	for i, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		counter, err := newFirewallCounter(kind, spec)
+		if err != nil {
+			return nil, err
+		}
+		fm.counters[i] = counter
+		fm.series[i].Name = spec
+	}
+
+	// Take the baseline now so a missing counter or privilege fails early
+	for i, counter := range fm.counters {
+		value, err := counter()
+		if err != nil {
+			return nil, err
+		}
+		fm.last[i] = value
+	}
+	fm.lastTime = time.Now()
+	return fm, nil
+}
+
+// newFirewallCounter parses one counter spec
+func newFirewallCounter(kind, spec string) (firewallCounter, error) {
+	if kind == "nft" {
+		table, name, ok := strings.Cut(spec, "/")
+		if !ok || table == "" || name == "" {
+			return nil, fmt.Errorf("nft counter %q should be TABLE/COUNTER", spec)
+		}
+		return func() (uint64, error) {
+			out, err := runFirewallCommand("nft", "-j", "list", "counters")
+			if err != nil {
+				return 0, err
+			}
+			return ParseNftCounter(out, table, name)
+		}, nil
+	}
+
+	chain, comment, _ := strings.Cut(spec, "/")
+	table := "filter"
+	if t, c, ok := strings.Cut(chain, "."); ok {
+		table, chain = t, c
+	}
+	if chain == "" {
+		return nil, fmt.Errorf("iptables counter %q should be [TABLE.]CHAIN[/COMMENT]", spec)
+	}
+	return func() (uint64, error) {
+		out, err := runFirewallCommand("iptables", "-w", "-t", table, "-L", chain, "-v", "-x", "-n")
+		if err != nil {
+			return 0, err
+		}
+		return ParseIptablesChain(out, comment)
+	}, nil
+}
+
+// runFirewallCommand runs a firewall tool, folding its stderr into the error
+func runFirewallCommand(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s (root or CAP_NET_ADMIN is required)", name, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+	return out, nil
+}
+
+// Sample implements Collector, reporting both counters' rates in bytes per second
+func (fm *FirewallMonitor) Sample() ([]Series, error) {
+	var current [2]uint64
+	for i, counter := range fm.counters {
+		value, err := counter()
+		if err != nil {
+			return nil, err
+		}
+		current[i] = value
+	}
+
+	currentTime := time.Now()
+	timeDiff := currentTime.Sub(fm.lastTime).Seconds()
+	if timeDiff < 0.01 {
+		return fm.series, nil
+	}
+	gap := sampleGap(fm.lastTime, currentTime)
+
+	for i := range current {
+		if gap {
+			fm.series[i].Value = 0
+		} else {
+			// Counters reset when rules are reloaded; counterDelta reports that as zero
+			fm.series[i].Value = uint64(float64(counterDelta(current[i], fm.last[i])) / timeDiff)
+		}
+		fm.last[i] = current[i]
+	}
+	fm.lastTime = currentTime
+
+	if gap {
+		return fm.series, ErrSampleGap
+	}
+	return fm.series, nil
+}
+
+// ParseNftCounter returns the byte count of a named counter in the output of
+// "nft -j list counters"
+func ParseNftCounter(data []byte, table, name string) (uint64, error) {
+	var listing struct {
+		Nftables []struct {
+			Counter *struct {
+				Table string `json:"table"`
+				Name  string `json:"name"`
+				Bytes uint64 `json:"bytes"`
+			} `json:"counter"`
+		} `json:"nftables"`
+	}
+	if err := json.Unmarshal(data, &listing); err != nil {
+		return 0, fmt.Errorf("unreadable nft output: %w", err)
+	}
+	for _, entry := range listing.Nftables {
+		if c := entry.Counter; c != nil && c.Table == table && c.Name == name {
+			return c.Bytes, nil
+		}
+	}
+	return 0, fmt.Errorf("nft counter %s/%s not found", table, name)
+}
+
+// ParseIptablesChain sums the byte counters in the output of
+// "iptables -L CHAIN -v -x -n". With a comment only the rules carrying that
+// comment are counted; without one the chain's policy counter is included too.
+func ParseIptablesChain(data []byte, comment string) (uint64, error) {
+	var total uint64
+	matched := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Chain FORWARD (policy ACCEPT 12 packets, 3456 bytes)
+		if fields[0] == "Chain" {
+			if comment != "" {
+				continue
+			}
+			for i := 1; i < len(fields); i++ {
+				if strings.TrimSuffix(fields[i], ")") == "bytes" {
+					if n, err := strconv.ParseUint(fields[i-1], 10, 64); err == nil {
+						total += n
+					}
+				}
+			}
+			continue
+		}
+
+		// pkts bytes target prot opt in out source destination [/* comment */]
+		if len(fields) < 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if comment != "" && !strings.Contains(line, "/* "+comment+" */") {
+			continue
+		}
+		total += n
+		matched = true
+	}
+	if comment != "" && !matched {
+		return 0, fmt.Errorf("no iptables rule with comment %q", comment)
+	}
+	return total, nil
+}
@@ -0,0 +1,29 @@
+// Package monitor provides detection of interrupted sample intervals
+package monitor
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrSampleGap is returned by Sample when the interval since the previous sample
+// was interrupted, e.g. by a system suspend or a wall-clock jump. The collector
+// has re-baselined its counters; callers should record a gap rather than a rate.
+var ErrSampleGap = errors.New("sample interval interrupted")
+
+// MaxSampleInterval is the longest interval over which a rate is still trusted
+const MaxSampleInterval = 5 * time.Second
+
+// sampleGap reports whether the interval between two readings was interrupted.
+//
+// Elapsed time is measured on the monotonic clock, which is immune to wall-clock
+// adjustments but (on Linux) stops while the system is suspended. Comparing it
+// with the wall-clock interval therefore exposes both suspends and clock jumps.
+func sampleGap(last, now time.Time) bool {
+	elapsed := now.Sub(last)
+	drift := now.Round(0).Sub(last.Round(0)) - elapsed
+	if drift < 0 {
+		drift = -drift
+	}
+	return elapsed > MaxSampleInterval || drift > MaxSampleInterval
+}
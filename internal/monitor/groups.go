@@ -0,0 +1,61 @@
+// Package monitor provides interface grouping
+package monitor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// InterfaceGroup sums the traffic of several interfaces under one name,
+// e.g. WAN=[ppp0] and LAN=[eth1,eth2,wlan0] on a home router
+type InterfaceGroup struct {
+	Name    string
+	Members []string // Interface names; shell patterns such as "eth*" are allowed
+}
+
+// GroupRates are the current rates of one interface group
+type GroupRates struct {
+	Name string
+	BandwidthRates
+}
+
+// ParseInterfaceGroup parses a "NAME=if1,if2" group definition
+func ParseInterfaceGroup(spec string) (InterfaceGroup, error) {
+	name, list, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return InterfaceGroup{}, fmt.Errorf("interface group %q should be NAME=IFACE[,IFACE...]", spec)
+	}
+
+	group := InterfaceGroup{Name: name}
+	for _, member := range strings.Split(list, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		if _, err := path.Match(member, ""); err != nil {
+			return InterfaceGroup{}, fmt.Errorf("interface group %s: bad pattern %q", name, member)
+		}
+		group.Members = append(group.Members, member)
+	}
+	if len(group.Members) == 0 {
+		return InterfaceGroup{}, fmt.Errorf("interface group %s has no interfaces", name)
+	}
+	return group, nil
+}
+
+// String formats the group in the form ParseInterfaceGroup accepts
+func (g InterfaceGroup) String() string {
+	return g.Name + "=" + strings.Join(g.Members, ",")
+}
+
+// Contains reports whether an interface belongs to the group
+func (g InterfaceGroup) Contains(name string) bool {
+	for _, member := range g.Members {
+		if matched, _ := path.Match(member, name); matched {
+			return true
+		}
+	}
+	return false
+}
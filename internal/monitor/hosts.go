@@ -0,0 +1,121 @@
+// Package monitor provides per-remote-host traffic rates
+package monitor
+
+import (
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HostRates are the current rates exchanged with one remote address
+type HostRates struct {
+	Addr netip.Addr
+	BandwidthRates
+}
+
+// HostStats tallies captured IP traffic per remote address, whatever the
+// protocol, for a top-talkers list. Add is safe to call from the capture
+// goroutine while Rates is called from the UI.
+type HostStats struct {
+	mu       sync.Mutex
+	bytes    map[netip.Addr]*[2]uint64 // cumulative upload, download
+	last     map[netip.Addr][2]uint64
+	lastTime time.Time
+	now      func() time.Time
+}
+
+// NewHostStats creates an empty per-host tally
+func NewHostStats() *HostStats {
+	return &HostStats{
+		bytes:    make(map[netip.Addr]*[2]uint64),
+		last:     make(map[netip.Addr][2]uint64),
+		lastTime: time.Now(),
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the clock used to work out rates (for testing)
+func (s *HostStats) SetClock(now func() time.Time) {
+	s.now = now
+	s.lastTime = now()
+}
+
+// Add counts a captured packet against its remote address; it matches
+// StartCapture's handler signature
+func (s *HostStats) Add(p *PacketInfo) {
+	remote, direction := p.Src, 1
+	if p.Outgoing {
+		remote, direction = p.Dst, 0
+	}
+	if !remote.IsValid() {
+		return
+	}
+	s.mu.Lock()
+	counts, ok := s.bytes[remote]
+	if !ok {
+		counts = new([2]uint64)
+		s.bytes[remote] = counts
+	}
+	counts[direction] += uint64(p.Length)
+	s.mu.Unlock()
+}
+
+// Rates returns the per-host rates since the previous call, ordered by the
+// given sort, busiest first. Hosts without traffic are left out, and
+// forgotten so the tally doesn't grow without bound.
+func (s *HostStats) Rates(order ProcessSort) []HostRates {
+	s.mu.Lock()
+	current := make(map[netip.Addr][2]uint64, len(s.bytes))
+	for addr, counts := range s.bytes {
+		if *counts == s.last[addr] {
+			delete(s.bytes, addr)
+			continue
+		}
+		current[addr] = *counts
+	}
+	s.mu.Unlock()
+
+	now := s.now()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+
+	hosts := make([]HostRates, 0, len(current))
+	for addr, counts := range current {
+		previous := s.last[addr]
+		hosts = append(hosts, HostRates{
+			Addr: addr,
+			BandwidthRates: BandwidthRates{
+				Upload:   uint64(float64(counts[0]-previous[0]) / elapsed),
+				Download: uint64(float64(counts[1]-previous[1]) / elapsed),
+			},
+		})
+	}
+	s.last = current
+	s.lastTime = now
+
+	sortHosts(hosts, order)
+	return hosts
+}
+
+// sortHosts orders hosts busiest first, ties by address
+func sortHosts(hosts []HostRates, order ProcessSort) {
+	value := func(h HostRates) uint64 {
+		switch order {
+		case SortByDownload:
+			return h.Download
+		case SortByUpload:
+			return h.Upload
+		default:
+			return h.Upload + h.Download
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if a, b := value(hosts[i]), value(hosts[j]); a != b {
+			return a > b
+		}
+		return hosts[i].Addr.Less(hosts[j].Addr)
+	})
+}
@@ -0,0 +1,117 @@
+// Package monitor provides an iperf3 client driver
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// IperfResult summarizes a finished iperf3 run
+type IperfResult struct {
+	Start    time.Time
+	Duration time.Duration
+	Sent     uint64 // Sender throughput in bytes per second
+	Received uint64 // Receiver throughput in bytes per second
+	Reverse  bool   // The server sent and peaks' host received (-R)
+}
+
+// IperfRunner drives the iperf3 client against a server
+type IperfRunner struct {
+	host    string
+	args    []string
+	command string
+}
+
+// NewIperfRunner creates a runner for the iperf3 server at host. Extra
+// arguments (e.g. "-R", "-t", "20") are passed through to iperf3.
+func NewIperfRunner(host string, args ...string) *IperfRunner {
+	return &IperfRunner{host: host, args: args, command: "iperf3"}
+}
+
+// Host returns the iperf3 server being tested against
+func (r *IperfRunner) Host() string {
+	return r.host
+}
+
+// Run executes one iperf3 test and returns its summary. It blocks for the
+// length of the test; traffic is charted live by the network collector.
+func (r *IperfRunner) Run() (IperfResult, error) {
+	path, err := exec.LookPath(r.command)
+	if err != nil {
+		return IperfResult{}, fmt.Errorf("iperf3 not found: %w", err)
+	}
+
+	args := append([]string{"-c", r.host, "-J"}, r.args...)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	start := time.Now()
+	runErr := cmd.Run()
+	result, err := ParseIperfJSON(stdout.Bytes())
+	if err != nil {
+		if runErr != nil {
+			return IperfResult{}, fmt.Errorf("iperf3 failed: %v %s", runErr, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return IperfResult{}, err
+	}
+	if result.Start.IsZero() {
+		result.Start = start
+	}
+	return result, nil
+}
+
+// iperfReport is the subset of iperf3's --json output peaks reads
+type iperfReport struct {
+	Start struct {
+		Timestamp struct {
+			Timesecs int64 `json:"timesecs"`
+		} `json:"timestamp"`
+		TestStart struct {
+			Reverse int `json:"reverse"`
+		} `json:"test_start"`
+	} `json:"start"`
+	End struct {
+		SumSent     iperfSum `json:"sum_sent"`
+		SumReceived iperfSum `json:"sum_received"`
+		// UDP tests report a single sum instead
+		Sum iperfSum `json:"sum"`
+	} `json:"end"`
+	Error string `json:"error"`
+}
+
+// iperfSum is one direction's totals in an iperf3 report
+type iperfSum struct {
+	Seconds       float64 `json:"seconds"`
+	BitsPerSecond float64 `json:"bits_per_second"`
+}
+
+// ParseIperfJSON extracts the summary from iperf3 --json output
+func ParseIperfJSON(data []byte) (IperfResult, error) {
+	var report iperfReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return IperfResult{}, fmt.Errorf("unreadable iperf3 report: %w", err)
+	}
+	if report.Error != "" {
+		return IperfResult{}, fmt.Errorf("iperf3: %s", report.Error)
+	}
+
+	sent, received := report.End.SumSent, report.End.SumReceived
+	if sent.Seconds == 0 && received.Seconds == 0 {
+		sent, received = report.End.Sum, report.End.Sum
+	}
+
+	result := IperfResult{
+		Duration: time.Duration(sent.Seconds * float64(time.Second)),
+		Sent:     uint64(sent.BitsPerSecond / 8),
+		Received: uint64(received.BitsPerSecond / 8),
+		Reverse:  report.Start.TestStart.Reverse != 0,
+	}
+	if secs := report.Start.Timestamp.Timesecs; secs > 0 {
+		result.Start = time.Unix(secs, 0)
+	}
+	return result, nil
+}
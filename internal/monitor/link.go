@@ -0,0 +1,18 @@
+// Package monitor provides interface link status and speed
+package monitor
+
+// LinkStatus describes the physical link of a network interface
+type LinkStatus struct {
+	Name  string
+	Up    bool   // carrier/media present; true when the platform cannot tell
+	Speed uint64 // negotiated speed in bits per second, 0 if unknown
+}
+
+// LinkStatuses returns the link status and negotiated speed of every interface.
+//
+// Linux reads /sys/class/net, the BSDs read the media status and baudrate the
+// kernel reports in the routing socket's interface list, and other platforms
+// fall back to the interface flags without speed information.
+func LinkStatuses() ([]LinkStatus, error) {
+	return linkStatuses()
+}
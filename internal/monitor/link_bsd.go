@@ -0,0 +1,53 @@
+//go:build freebsd || openbsd || netbsd
+
+package monitor
+
+import (
+	"net"
+	"runtime"
+	"syscall"
+)
+
+// linkStatuses reads each interface's media link state and baudrate from the
+// routing socket's interface list (the data ifconfig reports as "status" and
+// "media")
+func linkStatuses() ([]LinkStatus, error) {
+	rib, err := syscall.RouteRIB(syscall.NET_RT_IFLIST, 0)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseRoutingMessage(rib)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []LinkStatus
+	for _, msg := range msgs {
+		ifm, ok := msg.(*syscall.InterfaceMessage)
+		if !ok {
+			continue
+		}
+		iface, err := net.InterfaceByIndex(int(ifm.Header.Index))
+		if err != nil {
+			continue
+		}
+		statuses = append(statuses, LinkStatus{
+			Name:  iface.Name,
+			Up:    bsdLinkUp(int(ifm.Header.Data.Link_state)),
+			Speed: ifm.Header.Data.Baudrate,
+		})
+	}
+	return statuses, nil
+}
+
+// bsdLinkUp interprets a kernel LINK_STATE_* value. Interfaces without media
+// (loopback, tunnels) report LINK_STATE_UNKNOWN (0), which counts as up.
+func bsdLinkUp(state int) bool {
+	if runtime.GOOS == "openbsd" {
+		// INVALID (1), DOWN (2) and KALIVE_DOWN (3) are down; UP, HALF_DUPLEX
+		// and FULL_DUPLEX (4-6) are up
+		return state == 0 || state >= 4
+	}
+	// FreeBSD and NetBSD: UNKNOWN (0), DOWN (1), UP (2)
+	return state != 1
+}
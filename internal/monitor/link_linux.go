@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysClassNet is where Linux exposes per-interface link attributes
+const sysClassNet = "/sys/class/net"
+
+// linkStatuses reads operstate and speed (in Mbit/s) from sysfs
+func linkStatuses() ([]LinkStatus, error) {
+	entries, err := os.ReadDir(sysClassNet)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]LinkStatus, 0, len(entries))
+	for _, entry := range entries {
+		status := LinkStatus{Name: entry.Name(), Up: true}
+		dir := filepath.Join(sysClassNet, entry.Name())
+
+		// "unknown" is reported by interfaces without carrier detection (lo, tun)
+		if state, err := os.ReadFile(filepath.Join(dir, "operstate")); err == nil {
+			switch strings.TrimSpace(string(state)) {
+			case "down", "lowerlayerdown", "notpresent":
+				status.Up = false
+			}
+		}
+
+		// Reading speed fails with EINVAL while the link is down; -1 means unknown
+		if speed, err := os.ReadFile(filepath.Join(dir, "speed")); err == nil {
+			if mbps, err := strconv.ParseInt(strings.TrimSpace(string(speed)), 10, 64); err == nil && mbps > 0 {
+				status.Speed = uint64(mbps) * 1000000
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !freebsd && !openbsd && !netbsd
+
+package monitor
+
+import "net"
+
+// linkStatuses falls back to interface flags; negotiated speed is unavailable
+func linkStatuses() ([]LinkStatus, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]LinkStatus, 0, len(ifaces))
+	for _, iface := range ifaces {
+		statuses = append(statuses, LinkStatus{
+			Name: iface.Name,
+			Up:   iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagRunning != 0,
+		})
+	}
+	return statuses, nil
+}
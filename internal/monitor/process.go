@@ -0,0 +1,342 @@
+// Package monitor provides per-process traffic attribution
+package monitor
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProcessUnsupported is returned where sockets can't be mapped to processes
+var ErrProcessUnsupported = errors.New("per-process traffic is not supported on this platform")
+
+// ownerRefreshInterval is the longest the socket owner table is reused while
+// unattributed traffic keeps arriving
+const ownerRefreshInterval = 2 * time.Second
+
+// SocketKey identifies a local socket by protocol and port
+type SocketKey struct {
+	Protocol uint8
+	Port     uint16
+}
+
+// Process identifies a socket's owner
+type Process struct {
+	PID  int
+	Name string
+}
+
+// ProcessRates are the current rates of one process
+type ProcessRates struct {
+	Process
+	BandwidthRates
+}
+
+// ProcessSort orders ProcessStats.Rates
+type ProcessSort int
+
+const (
+	SortByTotal ProcessSort = iota
+	SortByDownload
+	SortByUpload
+)
+
+// String names the sort order for display
+func (s ProcessSort) String() string {
+	switch s {
+	case SortByDownload:
+		return "download"
+	case SortByUpload:
+		return "upload"
+	default:
+		return "total"
+	}
+}
+
+// ProcessStats attributes captured TCP and UDP traffic to the processes
+// owning the local sockets, like nethogs. Add is safe to call from the
+// capture goroutine while Rates is called from the UI. Traffic whose socket
+// has no known owner, e.g. forwarded or already closed, is reported under
+// PID 0. On macOS, which has no capture backend, the per-process counters
+// of nettop are tallied instead (see StartCounters).
+type ProcessStats struct {
+	mu       sync.Mutex
+	bytes    map[SocketKey]*[2]uint64 // cumulative upload, download
+	last     map[SocketKey][2]uint64
+	lastTime time.Time
+	owners   socketOwners
+	now      func() time.Time
+
+	// The two latest per-process readings of cumulative upload and download
+	// from AddCounters, when they were taken, and the rates between them
+	counted          bool
+	counters         map[Process][2]uint64
+	countersTime     time.Time
+	previous         map[Process][2]uint64
+	previousTime     time.Time
+	counterRates     []ProcessRates
+	counterRatesTime time.Time
+	counterErr       error
+}
+
+// socketOwners caches the socket owner table
+type socketOwners struct {
+	owners  map[SocketKey]Process
+	err     error
+	checked time.Time
+	resolve func() (map[SocketKey]Process, error)
+}
+
+// NewProcessStats creates an empty per-process tally using the platform's
+// socket owner table
+func NewProcessStats() *ProcessStats {
+	return &ProcessStats{
+		bytes:    make(map[SocketKey]*[2]uint64),
+		last:     make(map[SocketKey][2]uint64),
+		lastTime: time.Now(),
+		owners:   socketOwners{resolve: readSocketOwners},
+		now:      time.Now,
+	}
+}
+
+// SetOwnerResolver replaces the socket owner lookup and clock (for testing)
+func (s *ProcessStats) SetOwnerResolver(resolve func() (map[SocketKey]Process, error), now func() time.Time) {
+	s.owners.resolve = resolve
+	s.now = now
+	s.lastTime = now()
+}
+
+// StartCounters starts reading per-process byte counters on platforms that
+// provide them in place of packet capture, i.e. nettop on macOS. It reports
+// false where the tally must be fed captured packets with Add instead.
+func (s *ProcessStats) StartCounters() (bool, error) {
+	return startProcessCounters(s)
+}
+
+// AddCounters replaces the per-process byte counters, cumulative upload
+// then download, with a newer reading
+func (s *ProcessStats) AddCounters(counters map[Process][2]uint64) {
+	s.mu.Lock()
+	s.counted = true
+	s.previous, s.previousTime = s.counters, s.countersTime
+	s.counters, s.countersTime = counters, s.now()
+	s.mu.Unlock()
+}
+
+// failCounters records why the per-process counters stopped
+func (s *ProcessStats) failCounters(err error) {
+	s.mu.Lock()
+	s.counterErr = err
+	s.mu.Unlock()
+}
+
+// Add counts a captured packet against its local socket; it matches
+// StartCapture's handler signature
+func (s *ProcessStats) Add(p *PacketInfo) {
+	if p.Protocol != ProtocolTCP && p.Protocol != ProtocolUDP {
+		return
+	}
+	key, direction := SocketKey{Protocol: p.Protocol, Port: p.DstPort}, 1
+	if p.Outgoing {
+		key.Port, direction = p.SrcPort, 0
+	}
+	s.mu.Lock()
+	counts, ok := s.bytes[key]
+	if !ok {
+		counts = new([2]uint64)
+		s.bytes[key] = counts
+	}
+	counts[direction] += uint64(p.Length)
+	s.mu.Unlock()
+}
+
+// Rates returns the per-process rates since the previous call, ordered by
+// the given sort, busiest first. Processes without traffic are left out.
+func (s *ProcessStats) Rates(order ProcessSort) ([]ProcessRates, error) {
+	s.mu.Lock()
+	if s.counted || s.counterErr != nil {
+		defer s.mu.Unlock()
+		return s.ratesFromCounters(order), s.counterErr
+	}
+	current := make(map[SocketKey][2]uint64, len(s.bytes))
+	for key, counts := range s.bytes {
+		current[key] = *counts
+	}
+	s.mu.Unlock()
+
+	now := s.now()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	if elapsed <= 0 {
+		return nil, nil
+	}
+
+	byPID := make(map[int]*ProcessRates)
+	for key, counts := range current {
+		previous := s.last[key]
+		upload, download := counts[0]-previous[0], counts[1]-previous[1]
+		if upload == 0 && download == 0 {
+			continue
+		}
+		owner := s.owners.lookup(key, now)
+		rates, ok := byPID[owner.PID]
+		if !ok {
+			rates = &ProcessRates{Process: owner}
+			byPID[owner.PID] = rates
+		}
+		rates.Upload += uint64(float64(upload) / elapsed)
+		rates.Download += uint64(float64(download) / elapsed)
+	}
+	s.last = current
+	s.lastTime = now
+
+	processes := make([]ProcessRates, 0, len(byPID))
+	for _, rates := range byPID {
+		processes = append(processes, *rates)
+	}
+	sortProcesses(processes, order)
+	return processes, s.owners.err
+}
+
+// ratesFromCounters derives per-process rates from the two latest counter
+// readings, which are only taken every second or so
+func (s *ProcessStats) ratesFromCounters(order ProcessSort) []ProcessRates {
+	if s.countersTime.Equal(s.counterRatesTime) {
+		rates := slices.Clone(s.counterRates)
+		sortProcesses(rates, order)
+		return rates
+	}
+	elapsed := s.countersTime.Sub(s.previousTime).Seconds()
+	rates := make([]ProcessRates, 0, len(s.counters))
+	for process, counts := range s.counters {
+		previous, ok := s.previous[process]
+		// A process seen for the first time, or whose sockets closed, has
+		// no baseline to count from
+		if !ok || elapsed <= 0 || counts[0] < previous[0] || counts[1] < previous[1] {
+			continue
+		}
+		upload, download := counts[0]-previous[0], counts[1]-previous[1]
+		if upload == 0 && download == 0 {
+			continue
+		}
+		rates = append(rates, ProcessRates{Process: process, BandwidthRates: BandwidthRates{
+			Upload:   uint64(float64(upload) / elapsed),
+			Download: uint64(float64(download) / elapsed),
+		}})
+	}
+	s.counterRates, s.counterRatesTime = rates, s.countersTime
+	sortProcesses(rates, order)
+	return slices.Clone(rates)
+}
+
+// lookup finds a socket's process, re-reading the owner table when the
+// socket is new to it and the table is older than ownerRefreshInterval
+func (o *socketOwners) lookup(key SocketKey, now time.Time) Process {
+	owner, ok := o.owners[key]
+	if !ok && now.Sub(o.checked) >= ownerRefreshInterval {
+		o.owners, o.err = o.resolve()
+		o.checked = now
+		owner, ok = o.owners[key]
+	}
+	if !ok {
+		return Process{Name: "unknown"}
+	}
+	return owner
+}
+
+// sortProcesses orders processes busiest first, ties by name
+func sortProcesses(processes []ProcessRates, order ProcessSort) {
+	value := func(p ProcessRates) uint64 {
+		switch order {
+		case SortByDownload:
+			return p.Download
+		case SortByUpload:
+			return p.Upload
+		default:
+			return p.Upload + p.Download
+		}
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		if a, b := value(processes[i]), value(processes[j]); a != b {
+			return a > b
+		}
+		return processes[i].Name < processes[j].Name
+	})
+}
+
+// ParseSocketTable reads a /proc/net/tcp style table and returns the socket
+// inode bound to each local port
+func ParseSocketTable(r io.Reader) (map[uint16]uint64, error) {
+	inodes := make(map[uint16]uint64)
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		_, port, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		localPort, err := strconv.ParseUint(port, 16, 16)
+		if err != nil {
+			continue
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+		inodes[uint16(localPort)] = inode
+	}
+	return inodes, scanner.Err()
+}
+
+// ParseNettop reads the CSV that "nettop -P -L N -x -J bytes_in,bytes_out"
+// logs and passes each sample's cumulative upload and download per process
+// to handle. Every sample starts with a header row; processes are named
+// "name.pid".
+func ParseNettop(r io.Reader, handle func(map[Process][2]uint64)) error {
+	var sample map[Process][2]uint64
+	nameCol, inCol, outCol := -1, -1, -1
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if slices.Contains(fields, "bytes_in") {
+			if len(sample) > 0 {
+				handle(sample)
+			}
+			sample = make(map[Process][2]uint64)
+			nameCol = slices.Index(fields, "")
+			inCol = slices.Index(fields, "bytes_in")
+			outCol = slices.Index(fields, "bytes_out")
+			continue
+		}
+		if sample == nil || nameCol < 0 || outCol < 0 || len(fields) <= max(nameCol, inCol, outCol) {
+			continue
+		}
+		dot := strings.LastIndexByte(fields[nameCol], '.')
+		if dot < 0 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[nameCol][dot+1:])
+		if err != nil {
+			continue
+		}
+		download, errIn := strconv.ParseUint(fields[inCol], 10, 64)
+		upload, errOut := strconv.ParseUint(fields[outCol], 10, 64)
+		if errIn != nil || errOut != nil {
+			continue
+		}
+		sample[Process{PID: pid, Name: fields[nameCol][:dot]}] = [2]uint64{upload, download}
+	}
+	if len(sample) > 0 {
+		handle(sample)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,41 @@
+//go:build darwin
+
+// Package monitor provides per-process traffic from nettop on macOS
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// readSocketOwners reports ErrProcessUnsupported: without a capture
+// backend, sockets are never looked up on macOS
+func readSocketOwners() (map[SocketKey]Process, error) {
+	return nil, ErrProcessUnsupported
+}
+
+// startProcessCounters runs nettop, which logs each process's cumulative
+// bytes in and out every second, and feeds its samples to the tally. nettop
+// exits with peaks, on its first write after the pipe closes.
+func startProcessCounters(s *ProcessStats) (bool, error) {
+	cmd := exec.Command("nettop", "-P", "-x", "-L", "0", "-s", "1", "-J", "bytes_in,bytes_out")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return true, err
+	}
+	if err := cmd.Start(); err != nil {
+		return true, fmt.Errorf("failed to run nettop: %w", err)
+	}
+	go func() {
+		err := ParseNettop(stdout, s.AddCounters)
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+		if err == nil {
+			err = errors.New("exited")
+		}
+		s.failCounters(fmt.Errorf("nettop: %w", err))
+	}()
+	return true, nil
+}
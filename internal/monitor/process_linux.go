@@ -0,0 +1,86 @@
+//go:build linux
+
+// Package monitor provides the socket owner table on Linux
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// socketTables are the /proc/net tables of each protocol's sockets
+var socketTables = []struct {
+	path     string
+	protocol uint8
+}{
+	{"/proc/net/tcp", ProtocolTCP},
+	{"/proc/net/tcp6", ProtocolTCP},
+	{"/proc/net/udp", ProtocolUDP},
+	{"/proc/net/udp6", ProtocolUDP},
+}
+
+// readSocketOwners maps local sockets to processes: /proc/net lists each
+// socket's inode, and /proc/PID/fd links "socket:[inode]" to its owner.
+// Other users' processes are only visible to root.
+func readSocketOwners() (map[SocketKey]Process, error) {
+	byInode := make(map[uint64]SocketKey)
+	for _, table := range socketTables {
+		f, err := os.Open(table.path)
+		if err != nil {
+			continue // e.g. IPv6 disabled
+		}
+		inodes, err := ParseSocketTable(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		for port, inode := range inodes {
+			byInode[inode] = SocketKey{Protocol: table.protocol, Port: port}
+		}
+	}
+
+	pids, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[SocketKey]Process)
+	for _, dir := range pids {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue // exited, or another user's
+		}
+		var name string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			key, ok := byInode[inode]
+			if !ok {
+				continue
+			}
+			if name == "" {
+				comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+				name = strings.TrimSpace(string(comm))
+			}
+			owners[key] = Process{PID: pid, Name: name}
+		}
+	}
+	return owners, nil
+}
+
+// startProcessCounters leaves the tally to packet capture, which sees each
+// socket's traffic on Linux
+func startProcessCounters(s *ProcessStats) (bool, error) {
+	return false, nil
+}
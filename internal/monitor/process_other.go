@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+// Package monitor provides the socket owner table fallback for other platforms
+package monitor
+
+// readSocketOwners reports ErrProcessUnsupported outside Linux
+func readSocketOwners() (map[SocketKey]Process, error) {
+	return nil, ErrProcessUnsupported
+}
+
+// startProcessCounters reports ErrProcessUnsupported: there are neither
+// per-process counters nor a capture backend to attribute traffic with
+func startProcessCounters(s *ProcessStats) (bool, error) {
+	return false, ErrProcessUnsupported
+}
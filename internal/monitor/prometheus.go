@@ -0,0 +1,185 @@
+// Package monitor provides a collector polling a Prometheus server
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Series names reported by PrometheusCollector
+const (
+	SeriesQuery   = "query"
+	SeriesQueryUp = "query-up"
+)
+
+// DefaultPrometheusInterval is how often PrometheusCollector re-runs its
+// queries; faster than a typical scrape interval gains nothing
+const DefaultPrometheusInterval = 5 * time.Second
+
+// PrometheusCollector charts the result of PromQL queries, such as
+// rate(node_network_receive_bytes_total[1m]), so fleet metrics can be drawn
+// like a local NIC. The main query is drawn above the axis and the optional
+// upload query below it. A query returning several series is charted as
+// their sum. The server is polled in the background and Sample reports the
+// latest results, so a slow server never stalls the display.
+type PrometheusCollector struct {
+	endpoint string
+	queries  [2]string // download, upload
+	client   *http.Client
+	interval time.Duration
+
+	mu     sync.Mutex
+	values [2]uint64
+	err    error
+	series []Series
+}
+
+// NewPrometheusCollector starts polling the Prometheus server at baseURL
+// every interval. upload may be empty.
+func NewPrometheusCollector(baseURL, download, upload string, interval time.Duration) (*PrometheusCollector, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Prometheus URL %q (expected http://HOST:9090)", baseURL)
+	}
+	if download == "" {
+		return nil, fmt.Errorf("a PromQL query is required")
+	}
+	if interval <= 0 {
+		interval = DefaultPrometheusInterval
+	}
+	c := &PrometheusCollector{
+		endpoint: strings.TrimRight(baseURL, "/") + "/api/v1/query",
+		queries:  [2]string{download, upload},
+		client:   &http.Client{Timeout: interval},
+		interval: interval,
+		series: []Series{
+			{Name: SeriesQueryUp},
+			{Name: SeriesQuery},
+		},
+	}
+	go c.poll()
+	return c, nil
+}
+
+// poll re-runs the queries every interval
+func (c *PrometheusCollector) poll() {
+	for {
+		c.refresh()
+		time.Sleep(c.interval)
+	}
+}
+
+// refresh runs both queries and stores their results
+func (c *PrometheusCollector) refresh() {
+	var values [2]uint64
+	var err error
+	for i, query := range c.queries {
+		if query == "" {
+			continue
+		}
+		var value float64
+		if value, err = c.query(query); err != nil {
+			break
+		}
+		values[i] = uint64(math.Round(max(value, 0)))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	if err == nil {
+		c.values = values
+	}
+}
+
+// query runs one instant query and sums the values it returns
+func (c *PrometheusCollector) query(query string) (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.interval)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return ParsePrometheusResponse(resp.Body)
+}
+
+// ParsePrometheusResponse sums the values of an instant query response. Vector
+// and scalar results are accepted; NaN and infinite samples are skipped.
+func ParsePrometheusResponse(r io.Reader) (float64, error) {
+	var response struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+		Data      struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return 0, fmt.Errorf("reading Prometheus response: %w", err)
+	}
+	if response.Status != "success" {
+		return 0, fmt.Errorf("prometheus query failed: %s: %s", response.ErrorType, response.Error)
+	}
+
+	var samples [][2]any
+	switch response.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Value [2]any `json:"value"`
+		}
+		if err := json.Unmarshal(response.Data.Result, &vector); err != nil {
+			return 0, fmt.Errorf("reading Prometheus vector: %w", err)
+		}
+		for _, s := range vector {
+			samples = append(samples, s.Value)
+		}
+	case "scalar":
+		var scalar [2]any
+		if err := json.Unmarshal(response.Data.Result, &scalar); err != nil {
+			return 0, fmt.Errorf("reading Prometheus scalar: %w", err)
+		}
+		samples = append(samples, scalar)
+	default:
+		return 0, fmt.Errorf("unsupported Prometheus result type %q (expected an instant vector or scalar)", response.Data.ResultType)
+	}
+
+	var sum float64
+	for _, sample := range samples {
+		text, _ := sample[1].(string)
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid Prometheus sample value %q", text)
+		}
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			sum += value
+		}
+	}
+	return sum, nil
+}
+
+// Sample implements Collector. It returns the latest query results, or the
+// error from the last poll.
+func (c *PrometheusCollector) Sample() ([]Series, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.series[1].Value = c.values[0]
+	c.series[0].Value = c.values[1]
+	return c.series, nil
+}
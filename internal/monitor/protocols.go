@@ -0,0 +1,157 @@
+// Package monitor provides traffic breakdown by protocol and service
+package monitor
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// servicePort is a transport protocol and well-known port
+type servicePort struct {
+	protocol uint8
+	port     uint16
+}
+
+// serviceNames are the services told apart by well-known port
+var serviceNames = map[servicePort]string{
+	{ProtocolTCP, 443}:   "HTTPS",
+	{ProtocolUDP, 443}:   "QUIC",
+	{ProtocolTCP, 80}:    "HTTP",
+	{ProtocolTCP, 8080}:  "HTTP",
+	{ProtocolTCP, 22}:    "SSH",
+	{ProtocolUDP, 53}:    "DNS",
+	{ProtocolTCP, 53}:    "DNS",
+	{ProtocolTCP, 853}:   "DNS",
+	{ProtocolUDP, 5353}:  "mDNS",
+	{ProtocolUDP, 123}:   "NTP",
+	{ProtocolUDP, 67}:    "DHCP",
+	{ProtocolUDP, 68}:    "DHCP",
+	{ProtocolUDP, 546}:   "DHCP",
+	{ProtocolUDP, 547}:   "DHCP",
+	{ProtocolTCP, 25}:    "SMTP",
+	{ProtocolTCP, 465}:   "SMTP",
+	{ProtocolTCP, 587}:   "SMTP",
+	{ProtocolTCP, 143}:   "IMAP",
+	{ProtocolTCP, 993}:   "IMAP",
+	{ProtocolTCP, 21}:    "FTP",
+	{ProtocolTCP, 445}:   "SMB",
+	{ProtocolTCP, 3389}:  "RDP",
+	{ProtocolTCP, 5900}:  "VNC",
+	{ProtocolUDP, 1194}:  "OpenVPN",
+	{ProtocolUDP, 51820}: "WireGuard",
+	{ProtocolUDP, 3478}:  "STUN",
+}
+
+// ServiceName classifies a captured packet: by the service of its
+// well-known port for TCP and UDP (the lower port when both are known), else
+// by protocol, e.g. "HTTPS", "TCP other", "ICMP"
+func ServiceName(p *PacketInfo) string {
+	switch p.Protocol {
+	case ProtocolTCP, ProtocolUDP:
+		low, high := p.SrcPort, p.DstPort
+		if low > high {
+			low, high = high, low
+		}
+		for _, port := range []uint16{low, high} {
+			if name, ok := serviceNames[servicePort{p.Protocol, port}]; ok {
+				return name
+			}
+		}
+		if p.Protocol == ProtocolTCP {
+			return "TCP other"
+		}
+		return "UDP other"
+	case ProtocolICMP, ProtocolICMPv6:
+		return "ICMP"
+	default:
+		return "IP " + strconv.Itoa(int(p.Protocol))
+	}
+}
+
+// ProtocolStats tallies captured traffic by service and protocol. Add is
+// safe to call from the capture goroutine while Rates is called from the UI.
+type ProtocolStats struct {
+	mu       sync.Mutex
+	bytes    map[string]*[2]uint64 // cumulative upload, download
+	last     map[string][2]uint64
+	lastTime time.Time
+	now      func() time.Time
+}
+
+// NewProtocolStats creates an empty per-protocol tally
+func NewProtocolStats() *ProtocolStats {
+	return &ProtocolStats{
+		bytes:    make(map[string]*[2]uint64),
+		last:     make(map[string][2]uint64),
+		lastTime: time.Now(),
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the clock used to work out rates (for testing)
+func (s *ProtocolStats) SetClock(now func() time.Time) {
+	s.now = now
+	s.lastTime = now()
+}
+
+// Add counts a captured packet against its service; it matches
+// StartCapture's handler signature
+func (s *ProtocolStats) Add(p *PacketInfo) {
+	name := ServiceName(p)
+	direction := 1
+	if p.Outgoing {
+		direction = 0
+	}
+	s.mu.Lock()
+	counts, ok := s.bytes[name]
+	if !ok {
+		counts = new([2]uint64)
+		s.bytes[name] = counts
+	}
+	counts[direction] += uint64(p.Length)
+	s.mu.Unlock()
+}
+
+// Rates returns the per-service rates since the previous call, busiest
+// first. Services without traffic are left out.
+func (s *ProtocolStats) Rates() []ClassRates {
+	s.mu.Lock()
+	current := make(map[string][2]uint64, len(s.bytes))
+	for name, counts := range s.bytes {
+		current[name] = *counts
+	}
+	s.mu.Unlock()
+
+	now := s.now()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+
+	var rates []ClassRates
+	for name, counts := range current {
+		previous := s.last[name]
+		if counts == previous {
+			continue
+		}
+		rates = append(rates, ClassRates{
+			Name: name,
+			BandwidthRates: BandwidthRates{
+				Upload:   uint64(float64(counts[0]-previous[0]) / elapsed),
+				Download: uint64(float64(counts[1]-previous[1]) / elapsed),
+			},
+		})
+	}
+	s.last = current
+	s.lastTime = now
+
+	sort.Slice(rates, func(i, j int) bool {
+		if a, b := rates[i].Upload+rates[i].Download, rates[j].Upload+rates[j].Download; a != b {
+			return a > b
+		}
+		return rates[i].Name < rates[j].Name
+	})
+	return rates
+}
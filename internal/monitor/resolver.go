@@ -0,0 +1,88 @@
+// Package monitor provides cached reverse DNS lookups
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reverse lookups time out after resolveTimeout, at most maxLookups run at
+// once, and answers (including failures) are kept for resolveTTL
+const (
+	resolveTimeout = 3 * time.Second
+	maxLookups     = 4
+	resolveTTL     = 10 * time.Minute
+)
+
+// hostName is a cached reverse lookup; pending until the lookup returns
+type hostName struct {
+	name    string
+	pending bool
+	at      time.Time
+}
+
+// Resolver names addresses by reverse DNS in the background so the UI never
+// waits on a lookup. Name is safe to call from the UI while lookups finish.
+type Resolver struct {
+	mu     sync.Mutex
+	names  map[netip.Addr]hostName
+	slots  chan struct{}
+	lookup func(ctx context.Context, addr string) ([]string, error)
+	now    func() time.Time
+}
+
+// NewResolver creates an empty reverse DNS cache using the system resolver
+func NewResolver() *Resolver {
+	return &Resolver{
+		names:  make(map[netip.Addr]hostName),
+		slots:  make(chan struct{}, maxLookups),
+		lookup: net.DefaultResolver.LookupAddr,
+		now:    time.Now,
+	}
+}
+
+// SetLookup replaces the reverse lookup and clock (for testing)
+func (r *Resolver) SetLookup(lookup func(ctx context.Context, addr string) ([]string, error), now func() time.Time) {
+	r.lookup = lookup
+	r.now = now
+}
+
+// Name returns the cached hostname of addr. Until a lookup has answered, or
+// when the address has no name, it returns "" and false, starting a lookup
+// if none is running and a slot is free.
+func (r *Resolver) Name(addr netip.Addr) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cached, ok := r.names[addr]
+	if ok && (cached.pending || r.now().Sub(cached.at) < resolveTTL) {
+		return cached.name, cached.name != ""
+	}
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		// Busy; try again on a later call
+		return cached.name, cached.name != ""
+	}
+	cached.pending = true
+	r.names[addr] = cached
+	go r.resolve(addr)
+	return cached.name, cached.name != ""
+}
+
+// resolve looks addr up and caches the answer
+func (r *Resolver) resolve(addr netip.Addr) {
+	defer func() { <-r.slots }()
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	var name string
+	if names, err := r.lookup(ctx, addr.String()); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+	r.mu.Lock()
+	r.names[addr] = hostName{name: name, at: r.now()}
+	r.mu.Unlock()
+}
@@ -0,0 +1,98 @@
+// Package monitor provides default route change detection
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultRoute identifies the interface and gateway carrying default traffic
+type DefaultRoute struct {
+	Interface string
+	Gateway   net.IP // nil when the platform cannot report it
+}
+
+// String formats the route as "eth0 via 192.168.1.1"
+func (r DefaultRoute) String() string {
+	if r.Interface == "" {
+		return "none"
+	}
+	if r.Gateway == nil {
+		return r.Interface
+	}
+	return fmt.Sprintf("%s via %s", r.Interface, r.Gateway)
+}
+
+// Equal reports whether two routes use the same interface and gateway
+func (r DefaultRoute) Equal(other DefaultRoute) bool {
+	return r.Interface == other.Interface && r.Gateway.Equal(other.Gateway)
+}
+
+// ReadDefaultRoute returns the current default route.
+//
+// Linux reads the kernel routing table; other platforms find the interface
+// owning the source address the kernel picks for an outbound UDP socket
+// (no packets are sent) and leave the gateway unset.
+func ReadDefaultRoute() (DefaultRoute, error) {
+	return readDefaultRoute()
+}
+
+// RouteChange describes a default route or primary interface change
+type RouteChange struct {
+	From DefaultRoute
+	To   DefaultRoute
+	Time time.Time
+}
+
+// String formats the change as "eth0 → wlan0 via 10.0.0.1"
+func (c RouteChange) String() string {
+	return fmt.Sprintf("%s → %s", c.From, c.To)
+}
+
+// RouteWatcher polls the default route and reports changes, e.g. failing over
+// from ethernet to Wi-Fi, which otherwise shows up as a mysterious rate change
+type RouteWatcher struct {
+	read  func() (DefaultRoute, error)
+	now   func() time.Time
+	last  DefaultRoute
+	known bool
+}
+
+// NewRouteWatcher creates a watcher primed with the current default route
+func NewRouteWatcher() *RouteWatcher {
+	return NewRouteWatcherWithReader(ReadDefaultRoute)
+}
+
+// NewRouteWatcherWithReader creates a watcher reading routes from read
+func NewRouteWatcherWithReader(read func() (DefaultRoute, error)) *RouteWatcher {
+	w := &RouteWatcher{read: read, now: time.Now}
+	w.Check()
+	return w
+}
+
+// Current returns the last observed default route
+func (w *RouteWatcher) Current() DefaultRoute {
+	return w.last
+}
+
+// Check reads the default route, reporting a change since the previous check.
+// Read errors are treated as "no default route" (e.g. all links down).
+func (w *RouteWatcher) Check() (RouteChange, bool) {
+	route, err := w.read()
+	if err != nil {
+		route = DefaultRoute{}
+	}
+
+	if !w.known {
+		w.last, w.known = route, true
+		return RouteChange{}, false
+	}
+	if route.Equal(w.last) {
+		return RouteChange{}, false
+	}
+
+	change := RouteChange{From: w.last, To: route, Time: w.now()}
+	w.last = route
+	return change, true
+}
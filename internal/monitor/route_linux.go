@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errNoDefaultRoute is returned when the routing table has no default route
+var errNoDefaultRoute = errors.New("no default route")
+
+// readDefaultRoute picks the lowest-metric default route from /proc/net/route
+func readDefaultRoute() (DefaultRoute, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return DefaultRoute{}, err
+	}
+	defer file.Close()
+	return parseProcNetRoute(bufio.NewScanner(file))
+}
+
+// parseProcNetRoute parses /proc/net/route lines:
+// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+// Addresses are hex in host (little-endian) byte order.
+func parseProcNetRoute(scanner *bufio.Scanner) (DefaultRoute, error) {
+	const flagUp = 0x1
+
+	best := DefaultRoute{}
+	bestMetric := -1
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue // header or not a default route
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&flagUp == 0 {
+			continue
+		}
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil {
+			continue
+		}
+		if bestMetric >= 0 && metric >= bestMetric {
+			continue
+		}
+
+		route := DefaultRoute{Interface: fields[0]}
+		if raw, err := hex.DecodeString(fields[2]); err == nil && len(raw) == 4 {
+			gateway := make(net.IP, 4)
+			binary.BigEndian.PutUint32(gateway, binary.LittleEndian.Uint32(raw))
+			if !gateway.Equal(net.IPv4zero) {
+				route.Gateway = gateway
+			}
+		}
+		best, bestMetric = route, metric
+	}
+	if err := scanner.Err(); err != nil {
+		return DefaultRoute{}, err
+	}
+	if bestMetric < 0 {
+		return DefaultRoute{}, errNoDefaultRoute
+	}
+	return best, nil
+}
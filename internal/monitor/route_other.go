@@ -0,0 +1,36 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"fmt"
+	"net"
+)
+
+// readDefaultRoute finds the interface owning the source address chosen for
+// an outbound UDP "connection". Connecting a UDP socket sends no packets.
+func readDefaultRoute() (DefaultRoute, error) {
+	conn, err := net.Dial("udp", "192.0.2.1:9") // TEST-NET-1, never contacted
+	if err != nil {
+		return DefaultRoute{}, err
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return DefaultRoute{}, err
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(local) {
+				return DefaultRoute{Interface: iface.Name}, nil
+			}
+		}
+	}
+	return DefaultRoute{}, fmt.Errorf("no interface owns %s", local)
+}
@@ -0,0 +1,314 @@
+// Package monitor provides interface counters polled from a router or switch
+// over SNMP
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultSNMPPort is the port SNMP agents listen on
+const DefaultSNMPPort = "161"
+
+// SNMPPollInterval is how often SNMPSource polls the agent, matching the
+// chart's sample interval
+const SNMPPollInterval = 500 * time.Millisecond
+
+// snmpTimeout is how long SNMPSource waits for a response
+const snmpTimeout = 2 * time.Second
+
+// The 64-bit IF-MIB octet counters, ifXTable's ifHCInOctets and
+// ifHCOutOctets, without the trailing ifIndex
+var (
+	oidIfHCInOctets  = []uint32{1, 3, 6, 1, 2, 1, 31, 1, 1, 1, 6}
+	oidIfHCOutOctets = []uint32{1, 3, 6, 1, 2, 1, 31, 1, 1, 1, 10}
+)
+
+// BER tags used by SNMPv2c GetRequest and GetResponse messages
+const (
+	berInteger        = 0x02
+	berOctetString    = 0x04
+	berNull           = 0x05
+	berOID            = 0x06
+	berSequence       = 0x30
+	berCounter32      = 0x41
+	berGauge32        = 0x42
+	berCounter64      = 0x46
+	berNoSuchObject   = 0x80
+	berNoSuchInstance = 0x81
+	berEndOfMibView   = 0x82
+	berGetRequest     = 0xA0
+	berGetResponse    = 0xA2
+)
+
+// snmpVersion2c is the version field of an SNMPv2c message
+const snmpVersion2c = 1
+
+// SNMPSource reads one interface's octet counters from an SNMPv2c agent,
+// such as a router or managed switch. The agent is polled in the
+// background so a slow or unreachable device never stalls the display.
+type SNMPSource struct {
+	name      string
+	community string
+	oids      [2][]uint32 // in, out
+	conn      net.Conn
+
+	mu       sync.Mutex
+	counters InterfaceCounters
+	polled   bool
+	err      error
+}
+
+// NewSNMPSource starts polling ifIndex's counters from the agent at target
+// (HOST[:PORT]) with an SNMPv2c community
+func NewSNMPSource(target, community string, ifIndex int) (*SNMPSource, error) {
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, DefaultSNMPPort)
+	}
+	if host, _, err := net.SplitHostPort(target); err != nil || host == "" {
+		return nil, fmt.Errorf("invalid SNMP agent %q (expected HOST[:PORT])", target)
+	}
+	if ifIndex <= 0 {
+		return nil, fmt.Errorf("an interface index (ifIndex) of at least 1 is required")
+	}
+	if community == "" {
+		return nil, fmt.Errorf("an SNMP community is required")
+	}
+	conn, err := net.Dial("udp", target)
+	if err != nil {
+		return nil, err
+	}
+	s := &SNMPSource{
+		name:      fmt.Sprintf("if%d", ifIndex),
+		community: community,
+		oids: [2][]uint32{
+			append(slices.Clone(oidIfHCInOctets), uint32(ifIndex)),
+			append(slices.Clone(oidIfHCOutOctets), uint32(ifIndex)),
+		},
+		conn: conn,
+	}
+	go s.poll()
+	return s, nil
+}
+
+// poll queries the agent every SNMPPollInterval
+func (s *SNMPSource) poll() {
+	buf := make([]byte, 1500)
+	for {
+		in, out, err := s.get(buf)
+		s.mu.Lock()
+		s.err = err
+		if err == nil {
+			s.counters = InterfaceCounters{Name: s.name, BytesRecv: in, BytesSent: out}
+			s.polled = true
+		}
+		s.mu.Unlock()
+		time.Sleep(SNMPPollInterval)
+	}
+}
+
+// get requests the in and out octet counters, skipping stale responses to
+// earlier requests that timed out
+func (s *SNMPSource) get(buf []byte) (in, out uint64, err error) {
+	id := rand.Int32N(1 << 30)
+	request := encodeSNMPGet(s.community, id, s.oids[:]...)
+	if _, err := s.conn.Write(request); err != nil {
+		return 0, 0, err
+	}
+	s.conn.SetReadDeadline(time.Now().Add(snmpTimeout))
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			if isTimeout(err) {
+				return 0, 0, fmt.Errorf("no response from SNMP agent %s (check the address and community)", s.conn.RemoteAddr())
+			}
+			return 0, 0, err
+		}
+		responseID, values, err := ParseSNMPResponse(buf[:n])
+		if err != nil {
+			return 0, 0, err
+		}
+		if responseID != id {
+			continue
+		}
+		if len(values) != 2 {
+			return 0, 0, fmt.Errorf("SNMP agent returned %d values, expected 2", len(values))
+		}
+		return values[0], values[1], nil
+	}
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ReadCounters implements CounterSource. Before the first response arrives
+// it returns no interfaces.
+func (s *SNMPSource) ReadCounters(dst []InterfaceCounters, skip func(name []byte) bool) ([]InterfaceCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return dst, s.err
+	}
+	if !s.polled {
+		return dst, nil
+	}
+	return append(dst, s.counters), nil
+}
+
+// encodeSNMPGet encodes an SNMPv2c GetRequest for the given OIDs
+func encodeSNMPGet(community string, requestID int32, oids ...[]uint32) []byte {
+	var varbinds []byte
+	for _, oid := range oids {
+		varbinds = append(varbinds, berTLV(berSequence, append(berTLV(berOID, berOIDBytes(oid)), berNull, 0))...)
+	}
+	pdu := berTLV(berInteger, berInt(int64(requestID)))
+	pdu = append(pdu, berInteger, 1, 0) // error-status
+	pdu = append(pdu, berInteger, 1, 0) // error-index
+	pdu = append(pdu, berTLV(berSequence, varbinds)...)
+
+	message := berTLV(berInteger, berInt(snmpVersion2c))
+	message = append(message, berTLV(berOctetString, []byte(community))...)
+	message = append(message, berTLV(berGetRequest, pdu)...)
+	return berTLV(berSequence, message)
+}
+
+// ParseSNMPResponse decodes an SNMPv2c GetResponse, returning its request ID
+// and the counter value of each variable binding in order
+func ParseSNMPResponse(data []byte) (int32, []uint64, error) {
+	tag, message, _, err := berNext(data)
+	if err != nil || tag != berSequence {
+		return 0, nil, fmt.Errorf("malformed SNMP message")
+	}
+	var fields [3][]byte // version, community, PDU
+	for i := range fields {
+		if tag, fields[i], message, err = berNext(message); err != nil {
+			return 0, nil, fmt.Errorf("malformed SNMP message")
+		}
+	}
+	if tag != berGetResponse {
+		return 0, nil, fmt.Errorf("unexpected SNMP PDU type %#x", tag)
+	}
+
+	pdu := fields[2]
+	var header [3][]byte // request-id, error-status, error-index
+	for i := range header {
+		if _, header[i], pdu, err = berNext(pdu); err != nil {
+			return 0, nil, fmt.Errorf("malformed SNMP response")
+		}
+	}
+	if status := berUint(header[1]); status != 0 {
+		return 0, nil, fmt.Errorf("SNMP agent reported error status %d", status)
+	}
+	_, varbinds, _, err := berNext(pdu)
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed SNMP response")
+	}
+
+	var values []uint64
+	for len(varbinds) > 0 {
+		var varbind []byte
+		if _, varbind, varbinds, err = berNext(varbinds); err != nil {
+			return 0, nil, fmt.Errorf("malformed SNMP variable binding")
+		}
+		_, _, rest, err := berNext(varbind) // the OID
+		if err != nil {
+			return 0, nil, fmt.Errorf("malformed SNMP variable binding")
+		}
+		tag, value, _, err := berNext(rest)
+		if err != nil {
+			return 0, nil, fmt.Errorf("malformed SNMP variable binding")
+		}
+		switch tag {
+		case berCounter32, berCounter64, berGauge32, berInteger:
+			values = append(values, berUint(value))
+		case berNoSuchObject, berNoSuchInstance, berEndOfMibView:
+			return 0, nil, fmt.Errorf("no such interface, or no 64-bit counters (ifHCInOctets) on the SNMP agent")
+		default:
+			return 0, nil, fmt.Errorf("unexpected SNMP value type %#x", tag)
+		}
+	}
+	return int32(berUint(header[0])), values, nil
+}
+
+// berTLV encodes a tag, length and value
+func berTLV(tag byte, value []byte) []byte {
+	n := len(value)
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xFF:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+// berInt encodes a non-negative integer in as few bytes as BER allows
+func berInt(v int64) []byte {
+	out := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		out = append([]byte{byte(v)}, out...)
+	}
+	if out[0]&0x80 != 0 {
+		// Keep the sign bit clear
+		out = append([]byte{0}, out...)
+	}
+	return out
+}
+
+// berOIDBytes encodes an object identifier's arcs
+func berOIDBytes(oid []uint32) []byte {
+	out := []byte{byte(oid[0]*40 + oid[1])}
+	for _, arc := range oid[2:] {
+		var group []byte
+		group = append(group, byte(arc&0x7F))
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			group = append([]byte{byte(arc&0x7F) | 0x80}, group...)
+		}
+		out = append(out, group...)
+	}
+	return out
+}
+
+// berNext splits the first TLV off data
+func berNext(data []byte) (tag byte, value, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, fmt.Errorf("truncated")
+	}
+	tag, n, data := data[0], int(data[1]), data[2:]
+	if n&0x80 != 0 {
+		octets := n & 0x7F
+		if octets == 0 || octets > 2 || len(data) < octets {
+			return 0, nil, nil, fmt.Errorf("unsupported length")
+		}
+		n = 0
+		for _, b := range data[:octets] {
+			n = n<<8 | int(b)
+		}
+		data = data[octets:]
+	}
+	if len(data) < n {
+		return 0, nil, nil, fmt.Errorf("truncated")
+	}
+	return tag, data[:n], data[n:], nil
+}
+
+// berUint decodes an unsigned big-endian integer; Counter64 values may
+// carry a leading zero byte
+func berUint(value []byte) uint64 {
+	var v uint64
+	for _, b := range value {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
@@ -0,0 +1,174 @@
+// Package monitor provides throughput (speed) testing
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultSpeedTestURL is Cloudflare's speed test endpoint, which serves
+// arbitrary-size downloads at /__down and accepts uploads at /__up
+const DefaultSpeedTestURL = "https://speed.cloudflare.com"
+
+// Default transfer sizes and overall time limit for one speed test
+const (
+	defaultSpeedTestDownload = 25 * 1000 * 1000
+	defaultSpeedTestUpload   = 10 * 1000 * 1000
+	defaultSpeedTestTimeout  = 60 * time.Second
+)
+
+// SpeedTestResult holds the measured capacity of one speed test
+type SpeedTestResult struct {
+	Time     time.Time     `json:"time"`
+	Latency  time.Duration `json:"latency_ns"`
+	Download uint64        `json:"download_bytes_per_sec"`
+	Upload   uint64        `json:"upload_bytes_per_sec"`
+}
+
+// SpeedTester measures download and upload throughput against an HTTP endpoint
+// implementing the Cloudflare /__down and /__up protocol
+type SpeedTester struct {
+	baseURL       string
+	client        *http.Client
+	downloadBytes int64
+	uploadBytes   int64
+	timeout       time.Duration
+}
+
+// NewSpeedTester creates a speed tester for baseURL (DefaultSpeedTestURL if empty)
+func NewSpeedTester(baseURL string) *SpeedTester {
+	if baseURL == "" {
+		baseURL = DefaultSpeedTestURL
+	}
+	return &SpeedTester{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		client:        &http.Client{},
+		downloadBytes: defaultSpeedTestDownload,
+		uploadBytes:   defaultSpeedTestUpload,
+		timeout:       defaultSpeedTestTimeout,
+	}
+}
+
+// SetSizes overrides how many bytes are downloaded and uploaded
+func (s *SpeedTester) SetSizes(download, upload int64) {
+	s.downloadBytes = download
+	s.uploadBytes = upload
+}
+
+// Host returns the host of the endpoint being tested against
+func (s *SpeedTester) Host() string {
+	if u, err := url.Parse(s.baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return s.baseURL
+}
+
+// Run measures latency, then download and upload throughput
+func (s *SpeedTester) Run() (SpeedTestResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	result := SpeedTestResult{Time: time.Now()}
+
+	// An empty download approximates one HTTP round trip
+	latency, _, err := s.download(ctx, 0)
+	if err != nil {
+		return result, err
+	}
+	result.Latency = latency
+
+	elapsed, n, err := s.download(ctx, s.downloadBytes)
+	if err != nil {
+		return result, err
+	}
+	result.Download = bytesPerSecond(n, elapsed)
+
+	elapsed, err = s.upload(ctx, s.uploadBytes)
+	if err != nil {
+		return result, err
+	}
+	result.Upload = bytesPerSecond(s.uploadBytes, elapsed)
+
+	return result, nil
+}
+
+// download fetches size bytes, returning the elapsed time and bytes received
+func (s *SpeedTester) download(ctx context.Context, size int64) (time.Duration, int64, error) {
+	url := fmt.Sprintf("%s/__down?bytes=%d", s.baseURL, size)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("speed test download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("speed test download failed: %s", resp.Status)
+	}
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return 0, n, fmt.Errorf("speed test download failed: %w", err)
+	}
+	return time.Since(start), n, nil
+}
+
+// upload posts size bytes, returning the elapsed time
+func (s *SpeedTester) upload(ctx context.Context, size int64) (time.Duration, error) {
+	body := io.LimitReader(zeroReader{}, size)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/__up", body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("speed test upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("speed test upload failed: %s", resp.Status)
+	}
+	return time.Since(start), nil
+}
+
+// bytesPerSecond converts a transfer into a rate
+func bytesPerSecond(n int64, elapsed time.Duration) uint64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return uint64(float64(n) / elapsed.Seconds())
+}
+
+// zeroReader is an endless source of zero bytes for upload payloads
+type zeroReader struct{}
+
+// Read implements io.Reader
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// AppendSpeedTestLog appends a result to a JSON Lines file, creating it if needed
+func AppendSpeedTestLog(path string, result SpeedTestResult) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(result)
+}
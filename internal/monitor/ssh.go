@@ -0,0 +1,131 @@
+// Package monitor provides interface counters read from another host over SSH
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// SourceSSHPrefix names a host to read counters from over SSH, as
+// ssh://[USER@]HOST[:PORT]; a bare USER@HOST works too
+const SourceSSHPrefix = "ssh://"
+
+// sshRemoteScript prints the remote host's /proc/net/dev every half second,
+// with a blank line ending each snapshot. A single long-lived session keeps
+// the per-sample cost low compared to connecting once per tick, and needs
+// nothing installed on the server.
+const sshRemoteScript = `while cat /proc/net/dev; do echo; sleep 0.5; done`
+
+// IsSSHSource reports whether a source names a host to read over SSH
+func IsSSHSource(source string) bool {
+	return strings.HasPrefix(source, SourceSSHPrefix) || (strings.Contains(source, "@") && !strings.ContainsAny(source, "/: "))
+}
+
+// sshArgs returns the ssh arguments reaching the source's host. Keys and
+// options come from the user's ssh config and agent; peaks never prompts, as
+// the TUI owns the terminal.
+func sshArgs(source string) ([]string, error) {
+	target := strings.TrimPrefix(source, SourceSSHPrefix)
+	args := []string{"-T", "-o", "BatchMode=yes", "-o", "ServerAliveInterval=15"}
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		target = host
+		args = append(args, "-p", port)
+	}
+	if target == "" || strings.HasPrefix(target, "-") || strings.HasSuffix(target, "@") {
+		return nil, fmt.Errorf("invalid ssh source %q (expected user@host or ssh://[user@]host[:port])", source)
+	}
+	return append(args, target, sshRemoteScript), nil
+}
+
+// SSHSource reads a Linux server's interface counters over an SSH session
+type SSHSource struct {
+	host   string
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	mu     sync.Mutex
+	latest []byte
+	err    error
+}
+
+// NewSSHSource connects to the source's host with the system ssh client
+func NewSSHSource(source string) (*SSHSource, error) {
+	args, err := sshArgs(source)
+	if err != nil {
+		return nil, err
+	}
+	path, err := exec.LookPath("ssh")
+	if err != nil {
+		return nil, fmt.Errorf("ssh client unavailable: %w", err)
+	}
+
+	src := &SSHSource{host: args[len(args)-2]}
+	src.cmd = exec.Command(path, args...)
+	src.cmd.Stderr = &src.stderr
+	stdout, err := src.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := src.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	go src.read(bufio.NewScanner(stdout))
+	return src, nil
+}
+
+// read consumes snapshots from the session until it ends
+func (s *SSHSource) read(scanner *bufio.Scanner) {
+	var pending []byte
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			s.mu.Lock()
+			s.latest, pending = pending, s.latest[:0]
+			s.mu.Unlock()
+			continue
+		}
+		pending = append(append(pending, line...), '\n')
+	}
+
+	// Always reap ssh, ending it first if the output could not be read;
+	// once Wait returns nothing writes to stderr any more
+	err := scanner.Err()
+	if err != nil {
+		s.cmd.Process.Kill()
+	}
+	if waitErr := s.cmd.Wait(); err == nil {
+		err = waitErr
+	}
+	// ssh explains what went wrong (host key, authentication…) on stderr
+	if lines := strings.Split(strings.TrimSpace(s.stderr.String()), "\n"); lines[len(lines)-1] != "" {
+		err = fmt.Errorf("%s", strings.TrimSpace(lines[len(lines)-1]))
+	}
+	s.mu.Lock()
+	s.err = fmt.Errorf("ssh %s: %v", s.host, err)
+	s.mu.Unlock()
+}
+
+// Close ends the SSH session
+func (s *SSHSource) Close() error {
+	if err := s.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
+
+// ReadCounters implements CounterSource, parsing the most recent snapshot.
+// Before the first snapshot arrives it returns no interfaces.
+func (s *SSHSource) ReadCounters(dst []InterfaceCounters, skip func(name []byte) bool) ([]InterfaceCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return dst, s.err
+	}
+	return ParseProcNetDev(s.latest, dst, skip), nil
+}
@@ -0,0 +1,239 @@
+// Package monitor provides a collector for values piped in on stdin
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Series names reported by StdinCollector
+const (
+	SeriesValue1 = "value1"
+	SeriesValue2 = "value2"
+)
+
+// StdinScale is how many chart units one piped unit is worth: values are
+// charted in thousandths so fractional metrics such as a load average keep
+// their detail
+const StdinScale = 1000
+
+// StdinCollector charts numbers read line by line from a stream, turning
+// peaks into a plotter for any metric. Each line is a timestamp followed by
+// one or two values:
+//
+//	1718000000 0.42
+//	2024-06-10T12:00:00Z 120 80
+//	{"t": 1718000000, "value": 0.42}
+//	{"t": "2024-06-10T12:00:00Z", "up": 80, "down": 120}
+//
+// The first value is drawn above the axis and the second below it. Lines
+// that don't parse are skipped. Sample reports the peak of the values that
+// arrived since the previous sample, or holds the last value when none did.
+type StdinCollector struct {
+	mu      sync.Mutex
+	last    [2]uint64
+	peak    [2]uint64
+	fresh   bool
+	skipped int
+	done    bool
+	series  []Series
+}
+
+// NewStdinCollector starts reading values from r in the background
+func NewStdinCollector(r io.Reader) *StdinCollector {
+	c := &StdinCollector{
+		series: []Series{
+			{Name: SeriesValue2},
+			{Name: SeriesValue1},
+		},
+	}
+	go c.read(r)
+	return c
+}
+
+// followInterval is how often a followed file is checked for new lines
+const followInterval = 200 * time.Millisecond
+
+// NewFileCollector charts values from lines appended to a file, like tail -f.
+// Lines already in the file are skipped: their values would arrive all at
+// once rather than as they were written.
+func NewFileCollector(path string) (*StdinCollector, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return NewStdinCollector(followReader{file}), nil
+}
+
+// followReader reads a file without ever reaching its end: at the end it
+// waits for more to be written
+type followReader struct {
+	file *os.File
+}
+
+// Read implements io.Reader
+func (r followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// read parses lines until r is exhausted
+func (c *StdinCollector) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		_, values, err := ParseValueLine(line)
+		c.mu.Lock()
+		if err != nil {
+			c.skipped++
+		} else {
+			c.add(values)
+		}
+		c.mu.Unlock()
+	}
+	c.mu.Lock()
+	c.done = true
+	c.mu.Unlock()
+}
+
+// add records a parsed line; the caller holds the lock
+func (c *StdinCollector) add(values []float64) {
+	for i := range c.last {
+		var scaled uint64
+		if i < len(values) {
+			scaled = uint64(math.Round(values[i] * StdinScale))
+		}
+		c.last[i] = scaled
+		if c.fresh {
+			c.peak[i] = max(c.peak[i], scaled)
+		} else {
+			c.peak[i] = scaled
+		}
+	}
+	c.fresh = true
+}
+
+// Sample implements Collector
+func (c *StdinCollector) Sample() ([]Series, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := c.last
+	if c.fresh {
+		values = c.peak
+		c.fresh = false
+	}
+	c.series[1].Value = values[0]
+	c.series[0].Value = values[1]
+	return c.series, nil
+}
+
+// Skipped returns how many lines could not be parsed
+func (c *StdinCollector) Skipped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skipped
+}
+
+// Done reports whether the input has ended
+func (c *StdinCollector) Done() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done
+}
+
+// ParseValueLine parses a "timestamp value1 [value2]" line, or a JSON object
+// with a "t" timestamp and either "value" (and "value2") or "down" and "up".
+// Timestamps are Unix seconds or RFC 3339. Values must not be negative.
+func ParseValueLine(line string) (time.Time, []float64, error) {
+	if strings.HasPrefix(line, "{") {
+		return parseValueJSON(line)
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return time.Time{}, nil, fmt.Errorf("expected a timestamp and one or two values, got %q", line)
+	}
+	at, err := parseTimestamp(fields[0])
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	values := make([]float64, 0, 2)
+	for _, field := range fields[1:] {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return time.Time{}, nil, fmt.Errorf("invalid value %q", field)
+		}
+		values = append(values, value)
+	}
+	return at, values, nil
+}
+
+// parseValueJSON parses a JSON value line
+func parseValueJSON(line string) (time.Time, []float64, error) {
+	var record struct {
+		T      json.RawMessage `json:"t"`
+		Value  *float64        `json:"value"`
+		Value2 *float64        `json:"value2"`
+		Down   *float64        `json:"down"`
+		Up     *float64        `json:"up"`
+	}
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return time.Time{}, nil, err
+	}
+	var at time.Time
+	if len(record.T) > 0 {
+		var err error
+		if at, err = parseTimestamp(strings.Trim(string(record.T), `"`)); err != nil {
+			return time.Time{}, nil, err
+		}
+	}
+	first, second := record.Value, record.Value2
+	if first == nil {
+		first, second = record.Down, record.Up
+	}
+	if first == nil {
+		return time.Time{}, nil, fmt.Errorf("no value in %q", line)
+	}
+	values := []float64{*first}
+	if second != nil {
+		values = append(values, *second)
+	}
+	for _, value := range values {
+		if value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+			return time.Time{}, nil, fmt.Errorf("invalid value %v", value)
+		}
+	}
+	return at, values, nil
+}
+
+// parseTimestamp parses Unix seconds (possibly fractional) or RFC 3339
+func parseTimestamp(s string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(frac*1e9)), nil
+	}
+	at, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q (expected Unix seconds or RFC 3339)", s)
+	}
+	return at, nil
+}
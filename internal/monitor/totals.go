@@ -0,0 +1,47 @@
+// Package monitor provides per-interface traffic totals
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// InterfaceTotals is the traffic one interface carried while peaks ran
+type InterfaceTotals struct {
+	Name            string `json:"name"`
+	SessionUpload   uint64 `json:"session_upload"` // Bytes since peaks started
+	SessionDownload uint64 `json:"session_download"`
+	TodayUpload     uint64 `json:"today_upload"` // Bytes since local midnight (or since start)
+	TodayDownload   uint64 `json:"today_download"`
+	PeakUpload      uint64 `json:"peak_upload"` // Bytes per second
+	PeakDownload    uint64 `json:"peak_download"`
+	Errors          uint64 `json:"errors"` // Receive and transmit errors since start
+	Drops           uint64 `json:"drops"`
+}
+
+// dayKey identifies the local calendar day of t without allocating
+func dayKey(t time.Time) int {
+	return t.Year()*1000 + t.YearDay()
+}
+
+// addTraffic accounts one sample interval of an interface's traffic
+func (t *InterfaceTotals) addTraffic(sent, received, uploadRate, downloadRate uint64) {
+	t.SessionUpload += sent
+	t.SessionDownload += received
+	t.TodayUpload += sent
+	t.TodayDownload += received
+	t.PeakUpload = max(t.PeakUpload, uploadRate)
+	t.PeakDownload = max(t.PeakDownload, downloadRate)
+}
+
+// InterfaceTotals returns the totals of every monitored interface, by name
+func (bm *BandwidthMonitor) InterfaceTotals() []InterfaceTotals {
+	totals := make([]InterfaceTotals, 0, len(bm.interfaces))
+	for _, state := range bm.interfaces {
+		if !state.filtered {
+			totals = append(totals, state.totals)
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Name < totals[j].Name })
+	return totals
+}
@@ -0,0 +1,144 @@
+// Package monitor provides Wi-Fi link status
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WifiTimeout bounds a single Wi-Fi status read
+const WifiTimeout = 2 * time.Second
+
+// Errors reading the Wi-Fi status
+var (
+	// ErrNoWifi means no wireless interface was found
+	ErrNoWifi = errors.New("no Wi-Fi interface")
+	// ErrWifiUnsupported means the platform has no supported Wi-Fi tool
+	ErrWifiUnsupported = errors.New("Wi-Fi status is not supported on this platform")
+)
+
+// WifiStatus describes the association of a wireless interface
+type WifiStatus struct {
+	Interface string
+	Connected bool
+	SSID      string
+	Signal    int    // dBm, 0 if unknown
+	Quality   int    // signal quality in percent
+	RxRate    uint64 // receive bit rate in bits per second, 0 if unknown
+	TxRate    uint64 // transmit bit rate in bits per second, 0 if unknown
+}
+
+// ReadWifi returns the status of the first wireless interface: read with iw
+// on Linux, airport on macOS and netsh on Windows.
+func ReadWifi(ctx context.Context) (WifiStatus, error) {
+	return readWifi(ctx)
+}
+
+// signalQuality maps a signal strength to a percentage the way
+// NetworkManager does: -100 dBm and below is 0%, -50 dBm and above 100%
+func signalQuality(dbm int) int {
+	return min(max(2*(dbm+100), 0), 100)
+}
+
+// mbps parses a rate in Mbit/s, as the Wi-Fi tools print it, into bits per
+// second; the rate may be followed by a unit and modulation details
+func mbps(value string) uint64 {
+	field, _, _ := strings.Cut(strings.TrimSpace(value), " ")
+	rate, err := strconv.ParseFloat(field, 64)
+	if err != nil || rate < 0 {
+		return 0
+	}
+	return uint64(rate * 1e6)
+}
+
+// wifiFields calls field for each "key: value" line of a Wi-Fi tool's
+// output, trimmed, stopping when it returns false
+func wifiFields(output string, field func(key, value string) bool) {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && !field(strings.TrimSpace(key), strings.TrimSpace(value)) {
+			return
+		}
+	}
+}
+
+// ParseIwLink parses the output of "iw dev IFACE link", e.g.
+//
+//	Connected to aa:bb:cc:dd:ee:ff (on wlan0)
+//		SSID: home
+//		signal: -52 dBm
+//		rx bitrate: 866.7 MBit/s VHT-MCS 9 80MHz short GI VHT-NSS 2
+//		tx bitrate: 780.0 MBit/s VHT-MCS 8 80MHz short GI VHT-NSS 2
+func ParseIwLink(output string) WifiStatus {
+	var status WifiStatus
+	status.Connected = strings.HasPrefix(strings.TrimSpace(output), "Connected to")
+	wifiFields(output, func(key, value string) bool {
+		switch key {
+		case "SSID":
+			status.SSID = value
+		case "signal":
+			dbm, _, _ := strings.Cut(value, " ")
+			status.Signal, _ = strconv.Atoi(dbm)
+			status.Quality = signalQuality(status.Signal)
+		case "rx bitrate":
+			status.RxRate = mbps(value)
+		case "tx bitrate":
+			status.TxRate = mbps(value)
+		}
+		return true
+	})
+	return status
+}
+
+// ParseAirport parses the output of macOS's "airport -I", which reports
+// the transmit rate only
+func ParseAirport(output string) WifiStatus {
+	var status WifiStatus
+	wifiFields(output, func(key, value string) bool {
+		switch key {
+		case "SSID":
+			status.SSID = value
+			status.Connected = value != ""
+		case "agrCtlRSSI":
+			status.Signal, _ = strconv.Atoi(value)
+			status.Quality = signalQuality(status.Signal)
+		case "lastTxRate":
+			status.TxRate = mbps(value)
+		}
+		return true
+	})
+	return status
+}
+
+// ParseNetshWlan parses the first interface in the output of Windows's
+// "netsh wlan show interfaces", which reports signal quality in percent
+func ParseNetshWlan(output string) WifiStatus {
+	var status WifiStatus
+	wifiFields(output, func(key, value string) bool {
+		switch key {
+		case "Name":
+			if status.Interface != "" {
+				return false // the next interface
+			}
+			status.Interface = value
+		case "State":
+			status.Connected = value == "connected"
+		case "SSID":
+			status.SSID = value
+		case "Signal":
+			status.Quality, _ = strconv.Atoi(strings.TrimSuffix(value, "%"))
+			status.Signal = status.Quality/2 - 100
+		case "Receive rate (Mbps)":
+			status.RxRate = mbps(value)
+		case "Transmit rate (Mbps)":
+			status.TxRate = mbps(value)
+		}
+		return true
+	})
+	return status
+}
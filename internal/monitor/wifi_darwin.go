@@ -0,0 +1,21 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// airportPath is Apple's private command-line Wi-Fi tool
+const airportPath = "/System/Library/PrivateFrameworks/Apple80211.framework/Versions/Current/Resources/airport"
+
+// readWifi asks airport for the status of the Wi-Fi interface
+func readWifi(ctx context.Context) (WifiStatus, error) {
+	out, err := exec.CommandContext(ctx, airportPath, "-I").Output()
+	if err != nil {
+		return WifiStatus{}, fmt.Errorf("airport: %w", err)
+	}
+	status := ParseAirport(string(out))
+	status.Interface = "Wi-Fi"
+	return status, nil
+}
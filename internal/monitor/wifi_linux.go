@@ -0,0 +1,31 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// readWifi finds the first interface with a wireless directory in sysfs
+// and asks iw for its link
+func readWifi(ctx context.Context) (WifiStatus, error) {
+	entries, err := os.ReadDir(sysClassNet)
+	if err != nil {
+		return WifiStatus{}, err
+	}
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(sysClassNet, entry.Name(), "wireless")); err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, "iw", "dev", entry.Name(), "link").Output()
+		if err != nil {
+			return WifiStatus{Interface: entry.Name()}, fmt.Errorf("iw: %w", err)
+		}
+		status := ParseIwLink(string(out))
+		status.Interface = entry.Name()
+		return status, nil
+	}
+	return WifiStatus{}, ErrNoWifi
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package monitor
+
+import "context"
+
+// readWifi is not supported on this platform
+func readWifi(context.Context) (WifiStatus, error) {
+	return WifiStatus{}, ErrWifiUnsupported
+}
@@ -0,0 +1,20 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// readWifi asks netsh for the status of the first WLAN interface
+func readWifi(ctx context.Context) (WifiStatus, error) {
+	out, err := exec.CommandContext(ctx, "netsh", "wlan", "show", "interfaces").Output()
+	if err != nil {
+		return WifiStatus{}, fmt.Errorf("netsh: %w", err)
+	}
+	status := ParseNetshWlan(string(out))
+	if status.Interface == "" {
+		return WifiStatus{}, ErrNoWifi
+	}
+	return status, nil
+}
@@ -0,0 +1,124 @@
+// Package monitor provides WSL detection and Windows host counters
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IsWSL reports whether peaks is running inside the Windows Subsystem for Linux.
+// Under WSL2 the Linux interfaces only see the VM's traffic, not the host's.
+func IsWSL() bool {
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false
+	}
+	release = bytes.ToLower(release)
+	return bytes.Contains(release, []byte("microsoft")) || bytes.Contains(release, []byte("wsl"))
+}
+
+// windowsHostScript prints every adapter's counters as tab-separated lines,
+// with a blank line ending each snapshot. A single long-lived PowerShell keeps
+// the per-sample cost low compared to spawning one process per tick.
+const windowsHostScript = `$ProgressPreference = 'SilentlyContinue'
+while ($true) {
+  Get-NetAdapterStatistics | ForEach-Object { "$($_.Name)` + "`t" + `$($_.SentBytes)` + "`t" + `$($_.ReceivedBytes)" }
+  ""
+  Start-Sleep -Milliseconds 500
+}`
+
+// WindowsHostSource reads the Windows host's adapter counters from inside WSL
+// through the powershell.exe interop bridge
+type WindowsHostSource struct {
+	cmd    *exec.Cmd
+	mu     sync.Mutex
+	latest []InterfaceCounters
+	err    error
+}
+
+// NewWindowsHostSource starts the interop bridge. It fails outside WSL or when
+// powershell.exe is not reachable (e.g. interop disabled in wsl.conf).
+func NewWindowsHostSource() (*WindowsHostSource, error) {
+	if !IsWSL() {
+		return nil, fmt.Errorf("windows host counters are only available under WSL")
+	}
+	path, err := exec.LookPath("powershell.exe")
+	if err != nil {
+		return nil, fmt.Errorf("windows interop unavailable: %w", err)
+	}
+
+	cmd := exec.Command(path, "-NoProfile", "-NonInteractive", "-Command", windowsHostScript)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start windows interop bridge: %w", err)
+	}
+
+	src := &WindowsHostSource{cmd: cmd}
+	go src.read(bufio.NewScanner(stdout))
+	return src, nil
+}
+
+// read consumes snapshots from the bridge until it exits
+func (w *WindowsHostSource) read(scanner *bufio.Scanner) {
+	var pending []InterfaceCounters
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			w.mu.Lock()
+			w.latest, pending = pending, w.latest[:0]
+			w.mu.Unlock()
+			continue
+		}
+		if counters, ok := parseWindowsHostLine(line); ok {
+			pending = append(pending, counters)
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = w.cmd.Wait()
+	}
+	w.mu.Lock()
+	w.err = fmt.Errorf("windows interop bridge exited: %v", err)
+	w.mu.Unlock()
+}
+
+// parseWindowsHostLine parses a "name<TAB>sent<TAB>received" line
+func parseWindowsHostLine(line string) (InterfaceCounters, bool) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 3 {
+		return InterfaceCounters{}, false
+	}
+	sent, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return InterfaceCounters{}, false
+	}
+	recv, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return InterfaceCounters{}, false
+	}
+	return InterfaceCounters{Name: fields[0], BytesSent: sent, BytesRecv: recv}, true
+}
+
+// ReadCounters implements CounterSource, returning the most recent host snapshot.
+// Before the first snapshot arrives it returns no interfaces.
+func (w *WindowsHostSource) ReadCounters(dst []InterfaceCounters, skip func(name []byte) bool) ([]InterfaceCounters, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return dst, w.err
+	}
+	return append(dst, w.latest...), nil
+}
@@ -7,6 +7,7 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -16,10 +17,41 @@ import (
 type KeyMap struct {
 	Reset       key.Binding
 	Pause       key.Binding
+	Stop        key.Binding
+	Marker      key.Binding
+	Markers     key.Binding
 	Stats       key.Binding
 	DisplayMode key.Binding
 	ScalingMode key.Binding
+	LockScale   key.Binding
+	Smooth      key.Binding
+	Theme       key.Binding
 	TimeScale   key.Binding
+	SpeedTest   key.Binding
+	Screenshot  key.Binding
+	ExportCSV   key.Binding
+	ExportJSON  key.Binding
+	Copy        key.Binding
+	Ghost       key.Binding
+	NextIface   key.Binding
+	CursorLeft  key.Binding
+	CursorRight key.Binding
+	HideCursor  key.Binding
+	PrevIface   key.Binding
+	Processes   key.Binding
+	Connections key.Binding
+	Protocols   key.Binding
+	Units       key.Binding
+	Usage       key.Binding
+	UsagePeriod key.Binding
+	HostNames   key.Binding
+	Wifi        key.Binding
+	StatsPanel  key.Binding
+	ProcessSort key.Binding
+	SaveConfig  key.Binding
+	NextChart   key.Binding
+	Faster      key.Binding
+	Slower      key.Binding
 	Quit        key.Binding
 }
 
@@ -34,6 +66,18 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("p", " "),
 			key.WithHelp("p/space", "pause/resume"),
 		),
+		Stop: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "stop/resume sampling"),
+		),
+		Marker: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "drop a labeled marker"),
+		),
+		Markers: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", "list markers"),
+		),
 		Stats: key.NewBinding(
 			key.WithKeys("s"),
 			key.WithHelp("s", "toggle statusbar"),
@@ -46,10 +90,122 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("l"),
 			key.WithHelp("l", "cycle scaling mode"),
 		),
+		LockScale: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "lock/unlock scale"),
+		),
+		Smooth: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "toggle smoothing"),
+		),
+		Theme: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "cycle color theme"),
+		),
 		TimeScale: key.NewBinding(
 			key.WithKeys("t"),
 			key.WithHelp("t", "cycle time scale"),
 		),
+		SpeedTest: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "run speed test"),
+		),
+		Screenshot: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "save screenshot"),
+		),
+		ExportCSV: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "export data as CSV"),
+		),
+		ExportJSON: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "export data as JSON"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy stats"),
+		),
+		Ghost: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "ghost previous session"),
+		),
+		CursorLeft: key.NewBinding(
+			key.WithKeys("left", "shift+left"),
+			key.WithHelp("←", "move cursor back"),
+		),
+		CursorRight: key.NewBinding(
+			key.WithKeys("right", "shift+right"),
+			key.WithHelp("→", "move cursor forward"),
+		),
+		HideCursor: key.NewBinding(
+			key.WithKeys("esc", "end"),
+			key.WithHelp("esc", "hide cursor"),
+		),
+		NextIface: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next interface"),
+		),
+		PrevIface: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous interface"),
+		),
+		Processes: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "toggle process list"),
+		),
+		Connections: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "toggle connection list"),
+		),
+		Usage: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "toggle usage tab"),
+		),
+		UsagePeriod: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "cycle usage period"),
+		),
+		Protocols: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", "toggle protocol breakdown"),
+		),
+		Units: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "toggle bits/bytes per second"),
+		),
+		HostNames: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "toggle top-talker host names"),
+		),
+		Wifi: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "toggle Wi-Fi panel"),
+		),
+		StatsPanel: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "toggle rate statistics"),
+		),
+		ProcessSort: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "sort process and connection lists"),
+		),
+		SaveConfig: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "save settings"),
+		),
+		NextChart: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "focus next chart"),
+		),
+		Faster: key.NewBinding(
+			key.WithKeys("+", "=", "]"),
+			key.WithHelp("+", "refresh faster"),
+		),
+		Slower: key.NewBinding(
+			key.WithKeys("-", "_", "["),
+			key.WithHelp("-", "refresh slower"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q", "esc", "ctrl+c"),
 			key.WithHelp("q", "quit"),
@@ -66,6 +222,10 @@ type Stats struct {
 	StartTime     time.Time
 	// Optimization: cache update interval to reduce repeated calculations
 	updateInterval time.Duration
+	clock          func() time.Time
+	// Rolling statistics windows and the samples kept for them
+	windows []time.Duration
+	samples []rateSample
 }
 
 // NewStats creates a new stats tracker
@@ -73,6 +233,8 @@ func NewStats() *Stats {
 	return &Stats{
 		StartTime:      time.Now(),
 		updateInterval: 500 * time.Millisecond, // Cache the update interval
+		clock:          time.Now,
+		windows:        DefaultStatsWindows,
 	}
 }
 
@@ -93,11 +255,24 @@ func (s *Stats) Update(upload, download uint64) {
 	if download > s.PeakDownload {
 		s.PeakDownload = download
 	}
+	s.recordSample(upload, download)
+}
+
+// SetUpdateInterval sets the time between samples passed to Update
+func (s *Stats) SetUpdateInterval(interval time.Duration) {
+	s.updateInterval = interval
+}
+
+// SetClock injects the time source the uptime is measured with and
+// restarts the uptime from it
+func (s *Stats) SetClock(clock func() time.Time) {
+	s.clock = clock
+	s.StartTime = clock()
 }
 
 // GetUptime returns the uptime duration
 func (s *Stats) GetUptime() time.Duration {
-	return time.Since(s.StartTime)
+	return s.clock().Sub(s.StartTime)
 }
 
 // Reset resets all statistics
@@ -106,7 +281,8 @@ func (s *Stats) Reset() {
 	s.TotalDownload = 0
 	s.PeakUpload = 0
 	s.PeakDownload = 0
-	s.StartTime = time.Now()
+	s.samples = nil
+	s.StartTime = s.clock()
 }
 
 // Enhanced UI components
@@ -126,20 +302,142 @@ func (c *Components) GetStats() *Stats {
 	return c.stats
 }
 
+// Byte prefixes selectable with --prefixes
+const (
+	// PrefixesBinary steps by 1024 and writes KB, MB, …
+	PrefixesBinary = "binary"
+	// PrefixesIEC steps by 1024 and writes KiB, MiB, …
+	PrefixesIEC = "iec"
+	// PrefixesSI steps by 1000 and writes kB, MB, …
+	PrefixesSI = "si"
+)
+
+// The step and prefixes of byte rates and sizes, chosen with SetPrefixes
+var (
+	byteBase     uint64 = 1024
+	bytePrefixes        = []string{"K", "M", "G", "T", "P", "E"}
+)
+
+// ParsePrefixes validates a byte prefix name
+func ParsePrefixes(name string) (string, error) {
+	switch name {
+	case PrefixesBinary, PrefixesIEC, PrefixesSI:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown prefixes %q (expected binary, iec or si)", name)
+}
+
+// SetPrefixes chooses the prefixes FormatBandwidth, FormatBandwidthShort and
+// FormatBytes use. It is not safe to call while formatting: call it once
+// at startup.
+func SetPrefixes(name string) {
+	switch name {
+	case PrefixesIEC:
+		byteBase, bytePrefixes = 1024, []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+	case PrefixesSI:
+		byteBase, bytePrefixes = 1000, []string{"k", "M", "G", "T", "P", "E"}
+	default:
+		byteBase, bytePrefixes = 1024, []string{"K", "M", "G", "T", "P", "E"}
+	}
+}
+
 // FormatBandwidth formats bandwidth for UI display
 func FormatBandwidth(bps uint64) string {
-	const unit = 1024
-	if bps < unit {
+	if bps < byteBase {
 		return fmt.Sprintf("%d B/s", bps)
 	}
-	div, exp := uint64(unit), 0
-	for n := bps / unit; n >= unit; n /= unit {
-		div *= unit
+	div, exp := byteBase, 0
+	for n := bps / byteBase; n >= byteBase; n /= byteBase {
+		div *= byteBase
+		exp++
+	}
+	return fmt.Sprintf("%.2f %sB/s", float64(bps)/float64(div), bytePrefixes[exp])
+}
+
+// FormatBandwidthShort formats bandwidth in at most five columns, e.g.
+// "1.2M", for narrow panels
+func FormatBandwidthShort(bps uint64) string {
+	if bps < byteBase {
+		return fmt.Sprintf("%dB", bps)
+	}
+	unit := float64(byteBase)
+	value, exp := float64(bps)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	// One letter prefixes keep to five columns
+	prefix := bytePrefixes[exp][:1]
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, prefix)
+	}
+	return fmt.Sprintf("%.0f%s", value, prefix)
+}
+
+// Rate units selectable with --units
+const (
+	UnitsBytes = "bytes"
+	UnitsBits  = "bits"
+)
+
+// ParseUnits validates a rate unit name
+func ParseUnits(name string) (string, error) {
+	switch name {
+	case UnitsBytes, UnitsBits:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown units %q (expected bits or bytes)", name)
+}
+
+// FormatBitrate formats a byte rate in bits per second with SI prefixes,
+// as link speeds are quoted, e.g. "94.20 Mb/s"
+func FormatBitrate(bps uint64) string {
+	bits := float64(bps) * 8
+	if bits < 1000 {
+		return fmt.Sprintf("%.0f b/s", bits)
+	}
+	units := []string{"Kb/s", "Mb/s", "Gb/s", "Tb/s", "Pb/s", "Eb/s"}
+	exp := 0
+	for bits /= 1000; bits >= 1000 && exp < len(units)-1; bits /= 1000 {
+		exp++
+	}
+	return fmt.Sprintf("%.2f %s", bits, units[exp])
+}
+
+// FormatBitrateShort formats a byte rate in bits per second in at most five
+// columns, e.g. "94Mb", for narrow panels
+func FormatBitrateShort(bps uint64) string {
+	bits := float64(bps) * 8
+	if bits < 1000 {
+		return fmt.Sprintf("%.0fb", bits)
+	}
+	value, exp := bits/1000, 0
+	for value >= 1000 && exp < 4 {
+		value /= 1000
 		exp++
 	}
-	// Optimization: pre-defined units array to avoid string indexing
-	units := []string{"KB/s", "MB/s", "GB/s", "TB/s", "PB/s", "EB/s"}
-	return fmt.Sprintf("%.2f %s", float64(bps)/float64(div), units[exp])
+	units := "KMGTP"
+	if value < 10 {
+		return fmt.Sprintf("%.1f%cb", value, units[exp])
+	}
+	return fmt.Sprintf("%.0f%cb", value, units[exp])
+}
+
+// FormatCPU formats a CPU time rate (microseconds per second) as a percentage
+// of a single core
+func FormatCPU(usecPerSec uint64) string {
+	return fmt.Sprintf("%.1f%%", float64(usecPerSec)/1e4)
+}
+
+// FormatValue formats a value piped in with --stdin, which is charted in
+// thousandths
+func FormatValue(milli uint64) string {
+	return strconv.FormatFloat(float64(milli)/1000, 'g', 4, 64)
+}
+
+// FormatCPUTime formats accumulated CPU time (microseconds)
+func FormatCPUTime(usec uint64) string {
+	return FormatDuration(time.Duration(usec) * time.Microsecond)
 }
 
 // FormatDuration formats a duration in a human-readable way
@@ -158,26 +456,16 @@ func FormatDuration(d time.Duration) string {
 	}
 }
 
-// FormatBytes formats bytes in a human-readable way
+// FormatBytes formats bytes in a human-readable way, in up to terabytes
 func FormatBytes(bytes uint64) string {
-	const (
-		KB = 1024
-		MB = KB * 1024
-		GB = MB * 1024
-		TB = GB * 1024
-	)
-
-	// Optimization: avoid multiple comparisons, use single switch
-	switch {
-	case bytes >= TB:
-		return fmt.Sprintf("%.2f TB", float64(bytes)/TB)
-	case bytes >= GB:
-		return fmt.Sprintf("%.2f GB", float64(bytes)/GB)
-	case bytes >= MB:
-		return fmt.Sprintf("%.2f MB", float64(bytes)/MB)
-	case bytes >= KB:
-		return fmt.Sprintf("%.2f KB", float64(bytes)/KB)
-	default:
+	if bytes < byteBase {
 		return fmt.Sprintf("%d B", bytes)
 	}
+	unit := float64(byteBase)
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %sB", value, bytePrefixes[exp])
 }
@@ -0,0 +1,133 @@
+// Package ui provides rolling average and percentile statistics
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultStatsWindows are the windows rolling statistics are kept over
+var DefaultStatsWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// RateSummary sums up one direction's rates over a window
+type RateSummary struct {
+	Average uint64
+	Median  uint64
+	P95     uint64 // 95th percentile
+}
+
+// WindowStats are the rolling statistics over the last Window
+type WindowStats struct {
+	Window   time.Duration
+	Samples  int
+	Upload   RateSummary
+	Download RateSummary
+}
+
+// rateSample is a sample kept for the rolling statistics
+type rateSample struct {
+	at               time.Time
+	upload, download uint64
+}
+
+// ParseStatsWindows parses a comma-separated list of windows, e.g. 1m,5m,1h
+func ParseStatsWindows(value string) ([]time.Duration, error) {
+	var windows []time.Duration
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		window, err := time.ParseDuration(field)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid stats window %q (expected e.g. 1m,5m,15m)", field)
+		}
+		windows = append(windows, window)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no stats windows in %q", value)
+	}
+	return windows, nil
+}
+
+// SetWindows sets the windows Windowed summarises, shortest first. Samples
+// are kept for the longest of them.
+func (s *Stats) SetWindows(windows []time.Duration) {
+	s.windows = slices.Clone(windows)
+	slices.Sort(s.windows)
+	s.pruneSamples()
+}
+
+// Windowed returns the average, median and 95th percentile of each
+// direction's rates over each window, shortest first
+func (s *Stats) Windowed() []WindowStats {
+	now := s.clock()
+	stats := make([]WindowStats, 0, len(s.windows))
+	upload := make([]uint64, 0, len(s.samples))
+	download := make([]uint64, 0, len(s.samples))
+	for _, window := range s.windows {
+		upload, download = upload[:0], download[:0]
+		start := now.Add(-window)
+		for _, sample := range s.samples {
+			if sample.at.After(start) {
+				upload = append(upload, sample.upload)
+				download = append(download, sample.download)
+			}
+		}
+		stats = append(stats, WindowStats{
+			Window:   window,
+			Samples:  len(upload),
+			Upload:   summarize(upload),
+			Download: summarize(download),
+		})
+	}
+	return stats
+}
+
+// recordSample keeps a sample for the rolling statistics
+func (s *Stats) recordSample(upload, download uint64) {
+	if len(s.windows) == 0 {
+		return
+	}
+	s.samples = append(s.samples, rateSample{at: s.clock(), upload: upload, download: download})
+	s.pruneSamples()
+}
+
+// pruneSamples drops samples older than the longest window
+func (s *Stats) pruneSamples() {
+	if len(s.windows) == 0 {
+		s.samples = nil
+		return
+	}
+	start := s.clock().Add(-s.windows[len(s.windows)-1])
+	old := 0
+	for old < len(s.samples) && !s.samples[old].at.After(start) {
+		old++
+	}
+	s.samples = s.samples[old:]
+}
+
+// summarize sorts rates in place and sums them up. Percentiles are taken by
+// nearest rank, so they are always rates that were measured.
+func summarize(rates []uint64) RateSummary {
+	if len(rates) == 0 {
+		return RateSummary{}
+	}
+	var sum float64
+	for _, rate := range rates {
+		sum += float64(rate)
+	}
+	slices.Sort(rates)
+	return RateSummary{
+		Average: uint64(sum / float64(len(rates))),
+		Median:  percentile(rates, 50),
+		P95:     percentile(rates, 95),
+	}
+}
+
+// percentile returns the p-th percentile of sorted rates by nearest rank
+func percentile(sorted []uint64, p int) uint64 {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,119 @@
+// Package ui provides terminal width measurement
+package ui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-runewidth"
+)
+
+// StringWidth returns the number of terminal cells s occupies, ignoring ANSI
+// escape sequences. Unlike byte or rune counts it honors double-width and, on
+// CJK locales, East Asian ambiguous-width characters such as arrows.
+func StringWidth(s string) int {
+	return runewidth.StringWidth(ansi.Strip(s))
+}
+
+// Truncate cuts s to at most width cells, keeping ANSI escape sequences intact
+// and resetting styles if anything was cut. Wide characters that would
+// straddle the limit are dropped rather than split.
+func Truncate(s string, width int) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	used := 0
+	for i := 0; i < len(s); {
+		// Copy escape sequences through without counting them
+		if s[i] == '\x1b' {
+			end := escapeEnd(s, i)
+			b.WriteString(s[i:end])
+			i = end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runewidth.RuneWidth(r)
+		if used+w > width {
+			break
+		}
+		b.WriteString(s[i : i+size])
+		used += w
+		i += size
+	}
+	b.WriteString("\x1b[0m")
+	return b.String()
+}
+
+// escapeEnd returns the index just past the escape sequence starting at i.
+// CSI sequences end at a final byte in 0x40-0x7E; OSC sequences at BEL or ST.
+func escapeEnd(s string, i int) int {
+	if i+1 >= len(s) {
+		return len(s)
+	}
+	switch s[i+1] {
+	case '[':
+		for j := i + 2; j < len(s); j++ {
+			if s[j] >= 0x40 && s[j] <= 0x7E {
+				return j + 1
+			}
+		}
+		return len(s)
+	case ']':
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == '\a' {
+				return j + 1
+			}
+			if s[j] == '\x1b' && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 2
+			}
+		}
+		return len(s)
+	default:
+		return i + 2
+	}
+}
+
+// Overlay draws box over the lines of background with its top left corner at
+// column x of line y, keeping the background either side of it. Lines of box
+// past the end of background are dropped.
+func Overlay(background, box string, x, y int) string {
+	lines := strings.Split(background, "\n")
+	for i, row := range strings.Split(box, "\n") {
+		if y+i < 0 || y+i >= len(lines) {
+			continue
+		}
+		line := lines[y+i]
+		left := Truncate(line, x)
+		left += strings.Repeat(" ", max(x-StringWidth(left), 0))
+		lines[y+i] = left + row + skipCells(line, x+StringWidth(row))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// skipCells drops the first width cells of s, keeping the escape sequences
+// among them so the rest is styled as before. A double-width rune cut in half
+// becomes a space.
+func skipCells(s string, width int) string {
+	var escapes strings.Builder
+	used := 0
+	for i := 0; i < len(s); {
+		if used >= width {
+			return escapes.String() + strings.Repeat(" ", used-width) + s[i:]
+		}
+		if s[i] == '\x1b' {
+			end := escapeEnd(s, i)
+			escapes.WriteString(s[i:end])
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		used += runewidth.RuneWidth(r)
+		i += size
+	}
+	return escapes.String() + strings.Repeat(" ", max(used-width, 0))
+}
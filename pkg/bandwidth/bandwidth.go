@@ -0,0 +1,118 @@
+// Package bandwidth is the public API of peaks' cross-platform network rate
+// monitor, for embedding in other tools. It reads the interface counters
+// (/proc/net/dev on Linux, gopsutil elsewhere) and turns them into rates.
+//
+//	m := bandwidth.New()
+//	for range time.Tick(500 * time.Millisecond) {
+//		series, err := m.Sample()
+//		if errors.Is(err, bandwidth.ErrSampleGap) {
+//			continue // Resumed from suspend: no trustworthy rate
+//		}
+//		upload, download := bandwidth.SplitSeries(series)
+//		fmt.Println(upload, download)
+//	}
+//
+// New reads the counters once as a baseline, so the first Sample already
+// reports the rates since then. Only the methods below are part of the API;
+// the monitor behind them is free to change.
+package bandwidth
+
+import (
+	"github.com/marcodenic/peaks/internal/monitor"
+)
+
+// Monitor samples the network interfaces' byte counters and reports the
+// upload and download rates since the previous sample. It is not safe for
+// concurrent use.
+type Monitor struct {
+	monitor *monitor.BandwidthMonitor
+}
+
+// Rates is a pair of upload and download rates in bytes per second
+type Rates = monitor.BandwidthRates
+
+// InterfaceTotals is the traffic one interface carried since the monitor
+// was created
+type InterfaceTotals = monitor.InterfaceTotals
+
+// InterfaceGroup sums several interfaces into one named series
+type InterfaceGroup = monitor.InterfaceGroup
+
+// Series is a named rate in units per second
+type Series = monitor.Series
+
+// Collector is a source of rate samples. Monitor implements it, as do
+// peaks' other sources; the first series is upload and the second download.
+type Collector = monitor.Collector
+
+// ErrSampleGap is returned by Sample when the interval since the previous
+// sample was interrupted, e.g. by a suspend or a clock jump. The counters
+// are re-baselined; record a gap rather than a rate.
+var ErrSampleGap = monitor.ErrSampleGap
+
+// New creates a monitor of every interface except loopback, using the
+// platform's counters, and takes its baseline reading
+func New() *Monitor {
+	return &Monitor{monitor: monitor.NewBandwidthMonitor()}
+}
+
+// Sample implements Collector: the upload and download rates since the
+// previous sample, followed by each interface group's. After an
+// interrupted interval the rates are zero and the error is ErrSampleGap.
+// The slice is reused by the next call.
+func (m *Monitor) Sample() ([]Series, error) {
+	return m.monitor.Sample()
+}
+
+// Rates returns the upload and download rates since the previous sample
+// or call, like Sample without the groups
+func (m *Monitor) Rates() (upload, download uint64, err error) {
+	return m.monitor.GetCurrentRates()
+}
+
+// SetInterfaceFilter sets which interfaces count towards the rates; nil
+// restores DefaultInterfaceFilter
+func (m *Monitor) SetInterfaceFilter(filter func(name string) bool) {
+	m.monitor.SetInterfaceFilter(filter)
+}
+
+// SetInterfaceGroups adds a pair of series per group to Sample. With
+// groups set, the first group alone makes up the overall rates.
+func (m *Monitor) SetInterfaceGroups(groups []InterfaceGroup) {
+	m.monitor.SetInterfaceGroups(groups)
+}
+
+// SetFocus limits the rates to one interface; "" counts them all again
+func (m *Monitor) SetFocus(name string) {
+	m.monitor.SetFocus(name)
+}
+
+// InterfaceRates returns one interface's rates at the last sample, ignoring
+// the focus, and false if it isn't monitored
+func (m *Monitor) InterfaceRates(name string) (Rates, bool) {
+	return m.monitor.InterfaceRates(name)
+}
+
+// InterfaceTotals returns the traffic of each monitored interface since the
+// monitor was created, by name
+func (m *Monitor) InterfaceTotals() []InterfaceTotals {
+	return m.monitor.InterfaceTotals()
+}
+
+// DefaultInterfaceFilter is the filter a new Monitor starts with: every
+// interface except loopback. Pass a different one to SetInterfaceFilter.
+func DefaultInterfaceFilter(name string) bool {
+	return monitor.DefaultInterfaceFilter(name)
+}
+
+// ParseInterfaceGroup parses a group spec such as "LAN=eth1,eth2" for
+// SetInterfaceGroups
+func ParseInterfaceGroup(spec string) (InterfaceGroup, error) {
+	return monitor.ParseInterfaceGroup(spec)
+}
+
+// SplitSeries returns the first two series as an upload/download pair,
+// zero where missing
+func SplitSeries(series []Series) (upload, download uint64) {
+	return monitor.SplitSeries(series)
+}
@@ -0,0 +1,215 @@
+// Package braillechart is the public API of peaks' braille chart widget,
+// for embedding in other terminal UIs such as Bubble Tea programs. A Chart
+// keeps a rolling buffer of upload/download samples and renders it as a
+// block of styled text, split around a centre axis or overlaid.
+//
+//	ch := braillechart.New(braillechart.DefaultMaxPoints)
+//	ch.SetWidth(60)
+//	ch.SetHeight(10)
+//	ch.AddDataPoint(upload, download) // Once per sample, e.g. every 500ms
+//	fmt.Println(ch.Render())
+//
+// Render reuses its previous frame while nothing visible has changed, so it
+// is cheap to call on every redraw. Only the methods below are part of the
+// API; the renderer behind them is free to change.
+package braillechart
+
+import (
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/marcodenic/peaks/internal/chart"
+)
+
+// Chart is a braille chart of upload and download rates. It is not safe
+// for concurrent use.
+type Chart struct {
+	chart *chart.BrailleChart
+}
+
+// DataPoint is one sample returned by Chart.ExportData
+type DataPoint = chart.DataPoint
+
+// ScalingMode selects how rates map to column heights
+type ScalingMode = chart.ScalingMode
+
+// Scaling modes
+const (
+	ScalingLinear      = chart.ScalingLinear
+	ScalingLogarithmic = chart.ScalingLogarithmic
+	ScalingSquareRoot  = chart.ScalingSquareRoot
+)
+
+// TimeScale selects how much time the chart's width covers
+type TimeScale = chart.TimeScale
+
+// Time scales; those of three hours and more are drawn from aggregated
+// history
+const (
+	TimeScale1Min   = chart.TimeScale1Min
+	TimeScale3Min   = chart.TimeScale3Min
+	TimeScale5Min   = chart.TimeScale5Min
+	TimeScale10Min  = chart.TimeScale10Min
+	TimeScale15Min  = chart.TimeScale15Min
+	TimeScale30Min  = chart.TimeScale30Min
+	TimeScale60Min  = chart.TimeScale60Min
+	TimeScale3Hour  = chart.TimeScale3Hour
+	TimeScale6Hour  = chart.TimeScale6Hour
+	TimeScale12Hour = chart.TimeScale12Hour
+	TimeScale24Hour = chart.TimeScale24Hour
+)
+
+// GlyphSet selects the characters cells are drawn with
+type GlyphSet = chart.GlyphSet
+
+// Glyph sets
+const (
+	GlyphBraille = chart.GlyphBraille
+	GlyphBlock   = chart.GlyphBlock
+	GlyphASCII   = chart.GlyphASCII
+)
+
+// GutterPlacement selects which sides get an axis gutter with scale labels
+type GutterPlacement = chart.GutterPlacement
+
+// Gutter placements
+const (
+	GutterNone  = chart.GutterNone
+	GutterLeft  = chart.GutterLeft
+	GutterRight = chart.GutterRight
+	GutterBoth  = chart.GutterBoth
+)
+
+// Size limits: SetHeight won't go below MinHeight rows unless SetMinHeight
+// lowers it, and charts stay usable down to MinWidth columns
+const (
+	MinHeight = chart.MinChartHeight
+	MinWidth  = chart.MinChartWidth
+)
+
+// DefaultMaxPoints keeps 60 minutes of samples taken every 500ms, enough
+// for every time scale up to an hour
+const DefaultMaxPoints = 60 * 60 * 2
+
+// New creates a chart keeping up to maxPoints samples
+func New(maxPoints int) *Chart {
+	return &Chart{chart: chart.NewBrailleChart(maxPoints)}
+}
+
+// AddDataPoint appends a sample of upload and download rates
+func (c *Chart) AddDataPoint(upload, download uint64) {
+	c.chart.AddDataPoint(upload, download)
+}
+
+// AddGap appends an interrupted interval, drawn as a gap rather than a rate
+func (c *Chart) AddGap() {
+	c.chart.AddGap()
+}
+
+// Reset clears every sample
+func (c *Chart) Reset() {
+	c.chart.Reset()
+}
+
+// Render draws the chart as Height lines of Width columns
+func (c *Chart) Render() string {
+	return c.chart.Render()
+}
+
+// SetWidth sets the width in terminal columns
+func (c *Chart) SetWidth(width int) {
+	c.chart.SetWidth(width)
+}
+
+// SetHeight sets the height in terminal rows, at least MinHeight
+func (c *Chart) SetHeight(height int) {
+	c.chart.SetHeight(height)
+}
+
+// SetMinHeight lowers (or raises) the smallest height SetHeight accepts
+func (c *Chart) SetMinHeight(height int) {
+	c.chart.SetMinHeight(height)
+}
+
+// Width returns the width in terminal columns
+func (c *Chart) Width() int {
+	return c.chart.GetWidth()
+}
+
+// Height returns the height in terminal rows
+func (c *Chart) Height() int {
+	return c.chart.GetHeight()
+}
+
+// SetOverlay draws upload and download over each other instead of either
+// side of the centre axis
+func (c *Chart) SetOverlay(enabled bool) {
+	c.chart.SetOverlayMode(enabled)
+}
+
+// SetScalingMode selects how rates map to column heights
+func (c *Chart) SetScalingMode(mode ScalingMode) {
+	c.chart.SetScalingMode(mode)
+}
+
+// SetFixedMaxValue locks the top of the scale at maxValue, clipping taller
+// columns; 0 returns to scaling to the visible samples
+func (c *Chart) SetFixedMaxValue(maxValue uint64) {
+	c.chart.SetFixedMaxValue(maxValue)
+}
+
+// SetTimeScale selects how much time the width covers
+func (c *Chart) SetTimeScale(timeScale TimeScale) {
+	c.chart.SetTimeScale(timeScale)
+}
+
+// SetGlyphSet selects the characters cells are drawn with
+func (c *Chart) SetGlyphSet(set GlyphSet) {
+	c.chart.SetGlyphSet(set)
+}
+
+// SetGutter adds axis gutters with scale labels, formatted by
+// SetLabelFormatter
+func (c *Chart) SetGutter(placement GutterPlacement) {
+	c.chart.SetGutter(placement)
+}
+
+// SetLabelFormatter formats the rates of the gutter labels
+func (c *Chart) SetLabelFormatter(format func(uint64) string) {
+	c.chart.SetLabelFormatter(format)
+}
+
+// SetColors shades the upload and download gradients from #RRGGBB colors;
+// an empty color keeps the default
+func (c *Chart) SetColors(upload, download lipgloss.Color) {
+	c.chart.SetColors(upload, download)
+}
+
+// SetMonochrome draws without colors
+func (c *Chart) SetMonochrome(enabled bool) {
+	c.chart.SetMonochrome(enabled)
+}
+
+// SetPlainOutput leaves out every escape sequence, e.g. for logs and tests
+func (c *Chart) SetPlainOutput(enabled bool) {
+	c.chart.SetPlainOutput(enabled)
+}
+
+// SetSmoothing blends each column with its neighbours, so single-sample
+// spikes stand out less
+func (c *Chart) SetSmoothing(enabled bool) {
+	c.chart.SetSmoothing(enabled)
+}
+
+// ExportData copies the samples, oldest first, stamped back from the newest
+// one every interval
+func (c *Chart) ExportData(interval time.Duration) []DataPoint {
+	return c.chart.ExportData(interval)
+}
+
+// DetectGlyphSet picks the glyph set the terminal is likely to render, from
+// the locale and TERM in getenv and the operating system (runtime.GOOS)
+func DetectGlyphSet(getenv func(string) string, goos string) GlyphSet {
+	return chart.DetectGlyphSet(getenv, goos)
+}